@@ -553,6 +553,19 @@ func (alibaba *Alibaba) NetworkPricing() (*Network, error) {
 	}, nil
 }
 
+// Stubbed SnapshotPricing for Alibaba Cloud, which uses the snapshotStorage
+// custom pricing field until snapshot prices are fetched from the pricing API.
+func (alibaba *Alibaba) SnapshotPricing() (*Snapshot, error) {
+	cpricing, err := alibaba.Config.GetCustomPricingData()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Cost: cpricing.GetSnapshotStorageCost(),
+	}, nil
+}
+
 // Stubbed LoadBalancerPricing for Alibaba Cloud. Will look at this in Next PR
 func (alibaba *Alibaba) LoadBalancerPricing() (*LoadBalancer, error) {
 	return &LoadBalancer{
@@ -856,6 +869,7 @@ func (alibaba *Alibaba) GetKey(mapValue map[string]string, node *v1.Node) Key {
 }
 
 type AlibabaPVKey struct {
+	provisionedPerformance
 	ProviderID        string
 	RegionID          string
 	PVType            string
@@ -874,14 +888,15 @@ func (alibaba *Alibaba) GetPVKey(pv *v1.PersistentVolume, parameters map[string]
 	}
 	slimK8sDisk := generateSlimK8sDiskFromV1PV(pv, defaultRegion)
 	return &AlibabaPVKey{
-		ProviderID:        slimK8sDisk.ProviderID,
-		RegionID:          regionID,
-		PVType:            ALIBABA_PV_CLOUD_DISK_TYPE,
-		PVSubType:         slimK8sDisk.DiskType,
-		PVCategory:        slimK8sDisk.DiskCategory,
-		PVPerformaceLevel: slimK8sDisk.PerformanceLevel,
-		StorageClassName:  pv.Spec.StorageClassName,
-		SizeInGiB:         slimK8sDisk.SizeInGiB,
+		provisionedPerformance: newProvisionedPerformance(pv, parameters),
+		ProviderID:             slimK8sDisk.ProviderID,
+		RegionID:               regionID,
+		PVType:                 ALIBABA_PV_CLOUD_DISK_TYPE,
+		PVSubType:              slimK8sDisk.DiskType,
+		PVCategory:             slimK8sDisk.DiskCategory,
+		PVPerformaceLevel:      slimK8sDisk.PerformanceLevel,
+		StorageClassName:       pv.Spec.StorageClassName,
+		SizeInGiB:              slimK8sDisk.SizeInGiB,
 	}
 }
 
@@ -56,6 +56,10 @@ const (
 	AttachedState = "attached"
 
 	AWSHourlyPublicIPCost    = 0.005
+	AWSMonthlySnapshotCost   = 0.05
+	AWSMonthlyGP3IOPSCost    = 0.005
+	AWSMonthlyGP3MiBpsCost   = 0.04
+	AWSMonthlyPIOPSCost      = 0.065
 	EKSCapacityTypeLabel     = "eks.amazonaws.com/capacityType"
 	EKSCapacitySpotTypeValue = "SPOT"
 )
@@ -326,14 +330,50 @@ var volTypes = map[string]string{
 	"EBS:VolumeP-IOPS.piops": "io1",
 	"EBS:VolumeUsage.st1":    "st1",
 	"EBS:VolumeUsage.piops":  "io1",
+	"EBS:VolumeUsage.io2":    "io2",
 	"gp2":                    "EBS:VolumeUsage.gp2",
 	"gp3":                    "EBS:VolumeUsage.gp3",
 	"standard":               "EBS:VolumeUsage",
 	"sc1":                    "EBS:VolumeUsage.sc1",
 	"io1":                    "EBS:VolumeUsage.piops",
+	"io2":                    "EBS:VolumeUsage.io2",
 	"st1":                    "EBS:VolumeUsage.st1",
 }
 
+// awsPVPerformance describes how the provisioned IOPS and throughput of an EBS
+// volume type are billed: the UsageTypes of their prices, the list prices
+// used when those are not available, and the baseline included in the price
+// of storage.
+type awsPVPerformance struct {
+	IOPSUsageType         string
+	ThroughputUsageType   string
+	MonthlyIOPSCost       float64
+	MonthlyThroughputCost float64
+	BaselineIOPS          float64
+	BaselineThroughput    float64
+}
+
+// awsPVPerformanceTypes maps the EBS volume types which bill for provisioned
+// IOPS or throughput to their pricing. Tiered io2 IOPS pricing is not applied.
+var awsPVPerformanceTypes = map[string]awsPVPerformance{
+	"gp3": {
+		IOPSUsageType:         "EBS:VolumeP-IOPS.gp3",
+		ThroughputUsageType:   "EBS:VolumeP-Throughput.gp3",
+		MonthlyIOPSCost:       AWSMonthlyGP3IOPSCost,
+		MonthlyThroughputCost: AWSMonthlyGP3MiBpsCost,
+		BaselineIOPS:          3000,
+		BaselineThroughput:    125,
+	},
+	"io1": {
+		IOPSUsageType:   "EBS:VolumeP-IOPS.piops",
+		MonthlyIOPSCost: AWSMonthlyPIOPSCost,
+	},
+	"io2": {
+		IOPSUsageType:   "EBS:VolumeP-IOPS.io2",
+		MonthlyIOPSCost: AWSMonthlyPIOPSCost,
+	},
+}
+
 // locationToRegion maps AWS region names (As they come from Billing)
 // to actual region identifiers
 var locationToRegion = map[string]string{
@@ -658,12 +698,58 @@ func (aws *AWS) PVPricing(pvk PVKey) (*PV, error) {
 	pricing, ok := aws.Pricing[pvk.Features()]
 	if !ok {
 		log.Debugf("Persistent Volume pricing not found for %s: %s", pvk.GetStorageClass(), pvk.Features())
-		return &PV{}, nil
+		return aws.pvPerformancePricing(&PV{}, pvk), nil
 	}
-	return pricing.PV, nil
+	return aws.pvPerformancePricing(pricing.PV, pvk), nil
+}
+
+// pvPerformancePricing prices the IOPS and throughput provisioned for the
+// volume of the given key, preferring the prices of the downloaded price list
+// in the volume's region over list prices.
+func (aws *AWS) pvPerformancePricing(pv *PV, pvk PVKey) *PV {
+	key, ok := pvk.(*awsPVKey)
+	if !ok {
+		return pv
+	}
+	performance, ok := awsPVPerformanceTypes[key.volumeType()]
+	if !ok {
+		return pricePVPerformance(pv, pvk, pvPerformancePricing{})
+	}
+
+	pricing := newMonthlyPVPerformancePricing(performance.BaselineIOPS, performance.BaselineThroughput, performance.MonthlyIOPSCost, performance.MonthlyThroughputCost)
+	if cost, ok := aws.ebsUsageTypeHourlyCost(key.region(), performance.IOPSUsageType); ok {
+		pricing.HourlyCostPerIOPS = cost
+	}
+	if cost, ok := aws.ebsUsageTypeHourlyCost(key.region(), performance.ThroughputUsageType); ok {
+		pricing.HourlyCostPerThroughput = cost
+	}
+	return pricePVPerformance(pv, pvk, pricing)
+}
+
+// ebsUsageTypeHourlyCost returns the hourly price of one unit of an EBS
+// UsageType in the given region from the downloaded price list.
+func (aws *AWS) ebsUsageTypeHourlyCost(region, usageType string) (float64, bool) {
+	if usageType == "" {
+		return 0, false
+	}
+	pricing, ok := aws.Pricing[region+","+usageType]
+	if !ok || pricing.PV == nil {
+		return 0, false
+	}
+	if pricing.PV.Cost != "" {
+		cost, err := strconv.ParseFloat(pricing.PV.Cost, 64)
+		return cost, err == nil && cost > 0
+	}
+	// The io1 IOPS price is recorded as a monthly CostPerIO
+	monthly, err := strconv.ParseFloat(pricing.PV.CostPerIO, 64)
+	if err != nil || monthly <= 0 {
+		return 0, false
+	}
+	return monthly / timeutil.HoursPerMonth, true
 }
 
 type awsPVKey struct {
+	provisionedPerformance
 	Labels                 map[string]string
 	StorageClassParameters map[string]string
 	StorageClassName       string
@@ -680,6 +766,7 @@ func (aws *AWS) GetPVKey(pv *v1.PersistentVolume, parameters map[string]string,
 		providerID = pv.Spec.CSI.VolumeHandle
 	}
 	return &awsPVKey{
+		provisionedPerformance: newProvisionedPerformance(pv, parameters),
 		Labels:                 pv.Labels,
 		StorageClassName:       pv.Spec.StorageClassName,
 		StorageClassParameters: parameters,
@@ -698,22 +785,32 @@ func (key *awsPVKey) GetStorageClass() string {
 }
 
 func (key *awsPVKey) Features() string {
+	storageClass := key.volumeType()
+	// Storage class names are generally EBS volume types (gp2)
+	// Keys in Pricing are based on UsageTypes (EBS:VolumeType.gp2)
+	// Converts between the 2
+	class, ok := volTypes[storageClass]
+	if !ok {
+		log.Debugf("No voltype mapping for %s's storageClass: %s", key.Name, storageClass)
+	}
+	return key.region() + "," + class
+}
+
+// volumeType returns the EBS volume type of the key's StorageClass
+func (key *awsPVKey) volumeType() string {
 	storageClass := key.StorageClassParameters["type"]
 	if storageClass == "standard" {
 		storageClass = "gp2"
 	}
-	// Storage class names are generally EBS volume types (gp2)
-	// Keys in Pricing are based on UsageTypes (EBS:VolumeType.gp2)
-	// Converts between the 2
+	return storageClass
+}
+
+func (key *awsPVKey) region() string {
 	region, ok := util.GetRegion(key.Labels)
 	if !ok {
 		region = key.DefaultRegion
 	}
-	class, ok := volTypes[storageClass]
-	if !ok {
-		log.Debugf("No voltype mapping for %s's storageClass: %s", key.Name, storageClass)
-	}
-	return region + "," + class
+	return region
 }
 
 // GetKey maps node labels to information needed to retrieve pricing data
@@ -1138,6 +1235,12 @@ func (aws *AWS) NetworkPricing() (*Network, error) {
 	}, nil
 }
 
+// SnapshotPricing returns the list price of EBS snapshots in the standard tier,
+// per GiB-month of snapshot storage in us-east-1.
+func (aws *AWS) SnapshotPricing() (*Snapshot, error) {
+	return snapshotPricing(aws.Config, AWSMonthlySnapshotCost)
+}
+
 func (aws *AWS) LoadBalancerPricing() (*LoadBalancer, error) {
 	fffrc := 0.025
 	afrc := 0.010
@@ -6,7 +6,11 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"sync"
 	"testing"
+
+	"github.com/opencost/opencost/pkg/util"
+	"github.com/opencost/opencost/pkg/util/timeutil"
 )
 
 func Test_awsKey_getUsageType(t *testing.T) {
@@ -492,3 +496,25 @@ func Test_populate_pricing(t *testing.T) {
 	}
 
 }
+
+func TestAWS_SnapshotPricing(t *testing.T) {
+	// Without a configured price, the list price is used
+	aws := &AWS{Config: &ProviderConfig{lock: new(sync.Mutex), customPricing: &CustomPricing{}}}
+	snapshot, err := aws.SnapshotPricing()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := AWSMonthlySnapshotCost / timeutil.HoursPerMonth; !util.IsApproximately(snapshot.Cost, expected) {
+		t.Fatalf("expected list price %f; got %f", expected, snapshot.Cost)
+	}
+
+	// The configured price takes precedence
+	aws = &AWS{Config: &ProviderConfig{lock: new(sync.Mutex), customPricing: &CustomPricing{SnapshotStorage: "0.0001"}}}
+	snapshot, err = aws.SnapshotPricing()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if snapshot.Cost != 0.0001 {
+		t.Fatalf("expected configured price 0.0001; got %f", snapshot.Cost)
+	}
+}
@@ -41,8 +41,17 @@ const (
 	defaultSpotLabel                 = "kubernetes.azure.com/scalesetpriority"
 	defaultSpotLabelValue            = "spot"
 	AzureStorageUpdateType           = "AzureStorage"
+	AzureMonthlySnapshotCost         = 0.05
 )
 
+// azurePVPerformanceTypes maps the managed disk SKUs which bill for
+// provisioned IOPS or throughput to their list prices per IOPS-month and
+// MB/s-month, and the baseline included in the price of capacity.
+var azurePVPerformanceTypes = map[string]pvPerformancePricing{
+	"premiumv2_lrs": newMonthlyPVPerformancePricing(3000, 125, 0.0049, 0.04),
+	"ultrassd_lrs":  newMonthlyPVPerformancePricing(0, 0, 0.0497, 0.3475),
+}
+
 var (
 	regionCodeMappings = map[string]string{
 		"ap": "asia",
@@ -1106,6 +1115,12 @@ func (az *Azure) NetworkPricing() (*Network, error) {
 	}, nil
 }
 
+// SnapshotPricing on Azure returns the list price of standard managed disk
+// snapshots stored on locally redundant storage, per GiB-month.
+func (az *Azure) SnapshotPricing() (*Snapshot, error) {
+	return snapshotPricing(az.Config, AzureMonthlySnapshotCost)
+}
+
 // LoadBalancerPricing on Azure, LoadBalancer services correspond to public IPs. For now the pricing of LoadBalancer
 // services will be that of a standard static public IP https://azure.microsoft.com/en-us/pricing/details/ip-addresses/.
 // Azure still has load balancers which follow the standard pricing scheme based on rules
@@ -1117,6 +1132,7 @@ func (azr *Azure) LoadBalancerPricing() (*LoadBalancer, error) {
 }
 
 type azurePvKey struct {
+	provisionedPerformance
 	Labels                 map[string]string
 	StorageClass           string
 	StorageClassParameters map[string]string
@@ -1130,6 +1146,7 @@ func (az *Azure) GetPVKey(pv *v1.PersistentVolume, parameters map[string]string,
 		providerID = pv.Spec.AzureDisk.DiskName
 	}
 	return &azurePvKey{
+		provisionedPerformance: newProvisionedPerformance(pv, parameters),
 		Labels:                 pv.Labels,
 		StorageClass:           pv.Spec.StorageClassName,
 		StorageClassParameters: parameters,
@@ -1146,6 +1163,14 @@ func (key *azurePvKey) GetStorageClass() string {
 	return key.StorageClass
 }
 
+// diskSKU returns the SKU of the managed disks of the key's StorageClass
+func (key *azurePvKey) diskSKU() string {
+	if sku := key.StorageClassParameters["storageaccounttype"]; sku != "" {
+		return sku
+	}
+	return key.StorageClassParameters["skuName"]
+}
+
 func (key *azurePvKey) Features() string {
 	storageClass := key.StorageClassParameters["storageaccounttype"]
 	storageSKU := key.StorageClassParameters["skuName"]
@@ -1456,12 +1481,16 @@ func (az *Azure) PVPricing(pvk PVKey) (*PV, error) {
 	az.DownloadPricingDataLock.RLock()
 	defer az.DownloadPricingDataLock.RUnlock()
 
+	var performance pvPerformancePricing
+	if key, ok := pvk.(*azurePvKey); ok {
+		performance = azurePVPerformanceTypes[strings.ToLower(key.diskSKU())]
+	}
 	pricing, ok := az.Pricing[pvk.Features()]
 	if !ok {
 		log.Debugf("Persistent Volume pricing not found for %s: %s", pvk.GetStorageClass(), pvk.Features())
-		return &PV{}, nil
+		return pricePVPerformance(&PV{}, pvk, performance), nil
 	}
-	return pricing.PV, nil
+	return pricePVPerformance(pricing.PV, pvk, performance), nil
 }
 
 func (az *Azure) GetLocalStorageQuery(window, offset time.Duration, rate bool, used bool) string {
@@ -362,6 +362,7 @@ func (c *CSVProvider) GetKey(l map[string]string, n *v1.Node) Key {
 }
 
 type csvPVKey struct {
+	provisionedPerformance
 	Labels                 map[string]string
 	ProviderID             string
 	StorageClassName       string
@@ -385,6 +386,7 @@ func (key *csvPVKey) Features() string {
 func (c *CSVProvider) GetPVKey(pv *v1.PersistentVolume, parameters map[string]string, defaultRegion string) PVKey {
 	id := PVValueFromMapField(c.PVMapField, pv)
 	return &csvPVKey{
+		provisionedPerformance: newProvisionedPerformance(pv, parameters),
 		Labels:                 pv.Labels,
 		ProviderID:             id,
 		StorageClassName:       pv.Spec.StorageClassName,
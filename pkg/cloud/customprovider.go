@@ -238,9 +238,9 @@ func (cp *CustomProvider) PVPricing(pvk PVKey) (*PV, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &PV{
+	return pricePVPerformance(&PV{
 		Cost: cpricing.Storage,
-	}, nil
+	}, pvk, pvPerformancePricing{}), nil
 }
 
 func (cp *CustomProvider) NetworkPricing() (*Network, error) {
@@ -268,6 +268,19 @@ func (cp *CustomProvider) NetworkPricing() (*Network, error) {
 	}, nil
 }
 
+// SnapshotPricing returns the cost of volume snapshot storage configured by
+// the snapshotStorage custom pricing field.
+func (cp *CustomProvider) SnapshotPricing() (*Snapshot, error) {
+	cpricing, err := cp.Config.GetCustomPricingData()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Cost: cpricing.GetSnapshotStorageCost(),
+	}, nil
+}
+
 func (cp *CustomProvider) LoadBalancerPricing() (*LoadBalancer, error) {
 	cpricing, err := cp.Config.GetCustomPricingData()
 	if err != nil {
@@ -301,6 +314,7 @@ func (cp *CustomProvider) LoadBalancerPricing() (*LoadBalancer, error) {
 
 func (*CustomProvider) GetPVKey(pv *v1.PersistentVolume, parameters map[string]string, defaultRegion string) PVKey {
 	return &awsPVKey{
+		provisionedPerformance: newProvisionedPerformance(pv, parameters),
 		Labels:                 pv.Labels,
 		StorageClassName:       pv.Spec.StorageClassName,
 		StorageClassParameters: parameters,
@@ -42,10 +42,22 @@ const (
 	GCPMonthlySSDDiskCost   = 0.17
 	GCPMonthlyGP2DiskCost   = 0.1
 
+	GCPMonthlySnapshotCost = 0.05
+
 	GKEPreemptibleLabel = "cloud.google.com/gke-preemptible"
 	GKESpotLabel        = "cloud.google.com/gke-spot"
 )
 
+// gcpPVPerformanceTypes maps the Persistent Disk and Hyperdisk types which
+// bill for provisioned IOPS or throughput to their list prices per IOPS-month
+// and MiB/s-month, and the baseline included in the price of capacity.
+var gcpPVPerformanceTypes = map[string]pvPerformancePricing{
+	"pd-extreme":           newMonthlyPVPerformancePricing(0, 0, 0.065, 0),
+	"hyperdisk-balanced":   newMonthlyPVPerformancePricing(3000, 140, 0.005, 0.04),
+	"hyperdisk-extreme":    newMonthlyPVPerformancePricing(0, 0, 0.031, 0),
+	"hyperdisk-throughput": newMonthlyPVPerformancePricing(0, 0, 0, 0.05),
+}
+
 // List obtained by installing the `gcloud` CLI tool,
 // logging into gcp account, and running command
 // `gcloud compute regions list`
@@ -1110,12 +1122,16 @@ func (gcp *GCP) DownloadPricingData() error {
 func (gcp *GCP) PVPricing(pvk PVKey) (*PV, error) {
 	gcp.DownloadPricingDataLock.RLock()
 	defer gcp.DownloadPricingDataLock.RUnlock()
+	var performance pvPerformancePricing
+	if key, ok := pvk.(*pvKey); ok {
+		performance = gcpPVPerformanceTypes[key.StorageClassParameters["type"]]
+	}
 	pricing, ok := gcp.Pricing[pvk.Features()]
 	if !ok {
 		log.Infof("Persistent Volume pricing not found for %s: %s", pvk.GetStorageClass(), pvk.Features())
-		return &PV{}, nil
+		return pricePVPerformance(&PV{}, pvk, performance), nil
 	}
-	return pricing.PV, nil
+	return pricePVPerformance(pricing.PV, pvk, performance), nil
 }
 
 // Stubbed NetworkPricing for GCP. Pull directly from gcp.json for now
@@ -1144,6 +1160,12 @@ func (gcp *GCP) NetworkPricing() (*Network, error) {
 	}, nil
 }
 
+// SnapshotPricing returns the list price of standard Persistent Disk snapshot
+// storage per GiB-month.
+func (gcp *GCP) SnapshotPricing() (*Snapshot, error) {
+	return snapshotPricing(gcp.Config, GCPMonthlySnapshotCost)
+}
+
 func (gcp *GCP) LoadBalancerPricing() (*LoadBalancer, error) {
 	fffrc := 0.025
 	afrc := 0.010
@@ -1387,6 +1409,7 @@ func (gcp *GCP) getReservedInstances() ([]*GCPReservedInstance, error) {
 }
 
 type pvKey struct {
+	provisionedPerformance
 	ProviderID             string
 	Labels                 map[string]string
 	StorageClass           string
@@ -1408,6 +1431,7 @@ func (gcp *GCP) GetPVKey(pv *v1.PersistentVolume, parameters map[string]string,
 		providerID = pv.Spec.GCEPersistentDisk.PDName
 	}
 	return &pvKey{
+		provisionedPerformance: newProvisionedPerformance(pv, parameters),
 		ProviderID:             providerID,
 		Labels:                 pv.Labels,
 		StorageClass:           pv.Spec.StorageClassName,
@@ -25,6 +25,7 @@ import (
 	"github.com/opencost/opencost/pkg/env"
 	"github.com/opencost/opencost/pkg/log"
 	"github.com/opencost/opencost/pkg/util/httputil"
+	"github.com/opencost/opencost/pkg/util/timeutil"
 	"github.com/opencost/opencost/pkg/util/watcher"
 
 	v1 "k8s.io/api/core/v1"
@@ -98,6 +99,12 @@ type LoadBalancer struct {
 	Cost               float64  `json:"hourlyCost"`
 }
 
+// Snapshot is the interface by which the provider and cost model communicate volume snapshot
+// storage prices. The provider will best-effort try to fill out this struct.
+type Snapshot struct {
+	Cost float64 `json:"GiBHourlyCost"` // cost per GiB-hour of snapshot storage
+}
+
 // TODO: used for dynamic cloud provider price fetching.
 // determine what identifies a load balancer in the json returned from the cloud provider pricing API call
 // type LBKey interface {
@@ -125,13 +132,17 @@ type OrphanedResource struct {
 // PV is the interface by which the provider and cost model communicate PV prices.
 // The provider will best-effort try to fill out this struct.
 type PV struct {
-	Cost       string            `json:"hourlyCost"`
-	CostPerIO  string            `json:"costPerIOOperation"`
-	Class      string            `json:"storageClass"`
-	Size       string            `json:"size"`
-	Region     string            `json:"region"`
-	ProviderID string            `json:"providerID,omitempty"`
-	Parameters map[string]string `json:"parameters"`
+	Cost                  string            `json:"hourlyCost"`
+	CostPerIO             string            `json:"costPerIOOperation"`
+	Class                 string            `json:"storageClass"`
+	Size                  string            `json:"size"`
+	Region                string            `json:"region"`
+	ProviderID            string            `json:"providerID,omitempty"`
+	Parameters            map[string]string `json:"parameters"`
+	ProvisionedIOPS       string            `json:"provisionedIOPS,omitempty"`
+	ProvisionedThroughput string            `json:"provisionedThroughput,omitempty"` // MiB/s
+	IOPSCost              string            `json:"hourlyIOPSCost,omitempty"`        // hourly cost of the provisioned IOPS
+	ThroughputCost        string            `json:"hourlyThroughputCost,omitempty"`  // hourly cost of the provisioned throughput
 }
 
 // Key represents a way for nodes to match between the k8s API and a pricing API
@@ -146,6 +157,8 @@ type PVKey interface {
 	Features() string
 	GetStorageClass() string
 	ID() string
+	ProvisionedIOPS() float64       // ProvisionedIOPS returns 0 if the IOPS of the volume are not provisioned
+	ProvisionedThroughput() float64 // ProvisionedThroughput returns 0 if the throughput of the volume is not provisioned, otherwise MiB/s
 }
 
 // OutOfClusterAllocation represents a cloud provider cost not associated with kubernetes
@@ -175,6 +188,7 @@ type CustomPricing struct {
 	// Storage is a string-encoded float describing cost per GB-hour of storage
 	// (e.g. PV, disk) resources.
 	Storage                      string `json:"storage"`
+	SnapshotStorage              string `json:"snapshotStorage,omitempty"` // cost per GB-hour of volume snapshot storage, for providers without snapshot list prices
 	ZoneNetworkEgress            string `json:"zoneNetworkEgress"`
 	RegionNetworkEgress          string `json:"regionNetworkEgress"`
 	InternetNetworkEgress        string `json:"internetNetworkEgress"`
@@ -252,6 +266,44 @@ func (cp *CustomPricing) GetSharedOverheadCostPerMonth() float64 {
 	return sharedCostPerMonth
 }
 
+// GetSnapshotStorageCost parses and returns a float64 representation of the
+// configured cost per GB-hour of volume snapshot storage. If it is not set, or
+// cannot be parsed, the default price is returned.
+func (cp *CustomPricing) GetSnapshotStorageCost() float64 {
+	defaultCost, _ := strconv.ParseFloat(DefaultPricing().SnapshotStorage, 64)
+	if cp.SnapshotStorage == "" {
+		return defaultCost
+	}
+
+	cost, err := strconv.ParseFloat(cp.SnapshotStorage, 64)
+	if err != nil {
+		log.Errorf("SnapshotStorage: failed to parse snapshot storage cost \"%s\": %s", cp.SnapshotStorage, err)
+		return defaultCost
+	}
+
+	return cost
+}
+
+// snapshotPricing returns the Snapshot cost per GB-hour set in the given
+// custom pricing configuration, falling back to the given monthly list price
+// per GB when none is set.
+func snapshotPricing(config *ProviderConfig, monthlyCost float64) (*Snapshot, error) {
+	cpricing, err := config.GetCustomPricingData()
+	if err != nil {
+		return nil, err
+	}
+
+	if cpricing.SnapshotStorage == "" {
+		return &Snapshot{
+			Cost: monthlyCost / timeutil.HoursPerMonth,
+		}, nil
+	}
+
+	return &Snapshot{
+		Cost: cpricing.GetSnapshotStorageCost(),
+	}, nil
+}
+
 type ServiceAccountStatus struct {
 	Checks []*ServiceAccountCheck `json:"checks"`
 }
@@ -332,6 +384,7 @@ type Provider interface {
 	PVPricing(PVKey) (*PV, error)
 	NetworkPricing() (*Network, error)           // TODO: add key interface arg for dynamic price fetching
 	LoadBalancerPricing() (*LoadBalancer, error) // TODO: add key interface arg for dynamic price fetching
+	SnapshotPricing() (*Snapshot, error)         // TODO: add key interface arg for dynamic price fetching
 	AllNodePricing() (interface{}, error)
 	DownloadPricingData() error
 	GetKey(map[string]string, *v1.Node) Key
@@ -236,6 +236,14 @@ func DefaultPricing() *CustomPricing {
 		//     0.00005479452054794521
 		Storage: "0.00005479452",
 
+		// This is the "Standard snapshot storage" pricing in the "Disk
+		// pricing" table.
+		//
+		// (($.05 / month) per G(i?)B) *
+		//   month/730 hours =
+		//     0.00006849315068493151
+		SnapshotStorage: "0.00006849315",
+
 		ZoneNetworkEgress:     "0.01",
 		RegionNetworkEgress:   "0.01",
 		InternetNetworkEgress: "0.12",
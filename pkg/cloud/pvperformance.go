@@ -0,0 +1,154 @@
+package cloud
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/util/timeutil"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// PVProvisionedIOPSAnnotation sets the IOPS provisioned for a PV,
+	// overriding its StorageClass, e.g. after the volume was modified.
+	PVProvisionedIOPSAnnotation = "opencost.io/provisioned-iops"
+	// PVProvisionedThroughputAnnotation sets the throughput provisioned for a
+	// PV in MiB/s, overriding its StorageClass.
+	PVProvisionedThroughputAnnotation = "opencost.io/provisioned-throughput"
+)
+
+// StorageClass parameters setting the provisioned IOPS and throughput of a
+// volume, across the CSI drivers of the supported providers. Parameters are
+// matched case-insensitively.
+var (
+	pvIOPSParameters       = []string{"iops", "provisioned-iops-on-create", "diskiopsreadwrite"}
+	pvIOPSPerGiBParameters = []string{"iopspergb"}
+	pvThroughputParameters = []string{"throughput", "provisioned-throughput-on-create", "diskmbpsreadwrite"}
+)
+
+// provisionedPerformance is the IOPS and throughput, in MiB/s, provisioned for
+// a persistent volume. It is embedded in PVKeys to implement
+// ProvisionedIOPS and ProvisionedThroughput.
+type provisionedPerformance struct {
+	IOPS       float64
+	Throughput float64
+}
+
+func (pp provisionedPerformance) ProvisionedIOPS() float64 {
+	return pp.IOPS
+}
+
+func (pp provisionedPerformance) ProvisionedThroughput() float64 {
+	return pp.Throughput
+}
+
+// newProvisionedPerformance reads the IOPS and throughput provisioned for the
+// given PV from its annotations, falling back to the parameters of its
+// StorageClass.
+func newProvisionedPerformance(pv *v1.PersistentVolume, parameters map[string]string) provisionedPerformance {
+	pp := provisionedPerformance{}
+	if pv == nil {
+		return pp
+	}
+
+	if v, ok := lookupParameter(parameters, pvIOPSParameters); ok {
+		pp.IOPS = parseProvisionedQuantity(pv.Name, v)
+	} else if v, ok := lookupParameter(parameters, pvIOPSPerGiBParameters); ok {
+		if capacity, ok := pv.Spec.Capacity[v1.ResourceStorage]; ok {
+			gib := float64(capacity.Value()) / 1024 / 1024 / 1024
+			pp.IOPS = parseProvisionedQuantity(pv.Name, v) * gib
+		}
+	}
+	if v, ok := lookupParameter(parameters, pvThroughputParameters); ok {
+		pp.Throughput = parseProvisionedQuantity(pv.Name, v)
+	}
+
+	if v, ok := pv.Annotations[PVProvisionedIOPSAnnotation]; ok {
+		pp.IOPS = parseProvisionedQuantity(pv.Name, v)
+	}
+	if v, ok := pv.Annotations[PVProvisionedThroughputAnnotation]; ok {
+		pp.Throughput = parseProvisionedQuantity(pv.Name, v)
+	}
+
+	return pp
+}
+
+// lookupParameter returns the value of the first of the given keys found in
+// parameters, ignoring case.
+func lookupParameter(parameters map[string]string, keys []string) (string, bool) {
+	for k, v := range parameters {
+		for _, key := range keys {
+			if strings.EqualFold(k, key) {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// parseProvisionedQuantity parses IOPS or throughput values such as "3000" or
+// "250Mi", ignoring any trailing unit.
+func parseProvisionedQuantity(pvName, value string) float64 {
+	value = strings.TrimRightFunc(strings.TrimSpace(value), func(r rune) bool {
+		return !unicode.IsDigit(r)
+	})
+	if value == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Debugf("Unable to parse provisioned performance \"%s\" of pv %s: %s", value, pvName, err)
+		return 0
+	}
+	return f
+}
+
+// pvPerformancePricing is the price of the provisioned IOPS and throughput of
+// a volume type. IOPS and throughput up to the baseline are included in the
+// price of storage.
+type pvPerformancePricing struct {
+	BaselineIOPS            float64
+	BaselineThroughput      float64
+	HourlyCostPerIOPS       float64
+	HourlyCostPerThroughput float64 // per MiB/s
+}
+
+// newMonthlyPVPerformancePricing returns the pricing of a volume type from
+// its monthly list prices.
+func newMonthlyPVPerformancePricing(baselineIOPS, baselineThroughput, monthlyCostPerIOPS, monthlyCostPerThroughput float64) pvPerformancePricing {
+	return pvPerformancePricing{
+		BaselineIOPS:            baselineIOPS,
+		BaselineThroughput:      baselineThroughput,
+		HourlyCostPerIOPS:       monthlyCostPerIOPS / timeutil.HoursPerMonth,
+		HourlyCostPerThroughput: monthlyCostPerThroughput / timeutil.HoursPerMonth,
+	}
+}
+
+// pricePVPerformance returns a copy of pv carrying the IOPS and throughput
+// provisioned for the volume of the given key, and their hourly cost. The
+// given PV, which may be shared by all volumes of its type, is not modified.
+func pricePVPerformance(pv *PV, pvk PVKey, pricing pvPerformancePricing) *PV {
+	priced := &PV{}
+	if pv != nil {
+		*priced = *pv
+	}
+
+	if iops := pvk.ProvisionedIOPS(); iops > 0 {
+		priced.ProvisionedIOPS = strconv.FormatFloat(iops, 'f', -1, 64)
+		if billed := math.Max(iops-pricing.BaselineIOPS, 0); billed > 0 && pricing.HourlyCostPerIOPS > 0 {
+			priced.IOPSCost = strconv.FormatFloat(billed*pricing.HourlyCostPerIOPS, 'f', -1, 64)
+		}
+	}
+	if throughput := pvk.ProvisionedThroughput(); throughput > 0 {
+		priced.ProvisionedThroughput = strconv.FormatFloat(throughput, 'f', -1, 64)
+		if billed := math.Max(throughput-pricing.BaselineThroughput, 0); billed > 0 && pricing.HourlyCostPerThroughput > 0 {
+			priced.ThroughputCost = strconv.FormatFloat(billed*pricing.HourlyCostPerThroughput, 'f', -1, 64)
+		}
+	}
+
+	return priced
+}
@@ -0,0 +1,147 @@
+package cloud
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/opencost/opencost/pkg/util"
+	"github.com/opencost/opencost/pkg/util/timeutil"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewProvisionedPerformance(t *testing.T) {
+	cases := map[string]struct {
+		annotations map[string]string
+		capacity    string
+		parameters  map[string]string
+		expected    provisionedPerformance
+	}{
+		"ebs gp3": {
+			parameters: map[string]string{"type": "gp3", "iops": "6000", "throughput": "250"},
+			expected:   provisionedPerformance{IOPS: 6000, Throughput: 250},
+		},
+		"ebs io1 per GiB": {
+			capacity:   "100Gi",
+			parameters: map[string]string{"type": "io1", "iopsPerGB": "50"},
+			expected:   provisionedPerformance{IOPS: 5000},
+		},
+		"gce hyperdisk": {
+			parameters: map[string]string{"type": "hyperdisk-balanced", "provisioned-iops-on-create": "10000", "provisioned-throughput-on-create": "250Mi"},
+			expected:   provisionedPerformance{IOPS: 10000, Throughput: 250},
+		},
+		"azure ultra": {
+			parameters: map[string]string{"skuName": "UltraSSD_LRS", "DiskIOPSReadWrite": "4000", "DiskMBpsReadWrite": "200"},
+			expected:   provisionedPerformance{IOPS: 4000, Throughput: 200},
+		},
+		"annotations override storage class": {
+			annotations: map[string]string{PVProvisionedIOPSAnnotation: "8000", PVProvisionedThroughputAnnotation: "500"},
+			parameters:  map[string]string{"type": "gp3", "iops": "6000", "throughput": "250"},
+			expected:    provisionedPerformance{IOPS: 8000, Throughput: 500},
+		},
+		"not provisioned": {
+			parameters: map[string]string{"type": "gp2"},
+			expected:   provisionedPerformance{},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			pv := &v1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv", Annotations: c.annotations},
+			}
+			if c.capacity != "" {
+				pv.Spec.Capacity = v1.ResourceList{v1.ResourceStorage: resource.MustParse(c.capacity)}
+			}
+
+			actual := newProvisionedPerformance(pv, c.parameters)
+			if actual != c.expected {
+				t.Fatalf("expected %+v; got %+v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAWS_PVPricing_ProvisionedPerformance(t *testing.T) {
+	storage := &PV{Cost: "0.000109589", Class: "gp3", Region: "us-east-2"}
+	aws := &AWS{
+		Pricing: map[string]*AWSProductTerms{
+			"us-east-2,EBS:VolumeUsage.gp3":        {PV: storage},
+			"us-east-2,EBS:VolumeP-IOPS.gp3":       {PV: &PV{Cost: strconv.FormatFloat(0.005/timeutil.HoursPerMonth, 'f', -1, 64)}},
+			"us-east-2,EBS:VolumeP-Throughput.gp3": {PV: &PV{Cost: strconv.FormatFloat(0.04/timeutil.HoursPerMonth, 'f', -1, 64)}},
+		},
+	}
+
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+	key := aws.GetPVKey(pv, map[string]string{"type": "gp3", "iops": "6000", "throughput": "250"}, "us-east-2")
+
+	priced, err := aws.PVPricing(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if priced.Cost != storage.Cost {
+		t.Fatalf("expected storage cost %s; got %s", storage.Cost, priced.Cost)
+	}
+	if priced.ProvisionedIOPS != "6000" || priced.ProvisionedThroughput != "250" {
+		t.Fatalf("expected 6000 IOPS and 250 MiB/s; got %s and %s", priced.ProvisionedIOPS, priced.ProvisionedThroughput)
+	}
+
+	// Only the IOPS and throughput above the gp3 baseline are billed
+	iopsCost, _ := strconv.ParseFloat(priced.IOPSCost, 64)
+	if !util.IsApproximately(iopsCost, 3000*0.005/timeutil.HoursPerMonth) {
+		t.Fatalf("expected IOPS cost of %f; got %f", 3000*0.005/timeutil.HoursPerMonth, iopsCost)
+	}
+	throughputCost, _ := strconv.ParseFloat(priced.ThroughputCost, 64)
+	if !util.IsApproximately(throughputCost, 125*0.04/timeutil.HoursPerMonth) {
+		t.Fatalf("expected throughput cost of %f; got %f", 125*0.04/timeutil.HoursPerMonth, throughputCost)
+	}
+
+	// The PV shared by all gp3 volumes is left as is
+	if storage.IOPSCost != "" || storage.ProvisionedIOPS != "" {
+		t.Fatalf("expected shared pricing to be unmodified; got %+v", storage)
+	}
+
+	// A gp3 volume within the baseline is billed for storage only
+	key = aws.GetPVKey(pv, map[string]string{"type": "gp3"}, "us-east-2")
+	priced, _ = aws.PVPricing(key)
+	if priced.IOPSCost != "" || priced.ThroughputCost != "" {
+		t.Fatalf("expected no performance costs; got %s and %s", priced.IOPSCost, priced.ThroughputCost)
+	}
+}
+
+func TestPricePVPerformance(t *testing.T) {
+	pv := &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+
+	// Ultra disks have no baseline
+	az := &Azure{}
+	key := az.GetPVKey(pv, map[string]string{"skuName": "UltraSSD_LRS", "DiskIOPSReadWrite": "4000", "DiskMBpsReadWrite": "200"}, "eastus")
+	priced, err := az.PVPricing(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	iopsCost, _ := strconv.ParseFloat(priced.IOPSCost, 64)
+	if !util.IsApproximately(iopsCost, 4000*0.0497/timeutil.HoursPerMonth) {
+		t.Fatalf("expected IOPS cost of %f; got %f", 4000*0.0497/timeutil.HoursPerMonth, iopsCost)
+	}
+	throughputCost, _ := strconv.ParseFloat(priced.ThroughputCost, 64)
+	if !util.IsApproximately(throughputCost, 200*0.3475/timeutil.HoursPerMonth) {
+		t.Fatalf("expected throughput cost of %f; got %f", 200*0.3475/timeutil.HoursPerMonth, throughputCost)
+	}
+
+	// pd-extreme volumes bill for IOPS only
+	gcp := &GCP{}
+	key = gcp.GetPVKey(pv, map[string]string{"type": "pd-extreme", "provisioned-iops-on-create": "10000"}, "us-central1")
+	priced, err = gcp.PVPricing(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	iopsCost, _ = strconv.ParseFloat(priced.IOPSCost, 64)
+	if !util.IsApproximately(iopsCost, 10000*0.065/timeutil.HoursPerMonth) {
+		t.Fatalf("expected IOPS cost of %f; got %f", 10000*0.065/timeutil.HoursPerMonth, iopsCost)
+	}
+	if priced.ThroughputCost != "" {
+		t.Fatalf("expected no throughput cost; got %s", priced.ThroughputCost)
+	}
+}
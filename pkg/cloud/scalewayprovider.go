@@ -175,6 +175,19 @@ func (c *Scaleway) NetworkPricing() (*Network, error) {
 	}, nil
 }
 
+// SnapshotPricing for Scaleway isn't listed by its API, so the snapshotStorage
+// custom pricing field is used.
+func (c *Scaleway) SnapshotPricing() (*Snapshot, error) {
+	cpricing, err := c.Config.GetCustomPricingData()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Cost: cpricing.GetSnapshotStorageCost(),
+	}, nil
+}
+
 func (c *Scaleway) GetKey(l map[string]string, n *v1.Node) Key {
 	return &scalewayKey{
 		Labels: l,
@@ -182,6 +195,7 @@ func (c *Scaleway) GetKey(l map[string]string, n *v1.Node) Key {
 }
 
 type scalewayPVKey struct {
+	provisionedPerformance
 	Labels                 map[string]string
 	StorageClassName       string
 	StorageClassParameters map[string]string
@@ -206,6 +220,7 @@ func (c *Scaleway) GetPVKey(pv *v1.PersistentVolume, parameters map[string]strin
 	// the csi volume handle is the form <az>/<volume-id>
 	zone := strings.Split(pv.Spec.CSI.VolumeHandle, "/")[0]
 	return &scalewayPVKey{
+		provisionedPerformance: newProvisionedPerformance(pv, parameters),
 		Labels:                 pv.Labels,
 		StorageClassName:       pv.Spec.StorageClassName,
 		StorageClassParameters: parameters,
@@ -0,0 +1,160 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opencost/opencost/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedProvider is a Provider decorator which records a trace span for each
+// pricing call made against the underlying Provider, as a child of the span
+// of its context. Until bound to a context with ProviderWithContext, calls
+// are not traced, rather than being recorded as orphaned root spans.
+type tracedProvider struct {
+	Provider
+	providerType string
+	ctx          context.Context
+}
+
+// NewTracedProvider wraps the given Provider such that pricing calls are
+// recorded as trace spans. If tracing is not configured, the spans are no-ops.
+func NewTracedProvider(p Provider) Provider {
+	if p == nil {
+		return nil
+	}
+
+	// Don't double wrap
+	if tp, ok := p.(*tracedProvider); ok {
+		return tp
+	}
+
+	return &tracedProvider{
+		Provider:     p,
+		providerType: fmt.Sprintf("%T", p),
+	}
+}
+
+// ProviderWithContext returns the given Provider bound to the given context,
+// such that, if the Provider is traced, its pricing calls are recorded as
+// children of the span of that context. Other Providers are returned as is.
+func ProviderWithContext(ctx context.Context, p Provider) Provider {
+	tp, ok := p.(*tracedProvider)
+	if !ok || ctx == nil {
+		return p
+	}
+
+	return &tracedProvider{
+		Provider:     tp.Provider,
+		providerType: tp.providerType,
+		ctx:          ctx,
+	}
+}
+
+func (tp *tracedProvider) startSpan(name string, attrs ...attribute.KeyValue) trace.Span {
+	if tp.ctx == nil || !trace.SpanFromContext(tp.ctx).SpanContext().IsValid() {
+		return trace.SpanFromContext(context.Background())
+	}
+
+	attrs = append(attrs, attribute.String("cloud.provider.type", tp.providerType))
+	_, span := tracing.Start(tp.ctx, "cloud.Provider."+name, attrs...)
+	return span
+}
+
+func (tp *tracedProvider) NodePricing(key Key) (*Node, error) {
+	span := tp.startSpan("NodePricing", keyAttributes(key)...)
+	defer span.End()
+
+	node, err := tp.Provider.NodePricing(key)
+	tracing.RecordError(span, err)
+	if node != nil && node.PricingType != "" {
+		span.SetAttributes(attribute.String("cloud.pricing.type", string(node.PricingType)))
+	}
+	return node, err
+}
+
+func (tp *tracedProvider) PVPricing(key PVKey) (*PV, error) {
+	var attrs []attribute.KeyValue
+	if key != nil {
+		attrs = append(attrs,
+			attribute.String("cloud.pv.id", key.ID()),
+			attribute.String("cloud.pv.storageClass", key.GetStorageClass()),
+			attribute.String("cloud.pv.features", key.Features()),
+		)
+	}
+	span := tp.startSpan("PVPricing", attrs...)
+	defer span.End()
+
+	pv, err := tp.Provider.PVPricing(key)
+	tracing.RecordError(span, err)
+	return pv, err
+}
+
+func (tp *tracedProvider) NetworkPricing() (*Network, error) {
+	span := tp.startSpan("NetworkPricing")
+	defer span.End()
+
+	network, err := tp.Provider.NetworkPricing()
+	tracing.RecordError(span, err)
+	return network, err
+}
+
+func (tp *tracedProvider) LoadBalancerPricing() (*LoadBalancer, error) {
+	span := tp.startSpan("LoadBalancerPricing")
+	defer span.End()
+
+	lb, err := tp.Provider.LoadBalancerPricing()
+	tracing.RecordError(span, err)
+	return lb, err
+}
+
+func (tp *tracedProvider) SnapshotPricing() (*Snapshot, error) {
+	span := tp.startSpan("SnapshotPricing")
+	defer span.End()
+
+	snapshot, err := tp.Provider.SnapshotPricing()
+	tracing.RecordError(span, err)
+	return snapshot, err
+}
+
+func (tp *tracedProvider) AllNodePricing() (interface{}, error) {
+	span := tp.startSpan("AllNodePricing")
+	defer span.End()
+
+	pricing, err := tp.Provider.AllNodePricing()
+	tracing.RecordError(span, err)
+	return pricing, err
+}
+
+func (tp *tracedProvider) DownloadPricingData() error {
+	span := tp.startSpan("DownloadPricingData")
+	defer span.End()
+
+	err := tp.Provider.DownloadPricingData()
+	tracing.RecordError(span, err)
+	return err
+}
+
+func (tp *tracedProvider) ClusterManagementPricing() (string, float64, error) {
+	span := tp.startSpan("ClusterManagementPricing")
+	defer span.End()
+
+	platform, cost, err := tp.Provider.ClusterManagementPricing()
+	tracing.RecordError(span, err)
+	return platform, cost, err
+}
+
+func keyAttributes(key Key) []attribute.KeyValue {
+	if key == nil {
+		return nil
+	}
+
+	return []attribute.KeyValue{
+		attribute.String("cloud.node.id", key.ID()),
+		attribute.String("cloud.node.features", key.Features()),
+		attribute.String("cloud.node.gpuType", key.GPUType()),
+		attribute.Int("cloud.node.gpuCount", key.GPUCount()),
+	}
+}
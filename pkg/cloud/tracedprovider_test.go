@@ -0,0 +1,60 @@
+package cloud
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opencost/opencost/pkg/tracing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type networkPricingProvider struct {
+	Provider
+}
+
+func (p *networkPricingProvider) NetworkPricing() (*Network, error) {
+	return &Network{InternetNetworkEgressCost: 0.12}, nil
+}
+
+func TestTracedProvider_ProviderWithContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	tp := NewTracedProvider(&networkPricingProvider{})
+
+	// Calls without a context are not recorded as orphaned root spans
+	if _, err := tp.NetworkPricing(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Fatalf("expected no spans for unbound provider; got %d", len(spans))
+	}
+
+	ctx, parent := tracing.Start(context.Background(), "request")
+	network, err := ProviderWithContext(ctx, tp).NetworkPricing()
+	parent.End()
+	if err != nil || network.InternetNetworkEgressCost != 0.12 {
+		t.Fatalf("unexpected pricing: %v, %v", network, err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans; got %d", len(spans))
+	}
+	if spans[0].Name() != "cloud.Provider.NetworkPricing" || spans[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("expected NetworkPricing span to be a child of the request span")
+	}
+
+	// Providers which are not traced are returned as is
+	untraced := &networkPricingProvider{}
+	if ProviderWithContext(ctx, untraced) != untraced {
+		t.Fatalf("expected untraced provider to be returned as is")
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"github.com/opencost/opencost/pkg/log"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/api/policy/v1beta1"
 	stv1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -66,6 +70,30 @@ type ClusterCache interface {
 	// GetAllReplicationControllers returns all cached replication controllers
 	GetAllReplicationControllers() []*v1.ReplicationController
 
+	// GetAllCronJobs returns all cached cron jobs
+	GetAllCronJobs() []*batchv1.CronJob
+
+	// GetAllIngresses returns all cached ingresses
+	GetAllIngresses() []*networkingv1.Ingress
+
+	// GetAllHorizontalPodAutoscalers returns all cached horizontal pod autoscalers
+	GetAllHorizontalPodAutoscalers() []*autoscalingv2.HorizontalPodAutoscaler
+
+	// GetAllResourceQuotas returns all cached resource quotas
+	GetAllResourceQuotas() []*v1.ResourceQuota
+
+	// GetAllLimitRanges returns all cached limit ranges
+	GetAllLimitRanges() []*v1.LimitRange
+
+	// GetAllRollouts returns all cached Argo Rollouts
+	GetAllRollouts() []*Rollout
+
+	// GetAllVolumeSnapshots returns all cached CSI VolumeSnapshots
+	GetAllVolumeSnapshots() []*VolumeSnapshot
+
+	// GetAllVolumeSnapshotContents returns all cached CSI VolumeSnapshotContents
+	GetAllVolumeSnapshotContents() []*VolumeSnapshotContent
+
 	// SetConfigMapUpdateFunc sets the configmap update function
 	SetConfigMapUpdateFunc(func(interface{}))
 }
@@ -89,6 +117,14 @@ type KubernetesClusterCache struct {
 	jobsWatch                  WatchController
 	pdbWatch                   WatchController
 	replicationControllerWatch WatchController
+	cronJobWatch               WatchController
+	ingressWatch               WatchController
+	hpaWatch                   WatchController
+	resourceQuotaWatch         WatchController
+	limitRangeWatch            WatchController
+	rolloutWatch               WatchController
+	volumeSnapshotWatch        WatchController
+	volumeSnapshotContentWatch WatchController
 	stop                       chan struct{}
 }
 
@@ -98,6 +134,12 @@ func initializeCache(wc WatchController, wg *sync.WaitGroup, cancel chan struct{
 }
 
 func NewKubernetesClusterCache(client kubernetes.Interface) ClusterCache {
+	return NewKubernetesClusterCacheWithDynamic(client, nil)
+}
+
+// NewKubernetesClusterCacheWithDynamic creates a ClusterCache which additionally uses the dynamic client
+// to watch custom resources, such as Argo Rollouts, when they are served by the cluster.
+func NewKubernetesClusterCacheWithDynamic(client kubernetes.Interface, dynamicClient dynamic.Interface) ClusterCache {
 	coreRestClient := client.CoreV1().RESTClient()
 	appsRestClient := client.AppsV1().RESTClient()
 	storageRestClient := client.StorageV1().RESTClient()
@@ -155,9 +197,70 @@ func NewKubernetesClusterCache(client kubernetes.Interface) ClusterCache {
 
 	log.Infof("Done waiting")
 
+	// Additional resource kinds are optional: they may not be served by older clusters or
+	// permitted by existing RBAC rules, so they are warmed up without blocking the cache.
+	if !env.IsETLReadOnlyMode() {
+		kcc.initOptionalWatchers(dynamicClient, cancel)
+	}
+
 	return kcc
 }
 
+// initOptionalWatchers creates and asynchronously warms up watchers for each optional resource kind
+// served by the cluster.
+func (kcc *KubernetesClusterCache) initOptionalWatchers(dynamicClient dynamic.Interface, cancel chan struct{}) {
+	client := kcc.client
+
+	if isResourceServed(client, "batch/v1", "cronjobs") {
+		kcc.cronJobWatch = NewCachingWatcher(client.BatchV1().RESTClient(), "cronjobs", &batchv1.CronJob{}, "", fields.Everything())
+	}
+	if isResourceServed(client, "networking.k8s.io/v1", "ingresses") {
+		kcc.ingressWatch = NewCachingWatcher(client.NetworkingV1().RESTClient(), "ingresses", &networkingv1.Ingress{}, "", fields.Everything())
+	}
+	if isResourceServed(client, "autoscaling/v2", "horizontalpodautoscalers") {
+		kcc.hpaWatch = NewCachingWatcher(client.AutoscalingV2().RESTClient(), "horizontalpodautoscalers", &autoscalingv2.HorizontalPodAutoscaler{}, "", fields.Everything())
+	}
+	if isResourceServed(client, "v1", "resourcequotas") {
+		kcc.resourceQuotaWatch = NewCachingWatcher(client.CoreV1().RESTClient(), "resourcequotas", &v1.ResourceQuota{}, "", fields.Everything())
+	}
+	if isResourceServed(client, "v1", "limitranges") {
+		kcc.limitRangeWatch = NewCachingWatcher(client.CoreV1().RESTClient(), "limitranges", &v1.LimitRange{}, "", fields.Everything())
+	}
+	if dynamicClient != nil && isResourceServed(client, RolloutGroupVersionResource.GroupVersion().String(), RolloutGroupVersionResource.Resource) {
+		log.Infof("Argo Rollouts detected, watching rollouts")
+		kcc.rolloutWatch = newRolloutWatcher(dynamicClient)
+	}
+	if dynamicClient != nil && isResourceServed(client, VolumeSnapshotGroupVersionResource.GroupVersion().String(), VolumeSnapshotGroupVersionResource.Resource) {
+		log.Infof("CSI volume snapshots detected, watching volumesnapshots and volumesnapshotcontents")
+		kcc.volumeSnapshotWatch = newDynamicWatcher(dynamicClient, VolumeSnapshotGroupVersionResource)
+		kcc.volumeSnapshotContentWatch = newDynamicWatcher(dynamicClient, VolumeSnapshotContentGroupVersionResource)
+	}
+
+	for _, wc := range kcc.optionalWatchers() {
+		go wc.WarmUp(cancel)
+	}
+}
+
+// optionalWatchers returns the watchers for optional resource kinds which were created.
+func (kcc *KubernetesClusterCache) optionalWatchers() []WatchController {
+	var watchers []WatchController
+	for _, wc := range []WatchController{
+		kcc.cronJobWatch,
+		kcc.ingressWatch,
+		kcc.hpaWatch,
+		kcc.resourceQuotaWatch,
+		kcc.limitRangeWatch,
+		kcc.rolloutWatch,
+		kcc.volumeSnapshotWatch,
+		kcc.volumeSnapshotContentWatch,
+	} {
+		if wc != nil {
+			watchers = append(watchers, wc)
+		}
+	}
+	return watchers
+}
+
 func (kcc *KubernetesClusterCache) Run() {
 	if kcc.stop != nil {
 		return
@@ -180,6 +283,10 @@ func (kcc *KubernetesClusterCache) Run() {
 	go kcc.pdbWatch.Run(1, stopCh)
 	go kcc.replicationControllerWatch.Run(1, stopCh)
 
+	for _, wc := range kcc.optionalWatchers() {
+		go wc.Run(1, stopCh)
+	}
+
 	kcc.stop = stopCh
 }
 
@@ -318,6 +425,117 @@ func (kcc *KubernetesClusterCache) GetAllReplicationControllers() []*v1.Replicat
 	return rcs
 }
 
+func (kcc *KubernetesClusterCache) GetAllCronJobs() []*batchv1.CronJob {
+	var cronJobs []*batchv1.CronJob
+	if kcc.cronJobWatch == nil {
+		return cronJobs
+	}
+	items := kcc.cronJobWatch.GetAll()
+	for _, cronJob := range items {
+		cronJobs = append(cronJobs, cronJob.(*batchv1.CronJob))
+	}
+	return cronJobs
+}
+
+func (kcc *KubernetesClusterCache) GetAllIngresses() []*networkingv1.Ingress {
+	var ingresses []*networkingv1.Ingress
+	if kcc.ingressWatch == nil {
+		return ingresses
+	}
+	items := kcc.ingressWatch.GetAll()
+	for _, ingress := range items {
+		ingresses = append(ingresses, ingress.(*networkingv1.Ingress))
+	}
+	return ingresses
+}
+
+func (kcc *KubernetesClusterCache) GetAllHorizontalPodAutoscalers() []*autoscalingv2.HorizontalPodAutoscaler {
+	var hpas []*autoscalingv2.HorizontalPodAutoscaler
+	if kcc.hpaWatch == nil {
+		return hpas
+	}
+	items := kcc.hpaWatch.GetAll()
+	for _, hpa := range items {
+		hpas = append(hpas, hpa.(*autoscalingv2.HorizontalPodAutoscaler))
+	}
+	return hpas
+}
+
+func (kcc *KubernetesClusterCache) GetAllResourceQuotas() []*v1.ResourceQuota {
+	var quotas []*v1.ResourceQuota
+	if kcc.resourceQuotaWatch == nil {
+		return quotas
+	}
+	items := kcc.resourceQuotaWatch.GetAll()
+	for _, quota := range items {
+		quotas = append(quotas, quota.(*v1.ResourceQuota))
+	}
+	return quotas
+}
+
+func (kcc *KubernetesClusterCache) GetAllLimitRanges() []*v1.LimitRange {
+	var limitRanges []*v1.LimitRange
+	if kcc.limitRangeWatch == nil {
+		return limitRanges
+	}
+	items := kcc.limitRangeWatch.GetAll()
+	for _, limitRange := range items {
+		limitRanges = append(limitRanges, limitRange.(*v1.LimitRange))
+	}
+	return limitRanges
+}
+
+func (kcc *KubernetesClusterCache) GetAllRollouts() []*Rollout {
+	var rollouts []*Rollout
+	if kcc.rolloutWatch == nil {
+		return rollouts
+	}
+	items := kcc.rolloutWatch.GetAll()
+	for _, item := range items {
+		rollout, err := rolloutFromUnstructured(item.(*unstructured.Unstructured))
+		if err != nil {
+			log.DedupedWarningf(5, "Failed to decode rollout: %s", err)
+			continue
+		}
+		rollouts = append(rollouts, rollout)
+	}
+	return rollouts
+}
+
+func (kcc *KubernetesClusterCache) GetAllVolumeSnapshots() []*VolumeSnapshot {
+	var snapshots []*VolumeSnapshot
+	if kcc.volumeSnapshotWatch == nil {
+		return snapshots
+	}
+	items := kcc.volumeSnapshotWatch.GetAll()
+	for _, item := range items {
+		snapshot, err := volumeSnapshotFromUnstructured(item.(*unstructured.Unstructured))
+		if err != nil {
+			log.DedupedWarningf(5, "Failed to decode volume snapshot: %s", err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
+
+func (kcc *KubernetesClusterCache) GetAllVolumeSnapshotContents() []*VolumeSnapshotContent {
+	var contents []*VolumeSnapshotContent
+	if kcc.volumeSnapshotContentWatch == nil {
+		return contents
+	}
+	items := kcc.volumeSnapshotContentWatch.GetAll()
+	for _, item := range items {
+		content, err := volumeSnapshotContentFromUnstructured(item.(*unstructured.Unstructured))
+		if err != nil {
+			log.DedupedWarningf(5, "Failed to decode volume snapshot content: %s", err)
+			continue
+		}
+		contents = append(contents, content)
+	}
+	return contents
+}
+
 func (kcc *KubernetesClusterCache) SetConfigMapUpdateFunc(f func(interface{})) {
 	kcc.kubecostConfigMapWatch.SetUpdateHandler(f)
 }
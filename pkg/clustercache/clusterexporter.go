@@ -9,28 +9,38 @@ import (
 	"github.com/opencost/opencost/pkg/util/json"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/api/policy/v1beta1"
 	stv1 "k8s.io/api/storage/v1"
 )
 
 // clusterEncoding is used to represent the cluster objects in the encoded states.
 type clusterEncoding struct {
-	Namespaces             []*v1.Namespace                `json:"namespaces,omitempty"`
-	Nodes                  []*v1.Node                     `json:"nodes,omitempty"`
-	Pods                   []*v1.Pod                      `json:"pods,omitempty"`
-	Services               []*v1.Service                  `json:"services,omitempty"`
-	DaemonSets             []*appsv1.DaemonSet            `json:"daemonSets,omitempty"`
-	Deployments            []*appsv1.Deployment           `json:"deployments,omitempty"`
-	StatefulSets           []*appsv1.StatefulSet          `json:"statefulSets,omitempty"`
-	ReplicaSets            []*appsv1.ReplicaSet           `json:"replicaSets,omitempty"`
-	PersistentVolumes      []*v1.PersistentVolume         `json:"persistentVolumes,omitempty"`
-	PersistentVolumeClaims []*v1.PersistentVolumeClaim    `json:"persistentVolumeClaims,omitempty"`
-	StorageClasses         []*stv1.StorageClass           `json:"storageClasses,omitempty"`
-	Jobs                   []*batchv1.Job                 `json:"jobs,omitempty"`
-	PodDisruptionBudgets   []*v1beta1.PodDisruptionBudget `json:"podDisruptionBudgets,omitempty"`
-	ReplicationControllers []*v1.ReplicationController    `json:"replicationController,omitempty"`
+	Namespaces               []*v1.Namespace                          `json:"namespaces,omitempty"`
+	Nodes                    []*v1.Node                               `json:"nodes,omitempty"`
+	Pods                     []*v1.Pod                                `json:"pods,omitempty"`
+	Services                 []*v1.Service                            `json:"services,omitempty"`
+	DaemonSets               []*appsv1.DaemonSet                      `json:"daemonSets,omitempty"`
+	Deployments              []*appsv1.Deployment                     `json:"deployments,omitempty"`
+	StatefulSets             []*appsv1.StatefulSet                    `json:"statefulSets,omitempty"`
+	ReplicaSets              []*appsv1.ReplicaSet                     `json:"replicaSets,omitempty"`
+	PersistentVolumes        []*v1.PersistentVolume                   `json:"persistentVolumes,omitempty"`
+	PersistentVolumeClaims   []*v1.PersistentVolumeClaim              `json:"persistentVolumeClaims,omitempty"`
+	StorageClasses           []*stv1.StorageClass                     `json:"storageClasses,omitempty"`
+	Jobs                     []*batchv1.Job                           `json:"jobs,omitempty"`
+	PodDisruptionBudgets     []*v1beta1.PodDisruptionBudget           `json:"podDisruptionBudgets,omitempty"`
+	ReplicationControllers   []*v1.ReplicationController              `json:"replicationController,omitempty"`
+	CronJobs                 []*batchv1.CronJob                       `json:"cronJobs,omitempty"`
+	Ingresses                []*networkingv1.Ingress                  `json:"ingresses,omitempty"`
+	HorizontalPodAutoscalers []*autoscalingv2.HorizontalPodAutoscaler `json:"horizontalPodAutoscalers,omitempty"`
+	ResourceQuotas           []*v1.ResourceQuota                      `json:"resourceQuotas,omitempty"`
+	LimitRanges              []*v1.LimitRange                         `json:"limitRanges,omitempty"`
+	Rollouts                 []*Rollout                               `json:"rollouts,omitempty"`
+	VolumeSnapshots          []*VolumeSnapshot                        `json:"volumeSnapshots,omitempty"`
+	VolumeSnapshotContents   []*VolumeSnapshotContent                 `json:"volumeSnapshotContents,omitempty"`
 }
 
 // ClusterExporter manages and runs an file export process which dumps the local kubernetes cluster to a target location.
@@ -86,28 +96,38 @@ func (ce *ClusterExporter) Stop() {
 // Export stores the cluster cache data into a PODO, marshals as JSON, and saves it to the
 // target location.
 func (ce *ClusterExporter) Export() error {
-	c := ce.cluster
-	encoding := &clusterEncoding{
-		Namespaces:             c.GetAllNamespaces(),
-		Nodes:                  c.GetAllNodes(),
-		Pods:                   c.GetAllPods(),
-		Services:               c.GetAllServices(),
-		DaemonSets:             c.GetAllDaemonSets(),
-		Deployments:            c.GetAllDeployments(),
-		StatefulSets:           c.GetAllStatefulSets(),
-		ReplicaSets:            c.GetAllReplicaSets(),
-		PersistentVolumes:      c.GetAllPersistentVolumes(),
-		PersistentVolumeClaims: c.GetAllPersistentVolumeClaims(),
-		StorageClasses:         c.GetAllStorageClasses(),
-		Jobs:                   c.GetAllJobs(),
-		PodDisruptionBudgets:   c.GetAllPodDisruptionBudgets(),
-		ReplicationControllers: c.GetAllReplicationControllers(),
-	}
-
-	data, err := json.Marshal(encoding)
+	data, err := json.Marshal(newClusterEncoding(ce.cluster))
 	if err != nil {
 		return err
 	}
 
 	return ce.target.Write(data)
 }
+
+// newClusterEncoding copies the current state of the cluster cache into a clusterEncoding.
+func newClusterEncoding(c ClusterCache) *clusterEncoding {
+	return &clusterEncoding{
+		Namespaces:               c.GetAllNamespaces(),
+		Nodes:                    c.GetAllNodes(),
+		Pods:                     c.GetAllPods(),
+		Services:                 c.GetAllServices(),
+		DaemonSets:               c.GetAllDaemonSets(),
+		Deployments:              c.GetAllDeployments(),
+		StatefulSets:             c.GetAllStatefulSets(),
+		ReplicaSets:              c.GetAllReplicaSets(),
+		PersistentVolumes:        c.GetAllPersistentVolumes(),
+		PersistentVolumeClaims:   c.GetAllPersistentVolumeClaims(),
+		StorageClasses:           c.GetAllStorageClasses(),
+		Jobs:                     c.GetAllJobs(),
+		PodDisruptionBudgets:     c.GetAllPodDisruptionBudgets(),
+		ReplicationControllers:   c.GetAllReplicationControllers(),
+		CronJobs:                 c.GetAllCronJobs(),
+		Ingresses:                c.GetAllIngresses(),
+		HorizontalPodAutoscalers: c.GetAllHorizontalPodAutoscalers(),
+		ResourceQuotas:           c.GetAllResourceQuotas(),
+		LimitRanges:              c.GetAllLimitRanges(),
+		Rollouts:                 c.GetAllRollouts(),
+		VolumeSnapshots:          c.GetAllVolumeSnapshots(),
+		VolumeSnapshotContents:   c.GetAllVolumeSnapshotContents(),
+	}
+}
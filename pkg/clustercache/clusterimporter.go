@@ -7,8 +7,10 @@ import (
 	"github.com/opencost/opencost/pkg/log"
 	"github.com/opencost/opencost/pkg/util/json"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/api/policy/v1beta1"
 	stv1 "k8s.io/api/storage/v1"
 )
@@ -299,6 +301,126 @@ func (ci *ClusterImporter) GetAllReplicationControllers() []*v1.ReplicationContr
 	return cloneList
 }
 
+// GetAllCronJobs returns all cached cron jobs
+func (ci *ClusterImporter) GetAllCronJobs() []*batchv1.CronJob {
+	ci.dataLock.Lock()
+	defer ci.dataLock.Unlock()
+
+	// Deep copy here to avoid callers from corrupting the cache
+	// This also mimics the behavior of the default cluster cache impl.
+	cronJobs := ci.data.CronJobs
+	cloneList := make([]*batchv1.CronJob, 0, len(cronJobs))
+	for _, v := range cronJobs {
+		cloneList = append(cloneList, v.DeepCopy())
+	}
+	return cloneList
+}
+
+// GetAllIngresses returns all cached ingresses
+func (ci *ClusterImporter) GetAllIngresses() []*networkingv1.Ingress {
+	ci.dataLock.Lock()
+	defer ci.dataLock.Unlock()
+
+	// Deep copy here to avoid callers from corrupting the cache
+	// This also mimics the behavior of the default cluster cache impl.
+	ingresses := ci.data.Ingresses
+	cloneList := make([]*networkingv1.Ingress, 0, len(ingresses))
+	for _, v := range ingresses {
+		cloneList = append(cloneList, v.DeepCopy())
+	}
+	return cloneList
+}
+
+// GetAllHorizontalPodAutoscalers returns all cached horizontal pod autoscalers
+func (ci *ClusterImporter) GetAllHorizontalPodAutoscalers() []*autoscalingv2.HorizontalPodAutoscaler {
+	ci.dataLock.Lock()
+	defer ci.dataLock.Unlock()
+
+	// Deep copy here to avoid callers from corrupting the cache
+	// This also mimics the behavior of the default cluster cache impl.
+	hpas := ci.data.HorizontalPodAutoscalers
+	cloneList := make([]*autoscalingv2.HorizontalPodAutoscaler, 0, len(hpas))
+	for _, v := range hpas {
+		cloneList = append(cloneList, v.DeepCopy())
+	}
+	return cloneList
+}
+
+// GetAllResourceQuotas returns all cached resource quotas
+func (ci *ClusterImporter) GetAllResourceQuotas() []*v1.ResourceQuota {
+	ci.dataLock.Lock()
+	defer ci.dataLock.Unlock()
+
+	// Deep copy here to avoid callers from corrupting the cache
+	// This also mimics the behavior of the default cluster cache impl.
+	quotas := ci.data.ResourceQuotas
+	cloneList := make([]*v1.ResourceQuota, 0, len(quotas))
+	for _, v := range quotas {
+		cloneList = append(cloneList, v.DeepCopy())
+	}
+	return cloneList
+}
+
+// GetAllLimitRanges returns all cached limit ranges
+func (ci *ClusterImporter) GetAllLimitRanges() []*v1.LimitRange {
+	ci.dataLock.Lock()
+	defer ci.dataLock.Unlock()
+
+	// Deep copy here to avoid callers from corrupting the cache
+	// This also mimics the behavior of the default cluster cache impl.
+	limitRanges := ci.data.LimitRanges
+	cloneList := make([]*v1.LimitRange, 0, len(limitRanges))
+	for _, v := range limitRanges {
+		cloneList = append(cloneList, v.DeepCopy())
+	}
+	return cloneList
+}
+
+// GetAllRollouts returns all cached Argo Rollouts
+func (ci *ClusterImporter) GetAllRollouts() []*Rollout {
+	ci.dataLock.Lock()
+	defer ci.dataLock.Unlock()
+
+	// Deep copy here to avoid callers from corrupting the cache
+	// This also mimics the behavior of the default cluster cache impl.
+	rollouts := ci.data.Rollouts
+	cloneList := make([]*Rollout, 0, len(rollouts))
+	for _, v := range rollouts {
+		cloneList = append(cloneList, v.DeepCopy())
+	}
+	return cloneList
+}
+
+// GetAllVolumeSnapshots returns all cached CSI VolumeSnapshots
+func (ci *ClusterImporter) GetAllVolumeSnapshots() []*VolumeSnapshot {
+	ci.dataLock.Lock()
+	defer ci.dataLock.Unlock()
+
+	// Deep copy here to avoid callers from corrupting the cache
+	// This also mimics the behavior of the default cluster cache impl.
+	snapshots := ci.data.VolumeSnapshots
+	cloneList := make([]*VolumeSnapshot, 0, len(snapshots))
+	for _, v := range snapshots {
+		cloneList = append(cloneList, v.DeepCopy())
+	}
+	return cloneList
+}
+
+// GetAllVolumeSnapshotContents returns all cached CSI VolumeSnapshotContents
+func (ci *ClusterImporter) GetAllVolumeSnapshotContents() []*VolumeSnapshotContent {
+	ci.dataLock.Lock()
+	defer ci.dataLock.Unlock()
+
+	// Deep copy here to avoid callers from corrupting the cache
+	// This also mimics the behavior of the default cluster cache impl.
+	contents := ci.data.VolumeSnapshotContents
+	cloneList := make([]*VolumeSnapshotContent, 0, len(contents))
+	for _, v := range contents {
+		cloneList = append(cloneList, v.DeepCopy())
+	}
+	return cloneList
+}
+
 // SetConfigMapUpdateFunc sets the configmap update function
 func (ci *ClusterImporter) SetConfigMapUpdateFunc(_ func(interface{})) {
 	// TODO: (bolt) This function is still a bit strange to me for the ClusterCache interface.
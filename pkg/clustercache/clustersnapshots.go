@@ -0,0 +1,315 @@
+package clustercache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/storage"
+	"github.com/opencost/opencost/pkg/util/atomic"
+	"github.com/opencost/opencost/pkg/util/json"
+)
+
+// snapshotExt is the file extension used for gzip compressed snapshot files.
+const snapshotExt = ".json.gz"
+
+// maxDecodedSnapshots is the number of decoded snapshots kept in memory to avoid re-reading
+// storage for repeated queries against the same historical window.
+const maxDecodedSnapshots = 4
+
+// ErrNoClusterSnapshot is returned when there is no snapshot at or before a requested time.
+var ErrNoClusterSnapshot = fmt.Errorf("no cluster snapshot available")
+
+// ClusterSnapshotStore periodically writes compressed snapshots of a ClusterCache to storage and
+// provides read-only views of the cluster as it existed at a point in time. This allows metadata
+// (labels, owners, controllers) to be resolved for objects which have since been deleted.
+type ClusterSnapshotStore struct {
+	cluster   ClusterCache
+	store     storage.Storage
+	dir       string
+	interval  time.Duration
+	retention time.Duration
+	runState  atomic.AtomicRunState
+
+	lock      *sync.Mutex
+	index     []time.Time
+	indexed   bool
+	decoded   map[int64]*clusterEncoding
+	decodeSeq []int64
+}
+
+// NewClusterSnapshotStore creates a new ClusterSnapshotStore which snapshots cluster into dir on the
+// provided storage every interval, removing snapshots older than retention.
+func NewClusterSnapshotStore(cluster ClusterCache, store storage.Storage, dir string, interval, retention time.Duration) *ClusterSnapshotStore {
+	return &ClusterSnapshotStore{
+		cluster:   cluster,
+		store:     store,
+		dir:       dir,
+		interval:  interval,
+		retention: retention,
+		lock:      new(sync.Mutex),
+		decoded:   make(map[int64]*clusterEncoding),
+	}
+}
+
+// Run starts the automated process of taking a snapshot on a specific interval.
+func (css *ClusterSnapshotStore) Run() {
+	// in the event there is a race that occurs between Run() and Stop(), we
+	// ensure that we wait for the reset to occur before starting again
+	css.runState.WaitForReset()
+
+	if !css.runState.Start() {
+		log.Warnf("ClusterSnapshotStore already running")
+		return
+	}
+
+	go func() {
+		for {
+			if err := css.Snapshot(time.Now()); err != nil {
+				log.Warnf("Failed to snapshot cluster: %s", err)
+			}
+
+			if err := css.Prune(time.Now()); err != nil {
+				log.Warnf("Failed to prune cluster snapshots: %s", err)
+			}
+
+			select {
+			case <-time.After(css.interval):
+			case <-css.runState.OnStop():
+				css.runState.Reset()
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts the cluster snapshots on an interval
+func (css *ClusterSnapshotStore) Stop() {
+	css.runState.Stop()
+}
+
+// Snapshot writes a compressed snapshot of the current cluster state, indexed by the provided time.
+func (css *ClusterSnapshotStore) Snapshot(t time.Time) error {
+	if css.cluster == nil {
+		return fmt.Errorf("cluster cache is nil")
+	}
+
+	data, err := json.Marshal(newClusterEncoding(css.cluster))
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return fmt.Errorf("compressing snapshot: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("compressing snapshot: %w", err)
+	}
+
+	t = t.UTC().Truncate(time.Second)
+	if err := css.store.Write(css.snapshotPath(t), buf.Bytes()); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	css.lock.Lock()
+	defer css.lock.Unlock()
+
+	if err := css.loadIndex(); err != nil {
+		return err
+	}
+	css.insertIndex(t)
+
+	return nil
+}
+
+// Prune removes all snapshots older than the retention relative to now.
+func (css *ClusterSnapshotStore) Prune(now time.Time) error {
+	if css.retention <= 0 {
+		return nil
+	}
+
+	css.lock.Lock()
+	defer css.lock.Unlock()
+
+	if err := css.loadIndex(); err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-css.retention)
+
+	var kept []time.Time
+	for _, t := range css.index {
+		if !t.Before(cutoff) {
+			kept = append(kept, t)
+			continue
+		}
+
+		if err := css.store.Remove(css.snapshotPath(t)); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Failed to remove cluster snapshot %s: %s", css.snapshotPath(t), err)
+			kept = append(kept, t)
+			continue
+		}
+		delete(css.decoded, t.Unix())
+	}
+	css.index = kept
+
+	return nil
+}
+
+// Snapshots returns the times of all available snapshots in ascending order.
+func (css *ClusterSnapshotStore) Snapshots() ([]time.Time, error) {
+	css.lock.Lock()
+	defer css.lock.Unlock()
+
+	if err := css.loadIndex(); err != nil {
+		return nil, err
+	}
+
+	result := make([]time.Time, len(css.index))
+	copy(result, css.index)
+	return result, nil
+}
+
+// ClusterCacheAt returns a read-only ClusterCache containing the cluster state recorded by the most recent
+// snapshot taken at or before t. ErrNoClusterSnapshot is returned if no such snapshot exists.
+func (css *ClusterSnapshotStore) ClusterCacheAt(t time.Time) (ClusterCache, error) {
+	css.lock.Lock()
+	defer css.lock.Unlock()
+
+	if err := css.loadIndex(); err != nil {
+		return nil, err
+	}
+
+	// find the first snapshot after t, the one prior is the latest at or before t
+	i := sort.Search(len(css.index), func(i int) bool {
+		return css.index[i].After(t)
+	})
+	if i == 0 {
+		return nil, ErrNoClusterSnapshot
+	}
+
+	ce, err := css.decode(css.index[i-1])
+	if err != nil {
+		return nil, err
+	}
+
+	return newClusterSnapshot(ce), nil
+}
+
+// decode returns the decoded snapshot for t, reading from storage if it isn't already cached.
+// Assumes the lock is held.
+func (css *ClusterSnapshotStore) decode(t time.Time) (*clusterEncoding, error) {
+	key := t.Unix()
+	if ce, ok := css.decoded[key]; ok {
+		return ce, nil
+	}
+
+	data, err := css.store.Read(css.snapshotPath(t))
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing snapshot: %w", err)
+	}
+	defer gz.Close()
+
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing snapshot: %w", err)
+	}
+
+	ce := new(clusterEncoding)
+	if err := json.Unmarshal(raw, ce); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	css.decoded[key] = ce
+	css.decodeSeq = append(css.decodeSeq, key)
+	for len(css.decodeSeq) > maxDecodedSnapshots {
+		delete(css.decoded, css.decodeSeq[0])
+		css.decodeSeq = css.decodeSeq[1:]
+	}
+
+	return ce, nil
+}
+
+// loadIndex lists the snapshot directory the first time it's called so that snapshots written by a
+// previous process are available. Assumes the lock is held.
+func (css *ClusterSnapshotStore) loadIndex() error {
+	if css.indexed {
+		return nil
+	}
+
+	files, err := css.store.List(css.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("listing snapshots: %w", err)
+	}
+
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name, snapshotExt) {
+			continue
+		}
+
+		secs, err := strconv.ParseInt(strings.TrimSuffix(file.Name, snapshotExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		css.insertIndex(time.Unix(secs, 0).UTC())
+	}
+
+	css.indexed = true
+	return nil
+}
+
+// insertIndex adds t to the sorted index if it isn't already present. Assumes the lock is held.
+func (css *ClusterSnapshotStore) insertIndex(t time.Time) {
+	i := sort.Search(len(css.index), func(i int) bool {
+		return !css.index[i].Before(t)
+	})
+	if i < len(css.index) && css.index[i].Equal(t) {
+		// overwritten snapshot, drop any stale decoded copy
+		delete(css.decoded, t.Unix())
+		return
+	}
+
+	css.index = append(css.index, time.Time{})
+	copy(css.index[i+1:], css.index[i:])
+	css.index[i] = t
+}
+
+func (css *ClusterSnapshotStore) snapshotPath(t time.Time) string {
+	return path.Join(css.dir, strconv.FormatInt(t.Unix(), 10)+snapshotExt)
+}
+
+// clusterSnapshot is a read-only ClusterCache backed by a decoded snapshot.
+type clusterSnapshot struct {
+	*ClusterImporter
+}
+
+func newClusterSnapshot(ce *clusterEncoding) ClusterCache {
+	return &clusterSnapshot{
+		ClusterImporter: &ClusterImporter{
+			dataLock: new(sync.Mutex),
+			data:     ce,
+		},
+	}
+}
+
+// Run is a no-op for snapshots, which are static
+func (cs *clusterSnapshot) Run() {}
+
+// Stop is a no-op for snapshots, which are static
+func (cs *clusterSnapshot) Stop() {}
@@ -0,0 +1,90 @@
+package clustercache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/storage"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func podsNamed(names ...string) *clusterEncoding {
+	ce := new(clusterEncoding)
+	for _, name := range names {
+		ce.Pods = append(ce.Pods, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		})
+	}
+	return ce
+}
+
+func podNames(cache ClusterCache) map[string]bool {
+	names := map[string]bool{}
+	for _, pod := range cache.GetAllPods() {
+		names[pod.Name] = true
+	}
+	return names
+}
+
+func TestClusterSnapshotStore(t *testing.T) {
+	store := storage.NewFileStorage(t.TempDir())
+	live := newClusterSnapshot(podsNamed("pod-a", "pod-b")).(*clusterSnapshot)
+
+	snapshots := NewClusterSnapshotStore(live, store, "snapshots", time.Minute, 24*time.Hour)
+
+	t0 := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err := snapshots.Snapshot(t0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// pod-a is deleted, pod-c is created
+	live.data = podsNamed("pod-b", "pod-c")
+	t1 := t0.Add(10 * time.Minute)
+	if err := snapshots.Snapshot(t1); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := snapshots.ClusterCacheAt(t0.Add(-time.Second)); !errors.Is(err, ErrNoClusterSnapshot) {
+		t.Fatalf("expected ErrNoClusterSnapshot before the first snapshot, got: %v", err)
+	}
+
+	cache, err := snapshots.ClusterCacheAt(t0.Add(5 * time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if names := podNames(cache); !names["pod-a"] || names["pod-c"] {
+		t.Errorf("expected first snapshot to contain the deleted pod, got %v", names)
+	}
+
+	cache, err = snapshots.ClusterCacheAt(t1.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if names := podNames(cache); names["pod-a"] || !names["pod-c"] {
+		t.Errorf("expected latest snapshot, got %v", names)
+	}
+
+	// A new store over the same storage should discover existing snapshots
+	reloaded := NewClusterSnapshotStore(live, store, "snapshots", time.Minute, 24*time.Hour)
+	times, err := reloaded.Snapshots()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(times) != 2 || !times[0].Equal(t0) || !times[1].Equal(t1) {
+		t.Fatalf("expected snapshots at %s and %s, got %v", t0, t1, times)
+	}
+
+	// Pruning at t0 + 24h + 5m removes only the first snapshot
+	if err := reloaded.Prune(t0.Add(24*time.Hour + 5*time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	times, _ = reloaded.Snapshots()
+	if len(times) != 1 || !times[0].Equal(t1) {
+		t.Fatalf("expected only the second snapshot to be retained, got %v", times)
+	}
+	if exists, _ := store.Exists("snapshots/" + "1677628800" + snapshotExt); exists {
+		t.Errorf("expected pruned snapshot to be removed from storage")
+	}
+}
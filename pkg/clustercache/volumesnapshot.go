@@ -0,0 +1,186 @@
+package clustercache
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	rt "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// VolumeSnapshotGroupVersionResource identifies the CSI VolumeSnapshot custom resource
+var VolumeSnapshotGroupVersionResource = schema.GroupVersionResource{
+	Group:    "snapshot.storage.k8s.io",
+	Version:  "v1",
+	Resource: "volumesnapshots",
+}
+
+// VolumeSnapshotContentGroupVersionResource identifies the CSI VolumeSnapshotContent custom resource
+var VolumeSnapshotContentGroupVersionResource = schema.GroupVersionResource{
+	Group:    "snapshot.storage.k8s.io",
+	Version:  "v1",
+	Resource: "volumesnapshotcontents",
+}
+
+// VolumeSnapshot is a minimal representation of a CSI VolumeSnapshot, the namespaced request for a
+// snapshot of a PersistentVolumeClaim, which is also created by Velero when backing up volumes with
+// its CSI plugin. Only the fields required to price the snapshot and attribute it to its source are
+// retained.
+type VolumeSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              VolumeSnapshotSpec    `json:"spec"`
+	Status            *VolumeSnapshotStatus `json:"status,omitempty"`
+}
+
+// VolumeSnapshotSpec contains the subset of the VolumeSnapshot spec used by the cluster cache
+type VolumeSnapshotSpec struct {
+	Source                  VolumeSnapshotSource `json:"source"`
+	VolumeSnapshotClassName *string              `json:"volumeSnapshotClassName,omitempty"`
+}
+
+// VolumeSnapshotSource identifies the PersistentVolumeClaim a VolumeSnapshot was taken of, or the
+// pre-provisioned VolumeSnapshotContent it represents.
+type VolumeSnapshotSource struct {
+	PersistentVolumeClaimName *string `json:"persistentVolumeClaimName,omitempty"`
+	VolumeSnapshotContentName *string `json:"volumeSnapshotContentName,omitempty"`
+}
+
+// VolumeSnapshotStatus contains the subset of the VolumeSnapshot status used by the cluster cache
+type VolumeSnapshotStatus struct {
+	BoundVolumeSnapshotContentName *string            `json:"boundVolumeSnapshotContentName,omitempty"`
+	ReadyToUse                     *bool              `json:"readyToUse,omitempty"`
+	RestoreSize                    *resource.Quantity `json:"restoreSize,omitempty"`
+}
+
+// DeepCopy returns a deep copy of the VolumeSnapshot
+func (vs *VolumeSnapshot) DeepCopy() *VolumeSnapshot {
+	if vs == nil {
+		return nil
+	}
+
+	out := &VolumeSnapshot{
+		TypeMeta:   vs.TypeMeta,
+		ObjectMeta: *vs.ObjectMeta.DeepCopy(),
+		Spec: VolumeSnapshotSpec{
+			Source: VolumeSnapshotSource{
+				PersistentVolumeClaimName: copyStringPtr(vs.Spec.Source.PersistentVolumeClaimName),
+				VolumeSnapshotContentName: copyStringPtr(vs.Spec.Source.VolumeSnapshotContentName),
+			},
+			VolumeSnapshotClassName: copyStringPtr(vs.Spec.VolumeSnapshotClassName),
+		},
+	}
+	if vs.Status != nil {
+		out.Status = &VolumeSnapshotStatus{
+			BoundVolumeSnapshotContentName: copyStringPtr(vs.Status.BoundVolumeSnapshotContentName),
+			ReadyToUse:                     copyBoolPtr(vs.Status.ReadyToUse),
+		}
+		if vs.Status.RestoreSize != nil {
+			size := vs.Status.RestoreSize.DeepCopy()
+			out.Status.RestoreSize = &size
+		}
+	}
+	return out
+}
+
+// VolumeSnapshotContent is a minimal representation of a CSI VolumeSnapshotContent, the cluster-scoped
+// object representing a snapshot in the storage backend.
+type VolumeSnapshotContent struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              VolumeSnapshotContentSpec    `json:"spec"`
+	Status            *VolumeSnapshotContentStatus `json:"status,omitempty"`
+}
+
+// VolumeSnapshotContentSpec contains the subset of the VolumeSnapshotContent spec used by the cluster cache
+type VolumeSnapshotContentSpec struct {
+	VolumeSnapshotRef v1.ObjectReference          `json:"volumeSnapshotRef"`
+	DeletionPolicy    string                      `json:"deletionPolicy"`
+	Driver            string                      `json:"driver"`
+	Source            VolumeSnapshotContentSource `json:"source"`
+}
+
+// VolumeSnapshotContentSource identifies the volume a snapshot was taken of, or the pre-existing snapshot
+// in the storage backend.
+type VolumeSnapshotContentSource struct {
+	VolumeHandle   *string `json:"volumeHandle,omitempty"`
+	SnapshotHandle *string `json:"snapshotHandle,omitempty"`
+}
+
+// VolumeSnapshotContentStatus contains the subset of the VolumeSnapshotContent status used by the cluster cache.
+// RestoreSize is the size of the volume the snapshot restores to, in bytes.
+type VolumeSnapshotContentStatus struct {
+	SnapshotHandle *string `json:"snapshotHandle,omitempty"`
+	ReadyToUse     *bool   `json:"readyToUse,omitempty"`
+	RestoreSize    *int64  `json:"restoreSize,omitempty"`
+}
+
+// DeepCopy returns a deep copy of the VolumeSnapshotContent
+func (vsc *VolumeSnapshotContent) DeepCopy() *VolumeSnapshotContent {
+	if vsc == nil {
+		return nil
+	}
+
+	out := &VolumeSnapshotContent{
+		TypeMeta:   vsc.TypeMeta,
+		ObjectMeta: *vsc.ObjectMeta.DeepCopy(),
+		Spec: VolumeSnapshotContentSpec{
+			VolumeSnapshotRef: vsc.Spec.VolumeSnapshotRef,
+			DeletionPolicy:    vsc.Spec.DeletionPolicy,
+			Driver:            vsc.Spec.Driver,
+			Source: VolumeSnapshotContentSource{
+				VolumeHandle:   copyStringPtr(vsc.Spec.Source.VolumeHandle),
+				SnapshotHandle: copyStringPtr(vsc.Spec.Source.SnapshotHandle),
+			},
+		},
+	}
+	if vsc.Status != nil {
+		out.Status = &VolumeSnapshotContentStatus{
+			SnapshotHandle: copyStringPtr(vsc.Status.SnapshotHandle),
+			ReadyToUse:     copyBoolPtr(vsc.Status.ReadyToUse),
+		}
+		if vsc.Status.RestoreSize != nil {
+			size := *vsc.Status.RestoreSize
+			out.Status.RestoreSize = &size
+		}
+	}
+	return out
+}
+
+// volumeSnapshotFromUnstructured converts an unstructured VolumeSnapshot object into a VolumeSnapshot
+func volumeSnapshotFromUnstructured(obj *unstructured.Unstructured) (*VolumeSnapshot, error) {
+	snapshot := new(VolumeSnapshot)
+	err := rt.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// volumeSnapshotContentFromUnstructured converts an unstructured VolumeSnapshotContent object into a
+// VolumeSnapshotContent
+func volumeSnapshotContentFromUnstructured(obj *unstructured.Unstructured) (*VolumeSnapshotContent, error) {
+	content := new(VolumeSnapshotContent)
+	err := rt.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), content)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func copyStringPtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	out := *s
+	return &out
+}
+
+func copyBoolPtr(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+	out := *b
+	return &out
+}
@@ -0,0 +1,100 @@
+package clustercache
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestVolumeSnapshotFromUnstructured(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "snapshot.storage.k8s.io/v1",
+		"kind":       "VolumeSnapshot",
+		"metadata": map[string]interface{}{
+			"name":      "velero-data-abc12",
+			"namespace": "namespace1",
+		},
+		"spec": map[string]interface{}{
+			"source": map[string]interface{}{
+				"persistentVolumeClaimName": "data",
+			},
+			"volumeSnapshotClassName": "csi-aws-vsc",
+		},
+		"status": map[string]interface{}{
+			"boundVolumeSnapshotContentName": "snapcontent-1",
+			"readyToUse":                     true,
+			"restoreSize":                    "10Gi",
+		},
+	}}
+
+	snapshot, err := volumeSnapshotFromUnstructured(obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if snapshot.Namespace != "namespace1" || snapshot.Name != "velero-data-abc12" {
+		t.Fatalf("expected namespace1/velero-data-abc12; got %s/%s", snapshot.Namespace, snapshot.Name)
+	}
+	if snapshot.Spec.Source.PersistentVolumeClaimName == nil || *snapshot.Spec.Source.PersistentVolumeClaimName != "data" {
+		t.Fatalf("expected source claim data; got %v", snapshot.Spec.Source.PersistentVolumeClaimName)
+	}
+	if snapshot.Status == nil || snapshot.Status.RestoreSize == nil || snapshot.Status.RestoreSize.Value() != 10*1024*1024*1024 {
+		t.Fatalf("expected restore size of 10Gi; got %v", snapshot.Status)
+	}
+
+	// Copies share no state with the original
+	clone := snapshot.DeepCopy()
+	*clone.Spec.Source.PersistentVolumeClaimName = "other"
+	*clone.Status.BoundVolumeSnapshotContentName = "other"
+	if *snapshot.Spec.Source.PersistentVolumeClaimName != "data" || *snapshot.Status.BoundVolumeSnapshotContentName != "snapcontent-1" {
+		t.Fatalf("expected deep copy to be independent of original")
+	}
+}
+
+func TestVolumeSnapshotContentFromUnstructured(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "snapshot.storage.k8s.io/v1",
+		"kind":       "VolumeSnapshotContent",
+		"metadata": map[string]interface{}{
+			"name": "snapcontent-1",
+		},
+		"spec": map[string]interface{}{
+			"deletionPolicy": "Retain",
+			"driver":         "ebs.csi.aws.com",
+			"source": map[string]interface{}{
+				"volumeHandle": "vol-0123456789abcdef0",
+			},
+			"volumeSnapshotRef": map[string]interface{}{
+				"name":      "velero-data-abc12",
+				"namespace": "namespace1",
+			},
+		},
+		"status": map[string]interface{}{
+			"readyToUse":     true,
+			"restoreSize":    int64(10737418240),
+			"snapshotHandle": "snap-0123456789abcdef0",
+		},
+	}}
+
+	content, err := volumeSnapshotContentFromUnstructured(obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if content.Spec.DeletionPolicy != "Retain" || content.Spec.VolumeSnapshotRef.Namespace != "namespace1" {
+		t.Fatalf("unexpected spec: %+v", content.Spec)
+	}
+	if content.Status == nil || content.Status.SnapshotHandle == nil || *content.Status.SnapshotHandle != "snap-0123456789abcdef0" {
+		t.Fatalf("expected snapshot handle snap-0123456789abcdef0; got %v", content.Status)
+	}
+	if content.Status.RestoreSize == nil || *content.Status.RestoreSize != 10737418240 {
+		t.Fatalf("expected restore size of 10737418240; got %v", content.Status.RestoreSize)
+	}
+
+	// Copies share no state with the original
+	clone := content.DeepCopy()
+	*clone.Status.RestoreSize = 0
+	if *content.Status.RestoreSize != 10737418240 {
+		t.Fatalf("expected deep copy to be independent of original")
+	}
+}
@@ -54,6 +54,12 @@ type CachingWatchController struct {
 
 func NewCachingWatcher(restClient rest.Interface, resource string, resourceType rt.Object, namespace string, fieldSelector fields.Selector) WatchController {
 	resourceCache := cache.NewListWatchFromClient(restClient, resource, namespace, fieldSelector)
+	return NewCachingWatcherFromListWatch(resourceCache, resource, resourceType)
+}
+
+// NewCachingWatcherFromListWatch creates a caching WatchController using the provided ListerWatcher. This is
+// used for resources which aren't served by a typed rest client, such as custom resources.
+func NewCachingWatcherFromListWatch(resourceCache cache.ListerWatcher, resource string, resourceType rt.Object) WatchController {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	indexer, informer := cache.NewIndexerInformer(resourceCache, resourceType, 0, cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
@@ -57,8 +57,13 @@ func newKubernetesClusterCache() (kubernetes.Interface, clustercache.ClusterCach
 		return nil, nil, err
 	}
 
+	dynamicClient, err := kubeconfig.LoadDynamicClient("")
+	if err != nil {
+		log.Warnf("Failed to build Kubernetes dynamic client, custom resources will not be watched: %s", err)
+	}
+
 	// Create Kubernetes Cluster Cache + Watchers
-	k8sCache := clustercache.NewKubernetesClusterCache(kubeClientset)
+	k8sCache := clustercache.NewKubernetesClusterCacheWithDynamic(kubeClientset, dynamicClient)
 	k8sCache.Run()
 
 	return kubeClientset, k8sCache, nil
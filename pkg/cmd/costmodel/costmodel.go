@@ -16,6 +16,7 @@ import (
 	"github.com/opencost/opencost/pkg/filemanager"
 	"github.com/opencost/opencost/pkg/log"
 	"github.com/opencost/opencost/pkg/metrics"
+	"github.com/opencost/opencost/pkg/tracing"
 	"github.com/opencost/opencost/pkg/version"
 )
 
@@ -32,6 +33,13 @@ func Healthz(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 
 func Execute(opts *CostModelOpts) error {
 	log.Infof("Starting cost-model version %s", version.FriendlyVersion())
+
+	shutdownTracing, err := tracing.Initialize()
+	if err != nil {
+		log.Errorf("Failed to initialize tracing: %s", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	a := costmodel.Initialize()
 
 	StartExportWorker(context.Background(), a.Model)
@@ -40,7 +48,7 @@ func Execute(opts *CostModelOpts) error {
 	a.Router.GET("/healthz", Healthz)
 	a.Router.GET("/allocation", a.ComputeAllocationHandler)
 	a.Router.GET("/allocation/summary", a.ComputeAllocationHandlerSummary)
-	rootMux.Handle("/", a.Router)
+	rootMux.Handle("/", tracing.Middleware(a.Router))
 	rootMux.Handle("/metrics", promhttp.Handler())
 	telemetryHandler := metrics.ResponseMetricMiddleware(rootMux)
 	handler := cors.AllowAll().Handler(telemetryHandler)
@@ -58,7 +66,7 @@ func StartExportWorker(ctx context.Context, model costmodel.AllocationModel) {
 
 	fm, err := filemanager.NewFileManager(exportPath)
 	if err != nil {
-		log.Errorf("could not start CSV exporter", err)
+		log.Errorf("could not start CSV exporter: %s", err)
 		return
 	}
 	go func() {
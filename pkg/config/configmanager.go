@@ -96,3 +96,8 @@ func (cfm *ConfigFileManager) ConfigFileAt(path string) *ConfigFile {
 	cfm.files[path] = cf
 	return cf
 }
+
+// Storage returns the backing storage used for configuration files.
+func (cfm *ConfigFileManager) Storage() storage.Storage {
+	return cfm.store
+}
@@ -17,11 +17,13 @@ import (
 	"github.com/opencost/opencost/pkg/cloud"
 	"github.com/opencost/opencost/pkg/env"
 	"github.com/opencost/opencost/pkg/errors"
+	"github.com/opencost/opencost/pkg/filter"
 	"github.com/opencost/opencost/pkg/kubecost"
 	"github.com/opencost/opencost/pkg/log"
 	"github.com/opencost/opencost/pkg/prom"
 	"github.com/opencost/opencost/pkg/thanos"
 	"github.com/opencost/opencost/pkg/util"
+	filterv2 "github.com/opencost/opencost/pkg/util/allocationfilterutil/v2"
 	"github.com/opencost/opencost/pkg/util/json"
 	"github.com/patrickmn/go-cache"
 	prometheusClient "github.com/prometheus/client_golang/api"
@@ -2170,7 +2172,7 @@ func (a *Accesses) ComputeAllocationHandlerSummary(w http.ResponseWriter, r *htt
 		stepEnd := stepStart.Add(step)
 		stepWindow := kubecost.NewWindow(&stepStart, &stepEnd)
 
-		as, err := a.Model.ComputeAllocation(*stepWindow.Start(), *stepWindow.End(), resolution)
+		as, err := a.Model.ComputeAllocationWithContext(r.Context(), *stepWindow.Start(), *stepWindow.End(), resolution)
 		if err != nil {
 			WriteError(w, InternalServerError(err.Error()))
 			return
@@ -2182,7 +2184,7 @@ func (a *Accesses) ComputeAllocationHandlerSummary(w http.ResponseWriter, r *htt
 
 	// Aggregate, if requested
 	if len(aggregateBy) > 0 {
-		err = asr.AggregateBy(aggregateBy, nil)
+		err = asr.AggregateBy(aggregateBy, &kubecost.AllocationAggregationOptions{TraceContext: r.Context()})
 		if err != nil {
 			WriteError(w, InternalServerError(err.Error()))
 			return
@@ -2239,6 +2241,12 @@ func (a *Accesses) ComputeAllocationHandler(w http.ResponseWriter, r *http.Reque
 		http.Error(w, fmt.Sprintf("Invalid 'aggregate' parameter: %s", err), http.StatusBadRequest)
 	}
 
+	// Unaggregated, if true, returns allocations unaggregated, ignoring
+	// aggregate; e.g. for federation, which aggregates across clusters.
+	if qp.GetBool("unaggregated", false) {
+		aggregateBy = nil
+	}
+
 	// IncludeIdle, if true, uses Asset data to incorporate Idle Allocation
 	includeIdle := qp.GetBool("includeIdle", false)
 
@@ -2247,20 +2255,364 @@ func (a *Accesses) ComputeAllocationHandler(w http.ResponseWriter, r *http.Reque
 	// is not included.)
 	idleByNode := qp.GetBool("idleByNode", false)
 
+	// IdleByNodePool, if true, computes idle allocations at the node pool
+	// level, e.g. per EKS node group or GKE node pool. (Ignored if idleByNode
+	// is set.)
+	idleByNodePool := qp.GetBool("idleByNodePool", false)
+
 	// IncludeProportionalAssetResourceCosts, if true,
 	includeProportionalAssetResourceCosts := qp.GetBool("includeProportionalAssetResourceCosts", false)
 
-	asr, err := a.Model.QueryAllocation(window, resolution, step, aggregateBy, includeIdle, idleByNode, includeProportionalAssetResourceCosts)
+	// Filter is an optional parameter in the V2 filter language. Where
+	// possible, it is pushed down into the Prometheus queries, e.g.
+	// filter=namespace:"kubecost"+cluster:"cluster-one"
+	var allocFilter kubecost.AllocationFilter
+	if raw := qp.Get("filter", ""); raw != "" {
+		allocFilter, err = filterv2.ParseAllocationFilter(raw)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid 'filter' parameter: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// SharingPolicies is an optional comma-separated list of the names of
+	// configured sharing policies to apply, in order, e.g.
+	// sharingPolicies=ingress,monitoring
+	sharingPolicies, err := a.Model.SharingPolicies(qp.GetList("sharingPolicies", ","))
 	if err != nil {
-		if strings.Contains(strings.ToLower(err.Error()), "bad request") {
-			WriteError(w, BadRequest(err.Error()))
-		} else {
-			WriteError(w, InternalServerError(err.Error()))
+		writeQueryAllocationError(w, err)
+		return
+	}
+
+	// IdleOwners is an optional comma-separated list of the names of
+	// configured idle owners, in order of precedence, to charge for the idle
+	// of the nodes they own during the window, e.g.
+	// idleOwners=ml-team,data-team. Idle which is not owned is returned as
+	// idle. Requires includeIdle.
+	idleOwners, err := a.Model.IdleOwners(window, qp.GetList("idleOwners", ","))
+	if err != nil {
+		writeQueryAllocationError(w, err)
+		return
+	}
+
+	// Hierarchy, if true, nests each aggregate property within the previous
+	// one, returning a tree per step with subtotals at every level, rather
+	// than flattening them into composite keys like "cluster/namespace".
+	if qp.GetBool("hierarchy", false) {
+		trees, err := a.Model.QueryAllocationHierarchy(r.Context(), window, resolution, step, aggregateBy, allocFilter, sharingPolicies, idleOwners, includeIdle, idleByNode, idleByNodePool, includeProportionalAssetResourceCosts)
+		if err != nil {
+			writeQueryAllocationError(w, err)
+			return
+		}
+
+		w.Write(WrapData(trees, nil))
+		return
+	}
+
+	asr, err := a.Model.QueryAllocation(r.Context(), window, resolution, step, aggregateBy, allocFilter, sharingPolicies, idleOwners, includeIdle, idleByNode, idleByNodePool, includeProportionalAssetResourceCosts)
+	if err != nil {
+		writeQueryAllocationError(w, err)
+		return
+	}
+
+	w.Write(WrapData(asr, nil))
+}
+
+func writeQueryAllocationError(w http.ResponseWriter, err error) {
+	if strings.Contains(strings.ToLower(err.Error()), "bad request") {
+		WriteError(w, BadRequest(err.Error()))
+	} else {
+		WriteError(w, InternalServerError(err.Error()))
+	}
+}
+
+// ExplainAllocationHandler explains how the cost of a single allocation was
+// derived over the given window: the node prices used, the request or usage
+// charged for, the idle coefficients, shared costs, reconciliation
+// adjustments, and PV, network, and load balancer costs.
+func (a *Accesses) ExplainAllocationHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	// Window is a required field describing the window of time over which to
+	// explain the allocation.
+	window, err := kubecost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'window' parameter: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// Key is a required field naming the allocation to explain, as returned
+	// by /allocation/compute with the same aggregation. Without aggregation,
+	// it is the name of an unaggregated allocation, e.g.
+	// "cluster-one/node-1/kubecost/kubecost-cost-analyzer-abc/cost-model"
+	key := qp.Get("key", "")
+	if key == "" {
+		http.Error(w, "Missing 'key' parameter", http.StatusBadRequest)
+		return
+	}
+
+	// Resolution is an optional parameter, defaulting to the configured ETL
+	// resolution.
+	resolution := qp.GetDuration("resolution", env.GetETLResolution())
+
+	// Aggregation is an optional comma-separated list of fields by which the
+	// allocation was aggregated.
+	aggregateBy, err := ParseAggregationProperties(qp, "aggregate")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'aggregate' parameter: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// ShareIdle, if true, shares idle costs with the allocation. Either way,
+	// the idle coefficients are explained.
+	shareIdle := qp.GetBool("shareIdle", false)
+
+	// IdleByNode, if true, computes idle allocations at the node level.
+	idleByNode := qp.GetBool("idleByNode", false)
+
+	// IdleByNodePool, if true, computes idle allocations at the node pool
+	// level.
+	idleByNodePool := qp.GetBool("idleByNodePool", false)
+
+	sharingPolicies, err := a.Model.SharingPolicies(qp.GetList("sharingPolicies", ","))
+	if err != nil {
+		writeQueryAllocationError(w, err)
+		return
+	}
+
+	explanation, err := a.Model.ExplainAllocation(r.Context(), window, resolution, key, aggregateBy, sharingPolicies, shareIdle, idleByNode, idleByNodePool)
+	if err != nil {
+		writeQueryAllocationError(w, err)
+		return
+	}
+
+	if len(explanation.Items) == 0 {
+		WriteError(w, NotFound())
+		return
+	}
+
+	w.Write(WrapData(explanation, nil))
+}
+
+// NetworkCostMatrixHandler computes the cross-zone, cross-region, and internet
+// egress between sources and destinations, aggregated and filtered as for
+// /allocation/compute.
+func (a *Accesses) NetworkCostMatrixHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	// Window is a required field describing the window of time over which to
+	// compute network costs.
+	window, err := kubecost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'window' parameter: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// Resolution is an optional parameter, defaulting to the configured ETL
+	// resolution.
+	resolution := qp.GetDuration("resolution", env.GetETLResolution())
+
+	// Aggregation is an optional comma-separated list of fields by which to
+	// aggregate both sources and destinations, defaulting to namespace.
+	// Examples: "namespace", "controller", "cluster,namespace"
+	aggregateBy, err := ParseAggregationProperties(qp, "aggregate")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'aggregate' parameter: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// Filter is an optional parameter in the V2 filter language, matched
+	// against the source of the traffic.
+	var allocFilter kubecost.AllocationFilter
+	if raw := qp.Get("filter", ""); raw != "" {
+		allocFilter, err = filterv2.ParseAllocationFilter(raw)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid 'filter' parameter: %s", err), http.StatusBadRequest)
+			return
 		}
+	}
+
+	matrix, err := a.Model.ComputeNetworkCostMatrix(r.Context(), window, resolution, aggregateBy, allocFilter)
+	if err != nil {
+		writeQueryAllocationError(w, err)
+		return
+	}
+
+	w.Write(WrapData(matrix, nil))
+}
+
+// NodePoolsHandler computes the cost, utilization, request coverage and bin
+// packing efficiency of each node pool, e.g. EKS node groups, GKE node pools,
+// AKS agent pools and Karpenter node pools.
+func (a *Accesses) NodePoolsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	// Window is a required field describing the window of time over which to
+	// compute node pools.
+	window, err := kubecost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'window' parameter: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	// Resolution is an optional parameter, defaulting to the configured ETL
+	// resolution.
+	resolution := qp.GetDuration("resolution", env.GetETLResolution())
+
+	nps, err := a.Model.ComputeNodePools(r.Context(), window, resolution)
+	if err != nil {
+		writeQueryAllocationError(w, err)
+		return
+	}
+
+	w.Write(WrapData(nps, nil))
+}
+
+// AutoscalingSimulationHandler estimates the cost of running the current pods
+// of the cluster on a candidate node configuration, given in the request body,
+// and compares it with the cost of the current nodes over the window.
+func (a *Accesses) AutoscalingSimulationHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	// Window is an optional parameter, defaulting to 7d, describing the
+	// window of time over which to compute the cost of the current nodes.
+	window, err := kubecost.ParseWindowWithOffset(qp.Get("window", "7d"), env.GetParsedUTCOffset())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'window' parameter: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	req := &AutoscalingSimulationRequest{}
+	err = json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid simulation request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	sim, err := a.Model.SimulateAutoscaling(r.Context(), window, req)
+	if err != nil {
+		writeQueryAllocationError(w, err)
+		return
+	}
+
+	w.Write(WrapData(sim, nil))
+}
+
+// AuditResponse contains the AuditSets recorded by the AuditRunner and the
+// coverage of each type of audit.
+type AuditResponse struct {
+	Sets     []*kubecost.AuditSet    `json:"sets"`
+	Coverage *kubecost.AuditCoverage `json:"coverage"`
+}
+
+// AuditHandler returns the results of the audits run over the given window,
+// of the given type, with the coverage of every type of audit.
+func (a *Accesses) AuditHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
 
+	if a.AuditRunner == nil {
+		WriteError(w, BadRequest(fmt.Sprintf("Audits are disabled; set %s to enable them", env.AuditEnabledEnvVar)))
 		return
 	}
 
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	// Window is an optional parameter restricting the results to audits of
+	// windows which overlap it. By default, all retained audits are returned.
+	window := kubecost.NewWindow(nil, nil)
+	if qp.Get("window", "") != "" {
+		var err error
+		window, err = kubecost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
+		if err != nil {
+			WriteError(w, BadRequest(fmt.Sprintf("Invalid 'window' parameter: %s", err)))
+			return
+		}
+	}
+
+	// Type is an optional parameter restricting the results to one type of
+	// audit, e.g. "AuditAllocationReconciliation".
+	auditType := kubecost.ToAuditType(qp.Get("type", ""))
+	if auditType == kubecost.AuditInvalidType {
+		WriteError(w, BadRequest(fmt.Sprintf("Invalid 'type' parameter: %s", qp.Get("type", ""))))
+		return
+	}
+
+	resp := &AuditResponse{
+		Sets:     []*kubecost.AuditSet{},
+		Coverage: a.AuditRunner.Coverage(),
+	}
+	a.AuditRunner.AuditSets(window, auditType).Each(func(i int, set *kubecost.AuditSet) {
+		resp.Sets = append(resp.Sets, set)
+	})
+
+	w.Write(WrapData(resp, nil))
+}
+
+// ComputeAssetsHandler computes an AssetSetRange from the CostModel.
+func (a *Accesses) ComputeAssetsHandler(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	// Window is a required field describing the window of time over which to
+	// compute asset data.
+	window, err := kubecost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'window' parameter: %s", err), http.StatusBadRequest)
+		return
+	}
+	if window.IsOpen() || window.IsNegative() {
+		http.Error(w, fmt.Sprintf("Invalid 'window' parameter: illegal window: %s", window), http.StatusBadRequest)
+		return
+	}
+
+	// Step is an optional parameter that defines the duration per-set, i.e.
+	// the window for an AssetSet, of the AssetSetRange to be computed.
+	// Defaults to the window size, making one set.
+	step := qp.GetDuration("step", window.Duration())
+	if step <= 0 {
+		http.Error(w, fmt.Sprintf("Invalid 'step' parameter: %s", step), http.StatusBadRequest)
+		return
+	}
+
+	// Filter is an optional parameter in the V2 filter language, which is
+	// applied to each AssetSet, e.g. filter=type:"Node"+cluster:"cluster-one"
+	var assetFilter filter.Filter[kubecost.Asset]
+	if raw := qp.Get("filter", ""); raw != "" {
+		assetFilter, err = filterv2.ParseAssetFilter(raw)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid 'filter' parameter: %s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	asr := kubecost.NewAssetSetRange()
+
+	stepStart := *window.Start()
+	stepEnd := stepStart.Add(step)
+	for window.End().After(stepStart) {
+		assetSet, err := a.Model.ComputeAssets(stepStart, stepEnd)
+		if err != nil {
+			WriteError(w, InternalServerError(err.Error()))
+			return
+		}
+		if assetFilter != nil {
+			assetSet = assetSet.Filter(assetFilter)
+		}
+		asr.Append(assetSet)
+
+		stepStart = stepEnd
+		stepEnd = stepStart.Add(step)
+	}
+
 	w.Write(WrapData(asr, nil))
 }
 
@@ -1,15 +1,19 @@
 package costmodel
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/opencost/opencost/pkg/util/timeutil"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/opencost/opencost/pkg/env"
 	"github.com/opencost/opencost/pkg/kubecost"
 	"github.com/opencost/opencost/pkg/log"
 	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/tracing"
 )
 
 const (
@@ -24,6 +28,11 @@ const (
 	queryFmtCPUUsageAvg              = `avg(rate(container_cpu_usage_seconds_total{container!="", container_name!="POD", container!="POD"}[%s])) by (container_name, container, pod_name, pod, namespace, instance, %s)`
 	queryFmtGPUsRequested            = `avg(avg_over_time(kube_pod_container_resource_requests{resource="nvidia_com_gpu", container!="",container!="POD", node!=""}[%s])) by (container, pod, namespace, node, %s)`
 	queryFmtGPUsAllocated            = `avg(avg_over_time(container_gpu_allocation{container!="", container!="POD", node!=""}[%s])) by (container, pod, namespace, node, %s)`
+	queryFmtGPUsMIGRequested         = `avg(avg_over_time(kube_pod_container_resource_requests{resource=~"nvidia_com_mig_.*", container!="",container!="POD", node!=""}[%s])) by (container, pod, namespace, node, resource, %s)`
+	queryFmtGPUUtilization           = `avg(avg_over_time(DCGM_FI_DEV_GPU_UTIL{container!="", pod!=""}[%s])) by (container, pod, namespace, UUID, %s)`
+	queryFmtGPUMemoryUsed            = `avg(avg_over_time(DCGM_FI_DEV_FB_USED{container!="", pod!=""}[%s])) by (container, pod, namespace, UUID, %s)`
+	queryFmtEphemeralStorageRequests = `avg(avg_over_time(kube_pod_container_resource_requests{resource="ephemeral_storage", unit="byte", container!="", container!="POD", node!=""}[%s])) by (container, pod, namespace, node, %s)`
+	queryFmtEphemeralStorageUsageAvg = `sum(avg_over_time(container_fs_usage_bytes{container!="", container!="POD", device!="tmpfs"}[%s])) by (container, pod, namespace, %s)`
 	queryFmtNodeCostPerCPUHr         = `avg(avg_over_time(node_cpu_hourly_cost[%s])) by (node, %s, instance_type, provider_id)`
 	queryFmtNodeCostPerRAMGiBHr      = `avg(avg_over_time(node_ram_hourly_cost[%s])) by (node, %s, instance_type, provider_id)`
 	queryFmtNodeCostPerGPUHr         = `avg(avg_over_time(node_gpu_hourly_cost[%s])) by (node, %s, instance_type, provider_id)`
@@ -34,6 +43,8 @@ const (
 	queryFmtPVActiveMins             = `count(kube_persistentvolume_capacity_bytes) by (persistentvolume, %s)[%s:%s]`
 	queryFmtPVBytes                  = `avg(avg_over_time(kube_persistentvolume_capacity_bytes[%s])) by (persistentvolume, %s)`
 	queryFmtPVCostPerGiBHour         = `avg(avg_over_time(pv_hourly_cost[%s])) by (volumename, %s)`
+	queryFmtPVIOPSCostPerHr          = `avg(avg_over_time(pv_iops_hourly_cost[%s])) by (volumename, %s)`
+	queryFmtPVThroughputCostPerHr    = `avg(avg_over_time(pv_throughput_hourly_cost[%s])) by (volumename, %s)`
 	queryFmtNetZoneGiB               = `sum(increase(kubecost_pod_network_egress_bytes_total{internet="false", sameZone="false", sameRegion="true"}[%s])) by (pod_name, namespace, %s) / 1024 / 1024 / 1024`
 	queryFmtNetZoneCostPerGiB        = `avg(avg_over_time(kubecost_network_zone_egress_cost{}[%s])) by (%s)`
 	queryFmtNetRegionGiB             = `sum(increase(kubecost_pod_network_egress_bytes_total{internet="false", sameZone="false", sameRegion="false"}[%s])) by (pod_name, namespace, %s) / 1024 / 1024 / 1024`
@@ -42,7 +53,9 @@ const (
 	queryFmtNetInternetCostPerGiB    = `avg(avg_over_time(kubecost_network_internet_egress_cost{}[%s])) by (%s)`
 	queryFmtNetReceiveBytes          = `sum(increase(container_network_receive_bytes_total{pod!=""}[%s])) by (pod_name, pod, namespace, %s)`
 	queryFmtNetTransferBytes         = `sum(increase(container_network_transmit_bytes_total{pod!=""}[%s])) by (pod_name, pod, namespace, %s)`
+	queryFmtPodIPs                   = `avg(avg_over_time(kube_pod_info{pod_ip!=""}[%s])) by (namespace, pod, pod_ip, host_ip, node, %s)`
 	queryFmtNodeLabels               = `avg_over_time(kube_node_labels[%s])`
+	queryFmtNodeTaints               = `avg(avg_over_time(kube_node_spec_taint[%s])) by (node, key, value, %s)`
 	queryFmtNamespaceLabels          = `avg_over_time(kube_namespace_labels[%s])`
 	queryFmtNamespaceAnnotations     = `avg_over_time(kube_namespace_annotations[%s])`
 	queryFmtPodLabels                = `avg_over_time(kube_pod_labels[%s])`
@@ -56,6 +69,9 @@ const (
 	queryFmtReplicaSetsWithoutOwners = `avg(avg_over_time(kube_replicaset_owner{owner_kind="<none>", owner_name="<none>"}[%s])) by (replicaset, namespace, %s)`
 	queryFmtLBCostPerHr              = `avg(avg_over_time(kubecost_load_balancer_cost[%s])) by (namespace, service_name, %s)`
 	queryFmtLBActiveMins             = `count(kubecost_load_balancer_cost) by (namespace, service_name, %s)[%s:%s]`
+	queryFmtVolumeSnapshotActiveMins = `count(kubecost_volumesnapshot_size_bytes) by (namespace, volumesnapshot, persistentvolumeclaim, %s)[%s:%s]`
+	queryFmtVolumeSnapshotBytes      = `avg(avg_over_time(kubecost_volumesnapshot_size_bytes[%s])) by (namespace, volumesnapshot, provider_id, %s)`
+	queryFmtVolumeSnapshotCostPerGiB = `avg(avg_over_time(kubecost_volumesnapshot_storage_cost[%s])) by (%s)`
 	queryFmtOldestSample             = `min_over_time(timestamp(group(node_cpu_hourly_cost))[%s:%s])`
 	queryFmtNewestSample             = `max_over_time(timestamp(group(node_cpu_hourly_cost))[%s:%s])`
 
@@ -112,10 +128,30 @@ func (cm *CostModel) Name() string {
 // for the window defined by the given start and end times. The Allocations
 // returned are unaggregated (i.e. down to the container level).
 func (cm *CostModel) ComputeAllocation(start, end time.Time, resolution time.Duration) (*kubecost.AllocationSet, error) {
+	return cm.ComputeAllocationWithContext(context.Background(), start, end, resolution)
+}
+
+// ComputeAllocationWithContext is ComputeAllocation, but uses the given
+// context as the parent of all Prometheus requests and trace spans.
+func (cm *CostModel) ComputeAllocationWithContext(traceCtx context.Context, start, end time.Time, resolution time.Duration) (*kubecost.AllocationSet, error) {
+	return cm.computeAllocationWithPushdown(traceCtx, start, end, resolution, nil)
+}
+
+// computeAllocationWithPushdown is ComputeAllocationWithContext, but narrows
+// the Prometheus queries with the given pushdown, if any. Allocations not
+// matching the pushed filter may be missing or incomplete in the result.
+func (cm *CostModel) computeAllocationWithPushdown(traceCtx context.Context, start, end time.Time, resolution time.Duration, pushdown *allocationPushdown) (*kubecost.AllocationSet, error) {
+	traceCtx, span := tracing.Start(traceCtx, "CostModel.ComputeAllocation",
+		attribute.String("window", kubecost.NewClosedWindow(start, end).String()),
+		attribute.String("resolution", resolution.String()),
+	)
+	defer span.End()
 
 	// If the duration is short enough, compute the AllocationSet directly
 	if end.Sub(start) <= cm.MaxPrometheusQueryDuration {
-		return cm.computeAllocation(start, end, resolution)
+		as, err := cm.computeAllocation(traceCtx, start, end, resolution, pushdown)
+		tracing.RecordError(span, err)
+		return as, err
 	}
 
 	// If the duration exceeds the configured MaxPrometheusQueryDuration, then
@@ -142,8 +178,9 @@ func (cm *CostModel) ComputeAllocation(start, end time.Time, resolution time.Dur
 		e = s.Add(duration)
 
 		// Compute the individual AllocationSet for just (s, e)
-		as, err := cm.computeAllocation(s, e, resolution)
+		as, err := cm.computeAllocation(traceCtx, s, e, resolution, pushdown)
 		if err != nil {
+			tracing.RecordError(span, err)
 			return kubecost.NewAllocationSet(start, end), fmt.Errorf("error computing allocation for %s: %s", kubecost.NewClosedWindow(s, e), err)
 		}
 
@@ -160,6 +197,8 @@ func (cm *CostModel) ComputeAllocation(start, end time.Time, resolution time.Dur
 	// it is both acceptable and necessary to do so.
 	allocationAnnotations := map[string]map[string]string{}
 	allocationLabels := map[string]map[string]string{}
+	allocationNamespaceAnnotations := map[string]map[string]string{}
+	allocationNamespaceLabels := map[string]map[string]string{}
 	allocationServices := map[string]map[string]bool{}
 
 	// Also record errors and warnings, then append them to the results later.
@@ -186,6 +225,24 @@ func (cm *CostModel) ComputeAllocation(start, end time.Time, resolution time.Dur
 				}
 			}
 
+			if len(a.Properties.NamespaceAnnotations) > 0 {
+				if _, ok := allocationNamespaceAnnotations[k]; !ok {
+					allocationNamespaceAnnotations[k] = map[string]string{}
+				}
+				for name, val := range a.Properties.NamespaceAnnotations {
+					allocationNamespaceAnnotations[k][name] = val
+				}
+			}
+
+			if len(a.Properties.NamespaceLabels) > 0 {
+				if _, ok := allocationNamespaceLabels[k]; !ok {
+					allocationNamespaceLabels[k] = map[string]string{}
+				}
+				for name, val := range a.Properties.NamespaceLabels {
+					allocationNamespaceLabels[k][name] = val
+				}
+			}
+
 			if len(a.Properties.Services) > 0 {
 				if _, ok := allocationServices[k]; !ok {
 					allocationServices[k] = map[string]bool{}
@@ -226,6 +283,14 @@ func (cm *CostModel) ComputeAllocation(start, end time.Time, resolution time.Dur
 			a.Properties.Labels = labels
 		}
 
+		if annotations, ok := allocationNamespaceAnnotations[k]; ok {
+			a.Properties.NamespaceAnnotations = annotations
+		}
+
+		if labels, ok := allocationNamespaceLabels[k]; ok {
+			a.Properties.NamespaceLabels = labels
+		}
+
 		if services, ok := allocationServices[k]; ok {
 			a.Properties.Services = []string{}
 			for s := range services {
@@ -301,7 +366,10 @@ func (cm *CostModel) DateRange() (time.Time, time.Time, error) {
 	return oldest, newest, nil
 }
 
-func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Duration) (*kubecost.AllocationSet, error) {
+func (cm *CostModel) computeAllocation(traceCtx context.Context, start, end time.Time, resolution time.Duration, pushdown *allocationPushdown) (*kubecost.AllocationSet, error) {
+	traceCtx, span := tracing.Start(traceCtx, "CostModel.computeAllocation", attribute.String("window", kubecost.NewClosedWindow(start, end).String()))
+	defer span.End()
+
 	// 1. Build out Pod map from resolution-tuned, batched Pod start/end query
 	// 2. Run and apply the results of the remaining queries to
 	// 3. Build out AllocationSet from completed Pod map
@@ -349,7 +417,7 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	}
 
 	// TODO:CLEANUP remove "max batch" idea and clusterStart/End
-	err := cm.buildPodMap(window, resolution, env.GetETLMaxPrometheusQueryDuration(), podMap, clusterStart, clusterEnd, ingestPodUID, podUIDKeyMap)
+	err := cm.buildPodMap(traceCtx, window, resolution, env.GetETLMaxPrometheusQueryDuration(), podMap, clusterStart, clusterEnd, ingestPodUID, podUIDKeyMap, pushdown)
 	if err != nil {
 		log.Errorf("CostModel.ComputeAllocation: failed to build pod map: %s", err.Error())
 	}
@@ -364,40 +432,43 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	// Convert resolution duration to a query-ready string
 	resStr := timeutil.DurationString(resolution)
 
-	ctx := prom.NewNamedContext(cm.PrometheusClient, prom.AllocationContextName)
+	// The irate window of the CPU usage max subquery should be set to 2x the
+	// resolution, to make sure the irate always has two points to query in
+	// case the Prom scrape duration has been reduced to be equal to the
+	// resolution.
+	doubleResStr := timeutil.DurationString(2 * resolution)
 
-	queryRAMBytesAllocated := fmt.Sprintf(queryFmtRAMBytesAllocated, durStr, env.GetPromClusterLabel())
+	queries := newAllocationQueries(durStr, resStr, doubleResStr)
+
+	ctx := prom.NewNamedContext(cm.PrometheusClient, prom.AllocationContextName).WithContext(traceCtx)
+
+	queryRAMBytesAllocated := queries.query(allocationQueryRAMBytesAllocated, pushdown, pushdownNamespaceLabels)
 	resChRAMBytesAllocated := ctx.QueryAtTime(queryRAMBytesAllocated, end)
 
-	queryRAMRequests := fmt.Sprintf(queryFmtRAMRequests, durStr, env.GetPromClusterLabel())
+	queryRAMRequests := queries.query(allocationQueryRAMRequests, pushdown, pushdownContainerLabels)
 	resChRAMRequests := ctx.QueryAtTime(queryRAMRequests, end)
 
-	queryRAMUsageAvg := fmt.Sprintf(queryFmtRAMUsageAvg, durStr, env.GetPromClusterLabel())
+	queryRAMUsageAvg := queries.query(allocationQueryRAMUsageAvg, pushdown, pushdownContainerLabels)
 	resChRAMUsageAvg := ctx.QueryAtTime(queryRAMUsageAvg, end)
 
-	queryRAMUsageMax := fmt.Sprintf(queryFmtRAMUsageMax, durStr, env.GetPromClusterLabel())
+	queryRAMUsageMax := queries.query(allocationQueryRAMUsageMax, pushdown, pushdownContainerLabels)
 	resChRAMUsageMax := ctx.QueryAtTime(queryRAMUsageMax, end)
 
-	queryCPUCoresAllocated := fmt.Sprintf(queryFmtCPUCoresAllocated, durStr, env.GetPromClusterLabel())
+	queryCPUCoresAllocated := queries.query(allocationQueryCPUCoresAllocated, pushdown, pushdownNamespaceLabels)
 	resChCPUCoresAllocated := ctx.QueryAtTime(queryCPUCoresAllocated, end)
 
-	queryCPURequests := fmt.Sprintf(queryFmtCPURequests, durStr, env.GetPromClusterLabel())
+	queryCPURequests := queries.query(allocationQueryCPURequests, pushdown, pushdownContainerLabels)
 	resChCPURequests := ctx.QueryAtTime(queryCPURequests, end)
 
-	queryCPUUsageAvg := fmt.Sprintf(queryFmtCPUUsageAvg, durStr, env.GetPromClusterLabel())
+	queryCPUUsageAvg := queries.query(allocationQueryCPUUsageAvg, pushdown, pushdownContainerLabels)
 	resChCPUUsageAvg := ctx.QueryAtTime(queryCPUUsageAvg, end)
 
-	queryCPUUsageMax := fmt.Sprintf(queryFmtCPUUsageMaxRecordingRule, durStr, env.GetPromClusterLabel())
+	queryCPUUsageMax := queries.query(allocationQueryCPUUsageMaxRecordingRule, pushdown, pushdownContainerLabels)
 	resChCPUUsageMax := ctx.QueryAtTime(queryCPUUsageMax, end)
 	resCPUUsageMax, _ := resChCPUUsageMax.Await()
 	// If the recording rule has no data, try to fall back to the subquery.
 	if len(resCPUUsageMax) == 0 {
-		// The parameter after the metric ...{}[<thisone>] should be set to 2x
-		// the resolution, to make sure the irate always has two points to query
-		// in case the Prom scrape duration has been reduced to be equal to the
-		// resolution.
-		doubleResStr := timeutil.DurationString(2 * resolution)
-		queryCPUUsageMax = fmt.Sprintf(queryFmtCPUUsageMaxSubquery, doubleResStr, durStr, resStr, env.GetPromClusterLabel())
+		queryCPUUsageMax = queries.query(allocationQueryCPUUsageMaxSubquery, pushdown, pushdownContainerLabels)
 		resChCPUUsageMax = ctx.QueryAtTime(queryCPUUsageMax, end)
 		resCPUUsageMax, _ = resChCPUUsageMax.Await()
 
@@ -408,111 +479,155 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 		}
 	}
 
-	queryGPUsRequested := fmt.Sprintf(queryFmtGPUsRequested, durStr, env.GetPromClusterLabel())
+	queryGPUsRequested := queries.query(allocationQueryGPUsRequested, pushdown, pushdownContainerLabels)
 	resChGPUsRequested := ctx.QueryAtTime(queryGPUsRequested, end)
 
-	queryGPUsAllocated := fmt.Sprintf(queryFmtGPUsAllocated, durStr, env.GetPromClusterLabel())
+	queryGPUsAllocated := queries.query(allocationQueryGPUsAllocated, pushdown, pushdownContainerLabels)
 	resChGPUsAllocated := ctx.QueryAtTime(queryGPUsAllocated, end)
 
-	queryNodeCostPerCPUHr := fmt.Sprintf(queryFmtNodeCostPerCPUHr, durStr, env.GetPromClusterLabel())
+	queryGPUsMIGRequested := queries.query(allocationQueryGPUsMIGRequested, pushdown, pushdownContainerLabels)
+	resChGPUsMIGRequested := ctx.QueryAtTime(queryGPUsMIGRequested, end)
+
+	// When splitting GPUs by utilization, the utilization of every container
+	// sharing a GPU is required, so the query may only be narrowed by cluster.
+	gpuAllocationMode := env.GetGPUAllocationMode()
+	gpuUtilizationLabels := pushdownContainerLabels
+	if gpuAllocationMode == env.GPUAllocationModeUtilization {
+		gpuUtilizationLabels = pushdownClusterLabels
+	}
+	queryGPUUtilization := queries.query(allocationQueryGPUUtilization, pushdown, gpuUtilizationLabels)
+	resChGPUUtilization := ctx.QueryAtTime(queryGPUUtilization, end)
+
+	queryGPUMemoryUsed := queries.query(allocationQueryGPUMemoryUsed, pushdown, pushdownContainerLabels)
+	resChGPUMemoryUsed := ctx.QueryAtTime(queryGPUMemoryUsed, end)
+
+	queryEphemeralStorageRequests := queries.query(allocationQueryEphemeralStorageRequests, pushdown, pushdownContainerLabels)
+	resChEphemeralStorageRequests := ctx.QueryAtTime(queryEphemeralStorageRequests, end)
+
+	queryEphemeralStorageUsageAvg := queries.query(allocationQueryEphemeralStorageUsageAvg, pushdown, pushdownContainerLabels)
+	resChEphemeralStorageUsageAvg := ctx.QueryAtTime(queryEphemeralStorageUsageAvg, end)
+
+	queryNodeCostPerCPUHr := queries.query(allocationQueryNodeCostPerCPUHr, pushdown, pushdownNodeLabels)
 	resChNodeCostPerCPUHr := ctx.QueryAtTime(queryNodeCostPerCPUHr, end)
 
-	queryNodeCostPerRAMGiBHr := fmt.Sprintf(queryFmtNodeCostPerRAMGiBHr, durStr, env.GetPromClusterLabel())
+	queryNodeCostPerRAMGiBHr := queries.query(allocationQueryNodeCostPerRAMGiBHr, pushdown, pushdownNodeLabels)
 	resChNodeCostPerRAMGiBHr := ctx.QueryAtTime(queryNodeCostPerRAMGiBHr, end)
 
-	queryNodeCostPerGPUHr := fmt.Sprintf(queryFmtNodeCostPerGPUHr, durStr, env.GetPromClusterLabel())
+	queryNodeCostPerGPUHr := queries.query(allocationQueryNodeCostPerGPUHr, pushdown, pushdownNodeLabels)
 	resChNodeCostPerGPUHr := ctx.QueryAtTime(queryNodeCostPerGPUHr, end)
 
-	queryNodeIsSpot := fmt.Sprintf(queryFmtNodeIsSpot, durStr)
+	queryNodeIsSpot := queries.query(allocationQueryNodeIsSpot, pushdown, pushdownNodeLabels)
 	resChNodeIsSpot := ctx.QueryAtTime(queryNodeIsSpot, end)
 
-	queryPVCInfo := fmt.Sprintf(queryFmtPVCInfo, env.GetPromClusterLabel(), durStr, resStr)
+	queryPVCInfo := queries.query(allocationQueryPVCInfo, pushdown, pushdownNamespaceLabels)
 	resChPVCInfo := ctx.QueryAtTime(queryPVCInfo, end)
 
-	queryPodPVCAllocation := fmt.Sprintf(queryFmtPodPVCAllocation, durStr, env.GetPromClusterLabel())
+	queryPodPVCAllocation := queries.query(allocationQueryPodPVCAllocation, pushdown, pushdownNamespaceLabels)
 	resChPodPVCAllocation := ctx.QueryAtTime(queryPodPVCAllocation, end)
 
-	queryPVCBytesRequested := fmt.Sprintf(queryFmtPVCBytesRequested, durStr, env.GetPromClusterLabel())
+	queryPVCBytesRequested := queries.query(allocationQueryPVCBytesRequested, pushdown, pushdownNamespaceLabels)
 	resChPVCBytesRequested := ctx.QueryAtTime(queryPVCBytesRequested, end)
 
-	queryPVActiveMins := fmt.Sprintf(queryFmtPVActiveMins, env.GetPromClusterLabel(), durStr, resStr)
+	queryPVActiveMins := queries.query(allocationQueryPVActiveMins, pushdown, pushdownClusterLabels)
 	resChPVActiveMins := ctx.QueryAtTime(queryPVActiveMins, end)
 
-	queryPVBytes := fmt.Sprintf(queryFmtPVBytes, durStr, env.GetPromClusterLabel())
+	queryPVBytes := queries.query(allocationQueryPVBytes, pushdown, pushdownClusterLabels)
 	resChPVBytes := ctx.QueryAtTime(queryPVBytes, end)
 
-	queryPVCostPerGiBHour := fmt.Sprintf(queryFmtPVCostPerGiBHour, durStr, env.GetPromClusterLabel())
+	queryPVCostPerGiBHour := queries.query(allocationQueryPVCostPerGiBHour, pushdown, pushdownClusterLabels)
 	resChPVCostPerGiBHour := ctx.QueryAtTime(queryPVCostPerGiBHour, end)
 
-	queryNetTransferBytes := fmt.Sprintf(queryFmtNetTransferBytes, durStr, env.GetPromClusterLabel())
+	queryPVIOPSCostPerHr := queries.query(allocationQueryPVIOPSCostPerHr, pushdown, pushdownClusterLabels)
+	resChPVIOPSCostPerHr := ctx.QueryAtTime(queryPVIOPSCostPerHr, end)
+
+	queryPVThroughputCostPerHr := queries.query(allocationQueryPVThroughputCostPerHr, pushdown, pushdownClusterLabels)
+	resChPVThroughputCostPerHr := ctx.QueryAtTime(queryPVThroughputCostPerHr, end)
+
+	queryNetTransferBytes := queries.query(allocationQueryNetTransferBytes, pushdown, pushdownPodLabels)
 	resChNetTransferBytes := ctx.QueryAtTime(queryNetTransferBytes, end)
 
-	queryNetReceiveBytes := fmt.Sprintf(queryFmtNetReceiveBytes, durStr, env.GetPromClusterLabel())
+	queryNetReceiveBytes := queries.query(allocationQueryNetReceiveBytes, pushdown, pushdownPodLabels)
 	resChNetReceiveBytes := ctx.QueryAtTime(queryNetReceiveBytes, end)
 
-	queryNetZoneGiB := fmt.Sprintf(queryFmtNetZoneGiB, durStr, env.GetPromClusterLabel())
+	queryNetZoneGiB := queries.query(allocationQueryNetZoneGiB, pushdown, pushdownPodNameLabels)
 	resChNetZoneGiB := ctx.QueryAtTime(queryNetZoneGiB, end)
 
-	queryNetZoneCostPerGiB := fmt.Sprintf(queryFmtNetZoneCostPerGiB, durStr, env.GetPromClusterLabel())
+	queryNetZoneCostPerGiB := queries.query(allocationQueryNetZoneCostPerGiB, pushdown, pushdownClusterLabels)
 	resChNetZoneCostPerGiB := ctx.QueryAtTime(queryNetZoneCostPerGiB, end)
 
-	queryNetRegionGiB := fmt.Sprintf(queryFmtNetRegionGiB, durStr, env.GetPromClusterLabel())
+	queryNetRegionGiB := queries.query(allocationQueryNetRegionGiB, pushdown, pushdownPodNameLabels)
 	resChNetRegionGiB := ctx.QueryAtTime(queryNetRegionGiB, end)
 
-	queryNetRegionCostPerGiB := fmt.Sprintf(queryFmtNetRegionCostPerGiB, durStr, env.GetPromClusterLabel())
+	queryNetRegionCostPerGiB := queries.query(allocationQueryNetRegionCostPerGiB, pushdown, pushdownClusterLabels)
 	resChNetRegionCostPerGiB := ctx.QueryAtTime(queryNetRegionCostPerGiB, end)
 
-	queryNetInternetGiB := fmt.Sprintf(queryFmtNetInternetGiB, durStr, env.GetPromClusterLabel())
+	queryNetInternetGiB := queries.query(allocationQueryNetInternetGiB, pushdown, pushdownPodNameLabels)
 	resChNetInternetGiB := ctx.QueryAtTime(queryNetInternetGiB, end)
 
-	queryNetInternetCostPerGiB := fmt.Sprintf(queryFmtNetInternetCostPerGiB, durStr, env.GetPromClusterLabel())
+	queryNetInternetCostPerGiB := queries.query(allocationQueryNetInternetCostPerGiB, pushdown, pushdownClusterLabels)
 	resChNetInternetCostPerGiB := ctx.QueryAtTime(queryNetInternetCostPerGiB, end)
 
-	var resChNodeLabels prom.QueryResultsChan
-	if env.GetAllocationNodeLabelsEnabled() {
-		queryNodeLabels := fmt.Sprintf(queryFmtNodeLabels, durStr)
-		resChNodeLabels = ctx.QueryAtTime(queryNodeLabels, end)
+	// Flows are classified by the topology of both of their endpoints, so the
+	// addresses of all pods and the labels of all nodes are queried
+	var resChPodIPs, resChNetworkNodeLabels prom.QueryResultsChan
+	if cm.NetworkFlows != nil {
+		resChPodIPs = ctx.QueryAtTime(queries.query(allocationQueryPodIPs, nil, nil), end)
+		resChNetworkNodeLabels = ctx.QueryAtTime(queries.query(allocationQueryNodeLabels, nil, nil), end)
 	}
 
-	queryNamespaceLabels := fmt.Sprintf(queryFmtNamespaceLabels, durStr)
+	// Node labels are always queried, because node pools are derived from
+	// them, but are only applied to allocations if enabled
+	queryNodeLabels := queries.query(allocationQueryNodeLabels, pushdown, pushdownNodeLabels)
+	resChNodeLabels := ctx.QueryAtTime(queryNodeLabels, end)
+
+	queryNamespaceLabels := queries.query(allocationQueryNamespaceLabels, pushdown, pushdownNamespaceLabels)
 	resChNamespaceLabels := ctx.QueryAtTime(queryNamespaceLabels, end)
 
-	queryNamespaceAnnotations := fmt.Sprintf(queryFmtNamespaceAnnotations, durStr)
+	queryNamespaceAnnotations := queries.query(allocationQueryNamespaceAnnotations, pushdown, pushdownNamespaceLabels)
 	resChNamespaceAnnotations := ctx.QueryAtTime(queryNamespaceAnnotations, end)
 
-	queryPodLabels := fmt.Sprintf(queryFmtPodLabels, durStr)
+	queryPodLabels := queries.query(allocationQueryPodLabels, pushdown, pushdownNamespaceLabels)
 	resChPodLabels := ctx.QueryAtTime(queryPodLabels, end)
 
-	queryPodAnnotations := fmt.Sprintf(queryFmtPodAnnotations, durStr)
+	queryPodAnnotations := queries.query(allocationQueryPodAnnotations, pushdown, pushdownPodLabels)
 	resChPodAnnotations := ctx.QueryAtTime(queryPodAnnotations, end)
 
-	queryServiceLabels := fmt.Sprintf(queryFmtServiceLabels, durStr)
+	queryServiceLabels := queries.query(allocationQueryServiceLabels, pushdown, pushdownNamespaceLabels)
 	resChServiceLabels := ctx.QueryAtTime(queryServiceLabels, end)
 
-	queryDeploymentLabels := fmt.Sprintf(queryFmtDeploymentLabels, durStr)
+	queryDeploymentLabels := queries.query(allocationQueryDeploymentLabels, pushdown, pushdownNamespaceLabels)
 	resChDeploymentLabels := ctx.QueryAtTime(queryDeploymentLabels, end)
 
-	queryStatefulSetLabels := fmt.Sprintf(queryFmtStatefulSetLabels, durStr)
+	queryStatefulSetLabels := queries.query(allocationQueryStatefulSetLabels, pushdown, pushdownNamespaceLabels)
 	resChStatefulSetLabels := ctx.QueryAtTime(queryStatefulSetLabels, end)
 
-	queryDaemonSetLabels := fmt.Sprintf(queryFmtDaemonSetLabels, durStr, env.GetPromClusterLabel())
+	queryDaemonSetLabels := queries.query(allocationQueryDaemonSetLabels, pushdown, pushdownPodLabels)
 	resChDaemonSetLabels := ctx.QueryAtTime(queryDaemonSetLabels, end)
 
-	queryPodsWithReplicaSetOwner := fmt.Sprintf(queryFmtPodsWithReplicaSetOwner, durStr, env.GetPromClusterLabel())
+	queryPodsWithReplicaSetOwner := queries.query(allocationQueryPodsWithReplicaSetOwner, pushdown, pushdownPodLabels)
 	resChPodsWithReplicaSetOwner := ctx.QueryAtTime(queryPodsWithReplicaSetOwner, end)
 
-	queryReplicaSetsWithoutOwners := fmt.Sprintf(queryFmtReplicaSetsWithoutOwners, durStr, env.GetPromClusterLabel())
+	queryReplicaSetsWithoutOwners := queries.query(allocationQueryReplicaSetsWithoutOwners, pushdown, pushdownNamespaceLabels)
 	resChReplicaSetsWithoutOwners := ctx.QueryAtTime(queryReplicaSetsWithoutOwners, end)
 
-	queryJobLabels := fmt.Sprintf(queryFmtJobLabels, durStr, env.GetPromClusterLabel())
+	queryJobLabels := queries.query(allocationQueryJobLabels, pushdown, pushdownPodLabels)
 	resChJobLabels := ctx.QueryAtTime(queryJobLabels, end)
 
-	queryLBCostPerHr := fmt.Sprintf(queryFmtLBCostPerHr, durStr, env.GetPromClusterLabel())
+	queryLBCostPerHr := queries.query(allocationQueryLBCostPerHr, pushdown, pushdownNamespaceLabels)
 	resChLBCostPerHr := ctx.QueryAtTime(queryLBCostPerHr, end)
 
-	queryLBActiveMins := fmt.Sprintf(queryFmtLBActiveMins, env.GetPromClusterLabel(), durStr, resStr)
+	queryLBActiveMins := queries.query(allocationQueryLBActiveMins, pushdown, pushdownNamespaceLabels)
 	resChLBActiveMins := ctx.QueryAtTime(queryLBActiveMins, end)
 
+	queryVolumeSnapshotActiveMins := queries.query(allocationQuerySnapshotActiveMins, pushdown, pushdownNamespaceLabels)
+	resChVolumeSnapshotActiveMins := ctx.QueryAtTime(queryVolumeSnapshotActiveMins, end)
+
+	queryVolumeSnapshotBytes := queries.query(allocationQuerySnapshotBytes, pushdown, pushdownNamespaceLabels)
+	resChVolumeSnapshotBytes := ctx.QueryAtTime(queryVolumeSnapshotBytes, end)
+
+	queryVolumeSnapshotCostPerGiBHr := queries.query(allocationQuerySnapshotCostPerGiBHr, pushdown, pushdownClusterLabels)
+	resChVolumeSnapshotCostPerGiBHr := ctx.QueryAtTime(queryVolumeSnapshotCostPerGiBHr, end)
+
 	resCPUCoresAllocated, _ := resChCPUCoresAllocated.Await()
 	resCPURequests, _ := resChCPURequests.Await()
 	resCPUUsageAvg, _ := resChCPUUsageAvg.Await()
@@ -522,6 +637,11 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	resRAMUsageMax, _ := resChRAMUsageMax.Await()
 	resGPUsRequested, _ := resChGPUsRequested.Await()
 	resGPUsAllocated, _ := resChGPUsAllocated.Await()
+	resGPUsMIGRequested, _ := resChGPUsMIGRequested.Await()
+	resGPUUtilization, _ := resChGPUUtilization.Await()
+	resGPUMemoryUsed, _ := resChGPUMemoryUsed.Await()
+	resEphemeralStorageRequests, _ := resChEphemeralStorageRequests.Await()
+	resEphemeralStorageUsageAvg, _ := resChEphemeralStorageUsageAvg.Await()
 
 	resNodeCostPerCPUHr, _ := resChNodeCostPerCPUHr.Await()
 	resNodeCostPerRAMGiBHr, _ := resChNodeCostPerRAMGiBHr.Await()
@@ -531,6 +651,8 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	resPVActiveMins, _ := resChPVActiveMins.Await()
 	resPVBytes, _ := resChPVBytes.Await()
 	resPVCostPerGiBHour, _ := resChPVCostPerGiBHour.Await()
+	resPVIOPSCostPerHr, _ := resChPVIOPSCostPerHr.Await()
+	resPVThroughputCostPerHr, _ := resChPVThroughputCostPerHr.Await()
 
 	resPVCInfo, _ := resChPVCInfo.Await()
 	resPVCBytesRequested, _ := resChPVCBytesRequested.Await()
@@ -545,12 +667,13 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	resNetInternetGiB, _ := resChNetInternetGiB.Await()
 	resNetInternetCostPerGiB, _ := resChNetInternetCostPerGiB.Await()
 
-	var resNodeLabels []*prom.QueryResult
-	if env.GetAllocationNodeLabelsEnabled() {
-		if env.GetAllocationNodeLabelsEnabled() {
-			resNodeLabels, _ = resChNodeLabels.Await()
-		}
+	var resPodIPs, resNetworkNodeLabels []*prom.QueryResult
+	if cm.NetworkFlows != nil {
+		resPodIPs, _ = resChPodIPs.Await()
+		resNetworkNodeLabels, _ = resChNetworkNodeLabels.Await()
 	}
+
+	resNodeLabels, _ := resChNodeLabels.Await()
 	resNamespaceLabels, _ := resChNamespaceLabels.Await()
 	resNamespaceAnnotations, _ := resChNamespaceAnnotations.Await()
 	resPodLabels, _ := resChPodLabels.Await()
@@ -564,6 +687,9 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	resJobLabels, _ := resChJobLabels.Await()
 	resLBCostPerHr, _ := resChLBCostPerHr.Await()
 	resLBActiveMins, _ := resChLBActiveMins.Await()
+	resVolumeSnapshotActiveMins, _ := resChVolumeSnapshotActiveMins.Await()
+	resVolumeSnapshotBytes, _ := resChVolumeSnapshotBytes.Await()
+	resVolumeSnapshotCostPerGiBHr, _ := resChVolumeSnapshotCostPerGiBHr.Await()
 
 	if ctx.HasErrors() {
 		for _, err := range ctx.Errors() {
@@ -585,10 +711,33 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	applyRAMBytesUsedAvg(podMap, resRAMUsageAvg, podUIDKeyMap)
 	applyRAMBytesUsedMax(podMap, resRAMUsageMax, podUIDKeyMap)
 	applyGPUsAllocated(podMap, resGPUsRequested, resGPUsAllocated, podUIDKeyMap)
-	applyNetworkTotals(podMap, resNetTransferBytes, resNetReceiveBytes, podUIDKeyMap)
-	applyNetworkAllocation(podMap, resNetZoneGiB, resNetZoneCostPerGiB, podUIDKeyMap, networkCrossZoneCost)
-	applyNetworkAllocation(podMap, resNetRegionGiB, resNetRegionCostPerGiB, podUIDKeyMap, networkCrossRegionCost)
-	applyNetworkAllocation(podMap, resNetInternetGiB, resNetInternetCostPerGiB, podUIDKeyMap, networkInternetCost)
+	applyGPUsMIGRequested(podMap, resGPUsMIGRequested, podUIDKeyMap)
+	applyGPUUsage(podMap, resGPUUtilization, resGPUMemoryUsed, podUIDKeyMap, gpuAllocationMode == env.GPUAllocationModeUtilization)
+	applyEphemeralStorage(podMap, resEphemeralStorageRequests, resEphemeralStorageUsageAvg, podUIDKeyMap)
+
+	// Attribute network bytes and costs from flow logs, if configured, rather
+	// than from container metrics and the metrics of the network costs daemon
+	var networkFlows []*NetworkFlow
+	if cm.NetworkFlows != nil {
+		networkFlows, err = cm.NetworkFlows.Flows(start, end)
+		if err != nil {
+			log.Warnf("CostModel.ComputeAllocation: failed to read flow logs, falling back to network metrics: %s", err)
+			networkFlows = nil
+		}
+	}
+	if networkFlows != nil {
+		costPerGiB := map[string]map[string]float64{
+			networkCrossZoneCost:   networkCostPerGiBByCluster(resNetZoneCostPerGiB),
+			networkCrossRegionCost: networkCostPerGiBByCluster(resNetRegionCostPerGiB),
+			networkInternetCost:    networkCostPerGiBByCluster(resNetInternetCostPerGiB),
+		}
+		applyNetworkFlows(window, podMap, networkFlows, newNetworkTopology(resPodIPs, resNetworkNodeLabels), costPerGiB, podUIDKeyMap)
+	} else {
+		applyNetworkTotals(podMap, resNetTransferBytes, resNetReceiveBytes, podUIDKeyMap)
+		applyNetworkAllocation(podMap, resNetZoneGiB, resNetZoneCostPerGiB, podUIDKeyMap, networkCrossZoneCost)
+		applyNetworkAllocation(podMap, resNetRegionGiB, resNetRegionCostPerGiB, podUIDKeyMap, networkCrossRegionCost)
+		applyNetworkAllocation(podMap, resNetInternetGiB, resNetInternetCostPerGiB, podUIDKeyMap, networkInternetCost)
+	}
 
 	// In the case that a two pods with the same name had different containers,
 	// we will double-count the containers. There is no way to associate each
@@ -605,12 +754,14 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	if env.GetAllocationNodeLabelsEnabled() {
 		nodeLabels = resToNodeLabels(resNodeLabels)
 	}
+	nodePools := resToNodePools(resNodeLabels)
 	namespaceLabels := resToNamespaceLabels(resNamespaceLabels)
 	podLabels := resToPodLabels(resPodLabels, podUIDKeyMap, ingestPodUID)
 	namespaceAnnotations := resToNamespaceAnnotations(resNamespaceAnnotations)
 	podAnnotations := resToPodAnnotations(resPodAnnotations, podUIDKeyMap, ingestPodUID)
 	applyLabels(podMap, nodeLabels, namespaceLabels, podLabels)
 	applyAnnotations(podMap, namespaceAnnotations, podAnnotations)
+	applyNodePools(podMap, nodePools)
 
 	podDeploymentMap := labelsToPodControllerMap(podLabels, resToDeploymentLabels(resDeploymentLabels))
 	podStatefulSetMap := labelsToPodControllerMap(podLabels, resToStatefulSetLabels(resStatefulSetLabels))
@@ -622,6 +773,10 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	applyControllersToPods(podMap, podDaemonSetMap)
 	applyControllersToPods(podMap, podJobMap)
 	applyControllersToPods(podMap, podReplicaSetMap)
+	if cm.Cache != nil {
+		applyClusterCacheMetadata(podMap, cm.Cache, env.GetClusterID())
+	}
+	cm.applyClusterSnapshots(podMap, start, end)
 
 	serviceLabels := getServiceLabels(resServiceLabels)
 	allocsByService := map[serviceKey][]*kubecost.Allocation{}
@@ -637,6 +792,7 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	pvMap := map[pvKey]*pv{}
 	buildPVMap(resolution, pvMap, resPVCostPerGiBHour, resPVActiveMins)
 	applyPVBytes(pvMap, resPVBytes)
+	applyPVPerformanceCosts(pvMap, resPVIOPSCostPerHr, resPVThroughputCostPerHr)
 
 	// Build out the map of all PVCs with time running, bytes requested,
 	// and connect to the correct PV from pvMap. (If no PV exists, that
@@ -662,6 +818,11 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	getLoadBalancerCosts(lbMap, resLBCostPerHr, resLBActiveMins, resolution)
 	applyLoadBalancersToPods(window, podMap, lbMap, allocsByService)
 
+	// Attribute the storage of volume snapshots to the pods which mounted
+	// their source PVCs, or else to the namespace of the source PVC
+	snapshotMap := buildVolumeSnapshotMap(resolution, resVolumeSnapshotActiveMins, resVolumeSnapshotBytes, resVolumeSnapshotCostPerGiBHr)
+	applyVolumeSnapshotsToPods(window, podMap, snapshotMap, podPVCMap)
+
 	// Build out a map of Nodes with resource costs, discounts, and node types
 	// for converting resource allocation data to cumulative costs.
 	nodeMap := map[nodeKey]*nodePricing{}
@@ -671,7 +832,19 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 	applyNodeCostPerGPUHr(nodeMap, resNodeCostPerGPUHr)
 	applyNodeSpot(nodeMap, resNodeIsSpot)
 	applyNodeDiscount(nodeMap, cm)
-	cm.applyNodesToPod(podMap, nodeMap)
+
+	// Price ephemeral storage by the local disk assets of the nodes, if any
+	// was requested or used
+	var localDiskCosts map[nodeKey]float64
+	if len(resEphemeralStorageRequests) > 0 || len(resEphemeralStorageUsageAvg) > 0 {
+		diskMap, err := cm.ClusterDisks(start, end)
+		if err != nil {
+			log.Warnf("CostModel.ComputeAllocation: failed to compute local disks, using default local storage price: %s", err)
+		}
+		localDiskCosts = localDiskCostsPerGiBHr(diskMap)
+	}
+
+	cm.applyNodesToPod(podMap, nodeMap, localDiskCosts)
 
 	// (3) Build out AllocationSet from Pod map
 	for _, pod := range podMap {
@@ -691,3 +864,70 @@ func (cm *CostModel) computeAllocation(start, end time.Time, resolution time.Dur
 
 	return allocSet, nil
 }
+
+// applyClusterSnapshots resolves the controllers and labels of pods which were
+// deleted before Prometheus recorded them, using, for each pod, the latest
+// cluster snapshot taken during its lifetime, or, failing that, the first
+// taken after it, as the pod may have run briefly past its last sample.
+func (cm *CostModel) applyClusterSnapshots(podMap map[podKey]*pod, start, end time.Time) {
+	if cm.ClusterSnapshots == nil || len(podMap) == 0 {
+		return
+	}
+
+	snapshotTimes, err := cm.ClusterSnapshots.Snapshots()
+	if err != nil {
+		log.Warnf("CostModel.ComputeAllocation: failed to list cluster snapshots: %s", err)
+		return
+	}
+	if len(snapshotTimes) == 0 {
+		return
+	}
+
+	clusterID := env.GetClusterID()
+
+	// Group the pods of the local cluster by the snapshot which resolves them
+	podsBySnapshot := map[time.Time]map[podKey]*pod{}
+	for key, thisPod := range podMap {
+		if key.Cluster != clusterID {
+			continue
+		}
+
+		podStart, podEnd := thisPod.Start, thisPod.End
+		if podStart.IsZero() {
+			podStart = start
+		}
+		if podEnd.IsZero() {
+			podEnd = end
+		}
+
+		// i is the index of the first snapshot after the pod ended
+		i := sort.Search(len(snapshotTimes), func(i int) bool {
+			return snapshotTimes[i].After(podEnd)
+		})
+		var snapshotTime time.Time
+		if i > 0 && !snapshotTimes[i-1].Before(podStart) {
+			snapshotTime = snapshotTimes[i-1]
+		} else if i < len(snapshotTimes) {
+			snapshotTime = snapshotTimes[i]
+		} else {
+			continue
+		}
+
+		if _, ok := podsBySnapshot[snapshotTime]; !ok {
+			podsBySnapshot[snapshotTime] = map[podKey]*pod{}
+		}
+		podsBySnapshot[snapshotTime][key] = thisPod
+	}
+
+	for snapshotTime, pods := range podsBySnapshot {
+		snapshot, err := cm.ClusterSnapshots.ClusterCacheAt(snapshotTime)
+		if err != nil {
+			log.Warnf("CostModel.ComputeAllocation: failed to load cluster snapshot: %s", err)
+			return
+		}
+
+		if n := applyClusterCacheMetadata(pods, snapshot, clusterID); n > 0 {
+			log.Debugf("CostModel.ComputeAllocation: resolved metadata for %d pods from cluster snapshot", n)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package costmodel
+
+import (
+	"math"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/prom"
+)
+
+// applyEphemeralStorage sets the average ephemeral storage requested by each
+// container, from kube-state-metrics, and used, from cAdvisor filesystem
+// usage, which covers its writable layer, logs and emptyDir volumes. Like
+// RAM, ephemeral storage is charged by the greater of request and usage.
+func applyEphemeralStorage(podMap map[podKey]*pod, resEphemeralStorageRequests, resEphemeralStorageUsageAvg []*prom.QueryResult, podUIDKeyMap map[podKey][]podKey) {
+	allocs := map[*kubecost.Allocation]bool{}
+
+	for _, res := range resEphemeralStorageRequests {
+		key, err := resultPodKey(res, env.GetPromClusterLabel(), "namespace")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: ephemeral storage request result missing field: %s", err)
+			continue
+		}
+
+		container, err := res.GetString("container")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: ephemeral storage request query result missing 'container': %s", key)
+			continue
+		}
+
+		for _, thisPod := range resultPods(podMap, key, podUIDKeyMap) {
+			if _, ok := thisPod.Allocations[container]; !ok {
+				thisPod.appendContainer(container)
+			}
+
+			alloc := thisPod.Allocations[container]
+			alloc.EphemeralStorageBytesRequestAverage = res.Values[0].Value
+			allocs[alloc] = true
+		}
+	}
+
+	for _, res := range resEphemeralStorageUsageAvg {
+		key, err := resultPodKey(res, env.GetPromClusterLabel(), "namespace")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: ephemeral storage usage result missing field: %s", err)
+			continue
+		}
+
+		container, err := res.GetString("container")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: ephemeral storage usage query result missing 'container': %s", key)
+			continue
+		}
+
+		for _, thisPod := range resultPods(podMap, key, podUIDKeyMap) {
+			if _, ok := thisPod.Allocations[container]; !ok {
+				thisPod.appendContainer(container)
+			}
+
+			alloc := thisPod.Allocations[container]
+			alloc.EphemeralStorageBytesUsageAverage = res.Values[0].Value
+			allocs[alloc] = true
+		}
+	}
+
+	for alloc := range allocs {
+		bytes := math.Max(alloc.EphemeralStorageBytesRequestAverage, alloc.EphemeralStorageBytesUsageAverage)
+		alloc.EphemeralStorageByteHours = bytes * (alloc.Minutes() / 60.0)
+	}
+}
@@ -0,0 +1,92 @@
+package costmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/config"
+	"github.com/opencost/opencost/pkg/util"
+)
+
+// Responses recorded from a Prometheus scraping kube-state-metrics and
+// cAdvisor, for a container using less than it requested and one using more.
+const (
+	recordedEphemeralStorageRequests = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"cluster_id":"cluster1","container":"container1","namespace":"namespace1","node":"node1","pod":"pod1"},"value":[1672617600,"2147483648"]},
+		{"metric":{"cluster_id":"cluster1","container":"container1","namespace":"namespace1","node":"node1","pod":"pod2"},"value":[1672617600,"1073741824"]}
+	]}}`
+
+	recordedEphemeralStorageUsageAvg = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"cluster_id":"cluster1","container":"container1","namespace":"namespace1","pod":"pod1"},"value":[1672617600,"536870912"]},
+		{"metric":{"cluster_id":"cluster1","container":"container1","namespace":"namespace1","pod":"pod2"},"value":[1672617600,"4294967296"]}
+	]}}`
+)
+
+func TestApplyEphemeralStorage(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	podMap := newGPUTestPodMap(start, 10, "pod1", "pod2")
+
+	applyEphemeralStorage(podMap, recordedQueryResults(t, recordedEphemeralStorageRequests), recordedQueryResults(t, recordedEphemeralStorageUsageAvg), map[podKey][]podKey{})
+
+	gib := 1024.0 * 1024.0 * 1024.0
+
+	// Charged by request, when usage is below request
+	alloc := podMap[newPodKey("cluster1", "namespace1", "pod1")].Allocations["container1"]
+	if !util.IsApproximately(alloc.EphemeralStorageByteHours, 20.0*gib) {
+		t.Fatalf("expected 20 GiB-hours; got %f", alloc.EphemeralStorageByteHours/gib)
+	}
+	if !util.IsApproximately(alloc.EphemeralStorageEfficiency(), 0.25) {
+		t.Fatalf("expected efficiency of 0.25; got %f", alloc.EphemeralStorageEfficiency())
+	}
+
+	// Charged by usage, when usage exceeds request
+	alloc = podMap[newPodKey("cluster1", "namespace1", "pod2")].Allocations["container1"]
+	if !util.IsApproximately(alloc.EphemeralStorageByteHours, 40.0*gib) {
+		t.Fatalf("expected 40 GiB-hours; got %f", alloc.EphemeralStorageByteHours/gib)
+	}
+	if !util.IsApproximately(alloc.EphemeralStorageBytes(), 4.0*gib) {
+		t.Fatalf("expected 4 GiB; got %f", alloc.EphemeralStorageBytes()/gib)
+	}
+}
+
+func TestApplyNodesToPod_EphemeralStorage(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	podMap := newGPUTestPodMap(start, 10, "pod1", "pod2")
+
+	applyEphemeralStorage(podMap, recordedQueryResults(t, recordedEphemeralStorageRequests), recordedQueryResults(t, recordedEphemeralStorageUsageAvg), map[podKey][]podKey{})
+
+	alloc1 := podMap[newPodKey("cluster1", "namespace1", "pod1")].Allocations["container1"]
+	alloc1.Properties.Cluster, alloc1.Properties.Node = "cluster1", "node1"
+	alloc2 := podMap[newPodKey("cluster1", "namespace1", "pod2")].Allocations["container1"]
+	alloc2.Properties.Cluster, alloc2.Properties.Node = "cluster1", "node2"
+
+	// The local disk of node1 is 100 GiB, costing 2.0 over 10 hours, so 0.002
+	// per GiB-hour. The local disk of node2 has no cost.
+	gib := 1024.0 * 1024.0 * 1024.0
+	diskMap := map[DiskIdentifier]*Disk{
+		{"cluster1", "node1"}: {Cluster: "cluster1", Name: "node1", Local: true, Bytes: 100 * gib, Minutes: 600, Cost: 2.0},
+		{"cluster1", "node2"}: {Cluster: "cluster1", Name: "node2", Local: true, Bytes: 100 * gib, Minutes: 600},
+		{"cluster1", "pv1"}:   {Cluster: "cluster1", Name: "pv1", Bytes: 100 * gib, Minutes: 600, Cost: 5.0},
+	}
+	localDiskCosts := localDiskCostsPerGiBHr(diskMap)
+	if len(localDiskCosts) != 1 || !util.IsApproximately(localDiskCosts[newNodeKey("cluster1", "node1")], 0.002) {
+		t.Fatalf("expected a local disk cost of 0.002 for node1 only; got %v", localDiskCosts)
+	}
+
+	cm := &CostModel{
+		Provider: &cloud.CustomProvider{
+			Config: cloud.NewProviderConfig(config.NewConfigFileManager(nil), "fakeFile"),
+		},
+	}
+	cm.applyNodesToPod(podMap, map[nodeKey]*nodePricing{}, localDiskCosts)
+
+	if !util.IsApproximately(alloc1.EphemeralStorageCost, 20.0*0.002) {
+		t.Fatalf("expected ephemeral storage cost of %f; got %f", 20.0*0.002, alloc1.EphemeralStorageCost)
+	}
+
+	// Nodes without a local disk cost fall back to the default price
+	if !util.IsApproximately(alloc2.EphemeralStorageCost, 40.0*localStorageCostPerGiBHr) {
+		t.Fatalf("expected ephemeral storage cost of %f; got %f", 40.0*localStorageCostPerGiBHr, alloc2.EphemeralStorageCost)
+	}
+}
@@ -0,0 +1,224 @@
+package costmodel
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/prom"
+)
+
+// migProfileRegex matches the compute and memory of a MIG profile from the
+// resource name reported by kube-state-metrics, in which "nvidia.com/mig-1g.5gb"
+// becomes "nvidia_com_mig_1g_5gb".
+var migProfileRegex = regexp.MustCompile(`mig_(\d+)g_(\d+)gb`)
+
+// migSlicesPerGPU is the number of compute slices of a GPU which supports MIG.
+// The A100 and H100 are partitioned in sevenths.
+const migSlicesPerGPU = 7.0
+
+// migProfileFractions are the fractions of a GPU for MIG profiles of GPUs
+// which are not partitioned in sevenths, keyed by "<compute>g_<memory>gb".
+var migProfileFractions = map[string]float64{
+	// A30
+	"1g_6gb":  0.25,
+	"2g_12gb": 0.5,
+	"4g_24gb": 1.0,
+}
+
+// migFraction returns the fraction of its parent GPU provided by the MIG
+// resource with the given name, e.g. 1/7 for "nvidia_com_mig_1g_5gb". Media
+// extension profiles (e.g. "nvidia_com_mig_1g_5gb_me") are priced as their
+// base profile.
+func migFraction(resource string) (float64, error) {
+	match := migProfileRegex.FindStringSubmatch(resource)
+	if match == nil {
+		return 0.0, fmt.Errorf("unrecognized MIG resource %q", resource)
+	}
+
+	if fraction, ok := migProfileFractions[fmt.Sprintf("%sg_%sgb", match[1], match[2])]; ok {
+		return fraction, nil
+	}
+
+	slices, err := strconv.ParseFloat(match[1], 64)
+	if err != nil || slices <= 0 {
+		return 0.0, fmt.Errorf("invalid MIG resource %q", resource)
+	}
+
+	if slices >= migSlicesPerGPU {
+		return 1.0, nil
+	}
+	return slices / migSlicesPerGPU, nil
+}
+
+// resultPods returns the pods matching the given key, resolving pods by UID
+// when pod UIDs are being ingested.
+func resultPods(podMap map[podKey]*pod, key podKey, podUIDKeyMap map[podKey][]podKey) []*pod {
+	if thisPod, ok := podMap[key]; ok {
+		return []*pod{thisPod}
+	}
+
+	var pods []*pod
+	for _, uidKey := range podUIDKeyMap[key] {
+		if thisPod, ok := podMap[uidKey]; ok {
+			pods = append(pods, thisPod)
+		}
+	}
+	return pods
+}
+
+// applyGPUsMIGRequested adds the GPU hours of MIG slices requested by each
+// container, priced as fractions of their parent GPU, to the GPU hours
+// requested as whole GPUs.
+func applyGPUsMIGRequested(podMap map[podKey]*pod, resGPUsMIGRequested []*prom.QueryResult, podUIDKeyMap map[podKey][]podKey) {
+	for _, res := range resGPUsMIGRequested {
+		key, err := resultPodKey(res, env.GetPromClusterLabel(), "namespace")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: MIG request result missing field: %s", err)
+			continue
+		}
+
+		container, err := res.GetString("container")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: MIG request query result missing 'container': %s", key)
+			continue
+		}
+
+		resource, err := res.GetString("resource")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: MIG request query result missing 'resource': %s", key)
+			continue
+		}
+
+		fraction, err := migFraction(resource)
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: %s", err)
+			continue
+		}
+
+		for _, thisPod := range resultPods(podMap, key, podUIDKeyMap) {
+			if _, ok := thisPod.Allocations[container]; !ok {
+				thisPod.appendContainer(container)
+			}
+
+			hrs := thisPod.Allocations[container].Minutes() / 60.0
+			thisPod.Allocations[container].GPUHours += res.Values[0].Value * fraction * hrs
+		}
+	}
+}
+
+// gpuKey identifies a physical GPU by its cluster and UUID.
+type gpuKey struct {
+	Cluster string
+	UUID    string
+}
+
+// gpuUsage is the utilization of a GPU by a single container.
+type gpuUsage struct {
+	alloc       *kubecost.Allocation
+	utilization float64
+}
+
+// applyGPUUsage sets the average GPU and GPU memory usage of each container
+// from DCGM exporter metrics, where utilization is a percentage of a GPU and
+// memory used is in MiB. If splitByUtilization is true, the GPU hours of each
+// container with usage are replaced by its share of each GPU it ran on, in
+// proportion to its utilization of the GPU among all containers sharing it.
+// Containers without usage keep the GPU hours they requested.
+func applyGPUUsage(podMap map[podKey]*pod, resGPUUtilization, resGPUMemoryUsed []*prom.QueryResult, podUIDKeyMap map[podKey][]podKey, splitByUtilization bool) {
+	usageByGPU := map[gpuKey][]*gpuUsage{}
+
+	for _, res := range resGPUUtilization {
+		key, err := resultPodKey(res, env.GetPromClusterLabel(), "namespace")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: GPU utilization result missing field: %s", err)
+			continue
+		}
+
+		container, err := res.GetString("container")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: GPU utilization query result missing 'container': %s", key)
+			continue
+		}
+
+		uuid, _ := res.GetString("UUID")
+		gpu := gpuKey{Cluster: key.Cluster, UUID: uuid}
+
+		// The utilization of containers which are not being computed, e.g.
+		// because they were filtered out, still counts towards the total
+		// utilization of the GPU.
+		pods := resultPods(podMap, key, podUIDKeyMap)
+		if len(pods) == 0 {
+			usageByGPU[gpu] = append(usageByGPU[gpu], &gpuUsage{utilization: res.Values[0].Value})
+			continue
+		}
+
+		for _, thisPod := range pods {
+			if _, ok := thisPod.Allocations[container]; !ok {
+				thisPod.appendContainer(container)
+			}
+
+			alloc := thisPod.Allocations[container]
+			alloc.GPUUsageAverage += res.Values[0].Value / 100.0
+			usageByGPU[gpu] = append(usageByGPU[gpu], &gpuUsage{
+				alloc:       alloc,
+				utilization: res.Values[0].Value,
+			})
+		}
+	}
+
+	for _, res := range resGPUMemoryUsed {
+		key, err := resultPodKey(res, env.GetPromClusterLabel(), "namespace")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: GPU memory used result missing field: %s", err)
+			continue
+		}
+
+		container, err := res.GetString("container")
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel.ComputeAllocation: GPU memory used query result missing 'container': %s", key)
+			continue
+		}
+
+		for _, thisPod := range resultPods(podMap, key, podUIDKeyMap) {
+			if _, ok := thisPod.Allocations[container]; !ok {
+				thisPod.appendContainer(container)
+			}
+
+			thisPod.Allocations[container].GPUMemoryUsageAverage += res.Values[0].Value * 1024.0 * 1024.0
+		}
+	}
+
+	if !splitByUtilization {
+		return
+	}
+
+	// Sum each container's share of the GPUs it ran on, splitting GPUs which
+	// were not utilized at all evenly among the containers sharing them.
+	gpuShares := map[*kubecost.Allocation]float64{}
+	for _, usages := range usageByGPU {
+		total := 0.0
+		for _, usage := range usages {
+			total += usage.utilization
+		}
+
+		for _, usage := range usages {
+			if usage.alloc == nil {
+				continue
+			}
+
+			if total > 0.0 {
+				gpuShares[usage.alloc] += usage.utilization / total
+			} else {
+				gpuShares[usage.alloc] += 1.0 / float64(len(usages))
+			}
+		}
+	}
+
+	for alloc, share := range gpuShares {
+		alloc.GPUHours = share * alloc.Minutes() / 60.0
+	}
+}
@@ -0,0 +1,144 @@
+package costmodel
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/util"
+)
+
+// Responses recorded from a Prometheus scraping kube-state-metrics and the
+// DCGM exporter on a node with an A100 partitioned by MIG and a T4 shared by
+// time-slicing between pod2 and pod3.
+const (
+	recordedGPUsMIGRequested = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"cluster_id":"cluster1","container":"container1","namespace":"namespace1","node":"node1","pod":"pod1","resource":"nvidia_com_mig_1g_5gb"},"value":[1672617600,"2"]},
+		{"metric":{"cluster_id":"cluster1","container":"container1","namespace":"namespace1","node":"node1","pod":"pod1","resource":"nvidia_com_mig_3g_20gb"},"value":[1672617600,"1"]},
+		{"metric":{"cluster_id":"cluster1","container":"container1","namespace":"namespace1","node":"node1","pod":"pod4","resource":"nvidia_com_mig_2g_12gb"},"value":[1672617600,"1"]}
+	]}}`
+
+	recordedGPUUtilization = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"UUID":"GPU-8f6a3c2e","cluster_id":"cluster1","container":"container1","namespace":"namespace1","pod":"pod2"},"value":[1672617600,"60"]},
+		{"metric":{"UUID":"GPU-8f6a3c2e","cluster_id":"cluster1","container":"container1","namespace":"namespace1","pod":"pod3"},"value":[1672617600,"20"]},
+		{"metric":{"UUID":"GPU-8f6a3c2e","cluster_id":"cluster1","container":"container1","namespace":"namespace2","pod":"pod5"},"value":[1672617600,"20"]}
+	]}}`
+
+	recordedGPUMemoryUsed = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"UUID":"GPU-8f6a3c2e","cluster_id":"cluster1","container":"container1","namespace":"namespace1","pod":"pod2"},"value":[1672617600,"4096"]},
+		{"metric":{"UUID":"GPU-8f6a3c2e","cluster_id":"cluster1","container":"container1","namespace":"namespace1","pod":"pod3"},"value":[1672617600,"1024"]}
+	]}}`
+)
+
+func recordedQueryResults(t *testing.T, recorded string) []*prom.QueryResult {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(recorded), &raw); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	qrs := prom.NewQueryResults("recorded", raw)
+	if qrs.Error != nil {
+		t.Fatalf("unexpected error: %s", qrs.Error)
+	}
+	return qrs.Results
+}
+
+func newGPUTestPodMap(start time.Time, hours int, pods ...string) map[podKey]*pod {
+	end := start.Add(time.Duration(hours) * time.Hour)
+
+	podMap := map[podKey]*pod{}
+	for _, name := range pods {
+		key := newPodKey("cluster1", "namespace1", name)
+		podMap[key] = &pod{
+			Window:      kubecost.NewClosedWindow(start, end),
+			Start:       start,
+			End:         end,
+			Key:         key,
+			Allocations: map[string]*kubecost.Allocation{},
+		}
+	}
+	return podMap
+}
+
+func TestMIGFraction(t *testing.T) {
+	cases := map[string]float64{
+		"nvidia_com_mig_1g_5gb":    1.0 / 7.0,
+		"nvidia_com_mig_3g_20gb":   3.0 / 7.0,
+		"nvidia_com_mig_7g_80gb":   1.0,
+		"nvidia_com_mig_1g_5gb_me": 1.0 / 7.0,
+		"nvidia_com_mig_1g_6gb":    0.25,
+		"nvidia_com_mig_4g_24gb":   1.0,
+	}
+
+	for resource, expected := range cases {
+		fraction, err := migFraction(resource)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", resource, err)
+		}
+		if !util.IsApproximately(fraction, expected) {
+			t.Fatalf("%s: expected %f; got %f", resource, expected, fraction)
+		}
+	}
+
+	if _, err := migFraction("nvidia_com_gpu"); err == nil {
+		t.Fatalf("expected error for non-MIG resource")
+	}
+}
+
+func TestApplyGPUsMIGRequested(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	podMap := newGPUTestPodMap(start, 7, "pod1")
+
+	applyGPUsMIGRequested(podMap, recordedQueryResults(t, recordedGPUsMIGRequested), map[podKey][]podKey{})
+
+	// 2 x 1g.5gb and 1 x 3g.20gb is 5/7 of a GPU, for 7 hours
+	alloc := podMap[newPodKey("cluster1", "namespace1", "pod1")].Allocations["container1"]
+	if !util.IsApproximately(alloc.GPUHours, 5.0) {
+		t.Fatalf("expected 5.0 GPU hours; got %f", alloc.GPUHours)
+	}
+}
+
+func TestApplyGPUUsage(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	resUtil := recordedQueryResults(t, recordedGPUUtilization)
+	resMem := recordedQueryResults(t, recordedGPUMemoryUsed)
+
+	// By request, usage is recorded but GPU hours are unchanged
+	podMap := newGPUTestPodMap(start, 10, "pod2", "pod3")
+	podMap[newPodKey("cluster1", "namespace1", "pod2")].appendContainer("container1")
+	podMap[newPodKey("cluster1", "namespace1", "pod2")].Allocations["container1"].GPUHours = 10.0
+
+	applyGPUUsage(podMap, resUtil, resMem, map[podKey][]podKey{}, false)
+
+	alloc := podMap[newPodKey("cluster1", "namespace1", "pod2")].Allocations["container1"]
+	if !util.IsApproximately(alloc.GPUHours, 10.0) {
+		t.Fatalf("expected 10.0 GPU hours; got %f", alloc.GPUHours)
+	}
+	if !util.IsApproximately(alloc.GPUUsageAverage, 0.6) {
+		t.Fatalf("expected GPU usage of 0.6; got %f", alloc.GPUUsageAverage)
+	}
+	if !util.IsApproximately(alloc.GPUMemoryUsageAverage, 4096.0*1024.0*1024.0) {
+		t.Fatalf("expected GPU memory usage of 4GiB; got %f", alloc.GPUMemoryUsageAverage)
+	}
+	if !util.IsApproximately(alloc.GPUEfficiency(), 0.6) {
+		t.Fatalf("expected GPU efficiency of 0.6; got %f", alloc.GPUEfficiency())
+	}
+
+	// By utilization, the time-sliced GPU is split among the pods sharing it,
+	// including pod5, which is not being computed
+	podMap = newGPUTestPodMap(start, 10, "pod2", "pod3")
+	podMap[newPodKey("cluster1", "namespace1", "pod2")].appendContainer("container1")
+	podMap[newPodKey("cluster1", "namespace1", "pod2")].Allocations["container1"].GPUHours = 10.0
+
+	applyGPUUsage(podMap, resUtil, resMem, map[podKey][]podKey{}, true)
+
+	expected := map[string]float64{"pod2": 6.0, "pod3": 2.0}
+	for name, gpuHours := range expected {
+		alloc := podMap[newPodKey("cluster1", "namespace1", name)].Allocations["container1"]
+		if !util.IsApproximately(alloc.GPUHours, gpuHours) {
+			t.Fatalf("%s: expected %f GPU hours; got %f", name, gpuHours, alloc.GPUHours)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package costmodel
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"strconv"
@@ -8,11 +9,14 @@ import (
 	"time"
 
 	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/clustercache"
 	"github.com/opencost/opencost/pkg/env"
 	"github.com/opencost/opencost/pkg/kubecost"
 	"github.com/opencost/opencost/pkg/log"
 	"github.com/opencost/opencost/pkg/prom"
 	"github.com/opencost/opencost/pkg/util/timeutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 )
 
@@ -22,14 +26,14 @@ const MAX_CPU_CAP = 512
 
 /* Pod Helpers */
 
-func (cm *CostModel) buildPodMap(window kubecost.Window, resolution, maxBatchSize time.Duration, podMap map[podKey]*pod, clusterStart, clusterEnd map[string]time.Time, ingestPodUID bool, podUIDKeyMap map[podKey][]podKey) error {
+func (cm *CostModel) buildPodMap(traceCtx context.Context, window kubecost.Window, resolution, maxBatchSize time.Duration, podMap map[podKey]*pod, clusterStart, clusterEnd map[string]time.Time, ingestPodUID bool, podUIDKeyMap map[podKey][]podKey, pushdown *allocationPushdown) error {
 	// Assumes that window is positive and closed
 	start, end := *window.Start(), *window.End()
 
 	// Convert resolution duration to a query-ready string
 	resStr := timeutil.DurationString(resolution)
 
-	ctx := prom.NewNamedContext(cm.PrometheusClient, prom.AllocationContextName)
+	ctx := prom.NewNamedContext(cm.PrometheusClient, prom.AllocationContextName).WithContext(traceCtx)
 
 	// Query for (start, end) by (pod, namespace, cluster) over the given
 	// window, using the given resolution, and if necessary in batches no
@@ -66,12 +70,14 @@ func (cm *CostModel) buildPodMap(window kubecost.Window, resolution, maxBatchSiz
 
 			// Submit and profile query
 
+			queries := newAllocationQueries(durStr, resStr, "")
+
 			var queryPods string
 			// If ingesting UIDs, avg on them
 			if ingestPodUID {
-				queryPods = fmt.Sprintf(queryFmtPodsUID, env.GetPromClusterLabel(), durStr, resStr)
+				queryPods = queries.query(allocationQueryPodsUID, pushdown, pushdownNamespaceLabels)
 			} else {
-				queryPods = fmt.Sprintf(queryFmtPods, env.GetPromClusterLabel(), durStr, resStr)
+				queryPods = queries.query(allocationQueryPods, pushdown, pushdownNamespaceLabels)
 			}
 
 			queryProfile := time.Now()
@@ -713,7 +719,9 @@ func applyNetworkTotals(podMap map[podKey]*pod, resNetworkTransferBytes []*prom.
 	}
 }
 
-func applyNetworkAllocation(podMap map[podKey]*pod, resNetworkGiB []*prom.QueryResult, resNetworkCostPerGiB []*prom.QueryResult, podUIDKeyMap map[podKey][]podKey, networkCostSubType string) {
+// networkCostPerGiBByCluster returns the cost per GiB of a type of network
+// egress, keyed by cluster.
+func networkCostPerGiBByCluster(resNetworkCostPerGiB []*prom.QueryResult) map[string]float64 {
 	costPerGiBByCluster := map[string]float64{}
 
 	for _, res := range resNetworkCostPerGiB {
@@ -725,6 +733,12 @@ func applyNetworkAllocation(podMap map[podKey]*pod, resNetworkGiB []*prom.QueryR
 		costPerGiBByCluster[cluster] = res.Values[0].Value
 	}
 
+	return costPerGiBByCluster
+}
+
+func applyNetworkAllocation(podMap map[podKey]*pod, resNetworkGiB []*prom.QueryResult, resNetworkCostPerGiB []*prom.QueryResult, podUIDKeyMap map[podKey][]podKey, networkCostSubType string) {
+	costPerGiBByCluster := networkCostPerGiBByCluster(resNetworkCostPerGiB)
+
 	for _, res := range resNetworkGiB {
 		podKey, err := resultPodKey(res, env.GetPromClusterLabel(), "namespace")
 		if err != nil {
@@ -806,6 +820,25 @@ func resToNodeLabels(resNodeLabels []*prom.QueryResult) map[nodeKey]map[string]s
 	return nodeLabels
 }
 
+// resToNodePools returns the node pool of each node, detected from all of its
+// labels rather than only those in the node label include list.
+func resToNodePools(resNodeLabels []*prom.QueryResult) map[nodeKey]string {
+	nodePools := map[nodeKey]string{}
+
+	for _, res := range resNodeLabels {
+		nodeKey, err := resultNodeKey(res, env.GetPromClusterLabel(), "node")
+		if err != nil {
+			continue
+		}
+
+		if nodePool := kubecost.NodePoolFromLabels(res.GetLabels()); nodePool != "" {
+			nodePools[nodeKey] = nodePool
+		}
+	}
+
+	return nodePools
+}
+
 func resToNamespaceLabels(resNamespaceLabels []*prom.QueryResult) map[namespaceKey]map[string]string {
 	namespaceLabels := map[namespaceKey]map[string]string{}
 
@@ -941,9 +974,12 @@ func applyLabels(podMap map[podKey]*pod, nodeLabels map[nodeKey]map[string]strin
 
 			nsKey := podKey.namespaceKey
 			if labels, ok := namespaceLabels[nsKey]; ok {
+				allocNamespaceLabels := make(map[string]string, len(labels))
 				for k, v := range labels {
 					allocLabels[k] = v
+					allocNamespaceLabels[k] = v
 				}
+				alloc.Properties.NamespaceLabels = allocNamespaceLabels
 			}
 
 			if labels, ok := podLabels[podKey]; ok {
@@ -957,6 +993,23 @@ func applyLabels(podMap map[podKey]*pod, nodeLabels map[nodeKey]map[string]strin
 	}
 }
 
+func applyNodePools(podMap map[podKey]*pod, nodePools map[nodeKey]string) {
+	if len(nodePools) == 0 {
+		return
+	}
+
+	for _, pod := range podMap {
+		nodePool, ok := nodePools[newNodeKey(pod.Key.Cluster, pod.Node)]
+		if !ok {
+			continue
+		}
+
+		for _, alloc := range pod.Allocations {
+			alloc.Properties.NodePool = nodePool
+		}
+	}
+}
+
 func applyAnnotations(podMap map[podKey]*pod, namespaceAnnotations map[string]map[string]string, podAnnotations map[podKey]map[string]string) {
 	for key, pod := range podMap {
 		for _, alloc := range pod.Allocations {
@@ -967,9 +1020,12 @@ func applyAnnotations(podMap map[podKey]*pod, namespaceAnnotations map[string]ma
 			// Apply namespace annotations first, then pod annotations so that
 			// pod labels overwrite namespace labels.
 			if labels, ok := namespaceAnnotations[key.Namespace]; ok {
+				allocNamespaceAnnotations := make(map[string]string, len(labels))
 				for k, v := range labels {
 					allocAnnotations[k] = v
+					allocNamespaceAnnotations[k] = v
 				}
+				alloc.Properties.NamespaceAnnotations = allocNamespaceAnnotations
 			}
 			if labels, ok := podAnnotations[key]; ok {
 				for k, v := range labels {
@@ -1225,6 +1281,163 @@ func applyControllersToPods(podMap map[podKey]*pod, podControllerMap map[podKey]
 	}
 }
 
+// applyClusterCacheMetadata fills in the controller and labels of pods which
+// could not be resolved from Prometheus, using the pod objects in a cluster
+// cache or snapshot. Only pods belonging to the given cluster are considered.
+// Labels already set are never overwritten, and controllers are only replaced
+// when the cache refines them, e.g. a job created by a CronJob. Returns the
+// number of pods which were updated.
+func applyClusterCacheMetadata(podMap map[podKey]*pod, cache clustercache.ClusterCache, clusterID string) int {
+	owners := newClusterCacheOwners(cache)
+
+	updated := 0
+	for _, p := range cache.GetAllPods() {
+		key := newPodKey(clusterID, p.Namespace, p.Name)
+		thisPod, ok := podMap[key]
+		if !ok {
+			continue
+		}
+
+		controllerKind, controller := owners.podController(p)
+
+		changed := false
+		for _, alloc := range thisPod.Allocations {
+			if shouldReplaceController(alloc.Properties, controllerKind, controller) {
+				alloc.Properties.ControllerKind = controllerKind
+				alloc.Properties.Controller = controller
+				changed = true
+			}
+
+			if len(p.Labels) > 0 {
+				if alloc.Properties.Labels == nil {
+					alloc.Properties.Labels = map[string]string{}
+				}
+				for k, v := range p.Labels {
+					name := prom.SanitizeLabelName(k)
+					if _, ok := alloc.Properties.Labels[name]; !ok {
+						alloc.Properties.Labels[name] = v
+						changed = true
+					}
+				}
+			}
+		}
+
+		if changed {
+			updated++
+		}
+	}
+
+	// Jobs whose pods are no longer in the cache may still have been created
+	// by a CronJob which is, in which case the controller kind is refined.
+	for key, thisPod := range podMap {
+		if key.Cluster != clusterID {
+			continue
+		}
+		for _, alloc := range thisPod.Allocations {
+			if alloc.Properties.ControllerKind != "job" {
+				continue
+			}
+			if _, ok := owners.cronJobs[key.Namespace+"/"+alloc.Properties.Controller]; ok {
+				alloc.Properties.ControllerKind = "cronjob"
+			}
+		}
+	}
+
+	return updated
+}
+
+// shouldReplaceController returns true if the controller resolved from a
+// cluster cache should replace the controller already set on the properties.
+func shouldReplaceController(props *kubecost.AllocationProperties, controllerKind, controller string) bool {
+	if controller == "" {
+		return false
+	}
+	if props.Controller == "" {
+		return true
+	}
+
+	// CronJob and Rollout ownership can't be determined from Prometheus
+	// metrics alone, which report the Job or ReplicaSet instead.
+	switch controllerKind {
+	case "cronjob":
+		return props.ControllerKind == "job" && props.Controller == controller
+	case "rollout":
+		return props.ControllerKind == "replicaset"
+	}
+	return false
+}
+
+// clusterCacheOwners indexes the controlling owners of the intermediate
+// controllers in a cluster cache, used to resolve the top level controller of
+// a pod.
+type clusterCacheOwners struct {
+	replicaSetOwners map[string]metav1.OwnerReference
+	jobOwners        map[string]metav1.OwnerReference
+	cronJobs         map[string]struct{}
+}
+
+func newClusterCacheOwners(cache clustercache.ClusterCache) *clusterCacheOwners {
+	owners := &clusterCacheOwners{
+		replicaSetOwners: map[string]metav1.OwnerReference{},
+		jobOwners:        map[string]metav1.OwnerReference{},
+		cronJobs:         map[string]struct{}{},
+	}
+
+	for _, rs := range cache.GetAllReplicaSets() {
+		if owner := metav1.GetControllerOf(rs); owner != nil {
+			owners.replicaSetOwners[rs.Namespace+"/"+rs.Name] = *owner
+		}
+	}
+	for _, job := range cache.GetAllJobs() {
+		if owner := metav1.GetControllerOf(job); owner != nil {
+			owners.jobOwners[job.Namespace+"/"+job.Name] = *owner
+		}
+	}
+	for _, cronJob := range cache.GetAllCronJobs() {
+		owners.cronJobs[cronJob.Namespace+"/"+cronJob.Name] = struct{}{}
+	}
+
+	return owners
+}
+
+// podController returns the controller kind and name for a pod object using
+// its owner references, following ReplicaSets to their Deployments or
+// Rollouts and Jobs to their CronJobs, consistent with the controller
+// resolution done with Prometheus metrics.
+func (cco *clusterCacheOwners) podController(p *v1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(p)
+	if owner == nil {
+		return "", ""
+	}
+
+	switch owner.Kind {
+	case "ReplicaSet":
+		if rsOwner, ok := cco.replicaSetOwners[p.Namespace+"/"+owner.Name]; ok {
+			switch rsOwner.Kind {
+			case "Deployment":
+				return "deployment", rsOwner.Name
+			case "Rollout":
+				return "rollout", rsOwner.Name
+			}
+		}
+		return "replicaset", owner.Name
+	case "StatefulSet":
+		return "statefulset", owner.Name
+	case "DaemonSet":
+		return "daemonset", owner.Name
+	case "Job":
+		if jobOwner, ok := cco.jobOwners[p.Namespace+"/"+owner.Name]; ok && jobOwner.Kind == "CronJob" {
+			return "cronjob", jobOwner.Name
+		}
+		if match := isCron.FindStringSubmatch(owner.Name); match != nil {
+			return "job", match[1]
+		}
+		return "job", owner.Name
+	}
+
+	return "", ""
+}
+
 /* Service Helpers */
 
 func getServiceLabels(resServiceLabels []*prom.QueryResult) map[serviceKey]map[string]string {
@@ -1554,7 +1767,26 @@ func applyNodeDiscount(nodeMap map[nodeKey]*nodePricing, cm *CostModel) {
 	}
 }
 
-func (cm *CostModel) applyNodesToPod(podMap map[podKey]*pod, nodeMap map[nodeKey]*nodePricing) {
+// localDiskCostsPerGiBHr returns the hourly cost per GiB of the local disk of
+// each node, from the cost of its local disk asset over the window. Nodes
+// whose local disk has no size, duration or cost are omitted.
+func localDiskCostsPerGiBHr(diskMap map[DiskIdentifier]*Disk) map[nodeKey]float64 {
+	costs := map[nodeKey]float64{}
+	for _, disk := range diskMap {
+		if !disk.Local || disk.Bytes <= 0.0 || disk.Minutes <= 0.0 || disk.Cost <= 0.0 {
+			continue
+		}
+
+		gibHours := (disk.Bytes / 1024 / 1024 / 1024) * (disk.Minutes / 60.0)
+		costs[newNodeKey(disk.Cluster, disk.Name)] = disk.Cost / gibHours
+	}
+	return costs
+}
+
+// applyNodesToPod prices the resources of each allocation by the node it ran
+// on. Ephemeral storage is priced by the local disk cost of the node, or by
+// the default local storage price if it is unknown.
+func (cm *CostModel) applyNodesToPod(podMap map[podKey]*pod, nodeMap map[nodeKey]*nodePricing, localDiskCosts map[nodeKey]float64) {
 	for _, pod := range podMap {
 		for _, alloc := range pod.Allocations {
 			cluster := alloc.Properties.Cluster
@@ -1566,6 +1798,12 @@ func (cm *CostModel) applyNodesToPod(podMap map[podKey]*pod, nodeMap map[nodeKey
 			alloc.CPUCost = alloc.CPUCoreHours * node.CostPerCPUHr
 			alloc.RAMCost = (alloc.RAMByteHours / 1024 / 1024 / 1024) * node.CostPerRAMGiBHr
 			alloc.GPUCost = alloc.GPUHours * node.CostPerGPUHr
+
+			localDiskCostPerGiBHr, ok := localDiskCosts[thisNodeKey]
+			if !ok {
+				localDiskCostPerGiBHr = localStorageCostPerGiBHr
+			}
+			alloc.EphemeralStorageCost = (alloc.EphemeralStorageByteHours / 1024 / 1024 / 1024) * localDiskCostPerGiBHr
 		}
 	}
 }
@@ -1729,6 +1967,40 @@ func buildPVMap(resolution time.Duration, pvMap map[pvKey]*pv, resPVCostPerGiBHo
 	}
 }
 
+// applyPVPerformanceCosts records the hourly cost of the IOPS and throughput
+// provisioned for each pv.
+func applyPVPerformanceCosts(pvMap map[pvKey]*pv, resPVIOPSCostPerHr, resPVThroughputCostPerHr []*prom.QueryResult) {
+	for _, res := range resPVIOPSCostPerHr {
+		key, err := resultPVKey(res, env.GetPromClusterLabel(), "volumename")
+		if err != nil {
+			log.Warnf("CostModel.ComputeAllocation: pv IOPS cost query result missing field: %s", err)
+			continue
+		}
+
+		if _, ok := pvMap[key]; !ok {
+			log.Warnf("CostModel.ComputeAllocation: pv IOPS cost result for missing pv: %s", key)
+			continue
+		}
+
+		pvMap[key].IOPSCostPerHour = res.Values[0].Value
+	}
+
+	for _, res := range resPVThroughputCostPerHr {
+		key, err := resultPVKey(res, env.GetPromClusterLabel(), "volumename")
+		if err != nil {
+			log.Warnf("CostModel.ComputeAllocation: pv throughput cost query result missing field: %s", err)
+			continue
+		}
+
+		if _, ok := pvMap[key]; !ok {
+			log.Warnf("CostModel.ComputeAllocation: pv throughput cost result for missing pv: %s", key)
+			continue
+		}
+
+		pvMap[key].ThroughputCostPerHour = res.Values[0].Value
+	}
+}
+
 func applyPVBytes(pvMap map[pvKey]*pv, resPVBytes []*prom.QueryResult) {
 	for _, res := range resPVBytes {
 		key, err := resultPVKey(res, env.GetPromClusterLabel(), "persistentvolume")
@@ -1936,7 +2208,8 @@ func applyPVCsToPods(window kubecost.Window, podMap map[podKey]*pod, podPVCMap m
 				hrs := minutes / 60.0
 
 				gib := pvc.Bytes / 1024 / 1024 / 1024
-				cost := pvc.Volume.CostPerGiBHour * gib * hrs
+				iopsCost, throughputCost := pvc.Volume.performanceCost(hrs)
+				cost := pvc.Volume.CostPerGiBHour*gib*hrs + iopsCost + throughputCost
 				byteHours := pvc.Bytes * hrs
 				coef := getCoefficientFromComponents(coeffComponents)
 
@@ -1955,8 +2228,10 @@ func applyPVCsToPods(window kubecost.Window, podMap map[podKey]*pod, podPVCMap m
 				// would be equal to the values of the original pv
 				count := float64(len(pod.Allocations))
 				alloc.PVs[pvKey] = &kubecost.PVAllocation{
-					ByteHours: byteHours * coef / count,
-					Cost:      cost * coef / count,
+					ByteHours:      byteHours * coef / count,
+					Cost:           cost * coef / count,
+					IOPSCost:       iopsCost * coef / count,
+					ThroughputCost: throughputCost * coef / count,
 				}
 			}
 		}
@@ -1990,11 +2265,14 @@ func applyUnmountedPVs(window kubecost.Window, podMap map[podKey]*pod, pvMap map
 			}
 			gib := pv.Bytes / 1024 / 1024 / 1024
 			hrs := pv.minutes() / 60.0
-			cost := pv.CostPerGiBHour * gib * hrs
+			iopsCost, throughputCost := pv.performanceCost(hrs)
+			cost := pv.CostPerGiBHour*gib*hrs + iopsCost + throughputCost
 			unmountedPVs := kubecost.PVAllocations{
 				thisPVKey: {
-					ByteHours: pv.Bytes * hrs,
-					Cost:      cost,
+					ByteHours:      pv.Bytes * hrs,
+					Cost:           cost,
+					IOPSCost:       iopsCost,
+					ThroughputCost: throughputCost,
 				},
 			}
 			pod.Allocations[kubecost.UnmountedSuffix].PVs = pod.Allocations[kubecost.UnmountedSuffix].PVs.Add(unmountedPVs)
@@ -2021,11 +2299,14 @@ func applyUnmountedPVCs(window kubecost.Window, podMap map[podKey]*pod, pvcMap m
 			// however the pv bytes are what are going to determine cost
 			gib := pvc.Volume.Bytes / 1024 / 1024 / 1024
 			hrs := pvc.Volume.minutes() / 60.0
-			cost := pvc.Volume.CostPerGiBHour * gib * hrs
+			iopsCost, throughputCost := pvc.Volume.performanceCost(hrs)
+			cost := pvc.Volume.CostPerGiBHour*gib*hrs + iopsCost + throughputCost
 			unmountedPVs := kubecost.PVAllocations{
 				thisPVKey: {
-					ByteHours: pvc.Volume.Bytes * hrs,
-					Cost:      cost,
+					ByteHours:      pvc.Volume.Bytes * hrs,
+					Cost:           cost,
+					IOPSCost:       iopsCost,
+					ThroughputCost: throughputCost,
 				},
 			}
 			pod.Allocations[kubecost.UnmountedSuffix].PVs = pod.Allocations[kubecost.UnmountedSuffix].PVs.Add(unmountedPVs)
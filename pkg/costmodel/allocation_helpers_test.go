@@ -1,12 +1,24 @@
 package costmodel
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/clustercache"
+	"github.com/opencost/opencost/pkg/config"
+	"github.com/opencost/opencost/pkg/env"
 	"github.com/opencost/opencost/pkg/kubecost"
 	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/storage"
 	"github.com/opencost/opencost/pkg/util"
-	"testing"
-	"time"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const Ki = 1024
@@ -508,3 +520,194 @@ func TestCalculateStartAndEnd(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyClusterCacheMetadata(t *testing.T) {
+	isController := true
+	ownedBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &isController}}
+	}
+
+	encoded, err := json.Marshal(map[string]interface{}{
+		"pods": []*v1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "rollout-pod", Namespace: "ns", OwnerReferences: ownedBy("ReplicaSet", "web-6d4f"), Labels: map[string]string{"app.kubernetes.io/name": "web"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "cron-pod", Namespace: "ns", OwnerReferences: ownedBy("Job", "backup-27950400")}},
+		},
+		"replicaSets": []*appsv1.ReplicaSet{
+			{ObjectMeta: metav1.ObjectMeta{Name: "web-6d4f", Namespace: "ns", OwnerReferences: ownedBy("Rollout", "web")}},
+		},
+		"jobs": []*batchv1.Job{
+			{ObjectMeta: metav1.ObjectMeta{Name: "backup-27950400", Namespace: "ns", OwnerReferences: ownedBy("CronJob", "backup")}},
+		},
+		"cronJobs": []*batchv1.CronJob{
+			{ObjectMeta: metav1.ObjectMeta{Name: "report", Namespace: "ns"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	store := storage.NewFileStorage(t.TempDir())
+	if err := store.Write("cluster-cache.json", encoded); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cache := clustercache.NewClusterImporter(config.NewConfigFile(store, "cluster-cache.json"))
+	cache.Run()
+	defer cache.Stop()
+
+	newPod := func(name, controllerKind, controller string) *pod {
+		return &pod{
+			Key: newPodKey("cluster1", "ns", name),
+			Allocations: map[string]*kubecost.Allocation{
+				"container": {Properties: &kubecost.AllocationProperties{ControllerKind: controllerKind, Controller: controller}},
+			},
+		}
+	}
+
+	podMap := map[podKey]*pod{
+		newPodKey("cluster1", "ns", "rollout-pod"): newPod("rollout-pod", "", ""),
+		newPodKey("cluster1", "ns", "cron-pod"):    newPod("cron-pod", "job", "backup"),
+		// deleted pod from a Job created by a CronJob which still exists
+		newPodKey("cluster1", "ns", "deleted-pod"): newPod("deleted-pod", "job", "report"),
+	}
+
+	if updated := applyClusterCacheMetadata(podMap, cache, "cluster1"); updated != 2 {
+		t.Errorf("expected 2 pods to be updated, got %d", updated)
+	}
+
+	expected := map[string][2]string{
+		"rollout-pod": {"rollout", "web"},
+		"cron-pod":    {"cronjob", "backup"},
+		"deleted-pod": {"cronjob", "report"},
+	}
+	for name, exp := range expected {
+		props := podMap[newPodKey("cluster1", "ns", name)].Allocations["container"].Properties
+		if props.ControllerKind != exp[0] || props.Controller != exp[1] {
+			t.Errorf("%s: expected controller %s/%s, got %s/%s", name, exp[0], exp[1], props.ControllerKind, props.Controller)
+		}
+	}
+
+	labels := podMap[newPodKey("cluster1", "ns", "rollout-pod")].Allocations["container"].Properties.Labels
+	if labels["app_kubernetes_io_name"] != "web" {
+		t.Errorf("expected sanitized pod labels to be applied, got %v", labels)
+	}
+}
+
+func TestCostModel_ApplyClusterSnapshots(t *testing.T) {
+	isController := true
+	ownedBy := func(name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: "Job", Name: name, Controller: &isController}}
+	}
+
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	// Snapshots every 6h; short-pod only exists in the 06:00 snapshot, which
+	// falls between the start, middle and end of the window, and late-pod
+	// was last sampled before the 24:00 snapshot, but was still running.
+	store := storage.NewFileStorage(t.TempDir())
+	for h, pods := range map[int][]*v1.Pod{
+		0:  {},
+		6:  {{ObjectMeta: metav1.ObjectMeta{Name: "short-pod", Namespace: "ns", OwnerReferences: ownedBy("short")}}},
+		12: {},
+		18: {},
+		24: {{ObjectMeta: metav1.ObjectMeta{Name: "late-pod", Namespace: "ns", OwnerReferences: ownedBy("late")}}},
+	} {
+		data, err := json.Marshal(map[string]interface{}{"pods": pods})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(data)
+		gz.Close()
+
+		ts := start.Add(time.Duration(h) * time.Hour)
+		if err := store.Write(fmt.Sprintf("snapshots/%d.json.gz", ts.Unix()), buf.Bytes()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	cm := &CostModel{
+		ClusterSnapshots: clustercache.NewClusterSnapshotStore(nil, store, "snapshots", time.Hour, 0),
+	}
+
+	clusterID := env.GetClusterID()
+	newPod := func(name string, podStart, podEnd time.Time) *pod {
+		return &pod{
+			Key:   newPodKey(clusterID, "ns", name),
+			Start: podStart,
+			End:   podEnd,
+			Allocations: map[string]*kubecost.Allocation{
+				"container": {Properties: &kubecost.AllocationProperties{}},
+			},
+		}
+	}
+
+	podMap := map[podKey]*pod{
+		newPodKey(clusterID, "ns", "short-pod"): newPod("short-pod", start.Add(5*time.Hour), start.Add(7*time.Hour)),
+		newPodKey(clusterID, "ns", "late-pod"):  newPod("late-pod", start.Add(20*time.Hour), start.Add(23*time.Hour)),
+	}
+
+	cm.applyClusterSnapshots(podMap, start, end)
+
+	for name, controller := range map[string]string{"short-pod": "short", "late-pod": "late"} {
+		props := podMap[newPodKey(clusterID, "ns", name)].Allocations["container"].Properties
+		if props.ControllerKind != "job" || props.Controller != controller {
+			t.Errorf("%s: expected controller job/%s, got %s/%s", name, controller, props.ControllerKind, props.Controller)
+		}
+	}
+}
+
+func TestApplyPVPerformanceCosts(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+	window := kubecost.NewClosedWindow(start, start.Add(24*time.Hour))
+
+	pvMap := map[pvKey]*pv{
+		newPVKey("cluster1", "pv1"): {
+			Cluster:        "cluster1",
+			Name:           "pv1",
+			Start:          start,
+			End:            end,
+			Bytes:          100 * 1024 * 1024 * 1024,
+			CostPerGiBHour: 0.0001,
+		},
+	}
+
+	applyPVPerformanceCosts(pvMap,
+		recordedQueryResults(t, `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"cluster_id":"cluster1","volumename":"pv1"},"value":[1672617600,"0.02"]},
+			{"metric":{"cluster_id":"cluster1","volumename":"pv2"},"value":[1672617600,"0.02"]}
+		]}}`),
+		recordedQueryResults(t, `{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"cluster_id":"cluster1","volumename":"pv1"},"value":[1672617600,"0.005"]}
+		]}}`))
+
+	if len(pvMap) != 1 {
+		t.Fatalf("expected costs of missing pvs to be ignored; got %d pvs", len(pvMap))
+	}
+
+	podMap := map[podKey]*pod{}
+	applyUnmountedPVs(window, podMap, pvMap, map[pvcKey]*pvc{})
+
+	unmounted := podMap[getUnmountedPodKey("cluster1")]
+	if unmounted == nil {
+		t.Fatalf("expected unmounted pod for cluster1")
+	}
+	pva := unmounted.Allocations[kubecost.UnmountedSuffix].PVs[kubecost.PVKey{Cluster: "cluster1", Name: "pv1"}]
+	if pva == nil {
+		t.Fatalf("expected allocation of pv1")
+	}
+
+	// 10 hours of 100 GiB storage, provisioned IOPS and throughput
+	if !util.IsApproximately(pva.IOPSCost, 0.2) {
+		t.Fatalf("expected IOPS cost of 0.2; got %f", pva.IOPSCost)
+	}
+	if !util.IsApproximately(pva.ThroughputCost, 0.05) {
+		t.Fatalf("expected throughput cost of 0.05; got %f", pva.ThroughputCost)
+	}
+	if !util.IsApproximately(pva.Cost, 0.1+0.2+0.05) {
+		t.Fatalf("expected cost of 0.35; got %f", pva.Cost)
+	}
+}
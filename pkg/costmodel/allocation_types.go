@@ -97,8 +97,9 @@ func (p *pvc) Cost() float64 {
 
 	gib := p.Bytes / 1024 / 1024 / 1024
 	hrs := p.minutes() / 60.0
+	iopsCost, throughputCost := p.Volume.performanceCost(hrs)
 
-	return p.Volume.CostPerGiBHour * gib * hrs
+	return p.Volume.CostPerGiBHour*gib*hrs + iopsCost + throughputCost
 }
 
 // Minutes computes the number of minutes over which the pvc is defined
@@ -125,13 +126,15 @@ func (p *pvc) key() pvcKey {
 
 // pv describes a PersistentVolume
 type pv struct {
-	Start          time.Time `json:"start"`
-	End            time.Time `json:"end"`
-	Bytes          float64   `json:"bytes"`
-	CostPerGiBHour float64   `json:"costPerGiBHour"`
-	Cluster        string    `json:"cluster"`
-	Name           string    `json:"name"`
-	StorageClass   string    `json:"storageClass"`
+	Start                 time.Time `json:"start"`
+	End                   time.Time `json:"end"`
+	Bytes                 float64   `json:"bytes"`
+	CostPerGiBHour        float64   `json:"costPerGiBHour"`
+	IOPSCostPerHour       float64   `json:"iopsCostPerHour"`
+	ThroughputCostPerHour float64   `json:"throughputCostPerHour"`
+	Cluster               string    `json:"cluster"`
+	Name                  string    `json:"name"`
+	StorageClass          string    `json:"storageClass"`
 }
 
 func (p *pv) clone() *pv {
@@ -139,13 +142,15 @@ func (p *pv) clone() *pv {
 		return nil
 	}
 	return &pv{
-		Start:          p.Start,
-		End:            p.End,
-		Bytes:          p.Bytes,
-		CostPerGiBHour: p.CostPerGiBHour,
-		Cluster:        p.Cluster,
-		Name:           p.Name,
-		StorageClass:   p.StorageClass,
+		Start:                 p.Start,
+		End:                   p.End,
+		Bytes:                 p.Bytes,
+		CostPerGiBHour:        p.CostPerGiBHour,
+		IOPSCostPerHour:       p.IOPSCostPerHour,
+		ThroughputCostPerHour: p.ThroughputCostPerHour,
+		Cluster:               p.Cluster,
+		Name:                  p.Name,
+		StorageClass:          p.StorageClass,
 	}
 }
 
@@ -170,6 +175,14 @@ func (p *pv) equal(that *pv) bool {
 		return false
 	}
 
+	if p.IOPSCostPerHour != that.IOPSCostPerHour {
+		return false
+	}
+
+	if p.ThroughputCostPerHour != that.ThroughputCostPerHour {
+		return false
+	}
+
 	if p.Cluster != that.Cluster {
 		return false
 	}
@@ -193,6 +206,16 @@ func (p *pv) String() string {
 	return fmt.Sprintf("%s/%s{Bytes:%.2f, Cost/GiB*Hr:%.6f, StorageClass:%s}", p.Cluster, p.Name, p.Bytes, p.CostPerGiBHour, p.StorageClass)
 }
 
+// performanceCost returns the cost of the IOPS and throughput provisioned for
+// the pv over the given number of hours
+func (p *pv) performanceCost(hrs float64) (iopsCost, throughputCost float64) {
+	if p == nil {
+		return 0.0, 0.0
+	}
+
+	return p.IOPSCostPerHour * hrs, p.ThroughputCostPerHour * hrs
+}
+
 func (p *pv) minutes() float64 {
 	if p == nil {
 		return 0.0
@@ -0,0 +1,121 @@
+package costmodel
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/util/timeutil"
+)
+
+// ExplainAllocation explains the cost of the allocation with the given key,
+// after aggregating by the given properties, over the given window. Idle is
+// always computed, so that its coefficients can be explained, but is only
+// shared if requested.
+func (cm *CostModel) ExplainAllocation(traceCtx context.Context, window kubecost.Window, resolution time.Duration, key string, aggregate []string, sharingPolicies []*kubecost.SharingPolicy, shareIdle, idleByNode, idleByNodePool bool) (*kubecost.AllocationExplanation, error) {
+	asr, opts, err := cm.queryAllocationSets(traceCtx, window, resolution, window.Duration(), nil, sharingPolicies, nil, true, idleByNode, idleByNodePool, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(asr.Allocations) != 1 {
+		return nil, fmt.Errorf("expected one allocation set for %s; got %d", window, len(asr.Allocations))
+	}
+
+	if shareIdle {
+		opts.ShareIdle = kubecost.ShareWeighted
+	}
+
+	ae, err := kubecost.NewAllocationExplanation(asr.Allocations[0], key, aggregate, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error explaining %s for %s: %w", key, window, err)
+	}
+
+	nodePricing := map[nodeKey]*kubecost.AllocationNodePricingExplanation{}
+	for _, item := range ae.Items {
+		nk := newNodeKey(item.Properties.Cluster, item.Properties.Node)
+		np, ok := nodePricing[nk]
+		if !ok {
+			np = cm.explainNodePricing(traceCtx, *window.Start(), *window.End(), nk)
+			nodePricing[nk] = np
+		}
+
+		item.ApplyNodePricing(np)
+	}
+
+	ae.Finalize()
+
+	return ae, nil
+}
+
+// explainNodePricing determines the pricing of the given node over the given
+// window exactly as computeAllocation does, but only for the given node. It
+// returns nil if the pricing cannot be determined.
+func (cm *CostModel) explainNodePricing(traceCtx context.Context, start, end time.Time, nk nodeKey) *kubecost.AllocationNodePricingExplanation {
+	durStr := timeutil.DurationString(end.Sub(start))
+	if durStr == "" {
+		return nil
+	}
+
+	pushdown := newAllocationPushdown(kubecost.AllocationFilterAnd{
+		Filters: []kubecost.AllocationFilter{
+			kubecost.AllocationFilterCondition{Field: kubecost.FilterClusterID, Op: kubecost.FilterEquals, Value: nk.Cluster},
+			kubecost.AllocationFilterCondition{Field: kubecost.FilterNode, Op: kubecost.FilterEquals, Value: nk.Node},
+		},
+	})
+
+	queries := newAllocationQueries(durStr, "", "")
+
+	ctx := prom.NewNamedContext(cm.PrometheusClient, prom.AllocationContextName).WithContext(traceCtx)
+
+	queryNodeCostPerCPUHr := queries.query(allocationQueryNodeCostPerCPUHr, pushdown, pushdownNodeLabels)
+	resChNodeCostPerCPUHr := ctx.QueryAtTime(queryNodeCostPerCPUHr, end)
+
+	queryNodeCostPerRAMGiBHr := queries.query(allocationQueryNodeCostPerRAMGiBHr, pushdown, pushdownNodeLabels)
+	resChNodeCostPerRAMGiBHr := ctx.QueryAtTime(queryNodeCostPerRAMGiBHr, end)
+
+	queryNodeCostPerGPUHr := queries.query(allocationQueryNodeCostPerGPUHr, pushdown, pushdownNodeLabels)
+	resChNodeCostPerGPUHr := ctx.QueryAtTime(queryNodeCostPerGPUHr, end)
+
+	queryNodeIsSpot := queries.query(allocationQueryNodeIsSpot, pushdown, pushdownNodeLabels)
+	resChNodeIsSpot := ctx.QueryAtTime(queryNodeIsSpot, end)
+
+	resNodeCostPerCPUHr, _ := resChNodeCostPerCPUHr.Await()
+	resNodeCostPerRAMGiBHr, _ := resChNodeCostPerRAMGiBHr.Await()
+	resNodeCostPerGPUHr, _ := resChNodeCostPerGPUHr.Await()
+	resNodeIsSpot, _ := resChNodeIsSpot.Await()
+
+	if ctx.HasErrors() {
+		for _, err := range ctx.Errors() {
+			log.Errorf("CostModel.ExplainAllocation: query context error %s", err)
+		}
+		return nil
+	}
+
+	nodeMap := map[nodeKey]*nodePricing{}
+	applyNodeCostPerCPUHr(nodeMap, resNodeCostPerCPUHr)
+	applyNodeCostPerRAMGiBHr(nodeMap, resNodeCostPerRAMGiBHr)
+	applyNodeCostPerGPUHr(nodeMap, resNodeCostPerGPUHr)
+	applyNodeSpot(nodeMap, resNodeIsSpot)
+	applyNodeDiscount(nodeMap, cm)
+
+	node := cm.getNodePricing(nodeMap, nk)
+	if node == nil {
+		return nil
+	}
+
+	return &kubecost.AllocationNodePricingExplanation{
+		Node:             nk.Node,
+		ProviderID:       node.ProviderID,
+		NodeType:         node.NodeType,
+		Preemptible:      node.Preemptible,
+		CPUHourlyRate:    node.CostPerCPUHr,
+		RAMGiBHourlyRate: node.CostPerRAMGiBHr,
+		GPUHourlyRate:    node.CostPerGPUHr,
+		Discount:         node.Discount,
+		PricingSource:    node.Source,
+	}
+}
@@ -0,0 +1,71 @@
+package costmodel
+
+import (
+	"github.com/opencost/opencost/pkg/config"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+)
+
+// WatchAllocationPropertiesConfig loads the custom allocation property
+// definitions from the given config file, if it exists, and reloads them
+// whenever the file changes. The file contains a JSON list of definitions:
+//
+//	[{
+//	  "name": "costCenter",
+//	  "sources": [
+//	    {"type": "label", "name": "cost_center"},
+//	    {"type": "annotation", "name": "cost-center"},
+//	    {"type": "namespaceLabel", "name": "cost_center"}
+//	  ],
+//	  "default": "shared"
+//	}]
+func WatchAllocationPropertiesConfig(file *config.ConfigFile) {
+	exists, err := file.Exists()
+	if err != nil {
+		log.Errorf("Failed to check for allocation properties config %s: %s", file.Path(), err)
+		return
+	}
+
+	if exists {
+		data, err := file.Read()
+		if err != nil {
+			log.Warnf("Failed to read allocation properties config %s: %s", file.Path(), err)
+		} else {
+			updateAllocationProperties(data)
+		}
+	}
+
+	file.AddChangeHandler(func(changeType config.ChangeType, data []byte) {
+		if changeType == config.ChangeTypeDeleted {
+			log.Infof("Allocation properties config deleted, removing custom allocation properties")
+			kubecost.SetAllocationPropertyDefinitions(nil)
+			return
+		}
+
+		updateAllocationProperties(data)
+	})
+}
+
+func updateAllocationProperties(data []byte) {
+	defs, err := kubecost.ParseAllocationPropertyDefinitions(data)
+	if err != nil {
+		log.Errorf("Invalid allocation properties config, keeping existing custom allocation properties: %s", err)
+		return
+	}
+
+	// Custom properties are exported as CSV columns, so must not duplicate
+	// the fixed columns
+	err = validateCSVColumnNames(defs)
+	if err != nil {
+		log.Errorf("Invalid allocation properties config, keeping existing custom allocation properties: %s", err)
+		return
+	}
+
+	err = kubecost.SetAllocationPropertyDefinitions(defs)
+	if err != nil {
+		log.Errorf("Invalid allocation properties config, keeping existing custom allocation properties: %s", err)
+		return
+	}
+
+	log.Infof("Loaded %d custom allocation properties", len(defs))
+}
@@ -0,0 +1,266 @@
+package costmodel
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+)
+
+// allocationPushdown is the subset of an AllocationFilter which can be
+// expressed as Prometheus label matchers, so that the allocation queries only
+// return series which may belong to matching Allocations.
+//
+// Pushdown narrows the data queried; it does not replace the filter. Some
+// Allocations, like unmounted PVs, are not built from the filtered series, so
+// the complete filter must still be applied to the computed AllocationSet.
+//
+// A field is only pushed into a query if dropping the series it excludes
+// cannot change the costs of matching Allocations:
+//   - cluster and namespace are pushed into every query carrying the label,
+//     because resources shared between Allocations (PVCs, services, pods) are
+//     namespaced.
+//   - pod and container are pushed into queries of per-container data, but not
+//     into the queries which build the pod map or associate pods with PVCs and
+//     services, as those determine how shared costs are split.
+//   - node is pushed into the queries of node pricing and labels.
+type allocationPushdown struct {
+	values    map[kubecost.FilterField][]string
+	pushed    []kubecost.AllocationFilter
+	remainder []kubecost.AllocationFilter
+}
+
+// pushdownLabels maps the fields which can be pushed into a query to the
+// Prometheus labels holding them. The cluster label is always pushed.
+type pushdownLabels map[kubecost.FilterField]string
+
+var (
+	// Metrics which determine the set of pods and containers, or how shared
+	// costs are split between them
+	pushdownNamespaceLabels = pushdownLabels{
+		kubecost.FilterNamespace: "namespace",
+	}
+
+	// Metrics of data attributed to a single container
+	pushdownContainerLabels = pushdownLabels{
+		kubecost.FilterNamespace: "namespace",
+		kubecost.FilterPod:       "pod",
+		kubecost.FilterContainer: "container",
+	}
+
+	// Metrics of data attributed to a single pod
+	pushdownPodLabels = pushdownLabels{
+		kubecost.FilterNamespace: "namespace",
+		kubecost.FilterPod:       "pod",
+	}
+
+	// Metrics of data attributed to a single pod, labelled by "pod_name"
+	pushdownPodNameLabels = pushdownLabels{
+		kubecost.FilterNamespace: "namespace",
+		kubecost.FilterPod:       "pod_name",
+	}
+
+	// Metrics of data attributed to a node
+	pushdownNodeLabels = pushdownLabels{
+		kubecost.FilterNode: "node",
+	}
+
+	// Metrics which may only be narrowed by cluster
+	pushdownClusterLabels = pushdownLabels{}
+)
+
+// newAllocationPushdown splits the filter into the AND-ed conditions which can
+// be pushed into queries and the remainder, which can only be applied after
+// computing Allocations. It returns nil if no part of the filter can be pushed.
+func newAllocationPushdown(filter kubecost.AllocationFilter) *allocationPushdown {
+	if filter == nil {
+		return nil
+	}
+
+	p := &allocationPushdown{
+		values: map[kubecost.FilterField][]string{},
+	}
+
+	for _, conjunct := range conjuncts(filter) {
+		field, values, ok := pushableValues(conjunct)
+
+		// Only the first condition on each field is pushed. Any other is
+		// left to the remainder, rather than intersecting values.
+		if _, exists := p.values[field]; ok && !exists {
+			p.values[field] = values
+			p.pushed = append(p.pushed, conjunct)
+			continue
+		}
+
+		p.remainder = append(p.remainder, conjunct)
+	}
+
+	if len(p.pushed) == 0 {
+		return nil
+	}
+
+	return p
+}
+
+// conjuncts returns the filters which are AND-ed together to form the filter.
+func conjuncts(filter kubecost.AllocationFilter) []kubecost.AllocationFilter {
+	switch f := filter.(type) {
+	case kubecost.AllocationFilterAnd:
+		result := []kubecost.AllocationFilter{}
+		for _, inner := range f.Filters {
+			result = append(result, conjuncts(inner)...)
+		}
+		return result
+	case kubecost.AllocationFilterOr:
+		if len(f.Filters) == 1 {
+			return conjuncts(f.Filters[0])
+		}
+	}
+
+	return []kubecost.AllocationFilter{filter}
+}
+
+// pushableValues returns the field and values of a filter which is either an
+// equality condition on a pushable field, or an OR of such conditions on the
+// same field.
+func pushableValues(filter kubecost.AllocationFilter) (kubecost.FilterField, []string, bool) {
+	switch f := filter.(type) {
+	case kubecost.AllocationFilterCondition:
+		if !isPushableCondition(f) {
+			return "", nil, false
+		}
+		return f.Field, []string{f.Value}, true
+	case kubecost.AllocationFilterOr:
+		if len(f.Filters) == 0 {
+			return "", nil, false
+		}
+
+		var field kubecost.FilterField
+		values := []string{}
+		for i, inner := range f.Filters {
+			innerField, innerValues, ok := pushableValues(inner)
+			if !ok || (i > 0 && innerField != field) {
+				return "", nil, false
+			}
+			field = innerField
+			values = append(values, innerValues...)
+		}
+		return field, values, true
+	default:
+		return "", nil, false
+	}
+}
+
+func isPushableCondition(c kubecost.AllocationFilterCondition) bool {
+	if c.Op != kubecost.FilterEquals || c.Key != "" {
+		return false
+	}
+
+	switch c.Field {
+	case kubecost.FilterClusterID, kubecost.FilterNamespace, kubecost.FilterPod, kubecost.FilterContainer, kubecost.FilterNode:
+	default:
+		return false
+	}
+
+	// Reserved values like __unallocated__ and __idle__ match Allocations
+	// with an empty or synthesized property, which can't be matched by label.
+	return c.Value != "" && !(strings.HasPrefix(c.Value, "__") && strings.HasSuffix(c.Value, "__"))
+}
+
+// matchers returns the label matchers for the pushed fields available in the
+// given labels, sorted for deterministic queries.
+func (p *allocationPushdown) matchers(labels pushdownLabels) []string {
+	if p == nil {
+		return nil
+	}
+
+	result := []string{}
+
+	if values, ok := p.values[kubecost.FilterClusterID]; ok {
+		// Series without a cluster label are attributed to the local
+		// cluster, so must also match if it is included.
+		for _, value := range values {
+			if value == env.GetClusterID() {
+				values = append(values, "")
+				break
+			}
+		}
+		result = append(result, labelMatcher(env.GetPromClusterLabel(), values))
+	}
+
+	for field, label := range labels {
+		if values, ok := p.values[field]; ok {
+			result = append(result, labelMatcher(label, values))
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+func labelMatcher(label string, values []string) string {
+	if len(values) == 1 {
+		return fmt.Sprintf("%s=%s", label, strconv.Quote(values[0]))
+	}
+
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = regexp.QuoteMeta(value)
+	}
+	return fmt.Sprintf("%s=~%s", label, strconv.Quote(strings.Join(quoted, "|")))
+}
+
+// apply injects the label matchers available on the metric into the first
+// selector of the metric in the query. The query is returned unchanged if
+// nothing can be pushed.
+func (p *allocationPushdown) apply(query, metric string, labels pushdownLabels) string {
+	matchers := p.matchers(labels)
+	if len(matchers) == 0 {
+		return query
+	}
+
+	i := metricIndex(query, metric)
+	if i < 0 {
+		log.Warnf("CostModel.ComputeAllocation: cannot push filter into query without metric %s: %s", metric, query)
+		return query
+	}
+
+	selector := strings.Join(matchers, ", ")
+	end := i + len(metric)
+
+	if !strings.HasPrefix(query[end:], "{") {
+		return query[:end] + "{" + selector + "}" + query[end:]
+	}
+	if strings.HasPrefix(query[end:], "{}") {
+		return query[:end+1] + selector + query[end+1:]
+	}
+	return query[:end+1] + selector + ", " + query[end+1:]
+}
+
+// String describes the pushed and remaining parts of the filter, for debug
+// output.
+func (p *allocationPushdown) String() string {
+	if p == nil {
+		return "nothing pushed"
+	}
+
+	pushed := make([]string, len(p.pushed))
+	for i, f := range p.pushed {
+		pushed[i] = f.String()
+	}
+
+	remainder := make([]string, len(p.remainder))
+	for i, f := range p.remainder {
+		remainder[i] = f.String()
+	}
+
+	return fmt.Sprintf("pushed [%s] as {%s}; remainder [%s]",
+		strings.Join(pushed, " "),
+		strings.Join(p.matchers(pushdownContainerLabels), ", "),
+		strings.Join(remainder, " "))
+}
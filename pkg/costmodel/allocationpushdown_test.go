@@ -0,0 +1,167 @@
+package costmodel
+
+import (
+	"testing"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+)
+
+func TestNewAllocationPushdown(t *testing.T) {
+	namespace := kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: "kubecost"}
+	otherNamespace := kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: "default"}
+	pod := kubecost.AllocationFilterCondition{Field: kubecost.FilterPod, Op: kubecost.FilterEquals, Value: "pod-1"}
+	label := kubecost.AllocationFilterCondition{Field: kubecost.FilterLabel, Op: kubecost.FilterEquals, Key: "app", Value: "cost-analyzer"}
+	notNamespace := kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterNotEquals, Value: "kubecost"}
+	unallocated := kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: kubecost.UnallocatedSuffix}
+
+	cases := map[string]struct {
+		filter    kubecost.AllocationFilter
+		values    map[kubecost.FilterField][]string
+		remainder int
+	}{
+		"nil": {
+			filter: nil,
+		},
+		"single condition": {
+			filter: namespace,
+			values: map[kubecost.FilterField][]string{kubecost.FilterNamespace: {"kubecost"}},
+		},
+		"and with remainder": {
+			filter: kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+				namespace,
+				kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{pod, label}},
+			}},
+			values: map[kubecost.FilterField][]string{
+				kubecost.FilterNamespace: {"kubecost"},
+				kubecost.FilterPod:       {"pod-1"},
+			},
+			remainder: 1,
+		},
+		"or of same field": {
+			filter: kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{namespace, otherNamespace}},
+			values: map[kubecost.FilterField][]string{kubecost.FilterNamespace: {"kubecost", "default"}},
+		},
+		"or of different fields": {
+			filter: kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{namespace, pod}},
+		},
+		"second condition on field": {
+			filter:    kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{namespace, otherNamespace}},
+			values:    map[kubecost.FilterField][]string{kubecost.FilterNamespace: {"kubecost"}},
+			remainder: 1,
+		},
+		"not equals": {
+			filter: notNamespace,
+		},
+		"reserved value": {
+			filter: unallocated,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := newAllocationPushdown(c.filter)
+			if c.values == nil {
+				if p != nil {
+					t.Fatalf("expected nothing pushed; got %s", p)
+				}
+				return
+			}
+			if p == nil {
+				t.Fatalf("expected pushdown; got nil")
+			}
+
+			if len(p.values) != len(c.values) {
+				t.Fatalf("expected values %v; got %v", c.values, p.values)
+			}
+			for field, values := range c.values {
+				if len(p.values[field]) != len(values) {
+					t.Fatalf("expected values %v; got %v", c.values, p.values)
+				}
+				for i := range values {
+					if p.values[field][i] != values[i] {
+						t.Fatalf("expected values %v; got %v", c.values, p.values)
+					}
+				}
+			}
+
+			if len(p.remainder) != c.remainder {
+				t.Fatalf("expected %d remaining filters; got %d", c.remainder, len(p.remainder))
+			}
+		})
+	}
+}
+
+func TestAllocationPushdown_Apply(t *testing.T) {
+	t.Setenv(env.ClusterIDEnvVar, "cluster-one")
+
+	filter := kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+		kubecost.AllocationFilterCondition{Field: kubecost.FilterClusterID, Op: kubecost.FilterEquals, Value: "cluster-one"},
+		kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+			kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: "kubecost"},
+			kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: "kube-system"},
+		}},
+		kubecost.AllocationFilterCondition{Field: kubecost.FilterPod, Op: kubecost.FilterEquals, Value: "pod-1"},
+	}}
+	p := newAllocationPushdown(filter)
+
+	cases := map[string]struct {
+		query    string
+		metric   string
+		labels   pushdownLabels
+		expected string
+	}{
+		"existing matchers": {
+			query:    `avg(avg_over_time(container_cpu_allocation{container!=""}[1h])) by (container, pod)`,
+			metric:   "container_cpu_allocation",
+			labels:   pushdownNamespaceLabels,
+			expected: `avg(avg_over_time(container_cpu_allocation{cluster_id=~"cluster-one|", namespace=~"kubecost|kube-system", container!=""}[1h])) by (container, pod)`,
+		},
+		"empty matchers": {
+			query:    `avg(kube_pod_container_status_running{}) by (pod, namespace, cluster_id)[1h:5m]`,
+			metric:   "kube_pod_container_status_running",
+			labels:   pushdownNamespaceLabels,
+			expected: `avg(kube_pod_container_status_running{cluster_id=~"cluster-one|", namespace=~"kubecost|kube-system"}) by (pod, namespace, cluster_id)[1h:5m]`,
+		},
+		"no matchers": {
+			query:    `avg_over_time(kube_pod_annotations[1h])`,
+			metric:   "kube_pod_annotations",
+			labels:   pushdownPodLabels,
+			expected: `avg_over_time(kube_pod_annotations{cluster_id=~"cluster-one|", namespace=~"kubecost|kube-system", pod="pod-1"}[1h])`,
+		},
+		"renamed label": {
+			query:    `sum(increase(kubecost_pod_network_egress_bytes_total{internet="true"}[1h])) by (pod_name, namespace)`,
+			metric:   "kubecost_pod_network_egress_bytes_total",
+			labels:   pushdownPodNameLabels,
+			expected: `sum(increase(kubecost_pod_network_egress_bytes_total{cluster_id=~"cluster-one|", namespace=~"kubecost|kube-system", pod_name="pod-1", internet="true"}[1h])) by (pod_name, namespace)`,
+		},
+		"prefixed metric": {
+			query:    `avg_over_time(agent_kube_pod_annotations[1h]) or avg_over_time(kube_pod_annotations[1h])`,
+			metric:   "kube_pod_annotations",
+			labels:   pushdownClusterLabels,
+			expected: `avg_over_time(agent_kube_pod_annotations[1h]) or avg_over_time(kube_pod_annotations{cluster_id=~"cluster-one|"}[1h])`,
+		},
+		"cluster only": {
+			query:    `avg(avg_over_time(pv_hourly_cost[1h])) by (volumename)`,
+			metric:   "pv_hourly_cost",
+			labels:   pushdownClusterLabels,
+			expected: `avg(avg_over_time(pv_hourly_cost{cluster_id=~"cluster-one|"}[1h])) by (volumename)`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			actual := p.apply(c.query, c.metric, c.labels)
+			if actual != c.expected {
+				t.Fatalf("expected %s; got %s", c.expected, actual)
+			}
+		})
+	}
+
+	// A nil pushdown leaves queries unchanged
+	var none *allocationPushdown
+	query := `avg_over_time(kube_pod_labels[1h])`
+	if actual := none.apply(query, "kube_pod_labels", pushdownNamespaceLabels); actual != query {
+		t.Fatalf("expected %s; got %s", query, actual)
+	}
+}
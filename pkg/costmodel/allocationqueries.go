@@ -0,0 +1,482 @@
+package costmodel
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/pkg/config"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/errors"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/util/json"
+
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+// Placeholders which are replaced, in allocation query templates, by the
+// duration of the window, the resolution, twice the resolution (e.g. for
+// irate windows) and the cluster label.
+const (
+	allocationQueryWindowPlaceholder           = "__window__"
+	allocationQueryResolutionPlaceholder       = "__resolution__"
+	allocationQueryDoubleResolutionPlaceholder = "__double_resolution__"
+	allocationQueryClusterLabelPlaceholder     = "__cluster_label__"
+)
+
+// Names of the allocation queries which can be overridden.
+const (
+	allocationQueryPods                     = "pods"
+	allocationQueryPodsUID                  = "podsUID"
+	allocationQueryRAMBytesAllocated        = "ramBytesAllocated"
+	allocationQueryRAMRequests              = "ramRequests"
+	allocationQueryRAMUsageAvg              = "ramUsageAvg"
+	allocationQueryRAMUsageMax              = "ramUsageMax"
+	allocationQueryCPUCoresAllocated        = "cpuCoresAllocated"
+	allocationQueryCPURequests              = "cpuRequests"
+	allocationQueryCPUUsageAvg              = "cpuUsageAvg"
+	allocationQueryCPUUsageMaxRecordingRule = "cpuUsageMaxRecordingRule"
+	allocationQueryCPUUsageMaxSubquery      = "cpuUsageMaxSubquery"
+	allocationQueryGPUsRequested            = "gpusRequested"
+	allocationQueryGPUsAllocated            = "gpusAllocated"
+	allocationQueryGPUsMIGRequested         = "gpusMIGRequested"
+	allocationQueryGPUUtilization           = "gpuUtilization"
+	allocationQueryGPUMemoryUsed            = "gpuMemoryUsed"
+	allocationQueryEphemeralStorageRequests = "ephemeralStorageRequests"
+	allocationQueryEphemeralStorageUsageAvg = "ephemeralStorageUsageAvg"
+	allocationQueryNodeCostPerCPUHr         = "nodeCostPerCPUHr"
+	allocationQueryNodeCostPerRAMGiBHr      = "nodeCostPerRAMGiBHr"
+	allocationQueryNodeCostPerGPUHr         = "nodeCostPerGPUHr"
+	allocationQueryNodeIsSpot               = "nodeIsSpot"
+	allocationQueryPVCInfo                  = "pvcInfo"
+	allocationQueryPodPVCAllocation         = "podPVCAllocation"
+	allocationQueryPVCBytesRequested        = "pvcBytesRequested"
+	allocationQueryPVActiveMins             = "pvActiveMins"
+	allocationQueryPVBytes                  = "pvBytes"
+	allocationQueryPVCostPerGiBHour         = "pvCostPerGiBHour"
+	allocationQueryPVIOPSCostPerHr          = "pvIOPSCostPerHr"
+	allocationQueryPVThroughputCostPerHr    = "pvThroughputCostPerHr"
+	allocationQueryNetZoneGiB               = "netZoneGiB"
+	allocationQueryNetZoneCostPerGiB        = "netZoneCostPerGiB"
+	allocationQueryNetRegionGiB             = "netRegionGiB"
+	allocationQueryNetRegionCostPerGiB      = "netRegionCostPerGiB"
+	allocationQueryNetInternetGiB           = "netInternetGiB"
+	allocationQueryNetInternetCostPerGiB    = "netInternetCostPerGiB"
+	allocationQueryNetZoneGiBByDest         = "netZoneGiBByDestination"
+	allocationQueryNetRegionGiBByDest       = "netRegionGiBByDestination"
+	allocationQueryNetInternetGiBByDest     = "netInternetGiBByDestination"
+	allocationQueryNetReceiveBytes          = "netReceiveBytes"
+	allocationQueryNetTransferBytes         = "netTransferBytes"
+	allocationQueryPodIPs                   = "podIPs"
+	allocationQueryNodeLabels               = "nodeLabels"
+	allocationQueryNodeTaints               = "nodeTaints"
+	allocationQueryNamespaceLabels          = "namespaceLabels"
+	allocationQueryNamespaceAnnotations     = "namespaceAnnotations"
+	allocationQueryPodLabels                = "podLabels"
+	allocationQueryPodAnnotations           = "podAnnotations"
+	allocationQueryServiceLabels            = "serviceLabels"
+	allocationQueryDeploymentLabels         = "deploymentLabels"
+	allocationQueryStatefulSetLabels        = "statefulSetLabels"
+	allocationQueryDaemonSetLabels          = "daemonSetLabels"
+	allocationQueryJobLabels                = "jobLabels"
+	allocationQueryPodsWithReplicaSetOwner  = "podsWithReplicaSetOwner"
+	allocationQueryReplicaSetsWithoutOwners = "replicaSetsWithoutOwners"
+	allocationQueryLBCostPerHr              = "lbCostPerHr"
+	allocationQueryLBActiveMins             = "lbActiveMins"
+	allocationQuerySnapshotActiveMins       = "snapshotActiveMins"
+	allocationQuerySnapshotBytes            = "snapshotBytes"
+	allocationQuerySnapshotCostPerGiBHr     = "snapshotCostPerGiBHr"
+)
+
+// AllocationQueryTemplate is a PromQL query template, in which placeholders
+// are replaced when the query is run. Metric is the name of the metric into
+// whose selector allocation filters are pushed. If it is not set, the metric
+// of the default query is used, if the query still selects it; otherwise
+// filters are not pushed into the query.
+type AllocationQueryTemplate struct {
+	Query  string `json:"query"`
+	Metric string `json:"metric,omitempty"`
+}
+
+// render replaces the placeholders of the template with the given values.
+func (aqt AllocationQueryTemplate) render(window, resolution, doubleResolution string) string {
+	return strings.NewReplacer(
+		allocationQueryWindowPlaceholder, window,
+		allocationQueryResolutionPlaceholder, resolution,
+		allocationQueryDoubleResolutionPlaceholder, doubleResolution,
+		allocationQueryClusterLabelPlaceholder, env.GetPromClusterLabel(),
+	).Replace(aqt.Query)
+}
+
+// defaultAllocationQuery builds the template of a default query from its
+// format string, given the placeholders to substitute for its verbs.
+func defaultAllocationQuery(queryFmt, metric string, placeholders ...interface{}) AllocationQueryTemplate {
+	return AllocationQueryTemplate{
+		Query:  fmt.Sprintf(queryFmt, placeholders...),
+		Metric: metric,
+	}
+}
+
+// defaultAllocationQueries are the templates of the allocation queries which
+// are run unless overridden, keyed by name.
+var defaultAllocationQueries = map[string]AllocationQueryTemplate{
+	allocationQueryPods:                     defaultAllocationQuery(queryFmtPods, "kube_pod_container_status_running", allocationQueryClusterLabelPlaceholder, allocationQueryWindowPlaceholder, allocationQueryResolutionPlaceholder),
+	allocationQueryPodsUID:                  defaultAllocationQuery(queryFmtPodsUID, "kube_pod_container_status_running", allocationQueryClusterLabelPlaceholder, allocationQueryWindowPlaceholder, allocationQueryResolutionPlaceholder),
+	allocationQueryRAMBytesAllocated:        defaultAllocationQuery(queryFmtRAMBytesAllocated, "container_memory_allocation_bytes", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryRAMRequests:              defaultAllocationQuery(queryFmtRAMRequests, "kube_pod_container_resource_requests", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryRAMUsageAvg:              defaultAllocationQuery(queryFmtRAMUsageAvg, "container_memory_working_set_bytes", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryRAMUsageMax:              defaultAllocationQuery(queryFmtRAMUsageMax, "container_memory_working_set_bytes", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryCPUCoresAllocated:        defaultAllocationQuery(queryFmtCPUCoresAllocated, "container_cpu_allocation", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryCPURequests:              defaultAllocationQuery(queryFmtCPURequests, "kube_pod_container_resource_requests", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryCPUUsageAvg:              defaultAllocationQuery(queryFmtCPUUsageAvg, "container_cpu_usage_seconds_total", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryCPUUsageMaxRecordingRule: defaultAllocationQuery(queryFmtCPUUsageMaxRecordingRule, "kubecost_container_cpu_usage_irate", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryCPUUsageMaxSubquery:      defaultAllocationQuery(queryFmtCPUUsageMaxSubquery, "container_cpu_usage_seconds_total", allocationQueryDoubleResolutionPlaceholder, allocationQueryWindowPlaceholder, allocationQueryResolutionPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryGPUsRequested:            defaultAllocationQuery(queryFmtGPUsRequested, "kube_pod_container_resource_requests", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryGPUsAllocated:            defaultAllocationQuery(queryFmtGPUsAllocated, "container_gpu_allocation", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryGPUsMIGRequested:         defaultAllocationQuery(queryFmtGPUsMIGRequested, "kube_pod_container_resource_requests", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryGPUUtilization:           defaultAllocationQuery(queryFmtGPUUtilization, "DCGM_FI_DEV_GPU_UTIL", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryGPUMemoryUsed:            defaultAllocationQuery(queryFmtGPUMemoryUsed, "DCGM_FI_DEV_FB_USED", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryEphemeralStorageRequests: defaultAllocationQuery(queryFmtEphemeralStorageRequests, "kube_pod_container_resource_requests", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryEphemeralStorageUsageAvg: defaultAllocationQuery(queryFmtEphemeralStorageUsageAvg, "container_fs_usage_bytes", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNodeCostPerCPUHr:         defaultAllocationQuery(queryFmtNodeCostPerCPUHr, "node_cpu_hourly_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNodeCostPerRAMGiBHr:      defaultAllocationQuery(queryFmtNodeCostPerRAMGiBHr, "node_ram_hourly_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNodeCostPerGPUHr:         defaultAllocationQuery(queryFmtNodeCostPerGPUHr, "node_gpu_hourly_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNodeIsSpot:               defaultAllocationQuery(queryFmtNodeIsSpot, "kubecost_node_is_spot", allocationQueryWindowPlaceholder),
+	allocationQueryPVCInfo:                  defaultAllocationQuery(queryFmtPVCInfo, "kube_persistentvolumeclaim_info", allocationQueryClusterLabelPlaceholder, allocationQueryWindowPlaceholder, allocationQueryResolutionPlaceholder),
+	allocationQueryPodPVCAllocation:         defaultAllocationQuery(queryFmtPodPVCAllocation, "pod_pvc_allocation", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryPVCBytesRequested:        defaultAllocationQuery(queryFmtPVCBytesRequested, "kube_persistentvolumeclaim_resource_requests_storage_bytes", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryPVActiveMins:             defaultAllocationQuery(queryFmtPVActiveMins, "kube_persistentvolume_capacity_bytes", allocationQueryClusterLabelPlaceholder, allocationQueryWindowPlaceholder, allocationQueryResolutionPlaceholder),
+	allocationQueryPVBytes:                  defaultAllocationQuery(queryFmtPVBytes, "kube_persistentvolume_capacity_bytes", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryPVCostPerGiBHour:         defaultAllocationQuery(queryFmtPVCostPerGiBHour, "pv_hourly_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryPVIOPSCostPerHr:          defaultAllocationQuery(queryFmtPVIOPSCostPerHr, "pv_iops_hourly_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryPVThroughputCostPerHr:    defaultAllocationQuery(queryFmtPVThroughputCostPerHr, "pv_throughput_hourly_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetZoneGiB:               defaultAllocationQuery(queryFmtNetZoneGiB, "kubecost_pod_network_egress_bytes_total", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetZoneCostPerGiB:        defaultAllocationQuery(queryFmtNetZoneCostPerGiB, "kubecost_network_zone_egress_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetRegionGiB:             defaultAllocationQuery(queryFmtNetRegionGiB, "kubecost_pod_network_egress_bytes_total", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetRegionCostPerGiB:      defaultAllocationQuery(queryFmtNetRegionCostPerGiB, "kubecost_network_region_egress_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetInternetGiB:           defaultAllocationQuery(queryFmtNetInternetGiB, "kubecost_pod_network_egress_bytes_total", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetInternetCostPerGiB:    defaultAllocationQuery(queryFmtNetInternetCostPerGiB, "kubecost_network_internet_egress_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetZoneGiBByDest:         defaultAllocationQuery(queryFmtNetZoneGiBByDestination, "kubecost_pod_network_egress_bytes_total", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetRegionGiBByDest:       defaultAllocationQuery(queryFmtNetRegionGiBByDestination, "kubecost_pod_network_egress_bytes_total", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetInternetGiBByDest:     defaultAllocationQuery(queryFmtNetInternetGiBByDestination, "kubecost_pod_network_egress_bytes_total", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetReceiveBytes:          defaultAllocationQuery(queryFmtNetReceiveBytes, "container_network_receive_bytes_total", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNetTransferBytes:         defaultAllocationQuery(queryFmtNetTransferBytes, "container_network_transmit_bytes_total", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryPodIPs:                   defaultAllocationQuery(queryFmtPodIPs, "kube_pod_info", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNodeLabels:               defaultAllocationQuery(queryFmtNodeLabels, "kube_node_labels", allocationQueryWindowPlaceholder),
+	allocationQueryNodeTaints:               defaultAllocationQuery(queryFmtNodeTaints, "kube_node_spec_taint", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryNamespaceLabels:          defaultAllocationQuery(queryFmtNamespaceLabels, "kube_namespace_labels", allocationQueryWindowPlaceholder),
+	allocationQueryNamespaceAnnotations:     defaultAllocationQuery(queryFmtNamespaceAnnotations, "kube_namespace_annotations", allocationQueryWindowPlaceholder),
+	allocationQueryPodLabels:                defaultAllocationQuery(queryFmtPodLabels, "kube_pod_labels", allocationQueryWindowPlaceholder),
+	allocationQueryPodAnnotations:           defaultAllocationQuery(queryFmtPodAnnotations, "kube_pod_annotations", allocationQueryWindowPlaceholder),
+	allocationQueryServiceLabels:            defaultAllocationQuery(queryFmtServiceLabels, "service_selector_labels", allocationQueryWindowPlaceholder),
+	allocationQueryDeploymentLabels:         defaultAllocationQuery(queryFmtDeploymentLabels, "deployment_match_labels", allocationQueryWindowPlaceholder),
+	allocationQueryStatefulSetLabels:        defaultAllocationQuery(queryFmtStatefulSetLabels, "statefulSet_match_labels", allocationQueryWindowPlaceholder),
+	allocationQueryDaemonSetLabels:          defaultAllocationQuery(queryFmtDaemonSetLabels, "kube_pod_owner", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryJobLabels:                defaultAllocationQuery(queryFmtJobLabels, "kube_pod_owner", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryPodsWithReplicaSetOwner:  defaultAllocationQuery(queryFmtPodsWithReplicaSetOwner, "kube_pod_owner", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryReplicaSetsWithoutOwners: defaultAllocationQuery(queryFmtReplicaSetsWithoutOwners, "kube_replicaset_owner", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryLBCostPerHr:              defaultAllocationQuery(queryFmtLBCostPerHr, "kubecost_load_balancer_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQueryLBActiveMins:             defaultAllocationQuery(queryFmtLBActiveMins, "kubecost_load_balancer_cost", allocationQueryClusterLabelPlaceholder, allocationQueryWindowPlaceholder, allocationQueryResolutionPlaceholder),
+	allocationQuerySnapshotActiveMins:       defaultAllocationQuery(queryFmtVolumeSnapshotActiveMins, "kubecost_volumesnapshot_size_bytes", allocationQueryClusterLabelPlaceholder, allocationQueryWindowPlaceholder, allocationQueryResolutionPlaceholder),
+	allocationQuerySnapshotBytes:            defaultAllocationQuery(queryFmtVolumeSnapshotBytes, "kubecost_volumesnapshot_size_bytes", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+	allocationQuerySnapshotCostPerGiBHr:     defaultAllocationQuery(queryFmtVolumeSnapshotCostPerGiB, "kubecost_volumesnapshot_storage_cost", allocationQueryWindowPlaceholder, allocationQueryClusterLabelPlaceholder),
+}
+
+// allocationQueryOverrides holds the configured overrides of the default
+// allocation queries, keyed by name, and the results of validating them. The
+// generation is incremented whenever the overrides are replaced.
+var allocationQueryOverrides = struct {
+	lock        sync.RWMutex
+	byName      map[string]AllocationQueryTemplate
+	validations map[string]*prom.QueryValidation
+	generation  int
+}{
+	byName:      map[string]AllocationQueryTemplate{},
+	validations: map[string]*prom.QueryValidation{},
+}
+
+// allocationQueryValidationRetryInterval is the interval at which overrides
+// which could not be validated, e.g. while Prometheus is unreachable, are
+// validated again.
+var allocationQueryValidationRetryInterval = time.Minute
+
+// ParseAllocationQueryOverrides parses a JSON object of allocation query
+// templates keyed by name, returning an error if any name is unknown or any
+// query is empty.
+func ParseAllocationQueryOverrides(data []byte) (map[string]AllocationQueryTemplate, error) {
+	overrides := map[string]AllocationQueryTemplate{}
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parsing allocation queries: %w", err)
+	}
+
+	for name, aqt := range overrides {
+		def, ok := defaultAllocationQueries[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown allocation query: %s", name)
+		}
+
+		if strings.TrimSpace(aqt.Query) == "" {
+			return nil, fmt.Errorf("allocation query %s is empty", name)
+		}
+
+		if aqt.Metric == "" && selectsMetric(aqt.Query, def.Metric) {
+			aqt.Metric = def.Metric
+		}
+		if aqt.Metric != "" && !selectsMetric(aqt.Query, aqt.Metric) {
+			return nil, fmt.Errorf("allocation query %s does not select metric %s", name, aqt.Metric)
+		}
+		overrides[name] = aqt
+	}
+
+	return overrides, nil
+}
+
+// selectsMetric returns true if the query contains the given metric name, as
+// opposed to only a metric whose name contains it, e.g. a prefixed metric.
+func selectsMetric(query, metric string) bool {
+	return metricIndex(query, metric) >= 0
+}
+
+// metricIndex returns the index of the first occurrence of the given metric
+// name in the query, skipping metrics whose names only contain it, or -1 if
+// the query does not select the metric.
+func metricIndex(query, metric string) int {
+	for i := strings.Index(query, metric); i >= 0; {
+		end := i + len(metric)
+		if (i == 0 || !isMetricNameByte(query[i-1])) && (end == len(query) || !isMetricNameByte(query[end])) {
+			return i
+		}
+
+		j := strings.Index(query[i+1:], metric)
+		if j < 0 {
+			break
+		}
+		i += j + 1
+	}
+	return -1
+}
+
+func isMetricNameByte(b byte) bool {
+	return b == '_' || b == ':' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
+
+// SetAllocationQueryOverrides replaces the configured overrides of the
+// default allocation queries.
+func SetAllocationQueryOverrides(overrides map[string]AllocationQueryTemplate) {
+	allocationQueryOverrides.lock.Lock()
+	defer allocationQueryOverrides.lock.Unlock()
+
+	allocationQueryOverrides.byName = overrides
+	allocationQueryOverrides.validations = map[string]*prom.QueryValidation{}
+	allocationQueryOverrides.generation++
+}
+
+// ValidateAllocationQueryOverrides runs each overridden allocation query not
+// yet validated against the given client over the last hour, falling back to
+// the default query for any which Prometheus rejects. Overrides which cannot
+// be validated, e.g. because Prometheus is unreachable, are kept, and true is
+// returned so they can be validated again.
+func ValidateAllocationQueryOverrides(client prometheus.Client) bool {
+	allocationQueryOverrides.lock.RLock()
+	generation := allocationQueryOverrides.generation
+	pending := map[string]AllocationQueryTemplate{}
+	for name, aqt := range allocationQueryOverrides.byName {
+		if v, ok := allocationQueryOverrides.validations[name]; !ok || !v.Validated {
+			pending[name] = aqt
+		}
+	}
+	allocationQueryOverrides.lock.RUnlock()
+
+	// Run the queries without holding the lock, so that allocation queries are
+	// not blocked on Prometheus
+	validations := make(map[string]*prom.QueryValidation, len(pending))
+	for name, aqt := range pending {
+		validations[name] = prom.ValidateQuery(client, aqt.render("1h", "1m", "2m"))
+	}
+
+	allocationQueryOverrides.lock.Lock()
+	defer allocationQueryOverrides.lock.Unlock()
+
+	// Discard the validations if the overrides were replaced meanwhile
+	if generation != allocationQueryOverrides.generation {
+		return false
+	}
+
+	retry := false
+	for name, validation := range validations {
+		allocationQueryOverrides.validations[name] = validation
+
+		if !validation.Validated {
+			log.Warnf("Allocation query %s could not be validated, retrying in %s: %s", name, allocationQueryValidationRetryInterval, validation.Error)
+			retry = true
+		} else if !validation.Valid {
+			log.Errorf("Allocation query %s is invalid, using the default query: %s", name, validation.Error)
+			delete(allocationQueryOverrides.byName, name)
+		} else if !validation.HasData {
+			log.Warnf("Allocation query %s returned no data for the last hour: %s", name, validation.Query)
+		}
+	}
+
+	return retry
+}
+
+// validateAllocationQueryOverrides validates the configured overrides in the
+// background, retrying those which cannot be validated until they are, or the
+// overrides are replaced.
+func validateAllocationQueryOverrides(client prometheus.Client) {
+	allocationQueryOverrides.lock.RLock()
+	generation := allocationQueryOverrides.generation
+	allocationQueryOverrides.lock.RUnlock()
+
+	go func() {
+		defer errors.HandlePanic()
+
+		for ValidateAllocationQueryOverrides(client) {
+			time.Sleep(allocationQueryValidationRetryInterval)
+
+			allocationQueryOverrides.lock.RLock()
+			replaced := generation != allocationQueryOverrides.generation
+			allocationQueryOverrides.lock.RUnlock()
+
+			if replaced {
+				return
+			}
+		}
+	}()
+}
+
+// allocationQueryTemplate returns the effective template of the allocation
+// query with the given name.
+func allocationQueryTemplate(name string) AllocationQueryTemplate {
+	allocationQueryOverrides.lock.RLock()
+	defer allocationQueryOverrides.lock.RUnlock()
+
+	if aqt, ok := allocationQueryOverrides.byName[name]; ok {
+		return aqt
+	}
+	return defaultAllocationQueries[name]
+}
+
+// allocationQueries renders the effective allocation queries for a window of
+// the given duration and resolution.
+type allocationQueries struct {
+	window           string
+	resolution       string
+	doubleResolution string
+}
+
+func newAllocationQueries(window, resolution, doubleResolution string) *allocationQueries {
+	return &allocationQueries{
+		window:           window,
+		resolution:       resolution,
+		doubleResolution: doubleResolution,
+	}
+}
+
+// query renders the allocation query with the given name, pushing the given
+// filter into it by the given labels.
+func (aq *allocationQueries) query(name string, pushdown *allocationPushdown, labels pushdownLabels) string {
+	aqt := allocationQueryTemplate(name)
+
+	query := aqt.render(aq.window, aq.resolution, aq.doubleResolution)
+	if aqt.Metric == "" {
+		return query
+	}
+	return pushdown.apply(query, aqt.Metric, labels)
+}
+
+// AllocationQueryDiagnostic describes the effective template of an allocation
+// query, and the result of validating it if it is overridden.
+type AllocationQueryDiagnostic struct {
+	Name       string                `json:"name"`
+	Query      string                `json:"query"`
+	Metric     string                `json:"metric"`
+	Overridden bool                  `json:"overridden"`
+	Validation *prom.QueryValidation `json:"validation,omitempty"`
+}
+
+// AllocationQueryDiagnostics returns the effective templates of all
+// allocation queries, sorted by name.
+func AllocationQueryDiagnostics() []*AllocationQueryDiagnostic {
+	allocationQueryOverrides.lock.RLock()
+	defer allocationQueryOverrides.lock.RUnlock()
+
+	diagnostics := make([]*AllocationQueryDiagnostic, 0, len(defaultAllocationQueries))
+	for name, def := range defaultAllocationQueries {
+		aqt, overridden := allocationQueryOverrides.byName[name]
+		if !overridden {
+			aqt = def
+		}
+
+		diagnostics = append(diagnostics, &AllocationQueryDiagnostic{
+			Name:       name,
+			Query:      aqt.Query,
+			Metric:     aqt.Metric,
+			Overridden: overridden,
+			Validation: allocationQueryOverrides.validations[name],
+		})
+	}
+
+	sort.Slice(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Name < diagnostics[j].Name
+	})
+
+	return diagnostics
+}
+
+// WatchAllocationQueriesConfig loads overrides of the default allocation
+// queries from the given config file, if it exists, validates them against
+// the given client, and reloads them whenever the file changes. The file
+// contains a JSON object of query templates keyed by name, e.g.
+//
+//	{
+//	  "cpuUsageAvg": {
+//	    "query": "avg(rate(agent_container_cpu_usage_seconds_total{container!=\"\", container!=\"POD\"}[__window__])) by (container, pod, namespace, instance, __cluster_label__)"
+//	  }
+//	}
+//
+// The placeholders __window__, __resolution__, __double_resolution__ and
+// __cluster_label__ are replaced when each query is run. The effective
+// queries are listed by /diagnostics/allocationQueries.
+func WatchAllocationQueriesConfig(file *config.ConfigFile, client prometheus.Client) {
+	exists, err := file.Exists()
+	if err != nil {
+		log.Errorf("Failed to check for allocation queries config %s: %s", file.Path(), err)
+		return
+	}
+
+	if exists {
+		data, err := file.Read()
+		if err != nil {
+			log.Warnf("Failed to read allocation queries config %s: %s", file.Path(), err)
+		} else {
+			updateAllocationQueryOverrides(data, client)
+		}
+	}
+
+	file.AddChangeHandler(func(changeType config.ChangeType, data []byte) {
+		if changeType == config.ChangeTypeDeleted {
+			log.Infof("Allocation queries config deleted, using the default queries")
+			SetAllocationQueryOverrides(map[string]AllocationQueryTemplate{})
+			return
+		}
+
+		updateAllocationQueryOverrides(data, client)
+	})
+}
+
+func updateAllocationQueryOverrides(data []byte, client prometheus.Client) {
+	overrides, err := ParseAllocationQueryOverrides(data)
+	if err != nil {
+		log.Errorf("Invalid allocation queries config, keeping existing queries: %s", err)
+		return
+	}
+
+	SetAllocationQueryOverrides(overrides)
+	validateAllocationQueryOverrides(client)
+
+	log.Infof("Loaded %d allocation query overrides", len(overrides))
+}
@@ -0,0 +1,163 @@
+package costmodel
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+func TestDefaultAllocationQueries(t *testing.T) {
+	queries := newAllocationQueries("1h", "5m", "10m")
+	cl := env.GetPromClusterLabel()
+
+	// Default templates render exactly as the queries they replace
+	cases := map[string]string{
+		allocationQueryPods:                fmt.Sprintf(queryFmtPods, cl, "1h", "5m"),
+		allocationQueryCPURequests:         fmt.Sprintf(queryFmtCPURequests, "1h", cl),
+		allocationQueryCPUUsageMaxSubquery: fmt.Sprintf(queryFmtCPUUsageMaxSubquery, "10m", "1h", "5m", cl),
+		allocationQueryNodeIsSpot:          fmt.Sprintf(queryFmtNodeIsSpot, "1h"),
+		allocationQueryLBActiveMins:        fmt.Sprintf(queryFmtLBActiveMins, cl, "1h", "5m"),
+		allocationQueryNetZoneGiBByDest:    fmt.Sprintf(queryFmtNetZoneGiBByDestination, "1h", cl),
+	}
+
+	for name, expected := range cases {
+		if actual := queries.query(name, nil, pushdownContainerLabels); actual != expected {
+			t.Fatalf("%s: expected %s; got %s", name, expected, actual)
+		}
+	}
+}
+
+func TestParseAllocationQueryOverrides(t *testing.T) {
+	overrides, err := ParseAllocationQueryOverrides([]byte(`{
+		"cpuUsageAvg": {"query": "avg(rate(container_cpu_usage_seconds_total{job=\"agent\"}[__window__])) by (container, pod, namespace, __cluster_label__)"},
+		"ramUsageAvg": {"query": "avg(avg_over_time(agent_container_memory_working_set_bytes[__window__])) by (container, pod, namespace)"},
+		"podLabels": {"query": "avg_over_time(agent_kube_pod_labels[__window__])", "metric": "agent_kube_pod_labels"}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The metric of the default query is pushed into, if it is still selected
+	if overrides[allocationQueryCPUUsageAvg].Metric != "container_cpu_usage_seconds_total" {
+		t.Fatalf("expected default metric; got %s", overrides[allocationQueryCPUUsageAvg].Metric)
+	}
+	if overrides[allocationQueryRAMUsageAvg].Metric != "" {
+		t.Fatalf("expected no metric; got %s", overrides[allocationQueryRAMUsageAvg].Metric)
+	}
+
+	errCases := map[string]string{
+		"unknown name":    `{"cpuUsage": {"query": "up"}}`,
+		"empty query":     `{"cpuUsageAvg": {"query": " "}}`,
+		"missing metric":  `{"podLabels": {"query": "avg_over_time(kube_pod_labels[__window__])", "metric": "agent_kube_pod_labels"}}`,
+		"invalid content": `[]`,
+	}
+	for name, data := range errCases {
+		if _, err := ParseAllocationQueryOverrides([]byte(data)); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestAllocationQueries_Overridden(t *testing.T) {
+	overrides, err := ParseAllocationQueryOverrides([]byte(`{
+		"podLabels": {"query": "avg_over_time(agent_kube_pod_labels[__window__])", "metric": "agent_kube_pod_labels"},
+		"ramUsageAvg": {"query": "avg(avg_over_time(agent_container_memory_working_set_bytes[__window__])) by (container, pod, namespace)"},
+		"netInternetGiBByDestination": {"query": "sum(increase(kubecost_pod_network_egress_bytes_total{internet=\"true\", job=\"network-costs\"}[__window__])) by (pod_name, namespace, destination_pod_name, destination_namespace) / 1024 / 1024 / 1024"}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	SetAllocationQueryOverrides(overrides)
+	defer SetAllocationQueryOverrides(map[string]AllocationQueryTemplate{})
+
+	pushdown := newAllocationPushdown(kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: "kubecost"})
+	queries := newAllocationQueries("1h", "5m", "10m")
+
+	expected := `avg_over_time(agent_kube_pod_labels{namespace="kubecost"}[1h])`
+	if actual := queries.query(allocationQueryPodLabels, pushdown, pushdownNamespaceLabels); actual != expected {
+		t.Fatalf("expected %s; got %s", expected, actual)
+	}
+
+	// Filters are not pushed into queries without a metric
+	expected = `avg(avg_over_time(agent_container_memory_working_set_bytes[1h])) by (container, pod, namespace)`
+	if actual := queries.query(allocationQueryRAMUsageAvg, pushdown, pushdownContainerLabels); actual != expected {
+		t.Fatalf("expected %s; got %s", expected, actual)
+	}
+
+	// Queries of the network cost matrix are pushed into like the others
+	expected = `sum(increase(kubecost_pod_network_egress_bytes_total{namespace="kubecost", internet="true", job="network-costs"}[1h])) by (pod_name, namespace, destination_pod_name, destination_namespace) / 1024 / 1024 / 1024`
+	if actual := queries.query(allocationQueryNetInternetGiBByDest, pushdown, pushdownPodNameLabels); actual != expected {
+		t.Fatalf("expected %s; got %s", expected, actual)
+	}
+
+	overridden := 0
+	for _, diagnostic := range AllocationQueryDiagnostics() {
+		if diagnostic.Overridden {
+			overridden++
+		}
+	}
+	if overridden != 3 {
+		t.Fatalf("expected 3 overridden queries; got %d", overridden)
+	}
+}
+
+func TestValidateAllocationQueryOverrides(t *testing.T) {
+	var unavailable int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("query")
+		switch {
+		case strings.Contains(query, "invalid"):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+		case strings.Contains(query, "unavailable") && atomic.LoadInt32(&unavailable) == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"pod":"pod-1"},"value":[1,"1"]}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := prometheus.NewClient(prometheus.Config{Address: server.URL})
+	if err != nil {
+		t.Fatalf("creating prometheus client: %s", err)
+	}
+
+	SetAllocationQueryOverrides(map[string]AllocationQueryTemplate{
+		allocationQueryPodLabels:      {Query: "avg_over_time(agent_kube_pod_labels[__window__])"},
+		allocationQueryPodAnnotations: {Query: "avg_over_time(invalid[__window__])"},
+		allocationQueryNodeLabels:     {Query: "avg_over_time(unavailable[__window__])"},
+	})
+	defer SetAllocationQueryOverrides(map[string]AllocationQueryTemplate{})
+
+	// Only the query rejected by Prometheus falls back to the default, while
+	// the one which could not be validated is kept and validated again
+	if !ValidateAllocationQueryOverrides(client) {
+		t.Fatalf("expected a retry for the unavailable query")
+	}
+
+	overridden := map[string]bool{}
+	for _, diagnostic := range AllocationQueryDiagnostics() {
+		overridden[diagnostic.Name] = diagnostic.Overridden
+	}
+	if !overridden[allocationQueryPodLabels] || overridden[allocationQueryPodAnnotations] || !overridden[allocationQueryNodeLabels] {
+		t.Fatalf("expected podLabels and nodeLabels to be overridden; got %v", overridden)
+	}
+
+	atomic.StoreInt32(&unavailable, 0)
+	if ValidateAllocationQueryOverrides(client) {
+		t.Fatalf("expected no retry once Prometheus is available")
+	}
+	for _, diagnostic := range AllocationQueryDiagnostics() {
+		if diagnostic.Name == allocationQueryNodeLabels && (!diagnostic.Overridden || !diagnostic.Validation.Valid) {
+			t.Fatalf("expected nodeLabels to be overridden and valid; got %+v", diagnostic)
+		}
+	}
+}
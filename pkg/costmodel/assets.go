@@ -27,6 +27,11 @@ func (cm *CostModel) ComputeAssets(start, end time.Time) (*kubecost.AssetSet, er
 		return nil, fmt.Errorf("error computing disk assets for %s: %w", kubecost.NewClosedWindow(start, end), err)
 	}
 
+	snapshotMap, err := cm.ClusterVolumeSnapshots(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error computing snapshot assets for %s: %w", kubecost.NewClosedWindow(start, end), err)
+	}
+
 	for _, d := range diskMap {
 		s := d.Start
 		if s.Before(start) || s.After(end) {
@@ -45,6 +50,8 @@ func (cm *CostModel) ComputeAssets(start, end time.Time) (*kubecost.AssetSet, er
 		disk := kubecost.NewDisk(d.Name, d.Cluster, d.ProviderID, s, e, kubecost.NewWindow(&start, &end))
 		cm.propertiesFromCluster(disk.Properties)
 		disk.Cost = d.Cost
+		disk.IOPSCost = d.IOPSCost
+		disk.ThroughputCost = d.ThroughputCost
 		disk.ByteHours = d.Bytes * hours
 		if d.BytesUsedAvgPtr != nil {
 			byteHours := *d.BytesUsedAvgPtr * hours
@@ -90,6 +97,30 @@ func (cm *CostModel) ComputeAssets(start, end time.Time) (*kubecost.AssetSet, er
 		assetSet.Insert(loadBalancer, nil)
 	}
 
+	for _, vs := range snapshotMap {
+		name := fmt.Sprintf("%s/%s", vs.Namespace, vs.Name)
+
+		s := vs.Start
+		if s.Before(start) || s.After(end) {
+			log.Debugf("CostModel.ComputeAssets: snapshot '%s' start outside window: %s not in [%s, %s]", name, s.Format("2006-01-02T15:04:05"), start.Format("2006-01-02T15:04:05"), end.Format("2006-01-02T15:04:05"))
+			s = start
+		}
+
+		e := vs.End
+		if e.Before(start) || e.After(end) {
+			log.Debugf("CostModel.ComputeAssets: snapshot '%s' end outside window: %s not in [%s, %s]", name, e.Format("2006-01-02T15:04:05"), start.Format("2006-01-02T15:04:05"), end.Format("2006-01-02T15:04:05"))
+			e = end
+		}
+
+		snapshot := kubecost.NewSnapshot(name, vs.Cluster, vs.ProviderID, s, e, kubecost.NewWindow(&start, &end))
+		cm.propertiesFromCluster(snapshot.Properties)
+		snapshot.Cost = vs.Cost
+		snapshot.ByteHours = vs.ByteHours
+		snapshot.ClaimName = vs.Claim
+		snapshot.ClaimNamespace = vs.Namespace
+		assetSet.Insert(snapshot, nil)
+	}
+
 	for _, n := range nodeMap {
 		s := n.Start
 		if s.Before(start) || s.After(end) {
@@ -107,6 +138,7 @@ func (cm *CostModel) ComputeAssets(start, end time.Time) (*kubecost.AssetSet, er
 
 		node := kubecost.NewNode(n.Name, n.Cluster, n.ProviderID, s, e, kubecost.NewWindow(&start, &end))
 		cm.propertiesFromCluster(node.Properties)
+		node.Properties.NodePool = kubecost.NodePoolFromLabels(n.Labels)
 		node.NodeType = n.NodeType
 		node.CPUCoreHours = n.CPUCores * hours
 		node.RAMByteHours = n.RAMBytes * hours
@@ -146,6 +178,10 @@ func (cm *CostModel) ClusterLoadBalancers(start, end time.Time) (map[LoadBalance
 	return ClusterLoadBalancers(cm.PrometheusClient, start, end)
 }
 
+func (cm *CostModel) ClusterVolumeSnapshots(start, end time.Time) (map[VolumeSnapshotIdentifier]*VolumeSnapshot, error) {
+	return ClusterVolumeSnapshots(cm.PrometheusClient, start, end)
+}
+
 func (cm *CostModel) ClusterNodes(start, end time.Time) (map[NodeIdentifier]*Node, error) {
 	return ClusterNodes(cm.Provider, cm.PrometheusClient, start, end)
 }
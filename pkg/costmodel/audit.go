@@ -0,0 +1,322 @@
+package costmodel
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/errors"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/util/atomic"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxAuditSets is the number of AuditSets retained by the AuditRunner, i.e.
+// one week of hourly audits.
+const maxAuditSets = 7 * 24
+
+// auditAggregateBy are the properties by which aggregation is audited.
+var auditAggregateBy = []string{
+	kubecost.AllocationClusterProp,
+	kubecost.AllocationNodeProp,
+	kubecost.AllocationNamespaceProp,
+	kubecost.AllocationControllerProp,
+}
+
+// Only allow the audit metrics to be instantiated and registered once
+var auditMetricsInit sync.Once
+
+var (
+	auditDivergenceGv *prometheus.GaugeVec
+	auditFailuresCv   *prometheus.CounterVec
+)
+
+func initAuditMetrics() {
+	auditMetricsInit.Do(func() {
+		auditDivergenceGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "kubecost_audit_divergence",
+			Help: "kubecost_audit_divergence Largest relative difference between expected and actual costs in the last audit",
+		}, []string{"audit_type"})
+
+		auditFailuresCv = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kubecost_audit_failures_total",
+			Help: "kubecost_audit_failures_total Number of audits in which costs diverged beyond tolerance",
+		}, []string{"audit_type"})
+
+		prometheus.MustRegister(auditDivergenceGv, auditFailuresCv)
+	})
+}
+
+// AuditRunner periodically recomputes a sample window of Allocations and
+// Assets from Prometheus and checks them for consistency: Allocations,
+// including idle, against Assets; Assets against the current prices of the
+// cloud provider; Allocations and Assets computed over the whole window
+// against those accumulated over its hourly steps, as served by the APIs; and
+// aggregated Allocations against the sum of their parts. The resulting
+// AuditSets are retained for a week.
+type AuditRunner struct {
+	model     *CostModel
+	interval  time.Duration
+	window    time.Duration
+	tolerance float64
+	runState  atomic.AtomicRunState
+	lock      sync.RWMutex
+	sets      []*kubecost.AuditSet
+}
+
+// NewAuditRunner creates an AuditRunner which audits the most recent complete
+// window of the given duration at each interval, failing audits which
+// diverge beyond the given relative tolerance.
+func NewAuditRunner(model *CostModel, interval, window time.Duration, tolerance float64) *AuditRunner {
+	return &AuditRunner{
+		model:     model,
+		interval:  interval,
+		window:    window,
+		tolerance: tolerance,
+	}
+}
+
+// Start begins running audits in the background, returning false if they are
+// already running.
+func (ar *AuditRunner) Start() bool {
+	// wait for a reset to prevent a race between start and stop calls
+	ar.runState.WaitForReset()
+
+	if !ar.runState.Start() {
+		log.Errorf("Attempted to start audit runner when it's already running.")
+		return false
+	}
+
+	initAuditMetrics()
+
+	go func() {
+		defer errors.HandlePanic()
+
+		for {
+			end := time.Now().UTC().Truncate(time.Hour)
+			if _, err := ar.Run(context.Background(), end.Add(-ar.window), end); err != nil {
+				log.Errorf("Audit: %s", err)
+			}
+
+			select {
+			case <-time.After(ar.interval):
+			case <-ar.runState.OnStop():
+				ar.runState.Reset()
+				return
+			}
+		}
+	}()
+
+	return true
+}
+
+// Stop halts the audits after the current audit is completed.
+func (ar *AuditRunner) Stop() {
+	ar.runState.Stop()
+}
+
+// IsRunning returns true if audits are running in the background.
+func (ar *AuditRunner) IsRunning() bool {
+	return ar.runState.IsRunning()
+}
+
+// Run audits the given window, records the resulting AuditSet and emits its
+// metrics.
+func (ar *AuditRunner) Run(ctx context.Context, start, end time.Time) (*kubecost.AuditSet, error) {
+	window := kubecost.NewClosedWindow(start, end)
+	resolution := env.GetETLResolution()
+
+	step := time.Hour
+	if window.Duration() < step {
+		step = window.Duration()
+	}
+
+	log.Infof("Audit: running audits for %s", window)
+
+	// Expected results are computed over the whole window
+	allocSet, err := ar.model.ComputeAllocationWithContext(ctx, start, end, resolution)
+	if err != nil {
+		return nil, fmt.Errorf("computing allocations for %s: %w", window, err)
+	}
+
+	assetSet, err := ar.model.ComputeAssets(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("computing assets for %s: %w", window, err)
+	}
+
+	// Actual results are accumulated over the steps of the window, including
+	// idle by node, as they are served by the APIs
+	asr, _, err := ar.model.queryAllocationSets(ctx, window, resolution, step, nil, nil, nil, true, true, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	asr, err = asr.Accumulate(kubecost.AccumulateOptionAll)
+	if err != nil {
+		return nil, fmt.Errorf("accumulating allocations for %s: %w", window, err)
+	}
+	if len(asr.Allocations) != 1 {
+		return nil, fmt.Errorf("expected one accumulated allocation set for %s; got %d", window, len(asr.Allocations))
+	}
+	accAllocSet := asr.Allocations[0]
+
+	assetSetRange := kubecost.NewAssetSetRange()
+	for stepStart := start; stepStart.Before(end); stepStart = stepStart.Add(step) {
+		stepAssetSet, err := ar.model.ComputeAssets(stepStart, stepStart.Add(step))
+		if err != nil {
+			return nil, fmt.Errorf("computing assets for %s: %w", kubecost.NewClosedWindow(stepStart, stepStart.Add(step)), err)
+		}
+		assetSetRange.Append(stepAssetSet)
+	}
+
+	accAssetSet, err := assetSetRange.AccumulateToAssetSet()
+	if err != nil {
+		return nil, fmt.Errorf("accumulating assets for %s: %w", window, err)
+	}
+
+	auditSet := kubecost.NewAuditSet(start, end)
+	auditSet.AllocationReconciliation = kubecost.NewAllocationReconciliationAudit(accAllocSet, assetSet, ar.tolerance)
+	auditSet.AllocationTotal = kubecost.NewAllocationTotalAudit(allocSet, accAllocSet, ar.tolerance)
+	auditSet.AssetTotal = kubecost.NewAssetTotalAudit(assetSet, accAssetSet, ar.tolerance)
+	auditSet.ClusterEquality = kubecost.NewClusterEqualityAudit(accAllocSet, assetSet, ar.tolerance)
+
+	prices, err := ar.nodePrices(ctx)
+	if err != nil {
+		log.Warnf("Audit: skipping asset reconciliation for %s: %s", window, err)
+	} else {
+		auditSet.AssetReconciliation = kubecost.NewAssetReconciliationAudit(assetSet, prices, ar.tolerance)
+	}
+
+	auditSet.AllocationAgg, err = kubecost.NewAllocationAggAudit(accAllocSet, auditAggregateBy, ar.tolerance)
+	if err != nil {
+		return nil, fmt.Errorf("auditing aggregations for %s: %w", window, err)
+	}
+
+	ar.record(auditSet)
+	ar.emit(auditSet)
+
+	return auditSet, nil
+}
+
+// nodePrices returns the current hourly prices of the cloud provider for each
+// node of the local cluster, as emitted to Prometheus for costing Assets, in
+// the form expected by kubecost.NewAssetReconciliationAudit.
+func (ar *AuditRunner) nodePrices(ctx context.Context) (map[string]map[string]float64, error) {
+	if ar.model.Provider == nil || ar.model.Cache == nil {
+		return nil, fmt.Errorf("no cloud provider or cluster cache")
+	}
+
+	provider := cloud.ProviderWithContext(ctx, ar.model.Provider)
+	cfg, err := provider.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("getting pricing config: %w", err)
+	}
+
+	nodes, err := ar.model.GetNodeCost(provider)
+	if err != nil {
+		return nil, fmt.Errorf("getting node costs: %w", err)
+	}
+
+	// Like the emitted metrics, fall back to the configured prices for
+	// invalid prices
+	price := func(value, fallback string) float64 {
+		p, _ := strconv.ParseFloat(value, 64)
+		if math.IsNaN(p) || math.IsInf(p, 0) {
+			p, _ = strconv.ParseFloat(fallback, 64)
+			if math.IsNaN(p) || math.IsInf(p, 0) {
+				p = 0
+			}
+		}
+		return p
+	}
+
+	cluster := env.GetClusterID()
+	prices := make(map[string]map[string]float64, len(nodes))
+	for name, node := range nodes {
+		prices[fmt.Sprintf("%s/%s", cluster, name)] = map[string]float64{
+			"cpu": price(node.VCPUCost, cfg.CPU),
+			"ram": price(node.RAMCost, cfg.RAM),
+			"gpu": price(node.GPUCost, cfg.GPU),
+		}
+	}
+
+	return prices, nil
+}
+
+// record retains the given AuditSet, replacing any with the same window and
+// dropping the oldest beyond maxAuditSets.
+func (ar *AuditRunner) record(auditSet *kubecost.AuditSet) {
+	ar.lock.Lock()
+	defer ar.lock.Unlock()
+
+	sets := make([]*kubecost.AuditSet, 0, len(ar.sets)+1)
+	for _, set := range ar.sets {
+		if !set.Window.Equal(auditSet.Window) {
+			sets = append(sets, set)
+		}
+	}
+	sets = append(sets, auditSet)
+
+	if len(sets) > maxAuditSets {
+		sets = sets[len(sets)-maxAuditSets:]
+	}
+
+	ar.sets = sets
+}
+
+// emit records the divergence of each audit in the given AuditSet, and counts
+// those which failed.
+func (ar *AuditRunner) emit(auditSet *kubecost.AuditSet) {
+	initAuditMetrics()
+
+	statuses := auditSet.Statuses()
+	for auditType, divergence := range auditSet.MaxDivergence() {
+		auditDivergenceGv.WithLabelValues(string(auditType)).Set(divergence)
+
+		if statuses[auditType] == kubecost.FailedStatus {
+			log.Warnf("Audit: %s failed for %s with divergence %.4f", auditType, auditSet.Window, divergence)
+			auditFailuresCv.WithLabelValues(string(auditType)).Inc()
+		}
+	}
+}
+
+// AuditSets returns the retained AuditSets which overlap the given window,
+// containing only audits of the given type.
+func (ar *AuditRunner) AuditSets(window kubecost.Window, auditType kubecost.AuditType) *kubecost.AuditSetRange {
+	ar.lock.RLock()
+	defer ar.lock.RUnlock()
+
+	asr := &kubecost.AuditSetRange{}
+	for _, set := range ar.sets {
+		if window.Start() != nil && !set.Window.End().After(*window.Start()) {
+			continue
+		}
+		if window.End() != nil && !set.Window.Start().Before(*window.End()) {
+			continue
+		}
+
+		asr.Append(set.Filter(auditType))
+	}
+
+	return asr
+}
+
+// Coverage returns the windows covered by each type of audit in the retained
+// AuditSets.
+func (ar *AuditRunner) Coverage() *kubecost.AuditCoverage {
+	ar.lock.RLock()
+	defer ar.lock.RUnlock()
+
+	coverage := kubecost.NewAuditCoverage()
+	for _, set := range ar.sets {
+		coverage.Update(set)
+	}
+
+	return coverage
+}
@@ -0,0 +1,52 @@
+package costmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+)
+
+func TestAuditRunner_AuditSets(t *testing.T) {
+	ar := NewAuditRunner(nil, time.Hour, 24*time.Hour, kubecost.DefaultAuditTolerance)
+
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxAuditSets+2; i++ {
+		s := start.Add(time.Duration(i) * time.Hour)
+		auditSet := kubecost.NewAuditSet(s, s.Add(time.Hour))
+		auditSet.AllocationTotal = &kubecost.TotalAudit{Status: kubecost.PassedStatus}
+		if i%2 == 0 {
+			auditSet.ClusterEquality = &kubecost.EqualityAudit{Status: kubecost.PassedStatus}
+		}
+		ar.record(auditSet)
+	}
+
+	// Re-running a window replaces its AuditSet
+	last := start.Add(time.Duration(maxAuditSets+1) * time.Hour)
+	ar.record(kubecost.NewAuditSet(last, last.Add(time.Hour)))
+
+	all := ar.AuditSets(kubecost.NewWindow(nil, nil), kubecost.AuditAll)
+	if all.Length() != maxAuditSets {
+		t.Fatalf("expected %d audit sets; got %d", maxAuditSets, all.Length())
+	}
+
+	from, to := start.Add(10*time.Hour), start.Add(12*time.Hour)
+	asr := ar.AuditSets(kubecost.NewClosedWindow(from, to), kubecost.AuditClusterEquality)
+	if asr.Length() != 2 {
+		t.Fatalf("expected 2 audit sets; got %d", asr.Length())
+	}
+	asr.Each(func(i int, set *kubecost.AuditSet) {
+		if set.AllocationTotal != nil {
+			t.Fatalf("expected only %s audits", kubecost.AuditClusterEquality)
+		}
+	})
+
+	// The oldest sets, and the replaced set, are not covered
+	coverage := ar.Coverage()
+	if !coverage.AllocationTotal.Start().Equal(start.Add(2*time.Hour)) || !coverage.AllocationTotal.End().Equal(last) {
+		t.Fatalf("unexpected allocation total coverage: %s", coverage.AllocationTotal)
+	}
+	if coverage.AssetReconciliation.Start() != nil {
+		t.Fatalf("expected no asset reconciliation coverage; got %s", coverage.AssetReconciliation)
+	}
+}
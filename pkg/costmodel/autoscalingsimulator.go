@@ -0,0 +1,779 @@
+package costmodel
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/tracing"
+	"github.com/opencost/opencost/pkg/util"
+	"github.com/opencost/opencost/pkg/util/timeutil"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// simulatedSpotLabels mark a simulated node as spot, or preemptible, to the
+// pricing of each provider.
+var simulatedSpotLabels = map[string]string{
+	cloud.KarpenterCapacityTypeLabel:        cloud.KarpenterCapacitySpotTypeValue,
+	cloud.EKSCapacityTypeLabel:              cloud.EKSCapacitySpotTypeValue,
+	cloud.GKESpotLabel:                      "true",
+	"kubernetes.azure.com/scalesetpriority": "spot",
+}
+
+// AutoscalingSimulationRequest is a candidate node configuration with which
+// to simulate the cluster. SpotRatio is the fraction, between 0.0 and 1.0, of
+// the simulated nodes of each type which are spot.
+type AutoscalingSimulationRequest struct {
+	NodeTypes []*SimulatedNodeType `json:"nodeTypes"`
+	SpotRatio float64              `json:"spotRatio"`
+}
+
+// SimulatedNodeType is a kind of node which may be provisioned by the
+// simulation. The region defaults to that of the current nodes, and the
+// allocatable resources to those of current nodes of the same instance type,
+// or else to those priced by the provider. Labels and taints are matched
+// against the node selectors, node affinity and tolerations of pods. Each
+// instance type may only be given once. MaxNodes, if set, limits the number
+// of nodes of the type.
+type SimulatedNodeType struct {
+	InstanceType string            `json:"instanceType"`
+	Region       string            `json:"region,omitempty"`
+	CPUCores     float64           `json:"cpuCores,omitempty"`
+	RAMBytes     float64           `json:"ramBytes,omitempty"`
+	GPUs         float64           `json:"gpus,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Taints       []v1.Taint        `json:"taints,omitempty"`
+	MaxNodes     int               `json:"maxNodes,omitempty"`
+}
+
+// AutoscalingSimulation is the projected cost of running the current pods of
+// the cluster on the candidate node configuration, compared with the cost of
+// the current nodes over the window.
+type AutoscalingSimulation struct {
+	Window                kubecost.Window           `json:"window"`
+	SpotRatio             float64                   `json:"spotRatio"`
+	NodeGroups            []*SimulatedNodeGroup     `json:"nodeGroups"`
+	UnschedulablePods     []string                  `json:"unschedulablePods"`
+	CurrentNodes          int                       `json:"currentNodes"`
+	CurrentMonthlyCost    float64                   `json:"currentMonthlyCost"`
+	ProjectedNodes        int                       `json:"projectedNodes"`
+	ProjectedMonthlyCost  float64                   `json:"projectedMonthlyCost"`
+	MonthlyCostDifference float64                   `json:"monthlyCostDifference"`
+	Pricing               map[string]*SimulatedCost `json:"pricing"`
+}
+
+// SimulatedNodeGroup is the nodes of one type provisioned by the simulation.
+type SimulatedNodeGroup struct {
+	InstanceType    string  `json:"instanceType"`
+	Nodes           int     `json:"nodes"`
+	SpotNodes       int     `json:"spotNodes"`
+	OnDemandNodes   int     `json:"onDemandNodes"`
+	Pods            int     `json:"pods"`
+	CPUCores        float64 `json:"cpuCores"`
+	CPUCoresRequest float64 `json:"cpuCoresRequest"`
+	RAMBytes        float64 `json:"ramBytes"`
+	RAMBytesRequest float64 `json:"ramBytesRequest"`
+	MonthlyCost     float64 `json:"monthlyCost"`
+}
+
+// SimulatedCost is the hourly cost of a simulated node type, on-demand and
+// spot, as priced by the provider. Default is true if the provider could not
+// price the node type, and the configured default prices were used.
+type SimulatedCost struct {
+	OnDemandHourlyCost float64 `json:"onDemandHourlyCost"`
+	SpotHourlyCost     float64 `json:"spotHourlyCost"`
+	Default            bool    `json:"default,omitempty"`
+}
+
+// simulatedResources are the CPU cores, RAM bytes and GPUs requested by a pod
+// or allocatable on a node.
+type simulatedResources struct {
+	CPU float64
+	RAM float64
+	GPU float64
+}
+
+func (r simulatedResources) add(that simulatedResources) simulatedResources {
+	return simulatedResources{CPU: r.CPU + that.CPU, RAM: r.RAM + that.RAM, GPU: r.GPU + that.GPU}
+}
+
+func (r simulatedResources) sub(that simulatedResources) simulatedResources {
+	return simulatedResources{CPU: r.CPU - that.CPU, RAM: r.RAM - that.RAM, GPU: r.GPU - that.GPU}
+}
+
+func (r simulatedResources) fits(that simulatedResources) bool {
+	return that.CPU <= r.CPU && that.RAM <= r.RAM && that.GPU <= r.GPU
+}
+
+// simulatedNodeType is a SimulatedNodeType resolved for bin packing, with
+// its labels, allocatable resources and effective hourly cost.
+type simulatedNodeType struct {
+	*SimulatedNodeType
+	labels      map[string]string
+	allocatable simulatedResources
+	hourlyCost  float64
+}
+
+// simulatedNode is a node opened by the bin packing, with the resources
+// remaining after the daemon set pods and the pods placed on it.
+type simulatedNode struct {
+	nodeType  *simulatedNodeType
+	remaining simulatedResources
+	requested simulatedResources
+	pods      int
+}
+
+// SimulateAutoscaling bin packs the pods of the cluster onto the candidate
+// node types and compares the projected monthly cost with the cost of the
+// current nodes over the given window.
+func (cm *CostModel) SimulateAutoscaling(ctx context.Context, window kubecost.Window, req *AutoscalingSimulationRequest) (*AutoscalingSimulation, error) {
+	ctx, span := tracing.Start(ctx, "CostModel.SimulateAutoscaling")
+	defer span.End()
+
+	sim, err := cm.simulateAutoscaling(ctx, window, req)
+	tracing.RecordError(span, err)
+	return sim, err
+}
+
+func (cm *CostModel) simulateAutoscaling(ctx context.Context, window kubecost.Window, req *AutoscalingSimulationRequest) (*AutoscalingSimulation, error) {
+	if window.IsOpen() || window.IsNegative() {
+		return nil, fmt.Errorf("illegal window: %s", window)
+	}
+	if req == nil || len(req.NodeTypes) == 0 {
+		return nil, fmt.Errorf("bad request - at least one node type is required")
+	}
+	if req.SpotRatio < 0.0 || req.SpotRatio > 1.0 {
+		return nil, fmt.Errorf("bad request - spotRatio must be between 0.0 and 1.0, got %f", req.SpotRatio)
+	}
+
+	// Pricing and node groups are keyed by instance type
+	instanceTypes := map[string]bool{}
+	for _, nt := range req.NodeTypes {
+		if nt == nil {
+			continue
+		}
+		if instanceTypes[nt.InstanceType] {
+			return nil, fmt.Errorf("bad request - duplicate node type %s", nt.InstanceType)
+		}
+		instanceTypes[nt.InstanceType] = true
+	}
+
+	provider := cloud.ProviderWithContext(ctx, cm.Provider)
+	currentNodes := cm.Cache.GetAllNodes()
+
+	nodeTypes := make([]*simulatedNodeType, 0, len(req.NodeTypes))
+	pricing := map[string]*SimulatedCost{}
+	for _, nt := range req.NodeTypes {
+		snt, cost, err := resolveSimulatedNodeType(provider, nt, currentNodes, req.SpotRatio)
+		if err != nil {
+			return nil, err
+		}
+		nodeTypes = append(nodeTypes, snt)
+		pricing[nt.InstanceType] = cost
+	}
+
+	nodes, unschedulable := simulateBinPacking(cm.Cache.GetAllPods(), currentNodes, nodeTypes)
+
+	sim := &AutoscalingSimulation{
+		Window:            window.Clone(),
+		SpotRatio:         req.SpotRatio,
+		UnschedulablePods: make([]string, 0, len(unschedulable)),
+		Pricing:           pricing,
+	}
+
+	for _, pod := range unschedulable {
+		sim.UnschedulablePods = append(sim.UnschedulablePods, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+	}
+
+	sim.NodeGroups = simulatedNodeGroups(nodes, pricing, req.SpotRatio)
+	for _, group := range sim.NodeGroups {
+		sim.ProjectedNodes += group.Nodes
+		sim.ProjectedMonthlyCost += group.MonthlyCost
+	}
+
+	start, end := *window.Start(), *window.End()
+	clusterNodes, err := ClusterNodes(provider, cm.PrometheusClient, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error computing current nodes for %s: %w", window, err)
+	}
+
+	hours := window.Hours()
+	for _, node := range clusterNodes {
+		if node.Cluster != env.GetClusterID() {
+			continue
+		}
+
+		sim.CurrentNodes++
+		if hours > 0 {
+			totalCost := (node.CPUCost+node.RAMCost)*(1.0-node.Discount) + node.GPUCost
+			sim.CurrentMonthlyCost += totalCost / hours * timeutil.HoursPerMonth
+		}
+	}
+
+	sim.MonthlyCostDifference = sim.ProjectedMonthlyCost - sim.CurrentMonthlyCost
+
+	return sim, nil
+}
+
+// resolveSimulatedNodeType determines the labels, allocatable resources and
+// prices of a candidate node type.
+func resolveSimulatedNodeType(provider cloud.Provider, nt *SimulatedNodeType, currentNodes []*v1.Node, spotRatio float64) (*simulatedNodeType, *SimulatedCost, error) {
+	if nt == nil || nt.InstanceType == "" {
+		return nil, nil, fmt.Errorf("bad request - node types require an instance type")
+	}
+
+	region := nt.Region
+	if region == "" {
+		for _, n := range currentNodes {
+			if r, ok := util.GetRegion(n.Labels); ok {
+				region = r
+				break
+			}
+		}
+	}
+
+	labels := simulatedNodeLabels(nt, region)
+
+	allocatable := simulatedResources{CPU: nt.CPUCores, RAM: nt.RAMBytes, GPU: nt.GPUs}
+	if allocatable.CPU == 0 || allocatable.RAM == 0 {
+		for _, n := range currentNodes {
+			if it, _ := util.GetInstanceType(n.Labels); it != nt.InstanceType {
+				continue
+			}
+			if allocatable.CPU == 0 {
+				allocatable.CPU = float64(n.Status.Allocatable.Cpu().MilliValue()) / 1000.0
+			}
+			if allocatable.RAM == 0 {
+				allocatable.RAM = float64(n.Status.Allocatable.Memory().Value())
+			}
+			if allocatable.GPU == 0 {
+				if q, ok := n.Status.Allocatable["nvidia.com/gpu"]; ok {
+					allocatable.GPU = float64(q.Value())
+				}
+			}
+			break
+		}
+	}
+
+	onDemand, err := priceSimulatedNode(provider, labels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spotLabels := make(map[string]string, len(labels)+len(simulatedSpotLabels))
+	for k, v := range labels {
+		spotLabels[k] = v
+	}
+	for k, v := range simulatedSpotLabels {
+		spotLabels[k] = v
+	}
+	spot, err := priceSimulatedNode(provider, spotLabels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Fall back to the resources priced by the provider
+	if allocatable.CPU == 0 {
+		allocatable.CPU, _ = strconv.ParseFloat(onDemand.VCPU, 64)
+	}
+	if allocatable.RAM == 0 {
+		allocatable.RAM = parseSimulatedRAMBytes(onDemand)
+	}
+	if allocatable.GPU == 0 {
+		allocatable.GPU, _ = strconv.ParseFloat(onDemand.GPU, 64)
+	}
+	if allocatable.CPU <= 0 || allocatable.RAM <= 0 {
+		return nil, nil, fmt.Errorf("bad request - unable to determine the CPU and RAM of instance type %s; set cpuCores and ramBytes", nt.InstanceType)
+	}
+
+	cost := &SimulatedCost{
+		OnDemandHourlyCost: simulatedHourlyCost(onDemand, allocatable),
+		SpotHourlyCost:     simulatedHourlyCost(spot, allocatable),
+		Default:            onDemand.UsesBaseCPUPrice,
+	}
+
+	return &simulatedNodeType{
+		SimulatedNodeType: nt,
+		labels:            labels,
+		allocatable:       allocatable,
+		hourlyCost:        spotRatio*cost.SpotHourlyCost + (1.0-spotRatio)*cost.OnDemandHourlyCost,
+	}, cost, nil
+}
+
+// priceSimulatedNode prices a node with the given labels via the provider,
+// falling back to the configured default prices.
+func priceSimulatedNode(provider cloud.Provider, labels map[string]string) (*cloud.Node, error) {
+	node := &v1.Node{}
+	node.Labels = labels
+
+	cnode, err := provider.NodePricing(provider.GetKey(labels, node))
+	if err == nil && cnode != nil {
+		return cnode, nil
+	}
+
+	log.Infof("CostModel.SimulateAutoscaling: no pricing for %s, using default prices: %v", labels[v1.LabelInstanceTypeStable], err)
+
+	cfg, cfgErr := provider.GetConfig()
+	if cfgErr != nil {
+		return nil, fmt.Errorf("error getting default prices: %w", cfgErr)
+	}
+
+	return &cloud.Node{
+		VCPUCost:         cfg.CPU,
+		RAMCost:          cfg.RAM,
+		GPUCost:          cfg.GPU,
+		UsesBaseCPUPrice: true,
+	}, nil
+}
+
+// simulatedNodeLabels returns the labels of a simulated node: those of the
+// node type, along with its instance type, region and operating system.
+func simulatedNodeLabels(nt *SimulatedNodeType, region string) map[string]string {
+	labels := map[string]string{
+		v1.LabelInstanceType:       nt.InstanceType,
+		v1.LabelInstanceTypeStable: nt.InstanceType,
+		v1.LabelOSStable:           "linux",
+	}
+	if region != "" {
+		labels[v1.LabelTopologyRegion] = region
+	}
+	for k, v := range nt.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// simulatedHourlyCost returns the hourly cost of a priced node, either its
+// total cost or the sum of the cost of its resources.
+func simulatedHourlyCost(cnode *cloud.Node, allocatable simulatedResources) float64 {
+	if cost, err := strconv.ParseFloat(cnode.Cost, 64); err == nil && cost > 0 {
+		return cost
+	}
+
+	cpuCost, _ := strconv.ParseFloat(cnode.VCPUCost, 64)
+	ramCost, _ := strconv.ParseFloat(cnode.RAMCost, 64)
+	gpuCost, _ := strconv.ParseFloat(cnode.GPUCost, 64)
+
+	cost := cpuCost*allocatable.CPU + ramCost*allocatable.RAM/1024/1024/1024 + gpuCost*allocatable.GPU
+	if math.IsNaN(cost) {
+		return 0
+	}
+	return cost
+}
+
+// parseSimulatedRAMBytes parses the RAM of a priced node, e.g. "16 GiB".
+// Values without units are in GiB.
+func parseSimulatedRAMBytes(cnode *cloud.Node) float64 {
+	if b, err := strconv.ParseFloat(cnode.RAMBytes, 64); err == nil && b > 0 {
+		return b
+	}
+
+	ram := strings.ReplaceAll(cnode.RAM, " ", "")
+	ram = strings.TrimSuffix(strings.TrimSuffix(ram, "B"), "b")
+	if gib, err := strconv.ParseFloat(ram, 64); err == nil {
+		return gib * 1024 * 1024 * 1024
+	}
+	if q, err := resource.ParseQuantity(ram); err == nil {
+		return float64(q.Value())
+	}
+	return 0
+}
+
+// simulateBinPacking places the given pods onto nodes of the given types by
+// first-fit-decreasing: pods are placed, largest first, on the first open
+// node which they fit and may schedule on, or else on a new node of the
+// cheapest type which they fit and may schedule on. The requests of daemon
+// set pods, and of static pods running on every current node, are reserved on
+// every node on which they may schedule. Other static pods, e.g. those of the
+// control plane, are placed once each, like any other pod. Pods which fit no
+// node type are returned as unschedulable.
+func simulateBinPacking(pods []*v1.Pod, currentNodes []*v1.Node, nodeTypes []*simulatedNodeType) ([]*simulatedNode, []*v1.Pod) {
+	var workloads []*v1.Pod
+	daemonSets := map[string]*v1.Pod{}
+	var staticOwners []string
+	staticPods := map[string][]*v1.Pod{}
+
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+
+		owner, static := perNodeOwner(pod)
+		if owner == "" {
+			workloads = append(workloads, pod)
+		} else if static {
+			if _, ok := staticPods[owner]; !ok {
+				staticOwners = append(staticOwners, owner)
+			}
+			staticPods[owner] = append(staticPods[owner], pod)
+		} else if _, ok := daemonSets[owner]; !ok {
+			daemonSets[owner] = pod
+		}
+	}
+
+	for _, owner := range staticOwners {
+		mirrors := staticPods[owner]
+		if onEveryNode(mirrors, currentNodes) {
+			daemonSets[owner] = mirrors[0]
+		} else {
+			workloads = append(workloads, mirrors...)
+		}
+	}
+
+	// Reserve the requests of the daemon sets and static pods on each node
+	// type
+	overhead := make(map[*simulatedNodeType]simulatedResources, len(nodeTypes))
+	for _, nt := range nodeTypes {
+		for _, pod := range daemonSets {
+			if podSchedulesOn(pod, nt) {
+				overhead[nt] = overhead[nt].add(podRequests(pod))
+			}
+		}
+	}
+
+	// Sort pods by their largest share of the largest node type, descending
+	var largest simulatedResources
+	for _, nt := range nodeTypes {
+		largest.CPU = math.Max(largest.CPU, nt.allocatable.CPU)
+		largest.RAM = math.Max(largest.RAM, nt.allocatable.RAM)
+		largest.GPU = math.Max(largest.GPU, nt.allocatable.GPU)
+	}
+
+	requests := make(map[*v1.Pod]simulatedResources, len(workloads))
+	size := make(map[*v1.Pod]float64, len(workloads))
+	for _, pod := range workloads {
+		r := podRequests(pod)
+		requests[pod] = r
+
+		s := 0.0
+		if largest.CPU > 0 {
+			s = math.Max(s, r.CPU/largest.CPU)
+		}
+		if largest.RAM > 0 {
+			s = math.Max(s, r.RAM/largest.RAM)
+		}
+		if largest.GPU > 0 {
+			s = math.Max(s, r.GPU/largest.GPU)
+		}
+		size[pod] = s
+	}
+
+	sort.SliceStable(workloads, func(i, j int) bool {
+		if size[workloads[i]] != size[workloads[j]] {
+			return size[workloads[i]] > size[workloads[j]]
+		}
+		if workloads[i].Namespace != workloads[j].Namespace {
+			return workloads[i].Namespace < workloads[j].Namespace
+		}
+		return workloads[i].Name < workloads[j].Name
+	})
+
+	// Candidate node types for new nodes, cheapest first
+	byCost := make([]*simulatedNodeType, len(nodeTypes))
+	copy(byCost, nodeTypes)
+	sort.SliceStable(byCost, func(i, j int) bool {
+		return byCost[i].hourlyCost < byCost[j].hourlyCost
+	})
+
+	var nodes []*simulatedNode
+	var unschedulable []*v1.Pod
+	count := map[*simulatedNodeType]int{}
+
+	for _, pod := range workloads {
+		r := requests[pod]
+
+		var placed *simulatedNode
+		for _, node := range nodes {
+			if node.remaining.fits(r) && podSchedulesOn(pod, node.nodeType) {
+				placed = node
+				break
+			}
+		}
+
+		if placed == nil {
+			for _, nt := range byCost {
+				if nt.MaxNodes > 0 && count[nt] >= nt.MaxNodes {
+					continue
+				}
+
+				remaining := nt.allocatable.sub(overhead[nt])
+				if !remaining.fits(r) || !podSchedulesOn(pod, nt) {
+					continue
+				}
+
+				placed = &simulatedNode{nodeType: nt, remaining: remaining}
+				nodes = append(nodes, placed)
+				count[nt]++
+				break
+			}
+		}
+
+		if placed == nil {
+			unschedulable = append(unschedulable, pod)
+			continue
+		}
+
+		placed.remaining = placed.remaining.sub(r)
+		placed.requested = placed.requested.add(r)
+		placed.pods++
+	}
+
+	return nodes, unschedulable
+}
+
+// simulatedNodeGroups groups the simulated nodes by type, splitting each
+// group into spot and on-demand nodes by the spot ratio.
+func simulatedNodeGroups(nodes []*simulatedNode, pricing map[string]*SimulatedCost, spotRatio float64) []*SimulatedNodeGroup {
+	groups := map[string]*SimulatedNodeGroup{}
+
+	for _, node := range nodes {
+		it := node.nodeType.InstanceType
+		group, ok := groups[it]
+		if !ok {
+			group = &SimulatedNodeGroup{InstanceType: it}
+			groups[it] = group
+		}
+
+		group.Nodes++
+		group.Pods += node.pods
+		group.CPUCores += node.nodeType.allocatable.CPU
+		group.CPUCoresRequest += node.requested.CPU
+		group.RAMBytes += node.nodeType.allocatable.RAM
+		group.RAMBytesRequest += node.requested.RAM
+	}
+
+	result := make([]*SimulatedNodeGroup, 0, len(groups))
+	for it, group := range groups {
+		group.SpotNodes = int(math.Round(float64(group.Nodes) * spotRatio))
+		group.OnDemandNodes = group.Nodes - group.SpotNodes
+
+		if cost, ok := pricing[it]; ok {
+			hourly := float64(group.SpotNodes)*cost.SpotHourlyCost + float64(group.OnDemandNodes)*cost.OnDemandHourlyCost
+			group.MonthlyCost = hourly * timeutil.HoursPerMonth
+		}
+
+		result = append(result, group)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].InstanceType < result[j].InstanceType
+	})
+
+	return result
+}
+
+// perNodeOwner returns the name of the DaemonSet owning the given pod, or of
+// the static pod it mirrors, if any, and whether it is a static pod. Mirror
+// pods are owned by their Node, and named after the static pod with the name
+// of the node appended.
+func perNodeOwner(pod *v1.Pod) (string, bool) {
+	for _, ref := range pod.OwnerReferences {
+		switch ref.Kind {
+		case "DaemonSet":
+			return fmt.Sprintf("%s/%s", pod.Namespace, ref.Name), false
+		case "Node":
+			return fmt.Sprintf("%s/%s", pod.Namespace, strings.TrimSuffix(pod.Name, "-"+ref.Name)), true
+		}
+	}
+	return "", false
+}
+
+// onEveryNode returns true if the given mirror pods of a static pod run on
+// every one of the given nodes.
+func onEveryNode(mirrors []*v1.Pod, nodes []*v1.Node) bool {
+	if len(nodes) == 0 {
+		return false
+	}
+
+	running := make(map[string]bool, len(mirrors))
+	for _, pod := range mirrors {
+		for _, ref := range pod.OwnerReferences {
+			if ref.Kind == "Node" {
+				running[ref.Name] = true
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		if !running[node.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// podRequests returns the resources requested by a pod: the larger of the
+// sum of the requests of its containers and the largest request of its init
+// containers, plus its overhead.
+func podRequests(pod *v1.Pod) simulatedResources {
+	requests := func(c v1.Container) simulatedResources {
+		r := simulatedResources{
+			CPU: float64(c.Resources.Requests.Cpu().MilliValue()) / 1000.0,
+			RAM: float64(c.Resources.Requests.Memory().Value()),
+		}
+		if q, ok := c.Resources.Requests["nvidia.com/gpu"]; ok {
+			r.GPU = float64(q.Value())
+		} else if q, ok := c.Resources.Limits["nvidia.com/gpu"]; ok {
+			r.GPU = float64(q.Value())
+		}
+		return r
+	}
+
+	var total simulatedResources
+	for _, c := range pod.Spec.Containers {
+		total = total.add(requests(c))
+	}
+	for _, c := range pod.Spec.InitContainers {
+		r := requests(c)
+		total.CPU = math.Max(total.CPU, r.CPU)
+		total.RAM = math.Max(total.RAM, r.RAM)
+		total.GPU = math.Max(total.GPU, r.GPU)
+	}
+
+	if pod.Spec.Overhead != nil {
+		total.CPU += float64(pod.Spec.Overhead.Cpu().MilliValue()) / 1000.0
+		total.RAM += float64(pod.Spec.Overhead.Memory().Value())
+	}
+
+	return total
+}
+
+// podSchedulesOn returns true if the pod's node selector and required node
+// affinity match the labels of the node type, and the pod tolerates the
+// NoSchedule and NoExecute taints of the node type. Pinning to a current node,
+// by its hostname label, name field or the pod's node name, is ignored, as
+// the simulated nodes replace the current ones.
+func podSchedulesOn(pod *v1.Pod, nt *simulatedNodeType) bool {
+	for k, v := range pod.Spec.NodeSelector {
+		if k == v1.LabelHostname {
+			continue
+		}
+		if nt.labels[k] != v {
+			return false
+		}
+	}
+
+	if pod.Spec.Affinity != nil && pod.Spec.Affinity.NodeAffinity != nil {
+		if required := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+			if !nodeSelectorMatches(required, nt.labels) {
+				return false
+			}
+		}
+	}
+
+	for i := range nt.Taints {
+		taint := &nt.Taints[i]
+		if taint.Effect == v1.TaintEffectPreferNoSchedule {
+			continue
+		}
+
+		tolerated := false
+		for j := range pod.Spec.Tolerations {
+			if pod.Spec.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+
+	return true
+}
+
+// nodeSelectorMatches returns true if any of the terms of the node selector
+// match the given labels. Terms match if all of their label expressions
+// match. Expressions pinning a node, by its hostname label or name field, are
+// ignored; other field expressions are not supported, and never match.
+func nodeSelectorMatches(selector *v1.NodeSelector, labels map[string]string) bool {
+	if len(selector.NodeSelectorTerms) == 0 {
+		return true
+	}
+
+	for _, term := range selector.NodeSelectorTerms {
+		if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+			continue
+		}
+
+		matches := true
+		for _, req := range term.MatchFields {
+			if req.Key != "metadata.name" {
+				matches = false
+				break
+			}
+		}
+		for _, req := range term.MatchExpressions {
+			if !matches {
+				break
+			}
+			if req.Key == v1.LabelHostname {
+				continue
+			}
+			if !nodeSelectorRequirementMatches(req, labels) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nodeSelectorRequirementMatches(req v1.NodeSelectorRequirement, labels map[string]string) bool {
+	value, ok := labels[req.Key]
+
+	switch req.Operator {
+	case v1.NodeSelectorOpIn:
+		if !ok {
+			return false
+		}
+		for _, v := range req.Values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	case v1.NodeSelectorOpNotIn:
+		for _, v := range req.Values {
+			if ok && v == value {
+				return false
+			}
+		}
+		return true
+	case v1.NodeSelectorOpExists:
+		return ok
+	case v1.NodeSelectorOpDoesNotExist:
+		return !ok
+	case v1.NodeSelectorOpGt, v1.NodeSelectorOpLt:
+		if !ok || len(req.Values) != 1 {
+			return false
+		}
+		actual, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		expected, err := strconv.ParseInt(req.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if req.Operator == v1.NodeSelectorOpGt {
+			return actual > expected
+		}
+		return actual < expected
+	default:
+		return false
+	}
+}
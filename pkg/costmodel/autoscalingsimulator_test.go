@@ -0,0 +1,316 @@
+package costmodel
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/util"
+	"github.com/opencost/opencost/pkg/util/timeutil"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newSimulatedPod(name, cpu, ram string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: name},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Name: "container1",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse(cpu),
+						v1.ResourceMemory: resource.MustParse(ram),
+					},
+				},
+			}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func newSimulatedNodeType(instanceType string, cpu, ramGiB, hourlyCost float64, labels map[string]string, taints ...v1.Taint) *simulatedNodeType {
+	nt := &SimulatedNodeType{InstanceType: instanceType, Labels: labels, Taints: taints}
+	return &simulatedNodeType{
+		SimulatedNodeType: nt,
+		labels:            simulatedNodeLabels(nt, "us-east-1"),
+		allocatable:       simulatedResources{CPU: cpu, RAM: ramGiB * 1024 * 1024 * 1024},
+		hourlyCost:        hourlyCost,
+	}
+}
+
+func newSimulatedCurrentNode(name string) *v1.Node {
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func TestSimulateBinPacking(t *testing.T) {
+	small := newSimulatedNodeType("small", 2, 4, 0.1, nil)
+	large := newSimulatedNodeType("large", 8, 16, 0.5, nil)
+
+	daemon := newSimulatedPod("daemon-abc", "500m", "512Mi")
+	daemon.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "daemon"}}
+	daemon2 := newSimulatedPod("daemon-def", "500m", "512Mi")
+	daemon2.OwnerReferences = daemon.OwnerReferences
+
+	// Mirror pods of the same static pod on two nodes
+	static := newSimulatedPod("kube-proxy-node1", "250m", "256Mi")
+	static.OwnerReferences = []metav1.OwnerReference{{Kind: "Node", Name: "node1"}}
+	static2 := newSimulatedPod("kube-proxy-node2", "250m", "256Mi")
+	static2.OwnerReferences = []metav1.OwnerReference{{Kind: "Node", Name: "node2"}}
+
+	done := newSimulatedPod("done", "4", "1Gi")
+	done.Status.Phase = v1.PodSucceeded
+
+	pods := []*v1.Pod{
+		newSimulatedPod("pod1", "1", "1Gi"),
+		newSimulatedPod("pod2", "1", "1Gi"),
+		newSimulatedPod("pod3", "4", "4Gi"),
+		newSimulatedPod("pod4", "16", "1Gi"),
+		daemon,
+		daemon2,
+		static,
+		static2,
+		done,
+	}
+
+	currentNodes := []*v1.Node{newSimulatedCurrentNode("node1"), newSimulatedCurrentNode("node2")}
+
+	nodes, unschedulable := simulateBinPacking(pods, currentNodes, []*simulatedNodeType{large, small})
+
+	if len(unschedulable) != 1 || unschedulable[0].Name != "pod4" {
+		t.Fatalf("expected pod4 to be unschedulable; got %v", unschedulable)
+	}
+
+	// pod3 only fits a large node, and opens it first. Daemon set and static
+	// pod overhead leaves 3.25 cores, of which pod1 and pod2 take 2.
+	if len(nodes) != 1 {
+		t.Fatalf("expected 1 node; got %d", len(nodes))
+	}
+	if nodes[0].nodeType != large || nodes[0].pods != 3 {
+		t.Fatalf("expected 3 pods on a large node; got %d on %s", nodes[0].pods, nodes[0].nodeType.InstanceType)
+	}
+	if !util.IsApproximately(nodes[0].remaining.CPU, 1.25) {
+		t.Fatalf("expected 1.25 cores remaining; got %f", nodes[0].remaining.CPU)
+	}
+
+	// Without the large node type, the small pods are packed onto the
+	// cheapest type which fits them, one per node after overhead.
+	nodes, unschedulable = simulateBinPacking(pods, currentNodes, []*simulatedNodeType{small})
+	if len(unschedulable) != 2 {
+		t.Fatalf("expected 2 unschedulable pods; got %d", len(unschedulable))
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("expected 2 nodes; got %d", len(nodes))
+	}
+
+	// MaxNodes limits the nodes opened of a type
+	small.MaxNodes = 1
+	nodes, unschedulable = simulateBinPacking(pods, currentNodes, []*simulatedNodeType{small})
+	if len(nodes) != 1 || len(unschedulable) != 3 {
+		t.Fatalf("expected 1 node and 3 unschedulable pods; got %d and %d", len(nodes), len(unschedulable))
+	}
+}
+
+func TestSimulateBinPacking_PinnedStaticPods(t *testing.T) {
+	large := newSimulatedNodeType("large", 8, 16, 0.5, nil)
+	currentNodes := []*v1.Node{newSimulatedCurrentNode("node1"), newSimulatedCurrentNode("node2")}
+
+	proxy := newSimulatedPod("kube-proxy-node1", "250m", "256Mi")
+	proxy.OwnerReferences = []metav1.OwnerReference{{Kind: "Node", Name: "node1"}}
+	proxy2 := newSimulatedPod("kube-proxy-node2", "250m", "256Mi")
+	proxy2.OwnerReferences = []metav1.OwnerReference{{Kind: "Node", Name: "node2"}}
+
+	// A control plane static pod pinned to one node is not overhead of
+	// every node, but is placed once
+	apiserver := newSimulatedPod("kube-apiserver-node1", "1", "1Gi")
+	apiserver.OwnerReferences = []metav1.OwnerReference{{Kind: "Node", Name: "node1"}}
+
+	pods := []*v1.Pod{newSimulatedPod("pod1", "1", "1Gi"), proxy, proxy2, apiserver}
+
+	nodes, unschedulable := simulateBinPacking(pods, currentNodes, []*simulatedNodeType{large})
+	if len(unschedulable) != 0 {
+		t.Fatalf("expected no unschedulable pods; got %v", unschedulable)
+	}
+	if len(nodes) != 1 || nodes[0].pods != 2 {
+		t.Fatalf("expected pod1 and kube-apiserver on 1 node; got %d nodes", len(nodes))
+	}
+	if !util.IsApproximately(nodes[0].remaining.CPU, 5.75) {
+		t.Fatalf("expected 5.75 cores remaining; got %f", nodes[0].remaining.CPU)
+	}
+
+	// kube-proxy is no longer on every node once a node is added
+	currentNodes = append(currentNodes, newSimulatedCurrentNode("node3"))
+	nodes, _ = simulateBinPacking(pods, currentNodes, []*simulatedNodeType{large})
+	if len(nodes) != 1 || nodes[0].pods != 4 {
+		t.Fatalf("expected all 4 pods placed on 1 node; got %d nodes", len(nodes))
+	}
+}
+
+func TestPodSchedulesOn(t *testing.T) {
+	taint := v1.Taint{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}
+	general := newSimulatedNodeType("general", 4, 16, 0.2, map[string]string{"pool": "general"})
+	gpu := newSimulatedNodeType("gpu", 4, 16, 1.0, map[string]string{"pool": "gpu", "generation": "4"}, taint)
+
+	plain := newSimulatedPod("plain", "1", "1Gi")
+
+	selected := newSimulatedPod("selected", "1", "1Gi")
+	selected.Spec.NodeSelector = map[string]string{"pool": "gpu"}
+
+	tolerating := newSimulatedPod("tolerating", "1", "1Gi")
+	tolerating.Spec.NodeSelector = map[string]string{"pool": "gpu"}
+	tolerating.Spec.Tolerations = []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "gpu", Effect: v1.TaintEffectNoSchedule}}
+
+	affinity := newSimulatedPod("affinity", "1", "1Gi")
+	affinity.Spec.Tolerations = []v1.Toleration{{Operator: v1.TolerationOpExists}}
+	affinity.Spec.Affinity = &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{{
+					MatchExpressions: []v1.NodeSelectorRequirement{
+						{Key: "generation", Operator: v1.NodeSelectorOpGt, Values: []string{"3"}},
+						{Key: v1.LabelInstanceTypeStable, Operator: v1.NodeSelectorOpIn, Values: []string{"gpu", "gpu-large"}},
+					},
+				}},
+			},
+		},
+	}
+
+	hostname := newSimulatedPod("hostname", "1", "1Gi")
+	hostname.Spec.NodeName = "node1"
+	hostname.Spec.NodeSelector = map[string]string{v1.LabelHostname: "node1", "pool": "general"}
+
+	// Daemon set pods are pinned to their node by a name field
+	daemon := newSimulatedPod("daemon", "1", "1Gi")
+	daemon.Spec.Tolerations = []v1.Toleration{{Operator: v1.TolerationOpExists}}
+	daemon.Spec.Affinity = &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{{
+					MatchFields: []v1.NodeSelectorRequirement{
+						{Key: "metadata.name", Operator: v1.NodeSelectorOpIn, Values: []string{"node1"}},
+					},
+				}},
+			},
+		},
+	}
+
+	hostnameAffinity := newSimulatedPod("hostnameAffinity", "1", "1Gi")
+	hostnameAffinity.Spec.Affinity = &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{{
+					MatchExpressions: []v1.NodeSelectorRequirement{
+						{Key: v1.LabelHostname, Operator: v1.NodeSelectorOpIn, Values: []string{"node1"}},
+						{Key: "pool", Operator: v1.NodeSelectorOpIn, Values: []string{"general"}},
+					},
+				}},
+			},
+		},
+	}
+
+	otherField := newSimulatedPod("otherField", "1", "1Gi")
+	otherField.Spec.Affinity = &v1.Affinity{
+		NodeAffinity: &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{{
+					MatchFields: []v1.NodeSelectorRequirement{
+						{Key: "spec.unknown", Operator: v1.NodeSelectorOpIn, Values: []string{"x"}},
+					},
+				}},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		pod      *v1.Pod
+		nodeType *simulatedNodeType
+		expected bool
+	}{
+		"plain pod on general node":             {plain, general, true},
+		"plain pod on tainted node":             {plain, gpu, false},
+		"selector mismatch":                     {selected, general, false},
+		"selector match, untolerated taint":     {selected, gpu, false},
+		"selector match, tolerated taint":       {tolerating, gpu, true},
+		"affinity match":                        {affinity, gpu, true},
+		"affinity mismatch, missing generation": {affinity, general, false},
+		"hostname selector ignored":             {hostname, general, true},
+		"hostname selector, other label":        {hostname, gpu, false},
+		"daemon set name field ignored":         {daemon, gpu, true},
+		"hostname affinity ignored":             {hostnameAffinity, general, true},
+		"other field expression":                {otherField, general, false},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if actual := podSchedulesOn(c.pod, c.nodeType); actual != c.expected {
+				t.Fatalf("expected %t; got %t", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSimulateAutoscaling_DuplicateNodeTypes(t *testing.T) {
+	cm := &CostModel{}
+	window := kubecost.NewClosedWindow(time.Now().Add(-time.Hour), time.Now())
+	req := &AutoscalingSimulationRequest{
+		NodeTypes: []*SimulatedNodeType{{InstanceType: "m5.large"}, {InstanceType: "m5.large", MaxNodes: 2}},
+	}
+
+	_, err := cm.SimulateAutoscaling(context.Background(), window, req)
+	if err == nil || !strings.Contains(err.Error(), "duplicate node type m5.large") {
+		t.Fatalf("expected duplicate node type error; got %v", err)
+	}
+}
+
+func TestSimulatedNodePricing(t *testing.T) {
+	allocatable := simulatedResources{CPU: 4, RAM: 16 * 1024 * 1024 * 1024, GPU: 1}
+
+	// Total cost takes precedence over the cost of resources
+	if cost := simulatedHourlyCost(&cloud.Node{Cost: "0.5", VCPUCost: "1"}, allocatable); !util.IsApproximately(cost, 0.5) {
+		t.Fatalf("expected hourly cost 0.5; got %f", cost)
+	}
+
+	cnode := &cloud.Node{VCPUCost: "0.03", RAMCost: "0.004", GPUCost: "0.9"}
+	if cost := simulatedHourlyCost(cnode, allocatable); !util.IsApproximately(cost, 4*0.03+16*0.004+0.9) {
+		t.Fatalf("expected hourly cost %f; got %f", 4*0.03+16*0.004+0.9, cost)
+	}
+
+	ramCases := map[string]struct {
+		cnode    *cloud.Node
+		expected float64
+	}{
+		"bytes":    {&cloud.Node{RAMBytes: "1073741824", RAM: "2 GiB"}, 1024 * 1024 * 1024},
+		"GiB":      {&cloud.Node{RAM: "16 GiB"}, 16 * 1024 * 1024 * 1024},
+		"no units": {&cloud.Node{RAM: "8"}, 8 * 1024 * 1024 * 1024},
+		"quantity": {&cloud.Node{RAM: "512Mi"}, 512 * 1024 * 1024},
+		"missing":  {&cloud.Node{}, 0},
+	}
+	for name, c := range ramCases {
+		t.Run(name, func(t *testing.T) {
+			if actual := parseSimulatedRAMBytes(c.cnode); !util.IsApproximately(actual, c.expected) {
+				t.Fatalf("expected %f RAM bytes; got %f", c.expected, actual)
+			}
+		})
+	}
+
+	// Groups split their nodes into spot and on-demand by the spot ratio
+	nt := newSimulatedNodeType("m5.xlarge", 4, 16, 0, nil)
+	nodes := []*simulatedNode{{nodeType: nt, pods: 2}, {nodeType: nt, pods: 1}, {nodeType: nt}, {nodeType: nt}}
+	pricing := map[string]*SimulatedCost{"m5.xlarge": {OnDemandHourlyCost: 0.2, SpotHourlyCost: 0.1}}
+
+	groups := simulatedNodeGroups(nodes, pricing, 0.5)
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 node group; got %d", len(groups))
+	}
+	if groups[0].SpotNodes != 2 || groups[0].OnDemandNodes != 2 || groups[0].Pods != 3 {
+		t.Fatalf("expected 2 spot and 2 on-demand nodes with 3 pods; got %d, %d and %d", groups[0].SpotNodes, groups[0].OnDemandNodes, groups[0].Pods)
+	}
+	if expected := (2*0.1 + 2*0.2) * timeutil.HoursPerMonth; !util.IsApproximately(groups[0].MonthlyCost, expected) {
+		t.Fatalf("expected monthly cost %f; got %f", expected, groups[0].MonthlyCost)
+	}
+}
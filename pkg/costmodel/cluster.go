@@ -43,6 +43,11 @@ const (
 
 const maxLocalDiskSize = 200 // AWS limits root disks to 100 Gi, and occasional metric errors in filesystem size should not contribute to large costs.
 
+// localStorageCostPerGiBHr is the price of local disks, by which both local
+// disk assets and the ephemeral storage of allocations are priced.
+// TODO niko/assets how do we not hard-code this price?
+const localStorageCostPerGiBHr = 0.04 / 730.0
+
 // Costs represents cumulative and monthly cluster costs over a given duration. Costs
 // are broken down by cores, memory, and storage.
 type ClusterCosts struct {
@@ -115,6 +120,8 @@ type Disk struct {
 	ClaimName      string
 	ClaimNamespace string
 	Cost           float64
+	IOPSCost       float64 // included in Cost
+	ThroughputCost float64 // included in Cost
 	Bytes          float64
 
 	// These two fields may not be available at all times because they rely on
@@ -167,11 +174,12 @@ func ClusterDisks(client prometheus.Client, provider cloud.Provider, start, end
 	// [$/hr] * [min/res]*[hr/min] = [$/res]
 	hourlyToCumulative := float64(minsPerResolution) * (1.0 / 60.0)
 
-	// TODO niko/assets how do we not hard-code this price?
-	costPerGBHr := 0.04 / 730.0
+	costPerGBHr := localStorageCostPerGiBHr
 
 	ctx := prom.NewNamedContext(client, prom.ClusterContextName)
 	queryPVCost := fmt.Sprintf(`avg(avg_over_time(pv_hourly_cost[%s])) by (%s, persistentvolume,provider_id)`, durStr, env.GetPromClusterLabel())
+	queryPVIOPSCost := fmt.Sprintf(`avg(avg_over_time(pv_iops_hourly_cost[%s])) by (%s, persistentvolume)`, durStr, env.GetPromClusterLabel())
+	queryPVThroughputCost := fmt.Sprintf(`avg(avg_over_time(pv_throughput_hourly_cost[%s])) by (%s, persistentvolume)`, durStr, env.GetPromClusterLabel())
 	queryPVSize := fmt.Sprintf(`avg(avg_over_time(kube_persistentvolume_capacity_bytes[%s])) by (%s, persistentvolume)`, durStr, env.GetPromClusterLabel())
 	queryActiveMins := fmt.Sprintf(`avg(kube_persistentvolume_capacity_bytes) by (%s, persistentvolume)[%s:%dm]`, env.GetPromClusterLabel(), durStr, minsPerResolution)
 	queryPVStorageClass := fmt.Sprintf(`avg(avg_over_time(kubecost_pv_info[%s])) by (%s, persistentvolume, storageclass)`, durStr, env.GetPromClusterLabel())
@@ -186,6 +194,8 @@ func ClusterDisks(client prometheus.Client, provider cloud.Provider, start, end
 	queryLocalActiveMins := fmt.Sprintf(`count(node_total_hourly_cost) by (%s, node)[%s:%dm]`, env.GetPromClusterLabel(), durStr, minsPerResolution)
 
 	resChPVCost := ctx.QueryAtTime(queryPVCost, t)
+	resChPVIOPSCost := ctx.QueryAtTime(queryPVIOPSCost, t)
+	resChPVThroughputCost := ctx.QueryAtTime(queryPVThroughputCost, t)
 	resChPVSize := ctx.QueryAtTime(queryPVSize, t)
 	resChActiveMins := ctx.QueryAtTime(queryActiveMins, t)
 	resChPVStorageClass := ctx.QueryAtTime(queryPVStorageClass, t)
@@ -200,6 +210,8 @@ func ClusterDisks(client prometheus.Client, provider cloud.Provider, start, end
 	resChLocalActiveMins := ctx.QueryAtTime(queryLocalActiveMins, t)
 
 	resPVCost, _ := resChPVCost.Await()
+	resPVIOPSCost, _ := resChPVIOPSCost.Await()
+	resPVThroughputCost, _ := resChPVThroughputCost.Await()
 	resPVSize, _ := resChPVSize.Await()
 	resActiveMins, _ := resChActiveMins.Await()
 	resPVStorageClass, _ := resChPVStorageClass.Await()
@@ -256,6 +268,7 @@ func ClusterDisks(client prometheus.Client, provider cloud.Provider, start, end
 	}
 
 	pvCosts(diskMap, resolution, resActiveMins, resPVSize, resPVCost, resPVUsedAvg, resPVUsedMax, resPVCInfo, provider)
+	pvPerformanceCosts(diskMap, resPVIOPSCost, resPVThroughputCost)
 
 	for _, result := range resLocalStorageCost {
 		cluster, err := result.GetString(env.GetPromClusterLabel())
@@ -1298,6 +1311,52 @@ func ClusterCostsOverTime(cli prometheus.Client, provider cloud.Provider, startS
 	}, nil
 }
 
+// pvPerformanceCosts adds the cost of the IOPS and throughput provisioned for
+// each PV over its running time to the cost of its disk.
+func pvPerformanceCosts(diskMap map[DiskIdentifier]*Disk, resPVIOPSCost, resPVThroughputCost []*prom.QueryResult) {
+	for _, result := range resPVIOPSCost {
+		cluster, err := result.GetString(env.GetPromClusterLabel())
+		if err != nil {
+			cluster = env.GetClusterID()
+		}
+
+		name, err := result.GetString("persistentvolume")
+		if err != nil {
+			log.Warnf("ClusterDisks: PV IOPS cost data missing persistentvolume")
+			continue
+		}
+
+		disk, ok := diskMap[DiskIdentifier{cluster, name}]
+		if !ok {
+			continue
+		}
+		cost := result.Values[0].Value * (disk.Minutes / 60)
+		disk.IOPSCost += cost
+		disk.Cost += cost
+	}
+
+	for _, result := range resPVThroughputCost {
+		cluster, err := result.GetString(env.GetPromClusterLabel())
+		if err != nil {
+			cluster = env.GetClusterID()
+		}
+
+		name, err := result.GetString("persistentvolume")
+		if err != nil {
+			log.Warnf("ClusterDisks: PV throughput cost data missing persistentvolume")
+			continue
+		}
+
+		disk, ok := diskMap[DiskIdentifier{cluster, name}]
+		if !ok {
+			continue
+		}
+		cost := result.Values[0].Value * (disk.Minutes / 60)
+		disk.ThroughputCost += cost
+		disk.Cost += cost
+	}
+}
+
 func pvCosts(diskMap map[DiskIdentifier]*Disk, resolution time.Duration, resActiveMins, resPVSize, resPVCost, resPVUsedAvg, resPVUsedMax, resPVCInfo []*prom.QueryResult, cp cloud.Provider) {
 	for _, result := range resActiveMins {
 		cluster, err := result.GetString(env.GetPromClusterLabel())
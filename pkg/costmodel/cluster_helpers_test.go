@@ -1077,3 +1077,49 @@ func TestAssetCustompricing(t *testing.T) {
 	}
 
 }
+
+func TestPVPerformanceCosts(t *testing.T) {
+	diskMap := map[DiskIdentifier]*Disk{
+		{Cluster: "cluster1", Name: "pv1"}: {
+			Cluster:   "cluster1",
+			Name:      "pv1",
+			Cost:      0.1,
+			Minutes:   600,
+			Breakdown: &ClusterCostsBreakdown{},
+		},
+	}
+
+	resIOPSCost := []*prom.QueryResult{
+		{
+			Metric: map[string]interface{}{"cluster_id": "cluster1", "persistentvolume": "pv1"},
+			Values: []*util.Vector{{Value: 0.02}},
+		},
+	}
+	resThroughputCost := []*prom.QueryResult{
+		{
+			Metric: map[string]interface{}{"cluster_id": "cluster1", "persistentvolume": "pv1"},
+			Values: []*util.Vector{{Value: 0.005}},
+		},
+		// Disks which are not known are not created
+		{
+			Metric: map[string]interface{}{"cluster_id": "cluster1", "persistentvolume": "pv2"},
+			Values: []*util.Vector{{Value: 0.005}},
+		},
+	}
+
+	pvPerformanceCosts(diskMap, resIOPSCost, resThroughputCost)
+
+	if len(diskMap) != 1 {
+		t.Fatalf("expected 1 disk; got %d", len(diskMap))
+	}
+	disk := diskMap[DiskIdentifier{Cluster: "cluster1", Name: "pv1"}]
+	if !util.IsApproximately(disk.IOPSCost, 0.2) {
+		t.Fatalf("expected IOPS cost of 0.2; got %f", disk.IOPSCost)
+	}
+	if !util.IsApproximately(disk.ThroughputCost, 0.05) {
+		t.Fatalf("expected throughput cost of 0.05; got %f", disk.ThroughputCost)
+	}
+	if !util.IsApproximately(disk.Cost, 0.35) {
+		t.Fatalf("expected cost of 0.35; got %f", disk.Cost)
+	}
+}
@@ -1,6 +1,7 @@
 package costmodel
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -16,6 +17,7 @@ import (
 	"github.com/opencost/opencost/pkg/kubecost"
 	"github.com/opencost/opencost/pkg/log"
 	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/tracing"
 	"github.com/opencost/opencost/pkg/util"
 	prometheus "github.com/prometheus/client_golang/api"
 	prometheusClient "github.com/prometheus/client_golang/api"
@@ -52,12 +54,15 @@ var isCron = regexp.MustCompile(`^(.+)-(\d{10}|\d{8})$`)
 
 type CostModel struct {
 	Cache                      clustercache.ClusterCache
+	ClusterSnapshots           *clustercache.ClusterSnapshotStore
 	ClusterMap                 clusters.ClusterMap
 	MaxPrometheusQueryDuration time.Duration
 	RequestGroup               *singleflight.Group
 	ScrapeInterval             time.Duration
 	PrometheusClient           prometheus.Client
 	Provider                   costAnalyzerCloud.Provider
+	TotalsStore                kubecost.TotalsStore
+	NetworkFlows               *NetworkFlowSource
 	pricingMetadata            *costAnalyzerCloud.PricingMatchMetadata
 }
 
@@ -931,6 +936,12 @@ func GetPVCost(pv *costAnalyzerCloud.PV, kpv *v1.PersistentVolume, cp costAnalyz
 		pv.Cost = cfg.Storage
 		return err
 	}
+	if pvWithCost != nil {
+		pv.ProvisionedIOPS = pvWithCost.ProvisionedIOPS
+		pv.ProvisionedThroughput = pvWithCost.ProvisionedThroughput
+		pv.IOPSCost = pvWithCost.IOPSCost
+		pv.ThroughputCost = pvWithCost.ThroughputCost
+	}
 	if pvWithCost == nil || pvWithCost.Cost == "" {
 		pv.Cost = cfg.Storage
 		return nil // set default cost
@@ -2295,19 +2306,76 @@ func measureTimeAsync(start time.Time, threshold time.Duration, name string, ch
 	}
 }
 
-func (cm *CostModel) QueryAllocation(window kubecost.Window, resolution, step time.Duration, aggregate []string, includeIdle, idleByNode, includeProportionalAssetResourceCosts bool) (*kubecost.AllocationSetRange, error) {
+func (cm *CostModel) QueryAllocation(traceCtx context.Context, window kubecost.Window, resolution, step time.Duration, aggregate []string, filter kubecost.AllocationFilter, sharingPolicies []*kubecost.SharingPolicy, idleOwners []*kubecost.IdleOwner, includeIdle, idleByNode, idleByNodePool, includeProportionalAssetResourceCosts bool) (*kubecost.AllocationSetRange, error) {
+	asr, opts, err := cm.queryAllocationSets(traceCtx, window, resolution, step, filter, sharingPolicies, idleOwners, includeIdle, idleByNode, idleByNodePool, includeProportionalAssetResourceCosts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aggregate
+	err = asr.AggregateBy(aggregate, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating for %s: %w", window, err)
+	}
+
+	return asr, nil
+}
+
+// QueryAllocationHierarchy is QueryAllocation, but aggregates each step into
+// an AllocationTree, nesting each of the aggregate properties within the
+// previous one, rather than flattening them into composite keys.
+func (cm *CostModel) QueryAllocationHierarchy(traceCtx context.Context, window kubecost.Window, resolution, step time.Duration, aggregate []string, filter kubecost.AllocationFilter, sharingPolicies []*kubecost.SharingPolicy, idleOwners []*kubecost.IdleOwner, includeIdle, idleByNode, idleByNodePool, includeProportionalAssetResourceCosts bool) ([]*kubecost.AllocationTree, error) {
+	if len(aggregate) == 0 {
+		return nil, errors.New("bad request - hierarchy requires at least one aggregate property")
+	}
+
+	asr, opts, err := cm.queryAllocationSets(traceCtx, window, resolution, step, filter, sharingPolicies, idleOwners, includeIdle, idleByNode, idleByNodePool, includeProportionalAssetResourceCosts)
+	if err != nil {
+		return nil, err
+	}
+
+	trees := make([]*kubecost.AllocationTree, 0, len(asr.Allocations))
+	for _, as := range asr.Allocations {
+		tree, err := as.AggregateByHierarchy(aggregate, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error aggregating hierarchy for %s: %w", as.Window, err)
+		}
+		trees = append(trees, tree)
+	}
+
+	return trees, nil
+}
+
+// queryAllocationSets computes the unaggregated AllocationSets for each step
+// of the window, including idle if requested, and returns them with the
+// options with which they should be aggregated.
+func (cm *CostModel) queryAllocationSets(traceCtx context.Context, window kubecost.Window, resolution, step time.Duration, filter kubecost.AllocationFilter, sharingPolicies []*kubecost.SharingPolicy, idleOwners []*kubecost.IdleOwner, includeIdle, idleByNode, idleByNodePool, includeProportionalAssetResourceCosts bool) (*kubecost.AllocationSetRange, *kubecost.AllocationAggregationOptions, error) {
 	// Validate window is legal
 	if window.IsOpen() || window.IsNegative() {
-		return nil, fmt.Errorf("illegal window: %s", window)
+		return nil, nil, fmt.Errorf("illegal window: %s", window)
+	}
+
+	// Idle is required for charging idle to its owners
+	if len(idleOwners) > 0 && !includeIdle {
+		return nil, nil, errors.New("bad request - includeIdle must be set true if idleOwners is set")
 	}
 
 	// Idle is required for proportional asset costs
 	if includeProportionalAssetResourceCosts {
 		if !includeIdle {
-			return nil, errors.New("bad request - includeIdle must be set true if includeProportionalAssetResourceCosts is true")
+			return nil, nil, errors.New("bad request - includeIdle must be set true if includeProportionalAssetResourceCosts is true")
 		}
 	}
 
+	// Push the filter down into the Prometheus queries, unless computing idle
+	// or sharing by policy, which require the Allocations of every workload.
+	// Either way, the full filter is applied on aggregation.
+	var pushdown *allocationPushdown
+	if filter != nil && !includeIdle && len(sharingPolicies) == 0 {
+		pushdown = newAllocationPushdown(filter)
+		log.Debugf("CostModel.QueryAllocation: filter %s: %s", filter, pushdown)
+	}
+
 	// Begin with empty response
 	asr := kubecost.NewAllocationSetRange()
 
@@ -2316,20 +2384,53 @@ func (cm *CostModel) QueryAllocation(window kubecost.Window, resolution, step ti
 	stepStart := *window.Start()
 	stepEnd := stepStart.Add(step)
 	for window.End().After(stepStart) {
-		allocSet, err := cm.ComputeAllocation(stepStart, stepEnd, resolution)
+		// Only steps on the hour are recorded, because only those are
+		// compacted and retained by the totals store
+		recordTotals := cm.TotalsStore != nil && isOnTheHour(stepStart) && isOnTheHour(stepEnd)
+
+		// Totals must be recorded from every workload, so skip pushdown for
+		// steps the totals store has not recorded yet
+		stepPushdown := pushdown
+		if pushdown != nil && recordTotals {
+			if _, ok := cm.TotalsStore.GetAllocationTotalsByCluster(stepStart, stepEnd); !ok {
+				stepPushdown = nil
+			}
+		}
+
+		allocSet, err := cm.computeAllocationWithPushdown(traceCtx, stepStart, stepEnd, resolution, stepPushdown)
 		if err != nil {
-			return nil, fmt.Errorf("error computing allocations for %s: %w", kubecost.NewClosedWindow(stepStart, stepEnd), err)
+			return nil, nil, fmt.Errorf("error computing allocations for %s: %w", kubecost.NewClosedWindow(stepStart, stepEnd), err)
+		}
+
+		// Record the totals of each step for idle and reconciliation, unless
+		// the step was filtered by pushdown
+		if recordTotals && stepPushdown == nil {
+			if _, err := kubecost.UpdateAllocationTotalsStore(cm.TotalsStore, allocSet); err != nil {
+				log.Warnf("CostModel.QueryAllocation: failed to update allocation totals for %s: %s", allocSet.Window, err)
+			}
 		}
 
 		if includeIdle {
+			_, assetSpan := tracing.Start(traceCtx, "CostModel.ComputeAssets")
 			assetSet, err := cm.ComputeAssets(stepStart, stepEnd)
+			tracing.RecordError(assetSpan, err)
+			assetSpan.End()
 			if err != nil {
-				return nil, fmt.Errorf("error computing assets for %s: %w", kubecost.NewClosedWindow(stepStart, stepEnd), err)
+				return nil, nil, fmt.Errorf("error computing assets for %s: %w", kubecost.NewClosedWindow(stepStart, stepEnd), err)
 			}
 
+			if recordTotals {
+				if _, err := kubecost.UpdateAssetTotalsStore(cm.TotalsStore, assetSet); err != nil {
+					log.Warnf("CostModel.QueryAllocation: failed to update asset totals for %s: %s", assetSet.Window, err)
+				}
+			}
+
+			_, idleSpan := tracing.Start(traceCtx, "CostModel.computeIdleAllocations")
 			idleSet, err := computeIdleAllocations(allocSet, assetSet, true)
+			tracing.RecordError(idleSpan, err)
+			idleSpan.End()
 			if err != nil {
-				return nil, fmt.Errorf("error computing idle allocations for %s: %w", kubecost.NewClosedWindow(stepStart, stepEnd), err)
+				return nil, nil, fmt.Errorf("error computing idle allocations for %s: %w", kubecost.NewClosedWindow(stepStart, stepEnd), err)
 			}
 
 			for _, idleAlloc := range idleSet.Allocations {
@@ -2343,19 +2444,29 @@ func (cm *CostModel) QueryAllocation(window kubecost.Window, resolution, step ti
 		stepEnd = stepStart.Add(step)
 	}
 
-	// Set aggregation options and aggregate
+	// Set aggregation options
 	opts := &kubecost.AllocationAggregationOptions{
 		IncludeProportionalAssetResourceCosts: includeProportionalAssetResourceCosts,
 		IdleByNode:                            idleByNode,
+		IdleByNodePool:                        idleByNodePool,
+		Filter:                                filter,
+		SharingPolicies:                       sharingPolicies,
+		TraceContext:                          traceCtx,
+		AllocationTotalsStore:                 cm.TotalsStore,
 	}
 
-	// Aggregate
-	err := asr.AggregateBy(aggregate, opts)
-	if err != nil {
-		return nil, fmt.Errorf("error aggregating for %s: %w", window, err)
+	// Charge idle to its owners, leaving the rest as idle
+	if len(idleOwners) > 0 {
+		opts.ShareIdle = kubecost.ShareOwner
+		opts.IdleOwners = idleOwners
 	}
 
-	return asr, nil
+	return asr, opts, nil
+}
+
+// isOnTheHour returns true if the given time falls exactly on an hour.
+func isOnTheHour(t time.Time) bool {
+	return t.Equal(t.Truncate(time.Hour))
 }
 
 func computeIdleAllocations(allocSet *kubecost.AllocationSet, assetSet *kubecost.AssetSet, idleByNode bool) (*kubecost.AllocationSet, error) {
@@ -2374,6 +2485,15 @@ func computeIdleAllocations(allocSet *kubecost.AllocationSet, assetSet *kubecost
 		assetTotals = kubecost.ComputeAssetTotals(assetSet, kubecost.AssetClusterProp)
 	}
 
+	// Idle allocations by node carry the node pool of their node, so that
+	// they can be later partitioned by node pool.
+	nodePools := map[string]string{}
+	if idleByNode {
+		for _, node := range assetSet.Nodes {
+			nodePools[fmt.Sprintf("%s/%s", node.Properties.Cluster, node.Properties.Name)] = node.Properties.NodePool
+		}
+	}
+
 	start, end := *allocSet.Window.Start(), *allocSet.Window.End()
 	idleSet := kubecost.NewAllocationSet(start, end)
 
@@ -2404,6 +2524,7 @@ func computeIdleAllocations(allocSet *kubecost.AllocationSet, assetSet *kubecost
 				Cluster:    assetTotal.Cluster,
 				Node:       assetTotal.Node,
 				ProviderID: assetTotal.Node,
+				NodePool:   nodePools[key],
 			},
 			Start:   assetTotal.Start,
 			End:     assetTotal.End,
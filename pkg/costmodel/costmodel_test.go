@@ -1,7 +1,22 @@
 package costmodel
 
 import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/config"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+
+	prometheus "github.com/prometheus/client_golang/api"
 )
 
 func Test_CostData_GetController_CronJob(t *testing.T) {
@@ -65,3 +80,183 @@ func Test_CostData_GetController_CronJob(t *testing.T) {
 		})
 	}
 }
+
+// namespacePrometheusServer fakes the Prometheus query API of a cluster with a
+// single node costing one per core-hour, running one single-core container in
+// each of the given namespaces. Queries selecting a namespace only return its
+// container.
+func namespacePrometheusServer(t *testing.T, namespaces []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("query")
+		secs, err := strconv.ParseInt(r.FormValue("time"), 10, 64)
+		if err != nil {
+			t.Errorf("invalid query time %s: %s", r.FormValue("time"), err)
+		}
+		end := time.Unix(secs, 0)
+
+		type result struct {
+			Metric map[string]string `json:"metric"`
+			Values [][]interface{}   `json:"values"`
+		}
+		results := []result{}
+
+		selected := []string{}
+		for _, ns := range namespaces {
+			if !strings.Contains(query, "namespace=") || strings.Contains(query, strconv.Quote(ns)) {
+				selected = append(selected, ns)
+			}
+		}
+
+		value := [][]interface{}{{float64(end.Unix()), "1"}}
+
+		switch {
+		case strings.Contains(query, "kube_pod_container_status_running"):
+			var running [][]interface{}
+			for ts := end.Add(-time.Hour); !ts.After(end); ts = ts.Add(5 * time.Minute) {
+				running = append(running, []interface{}{float64(ts.Unix()), "1"})
+			}
+			for _, ns := range selected {
+				results = append(results, result{map[string]string{"namespace": ns, "pod": ns + "-pod"}, running})
+			}
+		case strings.Contains(query, "container_cpu_allocation"):
+			for _, ns := range selected {
+				results = append(results, result{map[string]string{"namespace": ns, "pod": ns + "-pod", "container": "container", "node": "node"}, value})
+			}
+		case strings.Contains(query, "kube_node_labels"):
+			results = append(results, result{map[string]string{"node": "node", "label_eks_amazonaws_com_nodegroup": "pool-a"}, value})
+		case strings.Contains(query, "node_cpu_hourly_cost"):
+			results = append(results, result{map[string]string{"node": "node", "instance_type": "m5.large", "provider_id": "node"}, value})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data": map[string]interface{}{
+				"resultType": "matrix",
+				"result":     results,
+			},
+		})
+	}))
+}
+
+func TestCostModel_QueryAllocationSets_TotalsWithPushdown(t *testing.T) {
+	prom := namespacePrometheusServer(t, []string{"ns1", "ns2"})
+	defer prom.Close()
+
+	client, err := prometheus.NewClient(prometheus.Config{Address: prom.URL})
+	if err != nil {
+		t.Fatalf("creating prometheus client: %s", err)
+	}
+	provider := &cloud.CustomProvider{
+		Config: cloud.NewProviderConfig(config.NewConfigFileManager(nil), "fakeFile"),
+	}
+	cm := NewCostModel(client, provider, nil, nil, time.Minute)
+	cm.TotalsStore = kubecost.NewMemoryTotalsStore()
+
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	window := kubecost.NewClosedWindow(start, end)
+	filter := kubecost.AllocationFilterCondition{
+		Field: kubecost.FilterNamespace,
+		Op:    kubecost.FilterEquals,
+		Value: "ns1",
+	}
+
+	// The first query must compute every workload, so the totals of the
+	// window are complete, and the second may push the filter down
+	for i := 0; i < 2; i++ {
+		asr, opts, err := cm.queryAllocationSets(context.Background(), window, time.Minute, time.Hour, filter, nil, nil, false, false, false, false)
+		if err != nil {
+			t.Fatalf("query %d: unexpected error: %s", i, err)
+		}
+
+		if err := asr.AggregateBy([]string{kubecost.AllocationNamespaceProp}, opts); err != nil {
+			t.Fatalf("query %d: unexpected error aggregating: %s", i, err)
+		}
+		allocs := asr.Slice()[0].Allocations
+		if len(allocs) != 1 || allocs["ns1"] == nil {
+			t.Fatalf("query %d: expected only ns1, got %v", i, allocs)
+		}
+
+		totals, ok := cm.TotalsStore.GetAllocationTotalsByCluster(start, end)
+		if !ok {
+			t.Fatalf("query %d: expected totals for %s", i, window)
+		}
+		cpuCost := 0.0
+		for _, art := range totals {
+			cpuCost += art.CPUCost
+		}
+		if math.Abs(cpuCost-2) > 1e-6 {
+			t.Errorf("query %d: expected total CPU cost of both namespaces, 2, got %f", i, cpuCost)
+		}
+	}
+}
+
+func TestCostModel_QueryAllocationSets_TotalsOnTheHour(t *testing.T) {
+	prom := namespacePrometheusServer(t, []string{"ns1", "ns2"})
+	defer prom.Close()
+
+	client, err := prometheus.NewClient(prometheus.Config{Address: prom.URL})
+	if err != nil {
+		t.Fatalf("creating prometheus client: %s", err)
+	}
+	provider := &cloud.CustomProvider{
+		Config: cloud.NewProviderConfig(config.NewConfigFileManager(nil), "fakeFile"),
+	}
+	cm := NewCostModel(client, provider, nil, nil, time.Minute)
+	cm.TotalsStore = kubecost.NewMemoryTotalsStore()
+
+	// Steps which are not on the hour are never compacted, so they are not
+	// recorded
+	start := time.Date(2023, 3, 1, 0, 30, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	window := kubecost.NewClosedWindow(start, end)
+
+	asr, opts, err := cm.queryAllocationSets(context.Background(), window, time.Minute, time.Hour, nil, nil, nil, false, false, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if asr.Length() != 1 {
+		t.Fatalf("expected 1 set; got %d", asr.Length())
+	}
+	if opts.AllocationTotalsStore == nil {
+		t.Fatalf("expected aggregation options to have the totals store")
+	}
+
+	if _, ok := cm.TotalsStore.GetAllocationTotalsByCluster(start, end); ok {
+		t.Fatalf("expected no totals for %s", window)
+	}
+}
+
+func TestCostModel_QueryAllocationSets_NodePoolWithoutNodeLabels(t *testing.T) {
+	t.Setenv(env.AllocationNodeLabelsEnabled, "false")
+
+	prom := namespacePrometheusServer(t, []string{"ns1"})
+	defer prom.Close()
+
+	client, err := prometheus.NewClient(prometheus.Config{Address: prom.URL})
+	if err != nil {
+		t.Fatalf("creating prometheus client: %s", err)
+	}
+	provider := &cloud.CustomProvider{
+		Config: cloud.NewProviderConfig(config.NewConfigFileManager(nil), "fakeFile"),
+	}
+	cm := NewCostModel(client, provider, nil, nil, time.Minute)
+
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	window := kubecost.NewClosedWindow(start, start.Add(time.Hour))
+
+	asr, _, err := cm.queryAllocationSets(context.Background(), window, time.Minute, time.Hour, nil, nil, nil, false, false, false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Node pools are resolved even though node labels are not applied
+	for _, alloc := range asr.Slice()[0].Allocations {
+		if alloc.Properties.NodePool != "pool-a" {
+			t.Errorf("%s: expected node pool pool-a; got %q", alloc.Name, alloc.Properties.NodePool)
+		}
+		if len(alloc.Properties.Labels) != 0 {
+			t.Errorf("%s: expected no node labels; got %v", alloc.Name, alloc.Properties.Labels)
+		}
+	}
+}
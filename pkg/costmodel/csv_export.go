@@ -9,6 +9,7 @@ import (
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/opencost/opencost/pkg/filemanager"
@@ -23,6 +24,60 @@ type AllocationModel interface {
 
 var errNoData = errors.New("no data")
 
+// csvPropertyColumns are the columns of the CSV export preceding the custom
+// allocation properties, and csvMetricColumns those following them.
+var (
+	csvPropertyColumns = []string{
+		"Date",
+		"Namespace",
+		"ControllerKind",
+		"ControllerName",
+		"Pod",
+		"Container",
+	}
+	csvMetricColumns = []string{
+		"CPUCoreUsageAverage",
+		"CPUCoreRequestAverage",
+		"RAMBytesUsageAverage",
+		"RAMBytesRequestAverage",
+		"NetworkReceiveBytes",
+		"NetworkTransferBytes",
+		"GPUs",
+		"PVBytes",
+		"EphemeralStorageBytesUsageAverage",
+		"EphemeralStorageBytesRequestAverage",
+
+		"CPUCost",
+		"RAMCost",
+		"NetworkCost",
+		"PVCost",
+		"GPUCost",
+		"EphemeralStorageCost",
+		"SnapshotCost",
+		"TotalCost",
+	}
+)
+
+// validateCSVColumnNames returns an error if any of the custom allocation
+// properties, which are exported as CSV columns, is named like one of the
+// fixed columns, which would duplicate the header.
+func validateCSVColumnNames(defs []*kubecost.AllocationPropertyDefinition) error {
+	for _, def := range defs {
+		if def == nil {
+			continue
+		}
+		for _, columns := range [][]string{csvPropertyColumns, csvMetricColumns} {
+			for _, column := range columns {
+				if strings.EqualFold(def.Name, column) {
+					return fmt.Errorf("allocation property name collides with CSV export column: %s", def.Name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func UpdateCSV(ctx context.Context, fileManager filemanager.FileManager, model AllocationModel) error {
 	exporter := &csvExporter{
 		FileManager: fileManager,
@@ -153,31 +208,27 @@ func (e *csvExporter) writeCSVToWriter(ctx context.Context, w io.Writer, dates [
 	fmtFloat := func(f float64) string {
 		return strconv.FormatFloat(f, 'f', -1, 64)
 	}
-	csvWriter := csv.NewWriter(w)
-	err := csvWriter.Write([]string{
-		"Date",
-		"Namespace",
-		"ControllerKind",
-		"ControllerName",
-		"Pod",
-		"Container",
 
-		"CPUCoreUsageAverage",
-		"CPUCoreRequestAverage",
-		"RAMBytesUsageAverage",
-		"RAMBytesRequestAverage",
-		"NetworkReceiveBytes",
-		"NetworkTransferBytes",
-		"GPUs",
-		"PVBytes",
+	// Custom allocation properties are exported as additional columns,
+	// following the Kubernetes properties. Headers are merged with any
+	// previous export, so the columns may change between exports.
+	customProps := kubecost.GetAllocationPropertyDefinitions()
+	customPropValues := func(alloc *kubecost.Allocation) []string {
+		values := make([]string, len(customProps))
+		for i, def := range customProps {
+			values[i], _ = def.Value(alloc.Properties)
+		}
+		return values
+	}
 
-		"CPUCost",
-		"RAMCost",
-		"NetworkCost",
-		"PVCost",
-		"GPUCost",
-		"TotalCost",
-	})
+	header := append([]string{}, csvPropertyColumns...)
+	for _, def := range customProps {
+		header = append(header, def.Name)
+	}
+	header = append(header, csvMetricColumns...)
+
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write(header)
 	if err != nil {
 		return err
 	}
@@ -197,14 +248,16 @@ func (e *csvExporter) writeCSVToWriter(ctx context.Context, w io.Writer, dates [
 
 			log.Infof("%f", alloc.TotalCost())
 
-			err := csvWriter.Write([]string{
+			row := []string{
 				date.Format("2006-01-02"),
 				alloc.Properties.Namespace,
 				alloc.Properties.ControllerKind,
 				alloc.Properties.Controller,
 				alloc.Properties.Pod,
 				alloc.Properties.Container,
-
+			}
+			row = append(row, customPropValues(alloc)...)
+			row = append(row,
 				fmtFloat(alloc.CPUCoreUsageAverage),
 				fmtFloat(alloc.CPUCoreRequestAverage),
 				fmtFloat(alloc.RAMBytesUsageAverage),
@@ -213,14 +266,20 @@ func (e *csvExporter) writeCSVToWriter(ctx context.Context, w io.Writer, dates [
 				fmtFloat(alloc.NetworkTransferBytes),
 				fmtFloat(alloc.GPUs()),
 				fmtFloat(alloc.PVBytes()),
+				fmtFloat(alloc.EphemeralStorageBytesUsageAverage),
+				fmtFloat(alloc.EphemeralStorageBytesRequestAverage),
 
 				fmtFloat(alloc.CPUTotalCost()),
 				fmtFloat(alloc.RAMTotalCost()),
 				fmtFloat(alloc.NetworkTotalCost()),
 				fmtFloat(alloc.PVCost()),
 				fmtFloat(alloc.GPUCost),
+				fmtFloat(alloc.EphemeralStorageTotalCost()),
+				fmtFloat(alloc.SnapshotCost),
 				fmtFloat(alloc.TotalCost()),
-			})
+			)
+
+			err := csvWriter.Write(row)
 			if err != nil {
 				return err
 			}
@@ -26,19 +26,23 @@ func Test_UpdateCSV(t *testing.T) {
 				return &kubecost.AllocationSet{
 					Allocations: map[string]*kubecost.Allocation{
 						"test": {
-							Start:                  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), // required for GPU metrics
-							End:                    time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
-							CPUCoreUsageAverage:    0.1,
-							CPUCoreRequestAverage:  0.2,
-							CPUCost:                0.3,
-							RAMBytesUsageAverage:   0.4,
-							RAMBytesRequestAverage: 0.5,
-							RAMCost:                0.6,
-							GPUHours:               48,
-							GPUCost:                0.8,
-							NetworkCost:            0.9,
-							NetworkTransferBytes:   10,
-							NetworkReceiveBytes:    11,
+							Start:                               time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), // required for GPU metrics
+							End:                                 time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+							CPUCoreUsageAverage:                 0.1,
+							CPUCoreRequestAverage:               0.2,
+							CPUCost:                             0.3,
+							RAMBytesUsageAverage:                0.4,
+							RAMBytesRequestAverage:              0.5,
+							RAMCost:                             0.6,
+							GPUHours:                            48,
+							GPUCost:                             0.8,
+							NetworkCost:                         0.9,
+							NetworkTransferBytes:                10,
+							NetworkReceiveBytes:                 11,
+							EphemeralStorageBytesUsageAverage:   12,
+							EphemeralStorageBytesRequestAverage: 13,
+							EphemeralStorageCost:                0.1,
+							SnapshotCost:                        0.2,
 							PVs: map[kubecost.PVKey]*kubecost.PVAllocation{
 								kubecost.PVKey{
 									Cluster: "test-cluster",
@@ -66,8 +70,8 @@ func Test_UpdateCSV(t *testing.T) {
 		assert.Len(t, model.ComputeAllocationCalls(), 1)
 		assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), model.ComputeAllocationCalls()[0].Start)
 		assert.Equal(t, time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), model.ComputeAllocationCalls()[0].End)
-		assert.Equal(t, `Date,Namespace,ControllerKind,ControllerName,Pod,Container,CPUCoreUsageAverage,CPUCoreRequestAverage,RAMBytesUsageAverage,RAMBytesRequestAverage,NetworkReceiveBytes,NetworkTransferBytes,GPUs,PVBytes,CPUCost,RAMCost,NetworkCost,PVCost,GPUCost,TotalCost
-2021-01-01,test-namespace,test-controller-kind,test-controller-name,test-pod,test-container,0.1,0.2,0.4,0.5,11,10,2,2,0.3,0.6,0.9,2,0.8,4.6000000000000005
+		assert.Equal(t, `Date,Namespace,ControllerKind,ControllerName,Pod,Container,CPUCoreUsageAverage,CPUCoreRequestAverage,RAMBytesUsageAverage,RAMBytesRequestAverage,NetworkReceiveBytes,NetworkTransferBytes,GPUs,PVBytes,EphemeralStorageBytesUsageAverage,EphemeralStorageBytesRequestAverage,CPUCost,RAMCost,NetworkCost,PVCost,GPUCost,EphemeralStorageCost,SnapshotCost,TotalCost
+2021-01-01,test-namespace,test-controller-kind,test-controller-name,test-pod,test-container,0.1,0.2,0.4,0.5,11,10,2,2,12,13,0.3,0.6,0.9,2,0.8,0.1,0.2,4.9
 `, string(storage.Data))
 	})
 
@@ -102,9 +106,9 @@ func Test_UpdateCSV(t *testing.T) {
 		// 2021-01-01 is already in the export file, so we only compute for 2021-01-02
 		assert.Equal(t, time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), model.ComputeAllocationCalls()[0].Start)
 		assert.Equal(t, time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC), model.ComputeAllocationCalls()[0].End)
-		assert.Equal(t, `Date,Namespace,CPUCoreUsageAverage,CPUCoreRequestAverage,CPUCost,RAMBytesUsageAverage,RAMBytesRequestAverage,RAMCost,ControllerKind,ControllerName,Pod,Container,NetworkReceiveBytes,NetworkTransferBytes,GPUs,PVBytes,NetworkCost,PVCost,GPUCost,TotalCost
-2021-01-01,test-namespace,0.1,0.2,0.3,0.4,0.5,0.6,,,,,,,,,,,,
-2021-01-02,test-namespace,0,0,1,0,0,0,,,,,0,0,0,0,0,0,0,1
+		assert.Equal(t, `Date,Namespace,CPUCoreUsageAverage,CPUCoreRequestAverage,CPUCost,RAMBytesUsageAverage,RAMBytesRequestAverage,RAMCost,ControllerKind,ControllerName,Pod,Container,NetworkReceiveBytes,NetworkTransferBytes,GPUs,PVBytes,EphemeralStorageBytesUsageAverage,EphemeralStorageBytesRequestAverage,NetworkCost,PVCost,GPUCost,EphemeralStorageCost,SnapshotCost,TotalCost
+2021-01-01,test-namespace,0.1,0.2,0.3,0.4,0.5,0.6,,,,,,,,,,,,,,,,
+2021-01-02,test-namespace,0,0,1,0,0,0,,,,,0,0,0,0,0,0,0,0,0,0,0,1
 `, string(storage.Data))
 	})
 
@@ -142,3 +146,17 @@ func Test_UpdateCSV(t *testing.T) {
 		require.Equal(t, err, errNoData)
 	})
 }
+
+func Test_validateCSVColumnNames(t *testing.T) {
+	newDef := func(name string) *kubecost.AllocationPropertyDefinition {
+		return &kubecost.AllocationPropertyDefinition{
+			Name:    name,
+			Sources: []*kubecost.AllocationPropertySource{{Type: kubecost.AllocationPropertySourceLabel, Name: name}},
+		}
+	}
+
+	assert.NoError(t, validateCSVColumnNames([]*kubecost.AllocationPropertyDefinition{newDef("costCenter")}))
+	assert.Error(t, validateCSVColumnNames([]*kubecost.AllocationPropertyDefinition{newDef("costCenter"), newDef("TotalCost")}))
+	assert.Error(t, validateCSVColumnNames([]*kubecost.AllocationPropertyDefinition{newDef("cpuCost")}))
+	assert.Error(t, validateCSVColumnNames([]*kubecost.AllocationPropertyDefinition{newDef("date")}))
+}
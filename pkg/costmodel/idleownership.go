@@ -0,0 +1,449 @@
+package costmodel
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/pkg/config"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/util/json"
+	"github.com/opencost/opencost/pkg/util/timeutil"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// idleOwnershipGenericLabels are node labels which say nothing about the
+// ownership of a node, so are ignored when matching the node selectors and
+// node affinity of an owner's pods.
+var idleOwnershipGenericLabels = map[string]bool{
+	v1.LabelOSStable:          true,
+	v1.LabelArchStable:        true,
+	"beta.kubernetes.io/os":   true,
+	"beta.kubernetes.io/arch": true,
+}
+
+// IdleOwnerConfig is the configuration of a kubecost.IdleOwner, which is
+// charged for the idle cost of the nodes it owns, in proportion to the cost
+// of the allocations of its namespaces.
+//
+// An owner owns every node of its Clusters, the Nodes and NodePools named
+// explicitly, and the nodes of any cluster which had all of NodeLabels or
+// any of NodeTaints, given as "key" or "key=value", during the window. If
+// NamespaceAffinity is set, it also owns the nodes selected by the node
+// selectors, or required node affinity, of the current pods of its
+// namespaces; the well-known OS and architecture labels are ignored, as they
+// select nodes shared by everyone. Labels and taints are those recorded by
+// kube_node_labels and kube_node_spec_taint over the window.
+type IdleOwnerConfig struct {
+	Name              string            `json:"name"`
+	Namespaces        []string          `json:"namespaces"`
+	Clusters          []string          `json:"clusters,omitempty"`
+	Nodes             []string          `json:"nodes,omitempty"`
+	NodePools         []string          `json:"nodePools,omitempty"`
+	NodeLabels        map[string]string `json:"nodeLabels,omitempty"`
+	NodeTaints        []string          `json:"nodeTaints,omitempty"`
+	NamespaceAffinity bool              `json:"namespaceAffinity,omitempty"`
+}
+
+// Validate returns an error if the configuration cannot own any idle.
+func (ioc *IdleOwnerConfig) Validate() error {
+	if ioc.Name == "" {
+		return fmt.Errorf("idle owner has no name")
+	}
+
+	if len(ioc.Namespaces) == 0 {
+		return fmt.Errorf("idle owner %s has no namespaces", ioc.Name)
+	}
+
+	if len(ioc.Clusters) == 0 && len(ioc.Nodes) == 0 && len(ioc.NodePools) == 0 && len(ioc.NodeLabels) == 0 && len(ioc.NodeTaints) == 0 && !ioc.NamespaceAffinity {
+		return fmt.Errorf("idle owner %s owns no clusters or nodes", ioc.Name)
+	}
+
+	return nil
+}
+
+// usesNodes returns true if the configuration owns nodes by their labels or
+// taints, which must be resolved against the nodes of the window.
+func (ioc *IdleOwnerConfig) usesNodes() bool {
+	return len(ioc.NodeLabels) > 0 || len(ioc.NodeTaints) > 0 || ioc.NamespaceAffinity
+}
+
+// idleOwnershipNode holds the labels, by sanitized name, and taints which a
+// node had during a window.
+type idleOwnershipNode struct {
+	labels map[string]string
+	taints []v1.Taint
+}
+
+// IdleOwner resolves the configuration into a kubecost.IdleOwner, against the
+// given nodes of the window and the current pods.
+func (ioc *IdleOwnerConfig) IdleOwner(nodes map[nodeKey]*idleOwnershipNode, pods []*v1.Pod) (*kubecost.IdleOwner, error) {
+	if err := ioc.Validate(); err != nil {
+		return nil, err
+	}
+
+	owned := kubecost.AllocationFilterOr{}
+	namespaces := make(map[string]bool, len(ioc.Namespaces))
+	for _, ns := range ioc.Namespaces {
+		namespaces[ns] = true
+		owned.Filters = append(owned.Filters, kubecost.AllocationFilterCondition{
+			Field: kubecost.FilterNamespace,
+			Op:    kubecost.FilterEquals,
+			Value: ns,
+		})
+	}
+
+	clusters := make(map[string]bool, len(ioc.Clusters))
+	for _, cluster := range ioc.Clusters {
+		clusters[cluster] = true
+	}
+
+	nodePools := make(map[string]bool, len(ioc.NodePools))
+	for _, nodePool := range ioc.NodePools {
+		nodePools[nodePool] = true
+	}
+
+	explicitNodes := make(map[string]bool, len(ioc.Nodes))
+	for _, node := range ioc.Nodes {
+		explicitNodes[node] = true
+	}
+
+	// Nodes owned by their labels, taints or the affinity of the owner's
+	// pods
+	var selectors []*v1.NodeSelector
+	if ioc.NamespaceAffinity {
+		for _, pod := range pods {
+			if !namespaces[pod.Namespace] {
+				continue
+			}
+			if selector := podOwnershipSelector(pod); selector != nil {
+				selectors = append(selectors, selector)
+			}
+		}
+	}
+
+	ownedNodes := map[nodeKey]bool{}
+	for key, node := range nodes {
+		if ioc.ownsNode(node, selectors) {
+			ownedNodes[key] = true
+		}
+	}
+
+	return &kubecost.IdleOwner{
+		Name: ioc.Name,
+		Idle: func(a *kubecost.Allocation) bool {
+			if a.Properties == nil {
+				return false
+			}
+
+			props := a.Properties
+			if clusters[props.Cluster] || explicitNodes[props.Node] || nodePools[props.NodePool] {
+				return true
+			}
+
+			return ownedNodes[newNodeKey(props.Cluster, props.Node)]
+		},
+		Owned: owned,
+	}, nil
+}
+
+func (ioc *IdleOwnerConfig) ownsNode(node *idleOwnershipNode, selectors []*v1.NodeSelector) bool {
+	if len(ioc.NodeLabels) > 0 {
+		matches := true
+		for k, v := range ioc.NodeLabels {
+			if node.labels[prom.SanitizeLabelName(k)] != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+
+	for _, taint := range ioc.NodeTaints {
+		key, value, hasValue := strings.Cut(taint, "=")
+		for _, t := range node.taints {
+			if t.Key == key && (!hasValue || t.Value == value) {
+				return true
+			}
+		}
+	}
+
+	for _, selector := range selectors {
+		if nodeSelectorMatches(selector, node.labels) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// podOwnershipSelector returns a node selector combining the node selector
+// and required node affinity of the given pod, without requirements on
+// generic labels, or nil if nothing remains by which to select nodes. Keys
+// are sanitized, to match the labels of kube_node_labels.
+func podOwnershipSelector(pod *v1.Pod) *v1.NodeSelector {
+	var selectorReqs []v1.NodeSelectorRequirement
+	for k, v := range pod.Spec.NodeSelector {
+		if idleOwnershipGenericLabels[k] {
+			continue
+		}
+		selectorReqs = append(selectorReqs, v1.NodeSelectorRequirement{
+			Key:      prom.SanitizeLabelName(k),
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{v},
+		})
+	}
+
+	var terms []v1.NodeSelectorTerm
+	if pod.Spec.Affinity != nil && pod.Spec.Affinity.NodeAffinity != nil && pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		for _, term := range pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+			var reqs []v1.NodeSelectorRequirement
+			for _, req := range term.MatchExpressions {
+				if !idleOwnershipGenericLabels[req.Key] {
+					req.Key = prom.SanitizeLabelName(req.Key)
+					reqs = append(reqs, req)
+				}
+			}
+			if len(reqs) > 0 {
+				terms = append(terms, v1.NodeSelectorTerm{MatchExpressions: append(reqs, selectorReqs...)})
+			}
+		}
+	}
+	if len(terms) == 0 && len(selectorReqs) > 0 {
+		terms = append(terms, v1.NodeSelectorTerm{MatchExpressions: selectorReqs})
+	}
+
+	if len(terms) == 0 {
+		return nil
+	}
+
+	return &v1.NodeSelector{NodeSelectorTerms: terms}
+}
+
+// idleOwnerConfigs holds the configured idle owners, keyed by name.
+var idleOwnerConfigs = struct {
+	lock   sync.RWMutex
+	byName map[string]*IdleOwnerConfig
+}{
+	byName: map[string]*IdleOwnerConfig{},
+}
+
+// ParseIdleOwnerConfigs parses a JSON list of idle owners, validating each.
+func ParseIdleOwnerConfigs(data []byte) ([]*IdleOwnerConfig, error) {
+	configs := []*IdleOwnerConfig{}
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("parsing idle owners: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, ioc := range configs {
+		if ioc == nil {
+			return nil, fmt.Errorf("nil idle owner")
+		}
+
+		if names[ioc.Name] {
+			return nil, fmt.Errorf("duplicate idle owner: %s", ioc.Name)
+		}
+		names[ioc.Name] = true
+
+		if err := ioc.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return configs, nil
+}
+
+// SetIdleOwnerConfigs replaces the configured idle owners.
+func SetIdleOwnerConfigs(configs []*IdleOwnerConfig) {
+	byName := make(map[string]*IdleOwnerConfig, len(configs))
+	for _, ioc := range configs {
+		byName[ioc.Name] = ioc
+	}
+
+	idleOwnerConfigs.lock.Lock()
+	defer idleOwnerConfigs.lock.Unlock()
+
+	idleOwnerConfigs.byName = byName
+}
+
+// IdleOwners returns the configured idle owners with the given names, in
+// order of precedence, resolved against the nodes of the given window and the
+// current pods, or an error if any is not configured.
+func (cm *CostModel) IdleOwners(window kubecost.Window, names []string) ([]*kubecost.IdleOwner, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	idleOwnerConfigs.lock.RLock()
+	configs := make([]*IdleOwnerConfig, 0, len(names))
+	for _, name := range names {
+		ioc, ok := idleOwnerConfigs.byName[name]
+		if !ok {
+			idleOwnerConfigs.lock.RUnlock()
+			return nil, fmt.Errorf("bad request - unknown idle owner: %s", name)
+		}
+		configs = append(configs, ioc)
+	}
+	idleOwnerConfigs.lock.RUnlock()
+
+	usesNodes := false
+	for _, ioc := range configs {
+		usesNodes = usesNodes || ioc.usesNodes()
+	}
+
+	var nodes map[nodeKey]*idleOwnershipNode
+	var pods []*v1.Pod
+	if usesNodes {
+		var err error
+		nodes, err = cm.idleOwnershipNodes(window)
+		if err != nil {
+			return nil, fmt.Errorf("resolving idle owners for %s: %w", window, err)
+		}
+
+		if cm.Cache != nil {
+			pods = cm.Cache.GetAllPods()
+		}
+	}
+
+	owners := make([]*kubecost.IdleOwner, 0, len(configs))
+	for _, ioc := range configs {
+		owner, err := ioc.IdleOwner(nodes, pods)
+		if err != nil {
+			return nil, err
+		}
+		owners = append(owners, owner)
+	}
+
+	return owners, nil
+}
+
+// idleOwnershipNodes queries the labels and taints of the nodes of every
+// cluster during the given window.
+func (cm *CostModel) idleOwnershipNodes(window kubecost.Window) (map[nodeKey]*idleOwnershipNode, error) {
+	if window.IsOpen() || window.IsNegative() {
+		return nil, fmt.Errorf("illegal window: %s", window)
+	}
+
+	nodes := map[nodeKey]*idleOwnershipNode{}
+
+	start, end := *window.Start(), *window.End()
+	if now := time.Now(); end.After(now) {
+		end = now
+	}
+	durStr := timeutil.DurationString(end.Sub(start))
+	if durStr == "" {
+		return nodes, nil
+	}
+
+	queries := newAllocationQueries(durStr, "", "")
+
+	ctx := prom.NewNamedContext(cm.PrometheusClient, prom.AllocationContextName)
+	resChNodeLabels := ctx.QueryAtTime(queries.query(allocationQueryNodeLabels, nil, nil), end)
+	resChNodeTaints := ctx.QueryAtTime(queries.query(allocationQueryNodeTaints, nil, nil), end)
+
+	resNodeLabels, _ := resChNodeLabels.Await()
+	resNodeTaints, _ := resChNodeTaints.Await()
+	if ctx.HasErrors() {
+		return nil, ctx.ErrorCollection()
+	}
+
+	clusterLabel := env.GetPromClusterLabel()
+	getNode := func(key nodeKey) *idleOwnershipNode {
+		node, ok := nodes[key]
+		if !ok {
+			node = &idleOwnershipNode{labels: map[string]string{}}
+			nodes[key] = node
+		}
+		return node
+	}
+
+	for _, res := range resNodeLabels {
+		key, err := resultNodeKey(res, clusterLabel, "node")
+		if err != nil {
+			continue
+		}
+
+		node := getNode(key)
+		for k, v := range res.GetLabels() {
+			node.labels[k] = v
+		}
+	}
+
+	for _, res := range resNodeTaints {
+		key, err := resultNodeKey(res, clusterLabel, "node")
+		if err != nil {
+			continue
+		}
+
+		taintKey, err := res.GetString("key")
+		if err != nil {
+			continue
+		}
+		taintValue, _ := res.GetString("value")
+
+		node := getNode(key)
+		node.taints = append(node.taints, v1.Taint{Key: taintKey, Value: taintValue})
+	}
+
+	return nodes, nil
+}
+
+// WatchIdleOwnershipConfig loads the idle owners from the given config file,
+// if it exists, and reloads them whenever the file changes. The file contains
+// a JSON list of owners:
+//
+//	[{
+//	  "name": "ml-team",
+//	  "namespaces": ["training", "inference"],
+//	  "nodeTaints": ["dedicated=ml"],
+//	  "nodePools": ["gpu-pool"]
+//	}]
+//
+// Owners are applied by name, in order of precedence, with the "idleOwners"
+// parameter of /allocation/compute.
+func WatchIdleOwnershipConfig(file *config.ConfigFile) {
+	exists, err := file.Exists()
+	if err != nil {
+		log.Errorf("Failed to check for idle ownership config %s: %s", file.Path(), err)
+		return
+	}
+
+	if exists {
+		data, err := file.Read()
+		if err != nil {
+			log.Warnf("Failed to read idle ownership config %s: %s", file.Path(), err)
+		} else {
+			updateIdleOwners(data)
+		}
+	}
+
+	file.AddChangeHandler(func(changeType config.ChangeType, data []byte) {
+		if changeType == config.ChangeTypeDeleted {
+			log.Infof("Idle ownership config deleted, removing idle owners")
+			SetIdleOwnerConfigs(nil)
+			return
+		}
+
+		updateIdleOwners(data)
+	})
+}
+
+func updateIdleOwners(data []byte) {
+	configs, err := ParseIdleOwnerConfigs(data)
+	if err != nil {
+		log.Errorf("Invalid idle ownership config, keeping existing idle owners: %s", err)
+		return
+	}
+
+	SetIdleOwnerConfigs(configs)
+
+	log.Infof("Loaded %d idle owners", len(configs))
+}
@@ -0,0 +1,210 @@
+package costmodel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+
+	prometheus "github.com/prometheus/client_golang/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseIdleOwnerConfigs(t *testing.T) {
+	cases := map[string]struct {
+		input       string
+		expectError bool
+	}{
+		"valid": {
+			input: `[
+				{"name": "ml-team", "namespaces": ["training"], "nodeTaints": ["dedicated=ml"], "nodePools": ["gpu-pool"]},
+				{"name": "data-team", "namespaces": ["etl"], "namespaceAffinity": true}
+			]`,
+		},
+		"duplicate": {
+			input:       `[{"name": "ml-team", "namespaces": ["training"], "nodes": ["node1"]}, {"name": "ml-team", "namespaces": ["etl"], "nodes": ["node2"]}]`,
+			expectError: true,
+		},
+		"missing name": {
+			input:       `[{"namespaces": ["training"], "nodes": ["node1"]}]`,
+			expectError: true,
+		},
+		"missing namespaces": {
+			input:       `[{"name": "ml-team", "nodes": ["node1"]}]`,
+			expectError: true,
+		},
+		"owns nothing": {
+			input:       `[{"name": "ml-team", "namespaces": ["training"]}]`,
+			expectError: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseIdleOwnerConfigs([]byte(c.input))
+			if c.expectError && err == nil {
+				t.Fatalf("expected error; got nil")
+			}
+			if !c.expectError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestIdleOwnerConfig_IdleOwner(t *testing.T) {
+	cluster := env.GetClusterID()
+
+	// Labels are sanitized, as recorded by kube_node_labels
+	nodes := map[nodeKey]*idleOwnershipNode{
+		newNodeKey(cluster, "tainted"):  {taints: []v1.Taint{{Key: "dedicated", Value: "ml"}}},
+		newNodeKey(cluster, "labelled"): {labels: map[string]string{"team": "ml", "tier": "batch"}},
+		newNodeKey(cluster, "selected"): {labels: map[string]string{"example_com_workload": "training", "kubernetes_io_os": "linux"}},
+		newNodeKey(cluster, "shared"):   {labels: map[string]string{"team": "ml", "kubernetes_io_os": "linux"}},
+		newNodeKey("other", "labelled"): {labels: map[string]string{"team": "ml", "tier": "batch"}},
+	}
+
+	pods := []*v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "training", Name: "trainer"},
+			Spec:       v1.PodSpec{NodeSelector: map[string]string{"example.com/workload": "training"}},
+		},
+		{
+			// Selecting on the OS says nothing about ownership
+			ObjectMeta: metav1.ObjectMeta{Namespace: "training", Name: "exporter"},
+			Spec:       v1.PodSpec{NodeSelector: map[string]string{v1.LabelOSStable: "linux"}},
+		},
+		{
+			// Pods of other namespaces are ignored
+			ObjectMeta: metav1.ObjectMeta{Namespace: "etl", Name: "loader"},
+			Spec:       v1.PodSpec{NodeSelector: map[string]string{"team": "ml"}},
+		},
+	}
+
+	ioc := &IdleOwnerConfig{
+		Name:              "ml-team",
+		Namespaces:        []string{"training", "inference"},
+		Clusters:          []string{"ml-cluster"},
+		NodePools:         []string{"gpu-pool"},
+		NodeLabels:        map[string]string{"team": "ml", "tier": "batch"},
+		NodeTaints:        []string{"dedicated"},
+		NamespaceAffinity: true,
+	}
+
+	owner, err := ioc.IdleOwner(nodes, pods)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	idleCases := map[string]struct {
+		props    *kubecost.AllocationProperties
+		expected bool
+	}{
+		"tainted node":                   {&kubecost.AllocationProperties{Cluster: cluster, Node: "tainted"}, true},
+		"labelled node":                  {&kubecost.AllocationProperties{Cluster: cluster, Node: "labelled"}, true},
+		"selected node":                  {&kubecost.AllocationProperties{Cluster: cluster, Node: "selected"}, true},
+		"partially labelled":             {&kubecost.AllocationProperties{Cluster: cluster, Node: "shared"}, false},
+		"node of other cluster":          {&kubecost.AllocationProperties{Cluster: "other", Node: "tainted"}, false},
+		"labelled node of other cluster": {&kubecost.AllocationProperties{Cluster: "other", Node: "labelled"}, true},
+		"owned cluster":                  {&kubecost.AllocationProperties{Cluster: "ml-cluster", Node: "any"}, true},
+		"owned node pool":                {&kubecost.AllocationProperties{Cluster: "other", Node: "any", NodePool: "gpu-pool"}, true},
+	}
+
+	for name, c := range idleCases {
+		t.Run(name, func(t *testing.T) {
+			if actual := owner.Idle(&kubecost.Allocation{Properties: c.props}); actual != c.expected {
+				t.Fatalf("expected %t; got %t", c.expected, actual)
+			}
+		})
+	}
+
+	for ns, expected := range map[string]bool{"training": true, "inference": true, "etl": false} {
+		alloc := &kubecost.Allocation{Properties: &kubecost.AllocationProperties{Cluster: cluster, Namespace: ns}}
+		if actual := owner.Owned.Matches(alloc); actual != expected {
+			t.Fatalf("expected namespace %s owned %t; got %t", ns, expected, actual)
+		}
+	}
+}
+
+func TestCostModel_IdleOwners(t *testing.T) {
+	// node1 of cluster2 was labelled for, and node2 of cluster3 tainted for,
+	// team-a during the window, though neither exists any longer
+	var queries int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("query")
+		atomic.AddInt32(&queries, 1)
+		switch {
+		case strings.Contains(query, "kube_node_labels"):
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"cluster_id":"cluster2","node":"node1","label_team":"team-a"},"value":[1,"1"]},
+				{"metric":{"cluster_id":"cluster2","node":"node2","label_team":"team-b"},"value":[1,"1"]}
+			]}}`))
+		case strings.Contains(query, "kube_node_spec_taint"):
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"cluster_id":"cluster3","node":"node2","key":"dedicated","value":"team-a"},"value":[1,"1"]}
+			]}}`))
+		default:
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := prometheus.NewClient(prometheus.Config{Address: server.URL})
+	if err != nil {
+		t.Fatalf("creating prometheus client: %s", err)
+	}
+	cm := &CostModel{PrometheusClient: client}
+
+	SetIdleOwnerConfigs([]*IdleOwnerConfig{
+		{Name: "team-a", Namespaces: []string{"a"}, NodeLabels: map[string]string{"team": "team-a"}, NodeTaints: []string{"dedicated=team-a"}},
+		{Name: "team-b", Namespaces: []string{"b"}, Clusters: []string{"cluster4"}},
+	})
+	defer SetIdleOwnerConfigs(nil)
+
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	window := kubecost.NewClosedWindow(start, start.Add(24*time.Hour))
+
+	owners, err := cm.IdleOwners(window, []string{"team-a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(owners) != 1 {
+		t.Fatalf("expected 1 owner; got %d", len(owners))
+	}
+
+	idleCases := map[string]struct {
+		props    *kubecost.AllocationProperties
+		expected bool
+	}{
+		"labelled node":    {&kubecost.AllocationProperties{Cluster: "cluster2", Node: "node1"}, true},
+		"other team node":  {&kubecost.AllocationProperties{Cluster: "cluster2", Node: "node2"}, false},
+		"tainted node":     {&kubecost.AllocationProperties{Cluster: "cluster3", Node: "node2"}, true},
+		"node not in data": {&kubecost.AllocationProperties{Cluster: "cluster3", Node: "node1"}, false},
+	}
+	for name, c := range idleCases {
+		t.Run(name, func(t *testing.T) {
+			if actual := owners[0].Idle(&kubecost.Allocation{Properties: c.props}); actual != c.expected {
+				t.Fatalf("expected %t; got %t", c.expected, actual)
+			}
+		})
+	}
+
+	// Owners of clusters, nodes and node pools need no queries
+	atomic.StoreInt32(&queries, 0)
+	if _, err := cm.IdleOwners(window, []string{"team-b"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := atomic.LoadInt32(&queries); n != 0 {
+		t.Fatalf("expected no queries; got %d", n)
+	}
+
+	if _, err := cm.IdleOwners(window, []string{"team-c"}); err == nil {
+		t.Fatalf("expected error for unknown idle owner")
+	}
+}
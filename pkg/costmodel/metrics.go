@@ -122,6 +122,8 @@ var (
 	gpuGv                      *prometheus.GaugeVec
 	gpuCountGv                 *prometheus.GaugeVec
 	pvGv                       *prometheus.GaugeVec
+	pvIOPSGv                   *prometheus.GaugeVec
+	pvThroughputGv             *prometheus.GaugeVec
 	spotGv                     *prometheus.GaugeVec
 	totalGv                    *prometheus.GaugeVec
 	ramAllocGv                 *prometheus.GaugeVec
@@ -133,6 +135,8 @@ var (
 	networkInternetEgressCostG prometheus.Gauge
 	clusterManagementCostGv    *prometheus.GaugeVec
 	lbCostGv                   *prometheus.GaugeVec
+	volumeSnapshotSizeGv       *prometheus.GaugeVec
+	volumeSnapshotCostG        prometheus.Gauge
 )
 
 // initCostModelMetrics uses a sync.Once to ensure that these metrics are only created once
@@ -184,6 +188,22 @@ func initCostModelMetrics(clusterCache clustercache.ClusterCache, provider cloud
 			toRegisterGV = append(toRegisterGV, pvGv)
 		}
 
+		if _, disabled := disabledMetrics["pv_iops_hourly_cost"]; !disabled {
+			pvIOPSGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "pv_iops_hourly_cost",
+				Help: "pv_iops_hourly_cost Cost per hour of the IOPS provisioned for a persistent disk",
+			}, []string{"volumename", "persistentvolume", "provider_id"})
+			toRegisterGV = append(toRegisterGV, pvIOPSGv)
+		}
+
+		if _, disabled := disabledMetrics["pv_throughput_hourly_cost"]; !disabled {
+			pvThroughputGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "pv_throughput_hourly_cost",
+				Help: "pv_throughput_hourly_cost Cost per hour of the throughput provisioned for a persistent disk",
+			}, []string{"volumename", "persistentvolume", "provider_id"})
+			toRegisterGV = append(toRegisterGV, pvThroughputGv)
+		}
+
 		if _, disabled := disabledMetrics["kubecost_node_is_spot"]; !disabled {
 			spotGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 				Name: "kubecost_node_is_spot",
@@ -272,6 +292,22 @@ func initCostModelMetrics(clusterCache clustercache.ClusterCache, provider cloud
 			toRegisterGV = append(toRegisterGV, lbCostGv)
 		}
 
+		if _, disabled := disabledMetrics["kubecost_volumesnapshot_size_bytes"]; !disabled {
+			volumeSnapshotSizeGv = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "kubecost_volumesnapshot_size_bytes",
+				Help: "kubecost_volumesnapshot_size_bytes Restore size of CSI volume snapshots",
+			}, []string{"namespace", "volumesnapshot", "persistentvolumeclaim", "volumesnapshotcontent", "provider_id"})
+			toRegisterGV = append(toRegisterGV, volumeSnapshotSizeGv)
+		}
+
+		if _, disabled := disabledMetrics["kubecost_volumesnapshot_storage_cost"]; !disabled {
+			volumeSnapshotCostG = prometheus.NewGauge(prometheus.GaugeOpts{
+				Name: "kubecost_volumesnapshot_storage_cost",
+				Help: "kubecost_volumesnapshot_storage_cost Cost per GiB-hour of volume snapshot storage",
+			})
+			toRegisterGauge = append(toRegisterGauge, volumeSnapshotCostG)
+		}
+
 		// Register cost-model metrics for emission
 		for _, gv := range toRegisterGV {
 			prometheus.MustRegister(gv)
@@ -304,6 +340,8 @@ type CostModelMetricsEmitter struct {
 	CPUPriceRecorder              *prometheus.GaugeVec
 	RAMPriceRecorder              *prometheus.GaugeVec
 	PersistentVolumePriceRecorder *prometheus.GaugeVec
+	PVIOPSPriceRecorder           *prometheus.GaugeVec
+	PVThroughputPriceRecorder     *prometheus.GaugeVec
 	GPUPriceRecorder              *prometheus.GaugeVec
 	GPUCountRecorder              *prometheus.GaugeVec
 	PVAllocationRecorder          *prometheus.GaugeVec
@@ -317,6 +355,12 @@ type CostModelMetricsEmitter struct {
 	NetworkZoneEgressRecorder     prometheus.Gauge
 	NetworkRegionEgressRecorder   prometheus.Gauge
 	NetworkInternetEgressRecorder prometheus.Gauge
+	VolumeSnapshotSizeRecorder    *prometheus.GaugeVec
+	VolumeSnapshotCostRecorder    prometheus.Gauge
+
+	// PushEmitter pushes the registered metrics to a remote endpoint when
+	// metric pushing is configured. Nil when only scraping is used.
+	PushEmitter *metrics.PushEmitter
 
 	// Concurrent Flow Control - Manages the run state of the metric emitter
 	runState atomic.AtomicRunState
@@ -348,6 +392,11 @@ func NewCostModelMetricsEmitter(promClient promclient.Client, clusterCache clust
 
 	metrics.InitKubecostTelemetry(metricsConfig)
 
+	pushEmitter, err := metrics.NewPushEmitterFromEnv()
+	if err != nil {
+		log.Errorf("Failed to configure metric push, metrics will only be available for scraping: %s", err)
+	}
+
 	return &CostModelMetricsEmitter{
 		PrometheusClient:              promClient,
 		KubeClusterCache:              clusterCache,
@@ -358,6 +407,8 @@ func NewCostModelMetricsEmitter(promClient promclient.Client, clusterCache clust
 		GPUPriceRecorder:              gpuGv,
 		GPUCountRecorder:              gpuCountGv,
 		PersistentVolumePriceRecorder: pvGv,
+		PVIOPSPriceRecorder:           pvIOPSGv,
+		PVThroughputPriceRecorder:     pvThroughputGv,
 		NodeSpotRecorder:              spotGv,
 		NodeTotalPriceRecorder:        totalGv,
 		RAMAllocationRecorder:         ramAllocGv,
@@ -369,6 +420,9 @@ func NewCostModelMetricsEmitter(promClient promclient.Client, clusterCache clust
 		NetworkInternetEgressRecorder: networkInternetEgressCostG,
 		ClusterManagementCostRecorder: clusterManagementCostGv,
 		LBCostRecorder:                lbCostGv,
+		VolumeSnapshotSizeRecorder:    volumeSnapshotSizeGv,
+		VolumeSnapshotCostRecorder:    volumeSnapshotCostG,
+		PushEmitter:                   pushEmitter,
 	}
 }
 
@@ -398,6 +452,10 @@ func (cmme *CostModelMetricsEmitter) Start() bool {
 		return false
 	}
 
+	if cmme.PushEmitter != nil {
+		cmme.PushEmitter.Start()
+	}
+
 	go func() {
 		defer errors.HandlePanic()
 
@@ -405,7 +463,9 @@ func (cmme *CostModelMetricsEmitter) Start() bool {
 		nodeSeen := make(map[string]bool)
 		loadBalancerSeen := make(map[string]bool)
 		pvSeen := make(map[string]bool)
+		pvPerformanceSeen := make(map[string]bool)
 		pvcSeen := make(map[string]bool)
+		volumeSnapshotSeen := make(map[string]bool)
 		nodeCostAverages := make(map[string]NodeCostAverages)
 
 		getKeyFromLabelStrings := func(labels ...string) string {
@@ -451,6 +511,16 @@ func (cmme *CostModelMetricsEmitter) Start() bool {
 				cmme.NetworkInternetEgressRecorder.Set(networkCosts.InternetNetworkEgressCost)
 			}
 
+			// Record snapshot storage pricing at global scope
+			if cmme.VolumeSnapshotCostRecorder != nil {
+				snapshotCosts, err := cmme.CloudProvider.SnapshotPricing()
+				if err != nil {
+					log.Debugf("Failed to retrieve snapshot costs: %s", err.Error())
+				} else {
+					cmme.VolumeSnapshotCostRecorder.Set(snapshotCosts.Cost)
+				}
+			}
+
 			// TODO: Pass PrometheusClient and CloudProvider into CostModel on instantiation so this isn't so awkward
 			data, err := cmme.Model.ComputeCostData(cmme.PrometheusClient, cmme.CloudProvider, "2m", "", "")
 			if err != nil {
@@ -664,6 +734,28 @@ func (cmme *CostModelMetricsEmitter) Start() bool {
 				cmme.PersistentVolumePriceRecorder.WithLabelValues(pv.Name, pv.Name, cacPv.ProviderID).Set(c)
 				labelKey := getKeyFromLabelStrings(pv.Name, pv.Name)
 				pvSeen[labelKey] = true
+
+				// Volumes without provisioned IOPS or throughput report no cost for them
+				if cacPv.IOPSCost != "" || cacPv.ThroughputCost != "" {
+					iopsCost, _ := strconv.ParseFloat(cacPv.IOPSCost, 64)
+					throughputCost, _ := strconv.ParseFloat(cacPv.ThroughputCost, 64)
+					if cmme.PVIOPSPriceRecorder != nil {
+						cmme.PVIOPSPriceRecorder.WithLabelValues(pv.Name, pv.Name, cacPv.ProviderID).Set(iopsCost)
+					}
+					if cmme.PVThroughputPriceRecorder != nil {
+						cmme.PVThroughputPriceRecorder.WithLabelValues(pv.Name, pv.Name, cacPv.ProviderID).Set(throughputCost)
+					}
+					pvPerformanceSeen[getKeyFromLabelStrings(pv.Name, pv.Name, cacPv.ProviderID)] = true
+				}
+			}
+
+			if cmme.VolumeSnapshotSizeRecorder != nil {
+				snapshots := volumeSnapshotInfos(cmme.KubeClusterCache.GetAllVolumeSnapshots(), cmme.KubeClusterCache.GetAllVolumeSnapshotContents())
+				for _, snapshot := range snapshots {
+					labels := snapshot.labels()
+					cmme.VolumeSnapshotSizeRecorder.WithLabelValues(labels...).Set(snapshot.Bytes)
+					volumeSnapshotSeen[getKeyFromLabelStrings(labels...)] = true
+				}
 			}
 
 			for labelString, seen := range nodeSeen {
@@ -744,6 +836,20 @@ func (cmme *CostModelMetricsEmitter) Start() bool {
 					pvSeen[labelString] = false
 				}
 			}
+			for labelString, seen := range pvPerformanceSeen {
+				if !seen {
+					labels := getLabelStringsFromKey(labelString)
+					if cmme.PVIOPSPriceRecorder != nil {
+						cmme.PVIOPSPriceRecorder.DeleteLabelValues(labels...)
+					}
+					if cmme.PVThroughputPriceRecorder != nil {
+						cmme.PVThroughputPriceRecorder.DeleteLabelValues(labels...)
+					}
+					delete(pvPerformanceSeen, labelString)
+				} else {
+					pvPerformanceSeen[labelString] = false
+				}
+			}
 			for labelString, seen := range pvcSeen {
 				if !seen {
 					labels := getLabelStringsFromKey(labelString)
@@ -753,6 +859,15 @@ func (cmme *CostModelMetricsEmitter) Start() bool {
 					pvcSeen[labelString] = false
 				}
 			}
+			for labelString, seen := range volumeSnapshotSeen {
+				if !seen {
+					labels := getLabelStringsFromKey(labelString)
+					cmme.VolumeSnapshotSizeRecorder.DeleteLabelValues(labels...)
+					delete(volumeSnapshotSeen, labelString)
+				} else {
+					volumeSnapshotSeen[labelString] = false
+				}
+			}
 
 			select {
 			case <-time.After(time.Minute):
@@ -770,4 +885,8 @@ func (cmme *CostModelMetricsEmitter) Start() bool {
 // or if the emission is paused.
 func (cmme *CostModelMetricsEmitter) Stop() {
 	cmme.runState.Stop()
+
+	if cmme.PushEmitter != nil {
+		cmme.PushEmitter.Stop()
+	}
 }
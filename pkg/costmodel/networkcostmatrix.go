@@ -0,0 +1,352 @@
+package costmodel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/util"
+	"github.com/opencost/opencost/pkg/util/timeutil"
+)
+
+const (
+	queryFmtNetZoneGiBByDestination     = `sum(increase(kubecost_pod_network_egress_bytes_total{internet="false", sameZone="false", sameRegion="true"}[%s])) by (pod_name, namespace, destination_pod_name, destination_namespace, %s) / 1024 / 1024 / 1024`
+	queryFmtNetRegionGiBByDestination   = `sum(increase(kubecost_pod_network_egress_bytes_total{internet="false", sameZone="false", sameRegion="false"}[%s])) by (pod_name, namespace, destination_pod_name, destination_namespace, %s) / 1024 / 1024 / 1024`
+	queryFmtNetInternetGiBByDestination = `sum(increase(kubecost_pod_network_egress_bytes_total{internet="true"}[%s])) by (pod_name, namespace, destination_pod_name, destination_namespace, %s) / 1024 / 1024 / 1024`
+)
+
+// Classifications of the network traffic in a NetworkCostMatrix
+const (
+	NetworkCostTypeCrossZone   = "crossZone"
+	NetworkCostTypeCrossRegion = "crossRegion"
+	NetworkCostTypeInternet    = "internet"
+)
+
+// NetworkCostMatrixInternet is the destination of internet traffic whose
+// destination is not known.
+const NetworkCostMatrixInternet = "__internet__"
+
+// networkCostTypes maps the Allocation network cost fields to the
+// classifications of a NetworkCostMatrix.
+var networkCostTypes = map[string]string{
+	networkCrossZoneCost:   NetworkCostTypeCrossZone,
+	networkCrossRegionCost: NetworkCostTypeCrossRegion,
+	networkInternetCost:    NetworkCostTypeInternet,
+}
+
+// NetworkCostMatrix is the network traffic and its cost from each source to
+// each destination over a window, where sources and destinations are
+// aggregated by the same properties as allocations.
+type NetworkCostMatrix struct {
+	Window    kubecost.Window           `json:"window"`
+	Aggregate []string                  `json:"aggregate"`
+	Entries   []*NetworkCostMatrixEntry `json:"entries"`
+}
+
+// NetworkCostMatrixEntry is the network traffic of one classification from a
+// source to a destination. Destinations which are not known are
+// __unallocated__, or __internet__ for internet traffic.
+type NetworkCostMatrixEntry struct {
+	Source      string  `json:"source"`
+	Destination string  `json:"destination"`
+	Type        string  `json:"type"`
+	Bytes       float64 `json:"bytes"`
+	Cost        float64 `json:"cost"`
+}
+
+type networkCostMatrixKey struct {
+	Source      string
+	Destination string
+	Type        string
+}
+
+// networkCostMatrixBuilder accumulates traffic between pods into a
+// NetworkCostMatrix, aggregating and filtering by the properties of the
+// allocations of each pod.
+type networkCostMatrixBuilder struct {
+	aggregate  []string
+	filter     kubecost.AllocationFilter
+	properties map[podKey]*kubecost.AllocationProperties
+	entries    map[networkCostMatrixKey]*NetworkCostMatrixEntry
+}
+
+func newNetworkCostMatrixBuilder(aggregate []string, filter kubecost.AllocationFilter, allocSet *kubecost.AllocationSet) *networkCostMatrixBuilder {
+	properties := map[podKey]*kubecost.AllocationProperties{}
+	if allocSet != nil {
+		for _, alloc := range allocSet.Allocations {
+			if alloc.Properties == nil || alloc.Properties.Pod == "" || alloc.IsIdle() || alloc.IsUnmounted() {
+				continue
+			}
+
+			key := newPodKey(alloc.Properties.Cluster, alloc.Properties.Namespace, alloc.Properties.Pod)
+			if _, ok := properties[key]; ok {
+				continue
+			}
+
+			props := alloc.Properties.Clone()
+			props.Container = ""
+			properties[key] = props
+		}
+	}
+
+	return &networkCostMatrixBuilder{
+		aggregate:  aggregate,
+		filter:     filter,
+		properties: properties,
+		entries:    map[networkCostMatrixKey]*NetworkCostMatrixEntry{},
+	}
+}
+
+// podProperties returns the properties of the allocations of the given pod,
+// or only its cluster, namespace, and name if it was not allocated.
+func (b *networkCostMatrixBuilder) podProperties(key podKey) *kubecost.AllocationProperties {
+	if props, ok := b.properties[key]; ok {
+		return props
+	}
+
+	return &kubecost.AllocationProperties{
+		Cluster:   key.Cluster,
+		Namespace: key.Namespace,
+		Pod:       key.Pod,
+	}
+}
+
+// add records traffic from the source to the destination, if the source
+// matches the filter. The destination may be nil, if it is not known.
+func (b *networkCostMatrixBuilder) add(src, dst *kubecost.AllocationProperties, costType string, byteCount, cost float64) {
+	if byteCount <= 0.0 {
+		return
+	}
+
+	if b.filter != nil && !b.filter.Matches(&kubecost.Allocation{Properties: src}) {
+		return
+	}
+
+	var dstName string
+	if dst != nil {
+		dstName = dst.GenerateKey(b.aggregate, nil)
+	} else if costType == NetworkCostTypeInternet {
+		dstName = NetworkCostMatrixInternet
+	} else {
+		dstName = kubecost.UnallocatedSuffix
+	}
+
+	key := networkCostMatrixKey{
+		Source:      src.GenerateKey(b.aggregate, nil),
+		Destination: dstName,
+		Type:        costType,
+	}
+
+	entry, ok := b.entries[key]
+	if !ok {
+		entry = &NetworkCostMatrixEntry{
+			Source:      key.Source,
+			Destination: key.Destination,
+			Type:        key.Type,
+		}
+		b.entries[key] = entry
+	}
+
+	entry.Bytes += byteCount
+	entry.Cost += cost
+}
+
+// addUsage records the egress of each pod from the joined results of the
+// network costs daemon, by destination where the metric provides it.
+func (b *networkCostMatrixBuilder) addUsage(usage map[string]*NetworkUsageData, costPerGiB map[string]map[string]float64) {
+	for _, u := range usage {
+		src := b.podProperties(newPodKey(u.ClusterID, u.Namespace, u.PodName))
+
+		var dst *kubecost.AllocationProperties
+		if u.DestinationPodName != "" {
+			dst = b.podProperties(newPodKey(u.ClusterID, u.DestinationNamespace, u.DestinationPodName))
+		} else if u.DestinationNamespace != "" {
+			dst = &kubecost.AllocationProperties{
+				Cluster:   u.ClusterID,
+				Namespace: u.DestinationNamespace,
+			}
+		}
+
+		egress := map[string][]*util.Vector{
+			networkCrossZoneCost:   u.NetworkZoneEgress,
+			networkCrossRegionCost: u.NetworkRegionEgress,
+			networkInternetCost:    u.NetworkInternetEgress,
+		}
+		for costField, vectors := range egress {
+			gib := 0.0
+			for _, v := range vectors {
+				gib += v.Value
+			}
+
+			cost := gib * costPerGiB[costField][u.ClusterID]
+			b.add(src, dst, networkCostTypes[costField], gib*1024.0*1024.0*1024.0, cost)
+		}
+	}
+}
+
+// addFlows records the bytes of each flow within the window which egresses
+// from a pod, classified by the topology of the cluster. Traffic which stays
+// within a zone is not charged, and so is not recorded.
+func (b *networkCostMatrixBuilder) addFlows(window kubecost.Window, flows []*NetworkFlow, topology networkTopology, costPerGiB map[string]map[string]float64) {
+	for _, flow := range flows {
+		srcEndpoint, ok := topology[flow.Source]
+		if !ok || srcEndpoint.Pod == nil {
+			continue
+		}
+
+		costField := topology.classify(flow.Source, flow.Destination)
+		if costField == "" {
+			continue
+		}
+
+		byteCount := flow.overlap(*window.Start(), *window.End())
+
+		var dst *kubecost.AllocationProperties
+		if dstEndpoint, ok := topology[flow.Destination]; ok && dstEndpoint.Pod != nil {
+			dst = b.podProperties(*dstEndpoint.Pod)
+		}
+
+		cost := byteCount / 1024.0 / 1024.0 / 1024.0 * costPerGiB[costField][srcEndpoint.Pod.Cluster]
+		b.add(b.podProperties(*srcEndpoint.Pod), dst, networkCostTypes[costField], byteCount, cost)
+	}
+}
+
+// matrix returns the recorded entries, in descending order of cost.
+func (b *networkCostMatrixBuilder) matrix(window kubecost.Window) *NetworkCostMatrix {
+	entries := make([]*NetworkCostMatrixEntry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Cost != entries[j].Cost {
+			return entries[i].Cost > entries[j].Cost
+		}
+		if entries[i].Source != entries[j].Source {
+			return entries[i].Source < entries[j].Source
+		}
+		if entries[i].Destination != entries[j].Destination {
+			return entries[i].Destination < entries[j].Destination
+		}
+		return entries[i].Type < entries[j].Type
+	})
+
+	return &NetworkCostMatrix{
+		Window:    window.Clone(),
+		Aggregate: b.aggregate,
+		Entries:   entries,
+	}
+}
+
+// ComputeNetworkCostMatrix computes the cross-zone, cross-region, and
+// internet egress between sources and destinations over the given window,
+// aggregated by the given properties, e.g. "namespace" or "controller", and
+// filtered by the properties of the source. Destinations are taken from flow
+// logs, if configured, or otherwise from the destination labels of the
+// network costs daemon metrics, where present.
+func (cm *CostModel) ComputeNetworkCostMatrix(traceCtx context.Context, window kubecost.Window, resolution time.Duration, aggregate []string, filter kubecost.AllocationFilter) (*NetworkCostMatrix, error) {
+	if window.IsOpen() || window.IsNegative() {
+		return nil, fmt.Errorf("illegal window: %s", window)
+	}
+
+	if len(aggregate) == 0 {
+		aggregate = []string{kubecost.AllocationNamespaceProp}
+	}
+
+	start, end := *window.Start(), *window.End()
+
+	durStr := timeutil.DurationString(end.Sub(start))
+	if durStr == "" {
+		return nil, fmt.Errorf("illegal duration value for %s", window)
+	}
+
+	// Allocations are computed without pushdown, so that the properties of
+	// destinations which do not match the filter are known.
+	allocSet, err := cm.ComputeAllocationWithContext(traceCtx, start, end, resolution)
+	if err != nil {
+		return nil, fmt.Errorf("error computing allocations for %s: %w", window, err)
+	}
+
+	var flows []*NetworkFlow
+	if cm.NetworkFlows != nil {
+		flows, err = cm.NetworkFlows.Flows(start, end)
+		if err != nil {
+			log.Warnf("CostModel.ComputeNetworkCostMatrix: failed to read flow logs, falling back to network metrics: %s", err)
+			flows = nil
+		}
+	}
+
+	pushdown := newAllocationPushdown(filter)
+	queries := newAllocationQueries(durStr, "", "")
+
+	ctx := prom.NewNamedContext(cm.PrometheusClient, prom.AllocationContextName).WithContext(traceCtx)
+
+	resChNetZoneCostPerGiB := ctx.QueryAtTime(queries.query(allocationQueryNetZoneCostPerGiB, pushdown, pushdownClusterLabels), end)
+	resChNetRegionCostPerGiB := ctx.QueryAtTime(queries.query(allocationQueryNetRegionCostPerGiB, pushdown, pushdownClusterLabels), end)
+	resChNetInternetCostPerGiB := ctx.QueryAtTime(queries.query(allocationQueryNetInternetCostPerGiB, pushdown, pushdownClusterLabels), end)
+
+	var resChNetZoneGiB, resChNetRegionGiB, resChNetInternetGiB prom.QueryResultsChan
+	var resChPodIPs, resChNodeLabels prom.QueryResultsChan
+	if flows != nil {
+		// Flows are classified by the topology of both of their endpoints, so
+		// the addresses of all pods and the labels of all nodes are queried
+		resChPodIPs = ctx.QueryAtTime(queries.query(allocationQueryPodIPs, nil, nil), end)
+		resChNodeLabels = ctx.QueryAtTime(queries.query(allocationQueryNodeLabels, nil, nil), end)
+	} else {
+		queryNetZoneGiB := queries.query(allocationQueryNetZoneGiBByDest, pushdown, pushdownPodNameLabels)
+		resChNetZoneGiB = ctx.QueryAtTime(queryNetZoneGiB, end)
+
+		queryNetRegionGiB := queries.query(allocationQueryNetRegionGiBByDest, pushdown, pushdownPodNameLabels)
+		resChNetRegionGiB = ctx.QueryAtTime(queryNetRegionGiB, end)
+
+		queryNetInternetGiB := queries.query(allocationQueryNetInternetGiBByDest, pushdown, pushdownPodNameLabels)
+		resChNetInternetGiB = ctx.QueryAtTime(queryNetInternetGiB, end)
+	}
+
+	resNetZoneCostPerGiB, _ := resChNetZoneCostPerGiB.Await()
+	resNetRegionCostPerGiB, _ := resChNetRegionCostPerGiB.Await()
+	resNetInternetCostPerGiB, _ := resChNetInternetCostPerGiB.Await()
+
+	var resNetZoneGiB, resNetRegionGiB, resNetInternetGiB []*prom.QueryResult
+	var resPodIPs, resNodeLabels []*prom.QueryResult
+	if flows != nil {
+		resPodIPs, _ = resChPodIPs.Await()
+		resNodeLabels, _ = resChNodeLabels.Await()
+	} else {
+		resNetZoneGiB, _ = resChNetZoneGiB.Await()
+		resNetRegionGiB, _ = resChNetRegionGiB.Await()
+		resNetInternetGiB, _ = resChNetInternetGiB.Await()
+	}
+
+	if ctx.HasErrors() {
+		for _, err := range ctx.Errors() {
+			log.Errorf("CostModel.ComputeNetworkCostMatrix: query context error %s", err)
+		}
+		return nil, ctx.ErrorCollection()
+	}
+
+	costPerGiB := map[string]map[string]float64{
+		networkCrossZoneCost:   networkCostPerGiBByCluster(resNetZoneCostPerGiB),
+		networkCrossRegionCost: networkCostPerGiBByCluster(resNetRegionCostPerGiB),
+		networkInternetCost:    networkCostPerGiBByCluster(resNetInternetCostPerGiB),
+	}
+
+	builder := newNetworkCostMatrixBuilder(aggregate, filter, allocSet)
+
+	if flows != nil {
+		builder.addFlows(window, flows, newNetworkTopology(resPodIPs, resNodeLabels), costPerGiB)
+	} else {
+		usage, err := GetNetworkUsageData(resNetZoneGiB, resNetRegionGiB, resNetInternetGiB, env.GetClusterID())
+		if err != nil {
+			return nil, fmt.Errorf("error joining network usage for %s: %w", window, err)
+		}
+		builder.addUsage(usage, costPerGiB)
+	}
+
+	return builder.matrix(window), nil
+}
@@ -0,0 +1,140 @@
+package costmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/util"
+)
+
+// Responses recorded from a Prometheus scraping the network costs daemon,
+// with and without destination labels.
+const (
+	recordedNetZoneGiBByDestination = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace1","pod_name":"pod1","destination_namespace":"namespace2","destination_pod_name":"pod3"},"value":[1677632400,"2"]},
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace1","pod_name":"pod2","destination_namespace":"namespace2","destination_pod_name":"pod3"},"value":[1677632400,"1"]},
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace1","pod_name":"pod1","destination_namespace":"namespace3"},"value":[1677632400,"1"]}
+	]}}`
+
+	recordedNetInternetGiB = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace1","pod_name":"pod1"},"value":[1677632400,"0.5"]},
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace2","pod_name":"pod3"},"value":[1677632400,"1"]}
+	]}}`
+)
+
+func newNetworkCostMatrixTestAllocationSet(start, end time.Time) *kubecost.AllocationSet {
+	allocSet := kubecost.NewAllocationSet(start, end)
+	for _, props := range []*kubecost.AllocationProperties{
+		{Cluster: "cluster1", Namespace: "namespace1", Pod: "pod1", Container: "container1", ControllerKind: "deployment", Controller: "frontend"},
+		{Cluster: "cluster1", Namespace: "namespace1", Pod: "pod2", Container: "container1", ControllerKind: "deployment", Controller: "frontend"},
+		{Cluster: "cluster1", Namespace: "namespace2", Pod: "pod3", Container: "container1", ControllerKind: "statefulset", Controller: "db"},
+	} {
+		allocSet.Set(kubecost.NewMockUnitAllocation(props.Pod, start, end.Sub(start), props))
+	}
+	return allocSet
+}
+
+func TestGetNetworkUsageData_Destinations(t *testing.T) {
+	usage, err := GetNetworkUsageData(recordedQueryResults(t, recordedNetZoneGiBByDestination), nil, recordedQueryResults(t, recordedNetInternetGiB), "cluster1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Usage without destinations is keyed by pod, as before
+	if _, ok := usage["namespace1,pod1,cluster1"]; !ok {
+		t.Fatalf("expected usage keyed by pod")
+	}
+
+	u, ok := usage["namespace1,pod1,cluster1,namespace2,pod3"]
+	if !ok {
+		t.Fatalf("expected usage keyed by pod and destination")
+	}
+	if u.DestinationNamespace != "namespace2" || u.DestinationPodName != "pod3" || u.NetworkZoneEgress[0].Value != 2.0 {
+		t.Fatalf("unexpected usage: %+v", u)
+	}
+}
+
+func TestNetworkCostMatrixBuilder_Usage(t *testing.T) {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	window := kubecost.NewClosedWindow(start, end)
+
+	usage, err := GetNetworkUsageData(recordedQueryResults(t, recordedNetZoneGiBByDestination), nil, recordedQueryResults(t, recordedNetInternetGiB), "cluster1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	costPerGiB := map[string]map[string]float64{
+		networkCrossZoneCost: {"cluster1": 0.01},
+		networkInternetCost:  {"cluster1": 0.12},
+	}
+
+	filter := kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: "namespace1"}
+	builder := newNetworkCostMatrixBuilder([]string{kubecost.AllocationControllerProp}, filter, newNetworkCostMatrixTestAllocationSet(start, end))
+	builder.addUsage(usage, costPerGiB)
+	matrix := builder.matrix(window)
+
+	gib := 1024.0 * 1024.0 * 1024.0
+	expected := []*NetworkCostMatrixEntry{
+		{Source: "deployment:frontend", Destination: NetworkCostMatrixInternet, Type: NetworkCostTypeInternet, Bytes: 0.5 * gib, Cost: 0.06},
+		{Source: "deployment:frontend", Destination: "statefulset:db", Type: NetworkCostTypeCrossZone, Bytes: 3 * gib, Cost: 0.03},
+		{Source: "deployment:frontend", Destination: kubecost.UnallocatedSuffix, Type: NetworkCostTypeCrossZone, Bytes: gib, Cost: 0.01},
+	}
+
+	// The egress of namespace2 is filtered out
+	if len(matrix.Entries) != len(expected) {
+		t.Fatalf("expected %d entries; got %d", len(expected), len(matrix.Entries))
+	}
+	for i, entry := range matrix.Entries {
+		e := expected[i]
+		if entry.Source != e.Source || entry.Destination != e.Destination || entry.Type != e.Type {
+			t.Fatalf("entry %d: expected %s -> %s (%s); got %s -> %s (%s)", i, e.Source, e.Destination, e.Type, entry.Source, entry.Destination, entry.Type)
+		}
+		if !util.IsApproximately(entry.Bytes, e.Bytes) || !util.IsApproximately(entry.Cost, e.Cost) {
+			t.Fatalf("entry %d: expected %f bytes at %f; got %f at %f", i, e.Bytes, e.Cost, entry.Bytes, entry.Cost)
+		}
+	}
+}
+
+func TestNetworkCostMatrixBuilder_Flows(t *testing.T) {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	window := kubecost.NewClosedWindow(start, end)
+
+	pod1 := newPodKey("cluster1", "namespace1", "pod1")
+	pod3 := newPodKey("cluster1", "namespace2", "pod3")
+	topology := networkTopology{
+		"10.0.1.5": {Pod: &pod1, Node: "node1", Zone: "us-east-1a", Region: "us-east-1"},
+		"10.0.1.6": {Node: "node1", Zone: "us-east-1a", Region: "us-east-1"},
+		"10.0.2.7": {Pod: &pod3, Node: "node2", Zone: "us-east-1b", Region: "us-east-1"},
+	}
+
+	gib := 1024.0 * 1024.0 * 1024.0
+	flows := []*NetworkFlow{
+		{Source: "10.0.1.5", Destination: "10.0.2.7", Bytes: gib, Start: start, End: start.Add(time.Minute)},
+		{Source: "10.0.1.5", Destination: "52.94.76.10", Bytes: gib, Start: start, End: start.Add(time.Minute)},
+		// Traffic within a zone is not charged
+		{Source: "10.0.1.5", Destination: "10.0.1.6", Bytes: gib, Start: start, End: start.Add(time.Minute)},
+		// Ingress from the internet is not charged to the pod
+		{Source: "52.94.76.10", Destination: "10.0.1.5", Bytes: gib, Start: start, End: start.Add(time.Minute)},
+	}
+	costPerGiB := map[string]map[string]float64{
+		networkCrossZoneCost: {"cluster1": 0.01},
+		networkInternetCost:  {"cluster1": 0.12},
+	}
+
+	builder := newNetworkCostMatrixBuilder([]string{kubecost.AllocationNamespaceProp}, nil, newNetworkCostMatrixTestAllocationSet(start, end))
+	builder.addFlows(window, flows, topology, costPerGiB)
+	matrix := builder.matrix(window)
+
+	if len(matrix.Entries) != 2 {
+		t.Fatalf("expected 2 entries; got %d", len(matrix.Entries))
+	}
+	if e := matrix.Entries[0]; e.Destination != NetworkCostMatrixInternet || !util.IsApproximately(e.Cost, 0.12) {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e := matrix.Entries[1]; e.Source != "namespace1" || e.Destination != "namespace2" || e.Type != NetworkCostTypeCrossZone || !util.IsApproximately(e.Cost, 0.01) {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+}
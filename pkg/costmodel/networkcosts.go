@@ -1,11 +1,29 @@
 package costmodel
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	costAnalyzerCloud "github.com/opencost/opencost/pkg/cloud"
 	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
 	"github.com/opencost/opencost/pkg/log"
 	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/storage"
 	"github.com/opencost/opencost/pkg/util"
+	"github.com/opencost/opencost/pkg/util/json"
+
+	v1 "k8s.io/api/core/v1"
 )
 
 // NetworkUsageVNetworkUsageDataector contains the network usage values for egress network traffic
@@ -13,6 +31,8 @@ type NetworkUsageData struct {
 	ClusterID             string
 	PodName               string
 	Namespace             string
+	DestinationNamespace  string
+	DestinationPodName    string
 	NetworkZoneEgress     []*util.Vector
 	NetworkRegionEgress   []*util.Vector
 	NetworkInternetEgress []*util.Vector
@@ -20,14 +40,17 @@ type NetworkUsageData struct {
 
 // NetworkUsageVector contains a network usage vector for egress network traffic
 type NetworkUsageVector struct {
-	ClusterID string
-	PodName   string
-	Namespace string
-	Values    []*util.Vector
+	ClusterID            string
+	PodName              string
+	Namespace            string
+	DestinationNamespace string
+	DestinationPodName   string
+	Values               []*util.Vector
 }
 
 // GetNetworkUsageData performs a join of the the results of zone, region, and internet usage queries to return a single
-// map containing network costs for each namespace+pod
+// map containing network costs for each namespace+pod. If the queries are grouped by destination_namespace and
+// destination_pod_name, usage is joined for each namespace+pod+destination instead.
 func GetNetworkUsageData(zr []*prom.QueryResult, rr []*prom.QueryResult, ir []*prom.QueryResult, defaultClusterID string) (map[string]*NetworkUsageData, error) {
 	zoneNetworkMap, err := getNetworkUsage(zr, defaultClusterID)
 	if err != nil {
@@ -49,10 +72,12 @@ func GetNetworkUsageData(zr []*prom.QueryResult, rr []*prom.QueryResult, ir []*p
 		existing, ok := usageData[k]
 		if !ok {
 			usageData[k] = &NetworkUsageData{
-				ClusterID:         v.ClusterID,
-				PodName:           v.PodName,
-				Namespace:         v.Namespace,
-				NetworkZoneEgress: v.Values,
+				ClusterID:            v.ClusterID,
+				PodName:              v.PodName,
+				Namespace:            v.Namespace,
+				DestinationNamespace: v.DestinationNamespace,
+				DestinationPodName:   v.DestinationPodName,
+				NetworkZoneEgress:    v.Values,
 			}
 			continue
 		}
@@ -64,10 +89,12 @@ func GetNetworkUsageData(zr []*prom.QueryResult, rr []*prom.QueryResult, ir []*p
 		existing, ok := usageData[k]
 		if !ok {
 			usageData[k] = &NetworkUsageData{
-				ClusterID:           v.ClusterID,
-				PodName:             v.PodName,
-				Namespace:           v.Namespace,
-				NetworkRegionEgress: v.Values,
+				ClusterID:            v.ClusterID,
+				PodName:              v.PodName,
+				Namespace:            v.Namespace,
+				DestinationNamespace: v.DestinationNamespace,
+				DestinationPodName:   v.DestinationPodName,
+				NetworkRegionEgress:  v.Values,
 			}
 			continue
 		}
@@ -82,6 +109,8 @@ func GetNetworkUsageData(zr []*prom.QueryResult, rr []*prom.QueryResult, ir []*p
 				ClusterID:             v.ClusterID,
 				PodName:               v.PodName,
 				Namespace:             v.Namespace,
+				DestinationNamespace:  v.DestinationNamespace,
+				DestinationPodName:    v.DestinationPodName,
 				NetworkInternetEgress: v.Values,
 			}
 			continue
@@ -158,12 +187,23 @@ func getNetworkUsage(qrs []*prom.QueryResult, defaultClusterID string) (map[stri
 			clusterID = defaultClusterID
 		}
 
+		// Destination labels are optional, and only present if the query is
+		// grouped by them
+		dstNamespace, _ := val.GetString("destination_namespace")
+		dstPodName, _ := val.GetString("destination_pod_name")
+
 		key := namespace + "," + podName + "," + clusterID
+		if dstNamespace != "" || dstPodName != "" {
+			key += "," + dstNamespace + "," + dstPodName
+		}
+
 		ncdmap[key] = &NetworkUsageVector{
-			ClusterID: clusterID,
-			Namespace: namespace,
-			PodName:   podName,
-			Values:    val.Values,
+			ClusterID:            clusterID,
+			Namespace:            namespace,
+			PodName:              podName,
+			DestinationNamespace: dstNamespace,
+			DestinationPodName:   dstPodName,
+			Values:               val.Values,
 		}
 	}
 	return ncdmap, nil
@@ -178,3 +218,507 @@ func max(x int, rest ...int) int {
 	}
 	return curr
 }
+
+// NetworkFlow is the number of bytes sent from one address to another over a
+// period of time, as recorded by a flow log.
+type NetworkFlow struct {
+	Source      string
+	Destination string
+	Bytes       float64
+	Start       time.Time
+	End         time.Time
+}
+
+// overlap returns the bytes of the flow sent within the given window,
+// assuming they were sent at a constant rate.
+func (nf *NetworkFlow) overlap(start, end time.Time) float64 {
+	// Flows recorded at an instant are within the window, or not
+	if !nf.End.After(nf.Start) {
+		if !nf.Start.Before(start) && nf.Start.Before(end) {
+			return nf.Bytes
+		}
+		return 0.0
+	}
+
+	if !nf.Start.Before(end) || !nf.End.After(start) {
+		return 0.0
+	}
+
+	s, e := nf.Start, nf.End
+	if s.Before(start) {
+		s = start
+	}
+	if e.After(end) {
+		e = end
+	}
+
+	return nf.Bytes * float64(e.Sub(s)) / float64(nf.End.Sub(nf.Start))
+}
+
+// NetworkFlowSource reads flow logs from a directory of a storage.Storage, as
+// an alternative to the metrics of the network costs daemon. Files may be AWS
+// VPC flow logs, in the default or a custom format with a header, or Cilium
+// Hubble flows as JSON lines. Hubble flows, as output by "hubble observe -o
+// json", do not record their size, so only flows which an exporter has
+// aggregated with a "bytes" count alongside the flow are counted:
+//
+//	{"flow": {"time": "2023-03-01T00:00:00Z", "verdict": "FORWARDED", "IP": {"source": "10.0.1.5", "destination": "10.0.2.7"}}, "bytes": 52344}
+//
+// Files ending in ".gz" are decompressed. The time range of the flows of each
+// file is indexed when it is first read, so that files are only read for the
+// windows they overlap, until they are modified.
+type NetworkFlowSource struct {
+	store storage.Storage
+	dir   string
+
+	lock  sync.Mutex
+	index map[string]*flowLogIndex
+}
+
+// flowLogIndex is the time range spanned by the flows of a flow log file, as
+// of the given modification time and size of the file.
+type flowLogIndex struct {
+	modTime time.Time
+	size    int64
+	start   time.Time
+	end     time.Time
+	empty   bool
+}
+
+// overlaps returns true if the flows of the file may overlap the given window.
+func (fli *flowLogIndex) overlaps(start, end time.Time) bool {
+	if fli.empty {
+		return false
+	}
+	return fli.start.Before(end) && !fli.end.Before(start)
+}
+
+// NewNetworkFlowSource creates a NetworkFlowSource which reads flow logs from
+// the given directory of the given storage.
+func NewNetworkFlowSource(store storage.Storage, dir string) *NetworkFlowSource {
+	return &NetworkFlowSource{
+		store: store,
+		dir:   dir,
+		index: map[string]*flowLogIndex{},
+	}
+}
+
+// Flows returns the flows of all flow log files modified since the start of
+// the given window which overlap the window.
+func (nfs *NetworkFlowSource) Flows(start, end time.Time) ([]*NetworkFlow, error) {
+	files, err := nfs.store.List(nfs.dir)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing flow logs: %w", err)
+	}
+
+	nfs.lock.Lock()
+	index := make(map[string]*flowLogIndex, len(files))
+	for _, file := range files {
+		if fli, ok := nfs.index[file.Name]; ok && fli.modTime.Equal(file.ModTime) && fli.size == file.Size {
+			index[file.Name] = fli
+		}
+	}
+	// Files which no longer exist are dropped from the index
+	nfs.index = index
+	nfs.lock.Unlock()
+
+	flows := []*NetworkFlow{}
+	for _, file := range files {
+		// Files last modified before the window cannot contain its flows
+		if file.ModTime.Before(start) {
+			continue
+		}
+
+		if fli, ok := index[file.Name]; ok && !fli.overlaps(start, end) {
+			continue
+		}
+
+		data, err := nfs.store.Read(path.Join(nfs.dir, file.Name))
+		if err != nil {
+			return nil, fmt.Errorf("reading flow log %s: %w", file.Name, err)
+		}
+
+		fileFlows, err := parseFlowLog(file.Name, data)
+		if err != nil {
+			log.DedupedWarningf(5, "NetworkFlowSource: failed to parse flow log %s: %s", file.Name, err)
+		}
+
+		fli := &flowLogIndex{
+			modTime: file.ModTime,
+			size:    file.Size,
+			empty:   len(fileFlows) == 0,
+		}
+		for _, flow := range fileFlows {
+			if fli.start.IsZero() || flow.Start.Before(fli.start) {
+				fli.start = flow.Start
+			}
+			if flow.End.After(fli.end) {
+				fli.end = flow.End
+			}
+
+			if flow.overlap(start, end) > 0.0 {
+				flows = append(flows, flow)
+			}
+		}
+
+		nfs.lock.Lock()
+		nfs.index[file.Name] = fli
+		nfs.lock.Unlock()
+	}
+
+	return flows, nil
+}
+
+// parseFlowLog parses the flows of a VPC flow log or of Hubble flows,
+// depending on the content of the file.
+func parseFlowLog(name string, data []byte) ([]*NetworkFlow, error) {
+	if strings.HasSuffix(name, ".gz") {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+
+		data, err = io.ReadAll(zr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseHubbleFlows(trimmed)
+	}
+	return parseVPCFlowLog(trimmed)
+}
+
+// vpcFlowLogDefaultFields are the fields of the default VPC flow log format.
+var vpcFlowLogDefaultFields = []string{"version", "account-id", "interface-id", "srcaddr", "dstaddr", "srcport", "dstport", "protocol", "packets", "bytes", "start", "end", "action", "log-status"}
+
+// parseVPCFlowLog parses space-separated VPC flow log records. If the first
+// line is a header, it determines the fields of the records. Rejected flows,
+// and records without data, are skipped, as are duplicates of a flow recorded
+// by both the source and destination network interfaces.
+func parseVPCFlowLog(data []byte) ([]*NetworkFlow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	fields := map[string]int{}
+	for i, field := range vpcFlowLogDefaultFields {
+		fields[field] = i
+	}
+
+	flows := []*NetworkFlow{}
+	seen := map[string]bool{}
+	for line := 0; scanner.Scan(); line++ {
+		record := strings.Fields(scanner.Text())
+		if len(record) == 0 {
+			continue
+		}
+
+		if line == 0 && !strings.Contains(record[0], ".") && !isNumeric(record[0]) {
+			fields = map[string]int{}
+			for i, field := range record {
+				fields[strings.ReplaceAll(field, "_", "-")] = i
+			}
+			continue
+		}
+
+		get := func(field string) string {
+			i, ok := fields[field]
+			if !ok || i >= len(record) {
+				return "-"
+			}
+			return record[i]
+		}
+
+		if action := get("action"); action != "-" && action != "ACCEPT" {
+			continue
+		}
+
+		src, dst := get("srcaddr"), get("dstaddr")
+		byteCount, err := strconv.ParseFloat(get("bytes"), 64)
+		if err != nil || src == "-" || dst == "-" {
+			continue
+		}
+
+		startSecs, err := strconv.ParseInt(get("start"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid start: %s", line+1, get("start"))
+		}
+		endSecs, err := strconv.ParseInt(get("end"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid end: %s", line+1, get("end"))
+		}
+
+		key := strings.Join([]string{src, dst, get("srcport"), get("dstport"), get("protocol"), get("start")}, " ")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		flows = append(flows, &NetworkFlow{
+			Source:      src,
+			Destination: dst,
+			Bytes:       byteCount,
+			Start:       time.Unix(startSecs, 0).UTC(),
+			End:         time.Unix(endSecs, 0).UTC(),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return flows, nil
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// hubbleFlow is the subset of a Hubble flow, with the number of bytes of the
+// aggregated flow, required to attribute its cost.
+type hubbleFlow struct {
+	Flow *struct {
+		Time    time.Time `json:"time"`
+		Verdict string    `json:"verdict"`
+		IP      *struct {
+			Source      string `json:"source"`
+			Destination string `json:"destination"`
+		} `json:"IP"`
+	} `json:"flow"`
+	Bytes float64 `json:"bytes"`
+}
+
+// parseHubbleFlows parses Hubble flows as JSON lines. Dropped flows, and flows
+// without IP addresses or a byte count, are skipped.
+func parseHubbleFlows(data []byte) ([]*NetworkFlow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	flows := []*NetworkFlow{}
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		hf := &hubbleFlow{}
+		if err := json.Unmarshal(text, hf); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if hf.Flow == nil || hf.Flow.IP == nil || hf.Bytes <= 0.0 {
+			continue
+		}
+		if hf.Flow.Verdict == "DROPPED" || hf.Flow.Verdict == "ERROR" {
+			continue
+		}
+
+		flows = append(flows, &NetworkFlow{
+			Source:      hf.Flow.IP.Source,
+			Destination: hf.Flow.IP.Destination,
+			Bytes:       hf.Bytes,
+			Start:       hf.Flow.Time,
+			End:         hf.Flow.Time,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return flows, nil
+}
+
+// networkEndpoint is the pod or node to which an IP address in the cluster is
+// assigned, and its topology.
+type networkEndpoint struct {
+	Pod    *podKey
+	Node   string
+	Zone   string
+	Region string
+}
+
+// networkTopology maps the IP addresses of the pods and nodes of a cluster to
+// their endpoints.
+type networkTopology map[string]*networkEndpoint
+
+// newNetworkTopology builds the topology of the clusters over a window from
+// the addresses of their pods and nodes, queried from kube_pod_info, and the
+// zone and region labels of their nodes, queried from kube_node_labels. Pods
+// on the host network are identified by their node. If an address was
+// assigned to several pods over the window, the last one queried is used.
+func newNetworkTopology(resPodIPs, resNodeLabels []*prom.QueryResult) networkTopology {
+	topology := networkTopology{}
+
+	nodes := map[nodeKey]*networkEndpoint{}
+	for _, res := range resNodeLabels {
+		key, err := resultNodeKey(res, env.GetPromClusterLabel(), "node")
+		if err != nil {
+			continue
+		}
+
+		labels := res.GetLabels()
+		nodes[key] = &networkEndpoint{
+			Node:   key.Node,
+			Zone:   nodeLabelValue(labels, v1.LabelTopologyZone, v1.LabelZoneFailureDomain),
+			Region: nodeLabelValue(labels, v1.LabelTopologyRegion, v1.LabelZoneRegion),
+		}
+	}
+
+	for _, res := range resPodIPs {
+		key, err := resultNodeKey(res, env.GetPromClusterLabel(), "node")
+		if err != nil {
+			continue
+		}
+
+		node, ok := nodes[key]
+		if !ok {
+			node = &networkEndpoint{Node: key.Node}
+			nodes[key] = node
+		}
+
+		hostIP, _ := res.GetString("host_ip")
+		if hostIP != "" {
+			topology[hostIP] = node
+		}
+
+		podIP, _ := res.GetString("pod_ip")
+		if podIP == "" || podIP == hostIP {
+			continue
+		}
+
+		podKey, err := resultPodKey(res, env.GetPromClusterLabel(), "namespace")
+		if err != nil {
+			continue
+		}
+
+		topology[podIP] = &networkEndpoint{
+			Pod:    &podKey,
+			Node:   node.Node,
+			Zone:   node.Zone,
+			Region: node.Region,
+		}
+	}
+
+	return topology
+}
+
+// nodeLabelValue returns the value of the first of the given node labels
+// among the labels of a kube_node_labels result, or "" if it has none of them.
+func nodeLabelValue(labels map[string]string, names ...string) string {
+	for _, name := range names {
+		if value, ok := labels[prom.SanitizeLabelName(name)]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// classify returns the network cost type of a flow between the given
+// addresses, or "" if the flow is free. Flows to or from a public address
+// outside of the cluster are internet traffic; flows to or from a private
+// address outside of the cluster are assumed to stay in the zone. Flows
+// within the cluster are classified by the zones and regions of the nodes of
+// their endpoints.
+func (nt networkTopology) classify(src, dst string) string {
+	srcEndpoint, srcOK := nt[src]
+	dstEndpoint, dstOK := nt[dst]
+
+	if !srcOK || !dstOK {
+		external := dst
+		if !srcOK {
+			external = src
+		}
+
+		ip := net.ParseIP(external)
+		if ip == nil || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+			return ""
+		}
+		return networkInternetCost
+	}
+
+	if srcEndpoint.Region != "" && dstEndpoint.Region != "" && srcEndpoint.Region != dstEndpoint.Region {
+		return networkCrossRegionCost
+	}
+	if srcEndpoint.Zone != "" && dstEndpoint.Zone != "" && srcEndpoint.Zone != dstEndpoint.Zone {
+		return networkCrossZoneCost
+	}
+	return ""
+}
+
+// applyNetworkFlows attributes the bytes of each flow within the window to
+// the containers of its source pod as transferred, and its destination pod as
+// received. The cost of each flow, by its classification, is attributed to
+// its source pod, as egress.
+func applyNetworkFlows(window kubecost.Window, podMap map[podKey]*pod, flows []*NetworkFlow, topology networkTopology, costPerGiB map[string]map[string]float64, podUIDKeyMap map[podKey][]podKey) {
+	for _, flow := range flows {
+		byteCount := flow.overlap(*window.Start(), *window.End())
+		if byteCount <= 0.0 {
+			continue
+		}
+
+		costType := topology.classify(flow.Source, flow.Destination)
+
+		if src, ok := topology[flow.Source]; ok && src.Pod != nil {
+			pods := resultPods(podMap, *src.Pod, podUIDKeyMap)
+			for _, thisPod := range pods {
+				for _, alloc := range thisPod.Allocations {
+					share := byteCount / float64(len(thisPod.Allocations)) / float64(len(pods))
+					alloc.NetworkTransferBytes += share
+
+					cost := share / 1024.0 / 1024.0 / 1024.0 * costPerGiB[costType][src.Pod.Cluster]
+					switch costType {
+					case networkCrossZoneCost:
+						alloc.NetworkCrossZoneCost += cost
+					case networkCrossRegionCost:
+						alloc.NetworkCrossRegionCost += cost
+					case networkInternetCost:
+						alloc.NetworkInternetCost += cost
+					}
+					alloc.NetworkCost += cost
+				}
+			}
+		}
+
+		if dst, ok := topology[flow.Destination]; ok && dst.Pod != nil {
+			pods := resultPods(podMap, *dst.Pod, podUIDKeyMap)
+			for _, thisPod := range pods {
+				for _, alloc := range thisPod.Allocations {
+					alloc.NetworkReceiveBytes += byteCount / float64(len(thisPod.Allocations)) / float64(len(pods))
+				}
+			}
+		}
+	}
+}
+
+// newNetworkFlowSourceFromEnv creates the NetworkFlowSource configured by the
+// environment, reading from the flow log bucket, if configured, or otherwise
+// from the given config storage. It returns nil if neither is available.
+func newNetworkFlowSourceFromEnv(configStore storage.Storage, configPrefix string) *NetworkFlowSource {
+	if bucketConfigPath := env.GetNetworkFlowLogsBucketConfig(); bucketConfigPath != "" {
+		bucketConfig, err := os.ReadFile(bucketConfigPath)
+		if err != nil {
+			log.Warnf("Failed to read flow log bucket config %s: %s", bucketConfigPath, err)
+			return nil
+		}
+
+		store, err := storage.NewBucketStorage(bucketConfig)
+		if err != nil {
+			log.Warnf("Failed to create flow log bucket storage: %s", err)
+			return nil
+		}
+
+		return NewNetworkFlowSource(store, env.GetNetworkFlowLogsDirectory())
+	}
+
+	if configStore == nil {
+		return nil
+	}
+	return NewNetworkFlowSource(configStore, path.Join(configPrefix, env.GetNetworkFlowLogsDirectory()))
+}
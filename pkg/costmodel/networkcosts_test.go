@@ -0,0 +1,213 @@
+package costmodel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/storage"
+	"github.com/opencost/opencost/pkg/util"
+)
+
+const testVPCFlowLog = `version account-id interface-id srcaddr dstaddr srcport dstport protocol packets bytes start end action log-status
+2 123456789010 eni-1235b8ca123456789 10.0.1.5 10.0.2.7 49761 443 6 20 4000 1677628800 1677628860 ACCEPT OK
+2 123456789010 eni-0f5cc1d1d7a2c4b6e 10.0.1.5 10.0.2.7 49761 443 6 20 4000 1677628800 1677628860 ACCEPT OK
+2 123456789010 eni-1235b8ca123456789 10.0.1.5 52.94.76.10 49762 443 6 10 1000 1677628800 1677628860 ACCEPT OK
+2 123456789010 eni-1235b8ca123456789 10.0.1.5 10.0.1.6 49763 80 6 10 2000 1677628800 1677628860 REJECT OK
+2 123456789010 eni-1235b8ca123456789 - - - - - - - 1677628800 1677628860 - NODATA
+`
+
+const testHubbleFlows = `{"flow":{"time":"2023-03-01T00:00:30Z","IP":{"source":"10.0.2.7","destination":"10.0.1.5"}},"bytes":8000}
+{"flow":{"time":"2023-03-01T00:00:30Z","IP":{"source":"10.0.2.7","destination":"10.0.1.5"}}}
+{"flow":{"time":"2023-03-01T00:00:30Z","verdict":"DROPPED","IP":{"source":"10.0.2.7","destination":"10.0.1.6"}},"bytes":1000}
+`
+
+func TestParseFlowLog(t *testing.T) {
+	flows, err := parseFlowLog("flows.log", []byte(testVPCFlowLog))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Duplicates, rejected flows and records without data are skipped
+	if len(flows) != 2 {
+		t.Fatalf("expected 2 flows; got %d", len(flows))
+	}
+	if flows[0].Bytes != 4000 || flows[0].Source != "10.0.1.5" || flows[0].Destination != "10.0.2.7" {
+		t.Fatalf("unexpected flow: %v", flows[0])
+	}
+	if !flows[0].Start.Equal(time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected start: %s", flows[0].Start)
+	}
+
+	// Compressed Hubble flows without a byte count, or dropped, are skipped
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(testHubbleFlows))
+	zw.Close()
+
+	flows, err = parseFlowLog("hubble.json.gz", buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(flows) != 1 || flows[0].Bytes != 8000 || flows[0].Source != "10.0.2.7" {
+		t.Fatalf("unexpected flows: %v", flows)
+	}
+}
+
+// countingStorage counts the reads of each file of a storage.Storage.
+type countingStorage struct {
+	storage.Storage
+	reads map[string]int
+}
+
+func (cs *countingStorage) Read(path string) ([]byte, error) {
+	cs.reads[path]++
+	return cs.Storage.Read(path)
+}
+
+func TestNetworkFlowSource_Flows(t *testing.T) {
+	store := &countingStorage{Storage: storage.NewFileStorage(t.TempDir()), reads: map[string]int{}}
+	store.Write("flowlogs/vpc.log", []byte(testVPCFlowLog))
+	store.Write("flowlogs/hubble.json", []byte(testHubbleFlows))
+
+	nfs := NewNetworkFlowSource(store, "flowlogs")
+
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	flows, err := nfs.Flows(start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(flows) != 3 {
+		t.Fatalf("expected 3 flows; got %d", len(flows))
+	}
+
+	// Flows outside of the window are excluded, and indexed files whose flows
+	// are outside of the window are not read again
+	flows, err = nfs.Flows(start.Add(time.Hour), start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(flows) != 0 {
+		t.Fatalf("expected no flows; got %d", len(flows))
+	}
+	if store.reads["flowlogs/vpc.log"] != 1 || store.reads["flowlogs/hubble.json"] != 1 {
+		t.Fatalf("expected each file to be read once; got %v", store.reads)
+	}
+
+	// Modified files are indexed again
+	store.Write("flowlogs/vpc.log", []byte(testVPCFlowLog+"2 123456789010 eni-1235b8ca123456789 10.0.1.5 10.0.2.7 49764 443 6 20 4000 1677632400 1677632460 ACCEPT OK\n"))
+
+	flows, err = nfs.Flows(start.Add(time.Hour), start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(flows) != 1 {
+		t.Fatalf("expected 1 flow; got %d", len(flows))
+	}
+	if store.reads["flowlogs/vpc.log"] != 2 || store.reads["flowlogs/hubble.json"] != 1 {
+		t.Fatalf("expected only the modified file to be read again; got %v", store.reads)
+	}
+}
+
+func TestNewNetworkTopology(t *testing.T) {
+	t.Setenv(env.ClusterIDEnvVar, "cluster1")
+
+	resPodIPs := []*prom.QueryResult{
+		{Metric: map[string]interface{}{"namespace": "namespace1", "pod": "pod1", "pod_ip": "10.0.1.5", "host_ip": "10.0.1.10", "node": "node1"}},
+		{Metric: map[string]interface{}{"namespace": "namespace1", "pod": "pod2", "pod_ip": "10.0.2.7", "host_ip": "10.0.2.10", "node": "node2"}},
+		// Pods on the host network are identified by their node
+		{Metric: map[string]interface{}{"namespace": "kube-system", "pod": "proxy", "pod_ip": "10.0.2.10", "host_ip": "10.0.2.10", "node": "node2"}},
+	}
+	resNodeLabels := []*prom.QueryResult{
+		{Metric: map[string]interface{}{"node": "node1", "label_topology_kubernetes_io_zone": "us-east-1a", "label_topology_kubernetes_io_region": "us-east-1"}},
+		{Metric: map[string]interface{}{"node": "node2", "label_failure_domain_beta_kubernetes_io_zone": "us-east-1b", "label_failure_domain_beta_kubernetes_io_region": "us-east-1"}},
+	}
+
+	topology := newNetworkTopology(resPodIPs, resNodeLabels)
+
+	pod1 := topology["10.0.1.5"]
+	if pod1 == nil || pod1.Pod == nil || *pod1.Pod != newPodKey("cluster1", "namespace1", "pod1") || pod1.Zone != "us-east-1a" || pod1.Region != "us-east-1" {
+		t.Fatalf("unexpected endpoint of pod1: %+v", pod1)
+	}
+	if node2 := topology["10.0.2.10"]; node2 == nil || node2.Pod != nil || node2.Node != "node2" || node2.Zone != "us-east-1b" {
+		t.Fatalf("unexpected endpoint of node2: %+v", node2)
+	}
+	if costType := topology.classify("10.0.1.5", "10.0.2.7"); costType != networkCrossZoneCost {
+		t.Fatalf("expected cross-zone flow; got %q", costType)
+	}
+}
+
+func TestApplyNetworkFlows(t *testing.T) {
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	window := kubecost.NewClosedWindow(start, end)
+
+	pod1 := newPodKey("cluster1", "namespace1", "pod1")
+	pod2 := newPodKey("cluster1", "namespace1", "pod2")
+	topology := networkTopology{
+		"10.0.1.5":  {Pod: &pod1, Node: "node1", Zone: "us-east-1a", Region: "us-east-1"},
+		"10.0.2.7":  {Pod: &pod2, Node: "node2", Zone: "us-east-1b", Region: "us-east-1"},
+		"10.0.3.9":  {Node: "node3", Zone: "us-west-2a", Region: "us-west-2"},
+		"10.0.1.10": {Node: "node1", Zone: "us-east-1a", Region: "us-east-1"},
+	}
+
+	cases := map[[2]string]string{
+		{"10.0.1.5", "10.0.2.7"}:    networkCrossZoneCost,
+		{"10.0.1.5", "10.0.3.9"}:    networkCrossRegionCost,
+		{"10.0.1.5", "10.0.1.10"}:   "",
+		{"10.0.1.5", "52.94.76.10"}: networkInternetCost,
+		{"52.94.76.10", "10.0.1.5"}: networkInternetCost,
+		{"10.0.1.5", "172.16.0.1"}:  "",
+	}
+	for addrs, expected := range cases {
+		if actual := topology.classify(addrs[0], addrs[1]); actual != expected {
+			t.Fatalf("%v: expected %q; got %q", addrs, expected, actual)
+		}
+	}
+
+	podMap := map[podKey]*pod{}
+	for _, key := range []podKey{pod1, pod2} {
+		podMap[key] = &pod{
+			Window:      window.Clone(),
+			Start:       start,
+			End:         end,
+			Key:         key,
+			Allocations: map[string]*kubecost.Allocation{},
+		}
+		podMap[key].appendContainer("container1")
+	}
+
+	gib := 1024.0 * 1024.0 * 1024.0
+	flows := []*NetworkFlow{
+		// Half of this flow falls within the window
+		{Source: "10.0.1.5", Destination: "10.0.2.7", Bytes: 2 * gib, Start: start.Add(-time.Minute), End: start.Add(time.Minute)},
+		{Source: "10.0.1.5", Destination: "52.94.76.10", Bytes: gib, Start: start, End: start.Add(time.Minute)},
+	}
+	costPerGiB := map[string]map[string]float64{
+		networkCrossZoneCost: {"cluster1": 0.01},
+		networkInternetCost:  {"cluster1": 0.12},
+	}
+
+	applyNetworkFlows(window, podMap, flows, topology, costPerGiB, map[podKey][]podKey{})
+
+	alloc1 := podMap[pod1].Allocations["container1"]
+	if !util.IsApproximately(alloc1.NetworkTransferBytes, 2*gib) {
+		t.Fatalf("expected 2GiB transferred; got %f", alloc1.NetworkTransferBytes)
+	}
+	if !util.IsApproximately(alloc1.NetworkCrossZoneCost, 0.01) || !util.IsApproximately(alloc1.NetworkInternetCost, 0.12) {
+		t.Fatalf("expected zone and internet costs of 0.01 and 0.12; got %f and %f", alloc1.NetworkCrossZoneCost, alloc1.NetworkInternetCost)
+	}
+	if !util.IsApproximately(alloc1.NetworkCost, 0.13) {
+		t.Fatalf("expected network cost of 0.13; got %f", alloc1.NetworkCost)
+	}
+
+	// Receivers are attributed ingress bytes, but no cost
+	alloc2 := podMap[pod2].Allocations["container1"]
+	if !util.IsApproximately(alloc2.NetworkReceiveBytes, gib) || alloc2.NetworkCost != 0.0 {
+		t.Fatalf("expected 1GiB received at no cost; got %f at %f", alloc2.NetworkReceiveBytes, alloc2.NetworkCost)
+	}
+}
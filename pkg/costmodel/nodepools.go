@@ -0,0 +1,190 @@
+package costmodel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+)
+
+// NodePoolSet is the cost and efficiency of each node pool, or node group,
+// over a window. Nodes which do not belong to a known kind of node pool are
+// reported under an __unallocated__ node pool per cluster.
+type NodePoolSet struct {
+	Window    kubecost.Window    `json:"window"`
+	NodePools []*NodePoolSummary `json:"nodePools"`
+}
+
+// NodePoolSummary is the capacity, cost and efficiency of a node pool.
+//
+// Utilization is the fraction of the capacity of the pool used by its
+// workloads, and request coverage is the fraction requested by them. Bin
+// packing efficiency is the fraction of the cost of the pool's CPU, RAM and
+// GPU allocated to workloads, i.e. not idle.
+type NodePoolSummary struct {
+	Cluster              string    `json:"cluster"`
+	NodePool             string    `json:"nodePool"`
+	Nodes                int       `json:"nodes"`
+	Start                time.Time `json:"start"`
+	End                  time.Time `json:"end"`
+	CPUCoreHours         float64   `json:"cpuCoreHours"`
+	CPUCoreRequestHours  float64   `json:"cpuCoreRequestHours"`
+	CPUCoreUsageHours    float64   `json:"cpuCoreUsageHours"`
+	RAMByteHours         float64   `json:"ramByteHours"`
+	RAMByteRequestHours  float64   `json:"ramByteRequestHours"`
+	RAMByteUsageHours    float64   `json:"ramByteUsageHours"`
+	CPUCost              float64   `json:"cpuCost"`
+	GPUCost              float64   `json:"gpuCost"`
+	RAMCost              float64   `json:"ramCost"`
+	TotalCost            float64   `json:"totalCost"`
+	IdleCost             float64   `json:"idleCost"`
+	CPUUtilization       float64   `json:"cpuUtilization"`
+	RAMUtilization       float64   `json:"ramUtilization"`
+	CPURequestCoverage   float64   `json:"cpuRequestCoverage"`
+	RAMRequestCoverage   float64   `json:"ramRequestCoverage"`
+	BinPackingEfficiency float64   `json:"binPackingEfficiency"`
+}
+
+// ComputeNodePools computes the cost, utilization, request coverage and bin
+// packing efficiency of each node pool over the given window.
+func (cm *CostModel) ComputeNodePools(traceCtx context.Context, window kubecost.Window, resolution time.Duration) (*NodePoolSet, error) {
+	if window.IsOpen() || window.IsNegative() {
+		return nil, fmt.Errorf("illegal window: %s", window)
+	}
+
+	start, end := *window.Start(), *window.End()
+
+	allocSet, err := cm.ComputeAllocationWithContext(traceCtx, start, end, resolution)
+	if err != nil {
+		return nil, fmt.Errorf("error computing allocations for %s: %w", window, err)
+	}
+
+	assetSet, err := cm.ComputeAssets(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("error computing assets for %s: %w", window, err)
+	}
+
+	return computeNodePools(allocSet, assetSet)
+}
+
+// computeNodePools rolls the nodes of the given AssetSet, and the allocations
+// running on them, up into their node pools. Allocations are matched to node
+// pools by node, so that node labels need not be enabled for allocations.
+func computeNodePools(allocSet *kubecost.AllocationSet, assetSet *kubecost.AssetSet) (*NodePoolSet, error) {
+	idleSet, err := computeIdleAllocations(allocSet, assetSet, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Discounted and reconciled node costs, keyed by (cluster, node)
+	assetTotals := kubecost.ComputeAssetTotals(assetSet, kubecost.AssetNodeProp)
+
+	pools := map[string]*NodePoolSummary{}
+	poolByNode := map[string]*NodePoolSummary{}
+
+	for _, node := range assetSet.Nodes {
+		nodePool := node.Properties.NodePool
+		if nodePool == "" {
+			nodePool = kubecost.UnallocatedSuffix
+		}
+
+		poolKey := fmt.Sprintf("%s/%s", node.Properties.Cluster, nodePool)
+		pool, ok := pools[poolKey]
+		if !ok {
+			pool = &NodePoolSummary{
+				Cluster:  node.Properties.Cluster,
+				NodePool: nodePool,
+				Start:    node.Start,
+				End:      node.End,
+			}
+			pools[poolKey] = pool
+		}
+
+		if pool.Start.After(node.Start) {
+			pool.Start = node.Start
+		}
+		if pool.End.Before(node.End) {
+			pool.End = node.End
+		}
+
+		nodeKey := fmt.Sprintf("%s/%s", node.Properties.Cluster, node.Properties.Name)
+		if _, ok := poolByNode[nodeKey]; !ok {
+			pool.Nodes++
+			poolByNode[nodeKey] = pool
+		}
+
+		pool.CPUCoreHours += node.CPUCoreHours
+		pool.RAMByteHours += node.RAMByteHours
+	}
+
+	for nodeKey, total := range assetTotals {
+		pool, ok := poolByNode[nodeKey]
+		if !ok {
+			continue
+		}
+
+		pool.CPUCost += total.TotalCPUCost()
+		pool.GPUCost += total.TotalGPUCost()
+		pool.RAMCost += total.TotalRAMCost()
+	}
+
+	for _, alloc := range allocSet.Allocations {
+		if alloc.IsIdle() || alloc.IsUnmounted() || alloc.Properties == nil {
+			continue
+		}
+
+		pool, ok := poolByNode[fmt.Sprintf("%s/%s", alloc.Properties.Cluster, alloc.Properties.Node)]
+		if !ok {
+			continue
+		}
+
+		hrs := alloc.Minutes() / 60.0
+		pool.CPUCoreRequestHours += alloc.CPUCoreRequestAverage * hrs
+		pool.CPUCoreUsageHours += alloc.CPUCoreUsageAverage * hrs
+		pool.RAMByteRequestHours += alloc.RAMBytesRequestAverage * hrs
+		pool.RAMByteUsageHours += alloc.RAMBytesUsageAverage * hrs
+	}
+
+	for _, idle := range idleSet.Allocations {
+		pool, ok := poolByNode[fmt.Sprintf("%s/%s", idle.Properties.Cluster, idle.Properties.Node)]
+		if !ok {
+			continue
+		}
+
+		pool.IdleCost += idle.CPUCost + idle.GPUCost + idle.RAMCost
+	}
+
+	nps := &NodePoolSet{
+		Window:    allocSet.Window.Clone(),
+		NodePools: make([]*NodePoolSummary, 0, len(pools)),
+	}
+
+	for _, pool := range pools {
+		pool.TotalCost = pool.CPUCost + pool.GPUCost + pool.RAMCost
+
+		if pool.CPUCoreHours > 0 {
+			pool.CPUUtilization = pool.CPUCoreUsageHours / pool.CPUCoreHours
+			pool.CPURequestCoverage = pool.CPUCoreRequestHours / pool.CPUCoreHours
+		}
+		if pool.RAMByteHours > 0 {
+			pool.RAMUtilization = pool.RAMByteUsageHours / pool.RAMByteHours
+			pool.RAMRequestCoverage = pool.RAMByteRequestHours / pool.RAMByteHours
+		}
+		if pool.TotalCost > 0 {
+			pool.BinPackingEfficiency = (pool.TotalCost - pool.IdleCost) / pool.TotalCost
+		}
+
+		nps.NodePools = append(nps.NodePools, pool)
+	}
+
+	sort.Slice(nps.NodePools, func(i, j int) bool {
+		if nps.NodePools[i].Cluster != nps.NodePools[j].Cluster {
+			return nps.NodePools[i].Cluster < nps.NodePools[j].Cluster
+		}
+		return nps.NodePools[i].NodePool < nps.NodePools[j].NodePool
+	})
+
+	return nps, nil
+}
@@ -0,0 +1,96 @@
+package costmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/util"
+)
+
+func TestComputeNodePools(t *testing.T) {
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * time.Hour)
+	window := kubecost.NewWindow(&start, &end)
+
+	newNode := func(name, nodePool string, cpuCost, ramCost float64) *kubecost.Node {
+		node := kubecost.NewNode(name, "cluster1", name, start, end, window)
+		node.Properties.NodePool = nodePool
+		node.CPUCoreHours = 4 * 10
+		node.RAMByteHours = 8 * 1024 * 1024 * 1024 * 10
+		node.CPUCost = cpuCost
+		node.RAMCost = ramCost
+		return node
+	}
+
+	newAlloc := func(name, node string, cpuCost, ramCost, cpuRequest, cpuUsage float64) *kubecost.Allocation {
+		return &kubecost.Allocation{
+			Name:                   name,
+			Properties:             &kubecost.AllocationProperties{Cluster: "cluster1", Node: node, Namespace: "namespace1", Pod: name, Container: name},
+			Window:                 window.Clone(),
+			Start:                  start,
+			End:                    end,
+			CPUCost:                cpuCost,
+			CPUCoreRequestAverage:  cpuRequest,
+			CPUCoreUsageAverage:    cpuUsage,
+			RAMCost:                ramCost,
+			RAMBytesRequestAverage: 2 * 1024 * 1024 * 1024,
+			RAMBytesUsageAverage:   1 * 1024 * 1024 * 1024,
+		}
+	}
+
+	assetSet := kubecost.NewAssetSet(start, end,
+		newNode("node1", "ng-1", 4, 4),
+		newNode("node2", "ng-1", 4, 4),
+		newNode("node3", "", 2, 2),
+	)
+	allocSet := kubecost.NewAllocationSet(start, end,
+		newAlloc("pod1", "node1", 3, 2, 2, 1),
+		newAlloc("pod2", "node2", 1, 2, 2, 1),
+		newAlloc("pod3", "node3", 1, 1, 1, 0.5),
+	)
+
+	nps, err := computeNodePools(allocSet, assetSet)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(nps.NodePools) != 2 {
+		t.Fatalf("expected 2 node pools; got %d", len(nps.NodePools))
+	}
+
+	// Node pools are sorted by cluster, then by node pool
+	unpooled, pool := nps.NodePools[0], nps.NodePools[1]
+
+	if unpooled.NodePool != kubecost.UnallocatedSuffix || unpooled.Nodes != 1 {
+		t.Fatalf("expected 1 node without a node pool; got %d in %s", unpooled.Nodes, unpooled.NodePool)
+	}
+
+	if pool.NodePool != "ng-1" || pool.Nodes != 2 {
+		t.Fatalf("expected 2 nodes in ng-1; got %d in %s", pool.Nodes, pool.NodePool)
+	}
+	if !util.IsApproximately(pool.TotalCost, 16) {
+		t.Fatalf("expected total cost 16; got %f", pool.TotalCost)
+	}
+	if !util.IsApproximately(pool.IdleCost, 8) {
+		t.Fatalf("expected idle cost 8; got %f", pool.IdleCost)
+	}
+	if !util.IsApproximately(pool.BinPackingEfficiency, 0.5) {
+		t.Fatalf("expected bin packing efficiency 0.5; got %f", pool.BinPackingEfficiency)
+	}
+
+	// 4 of 8 cores are requested and 2 of 8 are used
+	if !util.IsApproximately(pool.CPURequestCoverage, 0.5) {
+		t.Fatalf("expected CPU request coverage 0.5; got %f", pool.CPURequestCoverage)
+	}
+	if !util.IsApproximately(pool.CPUUtilization, 0.25) {
+		t.Fatalf("expected CPU utilization 0.25; got %f", pool.CPUUtilization)
+	}
+
+	// 4 of 16 GiB are requested and 2 of 16 GiB are used
+	if !util.IsApproximately(pool.RAMRequestCoverage, 0.25) {
+		t.Fatalf("expected RAM request coverage 0.25; got %f", pool.RAMRequestCoverage)
+	}
+	if !util.IsApproximately(pool.RAMUtilization, 0.125) {
+		t.Fatalf("expected RAM utilization 0.125; got %f", pool.RAMUtilization)
+	}
+}
@@ -88,6 +88,7 @@ type Accesses struct {
 	ClusterInfoProvider clusters.ClusterInfoProvider
 	Model               *CostModel
 	MetricsEmitter      *CostModelMetricsEmitter
+	AuditRunner         *AuditRunner
 	OutOfClusterCache   *cache.Cache
 	AggregateCache      *cache.Cache
 	CostDataCache       *cache.Cache
@@ -565,7 +566,7 @@ func (a *Accesses) GetAllNodePricing(w http.ResponseWriter, r *http.Request, ps
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	data, err := a.CloudProvider.AllNodePricing()
+	data, err := cloud.ProviderWithContext(r.Context(), a.CloudProvider).AllNodePricing()
 	w.Write(WrapData(data, err))
 }
 
@@ -919,6 +920,15 @@ func (a *Accesses) GetPrometheusMetrics(w http.ResponseWriter, _ *http.Request,
 	w.Write(WrapData(result, nil))
 }
 
+// GetAllocationQueries returns the effective allocation queries, noting which
+// are overridden and the results of validating them.
+func (a *Accesses) GetAllocationQueries(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	w.Write(WrapData(AllocationQueryDiagnostics(), nil))
+}
+
 func (a *Accesses) GetAllPersistentVolumes(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -1573,13 +1583,31 @@ func Initialize(additionalConfigWatchers ...*watcher.ConfigMapWatcher) *Accesses
 
 	configPrefix := env.GetConfigPathWithDefault("/var/configs/")
 
+	// Load user-defined allocation properties, e.g. business dimensions
+	// derived from labels and annotations
+	WatchAllocationPropertiesConfig(confManager.ConfigFileAt(path.Join(configPrefix, "allocation-properties.json")))
+
+	// Load sharing policies, which split shared costs by custom weights
+	WatchSharingPoliciesConfig(confManager.ConfigFileAt(path.Join(configPrefix, "sharing-policies.json")))
+
+	// Load idle owners, which are charged for the idle of the nodes they own
+	WatchIdleOwnershipConfig(confManager.ConfigFileAt(path.Join(configPrefix, "idle-ownership.json")))
+
+	// Load overrides of the default allocation queries, e.g. for relabelled
+	// metrics, and validate them against Prometheus
+	WatchAllocationQueriesConfig(confManager.ConfigFileAt(path.Join(configPrefix, "allocation-queries.json")), promCli)
+
 	// Create Kubernetes Cluster Cache + Watchers
 	var k8sCache clustercache.ClusterCache
 	if env.IsClusterCacheFileEnabled() {
 		importLocation := confManager.ConfigFileAt(path.Join(configPrefix, "cluster-cache.json"))
 		k8sCache = clustercache.NewClusterImporter(importLocation)
 	} else {
-		k8sCache = clustercache.NewKubernetesClusterCache(kubeClientset)
+		dynamicClient, err := kubeconfig.LoadDynamicClient("")
+		if err != nil {
+			log.Warnf("Failed to build Kubernetes dynamic client, custom resources will not be watched: %s", err)
+		}
+		k8sCache = clustercache.NewKubernetesClusterCacheWithDynamic(kubeClientset, dynamicClient)
 	}
 	k8sCache.Run()
 
@@ -1588,6 +1616,9 @@ func Initialize(additionalConfigWatchers ...*watcher.ConfigMapWatcher) *Accesses
 	if err != nil {
 		panic(err.Error())
 	}
+	if env.IsTracingEnabled() {
+		cloudProvider = cloud.NewTracedProvider(cloudProvider)
+	}
 
 	// Append the pricing config watcher
 	configWatchers.AddWatcher(cloud.ConfigWatcherFor(cloudProvider))
@@ -1702,6 +1733,39 @@ func Initialize(additionalConfigWatchers ...*watcher.ConfigMapWatcher) *Accesses
 		pc = promCli
 	}
 	costModel := NewCostModel(pc, cloudProvider, k8sCache, clusterMap, scrapeInterval)
+
+	// Persist periodic snapshots of the cluster cache so that metadata for
+	// deleted objects is available to historical queries
+	if env.IsClusterSnapshotsEnabled() {
+		if store := confManager.Storage(); store != nil {
+			snapshotDir := path.Join(configPrefix, env.GetClusterSnapshotDirectory())
+			costModel.ClusterSnapshots = clustercache.NewClusterSnapshotStore(k8sCache, store, snapshotDir, env.GetClusterSnapshotInterval(), env.GetClusterSnapshotRetention())
+			costModel.ClusterSnapshots.Run()
+		} else {
+			log.Warnf("Cluster snapshots are enabled, but no config storage is available")
+		}
+	}
+
+	// Persist the allocation and asset totals used for idle and reconciliation
+	// so that they survive restarts
+	if store := confManager.Storage(); store != nil {
+		totalsDir := path.Join(configPrefix, env.GetTotalsStoreDirectory())
+		totalsStore := kubecost.NewStorageTotalsStore(store, totalsDir, env.GetTotalsStoreCompactionInterval(), env.GetTotalsStoreHourlyRetention(), env.GetTotalsStoreRetention())
+		totalsStore.Run()
+		costModel.TotalsStore = totalsStore
+	} else {
+		log.Warnf("No config storage is available, allocation and asset totals will not be recorded")
+	}
+
+	// Attribute network costs from flow logs rather than the network costs
+	// daemon
+	if env.IsNetworkFlowLogsEnabled() {
+		costModel.NetworkFlows = newNetworkFlowSourceFromEnv(confManager.Storage(), configPrefix)
+		if costModel.NetworkFlows == nil {
+			log.Warnf("Network flow logs are enabled, but no flow log storage is available")
+		}
+	}
+
 	metricsEmitter := NewCostModelMetricsEmitter(promCli, k8sCache, cloudProvider, clusterInfoProvider, costModel)
 
 	a := &Accesses{
@@ -1749,11 +1813,23 @@ func Initialize(additionalConfigWatchers ...*watcher.ConfigMapWatcher) *Accesses
 		a.MetricsEmitter.Start()
 	}
 
+	if env.IsAuditEnabled() {
+		log.Infof("Init: audits enabled")
+		a.AuditRunner = NewAuditRunner(a.Model, env.GetAuditInterval(), env.GetAuditWindow(), env.GetAuditTolerance())
+		a.AuditRunner.Start()
+	}
+
 	a.Router.GET("/costDataModel", a.CostDataModel)
 	a.Router.GET("/costDataModelRange", a.CostDataModelRange)
 	a.Router.GET("/aggregatedCostModel", a.AggregateCostModelHandler)
 	a.Router.GET("/allocation/compute", a.ComputeAllocationHandler)
 	a.Router.GET("/allocation/compute/summary", a.ComputeAllocationHandlerSummary)
+	a.Router.GET("/allocation/explain", a.ExplainAllocationHandler)
+	a.Router.GET("/network/costMatrix", a.NetworkCostMatrixHandler)
+	a.Router.GET("/nodePools", a.NodePoolsHandler)
+	a.Router.POST("/autoscaling/simulate", a.AutoscalingSimulationHandler)
+	a.Router.GET("/audit", a.AuditHandler)
+	a.Router.GET("/assets", a.ComputeAssetsHandler)
 	a.Router.GET("/allNodePricing", a.GetAllNodePricing)
 	a.Router.POST("/refreshPricing", a.RefreshPricingData)
 	a.Router.GET("/clusterCostsOverTime", a.ClusterCostsOverTime)
@@ -1798,6 +1874,7 @@ func Initialize(additionalConfigWatchers ...*watcher.ConfigMapWatcher) *Accesses
 	// diagnostics
 	a.Router.GET("/diagnostics/requestQueue", a.GetPrometheusQueueState)
 	a.Router.GET("/diagnostics/prometheusMetrics", a.GetPrometheusMetrics)
+	a.Router.GET("/diagnostics/allocationQueries", a.GetAllocationQueries)
 
 	a.Router.GET("/logs/level", a.GetLogLevel)
 	a.Router.POST("/logs/level", a.SetLogLevel)
@@ -0,0 +1,239 @@
+package costmodel
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/opencost/opencost/pkg/config"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/prom"
+	filterv2 "github.com/opencost/opencost/pkg/util/allocationfilterutil/v2"
+	"github.com/opencost/opencost/pkg/util/json"
+	"github.com/opencost/opencost/pkg/util/timeutil"
+
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+// sharingPolicyWindowPlaceholder is replaced, in the query of a sharing
+// policy, by the duration of the window for which it is evaluated.
+const sharingPolicyWindowPlaceholder = "__window__"
+
+// SharingPolicyConfig is the configuration of a kubecost.SharingPolicy, with
+// filters in the v2 filter language. For the "query" weight, Query must return
+// a weight per namespace, e.g.
+//
+//	sum(increase(nginx_ingress_controller_requests[__window__])) by (namespace)
+//
+// It is evaluated at the end of each window, with "__window__" replaced by the
+// duration of the window.
+type SharingPolicyConfig struct {
+	Name       string                       `json:"name"`
+	Shared     string                       `json:"shared"`
+	Recipients string                       `json:"recipients,omitempty"`
+	Weight     kubecost.SharingWeightSource `json:"weight"`
+	Query      string                       `json:"query,omitempty"`
+}
+
+// SharingPolicy compiles the configuration into a kubecost.SharingPolicy,
+// which queries the given client for weights, if required.
+func (spc *SharingPolicyConfig) SharingPolicy(client prometheus.Client) (*kubecost.SharingPolicy, error) {
+	if spc.Shared == "" {
+		return nil, fmt.Errorf("sharing policy %s has no shared filter", spc.Name)
+	}
+
+	shared, err := filterv2.ParseAllocationFilter(spc.Shared)
+	if err != nil {
+		return nil, fmt.Errorf("sharing policy %s: invalid shared filter: %w", spc.Name, err)
+	}
+
+	var recipients kubecost.AllocationFilter
+	if spc.Recipients != "" {
+		recipients, err = filterv2.ParseAllocationFilter(spc.Recipients)
+		if err != nil {
+			return nil, fmt.Errorf("sharing policy %s: invalid recipients filter: %w", spc.Name, err)
+		}
+	}
+
+	policy := &kubecost.SharingPolicy{
+		Name:       spc.Name,
+		Shared:     shared,
+		Recipients: recipients,
+		Weight:     spc.Weight,
+	}
+
+	if spc.Weight == kubecost.SharingWeightQuery {
+		if spc.Query == "" {
+			return nil, fmt.Errorf("sharing policy %s has no query", spc.Name)
+		}
+		policy.NamespaceWeights = sharingPolicyQueryWeights(client, spc.Query)
+	}
+
+	err = policy.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func sharingPolicyQueryWeights(client prometheus.Client, query string) kubecost.SharingWeightFunc {
+	return func(window kubecost.Window) (map[string]float64, error) {
+		if window.IsOpen() {
+			return nil, fmt.Errorf("illegal window: %s", window)
+		}
+
+		durStr := timeutil.DurationString(window.Duration())
+		if durStr == "" {
+			return nil, fmt.Errorf("illegal duration value for %s", window)
+		}
+
+		ctx := prom.NewNamedContext(client, prom.AllocationContextName)
+		res, err := ctx.QueryAtTime(strings.ReplaceAll(query, sharingPolicyWindowPlaceholder, durStr), *window.End()).Await()
+		if err != nil {
+			return nil, fmt.Errorf("querying weights: %w", err)
+		}
+
+		weights := map[string]float64{}
+		for _, r := range res {
+			namespace, err := r.GetString("namespace")
+			if err != nil {
+				log.DedupedWarningf(5, "Sharing policy weight query result missing namespace: %s", query)
+				continue
+			}
+
+			if len(r.Values) == 0 {
+				continue
+			}
+
+			weights[namespace] += r.Values[0].Value
+		}
+
+		return weights, nil
+	}
+}
+
+// sharingPolicyConfigs holds the configured sharing policies, keyed by name.
+var sharingPolicyConfigs = struct {
+	lock   sync.RWMutex
+	byName map[string]*SharingPolicyConfig
+}{
+	byName: map[string]*SharingPolicyConfig{},
+}
+
+// ParseSharingPolicyConfigs parses a JSON list of sharing policies, validating
+// each.
+func ParseSharingPolicyConfigs(data []byte) ([]*SharingPolicyConfig, error) {
+	configs := []*SharingPolicyConfig{}
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("parsing sharing policies: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, spc := range configs {
+		if spc == nil {
+			return nil, fmt.Errorf("nil sharing policy")
+		}
+
+		if names[spc.Name] {
+			return nil, fmt.Errorf("duplicate sharing policy: %s", spc.Name)
+		}
+		names[spc.Name] = true
+
+		if _, err := spc.SharingPolicy(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return configs, nil
+}
+
+// SetSharingPolicyConfigs replaces the configured sharing policies.
+func SetSharingPolicyConfigs(configs []*SharingPolicyConfig) {
+	byName := make(map[string]*SharingPolicyConfig, len(configs))
+	for _, spc := range configs {
+		byName[spc.Name] = spc
+	}
+
+	sharingPolicyConfigs.lock.Lock()
+	defer sharingPolicyConfigs.lock.Unlock()
+
+	sharingPolicyConfigs.byName = byName
+}
+
+// SharingPolicies returns the configured sharing policies with the given
+// names, in order, or an error if any is not configured.
+func (cm *CostModel) SharingPolicies(names []string) ([]*kubecost.SharingPolicy, error) {
+	sharingPolicyConfigs.lock.RLock()
+	defer sharingPolicyConfigs.lock.RUnlock()
+
+	policies := make([]*kubecost.SharingPolicy, 0, len(names))
+	for _, name := range names {
+		spc, ok := sharingPolicyConfigs.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("bad request - unknown sharing policy: %s", name)
+		}
+
+		policy, err := spc.SharingPolicy(cm.PrometheusClient)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+// WatchSharingPoliciesConfig loads the sharing policies from the given config
+// file, if it exists, and reloads them whenever the file changes. The file
+// contains a JSON list of policies:
+//
+//	[{
+//	  "name": "ingress",
+//	  "shared": "namespace:\"ingress-nginx\"",
+//	  "recipients": "namespace!:\"kube-system\"",
+//	  "weight": "query",
+//	  "query": "sum(increase(nginx_ingress_controller_requests[__window__])) by (namespace)"
+//	}]
+//
+// Policies are applied by name with the "sharingPolicies" parameter of
+// /allocation/compute.
+func WatchSharingPoliciesConfig(file *config.ConfigFile) {
+	exists, err := file.Exists()
+	if err != nil {
+		log.Errorf("Failed to check for sharing policies config %s: %s", file.Path(), err)
+		return
+	}
+
+	if exists {
+		data, err := file.Read()
+		if err != nil {
+			log.Warnf("Failed to read sharing policies config %s: %s", file.Path(), err)
+		} else {
+			updateSharingPolicies(data)
+		}
+	}
+
+	file.AddChangeHandler(func(changeType config.ChangeType, data []byte) {
+		if changeType == config.ChangeTypeDeleted {
+			log.Infof("Sharing policies config deleted, removing sharing policies")
+			SetSharingPolicyConfigs(nil)
+			return
+		}
+
+		updateSharingPolicies(data)
+	})
+}
+
+func updateSharingPolicies(data []byte) {
+	configs, err := ParseSharingPolicyConfigs(data)
+	if err != nil {
+		log.Errorf("Invalid sharing policies config, keeping existing sharing policies: %s", err)
+		return
+	}
+
+	SetSharingPolicyConfigs(configs)
+
+	log.Infof("Loaded %d sharing policies", len(configs))
+}
@@ -0,0 +1,85 @@
+package costmodel
+
+import (
+	"testing"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+)
+
+func TestParseSharingPolicyConfigs(t *testing.T) {
+	cases := map[string]struct {
+		input       string
+		expectError bool
+	}{
+		"valid": {
+			input: `[
+				{"name": "ingress", "shared": "namespace:\"ingress-nginx\"", "recipients": "namespace!:\"kube-system\"", "weight": "query", "query": "sum(increase(nginx_ingress_controller_requests[__window__])) by (namespace)"},
+				{"name": "monitoring", "shared": "namespace:\"monitoring\"", "weight": "cpu"}
+			]`,
+		},
+		"duplicate": {
+			input:       `[{"name": "ingress", "shared": "namespace:\"ingress-nginx\"", "weight": "cost"}, {"name": "ingress", "shared": "namespace:\"monitoring\"", "weight": "cost"}]`,
+			expectError: true,
+		},
+		"invalid shared filter": {
+			input:       `[{"name": "ingress", "shared": "namespace:", "weight": "cost"}]`,
+			expectError: true,
+		},
+		"missing shared filter": {
+			input:       `[{"name": "ingress", "weight": "cost"}]`,
+			expectError: true,
+		},
+		"query without query": {
+			input:       `[{"name": "ingress", "shared": "namespace:\"ingress-nginx\"", "weight": "query"}]`,
+			expectError: true,
+		},
+		"invalid weight": {
+			input:       `[{"name": "ingress", "shared": "namespace:\"ingress-nginx\"", "weight": "requests"}]`,
+			expectError: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseSharingPolicyConfigs([]byte(c.input))
+			if c.expectError && err == nil {
+				t.Fatalf("expected error; got nil")
+			}
+			if !c.expectError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestCostModel_SharingPolicies(t *testing.T) {
+	configs, err := ParseSharingPolicyConfigs([]byte(`[{"name": "ingress", "shared": "namespace:\"ingress-nginx\"", "recipients": "namespace!:\"kube-system\"", "weight": "network"}]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	SetSharingPolicyConfigs(configs)
+	t.Cleanup(func() {
+		SetSharingPolicyConfigs(nil)
+	})
+
+	cm := &CostModel{}
+
+	policies, err := cm.SharingPolicies([]string{"ingress"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(policies) != 1 || policies[0].Weight != kubecost.SharingWeightNetwork {
+		t.Fatalf("expected ingress policy; got %v", policies)
+	}
+
+	ingress := &kubecost.Allocation{Properties: &kubecost.AllocationProperties{Namespace: "ingress-nginx"}}
+	system := &kubecost.Allocation{Properties: &kubecost.AllocationProperties{Namespace: "kube-system"}}
+	if !policies[0].Shared.Matches(ingress) || policies[0].Recipients.Matches(system) {
+		t.Fatalf("expected filters to be compiled")
+	}
+
+	_, err = cm.SharingPolicies([]string{"ingress", "monitoring"})
+	if err == nil {
+		t.Fatalf("expected error for unknown sharing policy")
+	}
+}
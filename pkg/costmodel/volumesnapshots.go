@@ -0,0 +1,363 @@
+package costmodel
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/opencost/opencost/pkg/clustercache"
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/prom"
+	"github.com/opencost/opencost/pkg/util/timeutil"
+
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+// volumeSnapshotInfo describes a volume snapshot in the cluster, as it is
+// emitted by the kubecost_volumesnapshot_size_bytes metric.
+type volumeSnapshotInfo struct {
+	Namespace  string
+	Name       string
+	Claim      string
+	Content    string
+	ProviderID string
+	Bytes      float64
+}
+
+// labels returns the label values of the volume snapshot's size metric
+func (vsi *volumeSnapshotInfo) labels() []string {
+	return []string{vsi.Namespace, vsi.Name, vsi.Claim, vsi.Content, vsi.ProviderID}
+}
+
+// volumeSnapshotInfos joins the given VolumeSnapshots with the
+// VolumeSnapshotContents bound to them. Contents without a VolumeSnapshot,
+// such as those retained after their VolumeSnapshot was deleted, are still
+// stored, and billed, by the provider, so they are included under the
+// namespace and name of the VolumeSnapshot they reference. Snapshots whose
+// size is not known yet are omitted.
+//
+// The size of a snapshot is its restore size, i.e. the size of the volume it
+// was taken of, which is an upper bound of the storage billed for it by
+// providers that store snapshots incrementally.
+func volumeSnapshotInfos(snapshots []*clustercache.VolumeSnapshot, contents []*clustercache.VolumeSnapshotContent) []*volumeSnapshotInfo {
+	contentsByName := make(map[string]*clustercache.VolumeSnapshotContent, len(contents))
+	for _, content := range contents {
+		contentsByName[content.Name] = content
+	}
+
+	var infos []*volumeSnapshotInfo
+	bound := map[string]bool{}
+
+	for _, snapshot := range snapshots {
+		info := &volumeSnapshotInfo{
+			Namespace: snapshot.Namespace,
+			Name:      snapshot.Name,
+		}
+		if snapshot.Spec.Source.PersistentVolumeClaimName != nil {
+			info.Claim = *snapshot.Spec.Source.PersistentVolumeClaimName
+		}
+
+		if snapshot.Status != nil && snapshot.Status.BoundVolumeSnapshotContentName != nil {
+			info.Content = *snapshot.Status.BoundVolumeSnapshotContentName
+		} else if snapshot.Spec.Source.VolumeSnapshotContentName != nil {
+			info.Content = *snapshot.Spec.Source.VolumeSnapshotContentName
+		}
+
+		if snapshot.Status != nil && snapshot.Status.RestoreSize != nil {
+			info.Bytes = float64(snapshot.Status.RestoreSize.Value())
+		}
+
+		if content, ok := contentsByName[info.Content]; ok {
+			bound[content.Name] = true
+			applyVolumeSnapshotContent(info, content)
+		}
+
+		if info.Bytes > 0 {
+			infos = append(infos, info)
+		}
+	}
+
+	for _, content := range contents {
+		if bound[content.Name] {
+			continue
+		}
+
+		info := &volumeSnapshotInfo{
+			Namespace: content.Spec.VolumeSnapshotRef.Namespace,
+			Name:      content.Spec.VolumeSnapshotRef.Name,
+			Content:   content.Name,
+		}
+		applyVolumeSnapshotContent(info, content)
+
+		if info.Namespace != "" && info.Name != "" && info.Bytes > 0 {
+			infos = append(infos, info)
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Namespace != infos[j].Namespace {
+			return infos[i].Namespace < infos[j].Namespace
+		}
+		if infos[i].Name != infos[j].Name {
+			return infos[i].Name < infos[j].Name
+		}
+		return infos[i].Content < infos[j].Content
+	})
+
+	return infos
+}
+
+// applyVolumeSnapshotContent sets the provider ID and, if it is known, the
+// size of the snapshot from its VolumeSnapshotContent, which describes the
+// snapshot in the storage backend.
+func applyVolumeSnapshotContent(info *volumeSnapshotInfo, content *clustercache.VolumeSnapshotContent) {
+	if content.Spec.Source.SnapshotHandle != nil {
+		info.ProviderID = *content.Spec.Source.SnapshotHandle
+	}
+
+	if content.Status == nil {
+		return
+	}
+	if content.Status.SnapshotHandle != nil {
+		info.ProviderID = *content.Status.SnapshotHandle
+	}
+	if content.Status.RestoreSize != nil && *content.Status.RestoreSize > 0 {
+		info.Bytes = float64(*content.Status.RestoreSize)
+	}
+}
+
+// VolumeSnapshotIdentifier identifies a volume snapshot by its cluster,
+// namespace and the name of its VolumeSnapshot.
+type VolumeSnapshotIdentifier struct {
+	Cluster   string
+	Namespace string
+	Name      string
+}
+
+func (k VolumeSnapshotIdentifier) String() string {
+	return fmt.Sprintf("%s/%s/%s", k.Cluster, k.Namespace, k.Name)
+}
+
+// resultVolumeSnapshotKey converts a Prometheus query result to a
+// VolumeSnapshotIdentifier, defaulting the cluster to the configured cluster ID.
+func resultVolumeSnapshotKey(res *prom.QueryResult) (VolumeSnapshotIdentifier, error) {
+	key := VolumeSnapshotIdentifier{}
+
+	cluster, err := res.GetString(env.GetPromClusterLabel())
+	if err != nil {
+		cluster = env.GetClusterID()
+	}
+	key.Cluster = cluster
+
+	namespace, err := res.GetString("namespace")
+	if err != nil {
+		return key, err
+	}
+	key.Namespace = namespace
+
+	name, err := res.GetString("volumesnapshot")
+	if err != nil {
+		return key, err
+	}
+	key.Name = name
+
+	return key, nil
+}
+
+// VolumeSnapshot describes the storage of a volume snapshot over a window
+type VolumeSnapshot struct {
+	Cluster        string
+	Namespace      string
+	Name           string
+	Claim          string
+	ProviderID     string
+	Bytes          float64
+	ByteHours      float64
+	CostPerGiBHour float64
+	Cost           float64
+	Start          time.Time
+	End            time.Time
+}
+
+// buildVolumeSnapshotMap builds the map of volume snapshots from the results
+// of the queries for the minutes each existed, their size and the cost of
+// snapshot storage per GiB-hour in each cluster.
+func buildVolumeSnapshotMap(resolution time.Duration, resActiveMins, resBytes, resCostPerGiBHr []*prom.QueryResult) map[VolumeSnapshotIdentifier]*VolumeSnapshot {
+	snapshotMap := map[VolumeSnapshotIdentifier]*VolumeSnapshot{}
+
+	for _, res := range resActiveMins {
+		key, err := resultVolumeSnapshotKey(res)
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel: volume snapshot minutes result missing field: %s", err)
+			continue
+		}
+		if len(res.Values) == 0 {
+			continue
+		}
+
+		// The source claim is unknown for pre-provisioned snapshots
+		claim, _ := res.GetString("persistentvolumeclaim")
+
+		s, e := calculateStartAndEnd(res, resolution)
+		snapshotMap[key] = &VolumeSnapshot{
+			Cluster:   key.Cluster,
+			Namespace: key.Namespace,
+			Name:      key.Name,
+			Claim:     claim,
+			Start:     s,
+			End:       e,
+		}
+	}
+
+	for _, res := range resBytes {
+		key, err := resultVolumeSnapshotKey(res)
+		if err != nil {
+			log.DedupedWarningf(10, "CostModel: volume snapshot bytes result missing field: %s", err)
+			continue
+		}
+		if len(res.Values) == 0 {
+			continue
+		}
+
+		snapshot, ok := snapshotMap[key]
+		if !ok {
+			log.DedupedWarningf(10, "CostModel: found bytes for volume snapshot without minutes: %s", key)
+			continue
+		}
+
+		snapshot.Bytes = res.Values[0].Value
+		if providerID, err := res.GetString("provider_id"); err == nil {
+			snapshot.ProviderID = providerID
+		}
+	}
+
+	costPerGiBHrByCluster := map[string]float64{}
+	for _, res := range resCostPerGiBHr {
+		cluster, err := res.GetString(env.GetPromClusterLabel())
+		if err != nil {
+			cluster = env.GetClusterID()
+		}
+		if len(res.Values) > 0 {
+			costPerGiBHrByCluster[cluster] = res.Values[0].Value
+		}
+	}
+
+	for _, snapshot := range snapshotMap {
+		costPerGiBHr, ok := costPerGiBHrByCluster[snapshot.Cluster]
+		if !ok {
+			log.DedupedWarningf(5, "CostModel: missing volume snapshot storage cost for cluster %s", snapshot.Cluster)
+		}
+		snapshot.CostPerGiBHour = costPerGiBHr
+
+		hours := snapshot.End.Sub(snapshot.Start).Hours()
+		if hours < 0 {
+			hours = 0
+		}
+		snapshot.ByteHours = snapshot.Bytes * hours
+		snapshot.Cost = snapshot.Bytes / 1024 / 1024 / 1024 * hours * costPerGiBHr
+	}
+
+	return snapshotMap
+}
+
+// ClusterVolumeSnapshots returns the volume snapshots which existed between
+// start and end, with their size and cumulative cost.
+func ClusterVolumeSnapshots(client prometheus.Client, start, end time.Time) (map[VolumeSnapshotIdentifier]*VolumeSnapshot, error) {
+	// Query for the duration between start and end
+	durStr := timeutil.DurationString(end.Sub(start))
+	if durStr == "" {
+		return nil, fmt.Errorf("illegal duration value for %s", kubecost.NewClosedWindow(start, end))
+	}
+
+	// Start from the time "end", querying backwards
+	t := end
+
+	resolution := env.GetETLResolution()
+	//Ensuring if ETL_RESOLUTION_SECONDS is less than 60s default it to 1m
+	var minsPerResolution int
+	if minsPerResolution = int(resolution.Minutes()); int(resolution.Minutes()) == 0 {
+		minsPerResolution = 1
+		log.DedupedWarningf(3, "ClusterVolumeSnapshots(): Configured ETL resolution (%d seconds) is below the 60 seconds threshold. Overriding with 1 minute.", int(resolution.Seconds()))
+	}
+
+	ctx := prom.NewNamedContext(client, prom.ClusterContextName)
+
+	queryActiveMins := fmt.Sprintf(`count(kubecost_volumesnapshot_size_bytes) by (namespace, volumesnapshot, persistentvolumeclaim, %s)[%s:%dm]`, env.GetPromClusterLabel(), durStr, minsPerResolution)
+	queryBytes := fmt.Sprintf(`avg(avg_over_time(kubecost_volumesnapshot_size_bytes[%s])) by (namespace, volumesnapshot, provider_id, %s)`, durStr, env.GetPromClusterLabel())
+	queryCostPerGiBHr := fmt.Sprintf(`avg(avg_over_time(kubecost_volumesnapshot_storage_cost[%s])) by (%s)`, durStr, env.GetPromClusterLabel())
+
+	resChActiveMins := ctx.QueryAtTime(queryActiveMins, t)
+	resChBytes := ctx.QueryAtTime(queryBytes, t)
+	resChCostPerGiBHr := ctx.QueryAtTime(queryCostPerGiBHr, t)
+
+	resActiveMins, _ := resChActiveMins.Await()
+	resBytes, _ := resChBytes.Await()
+	resCostPerGiBHr, _ := resChCostPerGiBHr.Await()
+
+	if ctx.HasErrors() {
+		return nil, ctx.ErrorCollection()
+	}
+
+	return buildVolumeSnapshotMap(time.Duration(minsPerResolution)*time.Minute, resActiveMins, resBytes, resCostPerGiBHr), nil
+}
+
+// applyVolumeSnapshotsToPods attributes the cost of each volume snapshot to
+// the Allocations of the pods which mounted the snapshot's source PVC while
+// the snapshot existed, proportional to the hours of overlap. Snapshots which
+// outlived their source PVC's pods, or whose source is unknown, are
+// attributed to the unmounted Allocation of the snapshot's namespace, which
+// is always the namespace of the source PVC.
+func applyVolumeSnapshotsToPods(window kubecost.Window, podMap map[podKey]*pod, snapshotMap map[VolumeSnapshotIdentifier]*VolumeSnapshot, podPVCMap map[podKey][]*pvc) {
+	podsByPVC := map[pvcKey]map[podKey]*pod{}
+	for thisPodKey, pvcs := range podPVCMap {
+		thisPod, ok := podMap[thisPodKey]
+		if !ok {
+			continue
+		}
+		for _, thisPVC := range pvcs {
+			if podsByPVC[thisPVC.key()] == nil {
+				podsByPVC[thisPVC.key()] = map[podKey]*pod{}
+			}
+			podsByPVC[thisPVC.key()][thisPodKey] = thisPod
+		}
+	}
+
+	for _, snapshot := range snapshotMap {
+		totalHours := 0.0
+		allocHours := make(map[*kubecost.Allocation]float64)
+
+		if snapshot.Claim != "" {
+			for _, thisPod := range podsByPVC[newPVCKey(snapshot.Cluster, snapshot.Namespace, snapshot.Claim)] {
+				for _, alloc := range thisPod.Allocations {
+					s, e := alloc.Start, alloc.End
+					if snapshot.Start.After(s) {
+						s = snapshot.Start
+					}
+					if snapshot.End.Before(e) {
+						e = snapshot.End
+					}
+					hours := e.Sub(s).Hours()
+					// A negative number of hours signifies no overlap between the windows
+					if hours > 0 {
+						totalHours += hours
+						allocHours[alloc] = hours
+					}
+				}
+			}
+		}
+
+		for alloc, hours := range allocHours {
+			alloc.SnapshotCost += snapshot.Cost * hours / totalHours
+			alloc.SnapshotByteHours += snapshot.ByteHours * hours / totalHours
+		}
+
+		if len(allocHours) == 0 {
+			thisPod := getUnmountedPodForNamespace(window, podMap, snapshot.Cluster, snapshot.Namespace)
+			alloc := thisPod.Allocations[kubecost.UnmountedSuffix]
+			alloc.SnapshotCost += snapshot.Cost
+			alloc.SnapshotByteHours += snapshot.ByteHours
+		}
+	}
+}
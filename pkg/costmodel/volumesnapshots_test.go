@@ -0,0 +1,165 @@
+package costmodel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/clustercache"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/util"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Responses recorded from a Prometheus scraping the metrics of volume
+// snapshots, at a resolution of one hour: two snapshots of the same PVC, one
+// taken while its pods were running and one after, and one snapshot whose
+// source PVC is unknown.
+const (
+	recordedVolumeSnapshotActiveMins = `{"status":"success","data":{"resultType":"matrix","result":[
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace1","persistentvolumeclaim":"data","volumesnapshot":"snap1"},"values":[[1672552800,"1"],[1672603200,"1"]]},
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace1","persistentvolumeclaim":"data","volumesnapshot":"snap2"},"values":[[1672578000,"1"],[1672603200,"1"]]},
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace2","volumesnapshot":"snap3"},"values":[[1672552800,"1"],[1672603200,"1"]]}
+	]}}`
+
+	recordedVolumeSnapshotBytes = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace1","provider_id":"snap-0a1","volumesnapshot":"snap1"},"value":[1672617600,"10737418240"]},
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace1","provider_id":"snap-0a2","volumesnapshot":"snap2"},"value":[1672617600,"10737418240"]},
+		{"metric":{"cluster_id":"cluster1","namespace":"namespace2","provider_id":"snap-0a3","volumesnapshot":"snap3"},"value":[1672617600,"5368709120"]}
+	]}}`
+
+	recordedVolumeSnapshotCostPerGiBHr = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"cluster_id":"cluster1"},"value":[1672617600,"0.001"]}
+	]}}`
+)
+
+func TestVolumeSnapshotInfos(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	int64Ptr := func(i int64) *int64 { return &i }
+	size := resource.MustParse("8Gi")
+
+	snapshots := []*clustercache.VolumeSnapshot{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "snap1"},
+			Spec: clustercache.VolumeSnapshotSpec{
+				Source: clustercache.VolumeSnapshotSource{PersistentVolumeClaimName: strPtr("data")},
+			},
+			Status: &clustercache.VolumeSnapshotStatus{
+				BoundVolumeSnapshotContentName: strPtr("snapcontent-1"),
+				RestoreSize:                    &size,
+			},
+		},
+		// Not yet bound and without a size, so it is omitted
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "snap2"},
+			Spec: clustercache.VolumeSnapshotSpec{
+				Source: clustercache.VolumeSnapshotSource{PersistentVolumeClaimName: strPtr("data")},
+			},
+		},
+	}
+
+	contents := []*clustercache.VolumeSnapshotContent{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "snapcontent-1"},
+			Spec: clustercache.VolumeSnapshotContentSpec{
+				VolumeSnapshotRef: v1.ObjectReference{Namespace: "namespace1", Name: "snap1"},
+				DeletionPolicy:    "Delete",
+			},
+			Status: &clustercache.VolumeSnapshotContentStatus{
+				SnapshotHandle: strPtr("snap-0a1"),
+				RestoreSize:    int64Ptr(10 * 1024 * 1024 * 1024),
+			},
+		},
+		// Retained after its VolumeSnapshot was deleted
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "snapcontent-0"},
+			Spec: clustercache.VolumeSnapshotContentSpec{
+				VolumeSnapshotRef: v1.ObjectReference{Namespace: "namespace0", Name: "snap0"},
+				DeletionPolicy:    "Retain",
+			},
+			Status: &clustercache.VolumeSnapshotContentStatus{
+				SnapshotHandle: strPtr("snap-0a0"),
+				RestoreSize:    int64Ptr(1024),
+			},
+		},
+	}
+
+	infos := volumeSnapshotInfos(snapshots, contents)
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 snapshots; got %d", len(infos))
+	}
+
+	expected := []volumeSnapshotInfo{
+		{Namespace: "namespace0", Name: "snap0", Content: "snapcontent-0", ProviderID: "snap-0a0", Bytes: 1024},
+		{Namespace: "namespace1", Name: "snap1", Claim: "data", Content: "snapcontent-1", ProviderID: "snap-0a1", Bytes: 10 * 1024 * 1024 * 1024},
+	}
+	for i, info := range infos {
+		if *info != expected[i] {
+			t.Fatalf("expected snapshot %+v; got %+v", expected[i], *info)
+		}
+	}
+}
+
+func TestApplyVolumeSnapshotsToPods(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	window := kubecost.NewClosedWindow(start, start.Add(24*time.Hour))
+
+	podMap := newGPUTestPodMap(start, 10, "pod1", "pod2")
+	podPVCMap := map[podKey][]*pvc{}
+	for key, thisPod := range podMap {
+		thisPod.appendContainer("container1")
+		podPVCMap[key] = []*pvc{{Cluster: "cluster1", Namespace: "namespace1", Name: "data"}}
+	}
+
+	snapshotMap := buildVolumeSnapshotMap(time.Hour,
+		recordedQueryResults(t, recordedVolumeSnapshotActiveMins),
+		recordedQueryResults(t, recordedVolumeSnapshotBytes),
+		recordedQueryResults(t, recordedVolumeSnapshotCostPerGiBHr))
+
+	// 10 GiB for 15 hours at $0.001 per GiB-hour
+	snap1 := snapshotMap[VolumeSnapshotIdentifier{Cluster: "cluster1", Namespace: "namespace1", Name: "snap1"}]
+	if snap1 == nil {
+		t.Fatalf("expected snapshot snap1")
+	}
+	if snap1.Claim != "data" || snap1.ProviderID != "snap-0a1" {
+		t.Fatalf("expected snapshot of claim data with provider ID snap-0a1; got %s, %s", snap1.Claim, snap1.ProviderID)
+	}
+	if !util.IsApproximately(snap1.Cost, 0.15) {
+		t.Fatalf("expected cost of 0.15; got %f", snap1.Cost)
+	}
+
+	applyVolumeSnapshotsToPods(window, podMap, snapshotMap, podPVCMap)
+
+	gib := 1024.0 * 1024.0 * 1024.0
+
+	// The snapshot taken while the pods were running is split between them
+	for _, name := range []string{"pod1", "pod2"} {
+		alloc := podMap[newPodKey("cluster1", "namespace1", name)].Allocations["container1"]
+		if !util.IsApproximately(alloc.SnapshotCost, 0.075) {
+			t.Fatalf("%s: expected snapshot cost of 0.075; got %f", name, alloc.SnapshotCost)
+		}
+		if !util.IsApproximately(alloc.SnapshotByteHours, 75.0*gib) {
+			t.Fatalf("%s: expected 75 GiB-hours; got %f", name, alloc.SnapshotByteHours/gib)
+		}
+	}
+
+	// The snapshot which outlived the pods, and the one without a source PVC,
+	// are attributed to the namespaces they were taken in
+	unmounted := podMap[newPodKey("cluster1", "namespace1", "namespace1-unmounted-pvcs")]
+	if unmounted == nil {
+		t.Fatalf("expected unmounted pod for namespace1")
+	}
+	if alloc := unmounted.Allocations[kubecost.UnmountedSuffix]; !util.IsApproximately(alloc.SnapshotCost, 0.08) {
+		t.Fatalf("expected snapshot cost of 0.08; got %f", alloc.SnapshotCost)
+	}
+
+	unmounted = podMap[newPodKey("cluster1", "namespace2", "namespace2-unmounted-pvcs")]
+	if unmounted == nil {
+		t.Fatalf("expected unmounted pod for namespace2")
+	}
+	if alloc := unmounted.Allocations[kubecost.UnmountedSuffix]; !util.IsApproximately(alloc.SnapshotCost, 0.075) {
+		t.Fatalf("expected snapshot cost of 0.075; got %f", alloc.SnapshotCost)
+	}
+}
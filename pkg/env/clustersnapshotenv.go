@@ -0,0 +1,33 @@
+package env
+
+import "time"
+
+const (
+	ClusterSnapshotsEnabledEnvVar   = "CLUSTER_SNAPSHOTS_ENABLED"
+	ClusterSnapshotIntervalEnvVar   = "CLUSTER_SNAPSHOT_INTERVAL"
+	ClusterSnapshotRetentionEnvVar  = "CLUSTER_SNAPSHOT_RETENTION"
+	ClusterSnapshotDirectoryEnvVar  = "CLUSTER_SNAPSHOT_DIRECTORY"
+	defaultClusterSnapshotDirectory = "cluster-snapshots"
+)
+
+// IsClusterSnapshotsEnabled returns true if periodic snapshots of the cluster cache should be
+// persisted so that metadata for deleted objects can be resolved for historical windows.
+func IsClusterSnapshotsEnabled() bool {
+	return GetBool(ClusterSnapshotsEnabledEnvVar, false)
+}
+
+// GetClusterSnapshotInterval returns the interval between cluster cache snapshots.
+func GetClusterSnapshotInterval() time.Duration {
+	return GetDuration(ClusterSnapshotIntervalEnvVar, 10*time.Minute)
+}
+
+// GetClusterSnapshotRetention returns the duration cluster cache snapshots are retained for.
+func GetClusterSnapshotRetention() time.Duration {
+	return GetDuration(ClusterSnapshotRetentionEnvVar, 7*24*time.Hour)
+}
+
+// GetClusterSnapshotDirectory returns the directory, relative to the config path, in which
+// cluster cache snapshots are stored.
+func GetClusterSnapshotDirectory() string {
+	return Get(ClusterSnapshotDirectoryEnvVar, defaultClusterSnapshotDirectory)
+}
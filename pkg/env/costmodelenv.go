@@ -97,6 +97,22 @@ const (
 	regionOverrideList = "REGION_OVERRIDE_LIST"
 
 	ExportCSVFile = "EXPORT_CSV_FILE"
+
+	AuditEnabledEnvVar         = "AUDIT_ENABLED"
+	AuditIntervalMinutesEnvVar = "AUDIT_INTERVAL_MINUTES"
+	AuditWindowHoursEnvVar     = "AUDIT_WINDOW_HOURS"
+	AuditToleranceEnvVar       = "AUDIT_TOLERANCE"
+
+	GPUAllocationModeEnvVar = "GPU_ALLOCATION_MODE"
+)
+
+const (
+	// GPUAllocationModeRequest allocates GPUs by container requests, with MIG
+	// slices priced as fractions of their parent GPU.
+	GPUAllocationModeRequest = "request"
+	// GPUAllocationModeUtilization splits each GPU among the containers
+	// sharing it (e.g. by time-slicing) in proportion to their utilization.
+	GPUAllocationModeUtilization = "utilization"
 )
 
 const DefaultConfigMountPath = "/var/configs"
@@ -543,3 +559,40 @@ func GetRegionOverrideList() []string {
 
 	return regionList
 }
+
+// IsAuditEnabled returns true if the audit runner, which periodically checks
+// computed costs for consistency, is enabled.
+func IsAuditEnabled() bool {
+	return GetBool(AuditEnabledEnvVar, false)
+}
+
+// GetAuditInterval returns the interval at which audits are run, defaulting
+// to one hour.
+func GetAuditInterval() time.Duration {
+	mins := time.Duration(GetInt64(AuditIntervalMinutesEnvVar, 60))
+	return mins * time.Minute
+}
+
+// GetAuditWindow returns the duration of the window audited by each run,
+// defaulting to one day.
+func GetAuditWindow() time.Duration {
+	hrs := time.Duration(GetInt64(AuditWindowHoursEnvVar, 24))
+	return hrs * time.Hour
+}
+
+// GetAuditTolerance returns the relative difference between expected and
+// actual costs beyond which an audit fails, defaulting to 1%.
+func GetAuditTolerance() float64 {
+	return GetFloat64(AuditToleranceEnvVar, 0.01)
+}
+
+// GetGPUAllocationMode returns the mode by which GPU costs are allocated to
+// containers, defaulting to GPUAllocationModeRequest.
+func GetGPUAllocationMode() string {
+	mode := Get(GPUAllocationModeEnvVar, GPUAllocationModeRequest)
+	if mode != GPUAllocationModeRequest && mode != GPUAllocationModeUtilization {
+		log.Warnf("Invalid %s %q, defaulting to %q", GPUAllocationModeEnvVar, mode, GPUAllocationModeRequest)
+		return GPUAllocationModeRequest
+	}
+	return mode
+}
@@ -0,0 +1,20 @@
+package env
+
+import "time"
+
+const (
+	FederationEnabledEnvVar      = "FEDERATION_ENABLED"
+	FederationQueryTimeoutEnvVar = "FEDERATION_QUERY_TIMEOUT"
+)
+
+// IsFederationEnabled returns true if the federated query endpoints, which fan out queries to all
+// registered cluster definitions, should be served.
+func IsFederationEnabled() bool {
+	return GetBool(FederationEnabledEnvVar, false)
+}
+
+// GetFederationQueryTimeout returns the maximum duration to wait for a single remote cluster to
+// respond to a federated query.
+func GetFederationQueryTimeout() time.Duration {
+	return GetDuration(FederationQueryTimeoutEnvVar, 2*time.Minute)
+}
@@ -0,0 +1,84 @@
+package env
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	MetricsPushModeEnvVar        = "METRICS_PUSH_MODE"
+	MetricsPushEndpointEnvVar    = "METRICS_PUSH_ENDPOINT"
+	MetricsPushIntervalEnvVar    = "METRICS_PUSH_INTERVAL"
+	MetricsPushTimeoutEnvVar     = "METRICS_PUSH_TIMEOUT"
+	MetricsPushBatchSizeEnvVar   = "METRICS_PUSH_BATCH_SIZE"
+	MetricsPushMaxRetriesEnvVar  = "METRICS_PUSH_MAX_RETRIES"
+	MetricsPushRetryDelayEnvVar  = "METRICS_PUSH_RETRY_DELAY"
+	MetricsPushHeadersEnvVar     = "METRICS_PUSH_HEADERS"
+	MetricsPushInsecureEnvVar    = "METRICS_PUSH_INSECURE_SKIP_VERIFY"
+	MetricsPushModeNone          = "none"
+	MetricsPushModeOTLP          = "otlp"
+	MetricsPushModeRemoteWrite   = "remote-write"
+	defaultMetricsPushBatchSize  = 2000
+	defaultMetricsPushMaxRetries = 3
+)
+
+// GetMetricsPushMode returns the environment variable value for MetricsPushModeEnvVar which selects
+// how metrics are pushed in addition to being exposed for scraping. Supported values are "none"
+// (the default), "otlp", and "remote-write".
+func GetMetricsPushMode() string {
+	return strings.ToLower(Get(MetricsPushModeEnvVar, MetricsPushModeNone))
+}
+
+// IsMetricsPushEnabled returns true if metrics should be pushed to a remote endpoint.
+func IsMetricsPushEnabled() bool {
+	mode := GetMetricsPushMode()
+	return mode != MetricsPushModeNone && mode != ""
+}
+
+// GetMetricsPushEndpoint returns the full URL metrics are pushed to, e.g.
+// http://otel-collector:4318/v1/metrics or http://cortex/api/v1/push
+func GetMetricsPushEndpoint() string {
+	return Get(MetricsPushEndpointEnvVar, "")
+}
+
+// GetMetricsPushInterval returns the interval at which metrics are gathered and pushed.
+func GetMetricsPushInterval() time.Duration {
+	return GetDuration(MetricsPushIntervalEnvVar, time.Minute)
+}
+
+// GetMetricsPushTimeout returns the timeout applied to each push request.
+func GetMetricsPushTimeout() time.Duration {
+	return GetDuration(MetricsPushTimeoutEnvVar, 30*time.Second)
+}
+
+// GetMetricsPushBatchSize returns the maximum number of series sent in a single push request.
+func GetMetricsPushBatchSize() int {
+	return GetInt(MetricsPushBatchSizeEnvVar, defaultMetricsPushBatchSize)
+}
+
+// GetMetricsPushMaxRetries returns the number of times a failed push request is retried.
+func GetMetricsPushMaxRetries() int {
+	return GetInt(MetricsPushMaxRetriesEnvVar, defaultMetricsPushMaxRetries)
+}
+
+// GetMetricsPushRetryDelay returns the initial delay between retries of a failed push request.
+func GetMetricsPushRetryDelay() time.Duration {
+	return GetDuration(MetricsPushRetryDelayEnvVar, time.Second)
+}
+
+// GetMetricsPushHeaders returns additional headers sent with each push request, configured as a
+// comma separated list of key=value pairs. This can be used to supply authorization or tenant headers.
+func GetMetricsPushHeaders() map[string]string {
+	headers := map[string]string{}
+	for _, kv := range GetList(MetricsPushHeadersEnvVar, ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return headers
+}
+
+// IsMetricsPushInsecureSkipVerify returns true if TLS verification should be skipped for push requests.
+func IsMetricsPushInsecureSkipVerify() bool {
+	return GetBool(MetricsPushInsecureEnvVar, false)
+}
@@ -0,0 +1,26 @@
+package env
+
+const (
+	NetworkFlowLogsEnabledEnvVar      = "NETWORK_FLOW_LOGS_ENABLED"
+	NetworkFlowLogsBucketConfigEnvVar = "NETWORK_FLOW_LOGS_BUCKET_CONFIG"
+	NetworkFlowLogsDirectoryEnvVar    = "NETWORK_FLOW_LOGS_DIRECTORY"
+	defaultNetworkFlowLogsDirectory   = "flowlogs"
+)
+
+// IsNetworkFlowLogsEnabled returns true if network costs should be attributed from flow logs
+// rather than from the metrics of the network costs daemon.
+func IsNetworkFlowLogsEnabled() bool {
+	return GetBool(NetworkFlowLogsEnabledEnvVar, false)
+}
+
+// GetNetworkFlowLogsBucketConfig returns the path of the bucket config of the storage from
+// which flow logs are read. If it is not set, flow logs are read from the config storage.
+func GetNetworkFlowLogsBucketConfig() string {
+	return Get(NetworkFlowLogsBucketConfigEnvVar, "")
+}
+
+// GetNetworkFlowLogsDirectory returns the directory of the flow log storage from which flow
+// logs are read. For the config storage, it is relative to the config path.
+func GetNetworkFlowLogsDirectory() string {
+	return Get(NetworkFlowLogsDirectoryEnvVar, defaultNetworkFlowLogsDirectory)
+}
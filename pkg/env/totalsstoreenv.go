@@ -0,0 +1,34 @@
+package env
+
+import "time"
+
+const (
+	TotalsStoreCompactionIntervalEnvVar = "TOTALS_STORE_COMPACTION_INTERVAL"
+	TotalsStoreRetentionEnvVar          = "TOTALS_STORE_RETENTION"
+	TotalsStoreHourlyRetentionEnvVar    = "TOTALS_STORE_HOURLY_RETENTION"
+	TotalsStoreDirectoryEnvVar          = "TOTALS_STORE_DIRECTORY"
+	defaultTotalsStoreDirectory         = "totals"
+)
+
+// GetTotalsStoreCompactionInterval returns the interval at which persisted totals are compacted
+// and pruned.
+func GetTotalsStoreCompactionInterval() time.Duration {
+	return GetDuration(TotalsStoreCompactionIntervalEnvVar, time.Hour)
+}
+
+// GetTotalsStoreRetention returns the duration persisted totals are retained for.
+func GetTotalsStoreRetention() time.Duration {
+	return GetDuration(TotalsStoreRetentionEnvVar, 90*24*time.Hour)
+}
+
+// GetTotalsStoreHourlyRetention returns the duration hourly totals are retained for before they are
+// compacted into daily totals.
+func GetTotalsStoreHourlyRetention() time.Duration {
+	return GetDuration(TotalsStoreHourlyRetentionEnvVar, 7*24*time.Hour)
+}
+
+// GetTotalsStoreDirectory returns the directory, relative to the config path, in which allocation
+// and asset totals are persisted.
+func GetTotalsStoreDirectory() string {
+	return Get(TotalsStoreDirectoryEnvVar, defaultTotalsStoreDirectory)
+}
@@ -0,0 +1,59 @@
+package env
+
+import "strings"
+
+const (
+	TracingExporterEnvVar      = "TRACING_EXPORTER"
+	TracingOTLPEndpointEnvVar  = "TRACING_OTLP_ENDPOINT"
+	TracingOTLPInsecureEnvVar  = "TRACING_OTLP_INSECURE"
+	TracingOTLPHeadersEnvVar   = "TRACING_OTLP_HEADERS"
+	TracingSampleRatioEnvVar   = "TRACING_SAMPLE_RATIO"
+	TracingServiceNameEnvVar   = "TRACING_SERVICE_NAME"
+	TracingExporterNone        = "none"
+	TracingExporterOTLP        = "otlp"
+	defaultTracingOTLPEndpoint = "localhost:4318"
+)
+
+// GetTracingExporter returns the environment variable value for TracingExporterEnvVar which
+// selects the span exporter. Supported values are "none" (the default) and "otlp".
+func GetTracingExporter() string {
+	return Get(TracingExporterEnvVar, TracingExporterNone)
+}
+
+// IsTracingEnabled returns true if spans should be exported to a non no-op exporter.
+func IsTracingEnabled() bool {
+	return GetTracingExporter() != TracingExporterNone
+}
+
+// GetTracingOTLPEndpoint returns the host:port of the OTLP/HTTP collector that spans are
+// exported to when the "otlp" exporter is selected.
+func GetTracingOTLPEndpoint() string {
+	return Get(TracingOTLPEndpointEnvVar, defaultTracingOTLPEndpoint)
+}
+
+// IsTracingOTLPInsecure returns true if the OTLP exporter should use plain HTTP instead of HTTPS.
+func IsTracingOTLPInsecure() bool {
+	return GetBool(TracingOTLPInsecureEnvVar, false)
+}
+
+// GetTracingOTLPHeaders returns the additional headers sent with each OTLP export request,
+// configured as a comma separated list of key=value pairs.
+func GetTracingOTLPHeaders() map[string]string {
+	headers := map[string]string{}
+	for _, kv := range GetList(TracingOTLPHeadersEnvVar, ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return headers
+}
+
+// GetTracingSampleRatio returns the ratio of root spans which should be sampled, between 0 and 1.
+func GetTracingSampleRatio() float64 {
+	return GetFloat64(TracingSampleRatioEnvVar, 1.0)
+}
+
+// GetTracingServiceName returns the service.name resource attribute attached to exported spans.
+func GetTracingServiceName() string {
+	return Get(TracingServiceNameEnvVar, "opencost")
+}
@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/opencost/opencost/pkg/log"
+)
+
+// NumericPropertied is used to validate the name of a numeric property field
+// and return its value
+type NumericPropertied interface {
+	NumericProperty(string) (float64, error)
+}
+
+// NumericOperation is an enum that represents comparisons that can be performed
+// when filtering on numeric values, like costs
+type NumericOperation string
+
+const (
+	// NumericGreaterThan passes if the property is greater than the value
+	NumericGreaterThan NumericOperation = "numericgreaterthan"
+
+	// NumericGreaterThanOrEqual passes if the property is greater than or
+	// equal to the value
+	NumericGreaterThanOrEqual = "numericgreaterthanorequal"
+
+	// NumericLessThan passes if the property is less than the value
+	NumericLessThan = "numericlessthan"
+
+	// NumericLessThanOrEqual passes if the property is less than or equal to
+	// the value
+	NumericLessThanOrEqual = "numericlessthanorequal"
+)
+
+// NumericProperty is the lowest-level type of filter on numeric properties. It
+// represents a comparison of a numeric field (totalCost, cpuCoreHours, etc.)
+// with a constant.
+type NumericProperty[T NumericPropertied] struct {
+	Field string
+	Op    NumericOperation
+	Value float64
+}
+
+func (np NumericProperty[T]) String() string {
+	return fmt.Sprintf(`(%s %s %g)`, np.Op, np.Field, np.Value)
+}
+
+func (np NumericProperty[T]) Matches(that T) bool {
+	thatValue, err := that.NumericProperty(np.Field)
+	if err != nil {
+		log.Errorf("Filter: NumericProperty: could not retrieve field %s: %s", np.Field, err.Error())
+		return false
+	}
+
+	switch np.Op {
+	case NumericGreaterThan:
+		return thatValue > np.Value
+	case NumericGreaterThanOrEqual:
+		return thatValue >= np.Value
+	case NumericLessThan:
+		return thatValue < np.Value
+	case NumericLessThanOrEqual:
+		return thatValue <= np.Value
+	default:
+		log.Errorf("Filter: NumericProperty: Unhandled filter op. This is a filter implementation error and requires immediate patching. Op: %s", np.Op)
+		return false
+	}
+}
@@ -0,0 +1,57 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/opencost/opencost/pkg/log"
+)
+
+// StringRegexProperty matches values whose string property matches the regular
+// expression. The expression is compiled when the filter is constructed, rather
+// than each time the filter is matched.
+type StringRegexProperty[T StringPropertied] struct {
+	Field string
+	Regex *regexp.Regexp
+}
+
+func (srp StringRegexProperty[T]) String() string {
+	return fmt.Sprintf(`(regex %s "%s")`, srp.Field, srp.Regex)
+}
+
+func (srp StringRegexProperty[T]) Matches(that T) bool {
+	thatString, err := that.StringProperty(srp.Field)
+	if err != nil {
+		log.Errorf("Filter: StringRegexProperty: could not retrieve field %s: %s", srp.Field, err.Error())
+		return false
+	}
+
+	return srp.Regex.MatchString(thatString)
+}
+
+// StringMapRegexProperty matches values for which the given key of a string map
+// property is present and its value matches the regular expression.
+type StringMapRegexProperty[T StringMapPropertied] struct {
+	Field string
+	Key   string
+	Regex *regexp.Regexp
+}
+
+func (smrp StringMapRegexProperty[T]) String() string {
+	return fmt.Sprintf(`(regex %s[%s] "%s")`, smrp.Field, smrp.Key, smrp.Regex)
+}
+
+func (smrp StringMapRegexProperty[T]) Matches(that T) bool {
+	thatMap, err := that.StringMapProperty(smrp.Field)
+	if err != nil {
+		log.Errorf("Filter: StringMapRegexProperty: could not retrieve field %s: %s", smrp.Field, err.Error())
+		return false
+	}
+
+	valueToCompare, keyIsPresent := thatMap[smrp.Key]
+	if !keyIsPresent {
+		return false
+	}
+
+	return smrp.Regex.MatchString(valueToCompare)
+}
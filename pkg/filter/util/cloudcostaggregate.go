@@ -5,6 +5,7 @@ import (
 
 	"github.com/opencost/opencost/pkg/filter"
 	"github.com/opencost/opencost/pkg/kubecost"
+	filterv2 "github.com/opencost/opencost/pkg/util/allocationfilterutil/v2"
 	"github.com/opencost/opencost/pkg/util/mapper"
 )
 
@@ -44,6 +45,17 @@ func CloudCostAggregateFilterFromParams(pmr mapper.PrimitiveMapReader) filter.Fi
 	return filter
 }
 
+// CloudCostAggregateFilterFromParamsWithV2 parses the "filter" parameter as a
+// V2 filter if present, falling back to the V1 parameters otherwise. A nil
+// filter is returned if neither is present.
+func CloudCostAggregateFilterFromParamsWithV2(pmr mapper.PrimitiveMapReader) (filter.Filter[*kubecost.CloudCostAggregate], error) {
+	if raw := pmr.Get("filter", ""); raw != "" {
+		return filterv2.ParseCloudCostAggregateFilter(raw)
+	}
+
+	return CloudCostAggregateFilterFromParams(pmr), nil
+}
+
 func filterV1SingleValueFromList(rawFilterValues []string, field string) filter.Filter[*kubecost.CloudCostAggregate] {
 	result := filter.Or[*kubecost.CloudCostAggregate]{
 		Filters: []filter.Filter[*kubecost.CloudCostAggregate]{},
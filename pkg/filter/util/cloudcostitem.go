@@ -0,0 +1,20 @@
+package util
+
+import (
+	"github.com/opencost/opencost/pkg/filter"
+	"github.com/opencost/opencost/pkg/kubecost"
+	filterv2 "github.com/opencost/opencost/pkg/util/allocationfilterutil/v2"
+	"github.com/opencost/opencost/pkg/util/mapper"
+)
+
+// CloudCostItemFilterFromParams parses the V2 "filter" parameter into a filter
+// of CloudCostItems. CloudCostItems have no V1 filter parameters, so a nil
+// filter is returned if "filter" is not present.
+func CloudCostItemFilterFromParams(pmr mapper.PrimitiveMapReader) (filter.Filter[*kubecost.CloudCostItem], error) {
+	raw := pmr.Get("filter", "")
+	if raw == "" {
+		return nil, nil
+	}
+
+	return filterv2.ParseCloudCostItemFilter(raw)
+}
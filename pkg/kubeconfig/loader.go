@@ -1,6 +1,7 @@
 package kubeconfig
 
 import (
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
@@ -28,3 +29,13 @@ func LoadKubeClient(path string) (*kubernetes.Clientset, error) {
 	}
 	return kubernetes.NewForConfig(config)
 }
+
+// LoadDynamicClient accepts a path to a kubeconfig to load and returns a dynamic client, which
+// can be used to access custom resources
+func LoadDynamicClient(path string) (dynamic.Interface, error) {
+	config, err := LoadKubeconfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(config)
+}
@@ -1,14 +1,17 @@
 package kubecost
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/tracing"
 	"github.com/opencost/opencost/pkg/util"
 	"github.com/opencost/opencost/pkg/util/timeutil"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // TODO Clean-up use of IsEmpty; nil checks should be separated for safety.
@@ -87,6 +90,27 @@ type Allocation struct {
 	// asset on which the allocation was run. It is optionally computed
 	// and appended to an Allocation, and so by default is is nil.
 	ProportionalAssetResourceCosts ProportionalAssetResourceCosts `json:"proportionalAssetResourceCosts"`
+	// SharingPolicyShares explain the shared cost distributed to the
+	// allocation by each SharingPolicy. Like ProportionalAssetResourceCosts,
+	// they are only computed on aggregation, so by default they are nil.
+	SharingPolicyShares SharingPolicyShares `json:"sharingPolicyShares,omitempty"` // @bingen:field[ignore]
+	// GPUUsageAverage is the average number of GPUs used, as reported by the
+	// DCGM exporter, and GPUMemoryUsageAverage the average bytes of GPU
+	// memory used. Both are zero if GPU usage is not being recorded.
+	GPUUsageAverage       float64 `json:"gpuUsageAverage"`       // @bingen:field[version=18]
+	GPUMemoryUsageAverage float64 `json:"gpuMemoryUsageAverage"` // @bingen:field[version=18]
+	// EphemeralStorage fields record the node-local storage consumed by the
+	// writable layers, logs and emptyDir volumes of containers, charged by
+	// the greater of request and usage at the price of the node's local disk.
+	EphemeralStorageByteHours           float64 `json:"ephemeralStorageByteHours"`          // @bingen:field[version=19]
+	EphemeralStorageBytesRequestAverage float64 `json:"ephemeralStorageByteRequestAverage"` // @bingen:field[version=19]
+	EphemeralStorageBytesUsageAverage   float64 `json:"ephemeralStorageByteUsageAverage"`   // @bingen:field[version=19]
+	EphemeralStorageCost                float64 `json:"ephemeralStorageCost"`               // @bingen:field[version=19]
+	EphemeralStorageCostAdjustment      float64 `json:"ephemeralStorageCostAdjustment"`     // @bingen:field[version=19]
+	// Snapshot fields record the storage of volume snapshots taken of the
+	// PVCs used by the Allocation, which are retained after the PVC is gone.
+	SnapshotByteHours float64 `json:"snapshotByteHours"` // @bingen:field[version=20]
+	SnapshotCost      float64 `json:"snapshotCost"`      // @bingen:field[version=20]
 }
 
 // RawAllocationOnlyData is information that only belong in "raw" Allocations,
@@ -154,8 +178,10 @@ func (pv PVAllocations) Clone() PVAllocations {
 	clonePV := make(map[PVKey]*PVAllocation, len(pv))
 	for k, v := range pv {
 		clonePV[k] = &PVAllocation{
-			ByteHours: v.ByteHours,
-			Cost:      v.Cost,
+			ByteHours:      v.ByteHours,
+			Cost:           v.Cost,
+			IOPSCost:       v.IOPSCost,
+			ThroughputCost: v.ThroughputCost,
 		}
 	}
 	return clonePV
@@ -175,6 +201,8 @@ func (pv PVAllocations) Add(that PVAllocations) PVAllocations {
 			}
 			apvAlloc.Cost += thatPVAlloc.Cost
 			apvAlloc.ByteHours += thatPVAlloc.ByteHours
+			apvAlloc.IOPSCost += thatPVAlloc.IOPSCost
+			apvAlloc.ThroughputCost += thatPVAlloc.ThroughputCost
 			apv[pvKey] = apvAlloc
 		}
 	}
@@ -227,10 +255,13 @@ func (pvk *PVKey) FromString(key string) error {
 }
 
 // PVAllocation contains the byte hour usage
-// and cost of an Allocation for a single PV
+// and cost of an Allocation for a single PV. IOPSCost and ThroughputCost
+// break out the share of Cost spent on provisioned performance.
 type PVAllocation struct {
-	ByteHours float64 `json:"byteHours"`
-	Cost      float64 `json:"cost"`
+	ByteHours      float64 `json:"byteHours"`
+	Cost           float64 `json:"cost"`
+	IOPSCost       float64 `json:"iopsCost"`       // @bingen:field[version=21]
+	ThroughputCost float64 `json:"throughputCost"` // @bingen:field[version=21]
 }
 
 // Equal returns true if the two PVAllocation instances contain approximately the same
@@ -243,7 +274,9 @@ func (pva *PVAllocation) Equal(that *PVAllocation) bool {
 		return false
 	}
 	return util.IsApproximately(pva.ByteHours, that.ByteHours) &&
-		util.IsApproximately(pva.Cost, that.Cost)
+		util.IsApproximately(pva.Cost, that.Cost) &&
+		util.IsApproximately(pva.IOPSCost, that.IOPSCost) &&
+		util.IsApproximately(pva.ThroughputCost, that.ThroughputCost)
 }
 
 type ProportionalAssetResourceCost struct {
@@ -364,6 +397,17 @@ func (a *Allocation) Clone() *Allocation {
 		SharedCost:                 a.SharedCost,
 		ExternalCost:               a.ExternalCost,
 		RawAllocationOnly:          a.RawAllocationOnly.Clone(),
+		SharingPolicyShares:        a.SharingPolicyShares.Clone(),
+		GPUUsageAverage:            a.GPUUsageAverage,
+		GPUMemoryUsageAverage:      a.GPUMemoryUsageAverage,
+
+		EphemeralStorageByteHours:           a.EphemeralStorageByteHours,
+		EphemeralStorageBytesRequestAverage: a.EphemeralStorageBytesRequestAverage,
+		EphemeralStorageBytesUsageAverage:   a.EphemeralStorageBytesUsageAverage,
+		EphemeralStorageCost:                a.EphemeralStorageCost,
+		EphemeralStorageCostAdjustment:      a.EphemeralStorageCostAdjustment,
+		SnapshotByteHours:                   a.SnapshotByteHours,
+		SnapshotCost:                        a.SnapshotCost,
 	}
 }
 
@@ -409,6 +453,12 @@ func (a *Allocation) Equal(that *Allocation) bool {
 	if !util.IsApproximately(a.GPUCostAdjustment, that.GPUCostAdjustment) {
 		return false
 	}
+	if !util.IsApproximately(a.GPUUsageAverage, that.GPUUsageAverage) {
+		return false
+	}
+	if !util.IsApproximately(a.GPUMemoryUsageAverage, that.GPUMemoryUsageAverage) {
+		return false
+	}
 	if !util.IsApproximately(a.NetworkTransferBytes, that.NetworkTransferBytes) {
 		return false
 	}
@@ -454,6 +504,21 @@ func (a *Allocation) Equal(that *Allocation) bool {
 	if !util.IsApproximately(a.ExternalCost, that.ExternalCost) {
 		return false
 	}
+	if !util.IsApproximately(a.EphemeralStorageByteHours, that.EphemeralStorageByteHours) {
+		return false
+	}
+	if !util.IsApproximately(a.EphemeralStorageCost, that.EphemeralStorageCost) {
+		return false
+	}
+	if !util.IsApproximately(a.EphemeralStorageCostAdjustment, that.EphemeralStorageCostAdjustment) {
+		return false
+	}
+	if !util.IsApproximately(a.SnapshotByteHours, that.SnapshotByteHours) {
+		return false
+	}
+	if !util.IsApproximately(a.SnapshotCost, that.SnapshotCost) {
+		return false
+	}
 
 	if !a.RawAllocationOnly.Equal(that.RawAllocationOnly) {
 		return false
@@ -472,7 +537,7 @@ func (a *Allocation) TotalCost() float64 {
 		return 0.0
 	}
 
-	return a.CPUTotalCost() + a.GPUTotalCost() + a.RAMTotalCost() + a.PVTotalCost() + a.NetworkTotalCost() + a.LBTotalCost() + a.SharedTotalCost() + a.ExternalCost
+	return a.CPUTotalCost() + a.GPUTotalCost() + a.RAMTotalCost() + a.PVTotalCost() + a.NetworkTotalCost() + a.LBTotalCost() + a.EphemeralStorageTotalCost() + a.SnapshotCost + a.SharedTotalCost() + a.ExternalCost
 }
 
 // CPUTotalCost calculates total CPU cost of Allocation including adjustment
@@ -535,6 +600,15 @@ func (a *Allocation) LoadBalancerTotalCost() float64 {
 	return a.LoadBalancerCost + a.LoadBalancerCostAdjustment
 }
 
+// EphemeralStorageTotalCost calculates total ephemeral storage cost of Allocation including adjustment
+func (a *Allocation) EphemeralStorageTotalCost() float64 {
+	if a == nil {
+		return 0.0
+	}
+
+	return a.EphemeralStorageCost + a.EphemeralStorageCostAdjustment
+}
+
 // SharedTotalCost calculates total shared cost of Allocation including adjustment
 func (a *Allocation) SharedTotalCost() float64 {
 	if a == nil {
@@ -608,6 +682,44 @@ func (a *Allocation) RAMEfficiency() float64 {
 	return 1.0
 }
 
+// GPUEfficiency is the ratio of usage to allocated GPUs. If there are no
+// allocated GPUs and no usage or cost, then efficiency is zero. If there are
+// no allocated GPUs, but there is usage or cost, then efficiency is 100%.
+func (a *Allocation) GPUEfficiency() float64 {
+	if a == nil {
+		return 0.0
+	}
+
+	if a.GPUs() > 0 {
+		return a.GPUUsageAverage / a.GPUs()
+	}
+
+	if a.GPUUsageAverage == 0.0 || a.GPUCost == 0.0 {
+		return 0.0
+	}
+
+	return 1.0
+}
+
+// EphemeralStorageEfficiency is the ratio of usage to request. If there is no
+// request and no usage or cost, then efficiency is zero. If there is no
+// request, but there is usage or cost, then efficiency is 100%.
+func (a *Allocation) EphemeralStorageEfficiency() float64 {
+	if a == nil {
+		return 0.0
+	}
+
+	if a.EphemeralStorageBytesRequestAverage > 0 {
+		return a.EphemeralStorageBytesUsageAverage / a.EphemeralStorageBytesRequestAverage
+	}
+
+	if a.EphemeralStorageBytesUsageAverage == 0.0 || a.EphemeralStorageCost == 0.0 {
+		return 0.0
+	}
+
+	return 1.0
+}
+
 // TotalEfficiency is the cost-weighted average of CPU and RAM efficiency. If
 // there is no cost at all, then efficiency is zero.
 func (a *Allocation) TotalEfficiency() float64 {
@@ -648,6 +760,24 @@ func (a *Allocation) GPUs() float64 {
 	return a.GPUHours / (a.Minutes() / 60.0)
 }
 
+// EphemeralStorageBytes converts the Allocation's EphemeralStorageByteHours
+// into average EphemeralStorageBytes
+func (a *Allocation) EphemeralStorageBytes() float64 {
+	if a.Minutes() <= 0.0 {
+		return 0.0
+	}
+	return a.EphemeralStorageByteHours / (a.Minutes() / 60.0)
+}
+
+// SnapshotBytes converts the Allocation's SnapshotByteHours into average
+// SnapshotBytes
+func (a *Allocation) SnapshotBytes() float64 {
+	if a.Minutes() <= 0.0 {
+		return 0.0
+	}
+	return a.SnapshotByteHours / (a.Minutes() / 60.0)
+}
+
 // PVBytes converts the Allocation's PVByteHours into average PVBytes
 func (a *Allocation) PVBytes() float64 {
 	if a.Minutes() <= 0.0 {
@@ -668,6 +798,7 @@ func (a *Allocation) ResetAdjustments() {
 	a.PVCostAdjustment = 0.0
 	a.NetworkCostAdjustment = 0.0
 	a.LoadBalancerCostAdjustment = 0.0
+	a.EphemeralStorageCostAdjustment = 0.0
 }
 
 // Resolution returns the duration of time covered by the Allocation
@@ -777,6 +908,14 @@ func (a *Allocation) add(that *Allocation) {
 		a.ProportionalAssetResourceCosts.Add(that.ProportionalAssetResourceCosts)
 	}
 
+	// Likewise, sum the shares of any SharingPolicy
+	if that.SharingPolicyShares != nil {
+		if a.SharingPolicyShares == nil {
+			a.SharingPolicyShares = SharingPolicyShares{}
+		}
+		a.SharingPolicyShares.Add(that.SharingPolicyShares)
+	}
+
 	// Overwrite regular intersection logic for the controller name property in the
 	// case that the Allocation keys are the same but the controllers are not.
 	if leftKey == rightKey &&
@@ -815,6 +954,18 @@ func (a *Allocation) add(that *Allocation) {
 	ramUseByteMins := a.RAMBytesUsageAverage * a.Minutes()
 	ramUseByteMins += that.RAMBytesUsageAverage * that.Minutes()
 
+	gpuUseMins := a.GPUUsageAverage * a.Minutes()
+	gpuUseMins += that.GPUUsageAverage * that.Minutes()
+
+	gpuMemUseByteMins := a.GPUMemoryUsageAverage * a.Minutes()
+	gpuMemUseByteMins += that.GPUMemoryUsageAverage * that.Minutes()
+
+	ephReqByteMins := a.EphemeralStorageBytesRequestAverage * a.Minutes()
+	ephReqByteMins += that.EphemeralStorageBytesRequestAverage * that.Minutes()
+
+	ephUseByteMins := a.EphemeralStorageBytesUsageAverage * a.Minutes()
+	ephUseByteMins += that.EphemeralStorageBytesUsageAverage * that.Minutes()
+
 	// Expand Start and End to be the "max" of among the given Allocations
 	if that.Start.Before(a.Start) {
 		a.Start = that.Start
@@ -830,17 +981,27 @@ func (a *Allocation) add(that *Allocation) {
 		a.CPUCoreUsageAverage = cpuUseCoreMins / a.Minutes()
 		a.RAMBytesRequestAverage = ramReqByteMins / a.Minutes()
 		a.RAMBytesUsageAverage = ramUseByteMins / a.Minutes()
+		a.GPUUsageAverage = gpuUseMins / a.Minutes()
+		a.GPUMemoryUsageAverage = gpuMemUseByteMins / a.Minutes()
+		a.EphemeralStorageBytesRequestAverage = ephReqByteMins / a.Minutes()
+		a.EphemeralStorageBytesUsageAverage = ephUseByteMins / a.Minutes()
 	} else {
 		a.CPUCoreRequestAverage = 0.0
 		a.CPUCoreUsageAverage = 0.0
 		a.RAMBytesRequestAverage = 0.0
 		a.RAMBytesUsageAverage = 0.0
+		a.GPUUsageAverage = 0.0
+		a.GPUMemoryUsageAverage = 0.0
+		a.EphemeralStorageBytesRequestAverage = 0.0
+		a.EphemeralStorageBytesUsageAverage = 0.0
 	}
 
 	// Sum all cumulative resource fields
 	a.CPUCoreHours += that.CPUCoreHours
 	a.GPUHours += that.GPUHours
 	a.RAMByteHours += that.RAMByteHours
+	a.EphemeralStorageByteHours += that.EphemeralStorageByteHours
+	a.SnapshotByteHours += that.SnapshotByteHours
 	a.NetworkTransferBytes += that.NetworkTransferBytes
 	a.NetworkReceiveBytes += that.NetworkReceiveBytes
 
@@ -853,6 +1014,8 @@ func (a *Allocation) add(that *Allocation) {
 	a.NetworkCrossRegionCost += that.NetworkCrossRegionCost
 	a.NetworkInternetCost += that.NetworkInternetCost
 	a.LoadBalancerCost += that.LoadBalancerCost
+	a.EphemeralStorageCost += that.EphemeralStorageCost
+	a.SnapshotCost += that.SnapshotCost
 	a.SharedCost += that.SharedCost
 	a.ExternalCost += that.ExternalCost
 
@@ -866,6 +1029,7 @@ func (a *Allocation) add(that *Allocation) {
 	a.PVCostAdjustment += that.PVCostAdjustment
 	a.NetworkCostAdjustment += that.NetworkCostAdjustment
 	a.LoadBalancerCostAdjustment += that.LoadBalancerCostAdjustment
+	a.EphemeralStorageCostAdjustment += that.EphemeralStorageCostAdjustment
 
 	// Any data that is in a "raw allocation only" is not valid in any
 	// sort of cumulative Allocation (like one that is added).
@@ -905,12 +1069,21 @@ func NewAllocationSet(start, end time.Time, allocs ...*Allocation) *AllocationSe
 // filtering results and sharing allocations. FilterFuncs are a list of match
 // functions such that, if any function fails, the allocation is ignored.
 // ShareFuncs are a list of match functions such that, if any function
-// succeeds, the allocation is marked as a shared resource. ShareIdle is a
-// simple flag for sharing idle resources.
+// succeeds, the allocation is marked as a shared resource. SharingPolicies
+// share the allocations matching each policy among its recipients, by the
+// policy's weight, rather than by ShareSplit. ShareIdle is a simple flag for
+// sharing idle resources; with ShareOwner, the idle owned by each of
+// IdleOwners is charged to that owner, and the rest is shared by weight if
+// ShareUnownedIdle, or else left as idle. IdleByNode and IdleByNodePool
+// partition idle by node or by node pool, rather than by cluster.
+// TraceContext, if set, is used as the parent of the trace spans recorded
+// while aggregating.
 type AllocationAggregationOptions struct {
 	AllocationTotalsStore                 AllocationTotalsStore
 	Filter                                AllocationFilter
 	IdleByNode                            bool
+	IdleByNodePool                        bool
+	IdleOwners                            []*IdleOwner
 	IncludeProportionalAssetResourceCosts bool
 	LabelConfig                           *LabelConfig
 	MergeUnallocated                      bool
@@ -919,8 +1092,11 @@ type AllocationAggregationOptions struct {
 	ShareFuncs                            []AllocationMatchFunc
 	ShareIdle                             string
 	ShareSplit                            string
+	ShareUnownedIdle                      bool
 	SharedHourlyCosts                     map[string]float64
+	SharingPolicies                       []*SharingPolicy
 	SplitIdle                             bool
+	TraceContext                          context.Context
 }
 
 // AggregateBy aggregates the Allocations in the given AllocationSet by the given
@@ -944,7 +1120,8 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 	//        unfiltered results. (See unit tests 5.a,b,c)
 	//     d) generate shared allocation for them given shared overhead, which
 	//        must happen after (2a) and (2b)
-	//     e) if there are shared resources, compute share coefficients
+	//     e) if there are shared resources, compute share coefficients, and
+	//        those of each sharing policy
 	//
 	//  3. Drop any allocation that fails any of the filters
 	//
@@ -958,7 +1135,8 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 	//
 	//  7. Apply idle filtration coefficients from step (2b)
 	//
-	//  8. Distribute shared allocations according to the share coefficients.
+	//  8. Distribute shared allocations according to the share coefficients,
+	//     then those of each sharing policy according to its coefficients.
 	//
 	//  9. If there are external allocations that can be aggregated into
 	//     the output (i.e. they can be used to generate a valid key for
@@ -985,8 +1163,18 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 		options.LabelConfig = NewLabelConfig()
 	}
 
+	traceCtx, span := tracing.Start(
+		options.TraceContext,
+		"AllocationSet.AggregateBy",
+		attribute.StringSlice("aggregateBy", aggregateBy),
+		attribute.Int("allocations", as.Length()),
+		attribute.String("shareIdle", options.ShareIdle),
+		attribute.String("shareSplit", options.ShareSplit),
+	)
+	defer span.End()
+
 	// idleFiltrationCoefficients relies on this being explicitly set
-	if options.ShareIdle != ShareWeighted {
+	if options.ShareIdle != ShareWeighted && options.ShareIdle != ShareOwner {
 		options.ShareIdle = ShareNone
 	}
 
@@ -1003,7 +1191,7 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 	// generateKey for why that makes sense.
 	shouldAggregate := aggregateBy != nil
 	shouldFilter := options.Filter != nil
-	shouldShare := len(options.SharedHourlyCosts) > 0 || len(options.ShareFuncs) > 0
+	shouldShare := len(options.SharedHourlyCosts) > 0 || len(options.ShareFuncs) > 0 || len(options.SharingPolicies) > 0
 	if !shouldAggregate && !shouldFilter && !shouldShare && options.ShareIdle == ShareNone && !options.IncludeProportionalAssetResourceCosts {
 		// There is nothing for AggregateBy to do, so simply return nil
 		return nil
@@ -1037,6 +1225,19 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 		Window: as.Window.Clone(),
 	}
 
+	// policySets will each be shared among aggSet, after shareSet, by the
+	// sharing policy at the same index
+	policySets := make([]*AllocationSet, len(options.SharingPolicies))
+	for i, policy := range options.SharingPolicies {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+
+		policySets[i] = &AllocationSet{
+			Window: as.Window.Clone(),
+		}
+	}
+
 	// (1) Loop and find all of the external, idle, and shared allocations. Add
 	// them to their respective sets, removing them from the set of allocations
 	// to aggregate.
@@ -1052,13 +1253,13 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 		}
 
 		// Idle allocations should be separated into idleSet if they are to be
-		// shared later on. If they are not to be shared, then add them to the
-		// aggSet like any other allocation.
+		// shared, or charged to their owners, later on. If they are not to be
+		// shared, then add them to the aggSet like any other allocation.
 		if alloc.IsIdle() {
 			delete(as.IdleKeys, alloc.Name)
 			delete(as.Allocations, alloc.Name)
 
-			if options.ShareIdle == ShareEven || options.ShareIdle == ShareWeighted {
+			if options.ShareIdle == ShareEven || options.ShareIdle == ShareWeighted || options.ShareIdle == ShareOwner {
 				idleSet.Insert(alloc)
 			} else {
 				aggSet.Insert(alloc)
@@ -1076,14 +1277,44 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 		// Shared allocations must be identified and separated prior to
 		// aggregation and filtering. That is, if any of the ShareFuncs return
 		// true for the allocation, then move it to shareSet.
+		shared := false
 		for _, sf := range options.ShareFuncs {
 			if sf(alloc) {
 				delete(as.IdleKeys, alloc.Name)
 				delete(as.Allocations, alloc.Name)
 				shareSet.Insert(alloc)
+				shared = true
 				break
 			}
 		}
+		if shared {
+			continue
+		}
+
+		// Otherwise, if the allocation matches the shared filter of a
+		// sharing policy, then move it to that policy's set. The first
+		// matching policy wins.
+		for i, policy := range options.SharingPolicies {
+			if policy.Shared.Matches(alloc) {
+				delete(as.Allocations, alloc.Name)
+				policySets[i].Insert(alloc)
+				break
+			}
+		}
+	}
+
+	// idleShareSet contains every allocation which is to be shared, so that
+	// each receives its portion of idle before being shared.
+	idleShareSet := shareSet
+	if len(policySets) > 0 {
+		idleShareSet = &AllocationSet{
+			Window: as.Window.Clone(),
+		}
+		for _, set := range append([]*AllocationSet{shareSet}, policySets...) {
+			for _, alloc := range set.Allocations {
+				idleShareSet.Insert(alloc)
+			}
+		}
 	}
 
 	// It's possible that no more un-shared, non-idle, non-external allocations
@@ -1125,15 +1356,46 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 
 	var err error
 
+	_, idleSpan := tracing.Start(traceCtx, "AllocationSet.AggregateBy.idle", attribute.Int("idleAllocations", idleSet.Length()))
+
+	// If idle is to be charged to its owners, then remove the owned idle
+	// from idleSet, recording the portion of it charged to each allocation.
+	// The remaining idle is shared by weight or, if it is not to be shared,
+	// added to the aggSet like any other allocation.
+	var ownedIdle map[string]*Allocation
+	unsharedIdle := options.ShareIdle == ShareNone
+	if options.ShareIdle == ShareOwner {
+		ownedIdle, err = computeOwnedIdle(options.IdleOwners, as, idleSet)
+		if err != nil {
+			err = fmt.Errorf("error computing owned idle: %s", err)
+			tracing.RecordError(idleSpan, err)
+			idleSpan.End()
+			return err
+		}
+
+		if !options.ShareUnownedIdle {
+			for _, idleAlloc := range idleSet.Allocations {
+				aggSet.Insert(idleAlloc)
+			}
+			idleSet = &AllocationSet{
+				Window: as.Window.Clone(),
+			}
+			unsharedIdle = true
+		}
+	}
+
 	// (2a) If there are idle costs to be shared, compute the coefficients for
 	// sharing them among the non-idle, non-aggregated allocations (including
 	// the shared allocations).
 	var idleCoefficients map[string]map[string]map[string]float64
 	if idleSet.Length() > 0 && options.ShareIdle != ShareNone {
-		idleCoefficients, allocatedTotalsMap, err = computeIdleCoeffs(options, as, shareSet)
+		idleCoefficients, allocatedTotalsMap, err = computeIdleCoeffs(options, as, idleShareSet)
 		if err != nil {
 			log.Warnf("AllocationSet.AggregateBy: compute idle coeff: %s", err)
-			return fmt.Errorf("error computing idle coefficients: %s", err)
+			err = fmt.Errorf("error computing idle coefficients: %s", err)
+			tracing.RecordError(idleSpan, err)
+			idleSpan.End()
+			return err
 		}
 	}
 
@@ -1142,14 +1404,20 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 	if options.IncludeProportionalAssetResourceCosts {
 		var parcCoefficients map[string]map[string]map[string]float64
 		if parcSet.Length() > 0 {
-			parcCoefficients, allocatedTotalsMap, err = computeIdleCoeffs(options, as, shareSet)
+			parcCoefficients, allocatedTotalsMap, err = computeIdleCoeffs(options, as, idleShareSet)
 			if err != nil {
 				log.Warnf("AllocationSet.AggregateBy: compute parc idle coeff: %s", err)
-				return fmt.Errorf("error computing parc coefficients: %s", err)
+				err = fmt.Errorf("error computing parc coefficients: %s", err)
+				tracing.RecordError(idleSpan, err)
+				idleSpan.End()
+				return err
 			}
 		}
 		if parcCoefficients == nil {
-			return fmt.Errorf("cannot include proportional resource costs because parc coefficients are nil")
+			err = fmt.Errorf("cannot include proportional resource costs because parc coefficients are nil")
+			tracing.RecordError(idleSpan, err)
+			idleSpan.End()
+			return err
 		}
 
 		for _, alloc := range as.Allocations {
@@ -1193,12 +1461,16 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 	// Note that this can happen for any field, not just cluster, so we again
 	// need to track this on a per-cluster or per-node, per-allocation, per-resource basis.
 	var idleFiltrationCoefficients map[string]map[string]map[string]float64
-	if shouldFilter && options.ShareIdle == ShareNone {
-		idleFiltrationCoefficients, _, err = computeIdleCoeffs(options, as, shareSet)
+	if shouldFilter && unsharedIdle {
+		idleFiltrationCoefficients, _, err = computeIdleCoeffs(options, as, idleShareSet)
 		if err != nil {
-			return fmt.Errorf("error computing idle filtration coefficients: %s", err)
+			err = fmt.Errorf("error computing idle filtration coefficients: %s", err)
+			tracing.RecordError(idleSpan, err)
+			idleSpan.End()
+			return err
 		}
 	}
+	idleSpan.End()
 
 	// (2d) Convert SharedHourlyCosts to Allocations in the shareSet. This must
 	// come after idle coefficients are computed so that allocations generated
@@ -1230,12 +1502,39 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 	// of the main allocation set. See above for details and an example.
 	var shareCoefficients map[string]float64
 	if shareSet.Length() > 0 {
+		_, shareSpan := tracing.Start(traceCtx, "AllocationSet.AggregateBy.shareCoefficients", attribute.Int("sharedAllocations", shareSet.Length()))
 		shareCoefficients, err = computeShareCoeffs(aggregateBy, options, as)
 		if err != nil {
-			return fmt.Errorf("error computing share coefficients: %s", err)
+			err = fmt.Errorf("error computing share coefficients: %s", err)
+			tracing.RecordError(shareSpan, err)
+			shareSpan.End()
+			return err
+		}
+		shareSpan.End()
+	}
+
+	// Compute the share coefficients of each sharing policy in the same way,
+	// but only over the policy's recipients, by the policy's weight.
+	policyCoefficients := make([]*sharingPolicyCoeffs, len(policySets))
+	for i, policySet := range policySets {
+		if policySet.Length() == 0 {
+			continue
+		}
+
+		policy := options.SharingPolicies[i]
+		_, policySpan := tracing.Start(traceCtx, "AllocationSet.AggregateBy.sharingPolicyCoefficients", attribute.String("policy", policy.Name), attribute.Int("sharedAllocations", policySet.Length()))
+		policyCoefficients[i], err = computeSharingPolicyCoeffs(aggregateBy, options, as, policy)
+		if err != nil {
+			err = fmt.Errorf("error computing sharing policy coefficients: %s", err)
+			tracing.RecordError(policySpan, err)
+			policySpan.End()
+			return err
 		}
+		policySpan.End()
 	}
 
+	_, aggSpan := tracing.Start(traceCtx, "AllocationSet.AggregateBy.aggregate")
+
 	// (3-5) Filter, distribute idle cost, and aggregate (in that order)
 	for _, alloc := range as.Allocations {
 		idleId, err := alloc.getIdleId(options)
@@ -1268,6 +1567,14 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 		// NOTE: if idle allocation is off (i.e. ShareIdle == ShareNone) then
 		// all idle allocations will be in the aggSet at this point, so idleSet
 		// will be empty and we won't enter this block.
+		if charged, ok := ownedIdle[alloc.Name]; ok {
+			alloc.CPUCoreHours += charged.CPUCoreHours
+			alloc.GPUHours += charged.GPUHours
+			alloc.RAMByteHours += charged.RAMByteHours
+			alloc.CPUCost += charged.CPUCost
+			alloc.GPUCost += charged.GPUCost
+			alloc.RAMCost += charged.RAMCost
+		}
 		if idleSet.Length() > 0 {
 			// Distribute idle allocations by coefficient per-idleId, per-allocation
 			for _, idleAlloc := range idleSet.Allocations {
@@ -1276,6 +1583,8 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 				iaidleId, err := idleAlloc.getIdleId(options)
 				if err != nil {
 					log.Errorf("AllocationSet.AggregateBy: Idle allocation is missing idleId %s", idleAlloc.Name)
+					tracing.RecordError(aggSpan, err)
+					aggSpan.End()
 					return err
 				}
 
@@ -1318,13 +1627,15 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 		// perform the actual basic aggregation step.
 		aggSet.Insert(alloc)
 	}
+	aggSpan.SetAttributes(attribute.Int("aggregatedAllocations", aggSet.Length()))
+	aggSpan.End()
 
 	// (6) If idle is shared and resources are shared, it's possible that some
 	// amount of idle cost will be shared with a shared resource. Distribute
 	// that idle allocation, if it exists, to the respective shared allocations
 	// before sharing with the aggregated allocations.
-	if idleSet.Length() > 0 && shareSet.Length() > 0 {
-		for _, alloc := range shareSet.Allocations {
+	if idleSet.Length() > 0 && idleShareSet.Length() > 0 {
+		for _, alloc := range idleShareSet.Allocations {
 			idleId, err := alloc.getIdleId(options)
 			if err != nil {
 				log.DedupedWarningf(3, "AllocationSet.AggregateBy: missing idleId for allocation: %s", alloc.Name)
@@ -1397,6 +1708,7 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 			iaidleId, err := idleAlloc.getIdleId(options)
 			if err != nil {
 				log.Errorf("AllocationSet.AggregateBy: Idle allocation is missing idleId %s", idleAlloc.Name)
+				tracing.RecordError(span, err)
 				return err
 			}
 
@@ -1413,6 +1725,8 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 
 	// (8) Distribute shared allocations according to the share coefficients.
 	if shareSet.Length() > 0 {
+		_, shareSpan := tracing.Start(traceCtx, "AllocationSet.AggregateBy.share", attribute.Int("sharedAllocations", shareSet.Length()))
+
 		for _, alloc := range aggSet.Allocations {
 			for _, sharedAlloc := range shareSet.Allocations {
 				if _, ok := shareCoefficients[alloc.Name]; !ok {
@@ -1425,6 +1739,57 @@ func (as *AllocationSet) AggregateBy(aggregateBy []string, options *AllocationAg
 				alloc.SharedCost += sharedAlloc.TotalCost() * shareCoefficients[alloc.Name]
 			}
 		}
+
+		shareSpan.End()
+	}
+
+	// Then distribute the allocations of each sharing policy according to
+	// its coefficients, recording each share so that it can be explained.
+	// If the policy has no recipients, its allocations are aggregated like
+	// any other, rather than being dropped.
+	for i, policySet := range policySets {
+		if policySet.Length() == 0 {
+			continue
+		}
+
+		policy := options.SharingPolicies[i]
+		spc := policyCoefficients[i]
+
+		if len(spc.coeffs) == 0 {
+			for _, alloc := range policySet.Allocations {
+				if options.Filter != nil && !options.Filter.Matches(alloc) {
+					continue
+				}
+
+				alloc.Name = alloc.generateKey(aggregateBy, options.LabelConfig)
+				aggSet.Insert(alloc)
+			}
+			continue
+		}
+
+		sharedCost := policySet.TotalCost()
+		for _, alloc := range aggSet.Allocations {
+			coeff, ok := spc.coeffs[alloc.Name]
+			if !ok {
+				continue
+			}
+
+			alloc.SharedCost += sharedCost * coeff
+
+			if alloc.SharingPolicyShares == nil {
+				alloc.SharingPolicyShares = SharingPolicyShares{}
+			}
+			alloc.SharingPolicyShares.Add(SharingPolicyShares{
+				policy.Name: {
+					Weight:      policy.Weight,
+					Value:       spc.values[alloc.Name],
+					TotalValue:  spc.totalValue,
+					SharedCost:  sharedCost,
+					Cost:        sharedCost * coeff,
+					Coefficient: coeff,
+				},
+			})
+		}
 	}
 
 	// (9) Aggregate external allocations into aggregated allocations. This may
@@ -1762,8 +2127,9 @@ func deriveProportionalAssetResourceCostsFromIdleCoefficients(idleCoeffs map[str
 	}, nil
 }
 
-// getIdleId returns the providerId or cluster of an Allocation depending on the IdleByNode
-// option in the AllocationAggregationOptions and an error if the respective field is missing
+// getIdleId returns the providerId, node pool or cluster of an Allocation depending on the
+// IdleByNode and IdleByNodePool options in the AllocationAggregationOptions and an error if
+// the respective field is missing
 func (a *Allocation) getIdleId(options *AllocationAggregationOptions) (string, error) {
 	var idleId string
 	if options.IdleByNode {
@@ -1772,6 +2138,13 @@ func (a *Allocation) getIdleId(options *AllocationAggregationOptions) (string, e
 		if idleId == "" {
 			return idleId, fmt.Errorf("ProviderId is not set")
 		}
+	} else if options.IdleByNodePool {
+		// Key allocations to the node pool within the cluster. Allocations
+		// on nodes outside of any node pool share a cluster-wide key.
+		idleId = fmt.Sprintf("%s/%s", a.Properties.Cluster, a.Properties.NodePool)
+		if a.Properties.Cluster == "" {
+			return idleId, fmt.Errorf("ClusterProp is not set")
+		}
 	} else {
 		// key the allocations by cluster id
 		idleId = a.Properties.Cluster
@@ -1802,6 +2175,11 @@ func (a *Allocation) StringProperty(property string) (string, error) {
 			return "", nil
 		}
 		return a.Properties.Node, nil
+	case AllocationNodePoolProp:
+		if a.Properties == nil {
+			return "", nil
+		}
+		return a.Properties.NodePool, nil
 	case AllocationContainerProp:
 		if a.Properties == nil {
 			return "", nil
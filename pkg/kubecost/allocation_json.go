@@ -11,50 +11,64 @@ import (
 // AllocationJSON  exists because there are expected JSON response fields
 // that are calculated values from methods on an annotation
 type AllocationJSON struct {
-	Name                           string                          `json:"name"`
-	Properties                     *AllocationProperties           `json:"properties"`
-	Window                         Window                          `json:"window"`
-	Start                          string                          `json:"start"`
-	End                            string                          `json:"end"`
-	Minutes                        *float64                        `json:"minutes"`
-	CPUCores                       *float64                        `json:"cpuCores"`
-	CPUCoreRequestAverage          *float64                        `json:"cpuCoreRequestAverage"`
-	CPUCoreUsageAverage            *float64                        `json:"cpuCoreUsageAverage"`
-	CPUCoreHours                   *float64                        `json:"cpuCoreHours"`
-	CPUCost                        *float64                        `json:"cpuCost"`
-	CPUCostAdjustment              *float64                        `json:"cpuCostAdjustment"`
-	CPUEfficiency                  *float64                        `json:"cpuEfficiency"`
-	GPUCount                       *float64                        `json:"gpuCount"`
-	GPUHours                       *float64                        `json:"gpuHours"`
-	GPUCost                        *float64                        `json:"gpuCost"`
-	GPUCostAdjustment              *float64                        `json:"gpuCostAdjustment"`
-	NetworkTransferBytes           *float64                        `json:"networkTransferBytes"`
-	NetworkReceiveBytes            *float64                        `json:"networkReceiveBytes"`
-	NetworkCost                    *float64                        `json:"networkCost"`
-	NetworkCrossZoneCost           *float64                        `json:"networkCrossZoneCost"`
-	NetworkCrossRegionCost         *float64                        `json:"networkCrossRegionCost"`
-	NetworkInternetCost            *float64                        `json:"networkInternetCost"`
-	NetworkCostAdjustment          *float64                        `json:"networkCostAdjustment"`
-	LoadBalancerCost               *float64                        `json:"loadBalancerCost"`
-	LoadBalancerCostAdjustment     *float64                        `json:"loadBalancerCostAdjustment"`
-	PVBytes                        *float64                        `json:"pvBytes"`
-	PVByteHours                    *float64                        `json:"pvByteHours"`
-	PVCost                         *float64                        `json:"pvCost"`
-	PVs                            PVAllocations                   `json:"pvs"`
-	PVCostAdjustment               *float64                        `json:"pvCostAdjustment"`
-	RAMBytes                       *float64                        `json:"ramBytes"`
-	RAMByteRequestAverage          *float64                        `json:"ramByteRequestAverage"`
-	RAMByteUsageAverage            *float64                        `json:"ramByteUsageAverage"`
-	RAMByteHours                   *float64                        `json:"ramByteHours"`
-	RAMCost                        *float64                        `json:"ramCost"`
-	RAMCostAdjustment              *float64                        `json:"ramCostAdjustment"`
-	RAMEfficiency                  *float64                        `json:"ramEfficiency"`
-	ExternalCost                   *float64                        `json:"externalCost"`
-	SharedCost                     *float64                        `json:"sharedCost"`
-	TotalCost                      *float64                        `json:"totalCost"`
-	TotalEfficiency                *float64                        `json:"totalEfficiency"`
-	RawAllocationOnly              *RawAllocationOnlyData          `json:"rawAllocationOnly,omitEmpty"`
-	ProportionalAssetResourceCosts *ProportionalAssetResourceCosts `json:"proportionalAssetResourceCosts,omitEmpty"`
+	Name                               string                          `json:"name"`
+	Properties                         *AllocationProperties           `json:"properties"`
+	Window                             Window                          `json:"window"`
+	Start                              string                          `json:"start"`
+	End                                string                          `json:"end"`
+	Minutes                            *float64                        `json:"minutes"`
+	CPUCores                           *float64                        `json:"cpuCores"`
+	CPUCoreRequestAverage              *float64                        `json:"cpuCoreRequestAverage"`
+	CPUCoreUsageAverage                *float64                        `json:"cpuCoreUsageAverage"`
+	CPUCoreHours                       *float64                        `json:"cpuCoreHours"`
+	CPUCost                            *float64                        `json:"cpuCost"`
+	CPUCostAdjustment                  *float64                        `json:"cpuCostAdjustment"`
+	CPUEfficiency                      *float64                        `json:"cpuEfficiency"`
+	GPUCount                           *float64                        `json:"gpuCount"`
+	GPUHours                           *float64                        `json:"gpuHours"`
+	GPUCost                            *float64                        `json:"gpuCost"`
+	GPUCostAdjustment                  *float64                        `json:"gpuCostAdjustment"`
+	GPUUsageAverage                    *float64                        `json:"gpuUsageAverage"`
+	GPUMemoryUsageAverage              *float64                        `json:"gpuMemoryUsageAverage"`
+	GPUEfficiency                      *float64                        `json:"gpuEfficiency"`
+	NetworkTransferBytes               *float64                        `json:"networkTransferBytes"`
+	NetworkReceiveBytes                *float64                        `json:"networkReceiveBytes"`
+	NetworkCost                        *float64                        `json:"networkCost"`
+	NetworkCrossZoneCost               *float64                        `json:"networkCrossZoneCost"`
+	NetworkCrossRegionCost             *float64                        `json:"networkCrossRegionCost"`
+	NetworkInternetCost                *float64                        `json:"networkInternetCost"`
+	NetworkCostAdjustment              *float64                        `json:"networkCostAdjustment"`
+	LoadBalancerCost                   *float64                        `json:"loadBalancerCost"`
+	LoadBalancerCostAdjustment         *float64                        `json:"loadBalancerCostAdjustment"`
+	PVBytes                            *float64                        `json:"pvBytes"`
+	PVByteHours                        *float64                        `json:"pvByteHours"`
+	PVCost                             *float64                        `json:"pvCost"`
+	PVs                                PVAllocations                   `json:"pvs"`
+	PVCostAdjustment                   *float64                        `json:"pvCostAdjustment"`
+	RAMBytes                           *float64                        `json:"ramBytes"`
+	RAMByteRequestAverage              *float64                        `json:"ramByteRequestAverage"`
+	RAMByteUsageAverage                *float64                        `json:"ramByteUsageAverage"`
+	RAMByteHours                       *float64                        `json:"ramByteHours"`
+	RAMCost                            *float64                        `json:"ramCost"`
+	RAMCostAdjustment                  *float64                        `json:"ramCostAdjustment"`
+	RAMEfficiency                      *float64                        `json:"ramEfficiency"`
+	EphemeralStorageBytes              *float64                        `json:"ephemeralStorageBytes"`
+	EphemeralStorageByteRequestAverage *float64                        `json:"ephemeralStorageByteRequestAverage"`
+	EphemeralStorageByteUsageAverage   *float64                        `json:"ephemeralStorageByteUsageAverage"`
+	EphemeralStorageByteHours          *float64                        `json:"ephemeralStorageByteHours"`
+	EphemeralStorageCost               *float64                        `json:"ephemeralStorageCost"`
+	EphemeralStorageCostAdjustment     *float64                        `json:"ephemeralStorageCostAdjustment"`
+	EphemeralStorageEfficiency         *float64                        `json:"ephemeralStorageEfficiency"`
+	SnapshotBytes                      *float64                        `json:"snapshotBytes"`
+	SnapshotByteHours                  *float64                        `json:"snapshotByteHours"`
+	SnapshotCost                       *float64                        `json:"snapshotCost"`
+	ExternalCost                       *float64                        `json:"externalCost"`
+	SharedCost                         *float64                        `json:"sharedCost"`
+	TotalCost                          *float64                        `json:"totalCost"`
+	TotalEfficiency                    *float64                        `json:"totalEfficiency"`
+	RawAllocationOnly                  *RawAllocationOnlyData          `json:"rawAllocationOnly,omitEmpty"`
+	ProportionalAssetResourceCosts     *ProportionalAssetResourceCosts `json:"proportionalAssetResourceCosts,omitEmpty"`
+	SharingPolicyShares                SharingPolicyShares             `json:"sharingPolicyShares,omitempty"`
 }
 
 func (aj *AllocationJSON) BuildFromAllocation(a *Allocation) {
@@ -78,6 +92,9 @@ func (aj *AllocationJSON) BuildFromAllocation(a *Allocation) {
 	aj.GPUHours = formatFloat64ForResponse(a.GPUHours)
 	aj.GPUCost = formatFloat64ForResponse(a.GPUCost)
 	aj.GPUCostAdjustment = formatFloat64ForResponse(a.GPUCostAdjustment)
+	aj.GPUUsageAverage = formatFloat64ForResponse(a.GPUUsageAverage)
+	aj.GPUMemoryUsageAverage = formatFloat64ForResponse(a.GPUMemoryUsageAverage)
+	aj.GPUEfficiency = formatFloat64ForResponse(a.GPUEfficiency())
 	aj.NetworkTransferBytes = formatFloat64ForResponse(a.NetworkTransferBytes)
 	aj.NetworkReceiveBytes = formatFloat64ForResponse(a.NetworkReceiveBytes)
 	aj.NetworkCost = formatFloat64ForResponse(a.NetworkCost)
@@ -99,12 +116,23 @@ func (aj *AllocationJSON) BuildFromAllocation(a *Allocation) {
 	aj.RAMCost = formatFloat64ForResponse(a.RAMCost)
 	aj.RAMCostAdjustment = formatFloat64ForResponse(a.RAMCostAdjustment)
 	aj.RAMEfficiency = formatFloat64ForResponse(a.RAMEfficiency())
+	aj.EphemeralStorageBytes = formatFloat64ForResponse(a.EphemeralStorageBytes())
+	aj.EphemeralStorageByteRequestAverage = formatFloat64ForResponse(a.EphemeralStorageBytesRequestAverage)
+	aj.EphemeralStorageByteUsageAverage = formatFloat64ForResponse(a.EphemeralStorageBytesUsageAverage)
+	aj.EphemeralStorageByteHours = formatFloat64ForResponse(a.EphemeralStorageByteHours)
+	aj.EphemeralStorageCost = formatFloat64ForResponse(a.EphemeralStorageCost)
+	aj.EphemeralStorageCostAdjustment = formatFloat64ForResponse(a.EphemeralStorageCostAdjustment)
+	aj.EphemeralStorageEfficiency = formatFloat64ForResponse(a.EphemeralStorageEfficiency())
+	aj.SnapshotBytes = formatFloat64ForResponse(a.SnapshotBytes())
+	aj.SnapshotByteHours = formatFloat64ForResponse(a.SnapshotByteHours)
+	aj.SnapshotCost = formatFloat64ForResponse(a.SnapshotCost)
 	aj.SharedCost = formatFloat64ForResponse(a.SharedCost)
 	aj.ExternalCost = formatFloat64ForResponse(a.ExternalCost)
 	aj.TotalCost = formatFloat64ForResponse(a.TotalCost())
 	aj.TotalEfficiency = formatFloat64ForResponse(a.TotalEfficiency())
 	aj.RawAllocationOnly = a.RawAllocationOnly
 	aj.ProportionalAssetResourceCosts = &a.ProportionalAssetResourceCosts
+	aj.SharingPolicyShares = a.SharingPolicyShares
 
 }
 
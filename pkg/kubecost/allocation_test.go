@@ -69,6 +69,12 @@ func TestAllocation_Add(t *testing.T) {
 		SharedCost:             2.00,
 		ExternalCost:           1.00,
 		RawAllocationOnly:      &RawAllocationOnlyData{},
+
+		EphemeralStorageByteHours:           4.0 * gib * hrs1,
+		EphemeralStorageBytesRequestAverage: 4.0 * gib,
+		EphemeralStorageBytesUsageAverage:   2.0 * gib,
+		EphemeralStorageCost:                0.50,
+		EphemeralStorageCostAdjustment:      0.25,
 	}
 	a1b := a1.Clone()
 
@@ -94,6 +100,10 @@ func TestAllocation_Add(t *testing.T) {
 		SharedCost:             0.00,
 		ExternalCost:           1.00,
 		RawAllocationOnly:      &RawAllocationOnlyData{},
+
+		EphemeralStorageByteHours:         2.0 * gib * hrs2,
+		EphemeralStorageBytesUsageAverage: 2.0 * gib,
+		EphemeralStorageCost:              0.25,
 	}
 	a2b := a2.Clone()
 
@@ -147,6 +157,12 @@ func TestAllocation_Add(t *testing.T) {
 	if !util.IsApproximately(a1.ExternalCost+a2.ExternalCost, act.ExternalCost) {
 		t.Fatalf("Allocation.Add: expected %f; actual %f", a1.ExternalCost+a2.ExternalCost, act.ExternalCost)
 	}
+	if !util.IsApproximately(a1.EphemeralStorageCost+a2.EphemeralStorageCost, act.EphemeralStorageCost) {
+		t.Fatalf("Allocation.Add: expected %f; actual %f", a1.EphemeralStorageCost+a2.EphemeralStorageCost, act.EphemeralStorageCost)
+	}
+	if !util.IsApproximately(a1.EphemeralStorageCostAdjustment+a2.EphemeralStorageCostAdjustment, act.EphemeralStorageCostAdjustment) {
+		t.Fatalf("Allocation.Add: expected %f; actual %f", a1.EphemeralStorageCostAdjustment+a2.EphemeralStorageCostAdjustment, act.EphemeralStorageCostAdjustment)
+	}
 
 	// ResourceHours should be cumulative
 	if !util.IsApproximately(a1.CPUCoreHours+a2.CPUCoreHours, act.CPUCoreHours) {
@@ -158,6 +174,9 @@ func TestAllocation_Add(t *testing.T) {
 	if !util.IsApproximately(a1.PVByteHours()+a2.PVByteHours(), act.PVByteHours()) {
 		t.Fatalf("Allocation.Add: expected %f; actual %f", a1.PVByteHours()+a2.PVByteHours(), act.PVByteHours())
 	}
+	if !util.IsApproximately(a1.EphemeralStorageByteHours+a2.EphemeralStorageByteHours, act.EphemeralStorageByteHours) {
+		t.Fatalf("Allocation.Add: expected %f; actual %f", a1.EphemeralStorageByteHours+a2.EphemeralStorageByteHours, act.EphemeralStorageByteHours)
+	}
 
 	// Minutes should be the duration between min(starts) and max(ends)
 	if !act.Start.Equal(a1.Start) || !act.End.Equal(a2.End) {
@@ -172,6 +191,8 @@ func TestAllocation_Add(t *testing.T) {
 	// CPU usage = (1.0*12.0 + 1.0*18.0)/(24.0) = 1.25
 	// RAM requests = (8.0*12.0 + 0.0*18.0)/(24.0) = 4.00
 	// RAM usage = (4.0*12.0 + 8.0*18.0)/(24.0) = 8.00
+	// Ephemeral storage requests = (4.0*12.0 + 0.0*18.0)/(24.0) = 2.00
+	// Ephemeral storage usage = (2.0*12.0 + 2.0*18.0)/(24.0) = 2.50
 	if !util.IsApproximately(1.75, act.CPUCoreRequestAverage) {
 		t.Fatalf("Allocation.Add: expected %f; actual %f", 1.75, act.CPUCoreRequestAverage)
 	}
@@ -184,6 +205,12 @@ func TestAllocation_Add(t *testing.T) {
 	if !util.IsApproximately(8.00*gib, act.RAMBytesUsageAverage) {
 		t.Fatalf("Allocation.Add: expected %f; actual %f", 8.00*gib, act.RAMBytesUsageAverage)
 	}
+	if !util.IsApproximately(2.00*gib, act.EphemeralStorageBytesRequestAverage) {
+		t.Fatalf("Allocation.Add: expected %f; actual %f", 2.00*gib, act.EphemeralStorageBytesRequestAverage)
+	}
+	if !util.IsApproximately(2.50*gib, act.EphemeralStorageBytesUsageAverage) {
+		t.Fatalf("Allocation.Add: expected %f; actual %f", 2.50*gib, act.EphemeralStorageBytesUsageAverage)
+	}
 
 	// Efficiency should be computed accurately from new request/usage
 	// CPU efficiency = 1.25/1.75 = 0.7142857
@@ -195,6 +222,9 @@ func TestAllocation_Add(t *testing.T) {
 	if !util.IsApproximately(2.0000000, act.RAMEfficiency()) {
 		t.Fatalf("Allocation.Add: expected %f; actual %f", 2.0000000, act.RAMEfficiency())
 	}
+	if !util.IsApproximately(1.2500000, act.EphemeralStorageEfficiency()) {
+		t.Fatalf("Allocation.Add: expected %f; actual %f", 1.2500000, act.EphemeralStorageEfficiency())
+	}
 	if !util.IsApproximately(1.279690, act.TotalEfficiency()) {
 		t.Fatalf("Allocation.Add: expected %f; actual %f", 1.279690, act.TotalEfficiency())
 	}
@@ -0,0 +1,204 @@
+package kubecost
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/opencost/opencost/pkg/prom"
+)
+
+// AllocationPropertySourceType describes where the value of a custom
+// allocation property is read from.
+type AllocationPropertySourceType string
+
+const (
+	AllocationPropertySourceLabel               AllocationPropertySourceType = "label"
+	AllocationPropertySourceAnnotation          AllocationPropertySourceType = "annotation"
+	AllocationPropertySourceNamespaceLabel      AllocationPropertySourceType = "namespaceLabel"
+	AllocationPropertySourceNamespaceAnnotation AllocationPropertySourceType = "namespaceAnnotation"
+)
+
+// AllocationPropertySource is a single label or annotation from which the
+// value of a custom allocation property may be read.
+type AllocationPropertySource struct {
+	Type AllocationPropertySourceType `json:"type"`
+	Name string                       `json:"name"`
+}
+
+// AllocationPropertyDefinition is a user-defined allocation property, e.g. a
+// business dimension like "costCenter", which is derived from the labels and
+// annotations of an Allocation. Sources are checked in order, and the first
+// with a non-empty value wins. If none match, Default is used.
+type AllocationPropertyDefinition struct {
+	Name    string                      `json:"name"`
+	Sources []*AllocationPropertySource `json:"sources"`
+	Default string                      `json:"default,omitempty"`
+}
+
+// reservedAllocationProps are the built-in properties which custom
+// definitions may not replace. The label-mapped properties (department, team,
+// etc.) are not reserved, so that they can be redefined with fallbacks.
+var reservedAllocationProps = map[string]bool{
+	strings.ToLower(AllocationClusterProp):        true,
+	strings.ToLower(AllocationNodeProp):           true,
+	strings.ToLower(AllocationNodePoolProp):       true,
+	strings.ToLower(AllocationContainerProp):      true,
+	strings.ToLower(AllocationControllerProp):     true,
+	strings.ToLower(AllocationControllerKindProp): true,
+	strings.ToLower(AllocationNamespaceProp):      true,
+	strings.ToLower(AllocationPodProp):            true,
+	strings.ToLower(AllocationProviderIDProp):     true,
+	strings.ToLower(AllocationServiceProp):        true,
+	strings.ToLower(AllocationLabelProp):          true,
+	strings.ToLower(AllocationAnnotationProp):     true,
+	strings.ToLower(AllocationDeploymentProp):     true,
+	strings.ToLower(AllocationStatefulSetProp):    true,
+	strings.ToLower(AllocationDaemonSetProp):      true,
+	strings.ToLower(AllocationJobProp):            true,
+}
+
+var allocationPropertyNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// Validate returns an error if the definition is not usable, e.g. because its
+// name collides with a built-in property.
+func (d *AllocationPropertyDefinition) Validate() error {
+	if d == nil {
+		return fmt.Errorf("nil allocation property definition")
+	}
+
+	if !allocationPropertyNameRegex.MatchString(d.Name) {
+		return fmt.Errorf("invalid allocation property name: %q", d.Name)
+	}
+
+	if reservedAllocationProps[strings.ToLower(d.Name)] {
+		return fmt.Errorf("allocation property name is reserved: %s", d.Name)
+	}
+
+	if len(d.Sources) == 0 {
+		return fmt.Errorf("allocation property %s has no sources", d.Name)
+	}
+
+	for _, source := range d.Sources {
+		if source == nil || source.Name == "" {
+			return fmt.Errorf("allocation property %s has a source without a name", d.Name)
+		}
+
+		switch source.Type {
+		case AllocationPropertySourceLabel, AllocationPropertySourceAnnotation, AllocationPropertySourceNamespaceLabel, AllocationPropertySourceNamespaceAnnotation:
+		default:
+			return fmt.Errorf("allocation property %s has invalid source type: %q", d.Name, source.Type)
+		}
+	}
+
+	return nil
+}
+
+// Value returns the value of the property for the given AllocationProperties,
+// which is the first non-empty source value, or the default. It returns false
+// if there is neither.
+func (d *AllocationPropertyDefinition) Value(p *AllocationProperties) (string, bool) {
+	if d == nil || p == nil {
+		return "", false
+	}
+
+	for _, source := range d.Sources {
+		var values map[string]string
+		switch source.Type {
+		case AllocationPropertySourceLabel:
+			values = p.Labels
+		case AllocationPropertySourceAnnotation:
+			values = p.Annotations
+		case AllocationPropertySourceNamespaceLabel:
+			values = p.NamespaceLabels
+		case AllocationPropertySourceNamespaceAnnotation:
+			values = p.NamespaceAnnotations
+		}
+
+		// Labels and annotations are stored with Prometheus-sanitized names
+		if value := values[prom.SanitizeLabelName(strings.TrimSpace(source.Name))]; value != "" {
+			return value, true
+		}
+	}
+
+	if d.Default != "" {
+		return d.Default, true
+	}
+
+	return "", false
+}
+
+// allocationPropertyDefinitions holds the configured custom allocation
+// properties, keyed by lower-case name, in addition to the order in which
+// they were configured.
+var allocationPropertyDefinitions = struct {
+	lock    sync.RWMutex
+	byName  map[string]*AllocationPropertyDefinition
+	ordered []*AllocationPropertyDefinition
+}{
+	byName: map[string]*AllocationPropertyDefinition{},
+}
+
+// SetAllocationPropertyDefinitions replaces the configured custom allocation
+// properties. If any definition is invalid, the existing definitions are kept
+// and an error is returned.
+func SetAllocationPropertyDefinitions(defs []*AllocationPropertyDefinition) error {
+	byName := make(map[string]*AllocationPropertyDefinition, len(defs))
+	for _, def := range defs {
+		if err := def.Validate(); err != nil {
+			return err
+		}
+
+		name := strings.ToLower(def.Name)
+		if _, ok := byName[name]; ok {
+			return fmt.Errorf("duplicate allocation property: %s", def.Name)
+		}
+		byName[name] = def
+	}
+
+	allocationPropertyDefinitions.lock.Lock()
+	defer allocationPropertyDefinitions.lock.Unlock()
+
+	allocationPropertyDefinitions.byName = byName
+	allocationPropertyDefinitions.ordered = defs
+
+	return nil
+}
+
+// GetAllocationPropertyDefinitions returns the configured custom allocation
+// properties, in the order in which they were configured.
+func GetAllocationPropertyDefinitions() []*AllocationPropertyDefinition {
+	allocationPropertyDefinitions.lock.RLock()
+	defer allocationPropertyDefinitions.lock.RUnlock()
+
+	return allocationPropertyDefinitions.ordered
+}
+
+// GetAllocationPropertyDefinition returns the custom allocation property with
+// the given name, ignoring case, if one is configured.
+func GetAllocationPropertyDefinition(name string) (*AllocationPropertyDefinition, bool) {
+	allocationPropertyDefinitions.lock.RLock()
+	defer allocationPropertyDefinitions.lock.RUnlock()
+
+	def, ok := allocationPropertyDefinitions.byName[strings.ToLower(name)]
+	return def, ok
+}
+
+// ParseAllocationPropertyDefinitions parses a JSON list of custom allocation
+// property definitions, validating each.
+func ParseAllocationPropertyDefinitions(data []byte) ([]*AllocationPropertyDefinition, error) {
+	defs := []*AllocationPropertyDefinition{}
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("parsing allocation property definitions: %w", err)
+	}
+
+	for _, def := range defs {
+		if err := def.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return defs, nil
+}
@@ -0,0 +1,228 @@
+package kubecost
+
+import (
+	"testing"
+)
+
+func setTestAllocationPropertyDefinitions(t *testing.T, defs ...*AllocationPropertyDefinition) {
+	t.Helper()
+
+	err := SetAllocationPropertyDefinitions(defs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	t.Cleanup(func() {
+		SetAllocationPropertyDefinitions(nil)
+	})
+}
+
+var costCenterProperty = &AllocationPropertyDefinition{
+	Name: "costCenter",
+	Sources: []*AllocationPropertySource{
+		{Type: AllocationPropertySourceLabel, Name: "cost_center"},
+		{Type: AllocationPropertySourceAnnotation, Name: "cost-center"},
+		{Type: AllocationPropertySourceNamespaceLabel, Name: "cost_center"},
+	},
+	Default: "shared",
+}
+
+func TestParseAllocationPropertyDefinitions(t *testing.T) {
+	cases := map[string]struct {
+		input       string
+		expectError bool
+	}{
+		"valid": {
+			input: `[{"name": "costCenter", "sources": [{"type": "label", "name": "cost_center"}, {"type": "namespaceAnnotation", "name": "cost-center"}], "default": "shared"}]`,
+		},
+		"override label-mapped property": {
+			input: `[{"name": "team", "sources": [{"type": "label", "name": "team"}, {"type": "namespaceLabel", "name": "team"}]}]`,
+		},
+		"reserved name": {
+			input:       `[{"name": "namespace", "sources": [{"type": "label", "name": "ns"}]}]`,
+			expectError: true,
+		},
+		"invalid name": {
+			input:       `[{"name": "cost:center", "sources": [{"type": "label", "name": "cost_center"}]}]`,
+			expectError: true,
+		},
+		"no sources": {
+			input:       `[{"name": "costCenter"}]`,
+			expectError: true,
+		},
+		"invalid source type": {
+			input:       `[{"name": "costCenter", "sources": [{"type": "nodeLabel", "name": "cost_center"}]}]`,
+			expectError: true,
+		},
+		"invalid json": {
+			input:       `{"name": "costCenter"}`,
+			expectError: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseAllocationPropertyDefinitions([]byte(c.input))
+			if c.expectError && err == nil {
+				t.Fatalf("expected error; got nil")
+			}
+			if !c.expectError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestSetAllocationPropertyDefinitions_Duplicate(t *testing.T) {
+	err := SetAllocationPropertyDefinitions([]*AllocationPropertyDefinition{
+		costCenterProperty,
+		{Name: "COSTCENTER", Sources: costCenterProperty.Sources},
+	})
+	if err == nil {
+		t.Fatalf("expected error for duplicate property")
+	}
+
+	if _, ok := GetAllocationPropertyDefinition("costCenter"); ok {
+		t.Fatalf("expected definitions to be unchanged after error")
+	}
+}
+
+func TestAllocationPropertyDefinition_Value(t *testing.T) {
+	cases := map[string]struct {
+		props    *AllocationProperties
+		expected string
+	}{
+		"label": {
+			props: &AllocationProperties{
+				Labels:          map[string]string{"cost_center": "eng"},
+				Annotations:     map[string]string{"cost_center": "ops"},
+				NamespaceLabels: map[string]string{"cost_center": "finance"},
+			},
+			expected: "eng",
+		},
+		"annotation fallback": {
+			props: &AllocationProperties{
+				Labels:      map[string]string{"cost_center": ""},
+				Annotations: map[string]string{"cost_center": "ops"},
+			},
+			expected: "ops",
+		},
+		"namespace label fallback": {
+			props: &AllocationProperties{
+				NamespaceLabels: map[string]string{"cost_center": "finance"},
+			},
+			expected: "finance",
+		},
+		"default": {
+			props:    &AllocationProperties{},
+			expected: "shared",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			value, ok := costCenterProperty.Value(c.props)
+			if !ok || value != c.expected {
+				t.Fatalf("expected %s; got %s (%t)", c.expected, value, ok)
+			}
+		})
+	}
+
+	noDefault := &AllocationPropertyDefinition{Name: "costCenter", Sources: costCenterProperty.Sources}
+	if value, ok := noDefault.Value(&AllocationProperties{}); ok {
+		t.Fatalf("expected no value; got %s", value)
+	}
+}
+
+func TestAllocationProperties_GenerateKey_CustomProperties(t *testing.T) {
+	setTestAllocationPropertyDefinitions(t,
+		costCenterProperty,
+		&AllocationPropertyDefinition{
+			Name: "team",
+			Sources: []*AllocationPropertySource{
+				{Type: AllocationPropertySourceLabel, Name: "squad"},
+			},
+		},
+	)
+
+	prop, err := ParseProperty("costcenter")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if prop != "costCenter" {
+		t.Fatalf("expected costCenter; got %s", prop)
+	}
+
+	props := &AllocationProperties{
+		Namespace:       "kubecost",
+		Labels:          map[string]string{"team": "platform"},
+		NamespaceLabels: map[string]string{"cost_center": "finance"},
+	}
+
+	// The redefined team property no longer reads the "team" label
+	key := props.GenerateKey([]string{AllocationNamespaceProp, prop, AllocationTeamProp}, nil)
+	if expected := "kubecost/finance/" + UnallocatedSuffix; key != expected {
+		t.Fatalf("expected %s; got %s", expected, key)
+	}
+}
+
+func TestAllocationFilterCondition_CustomProperty(t *testing.T) {
+	setTestAllocationPropertyDefinitions(t, costCenterProperty)
+
+	finance := &Allocation{Properties: &AllocationProperties{NamespaceLabels: map[string]string{"cost_center": "finance"}}}
+	shared := &Allocation{Properties: &AllocationProperties{}}
+
+	cases := map[string]struct {
+		filter   AllocationFilter
+		expected []bool
+	}{
+		"equals": {
+			filter:   AllocationFilterCondition{Field: FilterCustomProperty, Key: "costCenter", Op: FilterEquals, Value: "finance"},
+			expected: []bool{true, false},
+		},
+		"equals default": {
+			filter:   AllocationFilterCondition{Field: FilterCustomProperty, Key: "costCenter", Op: FilterEquals, Value: "shared"},
+			expected: []bool{false, true},
+		},
+		"unknown property": {
+			filter:   AllocationFilterCondition{Field: FilterCustomProperty, Key: "unknown", Op: FilterEquals, Value: UnallocatedSuffix},
+			expected: []bool{true, true},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			for i, a := range []*Allocation{finance, shared} {
+				if actual := c.filter.Matches(a); actual != c.expected[i] {
+					t.Fatalf("allocation %d: expected %t; got %t", i, c.expected[i], actual)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocationProperties_BinaryEncoding_NamespaceLabels(t *testing.T) {
+	props := &AllocationProperties{
+		Namespace:            "kubecost",
+		Labels:               map[string]string{"app": "cost-analyzer", "cost_center": "finance"},
+		NamespaceLabels:      map[string]string{"cost_center": "finance"},
+		NamespaceAnnotations: map[string]string{"owner": "platform"},
+	}
+
+	bs, err := props.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	decoded := &AllocationProperties{}
+	err = decoded.UnmarshalBinary(bs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !props.Equal(decoded) {
+		t.Fatalf("expected %s; got %s", props, decoded)
+	}
+	if decoded.NamespaceAnnotations["owner"] != "platform" {
+		t.Fatalf("expected namespace annotations to be decoded; got %v", decoded.NamespaceAnnotations)
+	}
+}
@@ -0,0 +1,381 @@
+package kubecost
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/opencost/opencost/pkg/util"
+)
+
+// AllocationExplanation is an audit trail of the cost of a single aggregated
+// Allocation, breaking it down into the unaggregated Allocations from which
+// it was aggregated, and the prices, usage, and coefficients from which each
+// of their costs was derived.
+type AllocationExplanation struct {
+	Status        AuditStatus                  `json:"status"`
+	Key           string                       `json:"key"`
+	Aggregate     []string                     `json:"aggregate"`
+	Window        Window                       `json:"window"`
+	ShareIdle     string                       `json:"shareIdle"`
+	Allocation    *Allocation                  `json:"allocation"`
+	Items         []*AllocationExplanationItem `json:"items"`
+	MissingValues []*AuditMissingValue         `json:"missingValues"`
+}
+
+// AllocationExplanationItem explains the cost of one of the unaggregated
+// Allocations of an AllocationExplanation.
+type AllocationExplanationItem struct {
+	Name             string                            `json:"name"`
+	Properties       *AllocationProperties             `json:"properties"`
+	NodePricing      *AllocationNodePricingExplanation `json:"nodePricing"`
+	CPU              *AllocationResourceExplanation    `json:"cpu"`
+	RAM              *AllocationResourceExplanation    `json:"ram"`
+	GPU              *AllocationResourceExplanation    `json:"gpu"`
+	Idle             *AllocationIdleExplanation        `json:"idle"`
+	Adjustments      map[string]float64                `json:"adjustments"`
+	PVs              []*AllocationPVExplanation        `json:"pvs"`
+	Network          *AllocationNetworkExplanation     `json:"network"`
+	LoadBalancerCost float64                           `json:"loadBalancerCost"`
+	TotalCost        float64                           `json:"totalCost"`
+}
+
+// AllocationNodePricingExplanation records the prices of the node on which an
+// Allocation ran, after any discount, and where those prices came from.
+type AllocationNodePricingExplanation struct {
+	Node             string  `json:"node"`
+	ProviderID       string  `json:"providerID"`
+	NodeType         string  `json:"nodeType"`
+	Preemptible      bool    `json:"preemptible"`
+	CPUHourlyRate    float64 `json:"cpuHourlyRate"`
+	RAMGiBHourlyRate float64 `json:"ramGiBHourlyRate"`
+	GPUHourlyRate    float64 `json:"gpuHourlyRate"`
+	Discount         float64 `json:"discount"`
+	PricingSource    string  `json:"pricingSource"`
+}
+
+// AllocationResourceExplanation records the amount of a resource for which an
+// Allocation was charged, and at what rate. Basis is "request" if the average
+// request was at least the average usage, and "usage" otherwise, since an
+// Allocation is charged for the greater of the two. Cost compares the cost
+// expected from Hours and HourlyRate with the actual cost.
+type AllocationResourceExplanation struct {
+	RequestAverage float64           `json:"requestAverage"`
+	UsageAverage   float64           `json:"usageAverage"`
+	Basis          string            `json:"basis"`
+	Hours          float64           `json:"hours"`
+	HourlyRate     float64           `json:"hourlyRate"`
+	Cost           *AuditFloatResult `json:"cost"`
+}
+
+// AllocationIdleExplanation records the idle cost shared with an Allocation,
+// by resource, and the inputs of ComputeIdleCoefficients.
+type AllocationIdleExplanation struct {
+	IdleID    string                                        `json:"idleId"`
+	Resources map[string]*AllocationIdleResourceExplanation `json:"resources"`
+}
+
+// AllocationIdleResourceExplanation records that an Allocation with Cost, of
+// the TotalCost of all Allocations with the same idle ID, received
+// Coefficient of the IdleCost, which is SharedIdleCost.
+type AllocationIdleResourceExplanation struct {
+	Cost           float64 `json:"cost"`
+	TotalCost      float64 `json:"totalCost"`
+	Coefficient    float64 `json:"coefficient"`
+	IdleCost       float64 `json:"idleCost"`
+	SharedIdleCost float64 `json:"sharedIdleCost"`
+}
+
+// AllocationPVExplanation records the cost of a PV attached to an Allocation.
+type AllocationPVExplanation struct {
+	Cluster       string  `json:"cluster"`
+	Name          string  `json:"name"`
+	ByteHours     float64 `json:"byteHours"`
+	GiBHourlyRate float64 `json:"gibHourlyRate"`
+	Cost          float64 `json:"cost"`
+}
+
+// AllocationNetworkExplanation records the network cost of an Allocation.
+type AllocationNetworkExplanation struct {
+	TransferBytes   float64 `json:"transferBytes"`
+	ReceiveBytes    float64 `json:"receiveBytes"`
+	CrossZoneCost   float64 `json:"crossZoneCost"`
+	CrossRegionCost float64 `json:"crossRegionCost"`
+	InternetCost    float64 `json:"internetCost"`
+	Cost            float64 `json:"cost"`
+}
+
+// NewAllocationExplanation explains the Allocation with the given key after
+// aggregating a clone of the given set by the given properties and options.
+// If aggregateBy is empty, the key is the name of an unaggregated Allocation.
+// If no Allocation has the key, the explanation has no items. Node pricing is
+// not known to the AllocationSet, so must be applied to each item with
+// ApplyNodePricing.
+func NewAllocationExplanation(as *AllocationSet, key string, aggregateBy []string, options *AllocationAggregationOptions) (*AllocationExplanation, error) {
+	if as == nil {
+		return nil, fmt.Errorf("cannot explain nil AllocationSet")
+	}
+
+	if options == nil {
+		options = &AllocationAggregationOptions{}
+	}
+
+	// Unaggregated Allocations are named by these properties
+	if len(aggregateBy) == 0 {
+		aggregateBy = []string{AllocationClusterProp, AllocationNodeProp, AllocationNamespaceProp, AllocationPodProp, AllocationContainerProp}
+	}
+
+	labelConfig := options.LabelConfig
+	if labelConfig == nil {
+		labelConfig = NewLabelConfig()
+	}
+
+	ae := &AllocationExplanation{
+		Status:        PassedStatus,
+		Key:           key,
+		Aggregate:     aggregateBy,
+		Window:        as.Window.Clone(),
+		ShareIdle:     options.ShareIdle,
+		Items:         []*AllocationExplanationItem{},
+		MissingValues: []*AuditMissingValue{},
+	}
+	if ae.ShareIdle != ShareWeighted && ae.ShareIdle != ShareEven {
+		ae.ShareIdle = ShareNone
+	}
+
+	// Totals by idle ID are the inputs of the idle coefficients
+	allocTotals := ComputeAllocationTotals(as, AllocationClusterProp)
+	if options.IdleByNode {
+		allocTotals = ComputeAllocationTotals(as, AllocationNodeProp)
+	} else if options.IdleByNodePool {
+		allocTotals = ComputeAllocationTotals(as, AllocationNodePoolProp)
+	}
+
+	idleCosts := map[string]*Allocation{}
+	for _, idleAlloc := range as.IdleAllocations() {
+		idleId, err := idleAlloc.getIdleId(options)
+		if err != nil {
+			continue
+		}
+		if _, ok := idleCosts[idleId]; !ok {
+			idleCosts[idleId] = &Allocation{}
+		}
+		idleCosts[idleId].CPUCost += idleAlloc.CPUCost
+		idleCosts[idleId].GPUCost += idleAlloc.GPUCost
+		idleCosts[idleId].RAMCost += idleAlloc.RAMCost
+	}
+
+	for _, alloc := range as.Allocations {
+		if alloc.IsIdle() || alloc.IsExternal() {
+			continue
+		}
+
+		if alloc.generateKey(aggregateBy, labelConfig) != key {
+			continue
+		}
+
+		item := newAllocationExplanationItem(alloc)
+
+		if idleId, err := alloc.getIdleId(options); err == nil {
+			item.Idle = explainAllocationIdle(ae.ShareIdle, idleId, alloc, allocTotals, idleCosts[idleId])
+		}
+
+		ae.Items = append(ae.Items, item)
+	}
+
+	sort.Slice(ae.Items, func(i, j int) bool {
+		return ae.Items[i].Name < ae.Items[j].Name
+	})
+
+	if len(ae.Items) == 0 {
+		ae.addMissingValue("allocation", key)
+		return ae, nil
+	}
+
+	// Aggregate a clone of the set to find the final Allocation, including
+	// any idle and shared costs. AggregateBy modifies its options, so copy.
+	opts := *options
+	aggSet := as.Clone()
+	err := aggSet.AggregateBy(aggregateBy, &opts)
+	if err != nil {
+		return nil, fmt.Errorf("aggregating: %w", err)
+	}
+	ae.Allocation = aggSet.Get(key)
+	if ae.Allocation == nil {
+		ae.addMissingValue("aggregated allocation", key)
+	}
+
+	return ae, nil
+}
+
+// ApplyNodePricing records the given node pricing for the item, and compares
+// the costs expected from the pricing with the actual costs.
+func (aei *AllocationExplanationItem) ApplyNodePricing(np *AllocationNodePricingExplanation) {
+	if aei == nil || np == nil {
+		return
+	}
+
+	aei.NodePricing = np
+
+	aei.CPU.HourlyRate = np.CPUHourlyRate
+	aei.CPU.Cost.Expected = aei.CPU.Hours * np.CPUHourlyRate
+
+	aei.RAM.HourlyRate = np.RAMGiBHourlyRate
+	aei.RAM.Cost.Expected = aei.RAM.Hours * np.RAMGiBHourlyRate
+
+	aei.GPU.HourlyRate = np.GPUHourlyRate
+	aei.GPU.Cost.Expected = aei.GPU.Hours * np.GPUHourlyRate
+}
+
+// Finalize sets the status of the explanation, given the node pricing of each
+// item: Passed if every cost matches the cost expected from the pricing, and
+// Warning otherwise, or if any value is missing.
+func (ae *AllocationExplanation) Finalize() {
+	for _, item := range ae.Items {
+		if item.NodePricing == nil {
+			ae.addMissingValue("node pricing", item.Name)
+			continue
+		}
+
+		for _, res := range []*AllocationResourceExplanation{item.CPU, item.RAM, item.GPU} {
+			if !util.IsApproximately(res.Cost.Expected, res.Cost.Actual) {
+				ae.Status = WarningStatus
+			}
+		}
+	}
+
+	if len(ae.MissingValues) > 0 {
+		ae.Status = WarningStatus
+	}
+}
+
+func (ae *AllocationExplanation) addMissingValue(description, key string) {
+	ae.MissingValues = append(ae.MissingValues, &AuditMissingValue{
+		Description: description,
+		Key:         key,
+	})
+	ae.Status = WarningStatus
+}
+
+func newAllocationExplanationItem(alloc *Allocation) *AllocationExplanationItem {
+	item := &AllocationExplanationItem{
+		Name:       alloc.Name,
+		Properties: alloc.Properties.Clone(),
+		CPU: &AllocationResourceExplanation{
+			RequestAverage: alloc.CPUCoreRequestAverage,
+			UsageAverage:   alloc.CPUCoreUsageAverage,
+			Basis:          resourceBasis(alloc.CPUCoreRequestAverage, alloc.CPUCoreUsageAverage),
+			Hours:          alloc.CPUCoreHours,
+			Cost:           &AuditFloatResult{Actual: alloc.CPUCost},
+		},
+		RAM: &AllocationResourceExplanation{
+			RequestAverage: alloc.RAMBytesRequestAverage,
+			UsageAverage:   alloc.RAMBytesUsageAverage,
+			Basis:          resourceBasis(alloc.RAMBytesRequestAverage, alloc.RAMBytesUsageAverage),
+			Hours:          alloc.RAMByteHours / 1024 / 1024 / 1024,
+			Cost:           &AuditFloatResult{Actual: alloc.RAMCost},
+		},
+		GPU: &AllocationResourceExplanation{
+			Basis: "request",
+			Hours: alloc.GPUHours,
+			Cost:  &AuditFloatResult{Actual: alloc.GPUCost},
+		},
+		Adjustments: map[string]float64{
+			"cpu":          alloc.CPUCostAdjustment,
+			"gpu":          alloc.GPUCostAdjustment,
+			"ram":          alloc.RAMCostAdjustment,
+			"pv":           alloc.PVCostAdjustment,
+			"network":      alloc.NetworkCostAdjustment,
+			"loadBalancer": alloc.LoadBalancerCostAdjustment,
+		},
+		PVs: []*AllocationPVExplanation{},
+		Network: &AllocationNetworkExplanation{
+			TransferBytes:   alloc.NetworkTransferBytes,
+			ReceiveBytes:    alloc.NetworkReceiveBytes,
+			CrossZoneCost:   alloc.NetworkCrossZoneCost,
+			CrossRegionCost: alloc.NetworkCrossRegionCost,
+			InternetCost:    alloc.NetworkInternetCost,
+			Cost:            alloc.NetworkCost,
+		},
+		LoadBalancerCost: alloc.LoadBalancerCost,
+		TotalCost:        alloc.TotalCost(),
+	}
+
+	for pvKey, pv := range alloc.PVs {
+		pve := &AllocationPVExplanation{
+			Cluster:   pvKey.Cluster,
+			Name:      pvKey.Name,
+			ByteHours: pv.ByteHours,
+			Cost:      pv.Cost,
+		}
+		if pv.ByteHours > 0 {
+			pve.GiBHourlyRate = pv.Cost / (pv.ByteHours / 1024 / 1024 / 1024)
+		}
+		item.PVs = append(item.PVs, pve)
+	}
+	sort.Slice(item.PVs, func(i, j int) bool {
+		return item.PVs[i].Name < item.PVs[j].Name
+	})
+
+	return item
+}
+
+func resourceBasis(request, usage float64) string {
+	if request >= usage {
+		return "request"
+	}
+	return "usage"
+}
+
+// explainAllocationIdle explains the idle cost shared with the given
+// Allocation. AllocationTotals are keyed in the same way as idle IDs, i.e. by
+// cluster, or by cluster and node. AggregateBy always weights the idle
+// coefficients, which also filter idle when it is not shared, so they are
+// explained regardless of shareIdle; only the SharedIdleCost depends on it.
+func explainAllocationIdle(shareIdle, idleId string, alloc *Allocation, allocTotals map[string]*AllocationTotals, idle *Allocation) *AllocationIdleExplanation {
+	cpuCoeff, gpuCoeff, ramCoeff := ComputeIdleCoefficients(ShareWeighted, idleId, alloc.CPUTotalCost(), alloc.GPUTotalCost(), alloc.RAMTotalCost(), allocTotals)
+
+	if idle == nil {
+		idle = &Allocation{}
+	}
+
+	shared := 1.0
+	if shareIdle == ShareNone {
+		shared = 0.0
+	}
+
+	aie := &AllocationIdleExplanation{
+		IdleID:    idleId,
+		Resources: map[string]*AllocationIdleResourceExplanation{},
+	}
+
+	var totals *AllocationTotals
+	if t, ok := allocTotals[idleId]; ok {
+		totals = t
+	} else {
+		totals = &AllocationTotals{}
+	}
+
+	aie.Resources["cpu"] = &AllocationIdleResourceExplanation{
+		Cost:           alloc.CPUTotalCost(),
+		TotalCost:      totals.TotalCPUCost(),
+		Coefficient:    cpuCoeff,
+		IdleCost:       idle.CPUCost,
+		SharedIdleCost: idle.CPUCost * cpuCoeff * shared,
+	}
+	aie.Resources["gpu"] = &AllocationIdleResourceExplanation{
+		Cost:           alloc.GPUTotalCost(),
+		TotalCost:      totals.TotalGPUCost(),
+		Coefficient:    gpuCoeff,
+		IdleCost:       idle.GPUCost,
+		SharedIdleCost: idle.GPUCost * gpuCoeff * shared,
+	}
+	aie.Resources["ram"] = &AllocationIdleResourceExplanation{
+		Cost:           alloc.RAMTotalCost(),
+		TotalCost:      totals.TotalRAMCost(),
+		Coefficient:    ramCoeff,
+		IdleCost:       idle.RAMCost,
+		SharedIdleCost: idle.RAMCost * ramCoeff * shared,
+	}
+
+	return aie
+}
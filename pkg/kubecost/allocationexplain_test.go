@@ -0,0 +1,141 @@
+package kubecost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/util"
+)
+
+func TestNewAllocationExplanation(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	as := GenerateMockAllocationSetClusterIdle(start)
+	numAllocs := as.Length()
+
+	ae, err := NewAllocationExplanation(as, "namespace1", []string{AllocationNamespaceProp}, &AllocationAggregationOptions{ShareIdle: ShareWeighted})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if as.Length() != numAllocs {
+		t.Fatalf("expected set to be unmodified with %d allocations; got %d", numAllocs, as.Length())
+	}
+
+	if len(ae.Items) != 3 {
+		t.Fatalf("expected 3 items; got %d", len(ae.Items))
+	}
+	if ae.Allocation == nil || ae.Allocation.Name != "namespace1" {
+		t.Fatalf("expected aggregated allocation namespace1; got %v", ae.Allocation)
+	}
+
+	// The cost of the aggregated allocation is the cost of each item, plus
+	// the idle cost shared with it
+	cost := 0.0
+	for _, item := range ae.Items {
+		if item.Properties.Namespace != "namespace1" {
+			t.Fatalf("expected item in namespace1; got %s", item.Name)
+		}
+
+		cost += item.TotalCost
+		for _, res := range item.Idle.Resources {
+			if res.TotalCost > 0 && !util.IsApproximately(res.Coefficient, res.Cost/res.TotalCost) {
+				t.Fatalf("%s: expected coefficient %f; got %f", item.Name, res.Cost/res.TotalCost, res.Coefficient)
+			}
+			cost += res.SharedIdleCost
+		}
+	}
+	if !util.IsApproximately(cost, ae.Allocation.TotalCost()) {
+		t.Fatalf("expected items to total %f; got %f", ae.Allocation.TotalCost(), cost)
+	}
+
+	// Without node pricing, the explanation is incomplete
+	ae.Finalize()
+	if ae.Status != WarningStatus || len(ae.MissingValues) != len(ae.Items) {
+		t.Fatalf("expected missing node pricing; got %s with %d missing values", ae.Status, len(ae.MissingValues))
+	}
+}
+
+func TestNewAllocationExplanation_ShareNone(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	as := GenerateMockAllocationSetClusterIdle(start)
+
+	ae, err := NewAllocationExplanation(as, "namespace1", []string{AllocationNamespaceProp}, &AllocationAggregationOptions{ShareIdle: ShareNone})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(ae.Items) != 3 {
+		t.Fatalf("expected 3 items; got %d", len(ae.Items))
+	}
+
+	// Idle is not shared, but the coefficients are still weighted
+	for _, item := range ae.Items {
+		for name, res := range item.Idle.Resources {
+			if res.TotalCost > 0 && !util.IsApproximately(res.Coefficient, res.Cost/res.TotalCost) {
+				t.Fatalf("%s %s: expected coefficient %f; got %f", item.Name, name, res.Cost/res.TotalCost, res.Coefficient)
+			}
+			if res.SharedIdleCost != 0 {
+				t.Fatalf("%s %s: expected no shared idle cost; got %f", item.Name, name, res.SharedIdleCost)
+			}
+		}
+	}
+}
+
+func TestAllocationExplanation_ApplyNodePricing(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	alloc := NewMockUnitAllocation("", start, day, nil)
+	alloc.Name = "cluster1/node1/namespace1/pod1/container1"
+	alloc.CPUCoreHours = 24
+	alloc.CPUCost = 12
+	alloc.CPUCoreUsageAverage = 2
+	alloc.RAMByteHours = 48 * 1024 * 1024 * 1024
+	alloc.RAMCost = 4.8
+	alloc.GPUHours = 0
+	alloc.GPUCost = 0
+
+	as := NewAllocationSet(start, start.Add(day), alloc)
+
+	ae, err := NewAllocationExplanation(as, alloc.Name, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(ae.Items) != 1 {
+		t.Fatalf("expected 1 item; got %d", len(ae.Items))
+	}
+
+	item := ae.Items[0]
+	if item.CPU.Basis != "usage" || item.RAM.Basis != "request" {
+		t.Fatalf("expected CPU usage and RAM request; got %s and %s", item.CPU.Basis, item.RAM.Basis)
+	}
+
+	item.ApplyNodePricing(&AllocationNodePricingExplanation{
+		Node:             "node1",
+		CPUHourlyRate:    0.5,
+		RAMGiBHourlyRate: 0.1,
+		PricingSource:    "prometheus",
+	})
+	ae.Finalize()
+	if ae.Status != PassedStatus {
+		t.Fatalf("expected %s; got %s: %v", PassedStatus, ae.Status, ae.MissingValues)
+	}
+
+	// A cost which does not match the pricing is a warning
+	item.CPU.Cost.Actual = 13
+	ae.Finalize()
+	if ae.Status != WarningStatus {
+		t.Fatalf("expected %s; got %s", WarningStatus, ae.Status)
+	}
+}
+
+func TestNewAllocationExplanation_NotFound(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	as := GenerateMockAllocationSet(start)
+
+	ae, err := NewAllocationExplanation(as, "namespace4", []string{AllocationNamespaceProp}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(ae.Items) != 0 || ae.Status != WarningStatus {
+		t.Fatalf("expected no items; got %d (%s)", len(ae.Items), ae.Status)
+	}
+}
@@ -2,7 +2,9 @@ package kubecost
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/opencost/opencost/pkg/log"
@@ -33,6 +35,37 @@ const (
 	FilterAlias      = "alias"
 
 	FilterServices = "services"
+
+	// FilterCustomProperty filters on the value of the custom allocation
+	// property named by the Key, e.g. "costCenter".
+	FilterCustomProperty = "property"
+
+	// Numeric fields, which can only be used with the numeric comparison
+	// operators (FilterGreaterThan, etc.)
+	//
+	// Like every other filter, numeric filters are matched against each
+	// Allocation before it is aggregated, so e.g. totalcost GreaterThan "100"
+	// keeps the containers costing more than 100, not the aggregated rows.
+
+	FilterTotalCost              = "totalcost"
+	FilterCPUCost                = "cpucost"
+	FilterGPUCost                = "gpucost"
+	FilterRAMCost                = "ramcost"
+	FilterPVCost                 = "pvcost"
+	FilterNetworkCost            = "networkcost"
+	FilterLoadBalancerCost       = "loadbalancercost"
+	FilterSharedCost             = "sharedcost"
+	FilterExternalCost           = "externalcost"
+	FilterCPUCoreHours           = "cpucorehours"
+	FilterRAMByteHours           = "rambytehours"
+	FilterGPUHours               = "gpuhours"
+	FilterCPUCoreRequestAverage  = "cpucorerequestaverage"
+	FilterCPUCoreUsageAverage    = "cpucoreusageaverage"
+	FilterRAMBytesRequestAverage = "rambytesrequestaverage"
+	FilterRAMBytesUsageAverage   = "rambytesusageaverage"
+	FilterCPUEfficiency          = "cpuefficiency"
+	FilterRAMEfficiency          = "ramefficiency"
+	FilterTotalEfficiency        = "totalefficiency"
 )
 
 // FilterOp is an enum that represents operations that can be performed
@@ -69,8 +102,59 @@ const (
 	// of Equals.
 	// ["kube-system", "abc123"] ContainsPrefix ["kube"] = true
 	FilterContainsPrefix = "containsprefix"
+
+	// FilterRegex matches strings against the regular expression in the
+	// filter Value. When comparing with a field represented by an array/slice,
+	// any matching element passes.
+	// "team-a" Regex "^team-.*" = true
+	FilterRegex = "regex"
+
+	// FilterNotRegex is the negation of FilterRegex. Missing keyed values,
+	// e.g. an absent label, pass.
+	FilterNotRegex = "notregex"
+
+	// FilterExists passes if the field has a value. For keyed fields, like
+	// labels, this is whether the key is present, regardless of its value.
+	// The filter Value is ignored.
+	FilterExists = "exists"
+
+	// FilterNotExists is the negation of FilterExists
+	FilterNotExists = "notexists"
+
+	// Numeric comparisons of a numeric field with the number in the filter
+	// Value.
+	// totalcost GreaterThan "100"
+	FilterGreaterThan         = "greaterthan"
+	FilterGreaterThanOrEquals = "greaterthanorequals"
+	FilterLessThan            = "lessthan"
+	FilterLessThanOrEquals    = "lessthanorequals"
 )
 
+// IsNumericFilterField returns true if the field holds a numeric value which
+// must be compared using the numeric filter operators.
+func IsNumericFilterField(field FilterField) bool {
+	switch field {
+	case FilterTotalCost, FilterCPUCost, FilterGPUCost, FilterRAMCost,
+		FilterPVCost, FilterNetworkCost, FilterLoadBalancerCost,
+		FilterSharedCost, FilterExternalCost, FilterCPUCoreHours,
+		FilterRAMByteHours, FilterGPUHours, FilterCPUCoreRequestAverage,
+		FilterCPUCoreUsageAverage, FilterRAMBytesRequestAverage,
+		FilterRAMBytesUsageAverage, FilterCPUEfficiency, FilterRAMEfficiency,
+		FilterTotalEfficiency:
+		return true
+	}
+	return false
+}
+
+// IsNumericFilterOp returns true if the op is a numeric comparison.
+func IsNumericFilterOp(op FilterOp) bool {
+	switch op {
+	case FilterGreaterThan, FilterGreaterThanOrEquals, FilterLessThan, FilterLessThanOrEquals:
+		return true
+	}
+	return false
+}
+
 // AllocationFilter represents anything that can be used to filter an
 // Allocation.
 //
@@ -124,6 +208,43 @@ type AllocationFilterCondition struct {
 	// Value is for _all_ filters. A filter of 'namespace:"kubecost"' has
 	// Value="kubecost"
 	Value string
+
+	// regex and number are the Value of regex conditions and numeric
+	// comparisons, compiled once by NewAllocationFilterCondition rather than
+	// each time the condition is matched.
+	regex  *regexp.Regexp
+	number *float64
+}
+
+// NewAllocationFilterCondition builds a condition, compiling the Value of a
+// FilterRegex or FilterNotRegex condition, or parsing that of a numeric
+// comparison. Conditions with these ops must be built here, as they do not
+// match otherwise. An error is returned if the Value is not a valid regular
+// expression or number, respectively.
+func NewAllocationFilterCondition(field FilterField, key string, op FilterOp, value string) (AllocationFilterCondition, error) {
+	afc := AllocationFilterCondition{
+		Field: field,
+		Op:    op,
+		Key:   key,
+		Value: value,
+	}
+
+	switch {
+	case op == FilterRegex || op == FilterNotRegex:
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return afc, fmt.Errorf("invalid regex '%s': %w", value, err)
+		}
+		afc.regex = re
+	case IsNumericFilterOp(op):
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return afc, fmt.Errorf("invalid number '%s' for field %s", value, field)
+		}
+		afc.number = &number
+	}
+
+	return afc, nil
 }
 
 func (afc AllocationFilterCondition) String() string {
@@ -142,7 +263,8 @@ func (filter AllocationFilterCondition) Flattened() AllocationFilter {
 
 func (left AllocationFilterCondition) Equals(right AllocationFilter) bool {
 	if rightAFC, ok := right.(AllocationFilterCondition); ok {
-		return left == rightAFC
+		// The compiled values follow from the others
+		return left.Field == rightAFC.Field && left.Op == rightAFC.Op && left.Key == rightAFC.Key && left.Value == rightAFC.Value
 	}
 	return false
 }
@@ -319,6 +441,10 @@ func (filter AllocationFilterCondition) Matches(a *Allocation) bool {
 		return false
 	}
 
+	if IsNumericFilterField(filter.Field) {
+		return filter.matchesNumeric(a)
+	}
+
 	// The Allocation's value for the field to compare
 	// We use an interface{} so this can contain the services []string slice
 	var valueToCompare interface{}
@@ -374,6 +500,19 @@ func (filter AllocationFilterCondition) Matches(a *Allocation) bool {
 		}
 	case FilterServices:
 		valueToCompare = a.Properties.Services
+	case FilterCustomProperty:
+		def, ok := GetAllocationPropertyDefinition(filter.Key)
+		if !ok {
+			toCompareMissing = true
+			break
+		}
+
+		val, ok := def.Value(a.Properties)
+		if !ok {
+			toCompareMissing = true
+		} else {
+			valueToCompare = val
+		}
 	default:
 		log.Errorf("Allocation Filter: Unhandled filter field. This is a filter implementation error and requires immediate patching. Field: %s", filter.Field)
 		return false
@@ -479,6 +618,22 @@ func (filter AllocationFilterCondition) Matches(a *Allocation) bool {
 		}
 
 		return false
+	case FilterRegex:
+		if toCompareMissing {
+			return false
+		}
+
+		return filter.matchesRegex(valueToCompare)
+	case FilterNotRegex:
+		if toCompareMissing {
+			return true
+		}
+
+		return !filter.matchesRegex(valueToCompare)
+	case FilterExists:
+		return filter.valueExists(valueToCompare, toCompareMissing)
+	case FilterNotExists:
+		return !filter.valueExists(valueToCompare, toCompareMissing)
 	default:
 		log.Errorf("Allocation Filter: Unhandled filter op. This is a filter implementation error and requires immediate patching. Op: %s", filter.Op)
 		return false
@@ -487,6 +642,119 @@ func (filter AllocationFilterCondition) Matches(a *Allocation) bool {
 	return false
 }
 
+// matchesRegex returns true if the value, or any element of a slice value,
+// matches the filter's expression.
+func (filter AllocationFilterCondition) matchesRegex(valueToCompare interface{}) bool {
+	if filter.regex == nil {
+		log.Errorf("Allocation Filter: regex condition on %s was not built with NewAllocationFilterCondition. This is a filter implementation error and requires immediate patching.", filter.Field)
+		return false
+	}
+
+	switch v := valueToCompare.(type) {
+	case string:
+		return filter.regex.MatchString(v)
+	case []string:
+		for _, s := range v {
+			if filter.regex.MatchString(s) {
+				return true
+			}
+		}
+		return false
+	default:
+		log.Warnf("Allocation Filter: invalid '%s' call for field with unsupported type", filter.Op)
+		return false
+	}
+}
+
+// valueExists returns true if a keyed value is present, even if empty, or an
+// unkeyed value is non-empty.
+func (filter AllocationFilterCondition) valueExists(valueToCompare interface{}, toCompareMissing bool) bool {
+	if toCompareMissing {
+		return false
+	}
+	if filter.Key != "" {
+		return true
+	}
+
+	switch v := valueToCompare.(type) {
+	case string:
+		return v != ""
+	case []string:
+		return len(v) > 0
+	default:
+		return valueToCompare != nil
+	}
+}
+
+// matchesNumeric compares the value of a numeric field with the number in the
+// filter's Value.
+func (filter AllocationFilterCondition) matchesNumeric(a *Allocation) bool {
+	if filter.number == nil {
+		log.Errorf("Allocation Filter: numeric condition on %s was not built with NewAllocationFilterCondition. This is a filter implementation error and requires immediate patching.", filter.Field)
+		return false
+	}
+	threshold := *filter.number
+
+	var value float64
+	switch filter.Field {
+	case FilterTotalCost:
+		value = a.TotalCost()
+	case FilterCPUCost:
+		value = a.CPUTotalCost()
+	case FilterGPUCost:
+		value = a.GPUTotalCost()
+	case FilterRAMCost:
+		value = a.RAMTotalCost()
+	case FilterPVCost:
+		value = a.PVTotalCost()
+	case FilterNetworkCost:
+		value = a.NetworkTotalCost()
+	case FilterLoadBalancerCost:
+		value = a.LBTotalCost()
+	case FilterSharedCost:
+		value = a.SharedTotalCost()
+	case FilterExternalCost:
+		value = a.ExternalCost
+	case FilterCPUCoreHours:
+		value = a.CPUCoreHours
+	case FilterRAMByteHours:
+		value = a.RAMByteHours
+	case FilterGPUHours:
+		value = a.GPUHours
+	case FilterCPUCoreRequestAverage:
+		value = a.CPUCoreRequestAverage
+	case FilterCPUCoreUsageAverage:
+		value = a.CPUCoreUsageAverage
+	case FilterRAMBytesRequestAverage:
+		value = a.RAMBytesRequestAverage
+	case FilterRAMBytesUsageAverage:
+		value = a.RAMBytesUsageAverage
+	case FilterCPUEfficiency:
+		value = a.CPUEfficiency()
+	case FilterRAMEfficiency:
+		value = a.RAMEfficiency()
+	case FilterTotalEfficiency:
+		value = a.TotalEfficiency()
+	default:
+		log.Errorf("Allocation Filter: Unhandled numeric filter field. This is a filter implementation error and requires immediate patching. Field: %s", filter.Field)
+		return false
+	}
+
+	switch filter.Op {
+	case FilterGreaterThan:
+		return value > threshold
+	case FilterGreaterThanOrEquals:
+		return value >= threshold
+	case FilterLessThan:
+		return value < threshold
+	case FilterLessThanOrEquals:
+		return value <= threshold
+	default:
+		log.Warnf("Allocation Filter: invalid '%s' call for numeric field %s", filter.Op, filter.Field)
+		return false
+	}
+}
+
 func (and AllocationFilterAnd) Matches(a *Allocation) bool {
 	filters := and.Filters
 	if len(filters) == 0 {
@@ -1781,3 +1781,39 @@ func Test_AllocationFilter_Equals(t *testing.T) {
 		})
 	}
 }
+
+func Test_NewAllocationFilterCondition(t *testing.T) {
+	alloc := &Allocation{
+		Properties: &AllocationProperties{Namespace: "team-a"},
+		CPUCost:    10,
+	}
+
+	regex, err := NewAllocationFilterCondition(FilterNamespace, "", FilterRegex, "^team-")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !regex.Matches(alloc) {
+		t.Fatalf("expected regex condition to match")
+	}
+
+	numeric, err := NewAllocationFilterCondition(FilterCPUCost, "", FilterGreaterThan, "5")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !numeric.Matches(alloc) {
+		t.Fatalf("expected numeric condition to match")
+	}
+
+	// Conditions built separately from the same values are equal
+	other, _ := NewAllocationFilterCondition(FilterNamespace, "", FilterRegex, "^team-")
+	if !regex.Equals(other) {
+		t.Fatalf("expected conditions built from the same values to be equal")
+	}
+
+	if _, err := NewAllocationFilterCondition(FilterNamespace, "", FilterRegex, "("); err == nil {
+		t.Fatalf("expected error for invalid regex")
+	}
+	if _, err := NewAllocationFilterCondition(FilterCPUCost, "", FilterLessThan, "ten"); err == nil {
+		t.Fatalf("expected error for invalid number")
+	}
+}
@@ -13,6 +13,7 @@ const (
 	AllocationNilProp            string = ""
 	AllocationClusterProp        string = "cluster"
 	AllocationNodeProp           string = "node"
+	AllocationNodePoolProp       string = "nodepool"
 	AllocationContainerProp      string = "container"
 	AllocationControllerProp     string = "controller"
 	AllocationControllerKindProp string = "controllerKind"
@@ -39,6 +40,8 @@ func ParseProperty(text string) (string, error) {
 		return AllocationClusterProp, nil
 	case "node":
 		return AllocationNodeProp, nil
+	case "nodepool":
+		return AllocationNodePoolProp, nil
 	case "container":
 		return AllocationContainerProp, nil
 	case "controller":
@@ -77,6 +80,10 @@ func ParseProperty(text string) (string, error) {
 		return AllocationTeamProp, nil
 	}
 
+	if def, ok := GetAllocationPropertyDefinition(strings.TrimSpace(text)); ok {
+		return def.Name, nil
+	}
+
 	if strings.HasPrefix(text, "label:") {
 		label := prom.SanitizeLabelName(strings.TrimSpace(strings.TrimPrefix(text, "label:")))
 		return fmt.Sprintf("label:%s", label), nil
@@ -103,6 +110,14 @@ type AllocationProperties struct {
 	ProviderID     string                `json:"providerID,omitempty"`
 	Labels         AllocationLabels      `json:"labels,omitempty"`
 	Annotations    AllocationAnnotations `json:"annotations,omitempty"`
+	// NamespaceLabels and NamespaceAnnotations are also merged into Labels
+	// and Annotations, but are kept separately so that custom properties can
+	// fall back to them explicitly.
+	NamespaceLabels      AllocationLabels      `json:"namespaceLabels,omitempty"`      // @bingen:field[version=17]
+	NamespaceAnnotations AllocationAnnotations `json:"namespaceAnnotations,omitempty"` // @bingen:field[version=17]
+	// NodePool is the node pool, or node group, of the allocation's node. See
+	// NodePoolFromLabels.
+	NodePool string `json:"nodePool,omitempty"` // @bingen:field[version=22]
 }
 
 // AllocationLabels is a schema-free mapping of key/value pairs that can be
@@ -127,6 +142,7 @@ func (p *AllocationProperties) Clone() *AllocationProperties {
 	clone.Namespace = p.Namespace
 	clone.Pod = p.Pod
 	clone.ProviderID = p.ProviderID
+	clone.NodePool = p.NodePool
 
 	var services []string
 	services = append(services, p.Services...)
@@ -144,6 +160,22 @@ func (p *AllocationProperties) Clone() *AllocationProperties {
 	}
 	clone.Annotations = annotations
 
+	if p.NamespaceLabels != nil {
+		namespaceLabels := make(map[string]string, len(p.NamespaceLabels))
+		for k, v := range p.NamespaceLabels {
+			namespaceLabels[k] = v
+		}
+		clone.NamespaceLabels = namespaceLabels
+	}
+
+	if p.NamespaceAnnotations != nil {
+		namespaceAnnotations := make(map[string]string, len(p.NamespaceAnnotations))
+		for k, v := range p.NamespaceAnnotations {
+			namespaceAnnotations[k] = v
+		}
+		clone.NamespaceAnnotations = namespaceAnnotations
+	}
+
 	return clone
 }
 
@@ -184,6 +216,10 @@ func (p *AllocationProperties) Equal(that *AllocationProperties) bool {
 		return false
 	}
 
+	if p.NodePool != that.NodePool {
+		return false
+	}
+
 	pLabels := p.Labels
 	thatLabels := that.Labels
 	if len(pLabels) == len(thatLabels) {
@@ -210,6 +246,14 @@ func (p *AllocationProperties) Equal(that *AllocationProperties) bool {
 		return false
 	}
 
+	if !stringMapsEqual(p.NamespaceLabels, that.NamespaceLabels) {
+		return false
+	}
+
+	if !stringMapsEqual(p.NamespaceAnnotations, that.NamespaceAnnotations) {
+		return false
+	}
+
 	pServices := p.Services
 	thatServices := that.Services
 	if len(pServices) == len(thatServices) {
@@ -228,6 +272,20 @@ func (p *AllocationProperties) Equal(that *AllocationProperties) bool {
 	return true
 }
 
+func stringMapsEqual(m1, m2 map[string]string) bool {
+	if len(m1) != len(m2) {
+		return false
+	}
+
+	for k, v1 := range m1 {
+		if v2, ok := m2[k]; !ok || v1 != v2 {
+			return false
+		}
+	}
+
+	return true
+}
+
 // GenerateKey generates a string that represents the key by which the
 // AllocationProperties should be aggregated, given the properties defined by
 // the aggregateBy parameter and the given label configuration.
@@ -245,11 +303,29 @@ func (p *AllocationProperties) GenerateKey(aggregateBy []string, labelConfig *La
 	names := []string{}
 
 	for _, agg := range aggregateBy {
+		// Custom properties take precedence, as they may redefine the
+		// label-mapped properties, e.g. department.
+		if def, ok := GetAllocationPropertyDefinition(agg); ok {
+			if value, ok := def.Value(p); ok {
+				names = append(names, value)
+			} else {
+				names = append(names, UnallocatedSuffix)
+			}
+			continue
+		}
+
 		switch true {
 		case agg == AllocationClusterProp:
 			names = append(names, p.Cluster)
 		case agg == AllocationNodeProp:
 			names = append(names, p.Node)
+		case agg == AllocationNodePoolProp:
+			nodePool := p.NodePool
+			if nodePool == "" {
+				// Indicate that allocation's node is not in a node pool
+				nodePool = UnallocatedSuffix
+			}
+			names = append(names, nodePool)
 		case agg == AllocationNamespaceProp:
 			names = append(names, p.Namespace)
 		case agg == AllocationControllerKindProp:
@@ -447,6 +523,9 @@ func (p *AllocationProperties) Intersection(that *AllocationProperties) *Allocat
 	if p.ProviderID == that.ProviderID {
 		intersectionProps.ProviderID = p.ProviderID
 	}
+	if p.NodePool == that.NodePool {
+		intersectionProps.NodePool = p.NodePool
+	}
 	return intersectionProps
 }
 
@@ -489,6 +568,10 @@ func (p *AllocationProperties) String() string {
 		strs = append(strs, "ProviderID:"+p.ProviderID)
 	}
 
+	if p.NodePool != "" {
+		strs = append(strs, "NodePool:"+p.NodePool)
+	}
+
 	if len(p.Services) > 0 {
 		strs = append(strs, "Services:"+strings.Join(p.Services, ";"))
 	}
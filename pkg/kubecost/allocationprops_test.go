@@ -16,6 +16,16 @@ func TestGenerateKey(t *testing.T) {
 		labelConfig     *LabelConfig
 		expected        string
 	}{
+		"aggregate by nodepool": {
+			aggregate:       []string{"cluster", "nodepool"},
+			allocationProps: &AllocationProperties{Cluster: "cluster1", NodePool: "pool-1"},
+			expected:        "cluster1/pool-1",
+		},
+		"aggregate by nodepool without node pool": {
+			aggregate:       []string{"nodepool"},
+			allocationProps: &AllocationProperties{Cluster: "cluster1"},
+			expected:        UnallocatedSuffix,
+		},
 		"aggregate by owner without owner labels": {
 			aggregate: []string{"owner"},
 			allocationProps: &AllocationProperties{
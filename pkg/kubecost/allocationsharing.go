@@ -0,0 +1,239 @@
+package kubecost
+
+import (
+	"fmt"
+
+	"github.com/opencost/opencost/pkg/log"
+)
+
+// SharingWeightSource describes how the cost shared by a SharingPolicy is
+// weighted among its recipients.
+type SharingWeightSource string
+
+const (
+	// SharingWeightCost weights recipients by their total, non-shared cost.
+	SharingWeightCost SharingWeightSource = "cost"
+
+	// SharingWeightCPU weights recipients by their CPU core-hours.
+	SharingWeightCPU SharingWeightSource = "cpu"
+
+	// SharingWeightRAM weights recipients by their RAM byte-hours.
+	SharingWeightRAM SharingWeightSource = "ram"
+
+	// SharingWeightNetwork weights recipients by the network bytes they
+	// transmitted and received.
+	SharingWeightNetwork SharingWeightSource = "network"
+
+	// SharingWeightQuery weights recipients by per-namespace weights, e.g.
+	// request counts, which are provided by the policy's NamespaceWeights.
+	SharingWeightQuery SharingWeightSource = "query"
+)
+
+// SharingWeightFunc returns a weight per namespace for the given window.
+type SharingWeightFunc func(window Window) (map[string]float64, error)
+
+// SharingPolicy shares the cost of the allocations matching Shared among the
+// allocations matching Recipients, in proportion to the given weight. A nil
+// Recipients filter shares with every allocation. For SharingWeightQuery, each
+// namespace's weight is divided among its allocations by cost, or evenly if
+// they have none.
+type SharingPolicy struct {
+	Name             string
+	Shared           AllocationFilter
+	Recipients       AllocationFilter
+	Weight           SharingWeightSource
+	NamespaceWeights SharingWeightFunc
+}
+
+// Validate returns an error if the policy cannot be applied.
+func (sp *SharingPolicy) Validate() error {
+	if sp == nil {
+		return fmt.Errorf("nil sharing policy")
+	}
+
+	if sp.Name == "" {
+		return fmt.Errorf("sharing policy has no name")
+	}
+
+	if sp.Shared == nil {
+		return fmt.Errorf("sharing policy %s has no shared filter", sp.Name)
+	}
+
+	switch sp.Weight {
+	case SharingWeightCost, SharingWeightCPU, SharingWeightRAM, SharingWeightNetwork:
+	case SharingWeightQuery:
+		if sp.NamespaceWeights == nil {
+			return fmt.Errorf("sharing policy %s has no namespace weights", sp.Name)
+		}
+	default:
+		return fmt.Errorf("sharing policy %s has invalid weight: %q", sp.Name, sp.Weight)
+	}
+
+	return nil
+}
+
+func (sp *SharingPolicy) isRecipient(alloc *Allocation) bool {
+	return sp.Recipients == nil || sp.Recipients.Matches(alloc)
+}
+
+// weight returns the weight of the given allocation for the given policy,
+// which excludes the per-namespace query weight.
+func (sp *SharingPolicy) weight(alloc *Allocation) float64 {
+	switch sp.Weight {
+	case SharingWeightCPU:
+		return alloc.CPUCoreHours
+	case SharingWeightRAM:
+		return alloc.RAMByteHours
+	case SharingWeightNetwork:
+		return alloc.NetworkTransferBytes + alloc.NetworkReceiveBytes
+	default:
+		return alloc.TotalCost() - alloc.SharedCost
+	}
+}
+
+// SharingPolicyShare explains the cost distributed to an Allocation by a
+// SharingPolicy: the Allocation's weight was Value of TotalValue, so it
+// received Coefficient of SharedCost, which is Cost. Across multiple windows,
+// each value is summed, so that the share is weighted by time.
+type SharingPolicyShare struct {
+	Weight      SharingWeightSource `json:"weight"`
+	Value       float64             `json:"value"`
+	TotalValue  float64             `json:"totalValue"`
+	SharedCost  float64             `json:"sharedCost"`
+	Cost        float64             `json:"cost"`
+	Coefficient float64             `json:"coefficient"`
+}
+
+// SharingPolicyShares are the SharingPolicyShare of an Allocation, keyed by
+// policy name.
+type SharingPolicyShares map[string]*SharingPolicyShare
+
+// Clone returns a deep copy of the shares.
+func (sps SharingPolicyShares) Clone() SharingPolicyShares {
+	if sps == nil {
+		return nil
+	}
+
+	clone := make(SharingPolicyShares, len(sps))
+	for name, share := range sps {
+		s := *share
+		clone[name] = &s
+	}
+
+	return clone
+}
+
+// Add adds the given shares into the receiver, summing the values of
+// matching policies.
+func (sps SharingPolicyShares) Add(that SharingPolicyShares) {
+	for name, share := range that {
+		curr, ok := sps[name]
+		if !ok {
+			s := *share
+			sps[name] = &s
+			continue
+		}
+
+		curr.Value += share.Value
+		curr.TotalValue += share.TotalValue
+		curr.SharedCost += share.SharedCost
+		curr.Cost += share.Cost
+		curr.Coefficient = 0.0
+		if curr.TotalValue > 0 {
+			curr.Coefficient = curr.Value / curr.TotalValue
+		}
+	}
+}
+
+// sharingPolicyCoeffs are the share coefficients of a single SharingPolicy,
+// keyed by post-aggregation name, in addition to the values from which they
+// were computed.
+type sharingPolicyCoeffs struct {
+	coeffs     map[string]float64
+	values     map[string]float64
+	totalValue float64
+}
+
+// computeSharingPolicyCoeffs computes the share coefficients of the given
+// policy over the non-shared allocations of the set, in the same manner as
+// computeShareCoeffs.
+func computeSharingPolicyCoeffs(aggregateBy []string, options *AllocationAggregationOptions, as *AllocationSet, policy *SharingPolicy) (*sharingPolicyCoeffs, error) {
+	var nsWeights map[string]float64
+	nsTotals := map[string]float64{}
+	nsCounts := map[string]int{}
+	if policy.Weight == SharingWeightQuery {
+		var err error
+		nsWeights, err = policy.NamespaceWeights(as.Window)
+		if err != nil {
+			return nil, fmt.Errorf("sharing policy %s: %w", policy.Name, err)
+		}
+	}
+
+	recipients := []*Allocation{}
+	for _, alloc := range as.Allocations {
+		if alloc.IsIdle() || alloc.IsUnmounted() {
+			continue
+		}
+
+		if !policy.isRecipient(alloc) {
+			continue
+		}
+
+		recipients = append(recipients, alloc)
+
+		if nsWeights != nil {
+			nsTotals[alloc.namespace()] += policy.weight(alloc)
+			nsCounts[alloc.namespace()]++
+		}
+	}
+
+	spc := &sharingPolicyCoeffs{
+		coeffs: map[string]float64{},
+		values: map[string]float64{},
+	}
+
+	for _, alloc := range recipients {
+		value := policy.weight(alloc)
+		if nsWeights != nil {
+			ns := alloc.namespace()
+			if nsTotals[ns] > 0 {
+				value = nsWeights[ns] * value / nsTotals[ns]
+			} else {
+				value = nsWeights[ns] / float64(nsCounts[ns])
+			}
+		}
+
+		if value <= 0 {
+			continue
+		}
+
+		// As in computeShareCoeffs, filtered allocations contribute to a
+		// "__filtered__" bin, which will be dropped.
+		name := alloc.generateKey(aggregateBy, options.LabelConfig)
+		if options.Filter != nil && !options.Filter.Matches(alloc) {
+			name = "__filtered__"
+		}
+
+		spc.values[name] += value
+		spc.totalValue += value
+	}
+
+	if spc.totalValue <= 0 {
+		log.Warnf("AllocationSet.AggregateBy: sharing policy %s has no recipients with %s weight", policy.Name, policy.Weight)
+		return spc, nil
+	}
+
+	for name, value := range spc.values {
+		spc.coeffs[name] = value / spc.totalValue
+	}
+
+	return spc, nil
+}
+
+func (a *Allocation) namespace() string {
+	if a.Properties == nil {
+		return ""
+	}
+
+	return a.Properties.Namespace
+}
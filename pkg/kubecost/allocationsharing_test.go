@@ -0,0 +1,234 @@
+package kubecost
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/util"
+)
+
+func generateSharingPolicyAllocationSet(start time.Time) *AllocationSet {
+	newAlloc := func(namespace, pod string, cpuCoreHours float64) *Allocation {
+		alloc := NewMockUnitAllocation(fmt.Sprintf("cluster1/%s/%s/container1", namespace, pod), start, day, &AllocationProperties{
+			Cluster:   "cluster1",
+			Node:      "node1",
+			Namespace: namespace,
+			Pod:       pod,
+			Container: "container1",
+		})
+		alloc.CPUCoreHours = cpuCoreHours
+		return alloc
+	}
+
+	return NewAllocationSet(start, start.Add(day),
+		newAlloc("ingress", "pod1", 1),
+		newAlloc("namespace1", "pod1", 3),
+		newAlloc("namespace1", "pod2", 3),
+		newAlloc("namespace2", "pod1", 2),
+		newAlloc("namespace3", "pod1", 0),
+	)
+}
+
+func TestAllocationSet_AggregateBy_SharingPolicies(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	ingress := AllocationFilterCondition{Field: FilterNamespace, Op: FilterEquals, Value: "ingress"}
+
+	cases := map[string]struct {
+		policy   *SharingPolicy
+		filter   AllocationFilter
+		expected map[string]float64
+	}{
+		"cpu": {
+			policy: &SharingPolicy{Name: "ingress", Shared: ingress, Weight: SharingWeightCPU},
+			expected: map[string]float64{
+				"namespace1": 0.75,
+				"namespace2": 0.25,
+			},
+		},
+		"cost": {
+			policy: &SharingPolicy{Name: "ingress", Shared: ingress, Weight: SharingWeightCost},
+			expected: map[string]float64{
+				"namespace1": 0.50,
+				"namespace2": 0.25,
+				"namespace3": 0.25,
+			},
+		},
+		"recipients": {
+			policy: &SharingPolicy{
+				Name:       "ingress",
+				Shared:     ingress,
+				Recipients: AllocationFilterCondition{Field: FilterNamespace, Op: FilterNotEquals, Value: "namespace1"},
+				Weight:     SharingWeightCost,
+			},
+			expected: map[string]float64{
+				"namespace2": 0.50,
+				"namespace3": 0.50,
+			},
+		},
+		"query": {
+			policy: &SharingPolicy{
+				Name:   "ingress",
+				Shared: ingress,
+				Weight: SharingWeightQuery,
+				NamespaceWeights: func(window Window) (map[string]float64, error) {
+					return map[string]float64{"namespace1": 1, "namespace3": 4}, nil
+				},
+			},
+			expected: map[string]float64{
+				"namespace1": 0.20,
+				"namespace3": 0.80,
+			},
+		},
+		"filtered": {
+			policy: &SharingPolicy{Name: "ingress", Shared: ingress, Weight: SharingWeightCPU},
+			filter: AllocationFilterCondition{Field: FilterNamespace, Op: FilterEquals, Value: "namespace2"},
+			expected: map[string]float64{
+				"namespace2": 0.25,
+			},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			as := generateSharingPolicyAllocationSet(start)
+			sharedCost := as.Get("cluster1/ingress/pod1/container1").TotalCost()
+
+			err := as.AggregateBy([]string{AllocationNamespaceProp}, &AllocationAggregationOptions{
+				Filter:          c.filter,
+				SharingPolicies: []*SharingPolicy{c.policy},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if as.Get("ingress") != nil {
+				t.Fatalf("expected ingress to be shared")
+			}
+
+			for ns, alloc := range as.Allocations {
+				coeff, ok := c.expected[ns]
+				if !ok {
+					if alloc.SharedCost != 0.0 || alloc.SharingPolicyShares != nil {
+						t.Fatalf("%s: expected no shared cost; got %f", ns, alloc.SharedCost)
+					}
+					continue
+				}
+
+				if !util.IsApproximately(alloc.SharedCost, sharedCost*coeff) {
+					t.Fatalf("%s: expected shared cost %f; got %f", ns, sharedCost*coeff, alloc.SharedCost)
+				}
+
+				share, ok := alloc.SharingPolicyShares[c.policy.Name]
+				if !ok {
+					t.Fatalf("%s: missing sharing policy share", ns)
+				}
+				if !util.IsApproximately(share.Coefficient, coeff) || !util.IsApproximately(share.Value/share.TotalValue, coeff) {
+					t.Fatalf("%s: expected coefficient %f; got %f (%f/%f)", ns, coeff, share.Coefficient, share.Value, share.TotalValue)
+				}
+				if !util.IsApproximately(share.SharedCost, sharedCost) || !util.IsApproximately(share.Cost, alloc.SharedCost) {
+					t.Fatalf("%s: expected %f of %f; got %f of %f", ns, alloc.SharedCost, sharedCost, share.Cost, share.SharedCost)
+				}
+			}
+		})
+	}
+}
+
+func TestAllocationSet_AggregateBy_SharingPolicies_NoRecipients(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	as := generateSharingPolicyAllocationSet(start)
+	totalCost := as.TotalCost()
+
+	err := as.AggregateBy([]string{AllocationNamespaceProp}, &AllocationAggregationOptions{
+		SharingPolicies: []*SharingPolicy{{
+			Name:   "ingress",
+			Shared: AllocationFilterCondition{Field: FilterNamespace, Op: FilterEquals, Value: "ingress"},
+			Weight: SharingWeightQuery,
+			NamespaceWeights: func(window Window) (map[string]float64, error) {
+				return map[string]float64{}, nil
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The shared allocation is not dropped if no one can receive its cost
+	if as.Get("ingress") == nil {
+		t.Fatalf("expected unshared allocation ingress")
+	}
+	if !util.IsApproximately(as.TotalCost(), totalCost) {
+		t.Fatalf("expected total cost %f; got %f", totalCost, as.TotalCost())
+	}
+}
+
+func TestAllocationSet_AggregateBy_SharingPolicies_Idle(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	as := GenerateMockAllocationSetClusterIdle(start)
+	totalCost := as.TotalCost()
+
+	err := as.AggregateBy([]string{AllocationNamespaceProp}, &AllocationAggregationOptions{
+		ShareIdle: ShareWeighted,
+		SharingPolicies: []*SharingPolicy{{
+			Name:   "namespace1",
+			Shared: AllocationFilterCondition{Field: FilterNamespace, Op: FilterEquals, Value: "namespace1"},
+			Weight: SharingWeightRAM,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if as.Get("namespace1") != nil {
+		t.Fatalf("expected namespace1 to be shared")
+	}
+
+	// The idle cost of the shared allocations is shared with them
+	if !util.IsApproximately(as.TotalCost(), totalCost) {
+		t.Fatalf("expected total cost %f; got %f", totalCost, as.TotalCost())
+	}
+}
+
+func TestSharingPolicy_Validate(t *testing.T) {
+	shared := AllocationFilterCondition{Field: FilterNamespace, Op: FilterEquals, Value: "ingress"}
+
+	cases := map[string]struct {
+		policy      *SharingPolicy
+		expectError bool
+	}{
+		"valid": {
+			policy: &SharingPolicy{Name: "ingress", Shared: shared, Weight: SharingWeightNetwork},
+		},
+		"no name": {
+			policy:      &SharingPolicy{Shared: shared, Weight: SharingWeightCost},
+			expectError: true,
+		},
+		"no shared filter": {
+			policy:      &SharingPolicy{Name: "ingress", Weight: SharingWeightCost},
+			expectError: true,
+		},
+		"invalid weight": {
+			policy:      &SharingPolicy{Name: "ingress", Shared: shared, Weight: "gpu"},
+			expectError: true,
+		},
+		"query without weights": {
+			policy:      &SharingPolicy{Name: "ingress", Shared: shared, Weight: SharingWeightQuery},
+			expectError: true,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := c.policy.Validate()
+			if c.expectError && err == nil {
+				t.Fatalf("expected error; got nil")
+			}
+			if !c.expectError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
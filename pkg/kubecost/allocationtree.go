@@ -0,0 +1,174 @@
+package kubecost
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AllocationTree is the result of a hierarchical aggregation of an
+// AllocationSet. Where AggregateBy flattens multiple properties into composite
+// keys, e.g. "cluster-one/kubecost", the tree nests each property within the
+// previous one, e.g. cluster → namespace → controller → pod, with subtotals at
+// every level.
+type AllocationTree struct {
+	Window    Window                `json:"window"`
+	Aggregate []string              `json:"aggregate"`
+	Nodes     []*AllocationTreeNode `json:"nodes"`
+}
+
+// AllocationTreeNode is a single aggregated Allocation within an
+// AllocationTree. Allocation holds the subtotals of all of its children,
+// including any shared cost distributed to it at its level. IdleCost is the
+// idle cost attributed to the node: if idle is shared, this cost is included
+// in the Allocation; if not, it is the amount that sharing would add.
+type AllocationTreeNode struct {
+	Name       string                `json:"name"`
+	Property   string                `json:"property"`
+	Allocation *Allocation           `json:"allocation"`
+	IdleCost   float64               `json:"idleCost"`
+	Children   []*AllocationTreeNode `json:"children,omitempty"`
+}
+
+// AggregateByHierarchy aggregates the AllocationSet by each prefix of the
+// given properties in turn, e.g. (cluster), (cluster, namespace), etc. and
+// nests the results into a tree. Idle and shared costs are distributed at each
+// level exactly as AggregateBy would with the same options. The receiving set
+// is not modified.
+func (as *AllocationSet) AggregateByHierarchy(aggregateBy []string, options *AllocationAggregationOptions) (*AllocationTree, error) {
+	if as == nil {
+		return nil, fmt.Errorf("cannot aggregate nil AllocationSet")
+	}
+
+	if len(aggregateBy) == 0 {
+		return nil, fmt.Errorf("hierarchical aggregation requires at least one property")
+	}
+
+	if options == nil {
+		options = &AllocationAggregationOptions{}
+	}
+
+	tree := &AllocationTree{
+		Window:    as.Window.Clone(),
+		Aggregate: aggregateBy,
+		Nodes:     []*AllocationTreeNode{},
+	}
+
+	// Record, for each level, the parent key and name of every aggregated
+	// key, as generated from the unaggregated Allocations. This avoids
+	// splitting the composite keys, whose values may contain "/".
+	parents := make([]map[string]string, len(aggregateBy))
+	names := make([]map[string]string, len(aggregateBy))
+	for level := range aggregateBy {
+		parents[level] = map[string]string{}
+		names[level] = map[string]string{}
+	}
+	for _, alloc := range as.Allocations {
+		if alloc.IsIdle() || alloc.IsExternal() {
+			continue
+		}
+
+		parentKey := ""
+		for level := range aggregateBy {
+			key := alloc.generateKey(aggregateBy[:level+1], options.LabelConfig)
+			parents[level][key] = parentKey
+			names[level][key] = alloc.generateKey(aggregateBy[level:level+1], options.LabelConfig)
+			parentKey = key
+		}
+	}
+
+	hasIdle := len(as.IdleAllocations()) > 0
+
+	var previous map[string]*AllocationTreeNode
+	for level := range aggregateBy {
+		aggSet, err := as.aggregateClone(aggregateBy[:level+1], options, options.ShareIdle)
+		if err != nil {
+			return nil, err
+		}
+
+		// Compute the idle cost of each node by comparing the aggregation
+		// with and without idle shared.
+		var withIdle, withoutIdle *AllocationSet
+		if hasIdle {
+			if options.ShareIdle == ShareWeighted || options.ShareIdle == ShareOwner {
+				withIdle = aggSet
+				withoutIdle, err = as.aggregateClone(aggregateBy[:level+1], options, ShareNone)
+			} else {
+				withoutIdle = aggSet
+				withIdle, err = as.aggregateClone(aggregateBy[:level+1], options, ShareWeighted)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		current := map[string]*AllocationTreeNode{}
+		for key, alloc := range aggSet.Allocations {
+			// Allocations without a parent which also exist at the previous
+			// level, like a merged idle allocation, are already in the tree.
+			parentKey, hasParent := parents[level][key]
+			if level > 0 && !hasParent {
+				if node, ok := previous[key]; ok {
+					current[key] = node
+					continue
+				}
+			}
+
+			node := &AllocationTreeNode{
+				Name:       key,
+				Property:   aggregateBy[level],
+				Allocation: alloc,
+			}
+			if name, ok := names[level][key]; ok {
+				node.Name = name
+			}
+
+			if hasIdle && !alloc.IsIdle() {
+				if shared, ok := withIdle.Allocations[key]; ok {
+					if unshared, ok := withoutIdle.Allocations[key]; ok {
+						node.IdleCost = shared.TotalCost() - unshared.TotalCost()
+					}
+				}
+			}
+
+			current[key] = node
+
+			if parent, ok := previous[parentKey]; ok && hasParent {
+				parent.Children = append(parent.Children, node)
+			} else {
+				tree.Nodes = append(tree.Nodes, node)
+			}
+		}
+
+		previous = current
+	}
+
+	sortAllocationTreeNodes(tree.Nodes)
+
+	return tree, nil
+}
+
+// aggregateClone aggregates a clone of the AllocationSet with a copy of the
+// given options, overriding ShareIdle. AggregateBy modifies both the set and
+// the options, so neither can be reused between aggregations.
+func (as *AllocationSet) aggregateClone(aggregateBy []string, options *AllocationAggregationOptions, shareIdle string) (*AllocationSet, error) {
+	opts := *options
+	opts.ShareIdle = shareIdle
+
+	aggSet := as.Clone()
+	err := aggSet.AggregateBy(aggregateBy, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return aggSet, nil
+}
+
+func sortAllocationTreeNodes(nodes []*AllocationTreeNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Allocation.Name < nodes[j].Allocation.Name
+	})
+
+	for _, node := range nodes {
+		sortAllocationTreeNodes(node.Children)
+	}
+}
@@ -0,0 +1,140 @@
+package kubecost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/util"
+)
+
+func TestAllocationSet_AggregateByHierarchy(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		shareIdle      string
+		expectIdleNode bool
+	}{
+		"idle not shared": {
+			shareIdle:      ShareNone,
+			expectIdleNode: true,
+		},
+		"idle shared": {
+			shareIdle:      ShareWeighted,
+			expectIdleNode: false,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			as := GenerateMockAllocationSetClusterIdle(start)
+			totalCost := as.TotalCost()
+			numAllocs := as.Length()
+
+			tree, err := as.AggregateByHierarchy(
+				[]string{AllocationClusterProp, AllocationNamespaceProp, AllocationPodProp},
+				&AllocationAggregationOptions{ShareIdle: c.shareIdle},
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// The receiving set must not be modified
+			if as.Length() != numAllocs {
+				t.Fatalf("expected set to be unmodified with %d allocations; got %d", numAllocs, as.Length())
+			}
+
+			treeCost := 0.0
+			idleCost := 0.0
+			foundIdleNode := false
+			for _, node := range tree.Nodes {
+				treeCost += node.Allocation.TotalCost()
+				idleCost += node.IdleCost
+
+				if node.Allocation.IsIdle() {
+					foundIdleNode = true
+					if len(node.Children) > 0 {
+						t.Fatalf("expected idle node to have no children")
+					}
+					continue
+				}
+
+				if node.Property != AllocationClusterProp {
+					t.Fatalf("expected top-level property %s; got %s", AllocationClusterProp, node.Property)
+				}
+
+				assertAllocationTreeSubtotals(t, node)
+			}
+
+			if foundIdleNode != c.expectIdleNode {
+				t.Fatalf("expected idle node: %t; got %t", c.expectIdleNode, foundIdleNode)
+			}
+
+			if !util.IsApproximately(treeCost, totalCost) {
+				t.Fatalf("expected tree total %f; got %f", totalCost, treeCost)
+			}
+
+			// Both idle allocations (cluster1: 20, cluster2: 10) are attributed
+			// to the clusters, whether or not idle is shared.
+			if !util.IsApproximately(idleCost, 30.0) {
+				t.Fatalf("expected idle cost %f; got %f", 30.0, idleCost)
+			}
+		})
+	}
+}
+
+func TestAllocationSet_AggregateByHierarchy_Names(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	as := GenerateMockAllocationSet(start)
+
+	tree, err := as.AggregateByHierarchy([]string{AllocationClusterProp, AllocationNamespaceProp}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(tree.Nodes) != 2 {
+		t.Fatalf("expected 2 clusters; got %d", len(tree.Nodes))
+	}
+
+	cluster1 := tree.Nodes[0]
+	if cluster1.Name != "cluster1" || cluster1.Allocation.Name != "cluster1" {
+		t.Fatalf("expected cluster1; got %s (%s)", cluster1.Name, cluster1.Allocation.Name)
+	}
+
+	for _, child := range cluster1.Children {
+		if child.Property != AllocationNamespaceProp {
+			t.Fatalf("expected property %s; got %s", AllocationNamespaceProp, child.Property)
+		}
+		if child.Allocation.Name != "cluster1/"+child.Name {
+			t.Fatalf("expected child of cluster1; got %s (%s)", child.Name, child.Allocation.Name)
+		}
+	}
+
+	if _, err := as.AggregateByHierarchy(nil, nil); err == nil {
+		t.Fatalf("expected error for empty aggregation")
+	}
+}
+
+// assertAllocationTreeSubtotals checks that the cost of every node is the sum
+// of the costs of its children.
+func assertAllocationTreeSubtotals(t *testing.T, node *AllocationTreeNode) {
+	t.Helper()
+
+	if len(node.Children) == 0 {
+		return
+	}
+
+	childCost := 0.0
+	childIdleCost := 0.0
+	for _, child := range node.Children {
+		childCost += child.Allocation.TotalCost()
+		childIdleCost += child.IdleCost
+		assertAllocationTreeSubtotals(t, child)
+	}
+
+	if !util.IsApproximately(childCost, node.Allocation.TotalCost()) {
+		t.Fatalf("%s: expected children to total %f; got %f", node.Allocation.Name, node.Allocation.TotalCost(), childCost)
+	}
+	if !util.IsApproximately(childIdleCost, node.IdleCost) {
+		t.Fatalf("%s: expected children's idle to total %f; got %f", node.Allocation.Name, node.IdleCost, childIdleCost)
+	}
+}
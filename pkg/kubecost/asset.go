@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/opencost/opencost/pkg/filter"
 	"github.com/opencost/opencost/pkg/log"
 	"github.com/opencost/opencost/pkg/util/json"
 	"github.com/opencost/opencost/pkg/util/timeutil"
@@ -57,6 +58,11 @@ type Asset interface {
 	Clone() Asset
 	Equal(Asset) bool
 
+	// Filtering
+	StringProperty(string) (string, error)
+	StringMapProperty(string) (map[string]string, error)
+	NumericProperty(string) (float64, error)
+
 	// Representations
 	encoding.BinaryMarshaler
 	encoding.BinaryUnmarshaler
@@ -297,6 +303,8 @@ func key(a Asset, aggregateBy []string, labelConfig *LabelConfig) (string, error
 			key = a.GetProperties().ProviderID
 		case s == string(AssetNameProp):
 			key = a.GetProperties().Name
+		case s == string(AssetNodePoolProp):
+			key = a.GetProperties().NodePool
 		case s == string(AssetDepartmentProp):
 			key = getKeyFromLabelConfig(a, labelConfig, labelConfig.DepartmentExternalLabel)
 		case s == string(AssetEnvironmentProp):
@@ -425,6 +433,66 @@ func (al AssetLabels) Append(newLabels map[string]string, overwrite bool) {
 // returning true for any given Asset if a condition is met.
 type AssetMatchFunc func(Asset) bool
 
+// assetStringProperty returns the value of the string property of the given
+// Asset, shared by the StringProperty implementations of each Asset type.
+func assetStringProperty(a Asset, property string) (string, error) {
+	if property == string(AssetTypeProp) {
+		return a.Type().String(), nil
+	}
+
+	props := a.GetProperties()
+	if props == nil {
+		props = &AssetProperties{}
+	}
+
+	switch AssetProperty(property) {
+	case AssetAccountProp:
+		return props.Account, nil
+	case AssetCategoryProp:
+		return props.Category, nil
+	case AssetClusterProp:
+		return props.Cluster, nil
+	case AssetNameProp:
+		return props.Name, nil
+	case AssetNodePoolProp:
+		return props.NodePool, nil
+	case AssetProjectProp:
+		return props.Project, nil
+	case AssetProviderProp:
+		return props.Provider, nil
+	case AssetProviderIDProp:
+		return props.ProviderID, nil
+	case AssetServiceProp:
+		return props.Service, nil
+	default:
+		return "", fmt.Errorf("Asset: StringProperty: invalid property name: %s", property)
+	}
+}
+
+// assetStringMapProperty returns the value of the string map property of the
+// given Asset, shared by the StringMapProperty implementations of each Asset type.
+func assetStringMapProperty(a Asset, property string) (map[string]string, error) {
+	switch AssetProperty(property) {
+	case AssetLabelProp:
+		return a.GetLabels(), nil
+	default:
+		return nil, fmt.Errorf("Asset: StringMapProperty: invalid property name: %s", property)
+	}
+}
+
+// assetNumericProperty returns the value of the numeric property of the given
+// Asset, shared by the NumericProperty implementations of each Asset type.
+func assetNumericProperty(a Asset, property string) (float64, error) {
+	switch AssetProperty(property) {
+	case AssetTotalCostProp:
+		return a.TotalCost(), nil
+	case AssetAdjustmentProp:
+		return a.GetAdjustment(), nil
+	default:
+		return 0, fmt.Errorf("Asset: NumericProperty: invalid property name: %s", property)
+	}
+}
+
 // AssetType identifies a type of Asset
 type AssetType int
 
@@ -452,6 +520,9 @@ const (
 
 	// SharedAssetType describes the Shared AssetType
 	SharedAssetType
+
+	// SnapshotAssetType describes the Snapshot AssetType
+	SnapshotAssetType
 )
 
 // ParseAssetType attempts to parse the given string into an AssetType
@@ -471,6 +542,8 @@ func ParseAssetType(text string) (AssetType, error) {
 		return NodeAssetType, nil
 	case "shared":
 		return SharedAssetType, nil
+	case "snapshot":
+		return SnapshotAssetType, nil
 	}
 	return AnyAssetType, fmt.Errorf("invalid asset type: %s", text)
 }
@@ -486,6 +559,7 @@ func (at AssetType) String() string {
 		"Network",
 		"Node",
 		"Shared",
+		"Snapshot",
 	}[at]
 }
 
@@ -517,6 +591,21 @@ func (a *Any) Type() AssetType {
 	return AnyAssetType
 }
 
+// StringProperty returns the value of a string property of the Any, for filtering
+func (a *Any) StringProperty(property string) (string, error) {
+	return assetStringProperty(a, property)
+}
+
+// StringMapProperty returns the value of a string map property of the Any, for filtering
+func (a *Any) StringMapProperty(property string) (map[string]string, error) {
+	return assetStringMapProperty(a, property)
+}
+
+// NumericProperty returns the value of a numeric property of the Any, for filtering
+func (a *Any) NumericProperty(property string) (float64, error) {
+	return assetNumericProperty(a, property)
+}
+
 // Properties returns the Asset's Properties
 func (a *Any) GetProperties() *AssetProperties {
 	return a.Properties
@@ -707,6 +796,21 @@ func (ca *Cloud) Type() AssetType {
 	return CloudAssetType
 }
 
+// StringProperty returns the value of a string property of the Cloud, for filtering
+func (ca *Cloud) StringProperty(property string) (string, error) {
+	return assetStringProperty(ca, property)
+}
+
+// StringMapProperty returns the value of a string map property of the Cloud, for filtering
+func (ca *Cloud) StringMapProperty(property string) (map[string]string, error) {
+	return assetStringMapProperty(ca, property)
+}
+
+// NumericProperty returns the value of a numeric property of the Cloud, for filtering
+func (ca *Cloud) NumericProperty(property string) (float64, error) {
+	return assetNumericProperty(ca, property)
+}
+
 // Properties returns the AssetProperties
 func (ca *Cloud) GetProperties() *AssetProperties {
 	return ca.Properties
@@ -932,6 +1036,21 @@ func (cm *ClusterManagement) Type() AssetType {
 	return ClusterManagementAssetType
 }
 
+// StringProperty returns the value of a string property of the ClusterManagement, for filtering
+func (cm *ClusterManagement) StringProperty(property string) (string, error) {
+	return assetStringProperty(cm, property)
+}
+
+// StringMapProperty returns the value of a string map property of the ClusterManagement, for filtering
+func (cm *ClusterManagement) StringMapProperty(property string) (map[string]string, error) {
+	return assetStringMapProperty(cm, property)
+}
+
+// NumericProperty returns the value of a numeric property of the ClusterManagement, for filtering
+func (cm *ClusterManagement) NumericProperty(property string) (float64, error) {
+	return assetNumericProperty(cm, property)
+}
+
 // Properties returns the Asset's Properties
 func (cm *ClusterManagement) GetProperties() *AssetProperties {
 	return cm.Properties
@@ -1114,6 +1233,8 @@ type Disk struct {
 	VolumeName     string   // @bingen:field[version=18]
 	ClaimName      string   // @bingen:field[version=18]
 	ClaimNamespace string   // @bingen:field[version=18]
+	IOPSCost       float64  // @bingen:field[version=20]
+	ThroughputCost float64  // @bingen:field[version=20]
 }
 
 // NewDisk creates and returns a new Disk Asset
@@ -1141,6 +1262,21 @@ func (d *Disk) Type() AssetType {
 	return DiskAssetType
 }
 
+// StringProperty returns the value of a string property of the Disk, for filtering
+func (d *Disk) StringProperty(property string) (string, error) {
+	return assetStringProperty(d, property)
+}
+
+// StringMapProperty returns the value of a string map property of the Disk, for filtering
+func (d *Disk) StringMapProperty(property string) (map[string]string, error) {
+	return assetStringMapProperty(d, property)
+}
+
+// NumericProperty returns the value of a numeric property of the Disk, for filtering
+func (d *Disk) NumericProperty(property string) (float64, error) {
+	return assetNumericProperty(d, property)
+}
+
 // Properties returns the Asset's Properties
 func (d *Disk) GetProperties() *AssetProperties {
 	return d.Properties
@@ -1303,6 +1439,8 @@ func (d *Disk) add(that *Disk) {
 
 	d.Adjustment += that.Adjustment
 	d.Cost += that.Cost
+	d.IOPSCost += that.IOPSCost
+	d.ThroughputCost += that.ThroughputCost
 
 	d.ByteHours += that.ByteHours
 
@@ -1368,6 +1506,8 @@ func (d *Disk) Clone() Asset {
 		VolumeName:     d.VolumeName,
 		ClaimName:      d.ClaimName,
 		ClaimNamespace: d.ClaimNamespace,
+		IOPSCost:       d.IOPSCost,
+		ThroughputCost: d.ThroughputCost,
 	}
 }
 
@@ -1438,6 +1578,12 @@ func (d *Disk) Equal(a Asset) bool {
 	if d.ClaimNamespace != that.ClaimNamespace {
 		return false
 	}
+	if d.IOPSCost != that.IOPSCost {
+		return false
+	}
+	if d.ThroughputCost != that.ThroughputCost {
+		return false
+	}
 
 	return true
 }
@@ -1544,6 +1690,21 @@ func (n *Network) Type() AssetType {
 	return NetworkAssetType
 }
 
+// StringProperty returns the value of a string property of the Network, for filtering
+func (n *Network) StringProperty(property string) (string, error) {
+	return assetStringProperty(n, property)
+}
+
+// StringMapProperty returns the value of a string map property of the Network, for filtering
+func (n *Network) StringMapProperty(property string) (map[string]string, error) {
+	return assetStringMapProperty(n, property)
+}
+
+// NumericProperty returns the value of a numeric property of the Network, for filtering
+func (n *Network) NumericProperty(property string) (float64, error) {
+	return assetNumericProperty(n, property)
+}
+
 // Properties returns the Asset's Properties
 func (n *Network) GetProperties() *AssetProperties {
 	return n.Properties
@@ -1752,6 +1913,297 @@ func (n *Network) String() string {
 	return toString(n)
 }
 
+// Snapshot is an Asset representing the storage of a single volume snapshot,
+// which is billed independently of the disk it was taken of and persists
+// after that disk has been deleted.
+type Snapshot struct {
+	Properties     *AssetProperties
+	Labels         AssetLabels
+	Start          time.Time
+	End            time.Time
+	Window         Window
+	Adjustment     float64
+	Cost           float64
+	ByteHours      float64
+	ClaimName      string // name of the PVC the snapshot was taken of
+	ClaimNamespace string // namespace of the PVC the snapshot was taken of
+}
+
+// NewSnapshot creates and returns a new Snapshot Asset
+func NewSnapshot(name, cluster, providerID string, start, end time.Time, window Window) *Snapshot {
+	properties := &AssetProperties{
+		Category:   StorageCategory,
+		Name:       name,
+		Cluster:    cluster,
+		ProviderID: providerID,
+		Service:    KubernetesService,
+	}
+
+	return &Snapshot{
+		Properties: properties,
+		Labels:     AssetLabels{},
+		Start:      start,
+		End:        end,
+		Window:     window.Clone(),
+	}
+}
+
+// Type returns the AssetType of the Asset
+func (s *Snapshot) Type() AssetType {
+	return SnapshotAssetType
+}
+
+// StringProperty returns the value of a string property of the Snapshot, for filtering
+func (s *Snapshot) StringProperty(property string) (string, error) {
+	return assetStringProperty(s, property)
+}
+
+// StringMapProperty returns the value of a string map property of the Snapshot, for filtering
+func (s *Snapshot) StringMapProperty(property string) (map[string]string, error) {
+	return assetStringMapProperty(s, property)
+}
+
+// NumericProperty returns the value of a numeric property of the Snapshot, for filtering
+func (s *Snapshot) NumericProperty(property string) (float64, error) {
+	return assetNumericProperty(s, property)
+}
+
+// Properties returns the Asset's Properties
+func (s *Snapshot) GetProperties() *AssetProperties {
+	return s.Properties
+}
+
+// SetProperties sets the Asset's Properties
+func (s *Snapshot) SetProperties(props *AssetProperties) {
+	s.Properties = props
+}
+
+// Labels returns the Asset's labels
+func (s *Snapshot) GetLabels() AssetLabels {
+	return s.Labels
+}
+
+// SetLabels sets the Asset's labels
+func (s *Snapshot) SetLabels(labels AssetLabels) {
+	s.Labels = labels
+}
+
+// Adjustment returns the Asset's cost adjustment
+func (s *Snapshot) GetAdjustment() float64 {
+	return s.Adjustment
+}
+
+// SetAdjustment sets the Asset's cost adjustment
+func (s *Snapshot) SetAdjustment(adj float64) {
+	s.Adjustment = adj
+}
+
+// TotalCost returns the Asset's total cost
+func (s *Snapshot) TotalCost() float64 {
+	return s.Cost + s.Adjustment
+}
+
+// Start returns the precise start time of the Asset within the window
+func (s *Snapshot) GetStart() time.Time {
+	return s.Start
+}
+
+// End returns the precise end time of the Asset within the window
+func (s *Snapshot) GetEnd() time.Time {
+	return s.End
+}
+
+// Minutes returns the number of minutes the Asset existed within the window
+func (s *Snapshot) Minutes() float64 {
+	snapMins := s.End.Sub(s.Start).Minutes()
+	windowMins := s.Window.Minutes()
+
+	if snapMins > windowMins {
+		log.Warnf("Asset ETL: Snapshot.Minutes exceeds window: %.2f > %.2f", snapMins, windowMins)
+		snapMins = windowMins
+	}
+
+	if snapMins < 0 {
+		snapMins = 0
+	}
+
+	return snapMins
+}
+
+// Window returns the window within which the Asset existed
+func (s *Snapshot) GetWindow() Window {
+	return s.Window
+}
+
+func (s *Snapshot) SetWindow(window Window) {
+	s.Window = window
+}
+
+// ExpandWindow expands the Asset's window by the given window
+func (s *Snapshot) ExpandWindow(window Window) {
+	s.Window = s.Window.Expand(window)
+}
+
+// SetStartEnd sets the Asset's Start and End fields
+func (s *Snapshot) SetStartEnd(start, end time.Time) {
+	if s.Window.Contains(start) {
+		s.Start = start
+	} else {
+		log.Warnf("Snapshot.SetStartEnd: start %s not in %s", start, s.Window)
+	}
+
+	if s.Window.Contains(end) {
+		s.End = end
+	} else {
+		log.Warnf("Snapshot.SetStartEnd: end %s not in %s", end, s.Window)
+	}
+}
+
+// Bytes returns the average number of bytes stored by the Snapshot
+func (s *Snapshot) Bytes() float64 {
+	hours := s.Minutes() / 60.0
+	if hours <= 0 {
+		return 0.0
+	}
+	return s.ByteHours / hours
+}
+
+// Add sums the Asset with the given Asset to produce a new Asset, maintaining
+// as much relevant information as possible (i.e. type, Properties, labels).
+func (s *Snapshot) Add(a Asset) Asset {
+	// Snapshot + Snapshot = Snapshot
+	if that, ok := a.(*Snapshot); ok {
+		this := s.Clone().(*Snapshot)
+		this.add(that)
+		return this
+	}
+
+	props := s.Properties.Merge(a.GetProperties())
+	labels := s.Labels.Merge(a.GetLabels())
+
+	start := s.Start
+	if a.GetStart().Before(start) {
+		start = a.GetStart()
+	}
+	end := s.End
+	if a.GetEnd().After(end) {
+		end = a.GetEnd()
+	}
+	window := s.Window.Expand(a.GetWindow())
+
+	// Snapshot + !Snapshot = Any
+	any := NewAsset(start, end, window)
+	any.SetProperties(props)
+	any.SetLabels(labels)
+	any.Adjustment = s.Adjustment + a.GetAdjustment()
+	any.Cost = (s.TotalCost() - s.Adjustment) + (a.TotalCost() - a.GetAdjustment())
+
+	return any
+}
+
+func (s *Snapshot) add(that *Snapshot) {
+	if s == nil {
+		s = that
+		return
+	}
+
+	props := s.Properties.Merge(that.Properties)
+	labels := s.Labels.Merge(that.Labels)
+	s.SetProperties(props)
+	s.SetLabels(labels)
+
+	start := s.Start
+	if that.Start.Before(start) {
+		start = that.Start
+	}
+	end := s.End
+	if that.End.After(end) {
+		end = that.End
+	}
+	window := s.Window.Expand(that.Window)
+	s.Start = start
+	s.End = end
+	s.Window = window
+
+	// Only keep the source claim if both snapshots were taken of the same one
+	if s.ClaimName != that.ClaimName || s.ClaimNamespace != that.ClaimNamespace {
+		s.ClaimName = ""
+		s.ClaimNamespace = ""
+	}
+
+	s.Cost += that.Cost
+	s.ByteHours += that.ByteHours
+	s.Adjustment += that.Adjustment
+}
+
+// Clone returns a deep copy of the given Snapshot
+func (s *Snapshot) Clone() Asset {
+	if s == nil {
+		return nil
+	}
+
+	return &Snapshot{
+		Properties:     s.Properties.Clone(),
+		Labels:         s.Labels.Clone(),
+		Start:          s.Start,
+		End:            s.End,
+		Window:         s.Window.Clone(),
+		Adjustment:     s.Adjustment,
+		Cost:           s.Cost,
+		ByteHours:      s.ByteHours,
+		ClaimName:      s.ClaimName,
+		ClaimNamespace: s.ClaimNamespace,
+	}
+}
+
+// Equal returns true if the two Assets match exactly
+func (s *Snapshot) Equal(a Asset) bool {
+	that, ok := a.(*Snapshot)
+	if !ok {
+		return false
+	}
+
+	if !s.Labels.Equal(that.Labels) {
+		return false
+	}
+	if !s.Properties.Equal(that.Properties) {
+		return false
+	}
+
+	if !s.Start.Equal(that.Start) {
+		return false
+	}
+	if !s.End.Equal(that.End) {
+		return false
+	}
+	if !s.Window.Equal(that.Window) {
+		return false
+	}
+
+	if s.Adjustment != that.Adjustment {
+		return false
+	}
+	if s.Cost != that.Cost {
+		return false
+	}
+	if s.ByteHours != that.ByteHours {
+		return false
+	}
+	if s.ClaimName != that.ClaimName {
+		return false
+	}
+	if s.ClaimNamespace != that.ClaimNamespace {
+		return false
+	}
+
+	return true
+}
+
+// String implements fmt.Stringer
+func (s *Snapshot) String() string {
+	return toString(s)
+}
+
 // Node is an Asset representing a single node in a cluster
 type Node struct {
 	Properties   *AssetProperties
@@ -1800,6 +2252,21 @@ func (n *Node) Type() AssetType {
 	return NodeAssetType
 }
 
+// StringProperty returns the value of a string property of the Node, for filtering
+func (n *Node) StringProperty(property string) (string, error) {
+	return assetStringProperty(n, property)
+}
+
+// StringMapProperty returns the value of a string map property of the Node, for filtering
+func (n *Node) StringMapProperty(property string) (map[string]string, error) {
+	return assetStringMapProperty(n, property)
+}
+
+// NumericProperty returns the value of a numeric property of the Node, for filtering
+func (n *Node) NumericProperty(property string) (float64, error) {
+	return assetNumericProperty(n, property)
+}
+
 // Properties returns the Asset's Properties
 func (n *Node) GetProperties() *AssetProperties {
 	return n.Properties
@@ -2210,6 +2677,21 @@ func (lb *LoadBalancer) Type() AssetType {
 	return LoadBalancerAssetType
 }
 
+// StringProperty returns the value of a string property of the LoadBalancer, for filtering
+func (lb *LoadBalancer) StringProperty(property string) (string, error) {
+	return assetStringProperty(lb, property)
+}
+
+// StringMapProperty returns the value of a string map property of the LoadBalancer, for filtering
+func (lb *LoadBalancer) StringMapProperty(property string) (map[string]string, error) {
+	return assetStringMapProperty(lb, property)
+}
+
+// NumericProperty returns the value of a numeric property of the LoadBalancer, for filtering
+func (lb *LoadBalancer) NumericProperty(property string) (float64, error) {
+	return assetNumericProperty(lb, property)
+}
+
 // Properties returns the Asset's Properties
 func (lb *LoadBalancer) GetProperties() *AssetProperties {
 	return lb.Properties
@@ -2428,6 +2910,21 @@ func (sa *SharedAsset) Type() AssetType {
 	return SharedAssetType
 }
 
+// StringProperty returns the value of a string property of the SharedAsset, for filtering
+func (sa *SharedAsset) StringProperty(property string) (string, error) {
+	return assetStringProperty(sa, property)
+}
+
+// StringMapProperty returns the value of a string map property of the SharedAsset, for filtering
+func (sa *SharedAsset) StringMapProperty(property string) (map[string]string, error) {
+	return assetStringMapProperty(sa, property)
+}
+
+// NumericProperty returns the value of a numeric property of the SharedAsset, for filtering
+func (sa *SharedAsset) NumericProperty(property string) (float64, error) {
+	return assetNumericProperty(sa, property)
+}
+
 // Properties returns the Asset's Properties
 func (sa *SharedAsset) GetProperties() *AssetProperties {
 	return sa.Properties
@@ -2609,6 +3106,7 @@ type AssetSet struct {
 	Nodes             map[string]*Node              //@bingen:field[ignore]
 	LoadBalancers     map[string]*LoadBalancer      //@bingen:field[ignore]
 	SharedAssets      map[string]*SharedAsset       //@bingen:field[ignore]
+	Snapshots         map[string]*Snapshot          //@bingen:field[ignore]
 	FromSource        string                        // stores the name of the source used to compute the data
 	Window            Window
 	Warnings          []string
@@ -2618,7 +3116,8 @@ type AssetSet struct {
 // This methid is executed before marshalling the AssetSet binary.
 func preProcessAssetSet(assetSet *AssetSet) {
 	length := len(assetSet.Any) + len(assetSet.Cloud) + len(assetSet.ClusterManagement) + len(assetSet.Disks) +
-		len(assetSet.Network) + len(assetSet.Nodes) + len(assetSet.LoadBalancers) + len(assetSet.SharedAssets)
+		len(assetSet.Network) + len(assetSet.Nodes) + len(assetSet.LoadBalancers) + len(assetSet.SharedAssets) +
+		len(assetSet.Snapshots)
 
 	if length != len(assetSet.Assets) {
 		log.Warnf("AssetSet concrete Asset maps are out of sync with AssetSet.Assets map.")
@@ -2683,6 +3182,12 @@ func addToConcreteMap(assetSet *AssetSet, key string, as Asset) {
 			assetSet.SharedAssets = make(map[string]*SharedAsset)
 		}
 		assetSet.SharedAssets[key] = asset
+
+	case *Snapshot:
+		if assetSet.Snapshots == nil {
+			assetSet.Snapshots = make(map[string]*Snapshot)
+		}
+		assetSet.Snapshots[key] = asset
 	}
 }
 
@@ -2712,6 +3217,9 @@ func removeFromConcreteMap(assetSet *AssetSet, key string, as Asset) {
 
 	case *SharedAsset:
 		delete(assetSet.SharedAssets, key)
+
+	case *Snapshot:
+		delete(assetSet.Snapshots, key)
 	}
 }
 
@@ -2799,6 +3307,34 @@ func (as *AssetSet) AggregateBy(aggregateBy []string, opts *AssetAggregationOpti
 	return nil
 }
 
+// Filter returns a new AssetSet containing clones of the Assets which match
+// the given filter.
+func (as *AssetSet) Filter(filters filter.Filter[Asset]) *AssetSet {
+	if as == nil {
+		return nil
+	}
+
+	if filters == nil {
+		return as.Clone()
+	}
+
+	result := NewAssetSet(as.Start(), as.End())
+	if as.AggregationKeys != nil {
+		result.AggregationKeys = append([]string{}, as.AggregationKeys...)
+	}
+	result.FromSource = as.FromSource
+
+	for key, asset := range as.Assets {
+		if filters.Matches(asset) {
+			clone := asset.Clone()
+			result.Assets[key] = clone
+			addToConcreteMap(result, key, clone)
+		}
+	}
+
+	return result
+}
+
 // Clone returns a new AssetSet with a deep copy of the given
 // AssetSet's assets.
 func (as *AssetSet) Clone() *AssetSet {
@@ -2865,6 +3401,11 @@ func (as *AssetSet) Clone() *AssetSet {
 		sharedAssetsMap = make(map[string]*SharedAsset, len(as.SharedAssets))
 	}
 
+	var snapshotsMap map[string]*Snapshot
+	if as.Snapshots != nil {
+		snapshotsMap = make(map[string]*Snapshot, len(as.Snapshots))
+	}
+
 	assetSet := &AssetSet{
 		Window:            NewWindow(&s, &e),
 		AggregationKeys:   aggregateBy,
@@ -2877,6 +3418,7 @@ func (as *AssetSet) Clone() *AssetSet {
 		Nodes:             nodesMap,
 		LoadBalancers:     loadBalancersMap,
 		SharedAssets:      sharedAssetsMap,
+		Snapshots:         snapshotsMap,
 		Errors:            errors,
 		Warnings:          warnings,
 	}
@@ -271,6 +271,8 @@ func (d *Disk) MarshalJSON() ([]byte, error) {
 	}
 	jsonEncode(buffer, "breakdown", d.Breakdown, ",")
 	jsonEncodeFloat64(buffer, "adjustment", d.Adjustment, ",")
+	jsonEncodeFloat64(buffer, "iopsCost", d.IOPSCost, ",")
+	jsonEncodeFloat64(buffer, "throughputCost", d.ThroughputCost, ",")
 	jsonEncodeFloat64(buffer, "totalCost", d.TotalCost(), ",")
 	jsonEncodeString(buffer, "storageClass", d.StorageClass, ",")
 	jsonEncodeString(buffer, "volumeName", d.VolumeName, ",")
@@ -342,6 +344,12 @@ func (d *Disk) InterfaceToDisk(itf interface{}) error {
 	if Cost, err := getTypedVal(fmap["totalCost"]); err == nil {
 		d.Cost = Cost.(float64) - d.Adjustment
 	}
+	if IOPSCost, err := getTypedVal(fmap["iopsCost"]); err == nil {
+		d.IOPSCost = IOPSCost.(float64)
+	}
+	if ThroughputCost, err := getTypedVal(fmap["throughputCost"]); err == nil {
+		d.ThroughputCost = ThroughputCost.(float64)
+	}
 	if ByteHours, err := getTypedVal(fmap["byteHours"]); err == nil {
 		d.ByteHours = ByteHours.(float64)
 	}
@@ -464,6 +472,99 @@ func (n *Network) InterfaceToNetwork(itf interface{}) error {
 
 }
 
+// Snapshot marshal and unmarshal
+
+// MarshalJSON implements json.Marshal interface
+func (s *Snapshot) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString("{")
+	jsonEncodeString(buffer, "type", s.Type().String(), ",")
+	jsonEncode(buffer, "properties", s.Properties, ",")
+	jsonEncode(buffer, "labels", s.Labels, ",")
+	jsonEncode(buffer, "window", s.Window, ",")
+	jsonEncodeString(buffer, "start", s.Start.Format(time.RFC3339), ",")
+	jsonEncodeString(buffer, "end", s.End.Format(time.RFC3339), ",")
+	jsonEncodeFloat64(buffer, "minutes", s.Minutes(), ",")
+	jsonEncodeFloat64(buffer, "byteHours", s.ByteHours, ",")
+	jsonEncodeFloat64(buffer, "bytes", s.Bytes(), ",")
+	jsonEncodeFloat64(buffer, "adjustment", s.Adjustment, ",")
+	jsonEncodeFloat64(buffer, "totalCost", s.TotalCost(), ",")
+	jsonEncodeString(buffer, "claimName", s.ClaimName, ",")
+	jsonEncodeString(buffer, "claimNamespace", s.ClaimNamespace, "")
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+func (s *Snapshot) UnmarshalJSON(b []byte) error {
+
+	var f interface{}
+
+	err := json.Unmarshal(b, &f)
+	if err != nil {
+		return err
+	}
+
+	err = s.InterfaceToSnapshot(f)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Converts interface{} to Snapshot, carrying over relevant fields
+func (s *Snapshot) InterfaceToSnapshot(itf interface{}) error {
+
+	fmap := itf.(map[string]interface{})
+
+	// parse properties map to AssetProperties
+	fproperties := fmap["properties"].(map[string]interface{})
+	properties := toAssetProp(fproperties)
+
+	// parse labels map to AssetLabels
+	labels := make(map[string]string)
+	for k, v := range fmap["labels"].(map[string]interface{}) {
+		labels[k] = v.(string)
+	}
+
+	// parse start and end strings to time.Time
+	start, err := time.Parse(time.RFC3339, fmap["start"].(string))
+	if err != nil {
+		return err
+	}
+	end, err := time.Parse(time.RFC3339, fmap["end"].(string))
+	if err != nil {
+		return err
+	}
+
+	s.Properties = &properties
+	s.Labels = labels
+	s.Start = start
+	s.End = end
+	s.Window = Window{
+		start: &start,
+		end:   &end,
+	}
+
+	if adjustment, err := getTypedVal(fmap["adjustment"]); err == nil {
+		s.Adjustment = adjustment.(float64)
+	}
+	if Cost, err := getTypedVal(fmap["totalCost"]); err == nil {
+		s.Cost = Cost.(float64) - s.Adjustment
+	}
+	if ByteHours, err := getTypedVal(fmap["byteHours"]); err == nil {
+		s.ByteHours = ByteHours.(float64)
+	}
+	if ClaimName, err := getTypedVal(fmap["claimName"]); err == nil {
+		s.ClaimName = ClaimName.(string)
+	}
+	if ClaimNamespace, err := getTypedVal(fmap["claimNamespace"]); err == nil {
+		s.ClaimNamespace = ClaimNamespace.(string)
+	}
+
+	return nil
+
+}
+
 // Node marshal and unmarshal
 
 // MarshalJSON implements json.Marshal interface
@@ -871,6 +972,17 @@ func (asr *AssetSetResponse) RawMessageToAssetSetResponse(assetMap map[string]*j
 
 			newAssetMap[key] = &sa
 
+		case "Snapshot":
+
+			var sn Snapshot
+			err := sn.InterfaceToSnapshot(f)
+
+			if err != nil {
+				return err
+			}
+
+			newAssetMap[key] = &sn
+
 		default:
 
 			var a Any
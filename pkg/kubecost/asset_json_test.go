@@ -169,6 +169,8 @@ func TestDisk_Unmarshal(t *testing.T) {
 	max := 50.0 * gb * hours
 	disk1.ByteUsageMax = &max
 	disk1.Cost = 4.0
+	disk1.IOPSCost = 1.5
+	disk1.ThroughputCost = 0.5
 	disk1.Local = 1.0
 	disk1.SetAdjustment(1.0)
 	disk1.Breakdown = &Breakdown{
@@ -224,6 +226,9 @@ func TestDisk_Unmarshal(t *testing.T) {
 	if disk1.Cost != disk2.Cost {
 		t.Fatalf("Disk Unmarshal: cost mutated in unmarshal")
 	}
+	if disk1.IOPSCost != disk2.IOPSCost || disk1.ThroughputCost != disk2.ThroughputCost {
+		t.Fatalf("Disk Unmarshal: IOPS or throughput cost mutated in unmarshal")
+	}
 
 	// Local from Disk is not marhsaled, and cannot be calculated from marshaled values.
 	// Currently, it is just ignored and not set in the resulting unmarshal to Disk. Thus,
@@ -503,6 +508,41 @@ func TestSharedAsset_Unmarshal(t *testing.T) {
 
 }
 
+func TestSnapshot_Unmarshal(t *testing.T) {
+
+	snapshot1 := NewSnapshot("namespace1/snap1", "cluster1", "snap-0a1", *unmarshalWindow.start, *unmarshalWindow.end, unmarshalWindow)
+	snapshot1.Cost = 1.2
+	snapshot1.ByteHours = 240.0
+	snapshot1.ClaimName = "data"
+	snapshot1.ClaimNamespace = "namespace1"
+	snapshot1.SetAdjustment(0.3)
+
+	bytes, _ := json.Marshal(snapshot1)
+
+	var testsn Snapshot
+	snapshot2 := &testsn
+
+	err := json.Unmarshal(bytes, snapshot2)
+
+	// Check if unmarshal was successful
+	if err != nil {
+		t.Fatalf("Snapshot Unmarshal: unexpected error: %s", err)
+	}
+
+	if snapshot1.ByteHours != snapshot2.ByteHours {
+		t.Fatalf("Snapshot Unmarshal: byteHours mutated in unmarshal")
+	}
+	if snapshot1.ClaimName != snapshot2.ClaimName || snapshot1.ClaimNamespace != snapshot2.ClaimNamespace {
+		t.Fatalf("Snapshot Unmarshal: claim mutated in unmarshal")
+	}
+
+	// As a final check, make sure the above checks out
+	if !snapshot1.Equal(snapshot2) {
+		t.Fatalf("Snapshot Unmarshal: Snapshot mutated in unmarshal")
+	}
+
+}
+
 func TestAssetset_Unmarshal(t *testing.T) {
 
 	var s time.Time
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/opencost/opencost/pkg/filter"
 	"github.com/opencost/opencost/pkg/util"
 )
 
@@ -810,6 +811,46 @@ func TestAssetSet_FindMatch(t *testing.T) {
 
 // Asserts that all Assets within an AssetSet have a Window that
 // matches that of the AssetSet.
+func TestAssetSet_Filter(t *testing.T) {
+	node := NewNode("node1", "cluster1", "node1", start1, start2, windows[0])
+	node.SetLabels(AssetLabels{"team": "platform"})
+	disk := NewDisk("disk1", "cluster1", "disk1", start1, start2, windows[0])
+	cloud := NewCloud(StorageCategory, "bucket1", start1, start2, windows[0])
+
+	as := NewAssetSet(start1, start2, node, disk, cloud)
+
+	var f filter.Filter[Asset] = filter.Or[Asset]{
+		Filters: []filter.Filter[Asset]{
+			filter.StringProperty[Asset]{
+				Field: string(AssetTypeProp),
+				Op:    filter.StringEquals,
+				Value: DiskAssetType.String(),
+			},
+			filter.StringMapProperty[Asset]{
+				Field: string(AssetLabelProp),
+				Op:    filter.StringMapEquals,
+				Key:   "team",
+				Value: "platform",
+			},
+		},
+	}
+
+	filtered := as.Filter(f)
+	if filtered.Length() != 2 {
+		t.Fatalf("expected 2 assets, got %d", filtered.Length())
+	}
+	if len(filtered.Nodes) != 1 || len(filtered.Disks) != 1 || len(filtered.Cloud) != 0 {
+		t.Errorf("expected concrete maps to be filtered, got %d nodes, %d disks, %d cloud", len(filtered.Nodes), len(filtered.Disks), len(filtered.Cloud))
+	}
+	if as.Length() != 3 {
+		t.Errorf("expected original set to be unchanged, got %d assets", as.Length())
+	}
+
+	if as.Filter(nil).Length() != 3 {
+		t.Errorf("expected nil filter to match all assets")
+	}
+}
+
 func TestAssetSet_InsertMatchingWindow(t *testing.T) {
 	setStart := time.Now().Round(time.Hour)
 	setEnd := setStart.Add(1 * time.Hour)
@@ -27,6 +27,9 @@ const (
 	// AssetNodeProp describes the node of the Asset
 	AssetNodeProp AssetProperty = "node"
 
+	// AssetNodePoolProp describes the node pool of the Asset
+	AssetNodePoolProp AssetProperty = "nodepool"
+
 	// AssetProjectProp describes the project of the Asset
 	AssetProjectProp AssetProperty = "project"
 
@@ -42,6 +45,15 @@ const (
 	// AssetTypeProp describes the type of the Asset
 	AssetTypeProp AssetProperty = "type"
 
+	// AssetLabelProp describes the labels of the Asset
+	AssetLabelProp AssetProperty = "label"
+
+	// AssetTotalCostProp describes the total cost of the Asset
+	AssetTotalCostProp AssetProperty = "totalCost"
+
+	// AssetAdjustmentProp describes the adjustment of the Asset
+	AssetAdjustmentProp AssetProperty = "adjustment"
+
 	// AssetDepartmentProp describes the department of the Asset
 	AssetDepartmentProp AssetProperty = "department"
 
@@ -69,6 +81,8 @@ func ParseAssetProperty(text string) (AssetProperty, error) {
 		return AssetClusterProp, nil
 	case "name":
 		return AssetNameProp, nil
+	case "nodepool":
+		return AssetNodePoolProp, nil
 	case "project":
 		return AssetProjectProp, nil
 	case "provider":
@@ -169,6 +183,7 @@ type AssetProperties struct {
 	Cluster    string `json:"cluster,omitempty"`
 	Name       string `json:"name,omitempty"`
 	ProviderID string `json:"providerID,omitempty"`
+	NodePool   string `json:"nodePool,omitempty"` // @bingen:field[version=21]
 }
 
 // Clone returns a cloned instance of the given AssetProperties
@@ -186,6 +201,7 @@ func (ap *AssetProperties) Clone() *AssetProperties {
 	clone.Cluster = ap.Cluster
 	clone.Name = ap.Name
 	clone.ProviderID = ap.ProviderID
+	clone.NodePool = ap.NodePool
 
 	return clone
 }
@@ -228,6 +244,10 @@ func (ap *AssetProperties) Equal(that *AssetProperties) bool {
 		return false
 	}
 
+	if ap.NodePool != that.NodePool {
+		return false
+	}
+
 	return true
 }
 
@@ -272,6 +292,10 @@ func (ap *AssetProperties) Keys(props []AssetProperty) []string {
 		keys = append(keys, ap.ProviderID)
 	}
 
+	if (props == nil || hasProp(props, AssetNodePoolProp)) && ap.NodePool != "" {
+		keys = append(keys, ap.NodePool)
+	}
+
 	return keys
 }
 
@@ -315,6 +339,10 @@ func (ap *AssetProperties) Merge(that *AssetProperties) *AssetProperties {
 		result.ProviderID = ap.ProviderID
 	}
 
+	if ap.NodePool == that.NodePool {
+		result.NodePool = ap.NodePool
+	}
+
 	return result
 }
 
@@ -358,6 +386,10 @@ func (ap *AssetProperties) String() string {
 		strs = append(strs, "ProviderID:"+ap.ProviderID)
 	}
 
+	if ap.NodePool != "" {
+		strs = append(strs, "NodePool:"+ap.NodePool)
+	}
+
 	return strings.Join(strs, ",")
 }
 
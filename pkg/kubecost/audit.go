@@ -189,6 +189,10 @@ type AssetReconciliationAudit struct {
 
 // Clone returns a deep copy of the caller
 func (ara *AssetReconciliationAudit) Clone() *AssetReconciliationAudit {
+	if ara == nil {
+		return nil
+	}
+
 	res := make(map[string]map[string]*AuditFloatResult, len(ara.Results))
 	for aggType, aggResults := range ara.Results {
 		copyAggResult := make(map[string]*AuditFloatResult, len(aggResults))
@@ -252,15 +256,28 @@ func NewAuditCoverage() *AuditCoverage {
 // Update expands the coverage of each Window in the coverage that the given AuditSet's Window if the corresponding Audit is not nil
 // Note: This means of determining coverage can lead to holes in the given window
 func (ac *AuditCoverage) Update(as *AuditSet) {
-	if as != nil && as.AllocationReconciliation != nil {
-		ac.AllocationReconciliation.Expand(as.Window)
-		ac.AllocationAgg.Expand(as.Window)
-		ac.AllocationTotal.Expand(as.Window)
-		ac.AssetTotal.Expand(as.Window)
-		ac.AssetReconciliation.Expand(as.Window)
-		ac.ClusterEquality.Expand(as.Window)
+	if as == nil {
+		return
 	}
 
+	if as.AllocationReconciliation != nil {
+		ac.AllocationReconciliation = ac.AllocationReconciliation.Expand(as.Window)
+	}
+	if as.AllocationAgg != nil {
+		ac.AllocationAgg = ac.AllocationAgg.Expand(as.Window)
+	}
+	if as.AllocationTotal != nil {
+		ac.AllocationTotal = ac.AllocationTotal.Expand(as.Window)
+	}
+	if as.AssetTotal != nil {
+		ac.AssetTotal = ac.AssetTotal.Expand(as.Window)
+	}
+	if as.AssetReconciliation != nil {
+		ac.AssetReconciliation = ac.AssetReconciliation.Expand(as.Window)
+	}
+	if as.ClusterEquality != nil {
+		ac.ClusterEquality = ac.ClusterEquality.Expand(as.Window)
+	}
 }
 
 // AuditSet is a ETLSet which contains all kind of Audits for a given Window
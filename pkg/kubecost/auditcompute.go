@@ -0,0 +1,469 @@
+package kubecost
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultAuditTolerance is the relative difference between the expected and
+// actual values of an audit beyond which the audit fails.
+const DefaultAuditTolerance = 0.01
+
+// auditCostThreshold is the absolute difference, in cost, below which expected
+// and actual values are considered equal regardless of tolerance, so that
+// rounding on tiny values does not fail audits.
+const auditCostThreshold = 0.001
+
+// Divergence returns the relative difference between the actual and expected
+// values, or the absolute difference if the expected value is zero.
+func (afr *AuditFloatResult) Divergence() float64 {
+	if afr == nil {
+		return 0.0
+	}
+
+	diff := math.Abs(afr.Actual - afr.Expected)
+	if afr.Expected == 0.0 {
+		return diff
+	}
+
+	return diff / math.Abs(afr.Expected)
+}
+
+// IsWithinTolerance returns true if the actual value is equal to the expected
+// value, within the given relative tolerance.
+func (afr *AuditFloatResult) IsWithinTolerance(tolerance float64) bool {
+	if afr == nil {
+		return true
+	}
+
+	if math.Abs(afr.Actual-afr.Expected) <= auditCostThreshold {
+		return true
+	}
+
+	return afr.Divergence() <= tolerance
+}
+
+// MaxDivergence returns the largest divergence of the audits in the set, by
+// audit type, for those audits which have been run.
+func (as *AuditSet) MaxDivergence() map[AuditType]float64 {
+	if as == nil {
+		return nil
+	}
+
+	as.RLock()
+	defer as.RUnlock()
+
+	divergence := map[AuditType]float64{}
+
+	if as.AllocationReconciliation != nil {
+		max := 0.0
+		for _, resources := range as.AllocationReconciliation.Resources {
+			max = math.Max(max, maxAuditDivergence(resources))
+		}
+		divergence[AuditAllocationReconciliation] = max
+	}
+
+	if as.AllocationAgg != nil {
+		max := 0.0
+		for _, results := range as.AllocationAgg.Results {
+			max = math.Max(max, maxAuditDivergence(results))
+		}
+		divergence[AuditAllocationAggStore] = max
+	}
+
+	if as.AllocationTotal != nil {
+		divergence[AuditAllocationTotalStore] = math.Max(maxAuditDivergence(as.AllocationTotal.TotalByCluster), maxAuditDivergence(as.AllocationTotal.TotalByNode))
+	}
+
+	if as.AssetTotal != nil {
+		divergence[AuditAssetTotalStore] = math.Max(maxAuditDivergence(as.AssetTotal.TotalByCluster), maxAuditDivergence(as.AssetTotal.TotalByNode))
+	}
+
+	if as.AssetReconciliation != nil {
+		max := 0.0
+		for _, results := range as.AssetReconciliation.Results {
+			max = math.Max(max, maxAuditDivergence(results))
+		}
+		divergence[AuditAssetReconciliation] = max
+	}
+
+	if as.ClusterEquality != nil {
+		divergence[AuditClusterEquality] = maxAuditDivergence(as.ClusterEquality.Clusters)
+	}
+
+	return divergence
+}
+
+// Statuses returns the status of each audit in the set which has been run.
+func (as *AuditSet) Statuses() map[AuditType]AuditStatus {
+	if as == nil {
+		return nil
+	}
+
+	as.RLock()
+	defer as.RUnlock()
+
+	statuses := map[AuditType]AuditStatus{}
+	if as.AllocationReconciliation != nil {
+		statuses[AuditAllocationReconciliation] = as.AllocationReconciliation.Status
+	}
+	if as.AllocationAgg != nil {
+		statuses[AuditAllocationAggStore] = as.AllocationAgg.Status
+	}
+	if as.AllocationTotal != nil {
+		statuses[AuditAllocationTotalStore] = as.AllocationTotal.Status
+	}
+	if as.AssetTotal != nil {
+		statuses[AuditAssetTotalStore] = as.AssetTotal.Status
+	}
+	if as.AssetReconciliation != nil {
+		statuses[AuditAssetReconciliation] = as.AssetReconciliation.Status
+	}
+	if as.ClusterEquality != nil {
+		statuses[AuditClusterEquality] = as.ClusterEquality.Status
+	}
+
+	return statuses
+}
+
+// Filter returns a copy of the AuditSet containing only the audit of the given
+// type, or every audit for AuditAll.
+func (as *AuditSet) Filter(auditType AuditType) *AuditSet {
+	if as == nil {
+		return nil
+	}
+
+	clone := as.Clone()
+	if auditType == AuditAll {
+		return clone
+	}
+
+	filtered := &AuditSet{Window: clone.Window}
+	switch auditType {
+	case AuditAllocationReconciliation:
+		filtered.AllocationReconciliation = clone.AllocationReconciliation
+	case AuditAllocationAggStore:
+		filtered.AllocationAgg = clone.AllocationAgg
+	case AuditAllocationTotalStore:
+		filtered.AllocationTotal = clone.AllocationTotal
+	case AuditAssetTotalStore:
+		filtered.AssetTotal = clone.AssetTotal
+	case AuditAssetReconciliation:
+		filtered.AssetReconciliation = clone.AssetReconciliation
+	case AuditClusterEquality:
+		filtered.ClusterEquality = clone.ClusterEquality
+	}
+
+	return filtered
+}
+
+// NewAllocationReconciliationAudit compares the compute costs of the given
+// Allocations, including idle, with those of the nodes in the given Assets,
+// by node and resource. The Allocations of each node, with the idle allocation
+// of the node, are expected to cost exactly what the node costs.
+func NewAllocationReconciliationAudit(allocSet *AllocationSet, assetSet *AssetSet, tolerance float64) *AllocationReconciliationAudit {
+	audit := &AllocationReconciliationAudit{
+		Description: "Compares the compute costs of the allocations on each node, including idle, with the cost of the node",
+		LastRun:     time.Now().UTC(),
+		Resources:   map[string]map[string]*AuditFloatResult{},
+	}
+
+	for key, art := range ComputeAssetTotals(assetSet, AssetNodeProp) {
+		audit.Resources[key] = map[string]*AuditFloatResult{
+			"cpu": {Expected: art.TotalCPUCost()},
+			"ram": {Expected: art.TotalRAMCost()},
+			"gpu": {Expected: art.TotalGPUCost()},
+		}
+	}
+
+	for _, alloc := range allocSet.Allocations {
+		if alloc.Properties == nil || alloc.Properties.Node == "" {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s", alloc.Properties.Cluster, alloc.Properties.Node)
+		resources, ok := audit.Resources[key]
+		if !ok {
+			audit.MissingValues = append(audit.MissingValues, &AuditMissingValue{
+				Description: "node of allocation missing from assets",
+				Key:         alloc.Name,
+			})
+			continue
+		}
+
+		resources["cpu"].Actual += alloc.CPUTotalCost()
+		resources["ram"].Actual += alloc.RAMTotalCost()
+		resources["gpu"].Actual += alloc.GPUTotalCost()
+	}
+
+	results := []*AuditFloatResult{}
+	for _, resources := range audit.Resources {
+		for _, res := range resources {
+			results = append(results, res)
+		}
+	}
+	audit.Status = auditStatus(results, audit.MissingValues, tolerance)
+
+	return audit
+}
+
+// NewAllocationTotalAudit compares the totals, by cluster and by node, of the
+// Allocations computed over a window with those accumulated from the
+// Allocations computed over the steps of the same window.
+func NewAllocationTotalAudit(expected, actual *AllocationSet, tolerance float64) *TotalAudit {
+	audit := &TotalAudit{
+		Description: "Compares allocation totals computed over the window with those accumulated over its steps",
+		LastRun:     time.Now().UTC(),
+	}
+
+	var byCluster, byNode []*AuditMissingValue
+	audit.TotalByCluster, byCluster = auditTotals(allocationTotalCosts(ComputeAllocationTotals(expected, AllocationClusterProp)), allocationTotalCosts(ComputeAllocationTotals(actual, AllocationClusterProp)))
+	audit.TotalByNode, byNode = auditTotals(allocationTotalCosts(ComputeAllocationTotals(expected, AllocationNodeProp)), allocationTotalCosts(ComputeAllocationTotals(actual, AllocationNodeProp)))
+	audit.MissingValues = append(byCluster, byNode...)
+	audit.Status = auditTotalStatus(audit, tolerance)
+
+	return audit
+}
+
+// NewAssetTotalAudit compares the totals, by cluster and by node, of the Assets
+// computed over a window with those accumulated from the Assets computed over
+// the steps of the same window.
+func NewAssetTotalAudit(expected, actual *AssetSet, tolerance float64) *TotalAudit {
+	audit := &TotalAudit{
+		Description: "Compares asset totals computed over the window with those accumulated over its steps",
+		LastRun:     time.Now().UTC(),
+	}
+
+	var byCluster, byNode []*AuditMissingValue
+	audit.TotalByCluster, byCluster = auditTotals(assetTotalCosts(ComputeAssetTotals(expected, AssetClusterProp)), assetTotalCosts(ComputeAssetTotals(actual, AssetClusterProp)))
+	audit.TotalByNode, byNode = auditTotals(assetTotalCosts(ComputeAssetTotals(expected, AssetNodeProp)), assetTotalCosts(ComputeAssetTotals(actual, AssetNodeProp)))
+	audit.MissingValues = append(byCluster, byNode...)
+	audit.Status = auditTotalStatus(audit, tolerance)
+
+	return audit
+}
+
+// NewAllocationAggAudit compares the cost of each Allocation produced by
+// aggregating the given set by each of the given properties with the sum of
+// the costs of the Allocations it aggregates. Idle Allocations are excluded.
+// The given set is not modified.
+func NewAllocationAggAudit(as *AllocationSet, aggregateBy []string, tolerance float64) (*AggAudit, error) {
+	audit := &AggAudit{
+		Description: "Compares aggregated allocations with the sum of the allocations they aggregate",
+		LastRun:     time.Now().UTC(),
+		Results:     map[string]map[string]*AuditFloatResult{},
+	}
+
+	labelConfig := NewLabelConfig()
+
+	results := []*AuditFloatResult{}
+	for _, prop := range aggregateBy {
+		parts := map[string]float64{}
+		for _, alloc := range as.Allocations {
+			if alloc.IsIdle() {
+				continue
+			}
+			parts[alloc.generateKey([]string{prop}, labelConfig)] += alloc.TotalCost()
+		}
+
+		agg := as.Clone()
+		for name, alloc := range agg.Allocations {
+			if alloc.IsIdle() {
+				agg.Delete(name)
+			}
+		}
+
+		err := agg.AggregateBy([]string{prop}, &AllocationAggregationOptions{LabelConfig: labelConfig})
+		if err != nil {
+			return nil, fmt.Errorf("aggregating by %s: %w", prop, err)
+		}
+
+		propResults := map[string]*AuditFloatResult{}
+		for name, cost := range parts {
+			alloc, ok := agg.Allocations[name]
+			if !ok {
+				audit.MissingValues = append(audit.MissingValues, &AuditMissingValue{
+					Description: fmt.Sprintf("missing from aggregation by %s", prop),
+					Key:         name,
+				})
+				continue
+			}
+
+			propResults[name] = &AuditFloatResult{Expected: cost, Actual: alloc.TotalCost()}
+			results = append(results, propResults[name])
+		}
+		audit.Results[prop] = propResults
+	}
+
+	audit.Status = auditStatus(results, audit.MissingValues, tolerance)
+
+	return audit, nil
+}
+
+// NewAssetReconciliationAudit compares the compute costs of the nodes in the
+// given Assets with those expected from the hourly prices of the cloud
+// provider, by node and resource. Prices are keyed by "cluster/node", then by
+// resource: "cpu" per core-hour, "ram" per GiB-hour and "gpu" per GPU-hour.
+// Costs are compared before discounts. Nodes without prices, e.g. those which
+// no longer exist, are not audited, nor are priced nodes missing from the
+// Assets, e.g. those created since.
+func NewAssetReconciliationAudit(assetSet *AssetSet, prices map[string]map[string]float64, tolerance float64) *AssetReconciliationAudit {
+	audit := &AssetReconciliationAudit{
+		Description: "Compares the compute costs of each node with those expected from the prices of the cloud provider",
+		LastRun:     time.Now().UTC(),
+		Results:     map[string]map[string]*AuditFloatResult{},
+	}
+
+	results := []*AuditFloatResult{}
+	for _, node := range assetSet.Nodes {
+		if node.Properties == nil {
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s", node.Properties.Cluster, node.Properties.Name)
+		nodePrices, ok := prices[key]
+		if !ok {
+			continue
+		}
+
+		resources := map[string]*AuditFloatResult{
+			"cpu": {Expected: node.CPUCoreHours * nodePrices["cpu"], Actual: node.CPUCost},
+			"ram": {Expected: node.RAMByteHours / 1024 / 1024 / 1024 * nodePrices["ram"], Actual: node.RAMCost},
+			"gpu": {Expected: node.GPUHours * nodePrices["gpu"], Actual: node.GPUCost},
+		}
+		for _, res := range resources {
+			results = append(results, res)
+		}
+		audit.Results[key] = resources
+	}
+
+	audit.Status = auditStatus(results, audit.MissingValues, tolerance)
+
+	return audit
+}
+
+// NewClusterEqualityAudit compares the total cost of the given Allocations,
+// including idle, with that of the given Assets, by cluster. Only the Assets
+// which are allocated, i.e. nodes, disks, load balancers, network and
+// snapshots, are counted.
+func NewClusterEqualityAudit(allocSet *AllocationSet, assetSet *AssetSet, tolerance float64) *EqualityAudit {
+	audit := &EqualityAudit{
+		Description: "Compares the total cost of allocations, including idle, with that of the allocated assets by cluster",
+		LastRun:     time.Now().UTC(),
+		Clusters:    map[string]*AuditFloatResult{},
+	}
+
+	expected := map[string]float64{}
+	for _, asset := range assetSet.Assets {
+		switch asset.Type() {
+		case NodeAssetType, DiskAssetType, LoadBalancerAssetType, NetworkAssetType, SnapshotAssetType:
+		default:
+			continue
+		}
+
+		cluster := ""
+		if asset.GetProperties() != nil {
+			cluster = asset.GetProperties().Cluster
+		}
+		expected[cluster] += asset.TotalCost()
+	}
+
+	actual := map[string]float64{}
+	for _, alloc := range allocSet.Allocations {
+		cluster := ""
+		if alloc.Properties != nil {
+			cluster = alloc.Properties.Cluster
+		}
+		actual[cluster] += alloc.TotalCost()
+	}
+
+	audit.Clusters, audit.MissingValues = auditTotals(expected, actual)
+	results := make([]*AuditFloatResult, 0, len(audit.Clusters))
+	for _, res := range audit.Clusters {
+		results = append(results, res)
+	}
+	audit.Status = auditStatus(results, audit.MissingValues, tolerance)
+
+	return audit
+}
+
+func allocationTotalCosts(totals map[string]*AllocationTotals) map[string]float64 {
+	costs := make(map[string]float64, len(totals))
+	for key, art := range totals {
+		costs[key] = art.TotalCost()
+	}
+	return costs
+}
+
+func assetTotalCosts(totals map[string]*AssetTotals) map[string]float64 {
+	costs := make(map[string]float64, len(totals))
+	for key, art := range totals {
+		costs[key] = art.TotalCost()
+	}
+	return costs
+}
+
+// auditTotals pairs the expected and actual costs by key, recording keys
+// missing from either side as missing values.
+func auditTotals(expected, actual map[string]float64) (map[string]*AuditFloatResult, []*AuditMissingValue) {
+	results := map[string]*AuditFloatResult{}
+	missing := []*AuditMissingValue{}
+
+	for key, cost := range expected {
+		results[key] = &AuditFloatResult{Expected: cost, Actual: actual[key]}
+		if _, ok := actual[key]; !ok {
+			missing = append(missing, &AuditMissingValue{Description: "missing from actual results", Key: key})
+		}
+	}
+
+	for key, cost := range actual {
+		if _, ok := expected[key]; !ok {
+			results[key] = &AuditFloatResult{Actual: cost}
+			missing = append(missing, &AuditMissingValue{Description: "missing from expected results", Key: key})
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Key < missing[j].Key
+	})
+
+	return results, missing
+}
+
+func auditTotalStatus(audit *TotalAudit, tolerance float64) AuditStatus {
+	results := make([]*AuditFloatResult, 0, len(audit.TotalByCluster)+len(audit.TotalByNode))
+	for _, res := range audit.TotalByCluster {
+		results = append(results, res)
+	}
+	for _, res := range audit.TotalByNode {
+		results = append(results, res)
+	}
+
+	return auditStatus(results, audit.MissingValues, tolerance)
+}
+
+// auditStatus fails if any result diverges beyond the given tolerance, warns
+// if any value is missing, and passes otherwise.
+func auditStatus(results []*AuditFloatResult, missing []*AuditMissingValue, tolerance float64) AuditStatus {
+	for _, res := range results {
+		if !res.IsWithinTolerance(tolerance) {
+			return FailedStatus
+		}
+	}
+
+	if len(missing) > 0 {
+		return WarningStatus
+	}
+
+	return PassedStatus
+}
+
+func maxAuditDivergence(results map[string]*AuditFloatResult) float64 {
+	max := 0.0
+	for _, res := range results {
+		max = math.Max(max, res.Divergence())
+	}
+	return max
+}
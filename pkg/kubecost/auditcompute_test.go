@@ -0,0 +1,207 @@
+package kubecost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/util"
+)
+
+func generateAuditSets(start time.Time) (*AllocationSet, *AssetSet) {
+	end := start.Add(day)
+
+	node := NewNode("node1", "cluster1", "node1", start, end, NewWindow(&start, &end))
+	node.CPUCost = 10.0
+	node.RAMCost = 5.0
+	node.GPUCost = 2.0
+
+	alloc := NewMockUnitAllocation("cluster1/node1/namespace1/pod1/container1", start, day, nil)
+	alloc.CPUCost = 6.0
+	alloc.RAMCost = 3.0
+	alloc.GPUCost = 2.0
+	alloc.PVs = nil
+	alloc.NetworkCost = 0.0
+	alloc.LoadBalancerCost = 0.0
+
+	idle := NewMockUnitAllocation("cluster1/node1/__idle__", start, day, &AllocationProperties{Cluster: "cluster1", Node: "node1"})
+	idle.CPUCost = 4.0
+	idle.RAMCost = 2.0
+	idle.GPUCost = 0.0
+
+	return NewAllocationSet(start, end, alloc, idle), NewAssetSet(start, end, node)
+}
+
+func TestNewAllocationReconciliationAudit(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	as, assets := generateAuditSets(start)
+	audit := NewAllocationReconciliationAudit(as, assets, DefaultAuditTolerance)
+	if audit.Status != PassedStatus {
+		t.Fatalf("expected %s; got %s: %v", PassedStatus, audit.Status, audit.MissingValues)
+	}
+
+	// Allocations which do not account for the cost of the node fail
+	as.Allocations["cluster1/node1/__idle__"].CPUCost = 3.0
+	audit = NewAllocationReconciliationAudit(as, assets, DefaultAuditTolerance)
+	if audit.Status != FailedStatus {
+		t.Fatalf("expected %s; got %s", FailedStatus, audit.Status)
+	}
+	cpu := audit.Resources["cluster1/node1"]["cpu"]
+	if cpu.Expected != 10.0 || cpu.Actual != 9.0 {
+		t.Fatalf("expected cpu 10.0 and 9.0; got %f and %f", cpu.Expected, cpu.Actual)
+	}
+
+	// Allocations on nodes which are missing from the assets warn
+	as.Allocations["cluster1/node1/__idle__"].CPUCost = 4.0
+	alloc := NewMockUnitAllocation("cluster1/node2/namespace1/pod2/container1", start, day, &AllocationProperties{Cluster: "cluster1", Node: "node2", Namespace: "namespace1"})
+	as.Insert(alloc)
+	audit = NewAllocationReconciliationAudit(as, assets, DefaultAuditTolerance)
+	if audit.Status != WarningStatus || len(audit.MissingValues) != 1 {
+		t.Fatalf("expected %s with 1 missing value; got %s with %d", WarningStatus, audit.Status, len(audit.MissingValues))
+	}
+}
+
+func TestNewClusterEqualityAudit(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	as, assets := generateAuditSets(start)
+	audit := NewClusterEqualityAudit(as, assets, DefaultAuditTolerance)
+	if audit.Status != PassedStatus {
+		t.Fatalf("expected %s; got %s: %v", PassedStatus, audit.Status, audit.MissingValues)
+	}
+	if !util.IsApproximately(audit.Clusters["cluster1"].Actual, 17.0) {
+		t.Fatalf("expected cluster1 to cost 17.0; got %f", audit.Clusters["cluster1"].Actual)
+	}
+
+	// Within tolerance, audits pass
+	as.Allocations["cluster1/node1/__idle__"].RAMCost += 0.1
+	audit = NewClusterEqualityAudit(as, assets, 0.05)
+	if audit.Status != PassedStatus {
+		t.Fatalf("expected %s; got %s", PassedStatus, audit.Status)
+	}
+
+	audit = NewClusterEqualityAudit(as, assets, 0.001)
+	if audit.Status != FailedStatus {
+		t.Fatalf("expected %s; got %s", FailedStatus, audit.Status)
+	}
+}
+
+func TestNewAssetReconciliationAudit(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, assets := generateAuditSets(start)
+	var node *Node
+	for _, n := range assets.Nodes {
+		node = n
+	}
+	node.CPUCoreHours = 20.0
+	node.RAMByteHours = 10.0 * 1024 * 1024 * 1024
+	node.GPUHours = 2.0
+
+	prices := map[string]map[string]float64{
+		"cluster1/node1": {"cpu": 0.5, "ram": 0.5, "gpu": 1.0},
+		// Nodes created since are not audited
+		"cluster1/node2": {"cpu": 0.5, "ram": 0.5, "gpu": 1.0},
+	}
+
+	audit := NewAssetReconciliationAudit(assets, prices, DefaultAuditTolerance)
+	if audit.Status != PassedStatus {
+		t.Fatalf("expected %s; got %s: %v", PassedStatus, audit.Status, audit.MissingValues)
+	}
+	if len(audit.Results) != 1 {
+		t.Fatalf("expected 1 audited node; got %d", len(audit.Results))
+	}
+
+	// Nodes costed at other prices than the cloud provider's fail
+	prices["cluster1/node1"]["ram"] = 0.6
+	audit = NewAssetReconciliationAudit(assets, prices, DefaultAuditTolerance)
+	if audit.Status != FailedStatus {
+		t.Fatalf("expected %s; got %s", FailedStatus, audit.Status)
+	}
+	ram := audit.Results["cluster1/node1"]["ram"]
+	if !util.IsApproximately(ram.Expected, 6.0) || !util.IsApproximately(ram.Actual, 5.0) {
+		t.Fatalf("expected ram 6.0 and 5.0; got %f and %f", ram.Expected, ram.Actual)
+	}
+}
+
+func TestNewAllocationTotalAudit(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	expected := GenerateMockAllocationSetClusterIdle(start)
+	actual := expected.Clone()
+
+	audit := NewAllocationTotalAudit(expected, actual, DefaultAuditTolerance)
+	if audit.Status != PassedStatus {
+		t.Fatalf("expected %s; got %s: %v", PassedStatus, audit.Status, audit.MissingValues)
+	}
+	if len(audit.TotalByCluster) != 2 {
+		t.Fatalf("expected totals for 2 clusters; got %d", len(audit.TotalByCluster))
+	}
+
+	for _, alloc := range actual.Allocations {
+		if !alloc.IsIdle() && alloc.Properties.Cluster == "cluster2" {
+			alloc.CPUCost += 10.0
+			break
+		}
+	}
+
+	audit = NewAllocationTotalAudit(expected, actual, DefaultAuditTolerance)
+	if audit.Status != FailedStatus {
+		t.Fatalf("expected %s; got %s", FailedStatus, audit.Status)
+	}
+	if audit.TotalByCluster["cluster1"].Divergence() != 0.0 {
+		t.Fatalf("expected cluster1 to match; got %v", audit.TotalByCluster["cluster1"])
+	}
+	if !util.IsApproximately(audit.TotalByCluster["cluster2"].Actual-audit.TotalByCluster["cluster2"].Expected, 10.0) {
+		t.Fatalf("expected cluster2 to diverge by 10.0; got %v", audit.TotalByCluster["cluster2"])
+	}
+}
+
+func TestNewAllocationAggAudit(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	as := GenerateMockAllocationSetClusterIdle(start)
+	numAllocs := as.Length()
+
+	audit, err := NewAllocationAggAudit(as, []string{AllocationClusterProp, AllocationNamespaceProp}, DefaultAuditTolerance)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if as.Length() != numAllocs {
+		t.Fatalf("expected set to be unmodified with %d allocations; got %d", numAllocs, as.Length())
+	}
+	if audit.Status != PassedStatus {
+		t.Fatalf("expected %s; got %s: %v", PassedStatus, audit.Status, audit.MissingValues)
+	}
+
+	expected := 0.0
+	for _, alloc := range as.Allocations {
+		if !alloc.IsIdle() && alloc.Properties.Namespace == "namespace1" {
+			expected += alloc.TotalCost()
+		}
+	}
+	res, ok := audit.Results[AllocationNamespaceProp]["namespace1"]
+	if !ok || !util.IsApproximately(res.Expected, expected) || !util.IsApproximately(res.Actual, expected) {
+		t.Fatalf("expected namespace1 to cost %f; got %v", expected, res)
+	}
+}
+
+func TestAuditSet_MaxDivergence(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	as, assets := generateAuditSets(start)
+	as.Allocations["cluster1/node1/__idle__"].CPUCost = 3.0
+
+	auditSet := NewAuditSet(start, start.Add(day))
+	auditSet.AllocationReconciliation = NewAllocationReconciliationAudit(as, assets, DefaultAuditTolerance)
+
+	divergence := auditSet.MaxDivergence()
+	if len(divergence) != 1 || !util.IsApproximately(divergence[AuditAllocationReconciliation], 0.1) {
+		t.Fatalf("expected divergence of 0.1; got %v", divergence)
+	}
+
+	filtered := auditSet.Filter(AuditClusterEquality)
+	if !filtered.IsEmpty() {
+		t.Fatalf("expected filtered set to be empty")
+	}
+}
@@ -14,8 +14,14 @@ const (
 	CloudCostBillingIDProp   string = "billingID"
 	CloudCostWorkGroupIDProp string = "workGroupID"
 	CloudCostProviderProp    string = "provider"
+	CloudCostProviderIDProp  string = "providerID"
 	CloudCostServiceProp     string = "service"
+	CloudCostCategoryProp    string = "category"
 	CloudCostLabelProp       string = "label"
+
+	CloudCostCostProp              string = "cost"
+	CloudCostNetCostProp           string = "netCost"
+	CloudCostKubernetesPercentProp string = "kubernetesPercent"
 )
 
 // CloudCostAggregateProperties unique property set for CloudCostAggregate within a window
@@ -166,6 +172,23 @@ func (cca *CloudCostAggregate) StringProperty(prop string) (string, error) {
 	}
 }
 
+func (cca *CloudCostAggregate) NumericProperty(prop string) (float64, error) {
+	if cca == nil {
+		return 0, nil
+	}
+
+	switch prop {
+	case CloudCostCostProp:
+		return cca.Cost, nil
+	case CloudCostNetCostProp:
+		return cca.NetCost, nil
+	case CloudCostKubernetesPercentProp:
+		return cca.KubernetesPercent, nil
+	default:
+		return 0, fmt.Errorf("invalid property name: %s", prop)
+	}
+}
+
 func (cca *CloudCostAggregate) add(that *CloudCostAggregate) {
 	if cca == nil {
 		log.Warnf("cannot add to nil CloudCostAggregate")
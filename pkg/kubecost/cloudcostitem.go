@@ -105,6 +105,57 @@ func NewCloudCostItem(start, end time.Time, cciProperties CloudCostItemPropertie
 	}
 }
 
+func (cci *CloudCostItem) StringProperty(prop string) (string, error) {
+	if cci == nil {
+		return "", nil
+	}
+
+	switch prop {
+	case CloudCostProviderIDProp:
+		return cci.Properties.ProviderID, nil
+	case CloudCostProviderProp:
+		return cci.Properties.Provider, nil
+	case CloudCostWorkGroupIDProp:
+		return cci.Properties.WorkGroupID, nil
+	case CloudCostBillingIDProp:
+		return cci.Properties.BillingID, nil
+	case CloudCostServiceProp:
+		return cci.Properties.Service, nil
+	case CloudCostCategoryProp:
+		return cci.Properties.Category, nil
+	default:
+		return "", fmt.Errorf("invalid property name: %s", prop)
+	}
+}
+
+func (cci *CloudCostItem) StringMapProperty(prop string) (map[string]string, error) {
+	if cci == nil {
+		return nil, nil
+	}
+
+	switch prop {
+	case CloudCostLabelProp:
+		return cci.Properties.Labels, nil
+	default:
+		return nil, fmt.Errorf("invalid property name: %s", prop)
+	}
+}
+
+func (cci *CloudCostItem) NumericProperty(prop string) (float64, error) {
+	if cci == nil {
+		return 0, nil
+	}
+
+	switch prop {
+	case CloudCostCostProp:
+		return cci.Cost, nil
+	case CloudCostNetCostProp:
+		return cci.NetCost, nil
+	default:
+		return 0, fmt.Errorf("invalid property name: %s", prop)
+	}
+}
+
 func (cci *CloudCostItem) Clone() *CloudCostItem {
 	return &CloudCostItem{
 		Properties:   cci.Properties.Clone(),
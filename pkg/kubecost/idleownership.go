@@ -0,0 +1,159 @@
+package kubecost
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ShareOwner indicates that idle should be charged to its owner; that is,
+// the idle of each node owned by an IdleOwner is shared, by weight, among the
+// allocations of that owner in the same cluster. Idle which is not owned is
+// shared by weight if ShareUnownedIdle, or else left as idle.
+const ShareOwner = "__owner__"
+
+// IdleOwner owns the idle allocations matching Idle, e.g. those of the nodes
+// of a node pool dedicated to a team, and the allocations matching Owned,
+// e.g. those of the team's namespaces, which are charged for that idle.
+type IdleOwner struct {
+	Name  string
+	Idle  AllocationMatchFunc
+	Owned AllocationFilter
+}
+
+// Validate returns an error if the owner cannot be applied.
+func (io *IdleOwner) Validate() error {
+	if io == nil {
+		return fmt.Errorf("nil idle owner")
+	}
+
+	if io.Name == "" {
+		return fmt.Errorf("idle owner has no name")
+	}
+
+	if io.Idle == nil {
+		return fmt.Errorf("idle owner %s has no idle match", io.Name)
+	}
+
+	if io.Owned == nil {
+		return fmt.Errorf("idle owner %s has no owned filter", io.Name)
+	}
+
+	return nil
+}
+
+// computeOwnedIdle charges the idle allocations of idleSet which are owned by
+// one of the given owners, the first matching owner winning, to the owner's
+// allocations of the same cluster in the given set. Each resource of the idle
+// is shared in proportion to the owned allocations' cost of that resource.
+// The charged idle is returned, keyed by allocation name, and removed from
+// idleSet, so that only idle which is not owned, or for whose resources the
+// owner has no cost, remains to be shared otherwise.
+func computeOwnedIdle(owners []*IdleOwner, as *AllocationSet, idleSet *AllocationSet) (map[string]*Allocation, error) {
+	ownedIdle := map[string]*Allocation{}
+	if len(owners) == 0 || idleSet.Length() == 0 {
+		return ownedIdle, nil
+	}
+
+	for _, owner := range owners {
+		if err := owner.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Owned allocations and their totals, by owner and cluster
+	type ownedTotals struct {
+		allocs []*Allocation
+		cpu    float64
+		gpu    float64
+		ram    float64
+	}
+	owned := make([]map[string]*ownedTotals, len(owners))
+	for i, owner := range owners {
+		owned[i] = map[string]*ownedTotals{}
+		for _, alloc := range as.Allocations {
+			if alloc.IsIdle() || alloc.Properties == nil || !owner.Owned.Matches(alloc) {
+				continue
+			}
+
+			totals, ok := owned[i][alloc.Properties.Cluster]
+			if !ok {
+				totals = &ownedTotals{}
+				owned[i][alloc.Properties.Cluster] = totals
+			}
+
+			totals.allocs = append(totals.allocs, alloc)
+			totals.cpu += alloc.CPUTotalCost()
+			totals.gpu += alloc.GPUTotalCost()
+			totals.ram += alloc.RAMTotalCost()
+		}
+	}
+
+	// Charge idle in a stable order, so that results are reproducible
+	idleNames := make([]string, 0, idleSet.Length())
+	for name := range idleSet.Allocations {
+		idleNames = append(idleNames, name)
+	}
+	sort.Strings(idleNames)
+
+	for _, name := range idleNames {
+		idleAlloc := idleSet.Allocations[name]
+		if idleAlloc.Properties == nil {
+			continue
+		}
+
+		for i, owner := range owners {
+			if !owner.Idle(idleAlloc) {
+				continue
+			}
+
+			totals, ok := owned[i][idleAlloc.Properties.Cluster]
+			if !ok {
+				break
+			}
+
+			for _, alloc := range totals.allocs {
+				charged, ok := ownedIdle[alloc.Name]
+				if !ok {
+					charged = &Allocation{Name: alloc.Name}
+					ownedIdle[alloc.Name] = charged
+				}
+
+				if totals.cpu > 0 {
+					coeff := alloc.CPUTotalCost() / totals.cpu
+					charged.CPUCoreHours += idleAlloc.CPUCoreHours * coeff
+					charged.CPUCost += idleAlloc.CPUCost * coeff
+				}
+				if totals.gpu > 0 {
+					coeff := alloc.GPUTotalCost() / totals.gpu
+					charged.GPUHours += idleAlloc.GPUHours * coeff
+					charged.GPUCost += idleAlloc.GPUCost * coeff
+				}
+				if totals.ram > 0 {
+					coeff := alloc.RAMTotalCost() / totals.ram
+					charged.RAMByteHours += idleAlloc.RAMByteHours * coeff
+					charged.RAMCost += idleAlloc.RAMCost * coeff
+				}
+			}
+
+			if totals.cpu > 0 {
+				idleAlloc.CPUCoreHours = 0
+				idleAlloc.CPUCost = 0
+			}
+			if totals.gpu > 0 {
+				idleAlloc.GPUHours = 0
+				idleAlloc.GPUCost = 0
+			}
+			if totals.ram > 0 {
+				idleAlloc.RAMByteHours = 0
+				idleAlloc.RAMCost = 0
+			}
+			if idleAlloc.CPUCost == 0 && idleAlloc.GPUCost == 0 && idleAlloc.RAMCost == 0 {
+				idleSet.Delete(name)
+			}
+
+			break
+		}
+	}
+
+	return ownedIdle, nil
+}
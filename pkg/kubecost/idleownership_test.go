@@ -0,0 +1,154 @@
+package kubecost
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/util"
+)
+
+func TestAllocationSet_AggregateBy_ShareOwner(t *testing.T) {
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	newAlloc := func(namespace, node string, cpuCost, ramCost float64) *Allocation {
+		props := &AllocationProperties{
+			Cluster:   "cluster1",
+			Node:      node,
+			Namespace: namespace,
+			Pod:       fmt.Sprintf("%s-%s", namespace, node),
+			Container: "container1",
+		}
+		alloc := NewMockUnitAllocation(fmt.Sprintf("cluster1/%s/%s/%s", node, namespace, props.Pod), start, 24*time.Hour, props)
+		alloc.CPUCost = cpuCost
+		alloc.RAMCost = ramCost
+		alloc.GPUCost = 0
+		return alloc
+	}
+
+	newIdle := func(node string, cpuCost, gpuCost float64) *Allocation {
+		return &Allocation{
+			Name:       fmt.Sprintf("cluster1/%s/%s", node, IdleSuffix),
+			Properties: &AllocationProperties{Cluster: "cluster1", Node: node, ProviderID: node},
+			Window:     NewWindow(&start, &end),
+			Start:      start,
+			End:        end,
+			CPUCost:    cpuCost,
+			GPUCost:    gpuCost,
+		}
+	}
+
+	// team-a owns node1, on which it runs namespace1. Its idle is charged to
+	// team-a's allocations by CPU cost, including those on node2, while the
+	// idle of node2 is shared by weight, or not, by ShareUnownedIdle.
+	owners := []*IdleOwner{
+		{
+			Name:  "team-a",
+			Idle:  func(a *Allocation) bool { return a.Properties.Node == "node1" },
+			Owned: AllocationFilterCondition{Field: FilterNamespace, Op: FilterEquals, Value: "namespace1"},
+		},
+	}
+
+	newSet := func() *AllocationSet {
+		return NewAllocationSet(start, end,
+			newAlloc("namespace1", "node1", 3, 1),
+			newAlloc("namespace1", "node2", 1, 1),
+			newAlloc("namespace2", "node2", 1, 1),
+			newIdle("node1", 8, 2),
+			newIdle("node2", 4, 0),
+		)
+	}
+
+	cases := map[string]struct {
+		shareIdle        string
+		shareUnownedIdle bool
+		expected         map[string]float64
+		idle             map[string]float64
+	}{
+		// Unowned idle is shared by weight
+		"share unowned idle": {
+			shareIdle:        ShareOwner,
+			shareUnownedIdle: true,
+			expected: map[string]float64{
+				"namespace1": (3 + 1) + 8*3/4.0 + 8*1/4.0 + 4/2.0,
+				"namespace2": 1 + 4/2.0,
+			},
+			idle: map[string]float64{"node1": 0},
+		},
+		// Unowned idle is left as idle
+		"leave unowned idle": {
+			shareIdle: ShareOwner,
+			expected: map[string]float64{
+				"namespace1": (3 + 1) + 8*3/4.0 + 8*1/4.0,
+				"namespace2": 1,
+			},
+			idle: map[string]float64{"node1": 0, "node2": 4},
+		},
+		// Without ShareOwner, owners are not charged, and idle is shared as
+		// usual
+		"share weighted": {
+			shareIdle: ShareWeighted,
+			expected: map[string]float64{
+				"namespace1": (3 + 1) + 8 + 4/2.0,
+				"namespace2": 1 + 4/2.0,
+			},
+			idle: map[string]float64{"node1": 0},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			as := newSet()
+			err := as.AggregateBy([]string{AllocationNamespaceProp}, &AllocationAggregationOptions{
+				ShareIdle:        c.shareIdle,
+				ShareUnownedIdle: c.shareUnownedIdle,
+				IdleOwners:       owners,
+				IdleByNode:       true,
+				SplitIdle:        true,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			for name, cpuCost := range c.expected {
+				alloc := as.Get(name)
+				if alloc == nil {
+					t.Fatalf("missing allocation %s", name)
+				}
+				if !util.IsApproximately(alloc.CPUCost, cpuCost) {
+					t.Fatalf("expected %s CPU cost %f; got %f", name, cpuCost, alloc.CPUCost)
+				}
+			}
+
+			// team-a has no GPU cost, so node1's idle GPU cost is left as
+			// idle, either way
+			for node, cpuCost := range c.idle {
+				idle := as.Get(fmt.Sprintf("cluster1/%s/%s", node, IdleSuffix))
+				if idle == nil {
+					t.Fatalf("missing idle allocation for %s", node)
+				}
+				if !util.IsApproximately(idle.CPUCost, cpuCost) {
+					t.Fatalf("expected %s idle CPU cost %f; got %f", node, cpuCost, idle.CPUCost)
+				}
+			}
+			idle := as.Get(fmt.Sprintf("cluster1/node1/%s", IdleSuffix))
+			if !util.IsApproximately(idle.GPUCost, 2) {
+				t.Fatalf("expected node1 idle GPU cost 2; got %f", idle.GPUCost)
+			}
+		})
+	}
+
+	// Owners must be valid
+	as := NewAllocationSet(start, end,
+		newAlloc("namespace1", "node1", 3, 1),
+		newIdle("node1", 8, 2),
+	)
+	err := as.AggregateBy([]string{AllocationNamespaceProp}, &AllocationAggregationOptions{
+		ShareIdle:  ShareOwner,
+		IdleOwners: []*IdleOwner{{Name: "team-a"}},
+	})
+	if err == nil {
+		t.Fatalf("expected error for invalid idle owner")
+	}
+}
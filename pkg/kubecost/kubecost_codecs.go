@@ -34,10 +34,10 @@ const (
 
 const (
 	// AssetsCodecVersion is used for any resources listed in the Assets version set
-	AssetsCodecVersion uint8 = 18
+	AssetsCodecVersion uint8 = 21
 
 	// AllocationCodecVersion is used for any resources listed in the Allocation version set
-	AllocationCodecVersion uint8 = 16
+	AllocationCodecVersion uint8 = 22
 
 	// AuditCodecVersion is used for any resources listed in the Audit version set
 	AuditCodecVersion uint8 = 1
@@ -96,6 +96,7 @@ var typeMap map[string]reflect.Type = map[string]reflect.Type{
 	"PVKey":                         reflect.TypeOf((*PVKey)(nil)).Elem(),
 	"RawAllocationOnlyData":         reflect.TypeOf((*RawAllocationOnlyData)(nil)).Elem(),
 	"SharedAsset":                   reflect.TypeOf((*SharedAsset)(nil)).Elem(),
+	"Snapshot":                      reflect.TypeOf((*Snapshot)(nil)).Elem(),
 	"TotalAudit":                    reflect.TypeOf((*TotalAudit)(nil)).Elem(),
 	"Window":                        reflect.TypeOf((*Window)(nil)).Elem(),
 }
@@ -765,6 +766,15 @@ func (target *Allocation) MarshalBinaryWithContext(ctx *EncodingContext) (err er
 		// --- [end][write][struct](RawAllocationOnlyData) ---
 
 	}
+	buff.WriteFloat64(target.GPUUsageAverage)                     // write float64
+	buff.WriteFloat64(target.GPUMemoryUsageAverage)               // write float64
+	buff.WriteFloat64(target.EphemeralStorageByteHours)           // write float64
+	buff.WriteFloat64(target.EphemeralStorageBytesRequestAverage) // write float64
+	buff.WriteFloat64(target.EphemeralStorageBytesUsageAverage)   // write float64
+	buff.WriteFloat64(target.EphemeralStorageCost)                // write float64
+	buff.WriteFloat64(target.EphemeralStorageCostAdjustment)      // write float64
+	buff.WriteFloat64(target.SnapshotByteHours)                   // write float64
+	buff.WriteFloat64(target.SnapshotCost)                        // write float64
 	return nil
 }
 
@@ -1028,6 +1038,87 @@ func (target *Allocation) UnmarshalBinaryWithContext(ctx *DecodingContext) (err
 		// --- [end][read][struct](RawAllocationOnlyData) ---
 
 	}
+	// field version check
+	if uint8(18) <= version {
+		xx := buff.ReadFloat64() // read float64
+		target.GPUUsageAverage = xx
+
+	} else {
+		target.GPUUsageAverage = float64(0) // default
+	}
+
+	// field version check
+	if uint8(18) <= version {
+		yy := buff.ReadFloat64() // read float64
+		target.GPUMemoryUsageAverage = yy
+
+	} else {
+		target.GPUMemoryUsageAverage = float64(0) // default
+	}
+
+	// field version check
+	if uint8(19) <= version {
+		zz := buff.ReadFloat64() // read float64
+		target.EphemeralStorageByteHours = zz
+
+	} else {
+		target.EphemeralStorageByteHours = float64(0) // default
+	}
+
+	// field version check
+	if uint8(19) <= version {
+		aaa := buff.ReadFloat64() // read float64
+		target.EphemeralStorageBytesRequestAverage = aaa
+
+	} else {
+		target.EphemeralStorageBytesRequestAverage = float64(0) // default
+	}
+
+	// field version check
+	if uint8(19) <= version {
+		bbb := buff.ReadFloat64() // read float64
+		target.EphemeralStorageBytesUsageAverage = bbb
+
+	} else {
+		target.EphemeralStorageBytesUsageAverage = float64(0) // default
+	}
+
+	// field version check
+	if uint8(19) <= version {
+		ccc := buff.ReadFloat64() // read float64
+		target.EphemeralStorageCost = ccc
+
+	} else {
+		target.EphemeralStorageCost = float64(0) // default
+	}
+
+	// field version check
+	if uint8(19) <= version {
+		ddd := buff.ReadFloat64() // read float64
+		target.EphemeralStorageCostAdjustment = ddd
+
+	} else {
+		target.EphemeralStorageCostAdjustment = float64(0) // default
+	}
+
+	// field version check
+	if uint8(20) <= version {
+		eee := buff.ReadFloat64() // read float64
+		target.SnapshotByteHours = eee
+
+	} else {
+		target.SnapshotByteHours = float64(0) // default
+	}
+
+	// field version check
+	if uint8(20) <= version {
+		fff := buff.ReadFloat64() // read float64
+		target.SnapshotCost = fff
+
+	} else {
+		target.SnapshotCost = float64(0) // default
+	}
+
 	return nil
 }
 
@@ -1191,6 +1282,66 @@ func (target *AllocationProperties) MarshalBinaryWithContext(ctx *EncodingContex
 	}
 	// --- [end][write][alias](AllocationAnnotations) ---
 
+	// --- [begin][write][alias](AllocationLabels) ---
+	if map[string]string(target.NamespaceLabels) == nil {
+		buff.WriteUInt8(uint8(0)) // write nil byte
+	} else {
+		buff.WriteUInt8(uint8(1)) // write non-nil byte
+
+		// --- [begin][write][map](map[string]string) ---
+		buff.WriteInt(len(map[string]string(target.NamespaceLabels))) // map length
+		for vvv, zzz := range map[string]string(target.NamespaceLabels) {
+			if ctx.IsStringTable() {
+				ppp := ctx.Table.AddOrGet(vvv)
+				buff.WriteInt(ppp) // write table index
+			} else {
+				buff.WriteString(vvv) // write string
+			}
+			if ctx.IsStringTable() {
+				qqq := ctx.Table.AddOrGet(zzz)
+				buff.WriteInt(qqq) // write table index
+			} else {
+				buff.WriteString(zzz) // write string
+			}
+		}
+		// --- [end][write][map](map[string]string) ---
+
+	}
+	// --- [end][write][alias](AllocationLabels) ---
+
+	// --- [begin][write][alias](AllocationAnnotations) ---
+	if map[string]string(target.NamespaceAnnotations) == nil {
+		buff.WriteUInt8(uint8(0)) // write nil byte
+	} else {
+		buff.WriteUInt8(uint8(1)) // write non-nil byte
+
+		// --- [begin][write][map](map[string]string) ---
+		buff.WriteInt(len(map[string]string(target.NamespaceAnnotations))) // map length
+		for vvvv, zzzz := range map[string]string(target.NamespaceAnnotations) {
+			if ctx.IsStringTable() {
+				pppp := ctx.Table.AddOrGet(vvvv)
+				buff.WriteInt(pppp) // write table index
+			} else {
+				buff.WriteString(vvvv) // write string
+			}
+			if ctx.IsStringTable() {
+				qqqq := ctx.Table.AddOrGet(zzzz)
+				buff.WriteInt(qqqq) // write table index
+			} else {
+				buff.WriteString(zzzz) // write string
+			}
+		}
+		// --- [end][write][map](map[string]string) ---
+
+	}
+	// --- [end][write][alias](AllocationAnnotations) ---
+
+	if ctx.IsStringTable() {
+		rrrr := ctx.Table.AddOrGet(target.NodePool)
+		buff.WriteInt(rrrr) // write table index
+	} else {
+		buff.WriteString(target.NodePool) // write string
+	}
 	return nil
 }
 
@@ -1432,6 +1583,114 @@ func (target *AllocationProperties) UnmarshalBinaryWithContext(ctx *DecodingCont
 	target.Annotations = AllocationAnnotations(tt)
 	// --- [end][read][alias](AllocationAnnotations) ---
 
+	// field version check
+	if uint8(17) <= version {
+		// --- [begin][read][alias](AllocationLabels) ---
+		var eee map[string]string
+		if buff.ReadUInt8() == uint8(0) {
+			eee = nil
+		} else {
+			// --- [begin][read][map](map[string]string) ---
+			ggg := buff.ReadInt() // map len
+			fff := make(map[string]string, ggg)
+			for jjj := 0; jjj < ggg; jjj++ {
+				var kkk string
+				var lll string
+				if ctx.IsStringTable() {
+					mmm := buff.ReadInt() // read string index
+					lll = ctx.Table[mmm]
+				} else {
+					lll = buff.ReadString() // read string
+				}
+				hhh := lll
+				kkk = hhh
+
+				var rrr string
+				var nnn string
+				if ctx.IsStringTable() {
+					ooo := buff.ReadInt() // read string index
+					nnn = ctx.Table[ooo]
+				} else {
+					nnn = buff.ReadString() // read string
+				}
+				sss := nnn
+				rrr = sss
+
+				fff[kkk] = rrr
+			}
+			eee = fff
+			// --- [end][read][map](map[string]string) ---
+
+		}
+		target.NamespaceLabels = AllocationLabels(eee)
+		// --- [end][read][alias](AllocationLabels) ---
+
+	} else {
+		target.NamespaceLabels = nil // default
+	}
+
+	// field version check
+	if uint8(17) <= version {
+		// --- [begin][read][alias](AllocationAnnotations) ---
+		var ttt map[string]string
+		if buff.ReadUInt8() == uint8(0) {
+			ttt = nil
+		} else {
+			// --- [begin][read][map](map[string]string) ---
+			www := buff.ReadInt() // map len
+			uuu := make(map[string]string, www)
+			for iii := 0; iii < www; iii++ {
+				var xxx string
+				var yyy string
+				if ctx.IsStringTable() {
+					aaaa := buff.ReadInt() // read string index
+					yyy = ctx.Table[aaaa]
+				} else {
+					yyy = buff.ReadString() // read string
+				}
+				eeee := yyy
+				xxx = eeee
+
+				var dddd string
+				var bbbb string
+				if ctx.IsStringTable() {
+					cccc := buff.ReadInt() // read string index
+					bbbb = ctx.Table[cccc]
+				} else {
+					bbbb = buff.ReadString() // read string
+				}
+				ffff := bbbb
+				dddd = ffff
+
+				uuu[xxx] = dddd
+			}
+			ttt = uuu
+			// --- [end][read][map](map[string]string) ---
+
+		}
+		target.NamespaceAnnotations = AllocationAnnotations(ttt)
+		// --- [end][read][alias](AllocationAnnotations) ---
+
+	} else {
+		target.NamespaceAnnotations = nil // default
+	}
+
+	// field version check
+	if uint8(22) <= version {
+		var hhhh string
+		if ctx.IsStringTable() {
+			kkkk := buff.ReadInt() // read string index
+			hhhh = ctx.Table[kkkk]
+		} else {
+			hhhh = buff.ReadString() // read string
+		}
+		gggg := hhhh
+		target.NodePool = gggg
+
+	} else {
+		target.NodePool = "" // default
+	}
+
 	return nil
 }
 
@@ -2667,6 +2926,12 @@ func (target *AssetProperties) MarshalBinaryWithContext(ctx *EncodingContext) (e
 	} else {
 		buff.WriteString(target.ProviderID) // write string
 	}
+	if ctx.IsStringTable() {
+		k := ctx.Table.AddOrGet(target.NodePool)
+		buff.WriteInt(k) // write table index
+	} else {
+		buff.WriteString(target.NodePool) // write string
+	}
 	return nil
 }
 
@@ -2804,6 +3069,22 @@ func (target *AssetProperties) UnmarshalBinaryWithContext(ctx *DecodingContext)
 	y := aa
 	target.ProviderID = y
 
+	// field version check
+	if uint8(21) <= version {
+		var dd string
+		if ctx.IsStringTable() {
+			ee := buff.ReadInt() // read string index
+			dd = ctx.Table[ee]
+		} else {
+			dd = buff.ReadString() // read string
+		}
+		cc := dd
+		target.NodePool = cc
+
+	} else {
+		target.NodePool = "" // default
+	}
+
 	return nil
 }
 
@@ -7023,6 +7304,8 @@ func (target *Disk) MarshalBinaryWithContext(ctx *EncodingContext) (err error) {
 	} else {
 		buff.WriteString(target.ClaimNamespace) // write string
 	}
+	buff.WriteFloat64(target.IOPSCost)       // write float64
+	buff.WriteFloat64(target.ThroughputCost) // write float64
 	return nil
 }
 
@@ -7284,6 +7567,24 @@ func (target *Disk) UnmarshalBinaryWithContext(ctx *DecodingContext) (err error)
 		target.ClaimNamespace = "" // default
 	}
 
+	// field version check
+	if uint8(20) <= version {
+		qq := buff.ReadFloat64() // read float64
+		target.IOPSCost = qq
+
+	} else {
+		target.IOPSCost = float64(0) // default
+	}
+
+	// field version check
+	if uint8(20) <= version {
+		rr := buff.ReadFloat64() // read float64
+		target.ThroughputCost = rr
+
+	} else {
+		target.ThroughputCost = float64(0) // default
+	}
+
 	return nil
 }
 
@@ -8499,8 +8800,10 @@ func (target *PVAllocation) MarshalBinaryWithContext(ctx *EncodingContext) (err
 	buff := ctx.Buffer
 	buff.WriteUInt8(AllocationCodecVersion) // version
 
-	buff.WriteFloat64(target.ByteHours) // write float64
-	buff.WriteFloat64(target.Cost)      // write float64
+	buff.WriteFloat64(target.ByteHours)      // write float64
+	buff.WriteFloat64(target.Cost)           // write float64
+	buff.WriteFloat64(target.IOPSCost)       // write float64
+	buff.WriteFloat64(target.ThroughputCost) // write float64
 	return nil
 }
 
@@ -8564,6 +8867,24 @@ func (target *PVAllocation) UnmarshalBinaryWithContext(ctx *DecodingContext) (er
 	b := buff.ReadFloat64() // read float64
 	target.Cost = b
 
+	// field version check
+	if uint8(21) <= version {
+		c := buff.ReadFloat64() // read float64
+		target.IOPSCost = c
+
+	} else {
+		target.IOPSCost = float64(0) // default
+	}
+
+	// field version check
+	if uint8(21) <= version {
+		d := buff.ReadFloat64() // read float64
+		target.ThroughputCost = d
+
+	} else {
+		target.ThroughputCost = float64(0) // default
+	}
+
 	return nil
 }
 
@@ -9024,6 +9345,303 @@ func (target *SharedAsset) UnmarshalBinaryWithContext(ctx *DecodingContext) (err
 	return nil
 }
 
+//--------------------------------------------------------------------------
+//  Snapshot
+//--------------------------------------------------------------------------
+
+// MarshalBinary serializes the internal properties of this Snapshot instance
+// into a byte array
+func (target *Snapshot) MarshalBinary() (data []byte, err error) {
+	ctx := &EncodingContext{
+		Buffer: util.NewBuffer(),
+		Table:  nil,
+	}
+
+	e := target.MarshalBinaryWithContext(ctx)
+	if e != nil {
+		return nil, e
+	}
+
+	encBytes := ctx.Buffer.Bytes()
+	return encBytes, nil
+}
+
+// MarshalBinaryWithContext serializes the internal properties of this Snapshot instance
+// into a byte array leveraging a predefined context.
+func (target *Snapshot) MarshalBinaryWithContext(ctx *EncodingContext) (err error) {
+	// panics are recovered and propagated as errors
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else if s, ok := r.(string); ok {
+				err = fmt.Errorf("Unexpected panic: %s", s)
+			} else {
+				err = fmt.Errorf("Unexpected panic: %+v", r)
+			}
+		}
+	}()
+
+	buff := ctx.Buffer
+	buff.WriteUInt8(AssetsCodecVersion) // version
+
+	if target.Properties == nil {
+		buff.WriteUInt8(uint8(0)) // write nil byte
+	} else {
+		buff.WriteUInt8(uint8(1)) // write non-nil byte
+
+		// --- [begin][write][struct](AssetProperties) ---
+		buff.WriteInt(0) // [compatibility, unused]
+		errA := target.Properties.MarshalBinaryWithContext(ctx)
+		if errA != nil {
+			return errA
+		}
+		// --- [end][write][struct](AssetProperties) ---
+
+	}
+	// --- [begin][write][alias](AssetLabels) ---
+	if map[string]string(target.Labels) == nil {
+		buff.WriteUInt8(uint8(0)) // write nil byte
+	} else {
+		buff.WriteUInt8(uint8(1)) // write non-nil byte
+
+		// --- [begin][write][map](map[string]string) ---
+		buff.WriteInt(len(map[string]string(target.Labels))) // map length
+		for v, z := range map[string]string(target.Labels) {
+			if ctx.IsStringTable() {
+				a := ctx.Table.AddOrGet(v)
+				buff.WriteInt(a) // write table index
+			} else {
+				buff.WriteString(v) // write string
+			}
+			if ctx.IsStringTable() {
+				b := ctx.Table.AddOrGet(z)
+				buff.WriteInt(b) // write table index
+			} else {
+				buff.WriteString(z) // write string
+			}
+		}
+		// --- [end][write][map](map[string]string) ---
+
+	}
+	// --- [end][write][alias](AssetLabels) ---
+
+	// --- [begin][write][reference](time.Time) ---
+	c, errB := target.Start.MarshalBinary()
+	if errB != nil {
+		return errB
+	}
+	buff.WriteInt(len(c))
+	buff.WriteBytes(c)
+	// --- [end][write][reference](time.Time) ---
+
+	// --- [begin][write][reference](time.Time) ---
+	d, errC := target.End.MarshalBinary()
+	if errC != nil {
+		return errC
+	}
+	buff.WriteInt(len(d))
+	buff.WriteBytes(d)
+	// --- [end][write][reference](time.Time) ---
+
+	// --- [begin][write][struct](Window) ---
+	buff.WriteInt(0) // [compatibility, unused]
+	errD := target.Window.MarshalBinaryWithContext(ctx)
+	if errD != nil {
+		return errD
+	}
+	// --- [end][write][struct](Window) ---
+
+	buff.WriteFloat64(target.Adjustment) // write float64
+	buff.WriteFloat64(target.Cost)       // write float64
+	buff.WriteFloat64(target.ByteHours)  // write float64
+	if ctx.IsStringTable() {
+		e := ctx.Table.AddOrGet(target.ClaimName)
+		buff.WriteInt(e) // write table index
+	} else {
+		buff.WriteString(target.ClaimName) // write string
+	}
+	if ctx.IsStringTable() {
+		f := ctx.Table.AddOrGet(target.ClaimNamespace)
+		buff.WriteInt(f) // write table index
+	} else {
+		buff.WriteString(target.ClaimNamespace) // write string
+	}
+	return nil
+}
+
+// UnmarshalBinary uses the data passed byte array to set all the internal properties of
+// the Snapshot type
+func (target *Snapshot) UnmarshalBinary(data []byte) error {
+	var table []string
+	buff := util.NewBufferFromBytes(data)
+
+	// string table header validation
+	if isBinaryTag(data, BinaryTagStringTable) {
+		buff.ReadBytes(len(BinaryTagStringTable)) // strip tag length
+		tl := buff.ReadInt()                      // table length
+		if tl > 0 {
+			table = make([]string, tl, tl)
+			for i := 0; i < tl; i++ {
+				table[i] = buff.ReadString()
+			}
+		}
+	}
+
+	ctx := &DecodingContext{
+		Buffer: buff,
+		Table:  table,
+	}
+
+	err := target.UnmarshalBinaryWithContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnmarshalBinaryWithContext uses the context containing a string table and binary buffer to set all the internal properties of
+// the Snapshot type
+func (target *Snapshot) UnmarshalBinaryWithContext(ctx *DecodingContext) (err error) {
+	// panics are recovered and propagated as errors
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else if s, ok := r.(string); ok {
+				err = fmt.Errorf("Unexpected panic: %s", s)
+			} else {
+				err = fmt.Errorf("Unexpected panic: %+v", r)
+			}
+		}
+	}()
+
+	buff := ctx.Buffer
+	version := buff.ReadUInt8()
+
+	if version > AssetsCodecVersion {
+		return fmt.Errorf("Invalid Version Unmarshaling Snapshot. Expected %d or less, got %d", AssetsCodecVersion, version)
+	}
+
+	if buff.ReadUInt8() == uint8(0) {
+		target.Properties = nil
+	} else {
+		// --- [begin][read][struct](AssetProperties) ---
+		a := &AssetProperties{}
+		buff.ReadInt() // [compatibility, unused]
+		errA := a.UnmarshalBinaryWithContext(ctx)
+		if errA != nil {
+			return errA
+		}
+		target.Properties = a
+		// --- [end][read][struct](AssetProperties) ---
+
+	}
+	// --- [begin][read][alias](AssetLabels) ---
+	var b map[string]string
+	if buff.ReadUInt8() == uint8(0) {
+		b = nil
+	} else {
+		// --- [begin][read][map](map[string]string) ---
+		d := buff.ReadInt() // map len
+		c := make(map[string]string, d)
+		for i := 0; i < d; i++ {
+			var v string
+			var f string
+			if ctx.IsStringTable() {
+				g := buff.ReadInt() // read string index
+				f = ctx.Table[g]
+			} else {
+				f = buff.ReadString() // read string
+			}
+			e := f
+			v = e
+
+			var z string
+			var k string
+			if ctx.IsStringTable() {
+				l := buff.ReadInt() // read string index
+				k = ctx.Table[l]
+			} else {
+				k = buff.ReadString() // read string
+			}
+			h := k
+			z = h
+
+			c[v] = z
+		}
+		b = c
+		// --- [end][read][map](map[string]string) ---
+
+	}
+	target.Labels = AssetLabels(b)
+	// --- [end][read][alias](AssetLabels) ---
+
+	// --- [begin][read][reference](time.Time) ---
+	m := &time.Time{}
+	n := buff.ReadInt()    // byte array length
+	o := buff.ReadBytes(n) // byte array
+	errB := m.UnmarshalBinary(o)
+	if errB != nil {
+		return errB
+	}
+	target.Start = *m
+	// --- [end][read][reference](time.Time) ---
+
+	// --- [begin][read][reference](time.Time) ---
+	p := &time.Time{}
+	q := buff.ReadInt()    // byte array length
+	r := buff.ReadBytes(q) // byte array
+	errC := p.UnmarshalBinary(r)
+	if errC != nil {
+		return errC
+	}
+	target.End = *p
+	// --- [end][read][reference](time.Time) ---
+
+	// --- [begin][read][struct](Window) ---
+	s := &Window{}
+	buff.ReadInt() // [compatibility, unused]
+	errD := s.UnmarshalBinaryWithContext(ctx)
+	if errD != nil {
+		return errD
+	}
+	target.Window = *s
+	// --- [end][read][struct](Window) ---
+
+	t := buff.ReadFloat64() // read float64
+	target.Adjustment = t
+
+	u := buff.ReadFloat64() // read float64
+	target.Cost = u
+
+	w := buff.ReadFloat64() // read float64
+	target.ByteHours = w
+
+	var y string
+	if ctx.IsStringTable() {
+		aa := buff.ReadInt() // read string index
+		y = ctx.Table[aa]
+	} else {
+		y = buff.ReadString() // read string
+	}
+	x := y
+	target.ClaimName = x
+
+	var cc string
+	if ctx.IsStringTable() {
+		dd := buff.ReadInt() // read string index
+		cc = ctx.Table[dd]
+	} else {
+		cc = buff.ReadString() // read string
+	}
+	bb := cc
+	target.ClaimNamespace = bb
+
+	return nil
+}
+
 //--------------------------------------------------------------------------
 //  TotalAudit
 //--------------------------------------------------------------------------
@@ -1,10 +1,49 @@
 package kubecost
 
 import (
+	"os"
+	"regexp"
+	"strconv"
 	"testing"
 	"time"
 )
 
+// TestBingenVersionSets checks that the version set directives in bingen.go
+// match the codec versions generated from them, which must be bumped together
+// whenever a serialized struct changes.
+func TestBingenVersionSets(t *testing.T) {
+	src, err := os.ReadFile("bingen.go")
+	if err != nil {
+		t.Fatalf("reading bingen.go: %s", err)
+	}
+
+	codecVersions := map[string]uint8{
+		"Assets":             AssetsCodecVersion,
+		"Allocation":         AllocationCodecVersion,
+		"Audit":              AuditCodecVersion,
+		"CloudCostAggregate": CloudCostAggregateCodecVersion,
+		"CloudCostItem":      CloudCostItemCodecVersion,
+	}
+
+	sets := regexp.MustCompile(`@bingen:set\[name=(\w+),version=(\d+)\]`).FindAllSubmatch(src, -1)
+	if len(sets) != len(codecVersions) {
+		t.Fatalf("expected %d version sets; got %d", len(codecVersions), len(sets))
+	}
+
+	for _, set := range sets {
+		name := string(set[1])
+		version, _ := strconv.Atoi(string(set[2]))
+
+		codecVersion, ok := codecVersions[name]
+		if !ok {
+			t.Fatalf("unexpected version set %s", name)
+		}
+		if int(codecVersion) != version {
+			t.Errorf("version set %s: expected version %d to match codec version %d", name, version, codecVersion)
+		}
+	}
+}
+
 func TestAllocation_BinaryEncoding(t *testing.T) {
 	// TODO niko/etl
 }
@@ -374,6 +413,8 @@ func TestDisk_BinaryEncoding(t *testing.T) {
 	a0 = NewDisk("any1", "cluster1", "世界", start, end, window)
 	a0.ByteHours = 100 * 1024 * 1024 * 1024 * hours
 	a0.Cost = 4.003
+	a0.IOPSCost = 1.2
+	a0.ThroughputCost = 0.3
 	a0.Local = 0.4
 	a0.Breakdown = &Breakdown{
 		Idle:   0.9,
@@ -413,6 +454,7 @@ func TestNode_BinaryEncoding(t *testing.T) {
 	var err error
 
 	a0 = NewNode("any1", "cluster1", "世界", start, end, window)
+	a0.Properties.NodePool = "pool-1"
 	a0.NodeType = "n2-standard"
 	a0.Preemptible = 1.0
 	a0.CPUCoreHours = 2.0 * hours
@@ -481,6 +523,7 @@ func TestProperties_BinaryEncoding(t *testing.T) {
 	p0.ControllerKind = "daemonset"
 	p0.Namespace = "namespace1"
 	p0.Node = "node1"
+	p0.NodePool = "pool-1"
 	p0.Pod = "daemonset-abc-123"
 	p0.Labels = map[string]string{
 		"app":  "cost-analyzer",
@@ -554,6 +597,60 @@ func TestShared_BinaryEncoding(t *testing.T) {
 	}
 }
 
+func TestSnapshot_BinaryEncoding(t *testing.T) {
+	ws := time.Date(2020, time.September, 16, 0, 0, 0, 0, time.UTC)
+	we := ws.Add(24 * time.Hour)
+	window := NewWindow(&ws, &we)
+
+	var a0, a1 *Snapshot
+	var bs []byte
+	var err error
+
+	a0 = NewSnapshot("namespace1/snap1", "cluster1", "snap-0a1", ws, we, window)
+	a0.Cost = 1.2
+	a0.ByteHours = 240.0 * 1024.0 * 1024.0 * 1024.0
+	a0.ClaimName = "data"
+	a0.ClaimNamespace = "namespace1"
+	a0.SetAdjustment(-0.2)
+
+	bs, err = a0.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Snapshot.Binary: unexpected error: %s", err)
+	}
+
+	a1 = &Snapshot{}
+	err = a1.UnmarshalBinary(bs)
+	if err != nil {
+		t.Fatalf("Snapshot.Binary: unexpected error: %s", err)
+	}
+
+	if !a0.Equal(a1) {
+		t.Fatalf("Snapshot.Binary: expected %v, found %v", a0, a1)
+	}
+
+	// Snapshots are decoded by type within an AssetSet
+	as0 := NewAssetSet(ws, we, a0)
+	bs, err = as0.MarshalBinary()
+	if err != nil {
+		t.Fatalf("AssetSet.Binary: unexpected error: %s", err)
+	}
+
+	as1 := &AssetSet{}
+	err = as1.UnmarshalBinary(bs)
+	if err != nil {
+		t.Fatalf("AssetSet.Binary: unexpected error: %s", err)
+	}
+
+	if len(as1.Snapshots) != 1 {
+		t.Fatalf("AssetSet.Binary: expected 1 snapshot, found %d", len(as1.Snapshots))
+	}
+	for _, snapshot := range as1.Snapshots {
+		if !a0.Equal(snapshot) {
+			t.Fatalf("AssetSet.Binary: expected %v, found %v", a0, snapshot)
+		}
+	}
+}
+
 func TestWindow_BinaryEncoding(t *testing.T) {
 	var w0, w1 Window
 	var bs []byte
@@ -0,0 +1,45 @@
+package kubecost
+
+import (
+	"fmt"
+
+	"github.com/opencost/opencost/pkg/prom"
+)
+
+// NodePoolLabels are the node labels identifying the node pool, or node group,
+// of a node, in order of precedence: EKS managed node groups, GKE node pools,
+// AKS agent pools and Karpenter node pools and provisioners.
+var NodePoolLabels = []string{
+	"eks.amazonaws.com/nodegroup",
+	"cloud.google.com/gke-nodepool",
+	"kubernetes.azure.com/agentpool",
+	"agentpool",
+	"karpenter.sh/nodepool",
+	"karpenter.sh/provisioner-name",
+}
+
+// NodePoolFromLabels returns the node pool of a node given its labels, or an
+// empty string if the node does not belong to a known kind of node pool.
+// Labels may be given as set on the node, or as sanitized by Prometheus, with
+// or without the "label_" prefix of kube_node_labels.
+func NodePoolFromLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	for _, name := range NodePoolLabels {
+		if v, ok := labels[name]; ok && v != "" {
+			return v
+		}
+
+		sanitized := prom.SanitizeLabelName(name)
+		if v, ok := labels[sanitized]; ok && v != "" {
+			return v
+		}
+		if v, ok := labels[fmt.Sprintf("label_%s", sanitized)]; ok && v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,126 @@
+package kubecost
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/util"
+)
+
+func TestNodePoolFromLabels(t *testing.T) {
+	cases := map[string]struct {
+		labels   map[string]string
+		expected string
+	}{
+		"eks node group": {
+			labels:   map[string]string{"eks.amazonaws.com/nodegroup": "ng-1"},
+			expected: "ng-1",
+		},
+		"gke node pool, sanitized": {
+			labels:   map[string]string{"cloud_google_com_gke_nodepool": "default-pool"},
+			expected: "default-pool",
+		},
+		"aks agent pool, kube_node_labels": {
+			labels:   map[string]string{"label_kubernetes_azure_com_agentpool": "nodepool1"},
+			expected: "nodepool1",
+		},
+		"legacy aks agent pool": {
+			labels:   map[string]string{"label_agentpool": "agentpool1"},
+			expected: "agentpool1",
+		},
+		"karpenter node pool": {
+			labels:   map[string]string{"karpenter.sh/nodepool": "general", "karpenter.sh/provisioner-name": "legacy"},
+			expected: "general",
+		},
+		"karpenter provisioner": {
+			labels:   map[string]string{"label_karpenter_sh_provisioner_name": "default"},
+			expected: "default",
+		},
+		"eks node group takes precedence": {
+			labels:   map[string]string{"eks.amazonaws.com/nodegroup": "ng-1", "karpenter.sh/nodepool": "general"},
+			expected: "ng-1",
+		},
+		"no node pool": {
+			labels:   map[string]string{"kubernetes.io/hostname": "node1"},
+			expected: "",
+		},
+		"nil labels": {
+			expected: "",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if actual := NodePoolFromLabels(c.labels); actual != c.expected {
+				t.Fatalf("expected node pool %q; got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAllocationSet_AggregateBy_IdleByNodePool(t *testing.T) {
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	newAlloc := func(namespace, node, nodePool string, cpuCost float64) *Allocation {
+		props := &AllocationProperties{
+			Cluster:   "cluster1",
+			Node:      node,
+			NodePool:  nodePool,
+			Namespace: namespace,
+			Pod:       fmt.Sprintf("%s-%s", namespace, node),
+			Container: "container1",
+		}
+		alloc := NewMockUnitAllocation(fmt.Sprintf("cluster1/%s/%s/%s", node, namespace, props.Pod), start, 24*time.Hour, props)
+		alloc.CPUCost = cpuCost
+		return alloc
+	}
+
+	newIdle := func(node, nodePool string, cpuCost float64) *Allocation {
+		return &Allocation{
+			Name:       fmt.Sprintf("cluster1/%s/%s", node, IdleSuffix),
+			Properties: &AllocationProperties{Cluster: "cluster1", Node: node, ProviderID: node, NodePool: nodePool},
+			Window:     NewWindow(&start, &end),
+			Start:      start,
+			End:        end,
+			CPUCost:    cpuCost,
+		}
+	}
+
+	// Node pool "a" has idle on node1 only, which is shared by the
+	// allocations on both of its nodes.
+	as := NewAllocationSet(start, end,
+		newAlloc("namespace1", "node1", "a", 1),
+		newAlloc("namespace2", "node2", "a", 1),
+		newAlloc("namespace1", "node3", "b", 1),
+		newIdle("node1", "a", 4),
+		newIdle("node2", "a", 0),
+		newIdle("node3", "b", 6),
+	)
+
+	err := as.AggregateBy([]string{AllocationNamespaceProp}, &AllocationAggregationOptions{
+		ShareIdle:      ShareWeighted,
+		IdleByNodePool: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]float64{
+		"namespace1": 1 + 2 + 1 + 6,
+		"namespace2": 1 + 2,
+	}
+	if as.Length() != len(expected) {
+		t.Fatalf("expected %d allocations; got %d", len(expected), as.Length())
+	}
+	for name, cpuCost := range expected {
+		alloc := as.Get(name)
+		if alloc == nil {
+			t.Fatalf("missing allocation %s", name)
+		}
+		if !util.IsApproximately(alloc.CPUCost, cpuCost) {
+			t.Fatalf("expected %s CPU cost %f; got %f", name, cpuCost, alloc.CPUCost)
+		}
+	}
+}
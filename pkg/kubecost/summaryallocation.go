@@ -34,6 +34,8 @@ type SummaryAllocation struct {
 	RAMBytesRequestAverage float64               `json:"ramByteRequestAverage"`
 	RAMBytesUsageAverage   float64               `json:"ramByteUsageAverage"`
 	RAMCost                float64               `json:"ramCost"`
+	EphemeralStorageCost   float64               `json:"ephemeralStorageCost"`
+	SnapshotCost           float64               `json:"snapshotCost"`
 	SharedCost             float64               `json:"sharedCost"`
 	ExternalCost           float64               `json:"externalCost"`
 	Share                  bool                  `json:"-"`
@@ -63,6 +65,8 @@ func NewSummaryAllocation(alloc *Allocation, reconcile, reconcileNetwork bool) *
 		RAMBytesRequestAverage: alloc.RAMBytesRequestAverage,
 		RAMBytesUsageAverage:   alloc.RAMBytesUsageAverage,
 		RAMCost:                alloc.RAMCost + alloc.RAMCostAdjustment,
+		EphemeralStorageCost:   alloc.EphemeralStorageCost + alloc.EphemeralStorageCostAdjustment,
+		SnapshotCost:           alloc.SnapshotCost,
 		SharedCost:             alloc.SharedCost,
 		ExternalCost:           alloc.ExternalCost,
 	}
@@ -76,6 +80,7 @@ func NewSummaryAllocation(alloc *Allocation, reconcile, reconcileNetwork bool) *
 		sa.LoadBalancerCost -= alloc.LoadBalancerCostAdjustment
 		sa.PVCost -= alloc.PVCostAdjustment
 		sa.RAMCost -= alloc.RAMCostAdjustment
+		sa.EphemeralStorageCost -= alloc.EphemeralStorageCostAdjustment
 	} else if !reconcileNetwork {
 		sa.NetworkCost -= alloc.NetworkCostAdjustment
 	}
@@ -134,6 +139,7 @@ func (sa *SummaryAllocation) Add(that *SummaryAllocation) error {
 
 	// Sum all cumulative cost fields
 	sa.CPUCost += that.CPUCost
+	sa.EphemeralStorageCost += that.EphemeralStorageCost
 	sa.ExternalCost += that.ExternalCost
 	sa.GPUCost += that.GPUCost
 	sa.LoadBalancerCost += that.LoadBalancerCost
@@ -141,6 +147,7 @@ func (sa *SummaryAllocation) Add(that *SummaryAllocation) error {
 	sa.PVCost += that.PVCost
 	sa.RAMCost += that.RAMCost
 	sa.SharedCost += that.SharedCost
+	sa.SnapshotCost += that.SnapshotCost
 
 	return nil
 }
@@ -162,6 +169,8 @@ func (sa *SummaryAllocation) Clone() *SummaryAllocation {
 		RAMBytesRequestAverage: sa.RAMBytesRequestAverage,
 		RAMBytesUsageAverage:   sa.RAMBytesUsageAverage,
 		RAMCost:                sa.RAMCost,
+		EphemeralStorageCost:   sa.EphemeralStorageCost,
+		SnapshotCost:           sa.SnapshotCost,
 		SharedCost:             sa.SharedCost,
 		ExternalCost:           sa.ExternalCost,
 	}
@@ -243,6 +252,14 @@ func (sa *SummaryAllocation) Equal(that *SummaryAllocation) bool {
 		return false
 	}
 
+	if sa.EphemeralStorageCost != that.EphemeralStorageCost {
+		return false
+	}
+
+	if sa.SnapshotCost != that.SnapshotCost {
+		return false
+	}
+
 	if sa.SharedCost != that.SharedCost {
 		return false
 	}
@@ -335,7 +352,7 @@ func (sa *SummaryAllocation) TotalCost() float64 {
 		return 0.0
 	}
 
-	return sa.CPUCost + sa.GPUCost + sa.RAMCost + sa.PVCost + sa.NetworkCost + sa.LoadBalancerCost + sa.SharedCost + sa.ExternalCost
+	return sa.CPUCost + sa.GPUCost + sa.RAMCost + sa.PVCost + sa.NetworkCost + sa.LoadBalancerCost + sa.EphemeralStorageCost + sa.SnapshotCost + sa.SharedCost + sa.ExternalCost
 }
 
 // TotalEfficiency is the cost-weighted average of CPU and RAM efficiency. If
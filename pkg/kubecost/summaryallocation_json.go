@@ -22,6 +22,8 @@ type SummaryAllocationResponse struct {
 	RAMBytesRequestAverage *float64  `json:"ramByteRequestAverage"`
 	RAMBytesUsageAverage   *float64  `json:"ramByteUsageAverage"`
 	RAMCost                *float64  `json:"ramCost"`
+	EphemeralStorageCost   *float64  `json:"ephemeralStorageCost"`
+	SnapshotCost           *float64  `json:"snapshotCost"`
 	SharedCost             *float64  `json:"sharedCost"`
 	ExternalCost           *float64  `json:"externalCost"`
 }
@@ -47,6 +49,8 @@ func (sa *SummaryAllocation) ToResponse() *SummaryAllocationResponse {
 		RAMBytesRequestAverage: formatutil.Float64ToResponse(sa.RAMBytesRequestAverage),
 		RAMBytesUsageAverage:   formatutil.Float64ToResponse(sa.RAMBytesUsageAverage),
 		RAMCost:                formatutil.Float64ToResponse(sa.RAMCost),
+		EphemeralStorageCost:   formatutil.Float64ToResponse(sa.EphemeralStorageCost),
+		SnapshotCost:           formatutil.Float64ToResponse(sa.SnapshotCost),
 		SharedCost:             formatutil.Float64ToResponse(sa.SharedCost),
 		ExternalCost:           formatutil.Float64ToResponse(sa.ExternalCost),
 	}
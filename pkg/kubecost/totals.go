@@ -107,8 +107,9 @@ func (art *AllocationTotals) TotalCost() float64 {
 }
 
 // ComputeAllocationTotals totals the resource costs of the given AllocationSet
-// using the given property, i.e. cluster or node, where "node" really means to
-// use the fully-qualified (cluster, node) tuple.
+// using the given property, i.e. cluster, node or nodepool, where "node" and
+// "nodepool" really mean to use the fully-qualified (cluster, node) and
+// (cluster, nodepool) tuples.
 func ComputeAllocationTotals(as *AllocationSet, prop string) map[string]*AllocationTotals {
 	arts := map[string]*AllocationTotals{}
 
@@ -118,10 +119,13 @@ func ComputeAllocationTotals(as *AllocationSet, prop string) map[string]*Allocat
 			continue
 		}
 
-		// Default to computing totals by Cluster, but allow override to use Node.
+		// Default to computing totals by Cluster, but allow override to use
+		// Node or NodePool.
 		key := alloc.Properties.Cluster
 		if prop == AllocationNodeProp {
 			key = fmt.Sprintf("%s/%s", alloc.Properties.Cluster, alloc.Properties.Node)
+		} else if prop == AllocationNodePoolProp {
+			key = fmt.Sprintf("%s/%s", alloc.Properties.Cluster, alloc.Properties.NodePool)
 		}
 
 		if _, ok := arts[key]; !ok {
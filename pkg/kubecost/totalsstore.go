@@ -0,0 +1,525 @@
+package kubecost
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/pkg/errors"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/storage"
+	"github.com/opencost/opencost/pkg/util/atomic"
+	"github.com/opencost/opencost/pkg/util/json"
+)
+
+// totalsExt is the file extension used for persisted totals.
+const totalsExt = ".json"
+
+// totalsEntry is the unit of persistence of a StorageTotalsStore: all of the
+// totals for a given window. Totals which have not been set are nil.
+type totalsEntry struct {
+	Start                     time.Time                    `json:"start"`
+	End                       time.Time                    `json:"end"`
+	AllocationTotalsByCluster map[string]*AllocationTotals `json:"allocationTotalsByCluster"`
+	AllocationTotalsByNode    map[string]*AllocationTotals `json:"allocationTotalsByNode"`
+	AssetTotalsByCluster      map[string]*AssetTotals      `json:"assetTotalsByCluster"`
+	AssetTotalsByNode         map[string]*AssetTotals      `json:"assetTotalsByNode"`
+}
+
+// StorageTotalsStore is a TotalsStore which persists totals to storage, so
+// that they survive restarts. Totals are kept in memory for reads, and written
+// through to storage, one file per window. Totals for a window which was not
+// set are composed from those of the windows which tile it, if any.
+//
+// Hourly totals older than the hourly retention are compacted into daily
+// totals, and all totals older than the retention are removed.
+type StorageTotalsStore struct {
+	store           storage.Storage
+	dir             string
+	interval        time.Duration
+	hourlyRetention time.Duration
+	retention       time.Duration
+	runState        atomic.AtomicRunState
+
+	lock    *sync.Mutex
+	entries map[string]*totalsEntry
+	indexed bool
+}
+
+// NewStorageTotalsStore creates a new StorageTotalsStore which persists totals
+// into dir on the provided storage, compacting and pruning them every interval
+// once Run is called.
+func NewStorageTotalsStore(store storage.Storage, dir string, interval, hourlyRetention, retention time.Duration) *StorageTotalsStore {
+	return &StorageTotalsStore{
+		store:           store,
+		dir:             dir,
+		interval:        interval,
+		hourlyRetention: hourlyRetention,
+		retention:       retention,
+		lock:            new(sync.Mutex),
+		entries:         map[string]*totalsEntry{},
+	}
+}
+
+// Run starts the automated process of compacting and pruning totals on a
+// specific interval.
+func (sts *StorageTotalsStore) Run() {
+	// in the event there is a race that occurs between Run() and Stop(), we
+	// ensure that we wait for the reset to occur before starting again
+	sts.runState.WaitForReset()
+
+	if !sts.runState.Start() {
+		log.Warnf("StorageTotalsStore already running")
+		return
+	}
+
+	go func() {
+		defer errors.HandlePanic()
+
+		for {
+			if err := sts.Compact(time.Now()); err != nil {
+				log.Warnf("Failed to compact totals: %s", err)
+			}
+
+			if err := sts.Prune(time.Now()); err != nil {
+				log.Warnf("Failed to prune totals: %s", err)
+			}
+
+			select {
+			case <-time.After(sts.interval):
+			case <-sts.runState.OnStop():
+				sts.runState.Reset()
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts the compaction and pruning of totals on an interval
+func (sts *StorageTotalsStore) Stop() {
+	sts.runState.Stop()
+}
+
+// GetAllocationTotalsByCluster retrieves the AllocationTotals
+// by cluster for the given start and end times.
+func (sts *StorageTotalsStore) GetAllocationTotalsByCluster(start time.Time, end time.Time) (map[string]*AllocationTotals, bool) {
+	return sts.getAllocationTotals(start, end, func(e *totalsEntry) map[string]*AllocationTotals {
+		return e.AllocationTotalsByCluster
+	})
+}
+
+// GetAllocationTotalsByNode retrieves the AllocationTotals
+// by node for the given start and end times.
+func (sts *StorageTotalsStore) GetAllocationTotalsByNode(start time.Time, end time.Time) (map[string]*AllocationTotals, bool) {
+	return sts.getAllocationTotals(start, end, func(e *totalsEntry) map[string]*AllocationTotals {
+		return e.AllocationTotalsByNode
+	})
+}
+
+// SetAllocationTotalsByCluster set the per-cluster AllocationTotals
+// to the given values for the given start and end times.
+func (sts *StorageTotalsStore) SetAllocationTotalsByCluster(start time.Time, end time.Time, arts map[string]*AllocationTotals) {
+	sts.set(start, end, func(e *totalsEntry) {
+		e.AllocationTotalsByCluster = cloneAllocationTotals(arts)
+	})
+}
+
+// SetAllocationTotalsByNode set the per-node AllocationTotals
+// to the given values for the given start and end times.
+func (sts *StorageTotalsStore) SetAllocationTotalsByNode(start time.Time, end time.Time, arts map[string]*AllocationTotals) {
+	sts.set(start, end, func(e *totalsEntry) {
+		e.AllocationTotalsByNode = cloneAllocationTotals(arts)
+	})
+}
+
+// GetAssetTotalsByCluster retrieves the AssetTotals
+// by cluster for the given start and end times.
+func (sts *StorageTotalsStore) GetAssetTotalsByCluster(start time.Time, end time.Time) (map[string]*AssetTotals, bool) {
+	return sts.getAssetTotals(start, end, func(e *totalsEntry) map[string]*AssetTotals {
+		return e.AssetTotalsByCluster
+	})
+}
+
+// GetAssetTotalsByNode retrieves the AssetTotals
+// by node for the given start and end times.
+func (sts *StorageTotalsStore) GetAssetTotalsByNode(start time.Time, end time.Time) (map[string]*AssetTotals, bool) {
+	return sts.getAssetTotals(start, end, func(e *totalsEntry) map[string]*AssetTotals {
+		return e.AssetTotalsByNode
+	})
+}
+
+// SetAssetTotalsByCluster set the per-cluster AssetTotals
+// to the given values for the given start and end times.
+func (sts *StorageTotalsStore) SetAssetTotalsByCluster(start time.Time, end time.Time, arts map[string]*AssetTotals) {
+	sts.set(start, end, func(e *totalsEntry) {
+		e.AssetTotalsByCluster = cloneAssetTotals(arts)
+	})
+}
+
+// SetAssetTotalsByNode set the per-node AssetTotals
+// to the given values for the given start and end times.
+func (sts *StorageTotalsStore) SetAssetTotalsByNode(start time.Time, end time.Time, arts map[string]*AssetTotals) {
+	sts.set(start, end, func(e *totalsEntry) {
+		e.AssetTotalsByNode = cloneAssetTotals(arts)
+	})
+}
+
+// Compact sums the hourly totals of each complete day which ended before the
+// hourly retention, relative to now, into daily totals, removing the hourly
+// totals. Days missing any hour are left as they are.
+func (sts *StorageTotalsStore) Compact(now time.Time) error {
+	sts.lock.Lock()
+	defer sts.lock.Unlock()
+
+	if err := sts.loadIndex(); err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-sts.hourlyRetention)
+
+	days := map[time.Time][]*totalsEntry{}
+	for _, e := range sts.entries {
+		if e.End.Sub(e.Start) != time.Hour || e.End.After(cutoff) {
+			continue
+		}
+
+		day := e.Start.UTC().Truncate(24 * time.Hour)
+		days[day] = append(days[day], e)
+	}
+
+	for day, hours := range days {
+		if len(hours) != 24 || day.Add(24*time.Hour).After(cutoff) {
+			continue
+		}
+
+		sort.Slice(hours, func(i, j int) bool {
+			return hours[i].Start.Before(hours[j].Start)
+		})
+
+		daily := &totalsEntry{Start: day, End: day.Add(24 * time.Hour)}
+		daily.AllocationTotalsByCluster, _ = sumAllocationTotals(hours, func(e *totalsEntry) map[string]*AllocationTotals { return e.AllocationTotalsByCluster })
+		daily.AllocationTotalsByNode, _ = sumAllocationTotals(hours, func(e *totalsEntry) map[string]*AllocationTotals { return e.AllocationTotalsByNode })
+		daily.AssetTotalsByCluster, _ = sumAssetTotals(hours, func(e *totalsEntry) map[string]*AssetTotals { return e.AssetTotalsByCluster })
+		daily.AssetTotalsByNode, _ = sumAssetTotals(hours, func(e *totalsEntry) map[string]*AssetTotals { return e.AssetTotalsByNode })
+
+		if err := sts.write(daily); err != nil {
+			return fmt.Errorf("writing daily totals for %s: %w", NewClosedWindow(daily.Start, daily.End), err)
+		}
+		sts.entries[storeKey(daily.Start, daily.End)] = daily
+
+		for _, e := range hours {
+			if err := sts.remove(e); err != nil {
+				log.Warnf("Failed to remove compacted totals %s: %s", sts.totalsPath(e.Start, e.End), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Prune removes all totals which ended before the retention relative to now.
+func (sts *StorageTotalsStore) Prune(now time.Time) error {
+	if sts.retention <= 0 {
+		return nil
+	}
+
+	sts.lock.Lock()
+	defer sts.lock.Unlock()
+
+	if err := sts.loadIndex(); err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-sts.retention)
+
+	for _, e := range sts.entries {
+		if !e.End.Before(cutoff) {
+			continue
+		}
+
+		if err := sts.remove(e); err != nil {
+			log.Warnf("Failed to remove totals %s: %s", sts.totalsPath(e.Start, e.End), err)
+		}
+	}
+
+	return nil
+}
+
+func (sts *StorageTotalsStore) getAllocationTotals(start, end time.Time, totals func(*totalsEntry) map[string]*AllocationTotals) (map[string]*AllocationTotals, bool) {
+	sts.lock.Lock()
+	defer sts.lock.Unlock()
+
+	if err := sts.loadIndex(); err != nil {
+		log.Warnf("Failed to load totals: %s", err)
+		return map[string]*AllocationTotals{}, false
+	}
+
+	entries, ok := sts.tile(start, end, func(e *totalsEntry) bool { return totals(e) != nil })
+	if !ok {
+		return map[string]*AllocationTotals{}, false
+	}
+
+	return sumAllocationTotals(entries, totals)
+}
+
+func (sts *StorageTotalsStore) getAssetTotals(start, end time.Time, totals func(*totalsEntry) map[string]*AssetTotals) (map[string]*AssetTotals, bool) {
+	sts.lock.Lock()
+	defer sts.lock.Unlock()
+
+	if err := sts.loadIndex(); err != nil {
+		log.Warnf("Failed to load totals: %s", err)
+		return map[string]*AssetTotals{}, false
+	}
+
+	entries, ok := sts.tile(start, end, func(e *totalsEntry) bool { return totals(e) != nil })
+	if !ok {
+		return map[string]*AssetTotals{}, false
+	}
+
+	return sumAssetTotals(entries, totals)
+}
+
+// tile returns the entries, for which has returns true, which exactly cover
+// the window from start to end without overlapping, preferring the longest
+// entry at each step. Assumes the lock is held.
+func (sts *StorageTotalsStore) tile(start, end time.Time, has func(*totalsEntry) bool) ([]*totalsEntry, bool) {
+	if e, ok := sts.entries[storeKey(start, end)]; ok && has(e) {
+		return []*totalsEntry{e}, true
+	}
+
+	byStart := map[int64]*totalsEntry{}
+	for _, e := range sts.entries {
+		if !has(e) || e.Start.Before(start) || e.End.After(end) {
+			continue
+		}
+
+		k := e.Start.Unix()
+		if longest, ok := byStart[k]; !ok || e.End.After(longest.End) {
+			byStart[k] = e
+		}
+	}
+
+	var entries []*totalsEntry
+	for cursor := start; cursor.Before(end); {
+		e, ok := byStart[cursor.Unix()]
+		if !ok {
+			return nil, false
+		}
+
+		entries = append(entries, e)
+		cursor = e.End
+	}
+
+	return entries, len(entries) > 0
+}
+
+// set applies the given update to the entry for the given window and writes
+// it to storage.
+func (sts *StorageTotalsStore) set(start, end time.Time, update func(*totalsEntry)) {
+	sts.lock.Lock()
+	defer sts.lock.Unlock()
+
+	if err := sts.loadIndex(); err != nil {
+		log.Warnf("Failed to load totals: %s", err)
+	}
+
+	k := storeKey(start, end)
+	e, ok := sts.entries[k]
+	if !ok {
+		e = &totalsEntry{Start: start.UTC(), End: end.UTC()}
+		sts.entries[k] = e
+	}
+	update(e)
+
+	if err := sts.write(e); err != nil {
+		log.Warnf("Failed to write totals for %s: %s", NewClosedWindow(start, end), err)
+	}
+}
+
+// loadIndex reads all totals from storage the first time it's called so that
+// totals written by a previous process are available. Assumes the lock is held.
+func (sts *StorageTotalsStore) loadIndex() error {
+	if sts.indexed {
+		return nil
+	}
+
+	files, err := sts.store.List(sts.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("listing totals: %w", err)
+	}
+
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name, totalsExt) {
+			continue
+		}
+
+		data, err := sts.store.Read(path.Join(sts.dir, file.Name))
+		if err != nil {
+			log.Warnf("Failed to read totals %s: %s", file.Name, err)
+			continue
+		}
+
+		e := new(totalsEntry)
+		if err := json.Unmarshal(data, e); err != nil {
+			log.Warnf("Failed to decode totals %s: %s", file.Name, err)
+			continue
+		}
+
+		k := storeKey(e.Start, e.End)
+		if _, ok := sts.entries[k]; !ok {
+			sts.entries[k] = e
+		}
+	}
+
+	sts.indexed = true
+	return nil
+}
+
+func (sts *StorageTotalsStore) write(e *totalsEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding totals: %w", err)
+	}
+
+	return sts.store.Write(sts.totalsPath(e.Start, e.End), data)
+}
+
+// remove deletes the given entry from storage and memory. Assumes the lock is
+// held.
+func (sts *StorageTotalsStore) remove(e *totalsEntry) error {
+	if err := sts.store.Remove(sts.totalsPath(e.Start, e.End)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	delete(sts.entries, storeKey(e.Start, e.End))
+	return nil
+}
+
+func (sts *StorageTotalsStore) totalsPath(start, end time.Time) string {
+	return path.Join(sts.dir, storeKey(start, end)+totalsExt)
+}
+
+// sumAllocationTotals sums the totals of the given entries by key, returning
+// false if any entry is missing them.
+func sumAllocationTotals(entries []*totalsEntry, totals func(*totalsEntry) map[string]*AllocationTotals) (map[string]*AllocationTotals, bool) {
+	sum := map[string]*AllocationTotals{}
+	for _, e := range entries {
+		ts := totals(e)
+		if ts == nil {
+			return nil, false
+		}
+
+		for key, art := range ts {
+			if acc, ok := sum[key]; ok {
+				acc.add(art)
+			} else {
+				sum[key] = art.Clone()
+			}
+		}
+	}
+
+	return sum, true
+}
+
+// sumAssetTotals sums the totals of the given entries by key, returning false
+// if any entry is missing them.
+func sumAssetTotals(entries []*totalsEntry, totals func(*totalsEntry) map[string]*AssetTotals) (map[string]*AssetTotals, bool) {
+	sum := map[string]*AssetTotals{}
+	for _, e := range entries {
+		ts := totals(e)
+		if ts == nil {
+			return nil, false
+		}
+
+		for key, art := range ts {
+			if acc, ok := sum[key]; ok {
+				acc.add(art)
+			} else {
+				sum[key] = art.Clone()
+			}
+		}
+	}
+
+	return sum, true
+}
+
+func cloneAllocationTotals(arts map[string]*AllocationTotals) map[string]*AllocationTotals {
+	if arts == nil {
+		return nil
+	}
+
+	clone := make(map[string]*AllocationTotals, len(arts))
+	for k, v := range arts {
+		clone[k] = v.Clone()
+	}
+	return clone
+}
+
+func cloneAssetTotals(arts map[string]*AssetTotals) map[string]*AssetTotals {
+	if arts == nil {
+		return nil
+	}
+
+	clone := make(map[string]*AssetTotals, len(arts))
+	for k, v := range arts {
+		clone[k] = v.Clone()
+	}
+	return clone
+}
+
+// add sums the costs of the given totals into the caller, which then covers
+// the windows of both. Count is the larger of the two, as the same
+// allocations are counted in each window.
+func (art *AllocationTotals) add(that *AllocationTotals) {
+	if that.Start.Before(art.Start) {
+		art.Start = that.Start
+	}
+	if that.End.After(art.End) {
+		art.End = that.End
+	}
+	art.Count = int(math.Max(float64(art.Count), float64(that.Count)))
+	art.CPUCost += that.CPUCost
+	art.CPUCostAdjustment += that.CPUCostAdjustment
+	art.GPUCost += that.GPUCost
+	art.GPUCostAdjustment += that.GPUCostAdjustment
+	art.LoadBalancerCost += that.LoadBalancerCost
+	art.LoadBalancerCostAdjustment += that.LoadBalancerCostAdjustment
+	art.NetworkCost += that.NetworkCost
+	art.NetworkCostAdjustment += that.NetworkCostAdjustment
+	art.PersistentVolumeCost += that.PersistentVolumeCost
+	art.PersistentVolumeCostAdjustment += that.PersistentVolumeCostAdjustment
+	art.RAMCost += that.RAMCost
+	art.RAMCostAdjustment += that.RAMCostAdjustment
+}
+
+// add sums the costs of the given totals into the caller, which then covers
+// the windows of both. Count is the larger of the two, as the same assets are
+// counted in each window.
+func (art *AssetTotals) add(that *AssetTotals) {
+	if that.Start.Before(art.Start) {
+		art.Start = that.Start
+	}
+	if that.End.After(art.End) {
+		art.End = that.End
+	}
+	art.Count = int(math.Max(float64(art.Count), float64(that.Count)))
+	art.AttachedVolumeCost += that.AttachedVolumeCost
+	art.AttachedVolumeCostAdjustment += that.AttachedVolumeCostAdjustment
+	art.ClusterManagementCost += that.ClusterManagementCost
+	art.ClusterManagementCostAdjustment += that.ClusterManagementCostAdjustment
+	art.CPUCost += that.CPUCost
+	art.CPUCostAdjustment += that.CPUCostAdjustment
+	art.GPUCost += that.GPUCost
+	art.GPUCostAdjustment += that.GPUCostAdjustment
+	art.LoadBalancerCost += that.LoadBalancerCost
+	art.LoadBalancerCostAdjustment += that.LoadBalancerCostAdjustment
+	art.PersistentVolumeCost += that.PersistentVolumeCost
+	art.PersistentVolumeCostAdjustment += that.PersistentVolumeCostAdjustment
+	art.RAMCost += that.RAMCost
+	art.RAMCostAdjustment += that.RAMCostAdjustment
+}
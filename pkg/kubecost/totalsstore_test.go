@@ -0,0 +1,112 @@
+package kubecost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/storage"
+	"github.com/opencost/opencost/pkg/util"
+)
+
+func setHourlyTotals(sts *StorageTotalsStore, start time.Time, hours int) {
+	for i := 0; i < hours; i++ {
+		s := start.Add(time.Duration(i) * time.Hour)
+		e := s.Add(time.Hour)
+
+		sts.SetAllocationTotalsByCluster(s, e, map[string]*AllocationTotals{
+			"cluster1": {Start: s, End: e, Cluster: "cluster1", Count: 2, CPUCost: 1.0, RAMCost: 0.5},
+		})
+		sts.SetAssetTotalsByNode(s, e, map[string]*AssetTotals{
+			"cluster1/node1": {Start: s, End: e, Cluster: "cluster1", Node: "node1", Count: 1, CPUCost: 2.0},
+		})
+	}
+}
+
+func TestStorageTotalsStore(t *testing.T) {
+	store := storage.NewFileStorage(t.TempDir())
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	sts := NewStorageTotalsStore(store, "totals", time.Hour, 7*24*time.Hour, 30*24*time.Hour)
+	setHourlyTotals(sts, start, 24)
+
+	// Totals survive a restart
+	sts = NewStorageTotalsStore(store, "totals", time.Hour, 7*24*time.Hour, 30*24*time.Hour)
+
+	arts, ok := sts.GetAllocationTotalsByCluster(start, start.Add(time.Hour))
+	if !ok || !util.IsApproximately(arts["cluster1"].CPUCost, 1.0) {
+		t.Fatalf("expected hourly allocation totals; got %v", arts)
+	}
+
+	// Totals which were not set are not found
+	if _, ok := sts.GetAllocationTotalsByNode(start, start.Add(time.Hour)); ok {
+		t.Fatalf("expected no allocation totals by node")
+	}
+
+	// Totals for a day are composed from its hours
+	day := start.Add(24 * time.Hour)
+	arts, ok = sts.GetAllocationTotalsByCluster(start, day)
+	if !ok {
+		t.Fatalf("expected daily allocation totals")
+	}
+	if !util.IsApproximately(arts["cluster1"].TotalCost(), 36.0) || arts["cluster1"].Count != 2 {
+		t.Fatalf("expected daily allocation totals of 36.0 for 2 allocations; got %f for %d", arts["cluster1"].TotalCost(), arts["cluster1"].Count)
+	}
+	if !arts["cluster1"].Start.Equal(start) || !arts["cluster1"].End.Equal(day) {
+		t.Fatalf("expected daily allocation totals to cover the day; got %s to %s", arts["cluster1"].Start, arts["cluster1"].End)
+	}
+
+	// Totals for windows which are not covered are not found
+	if _, ok := sts.GetAllocationTotalsByCluster(start, day.Add(time.Hour)); ok {
+		t.Fatalf("expected no allocation totals beyond the stored hours")
+	}
+}
+
+func TestStorageTotalsStore_Compact(t *testing.T) {
+	store := storage.NewFileStorage(t.TempDir())
+	start := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	sts := NewStorageTotalsStore(store, "totals", time.Hour, 2*day, 5*day)
+
+	// One complete day, and one day missing an hour
+	setHourlyTotals(sts, start, 24)
+	setHourlyTotals(sts, start.Add(day), 23)
+
+	if err := sts.Compact(start.Add(4 * day)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The complete day is compacted, so its hours are no longer available
+	if _, ok := sts.GetAssetTotalsByNode(start, start.Add(time.Hour)); ok {
+		t.Fatalf("expected hourly asset totals to be compacted")
+	}
+	ats, ok := sts.GetAssetTotalsByNode(start, start.Add(day))
+	if !ok || !util.IsApproximately(ats["cluster1/node1"].TotalCost(), 48.0) {
+		t.Fatalf("expected daily asset totals of 48.0; got %v", ats)
+	}
+
+	// The incomplete day is not
+	if _, ok := sts.GetAssetTotalsByNode(start.Add(day), start.Add(day+time.Hour)); !ok {
+		t.Fatalf("expected hourly asset totals of incomplete day to remain")
+	}
+
+	// Compaction is persisted
+	files, err := store.List("totals")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(files) != 24 {
+		t.Fatalf("expected 1 daily and 23 hourly totals files; got %d", len(files))
+	}
+
+	// Totals beyond retention are pruned
+	if err := sts.Prune(start.Add(6*day + time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := sts.GetAssetTotalsByNode(start, start.Add(day)); ok {
+		t.Fatalf("expected daily asset totals to be pruned")
+	}
+	if _, ok := sts.GetAssetTotalsByNode(start.Add(day), start.Add(day+time.Hour)); !ok {
+		t.Fatalf("expected hourly asset totals within retention to remain")
+	}
+}
@@ -1,10 +1,16 @@
 package log
 
-import "time"
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
 
 type Profiler struct {
 	profiles map[string]time.Duration
 	starts   map[string]time.Time
+	span     trace.Span
 }
 
 func NewProfiler() *Profiler {
@@ -14,6 +20,15 @@ func NewProfiler() *Profiler {
 	}
 }
 
+// NewSpanProfiler creates a Profiler which, in addition to accumulating
+// timings, records each stopped timing as an event on the given span, if the
+// span is recording; i.e. not while tracing is disabled.
+func NewSpanProfiler(span trace.Span) *Profiler {
+	p := NewProfiler()
+	p.span = span
+	return p
+}
+
 func (p *Profiler) Start(name string) {
 	if p == nil {
 		return
@@ -28,6 +43,9 @@ func (p *Profiler) Stop(name string) time.Duration {
 	if start, ok := p.starts[name]; ok {
 		elapsed := time.Since(start)
 		p.profiles[name] += elapsed
+		if p.span != nil && p.span.IsRecording() {
+			p.span.AddEvent(name, trace.WithAttributes(attribute.Int64("duration_ms", elapsed.Milliseconds())))
+		}
 		return elapsed
 	}
 	return 0
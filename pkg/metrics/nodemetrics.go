@@ -54,6 +54,9 @@ func (nsac KubeNodeCollector) Describe(ch chan<- *prometheus.Desc) {
 	if _, disabled := disabledMetrics["kube_node_status_condition"]; !disabled {
 		ch <- prometheus.NewDesc("kube_node_status_condition", "The condition of a cluster node.", []string{}, nil)
 	}
+	if _, disabled := disabledMetrics["kube_node_spec_taint"]; !disabled {
+		ch <- prometheus.NewDesc("kube_node_spec_taint", "The taint of a cluster node.", []string{}, nil)
+	}
 }
 
 // Collect is called by the Prometheus registry when collecting metrics.
@@ -135,6 +138,13 @@ func (nsac KubeNodeCollector) Collect(ch chan<- prometheus.Metric) {
 				}
 			}
 		}
+
+		// kube_node_spec_taint
+		if _, disabled := disabledMetrics["kube_node_spec_taint"]; !disabled {
+			for _, t := range node.Spec.Taints {
+				ch <- newKubeNodeSpecTaintMetric(nodeName, "kube_node_spec_taint", t.Key, t.Value, string(t.Effect))
+			}
+		}
 	}
 }
 
@@ -407,6 +417,73 @@ func (nam KubeNodeStatusConditionMetric) Write(m *dto.Metric) error {
 	return nil
 }
 
+//--------------------------------------------------------------------------
+//  KubeNodeSpecTaintMetric
+//--------------------------------------------------------------------------
+
+// KubeNodeSpecTaintMetric is a prometheus.Metric used to encode a duplicate
+// of the kube-state-metrics metric kube_node_spec_taint
+type KubeNodeSpecTaintMetric struct {
+	fqName string
+	help   string
+	node   string
+	key    string
+	value  string
+	effect string
+}
+
+// Creates a new KubeNodeSpecTaintMetric, implementation of prometheus.Metric
+func newKubeNodeSpecTaintMetric(node, fqname, key, value, effect string) KubeNodeSpecTaintMetric {
+	return KubeNodeSpecTaintMetric{
+		fqName: fqname,
+		help:   "kube_node_spec_taint the taint of a cluster node",
+		node:   node,
+		key:    key,
+		value:  value,
+		effect: effect,
+	}
+}
+
+// Desc returns the descriptor for the Metric. This method idempotently
+// returns the same descriptor throughout the lifetime of the Metric.
+func (nam KubeNodeSpecTaintMetric) Desc() *prometheus.Desc {
+	l := prometheus.Labels{
+		"node":   nam.node,
+		"key":    nam.key,
+		"value":  nam.value,
+		"effect": nam.effect,
+	}
+	return prometheus.NewDesc(nam.fqName, nam.help, []string{}, l)
+}
+
+// Write encodes the Metric into a "Metric" Protocol Buffer data
+// transmission object.
+func (nam KubeNodeSpecTaintMetric) Write(m *dto.Metric) error {
+	v := float64(1)
+	m.Gauge = &dto.Gauge{
+		Value: &v,
+	}
+	m.Label = []*dto.LabelPair{
+		{
+			Name:  toStringPtr("node"),
+			Value: &nam.node,
+		},
+		{
+			Name:  toStringPtr("key"),
+			Value: &nam.key,
+		},
+		{
+			Name:  toStringPtr("value"),
+			Value: &nam.value,
+		},
+		{
+			Name:  toStringPtr("effect"),
+			Value: &nam.effect,
+		},
+	}
+	return nil
+}
+
 // helper type for status condition reporting and metric rollup
 type statusCondition struct {
 	status string
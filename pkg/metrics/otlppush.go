@@ -0,0 +1,228 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/version"
+	dto "github.com/prometheus/client_model/go"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// OTLPExporter is a PushExporter which sends metrics to an OTLP/HTTP metrics endpoint using the
+// binary protobuf encoding.
+type OTLPExporter struct {
+	client    *http.Client
+	endpoint  string
+	headers   map[string]string
+	startTime time.Time
+}
+
+// NewOTLPExporter creates a new OTLPExporter posting to the full endpoint URL, e.g.
+// http://otel-collector:4318/v1/metrics
+func NewOTLPExporter(client *http.Client, endpoint string, headers map[string]string) *OTLPExporter {
+	return &OTLPExporter{
+		client:    client,
+		endpoint:  endpoint,
+		headers:   headers,
+		startTime: time.Now(),
+	}
+}
+
+// Name returns the name of the exporter.
+func (oe *OTLPExporter) Name() string {
+	return "otlp"
+}
+
+// Export converts the metric families to an OTLP export request and sends it to the endpoint.
+func (oe *OTLPExporter) Export(ctx context.Context, families []*dto.MetricFamily) error {
+	req := otlpRequestFor(families, oe.startTime, time.Now())
+
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encoding otlp request: %w", err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/x-protobuf"}
+	for k, v := range oe.headers {
+		headers[k] = v
+	}
+
+	return postPayload(ctx, oe.client, oe.endpoint, body, headers)
+}
+
+// otlpRequestFor converts prometheus metric families to an OTLP export request. Counters are sent as
+// monotonic cumulative sums, gauges and untyped metrics as gauges, and histograms and summaries as their
+// OTLP equivalents.
+func otlpRequestFor(families []*dto.MetricFamily, start, now time.Time) *colmetricspb.ExportMetricsServiceRequest {
+	startNano := uint64(start.UnixNano())
+	nowNano := uint64(now.UnixNano())
+
+	metrics := make([]*metricspb.Metric, 0, len(families))
+	for _, mf := range families {
+		m := &metricspb.Metric{
+			Name:        mf.GetName(),
+			Description: mf.GetHelp(),
+		}
+
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			points := make([]*metricspb.NumberDataPoint, 0, len(mf.GetMetric()))
+			for _, metric := range mf.GetMetric() {
+				points = append(points, numberDataPoint(metric, metric.GetCounter().GetValue(), startNano, nowNano))
+			}
+			m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+				DataPoints:             points,
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+			}}
+
+		case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+			points := make([]*metricspb.HistogramDataPoint, 0, len(mf.GetMetric()))
+			for _, metric := range mf.GetMetric() {
+				points = append(points, histogramDataPoint(metric, startNano, nowNano))
+			}
+			m.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+				DataPoints:             points,
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			}}
+
+		case dto.MetricType_SUMMARY:
+			points := make([]*metricspb.SummaryDataPoint, 0, len(mf.GetMetric()))
+			for _, metric := range mf.GetMetric() {
+				points = append(points, summaryDataPoint(metric, startNano, nowNano))
+			}
+			m.Data = &metricspb.Metric_Summary{Summary: &metricspb.Summary{DataPoints: points}}
+
+		case dto.MetricType_GAUGE:
+			points := make([]*metricspb.NumberDataPoint, 0, len(mf.GetMetric()))
+			for _, metric := range mf.GetMetric() {
+				points = append(points, numberDataPoint(metric, metric.GetGauge().GetValue(), 0, nowNano))
+			}
+			m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}}
+
+		default:
+			points := make([]*metricspb.NumberDataPoint, 0, len(mf.GetMetric()))
+			for _, metric := range mf.GetMetric() {
+				points = append(points, numberDataPoint(metric, metric.GetUntyped().GetValue(), 0, nowNano))
+			}
+			m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}}
+		}
+
+		metrics = append(metrics, m)
+	}
+
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{
+						stringKeyValue("service.name", env.GetTracingServiceName()),
+						stringKeyValue("service.version", version.FriendlyVersion()),
+						stringKeyValue("k8s.cluster.name", env.GetClusterID()),
+					},
+				},
+				ScopeMetrics: []*metricspb.ScopeMetrics{
+					{
+						Scope: &commonpb.InstrumentationScope{
+							Name:    "github.com/opencost/opencost",
+							Version: version.Version,
+						},
+						Metrics: metrics,
+					},
+				},
+			},
+		},
+	}
+}
+
+func numberDataPoint(metric *dto.Metric, value float64, startNano, nowNano uint64) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		Attributes:        labelAttributes(metric.GetLabel()),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      timestampNano(metric, nowNano),
+		Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+	}
+}
+
+// histogramDataPoint converts a prometheus histogram, which uses cumulative bucket counts, into an OTLP
+// histogram data point, which uses per-bucket counts with an implicit +Inf bucket.
+func histogramDataPoint(metric *dto.Metric, startNano, nowNano uint64) *metricspb.HistogramDataPoint {
+	h := metric.GetHistogram()
+	sum := h.GetSampleSum()
+
+	var bounds []float64
+	var counts []uint64
+	var previous uint64
+	for _, b := range h.GetBucket() {
+		if math.IsInf(b.GetUpperBound(), 1) {
+			continue
+		}
+		bounds = append(bounds, b.GetUpperBound())
+		counts = append(counts, b.GetCumulativeCount()-previous)
+		previous = b.GetCumulativeCount()
+	}
+	counts = append(counts, h.GetSampleCount()-previous)
+
+	return &metricspb.HistogramDataPoint{
+		Attributes:        labelAttributes(metric.GetLabel()),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      timestampNano(metric, nowNano),
+		Count:             h.GetSampleCount(),
+		Sum:               &sum,
+		BucketCounts:      counts,
+		ExplicitBounds:    bounds,
+	}
+}
+
+func summaryDataPoint(metric *dto.Metric, startNano, nowNano uint64) *metricspb.SummaryDataPoint {
+	s := metric.GetSummary()
+
+	quantiles := make([]*metricspb.SummaryDataPoint_ValueAtQuantile, 0, len(s.GetQuantile()))
+	for _, q := range s.GetQuantile() {
+		quantiles = append(quantiles, &metricspb.SummaryDataPoint_ValueAtQuantile{
+			Quantile: q.GetQuantile(),
+			Value:    q.GetValue(),
+		})
+	}
+
+	return &metricspb.SummaryDataPoint{
+		Attributes:        labelAttributes(metric.GetLabel()),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      timestampNano(metric, nowNano),
+		Count:             s.GetSampleCount(),
+		Sum:               s.GetSampleSum(),
+		QuantileValues:    quantiles,
+	}
+}
+
+func labelAttributes(labels []*dto.LabelPair) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(labels))
+	for _, l := range labels {
+		attrs = append(attrs, stringKeyValue(l.GetName(), l.GetValue()))
+	}
+	return attrs
+}
+
+func stringKeyValue(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+// timestampNano returns the explicit timestamp of the metric if one was set, otherwise now.
+func timestampNano(metric *dto.Metric, nowNano uint64) uint64 {
+	if metric.TimestampMs != nil {
+		return uint64(metric.GetTimestampMs()) * uint64(time.Millisecond)
+	}
+	return nowNano
+}
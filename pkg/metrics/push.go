@@ -0,0 +1,297 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/errors"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/util/atomic"
+	"github.com/opencost/opencost/pkg/util/retry"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// PushExporter encodes and sends a batch of gathered metric families to a remote endpoint.
+type PushExporter interface {
+	// Name returns a human readable name for the exporter, used for logging.
+	Name() string
+
+	// Export sends the provided metric families, returning an error if the remote endpoint
+	// did not accept them.
+	Export(ctx context.Context, families []*dto.MetricFamily) error
+}
+
+// PushOpts configures the behavior of a PushEmitter.
+type PushOpts struct {
+	// Interval is the period between metric pushes.
+	Interval time.Duration
+
+	// Timeout is the maximum duration of a single push request, including retries.
+	Timeout time.Duration
+
+	// BatchSize is the maximum number of series sent in a single request. A value <= 0
+	// sends all series in a single request.
+	BatchSize int
+
+	// MaxRetries is the number of additional attempts made for a failed request.
+	MaxRetries int
+
+	// RetryDelay is the initial delay between retries.
+	RetryDelay time.Duration
+}
+
+// PushEmitter periodically gathers metrics from a prometheus.Gatherer and pushes them to a remote
+// endpoint using a PushExporter. Metrics disabled in the MetricsConfig are not pushed.
+type PushEmitter struct {
+	gatherer prometheus.Gatherer
+	exporter PushExporter
+	opts     PushOpts
+	runState atomic.AtomicRunState
+}
+
+// NewPushEmitter creates a new PushEmitter which gathers from gatherer and sends using exporter.
+// Use Start() to begin pushing metrics.
+func NewPushEmitter(gatherer prometheus.Gatherer, exporter PushExporter, opts PushOpts) *PushEmitter {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+
+	return &PushEmitter{
+		gatherer: gatherer,
+		exporter: exporter,
+		opts:     opts,
+	}
+}
+
+// NewPushEmitterFromEnv creates a PushEmitter for the default prometheus registry configured from
+// the environment. If metric pushing is disabled, nil is returned with a nil error.
+func NewPushEmitterFromEnv() (*PushEmitter, error) {
+	if !env.IsMetricsPushEnabled() {
+		return nil, nil
+	}
+
+	endpoint := env.GetMetricsPushEndpoint()
+	if endpoint == "" {
+		return nil, fmt.Errorf("%s must be set when %s is %s", env.MetricsPushEndpointEnvVar, env.MetricsPushModeEnvVar, env.GetMetricsPushMode())
+	}
+
+	client := newPushClient(env.GetMetricsPushTimeout(), env.IsMetricsPushInsecureSkipVerify())
+	headers := env.GetMetricsPushHeaders()
+
+	var exporter PushExporter
+	switch mode := env.GetMetricsPushMode(); mode {
+	case env.MetricsPushModeOTLP:
+		exporter = NewOTLPExporter(client, endpoint, headers)
+	case env.MetricsPushModeRemoteWrite:
+		exporter = NewRemoteWriteExporter(client, endpoint, headers)
+	default:
+		return nil, fmt.Errorf("unsupported metrics push mode: %s", mode)
+	}
+
+	return NewPushEmitter(prometheus.DefaultGatherer, exporter, PushOpts{
+		Interval:   env.GetMetricsPushInterval(),
+		Timeout:    env.GetMetricsPushTimeout(),
+		BatchSize:  env.GetMetricsPushBatchSize(),
+		MaxRetries: env.GetMetricsPushMaxRetries(),
+		RetryDelay: env.GetMetricsPushRetryDelay(),
+	}), nil
+}
+
+// IsRunning returns true if metric pushing is running.
+func (pe *PushEmitter) IsRunning() bool {
+	return pe.runState.IsRunning()
+}
+
+// Start begins pushing metrics on the configured interval. Returns false if the emitter is already running.
+func (pe *PushEmitter) Start() bool {
+	// wait for a reset to prevent a race between start and stop calls
+	pe.runState.WaitForReset()
+
+	if !pe.runState.Start() {
+		log.Errorf("Attempted to start metric push emitter when it's already running.")
+		return false
+	}
+
+	log.Infof("Pushing metrics every %s via %s", pe.opts.Interval, pe.exporter.Name())
+
+	go func() {
+		defer errors.HandlePanic()
+
+		ticker := time.NewTicker(pe.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-pe.runState.OnStop():
+				pe.runState.Reset()
+				return
+			case <-ticker.C:
+			}
+
+			if err := pe.Push(context.Background()); err != nil {
+				log.Warnf("Failed to push metrics via %s: %s", pe.exporter.Name(), err)
+			}
+		}
+	}()
+
+	return true
+}
+
+// Stop halts metric pushing.
+func (pe *PushEmitter) Stop() {
+	pe.runState.Stop()
+}
+
+// Push gathers metrics and sends them to the exporter in batches. Each batch is retried independently,
+// and the first error encountered is returned after all batches have been attempted.
+func (pe *PushEmitter) Push(ctx context.Context) error {
+	families, err := pe.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return fmt.Errorf("gathering metrics: %w", err)
+	} else if err != nil {
+		log.DedupedWarningf(5, "Partial failure gathering metrics for push: %s", err)
+	}
+
+	// Re-read the metrics config each push so disabled metrics can be toggled without restarting
+	config, err := GetMetricsConfig()
+	if err != nil {
+		log.DedupedWarningf(5, "Failed to read metrics config for push: %s", err)
+	}
+	families = filterDisabledFamilies(families, config.GetDisabledMetricsMap())
+
+	var pushErr error
+	for _, batch := range batchFamilies(families, pe.opts.BatchSize) {
+		if err := pe.pushBatch(ctx, batch); err != nil && pushErr == nil {
+			pushErr = err
+		}
+	}
+
+	return pushErr
+}
+
+func (pe *PushEmitter) pushBatch(ctx context.Context, batch []*dto.MetricFamily) error {
+	ctx, cancel := context.WithTimeout(ctx, pe.opts.Timeout)
+	defer cancel()
+
+	_, err := retry.Retry(ctx, func() (struct{}, error) {
+		return struct{}{}, pe.exporter.Export(ctx, batch)
+	}, uint(pe.opts.MaxRetries+1), pe.opts.RetryDelay)
+
+	return err
+}
+
+// filterDisabledFamilies removes any metric families whose names are in the disabled set.
+func filterDisabledFamilies(families []*dto.MetricFamily, disabled map[string]struct{}) []*dto.MetricFamily {
+	if len(disabled) == 0 {
+		return families
+	}
+
+	filtered := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		if _, ok := disabled[mf.GetName()]; ok {
+			continue
+		}
+		filtered = append(filtered, mf)
+	}
+	return filtered
+}
+
+// batchFamilies splits the metric families into batches containing at most size series. Families
+// larger than size are split across batches.
+func batchFamilies(families []*dto.MetricFamily, size int) [][]*dto.MetricFamily {
+	if len(families) == 0 {
+		return nil
+	}
+	if size <= 0 {
+		return [][]*dto.MetricFamily{families}
+	}
+
+	var batches [][]*dto.MetricFamily
+	var current []*dto.MetricFamily
+	count := 0
+
+	for _, mf := range families {
+		remaining := mf.GetMetric()
+		for len(remaining) > 0 {
+			n := size - count
+			if n > len(remaining) {
+				n = len(remaining)
+			}
+
+			current = append(current, &dto.MetricFamily{
+				Name:   mf.Name,
+				Help:   mf.Help,
+				Type:   mf.Type,
+				Metric: remaining[:n],
+			})
+			remaining = remaining[n:]
+			count += n
+
+			if count >= size {
+				batches = append(batches, current)
+				current = nil
+				count = 0
+			}
+		}
+	}
+
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+func newPushClient(timeout time.Duration, insecureSkipVerify bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // #nosec Explicitly configured by the user
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
+
+// postPayload sends body to endpoint with the provided headers, returning an error for any non-2xx response.
+func postPayload(ctx context.Context, client *http.Client, endpoint string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,238 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func testRegistry(t *testing.T) *prometheus.Registry {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "node_total_hourly_cost",
+		Help: "node_total_hourly_cost Total node cost per hour",
+	}, []string{"node"})
+	gauge.WithLabelValues("node-a").Set(1.5)
+	gauge.WithLabelValues("node-b").Set(2.5)
+
+	counter := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "kubecost_http_requests_total",
+		Help: "kubecost_http_requests_total Total number of HTTP requests",
+	})
+	counter.Add(3)
+
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kubecost_http_response_time_seconds",
+		Help:    "kubecost_http_response_time_seconds Response time in seconds",
+		Buckets: []float64{0.1, 1},
+	})
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+
+	registry.MustRegister(gauge, counter, histogram)
+	return registry
+}
+
+func TestBatchFamilies(t *testing.T) {
+	families, err := testRegistry(t).Gather()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// 4 series in total: 1 counter, 1 histogram, 2 gauge series
+	batches := batchFamilies(families, 3)
+	if len(batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(batches))
+	}
+
+	total := 0
+	for _, batch := range batches {
+		count := 0
+		for _, mf := range batch {
+			count += len(mf.GetMetric())
+		}
+		if count > 3 {
+			t.Errorf("batch exceeds batch size: %d", count)
+		}
+		total += count
+	}
+	if total != 4 {
+		t.Errorf("expected 4 series across batches, got %d", total)
+	}
+
+	if len(batchFamilies(families, 0)) != 1 {
+		t.Errorf("expected a single batch when batch size is unbounded")
+	}
+}
+
+func TestFilterDisabledFamilies(t *testing.T) {
+	families, _ := testRegistry(t).Gather()
+
+	filtered := filterDisabledFamilies(families, map[string]struct{}{"node_total_hourly_cost": {}})
+	if len(filtered) != len(families)-1 {
+		t.Fatalf("expected %d families, got %d", len(families)-1, len(filtered))
+	}
+	for _, mf := range filtered {
+		if mf.GetName() == "node_total_hourly_cost" {
+			t.Errorf("disabled metric was not filtered")
+		}
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var received *colmetricspb.ExportMetricsServiceRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("unexpected content type: %s", r.Header.Get("Content-Type"))
+		}
+		if r.Header.Get("X-Tenant") != "team-a" {
+			t.Errorf("expected configured header to be sent")
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		received = &colmetricspb.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, received); err != nil {
+			t.Errorf("failed to decode otlp request: %s", err)
+		}
+	}))
+	defer server.Close()
+
+	families, _ := testRegistry(t).Gather()
+	exporter := NewOTLPExporter(server.Client(), server.URL+"/v1/metrics", map[string]string{"X-Tenant": "team-a"})
+	if err := exporter.Export(context.Background(), families); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	otlpMetrics := received.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+	if len(otlpMetrics) != 3 {
+		t.Fatalf("expected 3 metrics, got %d", len(otlpMetrics))
+	}
+
+	for _, m := range otlpMetrics {
+		switch m.GetName() {
+		case "kubecost_http_requests_total":
+			sum := m.GetSum()
+			if sum == nil || !sum.GetIsMonotonic() || sum.GetDataPoints()[0].GetAsDouble() != 3 {
+				t.Errorf("expected counter to be a monotonic sum of 3, got %v", m)
+			}
+		case "kubecost_http_response_time_seconds":
+			dp := m.GetHistogram().GetDataPoints()[0]
+			if dp.GetCount() != 3 || len(dp.GetExplicitBounds()) != 2 {
+				t.Fatalf("unexpected histogram data point: %v", dp)
+			}
+			for i, expected := range []uint64{1, 1, 1} {
+				if dp.GetBucketCounts()[i] != expected {
+					t.Errorf("bucket %d: expected %d, got %d", i, expected, dp.GetBucketCounts()[i])
+				}
+			}
+		case "node_total_hourly_cost":
+			if len(m.GetGauge().GetDataPoints()) != 2 {
+				t.Errorf("expected 2 gauge data points, got %v", m)
+			}
+		}
+	}
+}
+
+func TestRemoteWriteExporter(t *testing.T) {
+	var timeseries int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			t.Errorf("expected snappy content encoding")
+		}
+		if r.Header.Get("X-Prometheus-Remote-Write-Version") == "" {
+			t.Errorf("expected remote write version header")
+		}
+
+		compressed, _ := io.ReadAll(r.Body)
+		body, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			t.Errorf("failed to decompress body: %s", err)
+			return
+		}
+
+		for len(body) > 0 {
+			num, typ, n := protowire.ConsumeTag(body)
+			if n < 0 {
+				t.Errorf("malformed write request")
+				return
+			}
+			body = body[n:]
+			n = protowire.ConsumeFieldValue(num, typ, body)
+			if n < 0 {
+				t.Errorf("malformed write request")
+				return
+			}
+			body = body[n:]
+			if num == rwWriteRequestTimeseries {
+				atomic.AddInt32(&timeseries, 1)
+			}
+		}
+	}))
+	defer server.Close()
+
+	families, _ := testRegistry(t).Gather()
+	exporter := NewRemoteWriteExporter(server.Client(), server.URL, nil)
+	if err := exporter.Export(context.Background(), families); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// 2 gauges + 1 counter + histogram (3 buckets, _sum, _count)
+	if timeseries != 8 {
+		t.Errorf("expected 8 time series, got %d", timeseries)
+	}
+}
+
+type failingExporter struct {
+	failures int32
+	calls    int32
+}
+
+func (fe *failingExporter) Name() string { return "failing" }
+
+func (fe *failingExporter) Export(ctx context.Context, families []*dto.MetricFamily) error {
+	if atomic.AddInt32(&fe.calls, 1) <= fe.failures {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func TestPushEmitterRetries(t *testing.T) {
+	exporter := &failingExporter{failures: 2}
+	emitter := NewPushEmitter(testRegistry(t), exporter, PushOpts{
+		MaxRetries: 2,
+		RetryDelay: time.Millisecond,
+	})
+
+	if err := emitter.Push(context.Background()); err != nil {
+		t.Fatalf("expected push to succeed after retries, got: %s", err)
+	}
+	if exporter.calls != 3 {
+		t.Errorf("expected 3 export attempts, got %d", exporter.calls)
+	}
+
+	exporter = &failingExporter{failures: 5}
+	emitter = NewPushEmitter(testRegistry(t), exporter, PushOpts{
+		MaxRetries: 1,
+		RetryDelay: time.Millisecond,
+	})
+	if err := emitter.Push(context.Background()); err == nil {
+		t.Fatalf("expected push to fail once retries are exhausted")
+	}
+}
@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Prometheus remote write protocol field numbers and metadata types. See prompb/types.proto and
+// prompb/remote.proto in the prometheus repository.
+const (
+	rwWriteRequestTimeseries = 1
+	rwWriteRequestMetadata   = 3
+	rwTimeseriesLabels       = 1
+	rwTimeseriesSamples      = 2
+	rwLabelName              = 1
+	rwLabelValue             = 2
+	rwSampleValue            = 1
+	rwSampleTimestamp        = 2
+	rwMetadataType           = 1
+	rwMetadataFamilyName     = 2
+	rwMetadataHelp           = 4
+
+	rwMetadataTypeUnknown   = 0
+	rwMetadataTypeCounter   = 1
+	rwMetadataTypeGauge     = 2
+	rwMetadataTypeHistogram = 3
+	rwMetadataTypeGaugeHist = 4
+	rwMetadataTypeSummary   = 5
+)
+
+// RemoteWriteExporter is a PushExporter which sends metrics to a Prometheus remote write endpoint,
+// such as Prometheus with the remote write receiver enabled, Cortex, Thanos, or Mimir.
+type RemoteWriteExporter struct {
+	client   *http.Client
+	endpoint string
+	headers  map[string]string
+}
+
+// NewRemoteWriteExporter creates a new RemoteWriteExporter posting to the full endpoint URL, e.g.
+// http://prometheus:9090/api/v1/write
+func NewRemoteWriteExporter(client *http.Client, endpoint string, headers map[string]string) *RemoteWriteExporter {
+	return &RemoteWriteExporter{
+		client:   client,
+		endpoint: endpoint,
+		headers:  headers,
+	}
+}
+
+// Name returns the name of the exporter.
+func (rwe *RemoteWriteExporter) Name() string {
+	return "remote-write"
+}
+
+// Export encodes the metric families as a snappy compressed remote write request and sends it to the endpoint.
+func (rwe *RemoteWriteExporter) Export(ctx context.Context, families []*dto.MetricFamily) error {
+	body := snappy.Encode(nil, encodeWriteRequest(families, time.Now()))
+
+	headers := map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	}
+	for k, v := range rwe.headers {
+		headers[k] = v
+	}
+
+	return postPayload(ctx, rwe.client, rwe.endpoint, body, headers)
+}
+
+type rwLabel struct {
+	name, value string
+}
+
+// encodeWriteRequest encodes the metric families as a prompb.WriteRequest. Histograms and summaries are
+// expanded into their classic _bucket, _sum, and _count series.
+func encodeWriteRequest(families []*dto.MetricFamily, now time.Time) []byte {
+	nowMs := now.UnixMilli()
+
+	var buf []byte
+	for _, mf := range families {
+		name := mf.GetName()
+
+		for _, metric := range mf.GetMetric() {
+			ts := nowMs
+			if metric.TimestampMs != nil {
+				ts = metric.GetTimestampMs()
+			}
+
+			labels := make([]rwLabel, 0, len(metric.GetLabel())+2)
+			for _, l := range metric.GetLabel() {
+				labels = append(labels, rwLabel{l.GetName(), l.GetValue()})
+			}
+
+			appendSeries := func(suffix string, value float64, extra ...rwLabel) {
+				buf = protowire.AppendTag(buf, rwWriteRequestTimeseries, protowire.BytesType)
+				buf = protowire.AppendBytes(buf, encodeTimeseries(name+suffix, labels, extra, value, ts))
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				appendSeries("", metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				appendSeries("", metric.GetGauge().GetValue())
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := metric.GetHistogram()
+				hasInf := false
+				for _, b := range h.GetBucket() {
+					if math.IsInf(b.GetUpperBound(), 1) {
+						hasInf = true
+					}
+					appendSeries("_bucket", float64(b.GetCumulativeCount()), rwLabel{"le", formatFloat(b.GetUpperBound())})
+				}
+				if !hasInf {
+					appendSeries("_bucket", float64(h.GetSampleCount()), rwLabel{"le", "+Inf"})
+				}
+				appendSeries("_sum", h.GetSampleSum())
+				appendSeries("_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := metric.GetSummary()
+				for _, q := range s.GetQuantile() {
+					appendSeries("", q.GetValue(), rwLabel{"quantile", formatFloat(q.GetQuantile())})
+				}
+				appendSeries("_sum", s.GetSampleSum())
+				appendSeries("_count", float64(s.GetSampleCount()))
+			default:
+				appendSeries("", metric.GetUntyped().GetValue())
+			}
+		}
+
+		buf = protowire.AppendTag(buf, rwWriteRequestMetadata, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, encodeMetadata(mf))
+	}
+
+	return buf
+}
+
+func encodeTimeseries(name string, labels []rwLabel, extra []rwLabel, value float64, timestampMs int64) []byte {
+	all := make([]rwLabel, 0, len(labels)+len(extra)+1)
+	all = append(all, rwLabel{"__name__", name})
+	all = append(all, labels...)
+	all = append(all, extra...)
+
+	// Remote write receivers require labels sorted by name
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].name < all[j].name
+	})
+
+	var buf []byte
+	for _, l := range all {
+		var lb []byte
+		lb = protowire.AppendTag(lb, rwLabelName, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.name)
+		lb = protowire.AppendTag(lb, rwLabelValue, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.value)
+
+		buf = protowire.AppendTag(buf, rwTimeseriesLabels, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, lb)
+	}
+
+	var sb []byte
+	sb = protowire.AppendTag(sb, rwSampleValue, protowire.Fixed64Type)
+	sb = protowire.AppendFixed64(sb, math.Float64bits(value))
+	sb = protowire.AppendTag(sb, rwSampleTimestamp, protowire.VarintType)
+	sb = protowire.AppendVarint(sb, uint64(timestampMs))
+
+	buf = protowire.AppendTag(buf, rwTimeseriesSamples, protowire.BytesType)
+	buf = protowire.AppendBytes(buf, sb)
+
+	return buf
+}
+
+func encodeMetadata(mf *dto.MetricFamily) []byte {
+	var metaType uint64
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		metaType = rwMetadataTypeCounter
+	case dto.MetricType_GAUGE:
+		metaType = rwMetadataTypeGauge
+	case dto.MetricType_HISTOGRAM:
+		metaType = rwMetadataTypeHistogram
+	case dto.MetricType_GAUGE_HISTOGRAM:
+		metaType = rwMetadataTypeGaugeHist
+	case dto.MetricType_SUMMARY:
+		metaType = rwMetadataTypeSummary
+	default:
+		metaType = rwMetadataTypeUnknown
+	}
+
+	var buf []byte
+	buf = protowire.AppendTag(buf, rwMetadataType, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, metaType)
+	buf = protowire.AppendTag(buf, rwMetadataFamilyName, protowire.BytesType)
+	buf = protowire.AppendString(buf, mf.GetName())
+	if mf.GetHelp() != "" {
+		buf = protowire.AppendTag(buf, rwMetadataHelp, protowire.BytesType)
+		buf = protowire.AppendString(buf, mf.GetHelp())
+	}
+	return buf
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	if math.IsInf(f, -1) {
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
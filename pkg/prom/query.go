@@ -6,15 +6,19 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/opencost/opencost/pkg/env"
 	"github.com/opencost/opencost/pkg/errors"
 	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/tracing"
 	"github.com/opencost/opencost/pkg/util/httputil"
 	"github.com/opencost/opencost/pkg/util/json"
 	prometheus "github.com/prometheus/client_golang/api"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -27,12 +31,17 @@ const (
 // package scope to prevent calling duration parse each use
 var promQueryOffset time.Duration = env.GetPrometheusQueryOffset()
 
+// queryIDs is used to assign a unique, process-wide identifier to each query
+// run through a Context, which is recorded on the query's trace span
+var queryIDs atomic.Uint64
+
 // Context wraps a Prometheus client and provides methods for querying and
 // parsing query responses and errors.
 type Context struct {
 	Client         prometheus.Client
 	name           string
 	errorCollector *QueryErrorCollector
+	parent         context.Context
 }
 
 // NewContext creates a new Promethues querying context from the given client
@@ -53,6 +62,40 @@ func NewNamedContext(client prometheus.Client, name string) *Context {
 	return ctx
 }
 
+// WithContext returns a copy of the Context which uses the provided context as
+// the parent of outbound requests. Trace spans recorded for each query become
+// children of any span contained in c. The copy shares the ErrorCollector of
+// the original Context.
+func (ctx *Context) WithContext(c context.Context) *Context {
+	return &Context{
+		Client:         ctx.Client,
+		name:           ctx.name,
+		errorCollector: ctx.errorCollector,
+		parent:         c,
+	}
+}
+
+// requestContext returns the parent context of outbound requests, defaulting
+// to context.Background()
+func (ctx *Context) requestContext() context.Context {
+	if ctx.parent == nil {
+		return context.Background()
+	}
+	return ctx.parent
+}
+
+// startSpan creates a trace span for a single query run through the Context.
+func (ctx *Context) startSpan(name, query, profileLabel string) (context.Context, trace.Span) {
+	return tracing.Start(
+		ctx.requestContext(),
+		name,
+		attribute.Int64("prom.query.id", int64(queryIDs.Add(1))),
+		attribute.String("prom.query", query),
+		attribute.String("prom.context", ctx.name),
+		attribute.String("prom.profile", profileLabel),
+	)
+}
+
 // Warnings returns the warnings collected from the Context's ErrorCollector
 func (ctx *Context) Warnings() []*QueryWarning {
 	return ctx.errorCollector.Warnings()
@@ -147,7 +190,11 @@ func (ctx *Context) ProfileQueryAll(queries ...string) []QueryResultsChan {
 }
 
 func (ctx *Context) QuerySync(query string) ([]*QueryResult, v1.Warnings, error) {
-	raw, warnings, err := ctx.query(query, time.Now())
+	c, span := ctx.startSpan("prom.QuerySync", query, "")
+	defer span.End()
+
+	raw, warnings, err := ctx.query(c, query, time.Now())
+	tracing.RecordError(span, err)
 	if err != nil {
 		return nil, warnings, err
 	}
@@ -171,21 +218,45 @@ func runQuery(query string, ctx *Context, resCh QueryResultsChan, t time.Time, p
 	defer errors.HandlePanic()
 	startQuery := time.Now()
 
-	raw, warnings, requestError := ctx.query(query, t)
+	c, span := ctx.startSpan("prom.Query", query, profileLabel)
+	defer span.End()
+
+	raw, warnings, requestError := ctx.query(c, query, t)
 	results := NewQueryResults(query, raw)
 
 	// report all warnings, request, and parse errors (nils will be ignored)
 	ctx.errorCollector.Report(query, warnings, requestError, results.Error)
+	recordQueryResults(span, results, warnings, requestError)
 
 	if profileLabel != "" {
 		log.Profile(startQuery, profileLabel)
+		span.AddEvent(profileLabel, trace.WithAttributes(attribute.Int64("duration_ms", time.Since(startQuery).Milliseconds())))
 	}
 
 	resCh <- results
 }
 
+// recordQueryResults annotates a query span with the size of the result and any
+// warnings or errors returned
+func recordQueryResults(span trace.Span, results *QueryResults, warnings v1.Warnings, requestError error) {
+	span.SetAttributes(
+		attribute.Int("prom.results", len(results.Results)),
+		attribute.Int("prom.warnings", len(warnings)),
+	)
+
+	if requestError != nil {
+		tracing.RecordError(span, requestError)
+	} else {
+		tracing.RecordError(span, results.Error)
+	}
+}
+
 // RawQuery is a direct query to the prometheus client and returns the body of the response
 func (ctx *Context) RawQuery(query string, t time.Time) ([]byte, error) {
+	return ctx.rawQuery(ctx.requestContext(), query, t)
+}
+
+func (ctx *Context) rawQuery(c context.Context, query string, t time.Time) ([]byte, error) {
 	u := ctx.Client.URL(epQuery, nil)
 	q := u.Query()
 	q.Set("query", query)
@@ -212,7 +283,7 @@ func (ctx *Context) RawQuery(query string, t time.Time) ([]byte, error) {
 	// Note that the warnings return value from client.Do() is always nil using this
 	// version of the prometheus client library. We parse the warnings out of the response
 	// body after json decodidng completes.
-	resp, body, err := ctx.Client.Do(context.Background(), req)
+	resp, body, err := ctx.Client.Do(c, req)
 	if err != nil {
 		if resp == nil {
 			return nil, fmt.Errorf("query error: '%s' fetching query '%s'", err.Error(), query)
@@ -231,8 +302,8 @@ func (ctx *Context) RawQuery(query string, t time.Time) ([]byte, error) {
 	return body, err
 }
 
-func (ctx *Context) query(query string, t time.Time) (interface{}, v1.Warnings, error) {
-	body, err := ctx.RawQuery(query, t)
+func (ctx *Context) query(c context.Context, query string, t time.Time) (interface{}, v1.Warnings, error) {
+	body, err := ctx.rawQuery(c, query, t)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -287,7 +358,11 @@ func (ctx *Context) ProfileQueryRange(query string, start, end time.Time, step t
 }
 
 func (ctx *Context) QueryRangeSync(query string, start, end time.Time, step time.Duration) ([]*QueryResult, v1.Warnings, error) {
-	raw, warnings, err := ctx.queryRange(query, start, end, step)
+	c, span := ctx.startSpan("prom.QueryRangeSync", query, "")
+	defer span.End()
+
+	raw, warnings, err := ctx.queryRange(c, query, start, end, step)
+	tracing.RecordError(span, err)
 	if err != nil {
 		return nil, warnings, err
 	}
@@ -311,14 +386,19 @@ func runQueryRange(query string, start, end time.Time, step time.Duration, ctx *
 	defer errors.HandlePanic()
 	startQuery := time.Now()
 
-	raw, warnings, requestError := ctx.queryRange(query, start, end, step)
+	c, span := ctx.startSpan("prom.QueryRange", query, profileLabel)
+	defer span.End()
+
+	raw, warnings, requestError := ctx.queryRange(c, query, start, end, step)
 	results := NewQueryResults(query, raw)
 
 	// report all warnings, request, and parse errors (nils will be ignored)
 	ctx.errorCollector.Report(query, warnings, requestError, results.Error)
+	recordQueryResults(span, results, warnings, requestError)
 
 	if profileLabel != "" {
 		log.Profile(startQuery, profileLabel)
+		span.AddEvent(profileLabel, trace.WithAttributes(attribute.Int64("duration_ms", time.Since(startQuery).Milliseconds())))
 	}
 
 	resCh <- results
@@ -326,6 +406,10 @@ func runQueryRange(query string, start, end time.Time, step time.Duration, ctx *
 
 // RawQuery is a direct query to the prometheus client and returns the body of the response
 func (ctx *Context) RawQueryRange(query string, start, end time.Time, step time.Duration) ([]byte, error) {
+	return ctx.rawQueryRange(ctx.requestContext(), query, start, end, step)
+}
+
+func (ctx *Context) rawQueryRange(c context.Context, query string, start, end time.Time, step time.Duration) ([]byte, error) {
 	u := ctx.Client.URL(epQueryRange, nil)
 	q := u.Query()
 	q.Set("query", query)
@@ -348,7 +432,7 @@ func (ctx *Context) RawQueryRange(query string, start, end time.Time, step time.
 	// Note that the warnings return value from client.Do() is always nil using this
 	// version of the prometheus client library. We parse the warnings out of the response
 	// body after json decodidng completes.
-	resp, body, err := ctx.Client.Do(context.Background(), req)
+	resp, body, err := ctx.Client.Do(c, req)
 	if err != nil {
 		if resp == nil {
 			return nil, fmt.Errorf("Error: %s, Body: %s Query: %s", err.Error(), body, query)
@@ -367,8 +451,8 @@ func (ctx *Context) RawQueryRange(query string, start, end time.Time, step time.
 	return body, err
 }
 
-func (ctx *Context) queryRange(query string, start, end time.Time, step time.Duration) (interface{}, v1.Warnings, error) {
-	body, err := ctx.RawQueryRange(query, start, end, step)
+func (ctx *Context) queryRange(c context.Context, query string, start, end time.Time, step time.Duration) (interface{}, v1.Warnings, error) {
+	body, err := ctx.rawQueryRange(c, query, start, end, step)
 
 	if err != nil {
 		return nil, nil, err
@@ -1,9 +1,14 @@
 package prom
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/util/json"
 
 	prometheus "github.com/prometheus/client_golang/api"
 )
@@ -70,3 +75,81 @@ func validate(cli prometheus.Client, q string) (*PrometheusMetadata, error) {
 		KubecostDataExists: false,
 	}, nil
 }
+
+// QueryValidation represents the result of validating a query against
+// prometheus/thanos. Valid is only meaningful if the query was Validated, as
+// opposed to prometheus being unreachable or failing for other reasons.
+type QueryValidation struct {
+	Query     string `json:"query"`
+	Validated bool   `json:"validated"`
+	Valid     bool   `json:"valid"`
+	HasData   bool   `json:"hasData"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ValidateQuery executes the query against the provided client, reporting
+// whether it is accepted by prometheus and whether it returns any data. The
+// query is only reported invalid if prometheus rejects it, i.e. responds with
+// 400 (bad_data) or 422 (execution error).
+func ValidateQuery(cli prometheus.Client, q string) *QueryValidation {
+	u := cli.URL(epQuery, nil)
+	params := u.Query()
+	params.Set("query", q)
+	params.Set("time", strconv.FormatInt(time.Now().Unix(), 10))
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	if err != nil {
+		return &QueryValidation{
+			Query: q,
+			Error: err.Error(),
+		}
+	}
+
+	resp, body, err := cli.Do(context.Background(), req)
+	if err != nil {
+		return &QueryValidation{
+			Query: q,
+			Error: err.Error(),
+		}
+	}
+
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity {
+		return &QueryValidation{
+			Query:     q,
+			Validated: true,
+			Valid:     false,
+			Error:     fmt.Sprintf("%d (%s): %s", resp.StatusCode, http.StatusText(resp.StatusCode), body),
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &QueryValidation{
+			Query: q,
+			Error: fmt.Sprintf("%d (%s): %s", resp.StatusCode, http.StatusText(resp.StatusCode), body),
+		}
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return &QueryValidation{
+			Query: q,
+			Error: fmt.Sprintf("unmarshal error: %s", err),
+		}
+	}
+
+	results := NewQueryResults(q, raw)
+	if results.Error != nil {
+		return &QueryValidation{
+			Query: q,
+			Error: results.Error.Error(),
+		}
+	}
+
+	return &QueryValidation{
+		Query:     q,
+		Validated: true,
+		Valid:     true,
+		HasData:   len(results.Results) > 0,
+	}
+}
@@ -50,6 +50,20 @@ type ClusterDefinition struct {
 	Details map[string]interface{} `json:"details,omitempty"`
 }
 
+// BasicAuth returns the base64 encoded basic auth credentials stored in the cluster details, if any.
+func (cd *ClusterDefinition) BasicAuth() (string, bool) {
+	if cd.Details == nil {
+		return "", false
+	}
+
+	auth, ok := cd.Details[DetailsAuthKey].(string)
+	if !ok || auth == "" {
+		return "", false
+	}
+
+	return auth, true
+}
+
 // ClusterStorage interface defines an implementation prototype for a storage responsible
 // for ClusterDefinition instances
 type ClusterStorage interface {
@@ -0,0 +1,393 @@
+package clusters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/util/json"
+)
+
+const (
+	allocationComputePath = "/allocation/compute"
+	assetsPath            = "/assets"
+)
+
+// ClusterSource provides the cluster definitions which are queried by a Federator.
+type ClusterSource interface {
+	GetAll() []*ClusterDefinition
+}
+
+// ClusterError describes a failure to query a single cluster during a federated query.
+type ClusterError struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Error   string `json:"error"`
+}
+
+// FederatedAllocationQuery contains the parameters of a federated allocation query.
+type FederatedAllocationQuery struct {
+	Window      kubecost.Window
+	Resolution  time.Duration
+	Step        time.Duration
+	AggregateBy []string
+	IncludeIdle bool
+	ShareIdle   bool
+	IdleByNode  bool
+}
+
+// FederatedAllocationResult contains the merged allocations of all clusters which responded
+// successfully, along with errors for each cluster which did not.
+type FederatedAllocationResult struct {
+	Allocations *kubecost.AllocationSetRange `json:"allocations"`
+	Clusters    []string                     `json:"clusters"`
+	Errors      []*ClusterError              `json:"errors,omitempty"`
+}
+
+// FederatedAssetResult contains the merged assets of all clusters which responded successfully,
+// along with errors for each cluster which did not.
+type FederatedAssetResult struct {
+	Assets   *kubecost.AssetSetRange `json:"assets"`
+	Clusters []string                `json:"clusters"`
+	Errors   []*ClusterError         `json:"errors,omitempty"`
+}
+
+// Federator fans queries out to every cluster definition provided by a ClusterSource and merges
+// the results. Remote clusters are always queried for unaggregated data over the same absolute
+// window, so that aggregation and idle sharing can be applied across all clusters at once. Each
+// cluster ID may only be reported by one remote; the data of any later remote reporting the same
+// cluster ID is rejected with an error, rather than merged.
+type Federator struct {
+	clusters ClusterSource
+	client   *http.Client
+	timeout  time.Duration
+}
+
+// NewFederator creates a new Federator which queries the clusters provided by the source using the
+// client, waiting up to timeout for each cluster to respond.
+func NewFederator(clusters ClusterSource, client *http.Client, timeout time.Duration) *Federator {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Federator{
+		clusters: clusters,
+		client:   client,
+		timeout:  timeout,
+	}
+}
+
+// QueryAllocation queries /allocation/compute on each cluster, merges the returned sets by window,
+// then aggregates the merged range.
+func (f *Federator) QueryAllocation(ctx context.Context, query FederatedAllocationQuery) (*FederatedAllocationResult, error) {
+	windows, err := stepWindows(query.Window, query.Step)
+	if err != nil {
+		return nil, err
+	}
+
+	params := windowParams(query.Window, query.Step)
+	params.Set("unaggregated", "true")
+	if query.Resolution > 0 {
+		params.Set("resolution", query.Resolution.String())
+	}
+	if query.IncludeIdle || query.ShareIdle {
+		params.Set("includeIdle", "true")
+		params.Set("idleByNode", fmt.Sprintf("%t", query.IdleByNode))
+	}
+
+	sets := make([]*kubecost.AllocationSet, len(windows))
+	for i, w := range windows {
+		sets[i] = kubecost.NewAllocationSet(*w.Start(), *w.End())
+	}
+
+	result := &FederatedAllocationResult{
+		Clusters: []string{},
+	}
+
+	owners := clusterOwners{}
+	for _, resp := range f.fanOut(ctx, allocationComputePath, params) {
+		if resp.err != nil {
+			result.Errors = append(result.Errors, newClusterError(resp.cluster, resp.err))
+			continue
+		}
+
+		var remote []map[string]*kubecost.Allocation
+		if err := json.Unmarshal(resp.data, &remote); err != nil {
+			result.Errors = append(result.Errors, newClusterError(resp.cluster, fmt.Errorf("decoding allocations: %w", err)))
+			continue
+		}
+		if len(remote) != len(sets) {
+			result.Errors = append(result.Errors, newClusterError(resp.cluster, fmt.Errorf("expected %d allocation sets, received %d", len(sets), len(remote))))
+			continue
+		}
+
+		clusterIDs := map[string]bool{}
+		for _, allocs := range remote {
+			for _, alloc := range allocs {
+				if alloc == nil {
+					continue
+				}
+				if alloc.Properties == nil {
+					alloc.Properties = &kubecost.AllocationProperties{}
+				}
+				if alloc.Properties.Cluster == "" {
+					alloc.Properties.Cluster = resp.cluster.Name
+				}
+				clusterIDs[alloc.Properties.Cluster] = true
+			}
+		}
+		if err := owners.claim(resp.cluster, clusterIDs); err != nil {
+			result.Errors = append(result.Errors, newClusterError(resp.cluster, err))
+			continue
+		}
+
+		for i, allocs := range remote {
+			for _, alloc := range allocs {
+				if alloc != nil {
+					sets[i].Insert(alloc)
+				}
+			}
+		}
+		result.Clusters = append(result.Clusters, resp.cluster.Name)
+	}
+
+	asr := kubecost.NewAllocationSetRange(sets...)
+
+	opts := &kubecost.AllocationAggregationOptions{
+		IdleByNode:   query.IdleByNode,
+		TraceContext: ctx,
+	}
+	if query.ShareIdle {
+		opts.ShareIdle = kubecost.ShareWeighted
+	}
+
+	if err := asr.AggregateBy(query.AggregateBy, opts); err != nil {
+		return nil, fmt.Errorf("error aggregating federated allocations for %s: %w", query.Window, err)
+	}
+	result.Allocations = asr
+
+	return result, nil
+}
+
+// QueryAssets queries /assets on each cluster and merges the returned sets by window.
+func (f *Federator) QueryAssets(ctx context.Context, window kubecost.Window, step time.Duration) (*FederatedAssetResult, error) {
+	windows, err := stepWindows(window, step)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make([]*kubecost.AssetSet, len(windows))
+	for i, w := range windows {
+		sets[i] = kubecost.NewAssetSet(*w.Start(), *w.End())
+	}
+
+	result := &FederatedAssetResult{
+		Clusters: []string{},
+	}
+
+	owners := clusterOwners{}
+	for _, resp := range f.fanOut(ctx, assetsPath, windowParams(window, step)) {
+		if resp.err != nil {
+			result.Errors = append(result.Errors, newClusterError(resp.cluster, resp.err))
+			continue
+		}
+
+		var remote kubecost.AssetSetRangeResponse
+		if err := json.Unmarshal(resp.data, &remote); err != nil {
+			result.Errors = append(result.Errors, newClusterError(resp.cluster, fmt.Errorf("decoding assets: %w", err)))
+			continue
+		}
+		if len(remote.Assets) != len(sets) {
+			result.Errors = append(result.Errors, newClusterError(resp.cluster, fmt.Errorf("expected %d asset sets, received %d", len(sets), len(remote.Assets))))
+			continue
+		}
+
+		clusterIDs := map[string]bool{}
+		for _, as := range remote.Assets {
+			for _, asset := range as.Assets {
+				if asset == nil {
+					continue
+				}
+				if props := asset.GetProperties(); props != nil {
+					if props.Cluster == "" {
+						props.Cluster = resp.cluster.Name
+					}
+					clusterIDs[props.Cluster] = true
+				}
+			}
+		}
+		if err := owners.claim(resp.cluster, clusterIDs); err != nil {
+			result.Errors = append(result.Errors, newClusterError(resp.cluster, err))
+			continue
+		}
+
+		for i, as := range remote.Assets {
+			for _, asset := range as.Assets {
+				if asset == nil {
+					continue
+				}
+				if err := sets[i].Insert(asset, nil); err != nil {
+					log.Warnf("Federator: failed to insert asset from cluster %s: %s", resp.cluster.Name, err)
+				}
+			}
+		}
+		result.Clusters = append(result.Clusters, resp.cluster.Name)
+	}
+
+	result.Assets = kubecost.NewAssetSetRange(sets...)
+
+	return result, nil
+}
+
+// clusterOwners records the remote cluster definition which reported each
+// cluster ID during a federated query.
+type clusterOwners map[string]*ClusterDefinition
+
+// claim records the given cluster as the owner of the cluster IDs it reported,
+// or returns an error if any of them was already reported by another cluster.
+func (co clusterOwners) claim(cluster *ClusterDefinition, clusterIDs map[string]bool) error {
+	for id := range clusterIDs {
+		if owner, ok := co[id]; ok && owner != cluster {
+			return fmt.Errorf("cluster id %s is already reported by cluster %s (%s)", id, owner.Name, owner.Address)
+		}
+	}
+
+	for id := range clusterIDs {
+		co[id] = cluster
+	}
+
+	return nil
+}
+
+// clusterResponse is the data envelope payload, or the error, returned by a single cluster.
+type clusterResponse struct {
+	cluster *ClusterDefinition
+	data    json.RawMessage
+	err     error
+}
+
+// fanOut concurrently issues a GET request for path with the provided query parameters against every
+// cluster, returning the responses in the order the clusters were provided.
+func (f *Federator) fanOut(ctx context.Context, path string, params url.Values) []*clusterResponse {
+	clusters := f.clusters.GetAll()
+	responses := make([]*clusterResponse, len(clusters))
+
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		wg.Add(1)
+		go func(i int, cluster *ClusterDefinition) {
+			defer wg.Done()
+
+			data, err := f.query(ctx, cluster, path, params)
+			responses[i] = &clusterResponse{
+				cluster: cluster,
+				data:    data,
+				err:     err,
+			}
+		}(i, cluster)
+	}
+	wg.Wait()
+
+	return responses
+}
+
+// query issues a single request against a cluster, returning the data from the response envelope.
+func (f *Federator) query(ctx context.Context, cluster *ClusterDefinition, path string, params url.Values) (json.RawMessage, error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	reqURL := strings.TrimSuffix(cluster.Address, "/") + path + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	if auth, ok := cluster.BasicAuth(); ok {
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	var envelope struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	decodeErr := json.Unmarshal(body, &envelope)
+
+	if resp.StatusCode != http.StatusOK || (decodeErr == nil && envelope.Code != 0 && envelope.Code != http.StatusOK) {
+		message := envelope.Message
+		if message == "" {
+			message = strings.TrimSpace(string(body))
+		}
+		return nil, fmt.Errorf("%s returned status %d: %s", path, resp.StatusCode, message)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("decoding response: %w", decodeErr)
+	}
+
+	return envelope.Data, nil
+}
+
+func newClusterError(cluster *ClusterDefinition, err error) *ClusterError {
+	log.Warnf("Federator: failed to query cluster %s (%s): %s", cluster.Name, cluster.Address, err)
+
+	return &ClusterError{
+		ID:      cluster.ID,
+		Name:    cluster.Name,
+		Address: cluster.Address,
+		Error:   err.Error(),
+	}
+}
+
+// windowParams returns the query parameters which request the window as an absolute range, so that
+// every cluster computes exactly the same sets regardless of when it receives the request.
+func windowParams(window kubecost.Window, step time.Duration) url.Values {
+	params := url.Values{}
+	params.Set("window", fmt.Sprintf("%s,%s", window.Start().UTC().Format(time.RFC3339), window.End().UTC().Format(time.RFC3339)))
+	params.Set("step", step.String())
+	return params
+}
+
+// stepWindows returns the windows of each set in a range over window with the given step.
+func stepWindows(window kubecost.Window, step time.Duration) ([]kubecost.Window, error) {
+	if window.IsOpen() || window.IsNegative() {
+		return nil, fmt.Errorf("illegal window: %s", window)
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("illegal step: %s", step)
+	}
+
+	var windows []kubecost.Window
+
+	stepStart := *window.Start()
+	stepEnd := stepStart.Add(step)
+	for window.End().After(stepStart) {
+		windows = append(windows, kubecost.NewClosedWindow(stepStart, stepEnd))
+
+		stepStart = stepEnd
+		stepEnd = stepStart.Add(step)
+	}
+
+	return windows, nil
+}
@@ -0,0 +1,316 @@
+package clusters_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/pkg/cloud"
+	"github.com/opencost/opencost/pkg/config"
+	"github.com/opencost/opencost/pkg/costmodel"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/services/clusters"
+
+	prometheus "github.com/prometheus/client_golang/api"
+)
+
+type staticClusters []*clusters.ClusterDefinition
+
+func (sc staticClusters) GetAll() []*clusters.ClusterDefinition {
+	return sc
+}
+
+var (
+	federationStart  = time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	federationWindow = kubecost.NewClosedWindow(federationStart, federationStart.Add(2*time.Hour))
+)
+
+// testContainer is a running container of a remote cluster, allocated the
+// given number of CPU cores for the whole federation window.
+type testContainer struct {
+	namespace string
+	pod       string
+	cpuCores  float64
+}
+
+// prometheusServer fakes the Prometheus query API of a remote cluster, with a
+// single node costing nodeCPUCost per core-hour on which the given containers
+// run. If cluster is empty, results carry no cluster label. Queries for any
+// other metric return no results.
+func prometheusServer(t *testing.T, cluster string, nodeCPUCost float64, containers []testContainer) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+
+		query := r.FormValue("query")
+		secs, err := strconv.ParseInt(r.FormValue("time"), 10, 64)
+		if err != nil {
+			t.Errorf("invalid query time %s: %s", r.FormValue("time"), err)
+		}
+		end := time.Unix(secs, 0)
+
+		labels := func(kv ...string) map[string]string {
+			m := map[string]string{}
+			if cluster != "" {
+				m["cluster_id"] = cluster
+			}
+			for i := 0; i+1 < len(kv); i += 2 {
+				m[kv[i]] = kv[i+1]
+			}
+			return m
+		}
+		value := func(v float64) [][]interface{} {
+			return [][]interface{}{{float64(end.Unix()), strconv.FormatFloat(v, 'f', -1, 64)}}
+		}
+
+		type result struct {
+			Metric map[string]string `json:"metric"`
+			Values [][]interface{}   `json:"values"`
+		}
+		results := []result{}
+
+		switch {
+		case strings.Contains(query, "kube_pod_container_status_running"):
+			// Containers run for the whole hour queried, sampled every
+			// five minutes
+			var running [][]interface{}
+			for ts := end.Add(-time.Hour); !ts.After(end); ts = ts.Add(5 * time.Minute) {
+				running = append(running, []interface{}{float64(ts.Unix()), "1"})
+			}
+			for _, c := range containers {
+				results = append(results, result{labels("namespace", c.namespace, "pod", c.pod), running})
+			}
+		case strings.Contains(query, "container_cpu_allocation"):
+			for _, c := range containers {
+				results = append(results, result{labels("namespace", c.namespace, "pod", c.pod, "container", "container", "node", "node"), value(c.cpuCores)})
+			}
+		case strings.Contains(query, "node_cpu_hourly_cost"):
+			results = append(results, result{labels("node", "node", "instance_type", "m5.large", "provider_id", "node"), value(nodeCPUCost)})
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data": map[string]interface{}{
+				"resultType": "matrix",
+				"result":     results,
+			},
+		})
+	}))
+}
+
+// computeServer serves /allocation/compute of a remote cluster through the
+// real ComputeAllocationHandler, computing allocations from the given fake
+// Prometheus. If auth is set, requests must carry it as basic auth.
+func computeServer(t *testing.T, prom *httptest.Server, auth string) *httptest.Server {
+	client, err := prometheus.NewClient(prometheus.Config{Address: prom.URL})
+	if err != nil {
+		t.Fatalf("creating prometheus client: %s", err)
+	}
+
+	provider := &cloud.CustomProvider{
+		Config: cloud.NewProviderConfig(config.NewConfigFileManager(nil), "fakeFile"),
+	}
+	a := &costmodel.Accesses{
+		Model: costmodel.NewCostModel(client, provider, nil, nil, time.Minute),
+	}
+
+	router := httprouter.New()
+	router.GET("/allocation/compute", a.ComputeAllocationHandler)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth != "" && r.Header.Get("Authorization") != "Basic "+auth {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		router.ServeHTTP(w, r)
+	}))
+}
+
+func TestFederatorQueryAllocation(t *testing.T) {
+	auth := "dXNlcjpwYXNz" // user:pass
+
+	promA := prometheusServer(t, "cluster-a", 1, []testContainer{
+		{namespace: "ns1", pod: "pod-a", cpuCores: 1},
+	})
+	defer promA.Close()
+	clusterA := computeServer(t, promA, auth)
+	defer clusterA.Close()
+
+	// Remote clusters without a configured cluster id are attributed to
+	// their definition
+	promB := prometheusServer(t, "", 1, []testContainer{
+		{namespace: "ns1", pod: "pod-b", cpuCores: 2},
+		{namespace: "ns2", pod: "pod-c", cpuCores: 3},
+	})
+	defer promB.Close()
+	clusterB := computeServer(t, promB, "")
+	defer clusterB.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "prometheus unavailable", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	federator := clusters.NewFederator(staticClusters{
+		{ID: "a", Name: "cluster-a", Address: clusterA.URL, Details: map[string]interface{}{clusters.DetailsAuthKey: auth}},
+		{ID: "b", Name: "cluster-b", Address: clusterB.URL + "/"},
+		{ID: "c", Name: "cluster-c", Address: failing.URL},
+	}, nil, time.Minute)
+
+	result, err := federator.QueryAllocation(context.Background(), clusters.FederatedAllocationQuery{
+		Window:      federationWindow,
+		Step:        time.Hour,
+		AggregateBy: []string{kubecost.AllocationClusterProp, kubecost.AllocationNamespaceProp},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(result.Clusters) != 2 || result.Clusters[0] != "cluster-a" || result.Clusters[1] != "cluster-b" {
+		t.Errorf("expected cluster-a and cluster-b to succeed, got %v", result.Clusters)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].ID != "c" {
+		t.Fatalf("expected a single error for cluster-c, got %v", result.Errors)
+	}
+
+	if result.Allocations.Length() != 2 {
+		t.Fatalf("expected 2 allocation sets, got %d", result.Allocations.Length())
+	}
+
+	// Remote clusters must return their allocations unaggregated, so that
+	// each can be aggregated by namespace here
+	expected := map[string]float64{
+		"cluster-a/ns1": 1,
+		"cluster-b/ns1": 2,
+		"cluster-b/ns2": 3,
+	}
+	for _, as := range result.Allocations.Slice() {
+		if as.Length() != len(expected) {
+			t.Errorf("expected %d allocations, got %d: %v", len(expected), as.Length(), as.Allocations)
+		}
+		for name, cpuCost := range expected {
+			alloc := as.Allocations[name]
+			if alloc == nil {
+				t.Errorf("missing allocation %s", name)
+				continue
+			}
+			if math.Abs(alloc.CPUCost-cpuCost) > 1e-6 {
+				t.Errorf("expected %s CPU cost of %f, got %f", name, cpuCost, alloc.CPUCost)
+			}
+		}
+	}
+}
+
+func TestFederatorQueryAllocationUnauthorized(t *testing.T) {
+	prom := prometheusServer(t, "cluster-a", 1, nil)
+	defer prom.Close()
+	server := computeServer(t, prom, "dXNlcjpwYXNz")
+	defer server.Close()
+
+	federator := clusters.NewFederator(staticClusters{
+		{ID: "a", Name: "cluster-a", Address: server.URL},
+	}, server.Client(), time.Minute)
+
+	result, err := federator.QueryAllocation(context.Background(), clusters.FederatedAllocationQuery{
+		Window: federationWindow,
+		Step:   2 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(result.Errors) != 1 || len(result.Clusters) != 0 {
+		t.Fatalf("expected the cluster without credentials to fail, got %+v", result)
+	}
+}
+
+func TestFederatorQueryAllocationDuplicateClusterID(t *testing.T) {
+	promA := prometheusServer(t, "cluster-a", 1, []testContainer{
+		{namespace: "ns1", pod: "pod-a", cpuCores: 1},
+	})
+	defer promA.Close()
+	clusterA := computeServer(t, promA, "")
+	defer clusterA.Close()
+
+	// A second remote misconfigured with the same cluster id
+	promB := prometheusServer(t, "cluster-a", 1, []testContainer{
+		{namespace: "ns1", pod: "pod-a", cpuCores: 4},
+	})
+	defer promB.Close()
+	clusterB := computeServer(t, promB, "")
+	defer clusterB.Close()
+
+	federator := clusters.NewFederator(staticClusters{
+		{ID: "a", Name: "cluster-a", Address: clusterA.URL},
+		{ID: "b", Name: "cluster-b", Address: clusterB.URL},
+	}, nil, time.Minute)
+
+	result, err := federator.QueryAllocation(context.Background(), clusters.FederatedAllocationQuery{
+		Window:      federationWindow,
+		Step:        time.Hour,
+		AggregateBy: []string{kubecost.AllocationNamespaceProp},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(result.Clusters) != 1 || result.Clusters[0] != "cluster-a" {
+		t.Errorf("expected only cluster-a to succeed, got %v", result.Clusters)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].ID != "b" || !strings.Contains(result.Errors[0].Error, "cluster id cluster-a") {
+		t.Fatalf("expected a duplicate cluster id error for cluster-b, got %v", result.Errors)
+	}
+
+	for _, as := range result.Allocations.Slice() {
+		alloc := as.Allocations["ns1"]
+		if alloc == nil || math.Abs(alloc.CPUCost-1) > 1e-6 {
+			t.Errorf("expected ns1 CPU cost of 1 from cluster-a only, got %v", alloc)
+		}
+	}
+}
+
+func TestComputeAllocationDefaultAggregation(t *testing.T) {
+	prom := prometheusServer(t, "cluster-a", 1, []testContainer{
+		{namespace: "ns1", pod: "pod-a", cpuCores: 1},
+		{namespace: "ns2", pod: "pod-b", cpuCores: 2},
+	})
+	defer prom.Close()
+	cluster := computeServer(t, prom, "")
+	defer cluster.Close()
+
+	window := fmt.Sprintf("%s,%s", federationWindow.Start().Format(time.RFC3339), federationWindow.End().Format(time.RFC3339))
+
+	// Without aggregate, allocations are aggregated into a single one, unless
+	// requested unaggregated
+	for query, expected := range map[string]int{
+		"window=" + window:                        1,
+		"window=" + window + "&unaggregated=true": 2,
+	} {
+		resp, err := http.Get(cluster.URL + "/allocation/compute?" + query)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		var envelope struct {
+			Data []map[string]*kubecost.Allocation `json:"data"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&envelope)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("decoding response: %s", err)
+		}
+
+		if len(envelope.Data) != 1 || len(envelope.Data[0]) != expected {
+			t.Errorf("%s: expected %d allocations, got %v", query, expected, envelope.Data)
+		}
+	}
+}
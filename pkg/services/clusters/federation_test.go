@@ -0,0 +1,65 @@
+package clusters
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+)
+
+type staticClusters []*ClusterDefinition
+
+func (sc staticClusters) GetAll() []*ClusterDefinition {
+	return sc
+}
+
+var (
+	federationStart  = time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	federationWindow = kubecost.NewClosedWindow(federationStart, federationStart.Add(2*time.Hour))
+)
+
+func TestFederatorQueryAssets(t *testing.T) {
+	assetServer := func(cluster string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			end := federationStart.Add(2 * time.Hour)
+			node := kubecost.NewNode("node", cluster, "provider-"+cluster, federationStart, end, federationWindow)
+			node.CPUCost = 10
+
+			as := kubecost.NewAssetSet(federationStart, end, node)
+			w.Write(wrapData(kubecost.NewAssetSetRange(as), nil))
+		}))
+	}
+
+	clusterA := assetServer("cluster-a")
+	defer clusterA.Close()
+	clusterB := assetServer("cluster-b")
+	defer clusterB.Close()
+
+	federator := NewFederator(staticClusters{
+		{ID: "a", Name: "cluster-a", Address: clusterA.URL},
+		{ID: "b", Name: "cluster-b", Address: clusterB.URL},
+	}, nil, time.Minute)
+
+	result, err := federator.QueryAssets(context.Background(), federationWindow, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	as, err := result.Assets.Get(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(as.Nodes) != 2 {
+		t.Fatalf("expected a node from each cluster, got %d", len(as.Nodes))
+	}
+	if math.Abs(as.TotalCost()-20) > 1e-6 {
+		t.Errorf("expected total cost of 20, got %f", as.TotalCost())
+	}
+}
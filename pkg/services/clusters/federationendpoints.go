@@ -0,0 +1,111 @@
+package clusters
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/kubecost"
+	"github.com/opencost/opencost/pkg/util/httputil"
+)
+
+// FederationHTTPService is an implementation of HTTPService which provides federated allocation and
+// asset queries across all stored cluster definitions.
+type FederationHTTPService struct {
+	federator *Federator
+}
+
+// NewFederationHTTPService creates a new federated query http service
+func NewFederationHTTPService(federator *Federator) *FederationHTTPService {
+	return &FederationHTTPService{
+		federator: federator,
+	}
+}
+
+// Register assigns the endpoints and returns an error on failure.
+func (fhs *FederationHTTPService) Register(router *httprouter.Router) error {
+	router.GET("/federated/allocation/compute", fhs.ComputeAllocation)
+	router.GET("/federated/assets", fhs.ComputeAssets)
+
+	return nil
+}
+
+func (fhs *FederationHTTPService) ComputeAllocation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	window, err := kubecost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'window' parameter: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	aggregateBy, err := parseAggregateBy(qp.GetList("aggregate", ","))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'aggregate' parameter: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	query := FederatedAllocationQuery{
+		Window:      window,
+		Resolution:  qp.GetDuration("resolution", env.GetETLResolution()),
+		Step:        qp.GetDuration("step", window.Duration()),
+		AggregateBy: aggregateBy,
+		IncludeIdle: qp.GetBool("includeIdle", false),
+		ShareIdle:   qp.GetBool("shareIdle", false),
+		IdleByNode:  qp.GetBool("idleByNode", false),
+	}
+
+	result, err := fhs.federator.QueryAllocation(r.Context(), query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write(wrapData(result, nil))
+}
+
+func (fhs *FederationHTTPService) ComputeAssets(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := httputil.NewQueryParams(r.URL.Query())
+
+	window, err := kubecost.ParseWindowWithOffset(qp.Get("window", ""), env.GetParsedUTCOffset())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid 'window' parameter: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := fhs.federator.QueryAssets(r.Context(), window, qp.GetDuration("step", window.Duration()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Write(wrapData(result, nil))
+}
+
+// parseAggregateBy validates aggregation properties, which are applied by the primary rather than the
+// remote clusters.
+func parseAggregateBy(values []string) ([]string, error) {
+	aggregateBy := []string{}
+	for _, value := range values {
+		aggregate := strings.TrimSpace(value)
+		if aggregate == "" {
+			continue
+		}
+
+		if prop, err := kubecost.ParseProperty(aggregate); err == nil {
+			aggregateBy = append(aggregateBy, string(prop))
+		} else if strings.HasPrefix(aggregate, "label:") || strings.HasPrefix(aggregate, "annotation:") {
+			aggregateBy = append(aggregateBy, aggregate)
+		} else {
+			return nil, err
+		}
+	}
+	return aggregateBy, nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"net/http"
 	"path"
 
 	"github.com/opencost/opencost/pkg/env"
@@ -13,6 +14,13 @@ func NewClusterManagerService() HTTPService {
 	return clusters.NewClusterManagerHTTPService(newClusterManager())
 }
 
+// NewFederationService creates a new HTTPService implementation which fans allocation and asset queries
+// out to each of the clusters stored by the provided cluster manager
+func NewFederationService(clusterManager *clusters.ClusterManager) HTTPService {
+	federator := clusters.NewFederator(clusterManager, http.DefaultClient, env.GetFederationQueryTimeout())
+	return clusters.NewFederationHTTPService(federator)
+}
+
 // newClusterManager creates a new cluster manager instance for use in the service
 func newClusterManager() *clusters.ClusterManager {
 	clustersConfigFile := path.Join(env.GetCostAnalyzerVolumeMountPath(), "clusters/default-clusters.yaml")
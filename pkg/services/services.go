@@ -4,7 +4,9 @@ import (
 	"sync"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/opencost/opencost/pkg/env"
 	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/services/clusters"
 )
 
 // HTTPService defines an implementation prototype for an object capable of registering
@@ -57,9 +59,18 @@ func (dhs *defaultHTTPServices) RegisterAll(router *httprouter.Router) error {
 // NewCostModelServices creates an HTTPServices implementation containing any predefined
 // http services used with the cost-model
 func NewCostModelServices() HTTPServices {
+	clusterManager := newClusterManager()
+
+	services := []HTTPService{
+		clusters.NewClusterManagerHTTPService(clusterManager),
+	}
+
+	// Federated queries use the same cluster definitions managed by the cluster manager service
+	if env.IsFederationEnabled() {
+		services = append(services, NewFederationService(clusterManager))
+	}
+
 	return &defaultHTTPServices{
-		services: []HTTPService{
-			NewClusterManagerService(),
-		},
+		services: services,
 	}
 }
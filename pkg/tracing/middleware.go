@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span for each request handled by handler. Any
+// trace context propagated by the caller is used as the parent, and the span
+// is made available to the handler through the request context.
+func Middleware(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := Tracer().Start(
+			ctx,
+			fmt.Sprintf("HTTP %s %s", r.Method, r.URL.Path),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(r.Method),
+				semconv.HTTPTarget(r.URL.RequestURI()),
+				semconv.HTTPScheme(schemeOf(r)),
+				semconv.HTTPUserAgent(r.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		respWriter := &statusRecorder{ResponseWriter: rw, statusCode: http.StatusOK}
+		handler.ServeHTTP(respWriter, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPStatusCode(respWriter.statusCode))
+		if respWriter.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(respWriter.statusCode))
+		}
+	})
+}
+
+func schemeOf(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// statusRecorder implements http.ResponseWriter and records the status code
+// written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (sr *statusRecorder) WriteHeader(statusCode int) {
+	sr.statusCode = statusCode
+	sr.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush passes through to the underlying ResponseWriter if it supports
+// streaming responses.
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
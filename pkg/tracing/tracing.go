@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opencost/opencost/pkg/env"
+	"github.com/opencost/opencost/pkg/log"
+	"github.com/opencost/opencost/pkg/version"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the tracer used for all opencost spans.
+const InstrumentationName = "github.com/opencost/opencost"
+
+// ShutdownFunc flushes any buffered spans and releases exporter resources.
+type ShutdownFunc func(context.Context) error
+
+// Initialize configures the global tracer provider and propagators from the
+// environment. When no exporter is configured, the global no-op provider is
+// left in place, making every span created through this package free.
+func Initialize() (ShutdownFunc, error) {
+	noop := func(context.Context) error { return nil }
+
+	exporterName := env.GetTracingExporter()
+	switch exporterName {
+	case env.TracingExporterNone, "":
+		return noop, nil
+	case env.TracingExporterOTLP:
+	default:
+		return noop, fmt.Errorf("unsupported tracing exporter: %s", exporterName)
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(env.GetTracingOTLPEndpoint()),
+		otlptracehttp.WithHeaders(env.GetTracingOTLPHeaders()),
+	}
+	if env.IsTracingOTLPInsecure() {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return noop, fmt.Errorf("creating otlp trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(env.GetTracingServiceName()),
+		semconv.ServiceVersion(version.FriendlyVersion()),
+	))
+	if err != nil {
+		return noop, fmt.Errorf("creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(env.GetTracingSampleRatio()))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	log.Infof("Tracing: exporting spans via OTLP to %s", env.GetTracingOTLPEndpoint())
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the opencost tracer from the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Start creates a span with the given name and attributes as a child of any
+// span contained in ctx. A nil context is treated as context.Background().
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordError records err on the span and marks the span as failed. A nil
+// error is ignored.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
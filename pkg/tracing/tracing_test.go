@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opencost/opencost/pkg/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func withRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	return recorder
+}
+
+func TestInitializeDefaultsToNoop(t *testing.T) {
+	t.Setenv("TRACING_EXPORTER", "")
+
+	shutdown, err := Initialize()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error on shutdown: %s", err)
+	}
+
+	_, span := Start(context.Background(), "noop")
+	defer span.End()
+	if span.SpanContext().IsValid() {
+		t.Fatalf("expected a no-op span when no exporter is configured")
+	}
+}
+
+func TestInitializeUnsupportedExporter(t *testing.T) {
+	t.Setenv("TRACING_EXPORTER", "zipkin")
+
+	_, err := Initialize()
+	if err == nil {
+		t.Fatalf("expected error for unsupported exporter")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder := withRecorder(t)
+
+	var handlerSpan trace.SpanContext
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/allocation/compute?window=1d", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != "HTTP GET /allocation/compute" {
+		t.Errorf("unexpected span name: %s", span.Name())
+	}
+	if span.SpanContext().SpanID() != handlerSpan.SpanID() {
+		t.Errorf("expected handler context to carry the request span")
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected error status for 500 response, got %s", span.Status().Code)
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	recorder := withRecorder(t)
+
+	_, span := Start(context.Background(), "ok")
+	RecordError(span, nil)
+	span.End()
+
+	_, span = Start(context.Background(), "failed")
+	RecordError(span, errors.New("boom"))
+	span.End()
+
+	spans := recorder.Ended()
+	if spans[0].Status().Code != codes.Unset {
+		t.Errorf("expected unset status for nil error, got %s", spans[0].Status().Code)
+	}
+	if spans[1].Status().Code != codes.Error || len(spans[1].Events()) != 1 {
+		t.Errorf("expected error status and exception event for non-nil error")
+	}
+}
+
+func TestSpanProfilerEvents(t *testing.T) {
+	recorder := withRecorder(t)
+
+	_, span := Start(context.Background(), "profiled")
+	profiler := log.NewSpanProfiler(span)
+	profiler.Start("phase")
+	profiler.Stop("phase")
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	events := spans[0].Events()
+	if len(events) != 1 || events[0].Name != "phase" {
+		t.Fatalf("expected a single 'phase' event, got %v", events)
+	}
+}
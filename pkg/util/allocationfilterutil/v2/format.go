@@ -0,0 +1,217 @@
+package allocationfilterutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+)
+
+// ============================================================================
+// This file contains:
+// Formatting (kubecost.AllocationFilter -> string) for V2 of allocation filters
+// ============================================================================
+//
+// See parser.go for a formal grammar and external links.
+
+// FormatAllocationFilter converts a kubecost.AllocationFilter into a string of
+// the V2 Allocation Filter language, such that parsing the result produces an
+// equivalent filter. An error is returned if the filter uses a field or op
+// which can't be expressed in the language.
+func FormatAllocationFilter(filter kubecost.AllocationFilter) (string, error) {
+	if filter == nil {
+		return "", fmt.Errorf("cannot format nil filter")
+	}
+
+	return format(filter, false)
+}
+
+// format converts a filter to a string. If grouped is true, the filter is
+// being formatted as an operand of '+' and an OR must be parenthesized.
+func format(filter kubecost.AllocationFilter, grouped bool) (string, error) {
+	switch f := filter.(type) {
+	case kubecost.AllocationFilterCondition:
+		return formatConditions([]kubecost.AllocationFilterCondition{f})
+	case kubecost.AllocationFilterAnd:
+		if len(f.Filters) == 0 {
+			return "", fmt.Errorf("cannot format empty AND filter")
+		}
+
+		// A sequence of '!:' comparisons against the same key is ANDed, e.g.
+		// namespace!:"a","b"
+		if conds, ok := sameKeyConditions(f.Filters, false); ok {
+			return formatConditions(conds)
+		}
+
+		parts := make([]string, len(f.Filters))
+		for i, inner := range f.Filters {
+			s, err := format(inner, true)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+
+		return strings.Join(parts, "+"), nil
+	case kubecost.AllocationFilterOr:
+		if len(f.Filters) == 0 {
+			return "", fmt.Errorf("cannot format empty OR filter")
+		}
+
+		// A sequence of ':' comparisons against the same key is ORed, e.g.
+		// namespace:"a","b"
+		if conds, ok := sameKeyConditions(f.Filters, true); ok {
+			return formatConditions(conds)
+		}
+
+		if len(f.Filters) == 1 {
+			return format(f.Filters[0], grouped)
+		}
+
+		parts := make([]string, len(f.Filters))
+		for i, inner := range f.Filters {
+			s, err := format(inner, false)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+
+		s := strings.Join(parts, "|")
+		if grouped {
+			s = "(" + s + ")"
+		}
+		return s, nil
+	default:
+		return "", fmt.Errorf("cannot format filter %s", filter)
+	}
+}
+
+// sameKeyConditions returns the filters as conditions if they are all
+// conditions against the same field and key with a positive (':') or negative
+// ('!:') op.
+func sameKeyConditions(filters []kubecost.AllocationFilter, positive bool) ([]kubecost.AllocationFilterCondition, bool) {
+	conds := make([]kubecost.AllocationFilterCondition, 0, len(filters))
+	for _, filter := range filters {
+		cond, ok := filter.(kubecost.AllocationFilterCondition)
+		if !ok {
+			return nil, false
+		}
+		if isPositiveOp(cond.Op) != positive {
+			return nil, false
+		}
+		if len(conds) > 0 && (cond.Field != conds[0].Field || cond.Key != conds[0].Key || cond.Op != conds[0].Op) {
+			return nil, false
+		}
+		// Only comparisons with a list of values can be collapsed
+		if len(conds) > 0 && !isMultiValueOp(cond.Op) {
+			return nil, false
+		}
+		conds = append(conds, cond)
+	}
+
+	return conds, true
+}
+
+func isPositiveOp(op kubecost.FilterOp) bool {
+	switch op {
+	case kubecost.FilterNotEquals, kubecost.FilterNotContains, kubecost.FilterNotRegex, kubecost.FilterNotExists:
+		return false
+	}
+	return true
+}
+
+func isMultiValueOp(op kubecost.FilterOp) bool {
+	switch op {
+	case kubecost.FilterEquals, kubecost.FilterNotEquals,
+		kubecost.FilterContains, kubecost.FilterNotContains,
+		kubecost.FilterRegex, kubecost.FilterNotRegex:
+		return true
+	}
+	return false
+}
+
+var numericOpTokens = map[kubecost.FilterOp]string{
+	kubecost.FilterGreaterThan:         ">",
+	kubecost.FilterGreaterThanOrEquals: ">=",
+	kubecost.FilterLessThan:            "<",
+	kubecost.FilterLessThanOrEquals:    "<=",
+}
+
+// formatConditions formats conditions sharing a field, key, and op as a single
+// comparison.
+func formatConditions(conds []kubecost.AllocationFilterCondition) (string, error) {
+	first := conds[0]
+
+	var sb strings.Builder
+
+	if name, ok := kcFilterFieldToNumeric[first.Field]; ok {
+		opToken, ok := numericOpTokens[first.Op]
+		if !ok {
+			return "", fmt.Errorf("unsupported filter op '%s' for numeric field '%s'", first.Op, first.Field)
+		}
+		if _, err := strconv.ParseFloat(first.Value, 64); err != nil {
+			return "", fmt.Errorf("invalid number '%s' for numeric field '%s'", first.Value, first.Field)
+		}
+		return name + opToken + first.Value, nil
+	} else if name, ok := kcFilterFieldToFF1[first.Field]; ok {
+		if first.Key != "" {
+			return "", fmt.Errorf("unexpected key '%s' for field '%s'", first.Key, first.Field)
+		}
+		sb.WriteString(name)
+	} else if name, ok := kcFilterFieldToFF2[first.Field]; ok {
+		if first.Key == "" || strings.ContainsAny(first.Key, "]") {
+			return "", fmt.Errorf("invalid key '%s' for field '%s'", first.Key, first.Field)
+		}
+		sb.WriteString(fmt.Sprintf("%s[%s]", name, first.Key))
+	} else {
+		return "", fmt.Errorf("unsupported filter field '%s'", first.Field)
+	}
+
+	positiveOp, negativeOp := kubecost.FilterOp(kubecost.FilterEquals), kubecost.FilterOp(kubecost.FilterNotEquals)
+	if first.Field == kubecost.FilterServices {
+		positiveOp, negativeOp = kubecost.FilterContains, kubecost.FilterNotContains
+	}
+
+	switch first.Op {
+	case positiveOp:
+		sb.WriteString(":")
+	case negativeOp:
+		sb.WriteString("!:")
+	case kubecost.FilterRegex:
+		sb.WriteString("~")
+	case kubecost.FilterNotRegex:
+		sb.WriteString("!~")
+	case kubecost.FilterExists:
+		return sb.String() + " " + existsKeyword, nil
+	case kubecost.FilterNotExists:
+		return notKeyword + " " + sb.String() + " " + existsKeyword, nil
+	default:
+		return "", fmt.Errorf("unsupported filter op '%s' for field '%s'", first.Op, first.Field)
+	}
+
+	for i, cond := range conds {
+		if strings.Contains(cond.Value, `"`) {
+			return "", fmt.Errorf("unsupported '\"' in filter value '%s'", cond.Value)
+		}
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(`"` + cond.Value + `"`)
+	}
+
+	return sb.String(), nil
+}
+
+var kcFilterFieldToFF1 = invertFilterFields(ff1ToKCFilterField)
+var kcFilterFieldToFF2 = invertFilterFields(ff2ToKCFilterField)
+var kcFilterFieldToNumeric = invertFilterFields(numericToKCFilterField)
+
+func invertFilterFields(m map[string]kubecost.FilterField) map[kubecost.FilterField]string {
+	inverted := make(map[kubecost.FilterField]string, len(m))
+	for k, v := range m {
+		inverted[v] = k
+	}
+	return inverted
+}
@@ -0,0 +1,105 @@
+package allocationfilterutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	cases := []string{
+		`namespace:"kubecost"`,
+		`node!:"node1","node2"`,
+		`cluster:"cluster-one"+namespace!:"kube-system"`,
+		`label[app]:"cost-analyzer","kubecost"+annotation[owner]!:"__unallocated__"`,
+		`services:"svc1"+services!:"svc2"`,
+		`namespace:"a"|label[team]:"b"`,
+		`namespace:"a"+(cluster:"c1"|cluster:"c2")+controllerKind:"deployment"`,
+		`(namespace:"a"|namespace:"b")+(pod:"p1"|container:"c1"+controllerName:"x")`,
+		`NOT namespace:"a"`,
+		`NOT (namespace:"a"|label[team]:"b")+cluster:"c1"`,
+		`namespace~"^team-.*","^ops-"+pod!~"-canary$"`,
+		`label[app] exists+NOT annotation[owner] exists`,
+		`totalCost>100+cpuEfficiency<0.5|gpuHours>=1+ramCost<=2.25`,
+		`NOT totalCost>100`,
+	}
+
+	for _, input := range cases {
+		t.Run(input, func(t *testing.T) {
+			parsed, err := ParseAllocationFilter(input)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %s", err)
+			}
+
+			formatted, err := FormatAllocationFilter(parsed)
+			if err != nil {
+				t.Fatalf("unexpected format error: %s", err)
+			}
+
+			reparsed, err := ParseAllocationFilter(formatted)
+			if err != nil {
+				t.Fatalf("unexpected error parsing formatted filter %s: %s", formatted, err)
+			}
+
+			// Formatting is stable once NOT has been pushed down into the comparisons
+			reformatted, err := FormatAllocationFilter(reparsed)
+			if err != nil {
+				t.Fatalf("unexpected format error: %s", err)
+			}
+			if reformatted != formatted {
+				t.Fatalf("round trip mismatch for %s\nexpected: %s\ngot: %s", input, formatted, reformatted)
+			}
+
+			// Filters without NOT are reproduced exactly
+			if !strings.HasPrefix(input, "NOT") && !reflect.DeepEqual(parsed, reparsed) {
+				t.Errorf("expected identical filter for %s\nformatted: %s\nexpected: %s\ngot: %s", input, formatted, parsed, reparsed)
+			}
+		})
+	}
+}
+
+func TestFormatFromString(t *testing.T) {
+	// Filters constructed in code, e.g. by the v1 query filter parser, should
+	// format to a filter which parses to the same String() representation
+	filter := kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+		kubecost.AllocationFilterCondition{Field: kubecost.FilterClusterID, Op: kubecost.FilterEquals, Value: "cluster-one"},
+		kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+			kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: "kubecost"},
+			kubecost.AllocationFilterCondition{Field: kubecost.FilterLabel, Key: "app", Op: kubecost.FilterNotEquals, Value: "foo"},
+		}},
+	}}
+
+	formatted, err := FormatAllocationFilter(filter)
+	if err != nil {
+		t.Fatalf("unexpected format error: %s", err)
+	}
+	if formatted != `cluster:"cluster-one"+(namespace:"kubecost"|label[app]!:"foo")` {
+		t.Errorf("unexpected formatted filter: %s", formatted)
+	}
+
+	parsed, err := ParseAllocationFilter(formatted)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+	if parsed.Flattened().String() != filter.Flattened().String() {
+		t.Errorf("expected %s, got %s", filter.Flattened(), parsed.Flattened())
+	}
+}
+
+func TestFormatUnsupported(t *testing.T) {
+	cases := []kubecost.AllocationFilter{
+		kubecost.AllocationFilterNone{},
+		kubecost.AllocationFilterAnd{},
+		kubecost.AllocationFilterCondition{Field: kubecost.FilterAlias, Key: "team", Op: kubecost.FilterEquals, Value: "a"},
+		kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterStartsWith, Value: "kube"},
+		kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: `a"b`},
+	}
+
+	for _, filter := range cases {
+		if s, err := FormatAllocationFilter(filter); err == nil {
+			t.Errorf("expected error formatting %s, got %s", filter, s)
+		}
+	}
+}
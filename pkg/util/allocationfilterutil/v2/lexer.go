@@ -18,13 +18,25 @@ import (
 type tokenKind int
 
 const (
-	colon tokenKind = iota // ':'
-	comma                  // ','
-	plus                   // '+'
-
-	bangColon // '!:'
-
-	str // '"foo"'
+	colon      tokenKind = iota // ':'
+	comma                       // ','
+	plus                        // '+'
+	pipe                        // '|'
+	leftParen                   // '('
+	rightParen                  // ')'
+
+	bangColon    // '!:'
+	tilde        // '~'
+	bangTilde    // '!~'
+	greater      // '>'
+	greaterEqual // '>='
+	less         // '<'
+	lessEqual    // '<='
+	not          // 'NOT'
+	exists       // 'exists'
+
+	str    // '"foo"'
+	number // '100', '0.5'
 
 	filterField1 // 'namespace', 'cluster'
 	filterField2 // 'label', 'annotation'
@@ -35,8 +47,9 @@ const (
 )
 
 // These maps serve a dual purpose. (1) to help the lexer identify special
-// strings that should become filterField1/2 instead of identifiers and (2) to
-// help the parser convert tokens into AllocationFilterConditions.
+// strings that should become filterField1/2 instead of identifiers when
+// filtering Allocations and (2) to help the parser convert tokens into
+// AllocationFilterConditions.
 var ff1ToKCFilterField = map[string]kubecost.FilterField{
 	"cluster":        kubecost.FilterClusterID,
 	"node":           kubecost.FilterNode,
@@ -50,8 +63,64 @@ var ff1ToKCFilterField = map[string]kubecost.FilterField{
 var ff2ToKCFilterField = map[string]kubecost.FilterField{
 	"label":      kubecost.FilterLabel,
 	"annotation": kubecost.FilterAnnotation,
+	"property":   kubecost.FilterCustomProperty,
+}
+
+// numericToKCFilterField maps the numeric fields of Allocations, which are
+// lexed as filterField1 but only support numeric comparisons.
+var numericToKCFilterField = map[string]kubecost.FilterField{
+	"totalCost":              kubecost.FilterTotalCost,
+	"cpuCost":                kubecost.FilterCPUCost,
+	"gpuCost":                kubecost.FilterGPUCost,
+	"ramCost":                kubecost.FilterRAMCost,
+	"pvCost":                 kubecost.FilterPVCost,
+	"networkCost":            kubecost.FilterNetworkCost,
+	"loadBalancerCost":       kubecost.FilterLoadBalancerCost,
+	"sharedCost":             kubecost.FilterSharedCost,
+	"externalCost":           kubecost.FilterExternalCost,
+	"cpuCoreHours":           kubecost.FilterCPUCoreHours,
+	"ramByteHours":           kubecost.FilterRAMByteHours,
+	"gpuHours":               kubecost.FilterGPUHours,
+	"cpuCoreRequestAverage":  kubecost.FilterCPUCoreRequestAverage,
+	"cpuCoreUsageAverage":    kubecost.FilterCPUCoreUsageAverage,
+	"ramBytesRequestAverage": kubecost.FilterRAMBytesRequestAverage,
+	"ramBytesUsageAverage":   kubecost.FilterRAMBytesUsageAverage,
+	"cpuEfficiency":          kubecost.FilterCPUEfficiency,
+	"ramEfficiency":          kubecost.FilterRAMEfficiency,
+	"totalEfficiency":        kubecost.FilterTotalEfficiency,
+}
+
+// fieldSet is the set of fields which can be filtered on for a type, mapping
+// the name of each field in the filter language to the name of the property it
+// filters. Keyed fields require keyed access, e.g. label[app]. Numeric fields
+// are unkeyed and only support numeric comparisons, e.g. totalCost>100.
+type fieldSet struct {
+	unkeyed map[string]string
+	keyed   map[string]string
+	numeric map[string]string
+}
+
+var allocationFields = fieldSet{
+	unkeyed: fieldNames(ff1ToKCFilterField),
+	keyed:   fieldNames(ff2ToKCFilterField),
+	numeric: fieldNames(numericToKCFilterField),
+}
+
+func fieldNames(m map[string]kubecost.FilterField) map[string]string {
+	names := make(map[string]string, len(m))
+	for k, v := range m {
+		names[k] = string(v)
+	}
+	return names
 }
 
+// notKeyword is the reserved word negating the expression which follows it
+const notKeyword = "NOT"
+
+// existsKeyword is the reserved word testing for the presence of the field
+// which precedes it
+const existsKeyword = "exists"
+
 func (tk tokenKind) String() string {
 	switch tk {
 	case colon:
@@ -60,10 +129,34 @@ func (tk tokenKind) String() string {
 		return "comma"
 	case plus:
 		return "plus"
+	case pipe:
+		return "pipe"
+	case leftParen:
+		return "leftParen"
+	case rightParen:
+		return "rightParen"
 	case bangColon:
 		return "bangColon"
+	case tilde:
+		return "tilde"
+	case bangTilde:
+		return "bangTilde"
+	case greater:
+		return "greater"
+	case greaterEqual:
+		return "greaterEqual"
+	case less:
+		return "less"
+	case lessEqual:
+		return "lessEqual"
+	case not:
+		return "not"
+	case exists:
+		return "exists"
 	case str:
 		return "str"
+	case number:
+		return "number"
 	case filterField1:
 		return "filterField1"
 	case filterField2:
@@ -97,6 +190,7 @@ func (t token) String() string {
 
 type scanner struct {
 	source string
+	fields fieldSet
 	tokens []token
 	errors []error
 
@@ -169,12 +263,34 @@ func (s *scanner) scanToken() {
 		s.addToken(comma)
 	case '+':
 		s.addToken(plus)
+	case '|':
+		s.addToken(pipe)
+	case '(':
+		s.addToken(leftParen)
+	case ')':
+		s.addToken(rightParen)
 	case '!':
 		if s.match(':') {
 			s.addToken(bangColon)
+		} else if s.match('~') {
+			s.addToken(bangTilde)
 		} else {
 			s.errors = append(s.errors, fmt.Errorf("Position %d: Unexpected '!'", s.nextByte-1))
 		}
+	case '~':
+		s.addToken(tilde)
+	case '>':
+		if s.match('=') {
+			s.addToken(greaterEqual)
+		} else {
+			s.addToken(greater)
+		}
+	case '<':
+		if s.match('=') {
+			s.addToken(lessEqual)
+		} else {
+			s.addToken(less)
+		}
 	// strings
 	case '"':
 		s.string()
@@ -185,13 +301,14 @@ func (s *scanner) scanToken() {
 	case ' ', '\t', '\n', '\r':
 		break
 	default:
+		// numbers, which fall back to identifiers if followed by identifier
+		// characters, e.g. '100abc'
+		if isDigit(c) {
+			s.number()
+			break
+		}
+
 		// identifiers
-		//
-		// We can keep it simple and not _force_ the first character to be a
-		// non-number because we don't need numbers in this language. If we need
-		// to extend the language to support numbers, this has to become just
-		// isAlpha() and then s.identifier() will use isIdentifierChar() in
-		// its main loop.
 		if isIdentifierChar(c) {
 			s.identifier()
 			break
@@ -218,6 +335,29 @@ func isIdentifierChar(b byte) bool {
 		b == '_' // underscores are allowed because of Prometheus sanitization
 }
 
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func (s *scanner) number() {
+	for isDigit(s.peek()) {
+		s.advance()
+	}
+
+	// Fractional part, which requires a digit after the '.'
+	if s.peek() == '.' && s.nextByte+1 < len(s.source) && isDigit(s.source[s.nextByte+1]) {
+		s.advance()
+		for isDigit(s.peek()) {
+			s.advance()
+		}
+	} else if isIdentifierChar(s.peek()) {
+		s.identifier()
+		return
+	}
+
+	s.addToken(number)
+}
+
 func (s *scanner) string() {
 	for s.peek() != '"' && !s.atEnd() {
 		s.advance()
@@ -255,9 +395,15 @@ func (s *scanner) identifier() {
 	}
 
 	tokenText := s.source[s.lexemeStartByte:s.nextByte]
-	if _, ok := ff1ToKCFilterField[tokenText]; ok {
+	if tokenText == notKeyword {
+		s.addToken(not)
+	} else if tokenText == existsKeyword {
+		s.addToken(exists)
+	} else if _, ok := s.fields.unkeyed[tokenText]; ok {
+		s.addToken(filterField1)
+	} else if _, ok := s.fields.numeric[tokenText]; ok {
 		s.addToken(filterField1)
-	} else if _, ok := ff2ToKCFilterField[tokenText]; ok {
+	} else if _, ok := s.fields.keyed[tokenText]; ok {
 		s.addToken(filterField2)
 	} else {
 		s.addToken(identifier)
@@ -265,7 +411,12 @@ func (s *scanner) identifier() {
 }
 
 func lexAllocationFilterV2(raw string) ([]token, error) {
-	s := scanner{source: raw}
+	return lexFilterV2(raw, allocationFields)
+}
+
+// lexFilterV2 lexes a filter, identifying the fields in the provided field set.
+func lexFilterV2(raw string, fields fieldSet) ([]token, error) {
+	s := scanner{source: raw, fields: fields}
 	s.scanTokens()
 
 	if len(s.errors) > 0 {
@@ -43,6 +43,16 @@ func TestLexer(t *testing.T) {
 			input:    "!::,+",
 			expected: []token{{kind: bangColon, s: "!:"}, {kind: colon, s: ":"}, {kind: comma, s: ","}, {kind: plus, s: "+"}, {kind: eof}},
 		},
+		{
+			name:     "grouping and or",
+			input:    "(|)",
+			expected: []token{{kind: leftParen, s: "("}, {kind: pipe, s: "|"}, {kind: rightParen, s: ")"}, {kind: eof}},
+		},
+		{
+			name:     "not keyword",
+			input:    "NOT NOTE not",
+			expected: []token{{kind: not, s: "NOT"}, {kind: identifier, s: "NOTE"}, {kind: identifier, s: "not"}, {kind: eof}},
+		},
 		{
 			name:     "string",
 			input:    `"test"`,
@@ -67,8 +77,8 @@ func TestLexer(t *testing.T) {
 			name:  "whitespace variety",
 			input: "1 2" + string('\n') + `" ` + string('\n') + string('\t') + string('\r') + `a"` + string('\t') + string('\r') + "abc[foo a]" + " ",
 			expected: []token{
-				{kind: identifier, s: "1"},
-				{kind: identifier, s: "2"},
+				{kind: number, s: "1"},
+				{kind: number, s: "2"},
 				{kind: str, s: " " + string('\n') + string('\t') + string('\r') + "a"},
 				{kind: identifier, s: "abc"},
 				{kind: keyedAccess, s: "foo a"},
@@ -90,6 +100,56 @@ func TestLexer(t *testing.T) {
 				{kind: eof},
 			},
 		},
+		{
+			name:  "regex and exists",
+			input: `namespace~"^team-.*"+pod!~"a"+label[app] exists`,
+			expected: []token{
+				{kind: filterField1, s: "namespace"},
+				{kind: tilde, s: "~"},
+				{kind: str, s: "^team-.*"},
+				{kind: plus, s: "+"},
+				{kind: filterField1, s: "pod"},
+				{kind: bangTilde, s: "!~"},
+				{kind: str, s: "a"},
+				{kind: plus, s: "+"},
+				{kind: filterField2, s: "label"},
+				{kind: keyedAccess, s: "app"},
+				{kind: exists, s: "exists"},
+				{kind: eof},
+			},
+		},
+		{
+			name:  "numeric comparisons",
+			input: `totalCost>100+cpuCost>=0.5+gpuHours<2+ramCost<=1.25`,
+			expected: []token{
+				{kind: filterField1, s: "totalCost"},
+				{kind: greater, s: ">"},
+				{kind: number, s: "100"},
+				{kind: plus, s: "+"},
+				{kind: filterField1, s: "cpuCost"},
+				{kind: greaterEqual, s: ">="},
+				{kind: number, s: "0.5"},
+				{kind: plus, s: "+"},
+				{kind: filterField1, s: "gpuHours"},
+				{kind: less, s: "<"},
+				{kind: number, s: "2"},
+				{kind: plus, s: "+"},
+				{kind: filterField1, s: "ramCost"},
+				{kind: lessEqual, s: "<="},
+				{kind: number, s: "1.25"},
+				{kind: eof},
+			},
+		},
+		{
+			name:  "number followed by identifier characters",
+			input: `100abc 1.`,
+			expected: []token{
+				{kind: identifier, s: "100abc"},
+				{kind: number, s: "1"},
+				{kind: eof},
+			},
+			expectError: true,
+		},
 	}
 
 	for _, c := range cases {
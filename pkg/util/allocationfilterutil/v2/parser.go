@@ -1,11 +1,13 @@
 // allocationfilterutil provides functionality for parsing V2 of the Kubecost
-// filter language for Allocation types.
+// filter language for Allocation types, as well as the same language over the
+// fields of Assets and cloud costs.
 //
 // e.g. "filter=namespace:kubecost+controllerkind:deployment"
 package allocationfilterutil
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/opencost/opencost/pkg/kubecost"
@@ -19,6 +21,15 @@ import (
 //   label[app]:"cost-analyzer"
 //   node!:"node1","node2"
 //   cluster:"cluster-one"+namespace!:"kube-system"
+//   namespace:"kubecost"|label[team]:"platform"
+//   cluster:"cluster-one"+(namespace:"a"|NOT label[team]:"b")
+//   namespace~"^team-.*"+label[app] exists
+//   totalCost>100+cpuEfficiency<0.5
+//   property[costCenter]:"engineering"
+//
+// Numeric comparisons, like all conditions, are matched against each
+// Allocation before aggregation: totalCost>100 keeps the individual
+// allocations costing more than 100, which are then aggregated.
 //
 // The grammar is approximately as follows:
 //
@@ -27,47 +38,93 @@ import (
 //
 // [1] https://docs.google.com/document/d/1HKkp2bv3mnvfQoBZlpHjfZwQ0FzDLOHKpnwV9gQ_KgU/edit?pli=1
 //
-// <filter> ::= <comparison> ('+' <comparison>)*
-//              NOTE: Language can be extended to support ORs between
-//              comparisons by adding a '|' operator in between comparisons,
-//              though precedence will have to be carefully defined and it may
-//              require adding support for ()-enclosed statements to deal with
-//              precedence.
-//              This would allow for queries like:
-//                namespace:"x"|label[app]="foo"
+// <filter> ::= <and-filter> ('|' <and-filter>)*
+//
+// <and-filter> ::= <unary> ('+' <unary>)*
+//
+// <unary> ::= 'NOT' <unary>
+//           | <primary>
+//
+// <primary> ::= '(' <filter> ')'
+//             | <comparison>
+//
+//              NOTE: Precedence, from tightest to loosest binding, is 'NOT',
+//              '+' (AND), then '|' (OR). For example:
+//                NOT a+b|c == ((NOT a) AND b) OR c
+//              Parentheses can be used to override precedence.
+//
+//              NOT has no filter type of its own. It is pushed down to the
+//              comparisons using De Morgan's laws, so that ':' becomes '!:'
+//              and vice versa.
 //
 // <comparison> ::= <filter-key> <filter-op> <filter-value>
+//                | <filter-key> 'exists'
+//                | <numeric-field> <numeric-op> <number>
+//
+//              NOTE: The value of '~' and '!~' is a regular expression in
+//              RE2 syntax, which is unanchored unless it contains '^' or '$'.
 //
 // <filter-key> ::= <filter-field-2> <keyed-access>
 //                | <filter-field-1>
 //
-// <filter-op> ::= ':' | '!:'
+// <filter-op> ::= ':' | '!:' | '~' | '!~'
+//
+// <numeric-op> ::= '>' | '>=' | '<' | '<='
 //
 // <filter-value> ::= '"' <identifier> '"' (',' <filter-value>)*
 //
-// <filter-field-2> ::= 'label' | 'annotation'
+// <number> ::= [0-9]+ ('.' [0-9]+)? | '"' <number> '"'
+//
+// <filter-field-2> ::= 'label' | 'annotation' | 'property'
+//
+//              NOTE: 'property' filters on a custom allocation property, which
+//              must be configured, e.g. property[costCenter].
 //
 // <filter-field-1> ::= 'cluster' | 'node' | 'namespace'
 //                    | 'controllerName' | 'controllerKind'
 //                    | 'container' | 'pod' | 'services'
 //
+// <numeric-field> ::= 'totalCost' | 'cpuCost' | 'gpuCost' | 'ramCost'
+//                   | 'pvCost' | 'networkCost' | 'loadBalancerCost'
+//                   | 'sharedCost' | 'externalCost' | 'cpuCoreHours'
+//                   | 'ramByteHours' | 'gpuHours' | 'cpuCoreRequestAverage'
+//                   | 'cpuCoreUsageAverage' | 'ramBytesRequestAverage'
+//                   | 'ramBytesUsageAverage' | 'cpuEfficiency'
+//                   | 'ramEfficiency' | 'totalEfficiency'
+//
 // <keyed-access> ::= '[' <identifier> ']'
 //
 // <identifier> ::= --- valid K8s name or Prom-sanitized K8s name
 func ParseAllocationFilter(filter string) (kubecost.AllocationFilter, error) {
-	tokens, err := lexAllocationFilterV2(filter)
+	parsed, err := parse(filter, allocationFields)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled, err := compileAllocationFilter(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("parsing filter: %s", err)
+	}
+
+	return compiled, nil
+}
+
+// parse lexes and parses a filter over the given fields into an AST, which is
+// then compiled into a concrete filter type.
+func parse(filter string, fields fieldSet) (node, error) {
+	tokens, err := lexFilterV2(filter, fields)
 	if err != nil {
 		return nil, fmt.Errorf("lexing filter: %s", err)
 	}
 
 	p := parser{tokens: tokens}
 
-	parsedFilter, err := p.filter()
+	parsed, err := p.filter()
 	if err != nil {
 		return nil, fmt.Errorf("parsing filter: %s", err)
 	}
 
-	return parsedFilter, nil
+	return parsed, nil
 }
 
 // ============================================================================
@@ -148,13 +205,16 @@ func (p *parser) consume(tk tokenKind, message string) (token, error) {
 	return token{}, parseError(p.peek(), message)
 }
 
-// synchronize attempts to skip forward until the next '+', indicating the
-// start of a new <comparison>. This lets us do best-effort reporting of
-// multiple parse errors.
+// synchronize attempts to skip forward until the next '+' or '|', indicating
+// the start of a new <unary>, or until the end of the enclosing group. This
+// lets us do best-effort reporting of multiple parse errors.
 func (p *parser) synchronize() {
 	p.advance()
 	for !p.atEnd() {
-		if p.previous().kind == plus {
+		if p.previous().kind == plus || p.previous().kind == pipe {
+			return
+		}
+		if p.check(rightParen) {
 			return
 		}
 
@@ -162,156 +222,241 @@ func (p *parser) synchronize() {
 	}
 }
 
+// ----------------------------------------------------------------------------
+// AST
+// ----------------------------------------------------------------------------
+
+// node is a parsed filter expression. Nodes are independent of the type being
+// filtered and are compiled into a concrete filter once parsing succeeds.
+type node interface {
+	isNode()
+}
+
+// andNode is a sequence of '+' separated operands
+type andNode struct {
+	operands []node
+}
+
+// orNode is a sequence of '|' separated operands
+type orNode struct {
+	operands []node
+}
+
+// notNode is an operand preceded by 'NOT'
+type notNode struct {
+	not     token
+	operand node
+}
+
+// comparisonNode is a single <comparison>, e.g. label[app]!:"a","b". An
+// 'exists' comparison has no values and a numeric comparison has exactly one.
+type comparisonNode struct {
+	field  token
+	key    string
+	op     token
+	values []string
+}
+
+func (andNode) isNode()        {}
+func (orNode) isNode()         {}
+func (notNode) isNode()        {}
+func (comparisonNode) isNode() {}
+
 // ----------------------------------------------------------------------------
 // Parser grammar rules as recursive descent methods
 // ----------------------------------------------------------------------------
 
 // filter is the main method of the parser. It turns the token stream into an
-// AllocationFilter, reporting parse errors that occurred along the way.
-func (p *parser) filter() (kubecost.AllocationFilter, error) {
+// AST, reporting parse errors that occurred along the way.
+func (p *parser) filter() (node, error) {
+	f, err := p.orFilter()
+	if err != nil {
+		return f, err
+	}
+
+	if !p.atEnd() {
+		return f, parseError(p.peek(), "expect '+', '|', or end of filter")
+	}
+
+	return f, nil
+}
+
+// orFilter parses a sequence of AND filters separated by '|'. For backwards
+// compatibility, a filter without any '|' is returned as the AND filter itself.
+func (p *parser) orFilter() (node, error) {
 	var errs *multierror.Error
 
-	// Currently, a filter is only a sequence of AND operations
-	f := kubecost.AllocationFilterAnd{}
-	comparison, err := p.comparison()
+	left, err := p.andFilter()
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if !p.check(pipe) {
+		return left, errs.ErrorOrNil()
+	}
+
+	f := orNode{}
+	f.operands = append(f.operands, left)
+	for p.match(pipe) {
+		right, err := p.andFilter()
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+		f.operands = append(f.operands, right)
+	}
+
+	return f, errs.ErrorOrNil()
+}
+
+// andFilter parses a sequence of unary filters separated by '+'.
+func (p *parser) andFilter() (node, error) {
+	var errs *multierror.Error
+
+	f := andNode{}
+	unary, err := p.unary()
 	if err != nil {
 		errs = multierror.Append(errs, err)
 		p.synchronize()
 	} else {
-		f.Filters = append(f.Filters, comparison)
+		f.operands = append(f.operands, unary)
 	}
 	for p.match(plus) {
-		right, err := p.comparison()
+		right, err := p.unary()
 		if err != nil {
 			errs = multierror.Append(errs, err)
 			p.synchronize()
 		} else {
-			f.Filters = append(f.Filters, right)
+			f.operands = append(f.operands, right)
 		}
 	}
 
 	return f, errs.ErrorOrNil()
 }
 
-func (p *parser) comparison() (kubecost.AllocationFilter, error) {
-	field, key, err := p.filterKey()
-	if err != nil {
-		return nil, err
-	}
+// unary parses a primary filter, negating it for each preceding 'NOT'.
+func (p *parser) unary() (node, error) {
+	if p.match(not) {
+		notToken := p.previous()
 
-	opToken, err := p.filterOp()
-	if err != nil {
-		return nil, err
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{not: notToken, operand: inner}, nil
 	}
 
-	var op kubecost.FilterOp
+	return p.primary()
+}
 
-	switch field {
-	case "services":
-		switch opToken.kind {
-		case colon:
-			op = kubecost.FilterContains
-		case bangColon:
-			op = kubecost.FilterNotContains
-		default:
-			return nil, parseError(opToken, "implementation problem: unhandled op token for services filter")
+// primary parses either a parenthesized filter or a single comparison.
+func (p *parser) primary() (node, error) {
+	if p.match(leftParen) {
+		inner, err := p.orFilter()
+		if err != nil {
+			return nil, err
 		}
-	default:
-		switch opToken.kind {
-		case colon:
-			op = kubecost.FilterEquals
-		case bangColon:
-			op = kubecost.FilterNotEquals
-		default:
-			return nil, parseError(opToken, "implementation problem: unhandled op token")
+
+		_, err = p.consume(rightParen, "expect ')' after grouped filter")
+		if err != nil {
+			return nil, err
 		}
 
+		return inner, nil
 	}
 
-	values, err := p.filterValues()
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	field, key, err := p.filterKey()
 	if err != nil {
 		return nil, err
 	}
 
-	switch opToken.kind {
-	// In the != case, a sequence of filter values is ANDed
-	// Example:
-	// namespace!:"foo","bar" -> (and (notequals namespace foo)
-	//                                (notequals namespace bar))
-	case bangColon:
-		baseFilter := kubecost.AllocationFilterAnd{}
-
-		for _, v := range values {
-			baseFilter.Filters = append(baseFilter.Filters, kubecost.AllocationFilterCondition{
-				Field: field,
-				Key:   key,
-				Op:    op,
-				Value: v,
-			})
-		}
+	if p.match(exists) {
+		return comparisonNode{
+			field: field,
+			key:   key,
+			op:    p.previous(),
+		}, nil
+	}
 
-		return baseFilter, nil
-	default:
-		baseFilter := kubecost.AllocationFilterOr{}
-
-		for _, v := range values {
-			baseFilter.Filters = append(baseFilter.Filters, kubecost.AllocationFilterCondition{
-				Field: field,
-				Key:   key,
-				Op:    op,
-				Value: v,
-			})
-		}
+	opToken, err := p.filterOp()
+	if err != nil {
+		return nil, err
+	}
 
-		return baseFilter, nil
+	var values []string
+	if isNumericOp(opToken.kind) {
+		values, err = p.numericValue()
+	} else {
+		values, err = p.filterValues()
+	}
+	if err != nil {
+		return nil, err
 	}
 
+	return comparisonNode{
+		field:  field,
+		key:    key,
+		op:     opToken,
+		values: values,
+	}, nil
 }
 
 // filterKey parses a series of tokens that represent a "filter key", returning
 // an error if a filter key cannot be constructed.
 //
 // Examples:
-// tokens = [filterField2:label keyedAccess:app] -> FilterLabel, app, nil
-// tokens = [filterField1:namespace] -> FilterNamespace, "", nil
-func (p *parser) filterKey() (field kubecost.FilterField, key string, err error) {
-
+// tokens = [filterField2:label keyedAccess:app] -> filterField2:label, app, nil
+// tokens = [filterField1:namespace] -> filterField1:namespace, "", nil
+func (p *parser) filterKey() (field token, key string, err error) {
 	if p.match(filterField2) {
-		rawField := p.previous().s
-		mappedField, ok := ff2ToKCFilterField[rawField]
-		if !ok {
-			return "", "", parseError(p.previous(), "expect key-mapped filter field, like 'label' or 'annotation'")
-		}
+		field = p.previous()
 
 		_, err := p.consume(keyedAccess, "expect keyed access like '[app]' after a mapped field")
 		if err != nil {
-			return "", "", err
+			return token{}, "", err
 		}
 
 		key = p.previous().s
-		return mappedField, key, nil
+		return field, key, nil
 	}
 
-	_, err = p.consume(filterField1, "expect filter field")
+	field, err = p.consume(filterField1, "expect filter field")
 	if err != nil {
-		return "", "", err
+		return token{}, "", err
 	}
 
-	rawField := p.previous().s
-	mappedField, ok := ff1ToKCFilterField[rawField]
-	if !ok {
-		return "", "", parseError(p.previous(), "expect known filter field, like 'cluster' or 'namespace'")
-	}
-
-	return mappedField, "", nil
+	return field, "", nil
 }
 
 func (p *parser) filterOp() (token, error) {
-	if p.match(bangColon, colon) {
+	if p.match(bangColon, colon, tilde, bangTilde, greater, greaterEqual, less, lessEqual) {
 		return p.previous(), nil
 	}
 
-	return token{}, parseError(p.peek(), "expect filter op like ':' or '!:'")
+	return token{}, parseError(p.peek(), "expect filter op like ':', '!:', '~', '>', or 'exists'")
+}
+
+func isNumericOp(tk tokenKind) bool {
+	return tk == greater || tk == greaterEqual || tk == less || tk == lessEqual
+}
+
+// numericValue parses the single value of a numeric comparison, which may be
+// quoted.
+func (p *parser) numericValue() ([]string, error) {
+	if !p.match(number, str) {
+		return nil, parseError(p.peek(), "expect number as filter value")
+	}
+
+	value := p.previous()
+	if _, err := strconv.ParseFloat(value.s, 64); err != nil {
+		return nil, parseError(value, "expect number as filter value")
+	}
+
+	return []string{value.s}, nil
 }
 
 func (p *parser) filterValues() ([]string, error) {
@@ -334,3 +479,202 @@ func (p *parser) filterValues() ([]string, error) {
 
 	return vals, nil
 }
+
+// ----------------------------------------------------------------------------
+// Compilation to kubecost.AllocationFilter
+// ----------------------------------------------------------------------------
+
+// compileAllocationFilter converts a parsed filter into an AllocationFilter.
+func compileAllocationFilter(n node) (kubecost.AllocationFilter, error) {
+	switch f := n.(type) {
+	case andNode:
+		result := kubecost.AllocationFilterAnd{}
+		for _, operand := range f.operands {
+			compiled, err := compileAllocationFilter(operand)
+			if err != nil {
+				return nil, err
+			}
+			result.Filters = append(result.Filters, compiled)
+		}
+		return result, nil
+	case orNode:
+		result := kubecost.AllocationFilterOr{}
+		for _, operand := range f.operands {
+			compiled, err := compileAllocationFilter(operand)
+			if err != nil {
+				return nil, err
+			}
+			result.Filters = append(result.Filters, compiled)
+		}
+		return result, nil
+	case notNode:
+		inner, err := compileAllocationFilter(f.operand)
+		if err != nil {
+			return nil, err
+		}
+
+		negated, err := negate(inner)
+		if err != nil {
+			return nil, parseError(f.not, err.Error())
+		}
+		return negated, nil
+	case comparisonNode:
+		return compileAllocationComparison(f)
+	default:
+		return nil, fmt.Errorf("implementation problem: unhandled filter node %T", n)
+	}
+}
+
+func compileAllocationComparison(c comparisonNode) (kubecost.AllocationFilter, error) {
+	var field kubecost.FilterField
+	var ok bool
+
+	switch c.field.kind {
+	case filterField2:
+		field, ok = ff2ToKCFilterField[c.field.s]
+		if !ok {
+			return nil, parseError(c.field, "expect key-mapped filter field, like 'label', 'annotation' or 'property'")
+		}
+	default:
+		field, ok = ff1ToKCFilterField[c.field.s]
+		if !ok {
+			field, ok = numericToKCFilterField[c.field.s]
+		}
+		if !ok {
+			return nil, parseError(c.field, "expect known filter field, like 'cluster' or 'namespace'")
+		}
+	}
+
+	if field == kubecost.FilterCustomProperty {
+		if _, ok := kubecost.GetAllocationPropertyDefinition(c.key); !ok {
+			return nil, parseError(c.field, fmt.Sprintf("expect configured allocation property, got '%s'", c.key))
+		}
+	}
+
+	numeric := kubecost.IsNumericFilterField(field)
+	if numeric != isNumericOp(c.op.kind) {
+		if numeric {
+			return nil, parseError(c.op, "expect numeric comparison like '>' or '<=' for numeric field")
+		}
+		return nil, parseError(c.op, "expect numeric field for numeric comparison")
+	}
+
+	var op kubecost.FilterOp
+
+	switch c.op.kind {
+	case colon:
+		op = kubecost.FilterEquals
+		if field == kubecost.FilterServices {
+			op = kubecost.FilterContains
+		}
+	case bangColon:
+		op = kubecost.FilterNotEquals
+		if field == kubecost.FilterServices {
+			op = kubecost.FilterNotContains
+		}
+	case tilde:
+		op = kubecost.FilterRegex
+	case bangTilde:
+		op = kubecost.FilterNotRegex
+	case exists:
+		op = kubecost.FilterExists
+	case greater:
+		op = kubecost.FilterGreaterThan
+	case greaterEqual:
+		op = kubecost.FilterGreaterThanOrEquals
+	case less:
+		op = kubecost.FilterLessThan
+	case lessEqual:
+		op = kubecost.FilterLessThanOrEquals
+	default:
+		return nil, parseError(c.op, "implementation problem: unhandled op token")
+	}
+
+	values := c.values
+	switch c.op.kind {
+	case exists:
+		// 'exists' has no value, but still produces a single condition
+		values = []string{""}
+	}
+
+	// Building each condition compiles regular expressions and parses
+	// numbers once, reporting invalid ones as parse errors.
+	var conditions []kubecost.AllocationFilter
+	for _, v := range values {
+		condition, err := kubecost.NewAllocationFilterCondition(field, c.key, op, v)
+		if err != nil {
+			return nil, parseError(c.op, err.Error())
+		}
+		conditions = append(conditions, condition)
+	}
+
+	switch c.op.kind {
+	// In the != case, a sequence of filter values is ANDed
+	// Example:
+	// namespace!:"foo","bar" -> (and (notequals namespace foo)
+	//                                (notequals namespace bar))
+	case bangColon, bangTilde:
+		return kubecost.AllocationFilterAnd{Filters: conditions}, nil
+	default:
+		return kubecost.AllocationFilterOr{Filters: conditions}, nil
+	}
+}
+
+// negate returns the logical negation of a filter expressed using the existing
+// filter types, applying De Morgan's laws to AND and OR filters.
+func negate(filter kubecost.AllocationFilter) (kubecost.AllocationFilter, error) {
+	switch f := filter.(type) {
+	case kubecost.AllocationFilterCondition:
+		switch f.Op {
+		case kubecost.FilterEquals:
+			f.Op = kubecost.FilterNotEquals
+		case kubecost.FilterNotEquals:
+			f.Op = kubecost.FilterEquals
+		case kubecost.FilterContains:
+			f.Op = kubecost.FilterNotContains
+		case kubecost.FilterNotContains:
+			f.Op = kubecost.FilterContains
+		case kubecost.FilterRegex:
+			f.Op = kubecost.FilterNotRegex
+		case kubecost.FilterNotRegex:
+			f.Op = kubecost.FilterRegex
+		case kubecost.FilterExists:
+			f.Op = kubecost.FilterNotExists
+		case kubecost.FilterNotExists:
+			f.Op = kubecost.FilterExists
+		case kubecost.FilterGreaterThan:
+			f.Op = kubecost.FilterLessThanOrEquals
+		case kubecost.FilterGreaterThanOrEquals:
+			f.Op = kubecost.FilterLessThan
+		case kubecost.FilterLessThan:
+			f.Op = kubecost.FilterGreaterThanOrEquals
+		case kubecost.FilterLessThanOrEquals:
+			f.Op = kubecost.FilterGreaterThan
+		default:
+			return nil, fmt.Errorf("cannot negate filter op '%s'", f.Op)
+		}
+		return f, nil
+	case kubecost.AllocationFilterAnd:
+		negated := kubecost.AllocationFilterOr{}
+		for _, inner := range f.Filters {
+			n, err := negate(inner)
+			if err != nil {
+				return nil, err
+			}
+			negated.Filters = append(negated.Filters, n)
+		}
+		return negated, nil
+	case kubecost.AllocationFilterOr:
+		negated := kubecost.AllocationFilterAnd{}
+		for _, inner := range f.Filters {
+			n, err := negate(inner)
+			if err != nil {
+				return nil, err
+			}
+			negated.Filters = append(negated.Filters, n)
+		}
+		return negated, nil
+	default:
+		return nil, fmt.Errorf("cannot negate filter %s", filter)
+	}
+}
@@ -543,3 +543,362 @@ services!:"abc123"
 		})
 	}
 }
+
+func TestParseOrAndNot(t *testing.T) {
+	cases := []struct {
+		name           string
+		input          string
+		expected       kubecost.AllocationFilter
+		shouldMatch    []kubecost.Allocation
+		shouldNotMatch []kubecost.Allocation
+	}{
+		{
+			name:  "or",
+			input: `namespace:"a"|label[team]:"b"`,
+			expected: kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+				kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+					kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+						kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: "a"},
+					}},
+				}},
+				kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+					kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+						kubecost.AllocationFilterCondition{Field: kubecost.FilterLabel, Key: "team", Op: kubecost.FilterEquals, Value: "b"},
+					}},
+				}},
+			}},
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "a"}),
+				allocGenerator(kubecost.AllocationProperties{Namespace: "c", Labels: map[string]string{"team": "b"}}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "c", Labels: map[string]string{"team": "c"}}),
+			},
+		},
+		{
+			name:  "and binds tighter than or",
+			input: `namespace:"a"+cluster:"c1"|namespace:"b"`,
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "a", Cluster: "c1"}),
+				allocGenerator(kubecost.AllocationProperties{Namespace: "b", Cluster: "c2"}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "a", Cluster: "c2"}),
+			},
+		},
+		{
+			name:  "parentheses override precedence",
+			input: `namespace:"a"+(cluster:"c1"|cluster:"c2")`,
+			expected: kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+				kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+					kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterEquals, Value: "a"},
+				}},
+				kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+					kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+						kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+							kubecost.AllocationFilterCondition{Field: kubecost.FilterClusterID, Op: kubecost.FilterEquals, Value: "c1"},
+						}},
+					}},
+					kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+						kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+							kubecost.AllocationFilterCondition{Field: kubecost.FilterClusterID, Op: kubecost.FilterEquals, Value: "c2"},
+						}},
+					}},
+				}},
+			}},
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "a", Cluster: "c2"}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "b", Cluster: "c1"}),
+				allocGenerator(kubecost.AllocationProperties{Namespace: "a", Cluster: "c3"}),
+			},
+		},
+		{
+			name:  "not comparison",
+			input: `NOT namespace:"a","b"`,
+			expected: kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+				kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+					kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterNotEquals, Value: "a"},
+					kubecost.AllocationFilterCondition{Field: kubecost.FilterNamespace, Op: kubecost.FilterNotEquals, Value: "b"},
+				}},
+			}},
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "c"}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "a"}),
+				allocGenerator(kubecost.AllocationProperties{Namespace: "b"}),
+			},
+		},
+		{
+			name:  "not binds tighter than and",
+			input: `NOT namespace:"a"+services:"s1"`,
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "b", Services: []string{"s1"}}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "a", Services: []string{"s1"}}),
+				allocGenerator(kubecost.AllocationProperties{Namespace: "b", Services: []string{"s2"}}),
+			},
+		},
+		{
+			name:  "not group",
+			input: `NOT (namespace:"a"|services!:"s1")`,
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "b", Services: []string{"s1"}}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "a", Services: []string{"s1"}}),
+				allocGenerator(kubecost.AllocationProperties{Namespace: "b", Services: []string{"s2"}}),
+			},
+		},
+		{
+			name:  "double not",
+			input: `NOT NOT label[app]:"__unallocated__"`,
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{}}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{"app": "foo"}}),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := ParseAllocationFilter(c.input)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %s", err)
+			}
+			if c.expected != nil && !reflect.DeepEqual(result, c.expected) {
+				t.Fatalf("Expected:\n%s\nGot:\n%s", c.expected, result)
+			}
+
+			for _, shouldMatch := range c.shouldMatch {
+				if !result.Matches(&shouldMatch) {
+					t.Errorf("Failed to match %s", shouldMatch.Name)
+				}
+			}
+			for _, shouldNotMatch := range c.shouldNotMatch {
+				if result.Matches(&shouldNotMatch) {
+					t.Errorf("Incorrectly matched %s", shouldNotMatch.Name)
+				}
+			}
+		})
+	}
+}
+
+func costGenerator(props kubecost.AllocationProperties, cpuCost, ramCost float64) kubecost.Allocation {
+	a := allocGenerator(props)
+	a.CPUCost = cpuCost
+	a.CPUCoreHours = cpuCost
+	a.RAMCost = ramCost
+	return a
+}
+
+// builtCondition builds a condition whose value is compiled, as the parser
+// does for regex and numeric comparisons.
+func builtCondition(field kubecost.FilterField, key string, op kubecost.FilterOp, value string) kubecost.AllocationFilterCondition {
+	c, err := kubecost.NewAllocationFilterCondition(field, key, op, value)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestParseRegexExistsNumeric(t *testing.T) {
+	cases := []struct {
+		name           string
+		input          string
+		expected       kubecost.AllocationFilter
+		shouldMatch    []kubecost.Allocation
+		shouldNotMatch []kubecost.Allocation
+	}{
+		{
+			name:  "regex",
+			input: `namespace~"^team-.*"`,
+			expected: kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+				kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+					builtCondition(kubecost.FilterNamespace, "", kubecost.FilterRegex, "^team-.*"),
+				}},
+			}},
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "team-a"}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Namespace: "kube-system"}),
+				allocGenerator(kubecost.AllocationProperties{Namespace: "my-team-a"}),
+			},
+		},
+		{
+			name:  "not regex on labels and services",
+			input: `label[app]!~"^kube","cost"+services~"^svc-"`,
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{"app": "web"}, Services: []string{"other", "svc-web"}}),
+				allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{}, Services: []string{"svc-a"}}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{"app": "kubecost"}, Services: []string{"svc-a"}}),
+				allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{"app": "web"}, Services: []string{"other"}}),
+			},
+		},
+		{
+			name:  "exists",
+			input: `label[app] exists`,
+			expected: kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+				kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+					kubecost.AllocationFilterCondition{Field: kubecost.FilterLabel, Key: "app", Op: kubecost.FilterExists},
+				}},
+			}},
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{"app": ""}}),
+				allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{"app": "web"}}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{"team": "a"}}),
+			},
+		},
+		{
+			name:  "not exists",
+			input: `NOT controllerKind exists`,
+			shouldMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{Pod: "bare"}),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				allocGenerator(kubecost.AllocationProperties{ControllerKind: "deployment"}),
+			},
+		},
+		{
+			name:  "numeric",
+			input: `totalCost>100`,
+			expected: kubecost.AllocationFilterAnd{Filters: []kubecost.AllocationFilter{
+				kubecost.AllocationFilterOr{Filters: []kubecost.AllocationFilter{
+					builtCondition(kubecost.FilterTotalCost, "", kubecost.FilterGreaterThan, "100"),
+				}},
+			}},
+			shouldMatch: []kubecost.Allocation{
+				costGenerator(kubecost.AllocationProperties{Namespace: "a"}, 60, 50),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				costGenerator(kubecost.AllocationProperties{Namespace: "b"}, 50, 50),
+			},
+		},
+		{
+			name:  "numeric range",
+			input: `cpuCost>=1.5+cpuCoreHours<"10"`,
+			shouldMatch: []kubecost.Allocation{
+				costGenerator(kubecost.AllocationProperties{Namespace: "a"}, 1.5, 0),
+				costGenerator(kubecost.AllocationProperties{Namespace: "b"}, 9.99, 0),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				costGenerator(kubecost.AllocationProperties{Namespace: "c"}, 1, 0),
+				costGenerator(kubecost.AllocationProperties{Namespace: "d"}, 10, 0),
+			},
+		},
+		{
+			name:  "not numeric",
+			input: `NOT ramCost<=2`,
+			shouldMatch: []kubecost.Allocation{
+				costGenerator(kubecost.AllocationProperties{Namespace: "a"}, 0, 2.5),
+			},
+			shouldNotMatch: []kubecost.Allocation{
+				costGenerator(kubecost.AllocationProperties{Namespace: "b"}, 0, 2),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result, err := ParseAllocationFilter(c.input)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %s", err)
+			}
+			if c.expected != nil && !reflect.DeepEqual(result, c.expected) {
+				t.Fatalf("Expected:\n%s\nGot:\n%s", c.expected, result)
+			}
+
+			for _, shouldMatch := range c.shouldMatch {
+				if !result.Matches(&shouldMatch) {
+					t.Errorf("Failed to match %s", shouldMatch.Name)
+				}
+			}
+			for _, shouldNotMatch := range c.shouldNotMatch {
+				if result.Matches(&shouldNotMatch) {
+					t.Errorf("Incorrectly matched %s", shouldNotMatch.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCustomProperty(t *testing.T) {
+	err := kubecost.SetAllocationPropertyDefinitions([]*kubecost.AllocationPropertyDefinition{
+		{
+			Name: "costCenter",
+			Sources: []*kubecost.AllocationPropertySource{
+				{Type: kubecost.AllocationPropertySourceLabel, Name: "cost_center"},
+				{Type: kubecost.AllocationPropertySourceNamespaceLabel, Name: "cost_center"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer kubecost.SetAllocationPropertyDefinitions(nil)
+
+	filter, err := ParseAllocationFilter(`property[costCenter]:"finance"+NOT property[costCenter]~"^eng"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	shouldMatch := []kubecost.Allocation{
+		allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{"cost_center": "finance"}}),
+		allocGenerator(kubecost.AllocationProperties{NamespaceLabels: map[string]string{"cost_center": "finance"}}),
+	}
+	for _, alloc := range shouldMatch {
+		if !filter.Matches(&alloc) {
+			t.Errorf("expected %s to match", alloc.Name)
+		}
+	}
+
+	shouldNotMatch := []kubecost.Allocation{
+		allocGenerator(kubecost.AllocationProperties{Labels: map[string]string{"cost_center": "engineering"}}),
+		allocGenerator(kubecost.AllocationProperties{Annotations: map[string]string{"cost_center": "finance"}}),
+	}
+	for _, alloc := range shouldNotMatch {
+		if filter.Matches(&alloc) {
+			t.Errorf("expected %s not to match", alloc.Name)
+		}
+	}
+
+	if _, err := ParseAllocationFilter(`property[unknown]:"finance"`); err == nil {
+		t.Errorf("expected parse error for unknown property")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []string{
+		`namespace:"a"|`,
+		`(namespace:"a"`,
+		`namespace:"a")`,
+		`namespace:"a"+()`,
+		`NOT`,
+		`namespace:"a" namespace:"b"`,
+		`namespace~"("`,
+		`namespace>"1"`,
+		`totalCost:"100"`,
+		`totalCost exists`,
+		`totalCost>"abc"`,
+		`totalCost>100,200`,
+		`label exists`,
+	}
+
+	for _, input := range cases {
+		t.Run(input, func(t *testing.T) {
+			if _, err := ParseAllocationFilter(input); err == nil {
+				t.Errorf("expected parse error for %s", input)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package allocationfilterutil
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/opencost/opencost/pkg/filter"
+	"github.com/opencost/opencost/pkg/kubecost"
+)
+
+// ============================================================================
+// This file contains:
+// Compilation of the V2 filter language into the generic filter.Filter[T] for
+// types which expose their fields as properties, such as Assets and cloud
+// costs.
+// ============================================================================
+//
+// See parser.go for a formal grammar and external links.
+
+// filterLanguage describes the fields of the filter language for type T and
+// how a comparison against each of them is expressed as a filter.Filter[T].
+// For each func, key is empty unless the property is keyed.
+type filterLanguage[T any] struct {
+	fields fieldSet
+
+	// equals returns a filter matching values of T for which the property is
+	// equal to value.
+	equals func(property, key, value string) filter.Filter[T]
+
+	// regex returns a filter matching values of T for which the property
+	// matches the expression.
+	regex func(property, key string, re *regexp.Regexp) filter.Filter[T]
+
+	// exists returns a filter matching values of T for which the property is
+	// present.
+	exists func(property, key string) filter.Filter[T]
+
+	// compare returns a filter comparing the numeric property with value.
+	compare func(property string, op filter.NumericOperation, value float64) filter.Filter[T]
+}
+
+// propertiedLanguage returns a filterLanguage over the given fields for types
+// with string, string map, and numeric properties.
+func propertiedLanguage[T interface {
+	filter.StringPropertied
+	filter.StringMapPropertied
+	filter.NumericPropertied
+}](fields fieldSet) filterLanguage[T] {
+	return filterLanguage[T]{
+		fields: fields,
+		equals: func(property, key, value string) filter.Filter[T] {
+			if key != "" {
+				return filter.StringMapProperty[T]{
+					Field: property,
+					Op:    filter.StringMapEquals,
+					Key:   key,
+					Value: value,
+				}
+			}
+
+			return stringEquals[T](property, value)
+		},
+		regex: func(property, key string, re *regexp.Regexp) filter.Filter[T] {
+			if key != "" {
+				return filter.StringMapRegexProperty[T]{
+					Field: property,
+					Key:   key,
+					Regex: re,
+				}
+			}
+
+			return filter.StringRegexProperty[T]{
+				Field: property,
+				Regex: re,
+			}
+		},
+		exists: func(property, key string) filter.Filter[T] {
+			if key != "" {
+				return filter.StringMapProperty[T]{
+					Field: property,
+					Op:    filter.StringMapHasKey,
+					Key:   key,
+				}
+			}
+
+			return stringExists[T](property)
+		},
+		compare: numericCompare[T],
+	}
+}
+
+func stringEquals[T filter.StringPropertied](property, value string) filter.Filter[T] {
+	return filter.StringProperty[T]{
+		Field: property,
+		Op:    filter.StringEquals,
+		Value: value,
+	}
+}
+
+// stringExists matches non-empty values, which are the values not matched by
+// the unallocated value.
+func stringExists[T filter.StringPropertied](property string) filter.Filter[T] {
+	return filter.Not[T]{Filter: stringEquals[T](property, kubecost.UnallocatedSuffix)}
+}
+
+func numericCompare[T filter.NumericPropertied](property string, op filter.NumericOperation, value float64) filter.Filter[T] {
+	return filter.NumericProperty[T]{
+		Field: property,
+		Op:    op,
+		Value: value,
+	}
+}
+
+// Asset filter fields
+//
+// <filter-field-2> ::= 'label'
+//
+// <filter-field-1> ::= 'account' | 'category' | 'cluster' | 'name' | 'project' | 'provider' | 'providerID' | 'service' | 'type'
+//
+// <numeric-field> ::= 'totalCost' | 'adjustment'
+var assetLanguage = propertiedLanguage[kubecost.Asset](fieldSet{
+	unkeyed: map[string]string{
+		"account":    string(kubecost.AssetAccountProp),
+		"category":   string(kubecost.AssetCategoryProp),
+		"cluster":    string(kubecost.AssetClusterProp),
+		"name":       string(kubecost.AssetNameProp),
+		"project":    string(kubecost.AssetProjectProp),
+		"provider":   string(kubecost.AssetProviderProp),
+		"providerID": string(kubecost.AssetProviderIDProp),
+		"service":    string(kubecost.AssetServiceProp),
+		"type":       string(kubecost.AssetTypeProp),
+	},
+	keyed: map[string]string{
+		"label": string(kubecost.AssetLabelProp),
+	},
+	numeric: map[string]string{
+		"totalCost":  string(kubecost.AssetTotalCostProp),
+		"adjustment": string(kubecost.AssetAdjustmentProp),
+	},
+})
+
+// CloudCostItem filter fields
+//
+// <filter-field-2> ::= 'label'
+//
+// <filter-field-1> ::= 'billingID' | 'category' | 'provider' | 'providerID' | 'service' | 'workGroupID'
+//
+// <numeric-field> ::= 'cost' | 'netCost'
+var cloudCostItemLanguage = propertiedLanguage[*kubecost.CloudCostItem](fieldSet{
+	unkeyed: map[string]string{
+		"billingID":   kubecost.CloudCostBillingIDProp,
+		"category":    kubecost.CloudCostCategoryProp,
+		"provider":    kubecost.CloudCostProviderProp,
+		"providerID":  kubecost.CloudCostProviderIDProp,
+		"service":     kubecost.CloudCostServiceProp,
+		"workGroupID": kubecost.CloudCostWorkGroupIDProp,
+	},
+	keyed: map[string]string{
+		"label": kubecost.CloudCostLabelProp,
+	},
+	numeric: map[string]string{
+		"cost":    kubecost.CloudCostCostProp,
+		"netCost": kubecost.CloudCostNetCostProp,
+	},
+})
+
+// CloudCostAggregate filter fields. Aggregates retain only the value of the
+// label they were aggregated by, so 'label' is not keyed.
+//
+// <filter-field-1> ::= 'billingID' | 'label' | 'provider' | 'service' | 'workGroupID'
+//
+// <numeric-field> ::= 'cost' | 'netCost' | 'kubernetesPercent'
+var cloudCostAggregateLanguage = filterLanguage[*kubecost.CloudCostAggregate]{
+	fields: fieldSet{
+		unkeyed: map[string]string{
+			"billingID":   kubecost.CloudCostBillingIDProp,
+			"label":       kubecost.CloudCostLabelProp,
+			"provider":    kubecost.CloudCostProviderProp,
+			"service":     kubecost.CloudCostServiceProp,
+			"workGroupID": kubecost.CloudCostWorkGroupIDProp,
+		},
+		numeric: map[string]string{
+			"cost":              kubecost.CloudCostCostProp,
+			"netCost":           kubecost.CloudCostNetCostProp,
+			"kubernetesPercent": kubecost.CloudCostKubernetesPercentProp,
+		},
+	},
+	equals: func(property, key, value string) filter.Filter[*kubecost.CloudCostAggregate] {
+		return stringEquals[*kubecost.CloudCostAggregate](property, value)
+	},
+	regex: func(property, key string, re *regexp.Regexp) filter.Filter[*kubecost.CloudCostAggregate] {
+		return filter.StringRegexProperty[*kubecost.CloudCostAggregate]{
+			Field: property,
+			Regex: re,
+		}
+	},
+	exists: func(property, key string) filter.Filter[*kubecost.CloudCostAggregate] {
+		return stringExists[*kubecost.CloudCostAggregate](property)
+	},
+	compare: numericCompare[*kubecost.CloudCostAggregate],
+}
+
+// ParseAssetFilter converts a string of the V2 filter language into a filter
+// of Assets.
+//
+// Example queries:
+//
+//	type:"Node"+cluster:"cluster-one"
+//	category:"Storage"|label[team]:"platform"
+//	NOT providerID:"i-1234"
+func ParseAssetFilter(filter string) (filter.Filter[kubecost.Asset], error) {
+	return parseFilter(filter, assetLanguage)
+}
+
+// ParseCloudCostItemFilter converts a string of the V2 filter language into a
+// filter of CloudCostItems.
+//
+// Example queries:
+//
+//	provider:"AWS"+service:"AmazonEC2"
+//	label[team]:"platform"+category!:"Network"
+func ParseCloudCostItemFilter(filter string) (filter.Filter[*kubecost.CloudCostItem], error) {
+	return parseFilter(filter, cloudCostItemLanguage)
+}
+
+// ParseCloudCostAggregateFilter converts a string of the V2 filter language
+// into a filter of CloudCostAggregates.
+//
+// Example queries:
+//
+//	provider:"GCP"+billingID:"012345-6789AB"
+//	service:"Compute Engine"|label:"platform"
+func ParseCloudCostAggregateFilter(filter string) (filter.Filter[*kubecost.CloudCostAggregate], error) {
+	return parseFilter(filter, cloudCostAggregateLanguage)
+}
+
+func parseFilter[T any](raw string, lang filterLanguage[T]) (filter.Filter[T], error) {
+	parsed, err := parse(raw, lang.fields)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled, err := compileFilter(parsed, lang)
+	if err != nil {
+		return nil, fmt.Errorf("parsing filter: %s", err)
+	}
+
+	return compiled, nil
+}
+
+// compileFilter converts a parsed filter into a filter.Filter[T]. The structure
+// mirrors compileAllocationFilter, except that NOT is represented directly with
+// filter.Not.
+func compileFilter[T any](n node, lang filterLanguage[T]) (filter.Filter[T], error) {
+	switch f := n.(type) {
+	case andNode:
+		result := filter.And[T]{}
+		for _, operand := range f.operands {
+			compiled, err := compileFilter(operand, lang)
+			if err != nil {
+				return nil, err
+			}
+			result.Filters = append(result.Filters, compiled)
+		}
+		return result, nil
+	case orNode:
+		result := filter.Or[T]{}
+		for _, operand := range f.operands {
+			compiled, err := compileFilter(operand, lang)
+			if err != nil {
+				return nil, err
+			}
+			result.Filters = append(result.Filters, compiled)
+		}
+		return result, nil
+	case notNode:
+		inner, err := compileFilter(f.operand, lang)
+		if err != nil {
+			return nil, err
+		}
+		return filter.Not[T]{Filter: inner}, nil
+	case comparisonNode:
+		return compileComparison(f, lang)
+	default:
+		return nil, fmt.Errorf("implementation problem: unhandled filter node %T", n)
+	}
+}
+
+func compileComparison[T any](c comparisonNode, lang filterLanguage[T]) (filter.Filter[T], error) {
+	var property string
+	var ok, numeric bool
+
+	switch c.field.kind {
+	case filterField2:
+		property, ok = lang.fields.keyed[c.field.s]
+	default:
+		property, ok = lang.fields.unkeyed[c.field.s]
+		if !ok {
+			property, ok = lang.fields.numeric[c.field.s]
+			numeric = ok
+		}
+	}
+	if !ok {
+		return nil, parseError(c.field, "expect known filter field")
+	}
+
+	if numeric != isNumericOp(c.op.kind) {
+		if numeric {
+			return nil, parseError(c.op, "expect numeric comparison like '>' or '<=' for numeric field")
+		}
+		return nil, parseError(c.op, "expect numeric field for numeric comparison")
+	}
+
+	switch c.op.kind {
+	// As with Allocations, a sequence of '!:' or '!~' values is ANDed and a
+	// sequence of ':' or '~' values is ORed
+	case bangColon:
+		result := filter.And[T]{}
+		for _, v := range c.values {
+			result.Filters = append(result.Filters, filter.Not[T]{Filter: lang.equals(property, c.key, v)})
+		}
+		return result, nil
+	case colon:
+		result := filter.Or[T]{}
+		for _, v := range c.values {
+			result.Filters = append(result.Filters, lang.equals(property, c.key, v))
+		}
+		return result, nil
+	case bangTilde:
+		result := filter.And[T]{}
+		for _, v := range c.values {
+			re, err := regexp.Compile(v)
+			if err != nil {
+				return nil, parseError(c.op, fmt.Sprintf("invalid regex '%s': %s", v, err))
+			}
+			result.Filters = append(result.Filters, filter.Not[T]{Filter: lang.regex(property, c.key, re)})
+		}
+		return result, nil
+	case tilde:
+		result := filter.Or[T]{}
+		for _, v := range c.values {
+			re, err := regexp.Compile(v)
+			if err != nil {
+				return nil, parseError(c.op, fmt.Sprintf("invalid regex '%s': %s", v, err))
+			}
+			result.Filters = append(result.Filters, lang.regex(property, c.key, re))
+		}
+		return result, nil
+	case exists:
+		return lang.exists(property, c.key), nil
+	case greater, greaterEqual, less, lessEqual:
+		value, err := strconv.ParseFloat(c.values[0], 64)
+		if err != nil {
+			return nil, parseError(c.op, "expect number as filter value")
+		}
+		return lang.compare(property, numericOps[c.op.kind], value), nil
+	default:
+		return nil, parseError(c.op, "implementation problem: unhandled op token")
+	}
+}
+
+var numericOps = map[tokenKind]filter.NumericOperation{
+	greater:      filter.NumericGreaterThan,
+	greaterEqual: filter.NumericGreaterThanOrEqual,
+	less:         filter.NumericLessThan,
+	lessEqual:    filter.NumericLessThanOrEqual,
+}
@@ -0,0 +1,158 @@
+package allocationfilterutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opencost/opencost/pkg/kubecost"
+)
+
+var (
+	propertyFilterStart  = time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	propertyFilterEnd    = propertyFilterStart.Add(time.Hour)
+	propertyFilterWindow = kubecost.NewClosedWindow(propertyFilterStart, propertyFilterEnd)
+)
+
+func assetGenerator(asset kubecost.Asset, labels map[string]string) kubecost.Asset {
+	asset.SetLabels(labels)
+	return asset
+}
+
+func TestParseAssetFilter(t *testing.T) {
+	n := kubecost.NewNode("node1", "cluster-one", "i-1234", propertyFilterStart, propertyFilterEnd, propertyFilterWindow)
+	n.CPUCost = 10
+	node := assetGenerator(n, map[string]string{"team": "platform"})
+	disk := assetGenerator(
+		kubecost.NewDisk("disk1", "cluster-two", "vol-1234", propertyFilterStart, propertyFilterEnd, propertyFilterWindow),
+		map[string]string{"team": "data"},
+	)
+	cloud := assetGenerator(
+		kubecost.NewCloud(kubecost.StorageCategory, "bucket-1234", propertyFilterStart, propertyFilterEnd, propertyFilterWindow),
+		nil,
+	)
+
+	cases := []struct {
+		input          string
+		shouldMatch    []kubecost.Asset
+		shouldNotMatch []kubecost.Asset
+	}{
+		{
+			input:          `type:"Node"`,
+			shouldMatch:    []kubecost.Asset{node},
+			shouldNotMatch: []kubecost.Asset{disk, cloud},
+		},
+		{
+			input:          `cluster:"cluster-one","cluster-two"+type!:"Node"`,
+			shouldMatch:    []kubecost.Asset{disk},
+			shouldNotMatch: []kubecost.Asset{node, cloud},
+		},
+		{
+			input:          `providerID:"bucket-1234"|label[team]:"platform"`,
+			shouldMatch:    []kubecost.Asset{node, cloud},
+			shouldNotMatch: []kubecost.Asset{disk},
+		},
+		{
+			input:          `providerID~"^(i|vol)-"+label[team] exists`,
+			shouldMatch:    []kubecost.Asset{node, disk},
+			shouldNotMatch: []kubecost.Asset{cloud},
+		},
+		{
+			input:          `totalCost>5`,
+			shouldMatch:    []kubecost.Asset{node},
+			shouldNotMatch: []kubecost.Asset{disk, cloud},
+		},
+		{
+			input:          `NOT label[team]~"^plat"`,
+			shouldMatch:    []kubecost.Asset{disk, cloud},
+			shouldNotMatch: []kubecost.Asset{node},
+		},
+		{
+			input:          `NOT category:"Storage"`,
+			shouldMatch:    []kubecost.Asset{node},
+			shouldNotMatch: []kubecost.Asset{disk, cloud},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			f, err := ParseAssetFilter(c.input)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %s", err)
+			}
+
+			for _, asset := range c.shouldMatch {
+				if !f.Matches(asset) {
+					t.Errorf("expected %s to match %s", f, asset)
+				}
+			}
+			for _, asset := range c.shouldNotMatch {
+				if f.Matches(asset) {
+					t.Errorf("expected %s not to match %s", f, asset)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCloudCostFilters(t *testing.T) {
+	item := kubecost.NewCloudCostItem(propertyFilterStart, propertyFilterEnd, kubecost.CloudCostItemProperties{
+		ProviderID: "i-1234",
+		Provider:   "AWS",
+		Service:    "AmazonEC2",
+		Category:   "Compute",
+		Labels:     kubecost.CloudCostItemLabels{"team": "platform"},
+	}, true, 1, 1)
+
+	f, err := ParseCloudCostItemFilter(`provider:"AWS"+label[team]:"platform"+category!:"Network"`)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+	if !f.Matches(item) {
+		t.Errorf("expected %s to match item", f)
+	}
+
+	f, err = ParseCloudCostItemFilter(`NOT (service:"AmazonEC2"|providerID:"i-5678")`)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+	if f.Matches(item) {
+		t.Errorf("expected %s not to match item", f)
+	}
+
+	agg := &kubecost.CloudCostAggregate{
+		Properties: kubecost.CloudCostAggregateProperties{
+			Provider:   "GCP",
+			BillingID:  "012345-6789AB",
+			LabelValue: "platform",
+		},
+	}
+
+	f2, err := ParseCloudCostAggregateFilter(`billingID:"012345-6789AB"+label:"platform"`)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+	if !f2.Matches(agg) {
+		t.Errorf("expected %s to match aggregate", f2)
+	}
+
+	f2, err = ParseCloudCostAggregateFilter(`provider~"^G"+cost<=0+label exists`)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+	if !f2.Matches(agg) {
+		t.Errorf("expected %s to match aggregate", f2)
+	}
+
+	// Aggregates only retain a single label value, so keyed labels are invalid
+	if _, err := ParseCloudCostAggregateFilter(`label[team]:"platform"`); err == nil {
+		t.Errorf("expected an error for a keyed label on aggregates")
+	}
+	// Numeric fields only support numeric comparisons
+	if _, err := ParseCloudCostItemFilter(`netCost:"1"`); err == nil {
+		t.Errorf("expected an error for a string comparison of a numeric field")
+	}
+	// Asset fields are not valid for cloud costs
+	if _, err := ParseCloudCostItemFilter(`cluster:"cluster-one"`); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+}